@@ -0,0 +1,225 @@
+package authentication
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/nerufuyo/roastume/src/manager"
+)
+
+var (
+	// ErrQueueFull is returned by ProcessAsync when the work queue is at capacity
+	ErrQueueFull = errors.New("async work queue is full")
+	// ErrManagerClosed is returned by ProcessAsync after Close
+	ErrManagerClosed = errors.New("manager is closed")
+)
+
+// Priority orders queued asynchronous work
+type Priority int
+
+const (
+	// PriorityNormal is the default priority
+	PriorityNormal Priority = iota
+	// PriorityHigh work is dequeued before normal work
+	PriorityHigh
+)
+
+// AsyncOptions holds per-call settings for ProcessAsync
+type AsyncOptions struct {
+	BufferSize int
+	Timeout    time.Duration
+	Priority   Priority
+}
+
+// AsyncOption configures a single ProcessAsync call
+type AsyncOption func(*AsyncOptions)
+
+// WithBufferSize sets the capacity of the returned result channel
+func WithBufferSize(size int) AsyncOption {
+	return func(o *AsyncOptions) {
+		o.BufferSize = size
+	}
+}
+
+// WithTimeout bounds the time from submission to result
+func WithTimeout(timeout time.Duration) AsyncOption {
+	return func(o *AsyncOptions) {
+		o.Timeout = timeout
+	}
+}
+
+// WithPriority sets the queue priority of the call
+func WithPriority(priority Priority) AsyncOption {
+	return func(o *AsyncOptions) {
+		o.Priority = priority
+	}
+}
+
+// asyncJob is a unit of queued work
+type asyncJob struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	data   interface{}
+	out    chan *Result
+}
+
+// workQueue is a bounded two-priority queue drained by a fixed worker pool
+type workQueue struct {
+	high   chan *asyncJob
+	normal chan *asyncJob
+	done   chan struct{}
+	wg     sync.WaitGroup
+	once   sync.Once
+	// mu orders submit against stop: once closed is set no job is
+	// enqueued, so the drain in stop answers every accepted job
+	mu     sync.Mutex
+	closed bool
+}
+
+// newWorkQueue creates a queue holding at most size jobs per priority
+func newWorkQueue(size int) *workQueue {
+	if size <= 0 {
+		size = 1
+	}
+	return &workQueue{
+		high:   make(chan *asyncJob, size),
+		normal: make(chan *asyncJob, size),
+		done:   make(chan struct{}),
+	}
+}
+
+// start launches the worker pool
+func (q *workQueue) start(workers int, run func(*asyncJob)) {
+	if workers <= 0 {
+		workers = 1
+	}
+	for i := 0; i < workers; i++ {
+		q.wg.Add(1)
+		go q.work(run)
+	}
+}
+
+// work drains the queue, always preferring high priority jobs
+func (q *workQueue) work(run func(*asyncJob)) {
+	defer q.wg.Done()
+	for {
+		select {
+		case job := <-q.high:
+			run(job)
+			continue
+		default:
+		}
+
+		select {
+		case job := <-q.high:
+			run(job)
+		case job := <-q.normal:
+			run(job)
+		case <-q.done:
+			return
+		}
+	}
+}
+
+// submit enqueues a job without blocking
+func (q *workQueue) submit(job *asyncJob, priority Priority) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return ErrManagerClosed
+	}
+
+	target := q.normal
+	if priority == PriorityHigh {
+		target = q.high
+	}
+	select {
+	case target <- job:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+// stop shuts down the workers and fails any jobs still queued
+func (q *workQueue) stop() {
+	q.once.Do(func() {
+		q.mu.Lock()
+		q.closed = true
+		close(q.done)
+		q.mu.Unlock()
+		q.wg.Wait()
+		for _, ch := range []chan *asyncJob{q.high, q.normal} {
+			for {
+				select {
+				case job := <-ch:
+					job.cancel()
+					job.out <- &Result{Status: "error", Message: ErrManagerClosed.Error()}
+					close(job.out)
+					continue
+				default:
+				}
+				break
+			}
+		}
+	})
+}
+
+// ProcessAsync queues authentication processing on the bounded worker pool;
+// it returns ErrQueueFull immediately instead of blocking when the queue is
+// at capacity
+func (m *Manager) ProcessAsync(ctx context.Context, data interface{}, opts ...AsyncOption) (<-chan *Result, error) {
+	options := AsyncOptions{BufferSize: 1, Priority: PriorityNormal}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.BufferSize < 1 {
+		options.BufferSize = 1
+	}
+
+	m.queueOnce.Do(func() {
+		m.mu.RLock()
+		workers := m.config.AsyncWorkers
+		m.mu.RUnlock()
+		m.queue.start(workers, m.runAsyncJob)
+	})
+
+	var jobCtx context.Context
+	var cancel context.CancelFunc
+	if options.Timeout > 0 {
+		jobCtx, cancel = context.WithTimeout(ctx, options.Timeout)
+	} else {
+		jobCtx, cancel = context.WithCancel(ctx)
+	}
+	job := &asyncJob{ctx: jobCtx, cancel: cancel, data: data, out: make(chan *Result, options.BufferSize)}
+
+	if err := m.queue.submit(job, options.Priority); err != nil {
+		cancel()
+		m.logger.Printf("Rejected async authentication request: %v", err)
+		return nil, err
+	}
+	return job.out, nil
+}
+
+// runAsyncJob processes a dequeued job and delivers its result
+func (m *Manager) runAsyncJob(job *asyncJob) {
+	defer close(job.out)
+	defer job.cancel()
+
+	var result *Result
+	if err := job.ctx.Err(); err != nil {
+		result = &Result{Status: "error", Message: err.Error()}
+	} else if r, err := m.Process(job.ctx, job.data); err != nil {
+		id, _ := manager.OperationIDOf(err)
+		result = &Result{Status: "error", Message: err.Error(), OperationID: id}
+	} else {
+		result = r
+	}
+
+	select {
+	case job.out <- result:
+	case <-job.ctx.Done():
+	}
+}
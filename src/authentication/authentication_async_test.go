@@ -0,0 +1,87 @@
+package authentication
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestProcessAsync(t *testing.T) {
+	m := NewManager(nil)
+	defer m.Close()
+	out, err := m.ProcessAsync(context.Background(), &Attempt{Subject: "alice"}, WithTimeout(time.Second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	result, ok := <-out
+	if !ok || result.Status != "success" || result.OperationID == "" {
+		t.Fatalf("result = %+v, %v", result, ok)
+	}
+	if _, ok := <-out; ok {
+		t.Error("result channel not closed")
+	}
+}
+
+func TestProcessAsyncAfterClose(t *testing.T) {
+	m := NewManager(nil)
+	m.Close()
+	if _, err := m.ProcessAsync(context.Background(), &Attempt{Subject: "alice"}); !errors.Is(err, ErrManagerClosed) {
+		t.Fatalf("ProcessAsync after Close = %v, want ErrManagerClosed", err)
+	}
+}
+
+func TestCloseAnswersEveryAcceptedJob(t *testing.T) {
+	config := DefaultConfig()
+	config.AsyncWorkers = 2
+	config.AsyncQueueSize = 1000
+	m := NewManager(config)
+
+	var mu sync.Mutex
+	var accepted []<-chan *Result
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				out, err := m.ProcessAsync(context.Background(), &Attempt{Subject: "alice"})
+				if errors.Is(err, ErrManagerClosed) {
+					return
+				}
+				if err == nil {
+					mu.Lock()
+					accepted = append(accepted, out)
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	time.Sleep(10 * time.Millisecond)
+	m.Close()
+	wg.Wait()
+
+	timeout := time.After(5 * time.Second)
+	for _, out := range accepted {
+		select {
+		case <-out:
+		case <-timeout:
+			t.Fatalf("an accepted job of %d got no result after Close", len(accepted))
+		}
+	}
+}
+
+func TestProcessAsyncWhileReconfiguring(t *testing.T) {
+	m := NewManager(nil)
+	defer m.Close()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		m.SetConfig(DefaultConfig())
+	}()
+	if _, err := m.ProcessAsync(context.Background(), &Attempt{Subject: "alice"}); err != nil {
+		t.Fatal(err)
+	}
+	<-done
+}
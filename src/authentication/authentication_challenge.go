@@ -0,0 +1,210 @@
+package authentication
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrChallengeFailed is returned when a required challenge was not passed
+var ErrChallengeFailed = errors.New("challenge failed")
+
+// ChallengeReason describes why a challenge was triggered
+type ChallengeReason string
+
+const (
+	// ReasonNewIP indicates the subject logged in from an unseen address
+	ReasonNewIP ChallengeReason = "new_ip"
+	// ReasonRepeatedFailures indicates too many recent failed attempts
+	ReasonRepeatedFailures ChallengeReason = "repeated_failures"
+)
+
+// Attempt describes a single login attempt passed to Process
+type Attempt struct {
+	Subject           string            `json:"subject"`
+	RemoteIP          string            `json:"remote_ip"`
+	ChallengeResponse string            `json:"challenge_response,omitempty"`
+	Metadata          map[string]string `json:"metadata,omitempty"`
+}
+
+// Challenge verifies a challenge response (reCAPTCHA, hCaptcha or a custom
+// check) for an attempt flagged as suspicious
+type Challenge interface {
+	Verify(ctx context.Context, attempt *Attempt, reasons []ChallengeReason) error
+}
+
+// ChallengeFunc adapts an ordinary function to the Challenge interface
+type ChallengeFunc func(ctx context.Context, attempt *Attempt, reasons []ChallengeReason) error
+
+// Verify calls f(ctx, attempt, reasons)
+func (f ChallengeFunc) Verify(ctx context.Context, attempt *Attempt, reasons []ChallengeReason) error {
+	return f(ctx, attempt, reasons)
+}
+
+// SetChallenge installs the challenge invoked for suspicious attempts;
+// passing nil disables challenges
+func (m *Manager) SetChallenge(challenge Challenge) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.challenge = challenge
+}
+
+// asAttempt extracts an Attempt from Process input
+func asAttempt(data interface{}) (*Attempt, bool) {
+	switch v := data.(type) {
+	case *Attempt:
+		return v, v != nil
+	case Attempt:
+		return &v, true
+	default:
+		return nil, false
+	}
+}
+
+// runChallenge evaluates risk heuristics and invokes the challenge hook;
+// forced reasons require a challenge even when no heuristic fires
+func (m *Manager) runChallenge(ctx context.Context, s *attemptSetup, attempt *Attempt, forced ...ChallengeReason) error {
+	if attempt == nil {
+		return nil
+	}
+	if s.challenge == nil {
+		if len(forced) > 0 {
+			return fmt.Errorf("%w: no challenge configured for %v", ErrChallengeFailed, forced)
+		}
+		return nil
+	}
+
+	reasons := append(append([]ChallengeReason(nil), forced...), m.tracker.suspicious(attempt, s.config.ChallengeFailureThreshold, s.config.ChallengeWindow)...)
+	if len(reasons) == 0 {
+		return nil
+	}
+
+	m.logger.Printf("Challenging attempt for %q: %v", attempt.Subject, reasons)
+	if err := s.challenge.Verify(ctx, attempt, reasons); err != nil {
+		return fmt.Errorf("%w: %v", ErrChallengeFailed, err)
+	}
+	return nil
+}
+
+// trackerSweepEvery is how many recorded attempts an attemptTracker or a
+// VelocitySignal takes between dropping expired entries
+const trackerSweepEvery = 1024
+
+// knownIPRetention is how long an address stays known to a subject after
+// its last successful login from there
+const knownIPRetention = 90 * 24 * time.Hour
+
+// attemptTracker remembers known addresses and recent failures per subject;
+// entries expire, so subjects and addresses seen once do not pile up
+type attemptTracker struct {
+	mu       sync.Mutex
+	now      func() time.Time
+	knownIPs map[string]map[string]time.Time
+	failures map[string][]time.Time
+	records  int
+}
+
+// newAttemptTracker creates an empty tracker
+func newAttemptTracker() *attemptTracker {
+	return &attemptTracker{
+		now:      time.Now,
+		knownIPs: make(map[string]map[string]time.Time),
+		failures: make(map[string][]time.Time),
+	}
+}
+
+// suspicious returns the heuristics that fire for the attempt
+func (t *attemptTracker) suspicious(a *Attempt, threshold int, window time.Duration) []ChallengeReason {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var reasons []ChallengeReason
+	if ips, ok := t.knownIPs[a.Subject]; ok && a.RemoteIP != "" {
+		if _, seen := ips[a.RemoteIP]; !seen {
+			reasons = append(reasons, ReasonNewIP)
+		}
+	}
+	if threshold > 0 && len(t.recentFailures(a.Subject, window)) >= threshold {
+		reasons = append(reasons, ReasonRepeatedFailures)
+	}
+	return reasons
+}
+
+// recentFailures prunes and returns failures inside the window, forgetting
+// subjects left without any
+func (t *attemptTracker) recentFailures(subject string, window time.Duration) []time.Time {
+	all := t.failures[subject]
+	if window <= 0 {
+		return all
+	}
+	cutoff := t.now().Add(-window)
+	kept := all[:0]
+	for _, at := range all {
+		if at.After(cutoff) {
+			kept = append(kept, at)
+		}
+	}
+	if len(kept) == 0 {
+		delete(t.failures, subject)
+		return nil
+	}
+	t.failures[subject] = kept
+	return kept
+}
+
+// recordFailure notes a failed attempt, dropping failures older than window
+func (t *attemptTracker) recordFailure(a *Attempt, window time.Duration) {
+	if a == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.recorded(window)
+	failures := t.recentFailures(a.Subject, window)
+	t.failures[a.Subject] = append(failures, t.now())
+}
+
+// recordSuccess remembers the address and clears failures
+func (t *attemptTracker) recordSuccess(a *Attempt, window time.Duration) {
+	if a == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.recorded(window)
+	if t.knownIPs[a.Subject] == nil {
+		t.knownIPs[a.Subject] = make(map[string]time.Time)
+	}
+	if a.RemoteIP != "" {
+		t.knownIPs[a.Subject][a.RemoteIP] = t.now()
+	}
+	delete(t.failures, a.Subject)
+}
+
+// recorded counts a recorded attempt, sweeping every trackerSweepEvery
+func (t *attemptTracker) recorded(window time.Duration) {
+	if t.records++; t.records%trackerSweepEvery == 0 {
+		t.sweep(window)
+	}
+}
+
+// sweep drops failures older than window and addresses not used within
+// knownIPRetention, and the subjects left without either
+func (t *attemptTracker) sweep(window time.Duration) {
+	for subject := range t.failures {
+		t.recentFailures(subject, window)
+	}
+	cutoff := t.now().Add(-knownIPRetention)
+	for subject, ips := range t.knownIPs {
+		for ip, seen := range ips {
+			if seen.Before(cutoff) {
+				delete(ips, ip)
+			}
+		}
+		if len(ips) == 0 {
+			delete(t.knownIPs, subject)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+package authentication
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestAttemptTrackerForgetsExpiredEntries(t *testing.T) {
+	now := time.Now()
+	tracker := newAttemptTracker()
+	tracker.now = func() time.Time { return now }
+
+	for i := 0; i < trackerSweepEvery/2; i++ {
+		subject := fmt.Sprintf("user%d", i)
+		tracker.recordFailure(&Attempt{Subject: subject}, time.Minute)
+		tracker.recordSuccess(&Attempt{Subject: subject, RemoteIP: "10.0.0.1"}, time.Minute)
+		tracker.recordFailure(&Attempt{Subject: subject}, time.Minute)
+	}
+	if len(tracker.failures) != trackerSweepEvery/2 || len(tracker.knownIPs) != trackerSweepEvery/2 {
+		t.Fatalf("tracking %d failing and %d known subjects", len(tracker.failures), len(tracker.knownIPs))
+	}
+
+	now = now.Add(knownIPRetention + time.Hour)
+	for i := 0; i < trackerSweepEvery/2; i++ {
+		tracker.recordFailure(&Attempt{Subject: "mallory"}, time.Minute)
+	}
+	if len(tracker.failures) != 1 || len(tracker.knownIPs) != 0 {
+		t.Errorf("after the sweep: %d failing and %d known subjects, want 1 and 0", len(tracker.failures), len(tracker.knownIPs))
+	}
+	if got := len(tracker.failures["mallory"]); got != trackerSweepEvery/2 {
+		t.Errorf("mallory has %d failures, want %d", got, trackerSweepEvery/2)
+	}
+
+	now = now.Add(2 * time.Minute)
+	tracker.recordFailure(&Attempt{Subject: "mallory"}, time.Minute)
+	if got := len(tracker.failures["mallory"]); got != 1 {
+		t.Errorf("mallory has %d failures after the window, want 1", got)
+	}
+}
+
+func TestRunChallengeKeepsForcedReasons(t *testing.T) {
+	m := NewManager(nil)
+	var got [][]ChallengeReason
+	s := &attemptSetup{
+		config: *DefaultConfig(),
+		challenge: ChallengeFunc(func(ctx context.Context, attempt *Attempt, reasons []ChallengeReason) error {
+			got = append(got, reasons)
+			return nil
+		}),
+	}
+	s.config.ChallengeFailureThreshold = 1
+	m.tracker.recordFailure(&Attempt{Subject: "alice"}, s.config.ChallengeWindow)
+	m.tracker.recordFailure(&Attempt{Subject: "bob"}, s.config.ChallengeWindow)
+
+	forced := make([]ChallengeReason, 1, 4)
+	forced[0] = ReasonHighRisk
+	for _, subject := range []string{"alice", "bob"} {
+		if err := m.runChallenge(context.Background(), s, &Attempt{Subject: subject}, forced...); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m.runChallenge(context.Background(), s, &Attempt{Subject: "carol"}, forced...)
+	if len(got) != 3 || len(got[0]) != 2 || len(got[2]) != 1 {
+		t.Fatalf("reasons = %v", got)
+	}
+	got[0][1] = "changed"
+	if got[1][1] != ReasonRepeatedFailures || forced[:2][1] == "changed" {
+		t.Errorf("challenges share the caller's reasons: %v, forced %v", got, forced[:2])
+	}
+}
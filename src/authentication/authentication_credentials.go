@@ -0,0 +1,92 @@
+package authentication
+
+import (
+	"context"
+	"sync"
+
+	"github.com/nerufuyo/roastume/src/validation"
+)
+
+// Credentials are the username, email and password submitted on sign-up
+// or when changing a password
+type Credentials struct {
+	Username string `json:"username"`
+	Email    string `json:"email,omitempty"`
+	Password string `json:"password"`
+}
+
+// credentialChecker checks credentials with the rule set of the configured
+// policy, rebuilt when the policy changes
+type credentialChecker struct {
+	mu     sync.Mutex
+	engine *validation.Manager
+	policy *validation.CredentialPolicy
+	rules  *validation.RuleSet
+}
+
+// SetValidation makes engine check credentials, so they share its catalog,
+// locale handling and masking with payload validation; until then a
+// default engine is used
+func (m *Manager) SetValidation(engine *validation.Manager) {
+	m.credentials.mu.Lock()
+	defer m.credentials.mu.Unlock()
+	m.credentials.engine = engine
+}
+
+// CredentialPolicy returns the configured policy, or the default one
+func (m *Manager) CredentialPolicy() *validation.CredentialPolicy {
+	if policy := m.setup().config.CredentialPolicy; policy != nil {
+		return policy
+	}
+	return validation.DefaultCredentialPolicy()
+}
+
+// CredentialsPack returns the rule pack enforcing this manager's policy,
+// for API validation to install so payloads are checked exactly like
+// ValidateCredentials checks credentials:
+//
+//	engine.InstallPack(auth.CredentialsPack())
+//	engine.ProcessWithProfile(ctx, validation.ProfileCredentials, body)
+func (m *Manager) CredentialsPack() validation.RulePack {
+	return validation.CredentialsPack(m.CredentialPolicy())
+}
+
+// ValidateCredentials checks credentials against the credential policy;
+// failures are validation.ValidationErrors with paths "username", "email"
+// and "password", and password values never appear in messages
+func (m *Manager) ValidateCredentials(ctx context.Context, credentials Credentials) error {
+	policy := m.setup().config.CredentialPolicy
+
+	m.credentials.mu.Lock()
+	if m.credentials.engine == nil {
+		m.credentials.engine = validation.NewManager(validation.DefaultConfig())
+	}
+	if m.credentials.rules == nil || m.credentials.policy != policy {
+		m.credentials.policy = policy
+		m.credentials.rules = validation.CredentialRules(policy)
+	}
+	engine, rules := m.credentials.engine, m.credentials.rules
+	m.credentials.mu.Unlock()
+
+	results := engine.CheckWith(ctx, credentials, rules)
+	for _, v := range results.Warnings {
+		m.logger.Warnf("Credentials: %s", v)
+	}
+	if err := results.Err(); err != nil {
+		m.logger.Debugf("Credentials rejected: %v", err)
+		return err
+	}
+	return nil
+}
+
+// asCredentials extracts credentials from data passed to Validate
+func asCredentials(data interface{}) (*Credentials, bool) {
+	switch v := data.(type) {
+	case *Credentials:
+		return v, v != nil
+	case Credentials:
+		return &v, true
+	default:
+		return nil, false
+	}
+}
@@ -0,0 +1,36 @@
+package authentication
+
+import (
+	"context"
+	"testing"
+
+	"github.com/nerufuyo/roastume/src/validation"
+)
+
+func TestCredentialPolicyFollowsSetConfig(t *testing.T) {
+	m := NewManager(nil)
+	strict := validation.DefaultCredentialPolicy()
+	strict.PasswordMinLength = 20
+	config := DefaultConfig()
+	config.CredentialPolicy = strict
+
+	credentials := Credentials{Username: "alice", Password: "correct horse"}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		m.SetConfig(config)
+	}()
+	m.CredentialPolicy()
+	m.ValidateCredentials(context.Background(), credentials)
+	<-done
+
+	if got := m.CredentialPolicy(); got != strict {
+		t.Errorf("CredentialPolicy = %+v, want the configured one", got)
+	}
+	if err := m.ValidateCredentials(context.Background(), credentials); err == nil {
+		t.Error("a 13 character password passed a 20 character minimum")
+	}
+	if _, err := m.Process(context.Background(), &credentials); err == nil {
+		t.Error("Process accepted credentials failing the policy")
+	}
+}
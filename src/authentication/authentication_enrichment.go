@@ -0,0 +1,242 @@
+package authentication
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Principal is the authenticated identity produced by a successful Process
+type Principal struct {
+	Subject    string                 `json:"subject"`
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
+}
+
+// FailurePolicy decides what happens when an enricher fails
+type FailurePolicy int
+
+const (
+	// FailOpen logs the failure and continues without the attributes
+	FailOpen FailurePolicy = iota
+	// FailClosed rejects the authentication
+	FailClosed
+)
+
+// Enricher looks up additional attributes for an authenticated principal
+type Enricher interface {
+	Name() string
+	Enrich(ctx context.Context, principal *Principal) (map[string]interface{}, error)
+}
+
+// EnricherOptions controls how a single enricher is run
+type EnricherOptions struct {
+	Timeout time.Duration
+	Policy  FailurePolicy
+}
+
+// enricherEntry pairs an enricher with its options
+type enricherEntry struct {
+	enricher Enricher
+	options  EnricherOptions
+}
+
+// AddEnricher appends an enricher to the chain; enrichers run in the order
+// they were added and later attributes override earlier ones
+func (m *Manager) AddEnricher(enricher Enricher, options EnricherOptions) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.enrichers = append(m.enrichers, enricherEntry{enricher: enricher, options: options})
+}
+
+// enrich runs the enricher chain of s against the principal
+func (m *Manager) enrich(ctx context.Context, s *attemptSetup, principal *Principal) error {
+	for _, entry := range s.enrichers {
+		attrs, err := runEnricher(ctx, entry, principal)
+		if err != nil {
+			if entry.options.Policy == FailClosed {
+				return fmt.Errorf("enricher %s: %w", entry.enricher.Name(), err)
+			}
+			m.logger.Printf("Enricher %s failed, continuing: %v", entry.enricher.Name(), err)
+			continue
+		}
+		for k, v := range attrs {
+			principal.Attributes[k] = v
+		}
+	}
+	return nil
+}
+
+// runEnricher applies the per-enricher timeout
+func runEnricher(ctx context.Context, entry enricherEntry, principal *Principal) (map[string]interface{}, error) {
+	if entry.options.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, entry.options.Timeout)
+		defer cancel()
+	}
+	return entry.enricher.Enrich(ctx, principal)
+}
+
+// HTTPEnricher fetches a JSON object of attributes from an HTTP endpoint;
+// "{subject}" in URL is replaced with the escaped principal subject
+type HTTPEnricher struct {
+	EnricherName string
+	URL          string
+	Client       *http.Client
+	Header       http.Header
+}
+
+// Name returns the enricher name
+func (e *HTTPEnricher) Name() string {
+	if e.EnricherName != "" {
+		return e.EnricherName
+	}
+	return "http"
+}
+
+// Enrich performs the HTTP lookup
+func (e *HTTPEnricher) Enrich(ctx context.Context, principal *Principal) (map[string]interface{}, error) {
+	target := strings.ReplaceAll(e.URL, "{subject}", url.PathEscape(principal.Subject))
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, vs := range e.Header {
+		for _, v := range vs {
+			req.Header.Add(k, v)
+		}
+	}
+
+	client := e.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	attrs := make(map[string]interface{})
+	if err := json.NewDecoder(resp.Body).Decode(&attrs); err != nil {
+		return nil, fmt.Errorf("decode response: %w", err)
+	}
+	return attrs, nil
+}
+
+// SQLEnricher reads attributes from the first row of a query; the query
+// receives the principal subject as its only argument
+type SQLEnricher struct {
+	EnricherName string
+	DB           *sql.DB
+	Query        string
+}
+
+// Name returns the enricher name
+func (e *SQLEnricher) Name() string {
+	if e.EnricherName != "" {
+		return e.EnricherName
+	}
+	return "sql"
+}
+
+// Enrich runs the query and maps columns to attributes
+func (e *SQLEnricher) Enrich(ctx context.Context, principal *Principal) (map[string]interface{}, error) {
+	rows, err := e.DB.QueryContext(ctx, e.Query, principal.Subject)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+		return nil, nil
+	}
+
+	values := make([]interface{}, len(columns))
+	ptrs := make([]interface{}, len(columns))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	if err := rows.Scan(ptrs...); err != nil {
+		return nil, err
+	}
+
+	attrs := make(map[string]interface{}, len(columns))
+	for i, col := range columns {
+		if b, ok := values[i].([]byte); ok {
+			attrs[col] = string(b)
+		} else {
+			attrs[col] = values[i]
+		}
+	}
+	return attrs, nil
+}
+
+// DirectorySearcher is the subset of an LDAP client used by LDAPEnricher
+type DirectorySearcher interface {
+	Search(ctx context.Context, filter string, attributes []string) (map[string][]string, error)
+}
+
+// LDAPEnricher reads attributes from a directory; "{subject}" in Filter is
+// replaced with the escaped principal subject
+type LDAPEnricher struct {
+	EnricherName string
+	Searcher     DirectorySearcher
+	Filter       string
+	Attributes   []string
+}
+
+// Name returns the enricher name
+func (e *LDAPEnricher) Name() string {
+	if e.EnricherName != "" {
+		return e.EnricherName
+	}
+	return "ldap"
+}
+
+// Enrich performs the directory search
+func (e *LDAPEnricher) Enrich(ctx context.Context, principal *Principal) (map[string]interface{}, error) {
+	filter := strings.ReplaceAll(e.Filter, "{subject}", escapeLDAPFilter(principal.Subject))
+	entries, err := e.Searcher.Search(ctx, filter, e.Attributes)
+	if err != nil {
+		return nil, err
+	}
+
+	attrs := make(map[string]interface{}, len(entries))
+	for k, vs := range entries {
+		if len(vs) == 1 {
+			attrs[k] = vs[0]
+		} else {
+			attrs[k] = vs
+		}
+	}
+	return attrs, nil
+}
+
+// escapeLDAPFilter escapes special characters per RFC 4515
+func escapeLDAPFilter(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch r {
+		case '\\', '*', '(', ')', 0:
+			fmt.Fprintf(&b, "\\%02x", r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,258 @@
+package authentication
+
+import (
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrMalformedLicense is returned when a license blob cannot be decoded
+	ErrMalformedLicense = errors.New("malformed license")
+	// ErrInvalidSignature is returned when a signature does not verify
+	ErrInvalidSignature = errors.New("invalid signature")
+	// ErrLicenseExpired is returned for licenses past their expiry
+	ErrLicenseExpired = errors.New("license expired")
+	// ErrLicenseRevoked is returned for licenses listed in the revocation list
+	ErrLicenseRevoked = errors.New("license revoked")
+	// ErrMachineMismatch is returned when a license is bound to another machine
+	ErrMachineMismatch = errors.New("license bound to a different machine")
+	// ErrUnknownKey is returned when a key provider has no key for an ID
+	ErrUnknownKey = errors.New("unknown key")
+	// ErrDocumentType is returned when a signed blob is a different kind of
+	// document than expected, e.g. a license presented as a token
+	ErrDocumentType = errors.New("wrong signed document type")
+)
+
+// Kinds of signed documents; each is signed into its payload as "typ" and
+// checked on verification, so one kind is never accepted as another
+const (
+	typLicense        = "license"
+	typToken          = "token"
+	typRevocationList = "revocation-list"
+)
+
+// KeyProvider resolves public keys used to verify signed material
+type KeyProvider interface {
+	PublicKey(keyID string) (ed25519.PublicKey, error)
+}
+
+// StaticKeyProvider is a fixed in-memory set of public keys
+type StaticKeyProvider map[string]ed25519.PublicKey
+
+// PublicKey returns the key registered under keyID
+func (p StaticKeyProvider) PublicKey(keyID string) (ed25519.PublicKey, error) {
+	key, ok := p[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKey, keyID)
+	}
+	return key, nil
+}
+
+// License is a signed offline credential (license key or capability blob)
+type License struct {
+	ID           string    `json:"id"`
+	KeyID        string    `json:"key_id"`
+	Subject      string    `json:"subject"`
+	MachineID    string    `json:"machine_id,omitempty"`
+	Capabilities []string  `json:"capabilities,omitempty"`
+	IssuedAt     time.Time `json:"issued_at"`
+	ExpiresAt    time.Time `json:"expires_at,omitempty"`
+}
+
+// RevocationList is a signed snapshot of revoked license IDs that can be
+// shipped alongside the application
+type RevocationList struct {
+	KeyID    string    `json:"key_id"`
+	IssuedAt time.Time `json:"issued_at"`
+	Revoked  []string  `json:"revoked"`
+}
+
+// Contains reports whether the license ID is revoked
+func (r *RevocationList) Contains(id string) bool {
+	if r == nil {
+		return false
+	}
+	for _, revoked := range r.Revoked {
+		if revoked == id {
+			return true
+		}
+	}
+	return false
+}
+
+// SignLicense encodes and signs a license as "payload.signature"
+func SignLicense(key ed25519.PrivateKey, license *License) (string, error) {
+	return signBlob(key, typLicense, license)
+}
+
+// SignRevocationList encodes and signs a revocation list snapshot
+func SignRevocationList(key ed25519.PrivateKey, list *RevocationList) (string, error) {
+	return signBlob(key, typRevocationList, list)
+}
+
+// signBlob marshals v, tagged as a document of kind typ, and appends an
+// ed25519 signature
+func signBlob(key ed25519.PrivateKey, typ string, v interface{}) (string, error) {
+	fields, err := json.Marshal(v)
+	if err != nil {
+		return "", err
+	}
+	var members map[string]json.RawMessage
+	if err := json.Unmarshal(fields, &members); err != nil {
+		return "", err
+	}
+	members["typ"], _ = json.Marshal(typ)
+	payload, err := json.Marshal(members)
+	if err != nil {
+		return "", err
+	}
+	sig := ed25519.Sign(key, payload)
+	enc := base64.RawURLEncoding
+	return enc.EncodeToString(payload) + "." + enc.EncodeToString(sig), nil
+}
+
+// openBlob decodes a signed blob of kind typ, resolving the key via the
+// embedded key_id
+func openBlob(keys KeyProvider, typ string, blob string, v interface{}) error {
+	parts := strings.Split(strings.TrimSpace(blob), ".")
+	if len(parts) != 2 {
+		return ErrMalformedLicense
+	}
+	enc := base64.RawURLEncoding
+	payload, err := enc.DecodeString(parts[0])
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrMalformedLicense, err)
+	}
+	sig, err := enc.DecodeString(parts[1])
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrMalformedLicense, err)
+	}
+
+	var header struct {
+		KeyID string `json:"key_id"`
+		Typ   string `json:"typ"`
+	}
+	if err := json.Unmarshal(payload, &header); err != nil {
+		return fmt.Errorf("%w: %v", ErrMalformedLicense, err)
+	}
+	pub, err := keys.PublicKey(header.KeyID)
+	if err != nil {
+		return err
+	}
+	if !ed25519.Verify(pub, payload, sig) {
+		return ErrInvalidSignature
+	}
+	if header.Typ != typ {
+		return fmt.Errorf("%w: got %q, want %q", ErrDocumentType, header.Typ, typ)
+	}
+	return json.Unmarshal(payload, v)
+}
+
+// LicenseVerifier verifies offline licenses without network access
+type LicenseVerifier struct {
+	Keys      KeyProvider
+	MachineID string
+	Now       func() time.Time
+
+	mu  sync.RWMutex
+	crl *RevocationList
+}
+
+// NewLicenseVerifier creates a verifier for the given keys and machine
+func NewLicenseVerifier(keys KeyProvider, machineID string) *LicenseVerifier {
+	return &LicenseVerifier{Keys: keys, MachineID: machineID, Now: time.Now}
+}
+
+// ParseRevocationList verifies and decodes a signed revocation list
+func ParseRevocationList(keys KeyProvider, blob string) (*RevocationList, error) {
+	list := &RevocationList{}
+	if err := openBlob(keys, typRevocationList, blob, list); err != nil {
+		return nil, fmt.Errorf("revocation list: %w", err)
+	}
+	return list, nil
+}
+
+// LoadRevocationList verifies and installs an embedded CRL snapshot; older
+// snapshots than the one installed are ignored
+func (v *LicenseVerifier) LoadRevocationList(blob string) error {
+	list, err := ParseRevocationList(v.Keys, blob)
+	if err != nil {
+		return err
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if v.crl == nil || list.IssuedAt.After(v.crl.IssuedAt) {
+		v.crl = list
+	}
+	return nil
+}
+
+// Verify checks signature, expiry, machine binding and revocation
+func (v *LicenseVerifier) Verify(blob string) (*License, error) {
+	license := &License{}
+	if err := openBlob(v.Keys, typLicense, blob, license); err != nil {
+		return nil, err
+	}
+
+	now := time.Now
+	if v.Now != nil {
+		now = v.Now
+	}
+	if !license.ExpiresAt.IsZero() && now().After(license.ExpiresAt) {
+		return nil, ErrLicenseExpired
+	}
+	if license.MachineID != "" && license.MachineID != v.MachineID {
+		return nil, ErrMachineMismatch
+	}
+
+	v.mu.RLock()
+	revoked := v.crl.Contains(license.ID)
+	v.mu.RUnlock()
+	if revoked {
+		return nil, ErrLicenseRevoked
+	}
+	return license, nil
+}
+
+// SetLicenseVerifier installs the verifier used by AuthenticateLicense
+func (m *Manager) SetLicenseVerifier(verifier *LicenseVerifier) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.licenses = verifier
+}
+
+// AuthenticateLicense verifies an offline license and returns its principal
+func (m *Manager) AuthenticateLicense(ctx context.Context, blob string) (*Principal, error) {
+	m.mu.RLock()
+	verifier := m.licenses
+	m.mu.RUnlock()
+	if verifier == nil {
+		return nil, fmt.Errorf("license verification not configured")
+	}
+
+	license, err := verifier.Verify(blob)
+	if err != nil {
+		m.logger.Printf("License verification failed: %v", err)
+		return nil, fmt.Errorf("license verification failed: %w", err)
+	}
+
+	principal := &Principal{
+		Subject: license.Subject,
+		Attributes: map[string]interface{}{
+			"license_id":   license.ID,
+			"capabilities": license.Capabilities,
+		},
+	}
+	if err := m.enrich(ctx, m.setup(), principal); err != nil {
+		return nil, fmt.Errorf("enrichment failed: %w", err)
+	}
+	return principal, nil
+}
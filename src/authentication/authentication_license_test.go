@@ -0,0 +1,54 @@
+package authentication
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSignedDocumentsAreNotInterchangeable(t *testing.T) {
+	pub, key, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := StaticKeyProvider{"k1": pub}
+	now := time.Now().UTC()
+
+	license, err := SignLicense(key, &License{ID: "lic", KeyID: "k1", Subject: "alice", MachineID: "m1", IssuedAt: now, ExpiresAt: now.Add(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, _, err := MintToken(key, "k1", "alice", time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	crl, err := SignRevocationList(key, &RevocationList{KeyID: "k1", IssuedAt: now})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	verifier := NewLicenseVerifier(keys, "m1")
+	if _, err := verifier.Verify(license); err != nil {
+		t.Errorf("Verify(license) = %v", err)
+	}
+	if _, err := VerifyToken(keys, token, nil); err != nil {
+		t.Errorf("VerifyToken(token) = %v", err)
+	}
+	if _, err := ParseRevocationList(keys, crl); err != nil {
+		t.Errorf("ParseRevocationList(crl) = %v", err)
+	}
+
+	for name, check := range map[string]func() error{
+		"license as token": func() error { _, err := VerifyToken(keys, license, nil); return err },
+		"crl as token":     func() error { _, err := VerifyToken(keys, crl, nil); return err },
+		"token as license": func() error { _, err := verifier.Verify(token); return err },
+		"crl as license":   func() error { _, err := verifier.Verify(crl); return err },
+		"license as crl":   func() error { _, err := ParseRevocationList(keys, license); return err },
+		"token as crl":     func() error { _, err := ParseRevocationList(keys, token); return err },
+	} {
+		if err := check(); !errors.Is(err, ErrDocumentType) {
+			t.Errorf("%s: err = %v, want ErrDocumentType", name, err)
+		}
+	}
+}
@@ -0,0 +1,318 @@
+// Package authentication provides professional authentication functionality
+// with comprehensive error handling, logging, and configuration management
+// following Go best practices and idiomatic patterns.
+//
+// Author: Automation System
+// Version: 1.0.0
+// Created: 2025-07-04T14:04:26.600245
+package authentication
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/nerufuyo/roastume/src/logging"
+	"github.com/nerufuyo/roastume/src/manager"
+	"github.com/nerufuyo/roastume/src/validation"
+)
+
+// Status represents the current state of authentication operations
+type Status = manager.Status
+
+const (
+	// StatusPending indicates operation is pending
+	StatusPending = manager.StatusPending
+	// StatusProcessing indicates operation is in progress
+	StatusProcessing = manager.StatusProcessing
+	// StatusCompleted indicates operation completed successfully
+	StatusCompleted = manager.StatusCompleted
+	// StatusFailed indicates operation failed
+	StatusFailed = manager.StatusFailed
+	// StatusCanceled indicates operation was canceled before it completed
+	StatusCanceled = manager.StatusCanceled
+)
+
+// OperationID identifies one Process call, see Result.OperationID
+type OperationID = manager.OperationID
+
+// ErrTimeout is returned by Process when an operation outlasts Config.Timeout
+var ErrTimeout = manager.ErrTimeout
+
+// Config holds configuration settings for authentication operations
+type Config struct {
+	Enabled   bool          `json:"enabled"`
+	Timeout   time.Duration `json:"timeout"`
+	Retries   int           `json:"retries"`
+	RetryMaxElapsed time.Duration `json:"retry_max_elapsed"`
+	MaxConcurrency  int           `json:"max_concurrency"`
+	LogLevel  string        `json:"log_level"`
+	ChallengeFailureThreshold int           `json:"challenge_failure_threshold"`
+	ChallengeWindow           time.Duration `json:"challenge_window"`
+	RiskMFAThreshold          float64       `json:"risk_mfa_threshold"`
+	RiskDenyThreshold         float64       `json:"risk_deny_threshold"`
+	AsyncWorkers              int           `json:"async_workers"`
+	AsyncQueueSize            int           `json:"async_queue_size"`
+	SessionTTL                time.Duration `json:"session_ttl"`
+	// CredentialPolicy governs usernames, emails and passwords; nil uses
+	// validation.DefaultCredentialPolicy
+	CredentialPolicy *validation.CredentialPolicy `json:"credential_policy,omitempty"`
+}
+
+// DefaultConfig returns a default configuration
+func DefaultConfig() *Config {
+	return &Config{
+		Enabled:  true,
+		Timeout:  30 * time.Second,
+		Retries:  3,
+		LogLevel: "",
+		ChallengeFailureThreshold: 3,
+		ChallengeWindow:           15 * time.Minute,
+		RiskMFAThreshold:          0.5,
+		RiskDenyThreshold:         0.9,
+		AsyncWorkers:              4,
+		AsyncQueueSize:            64,
+		SessionTTL:                24 * time.Hour,
+	}
+}
+
+// core returns the settings the manager core enforces
+func (c *Config) core() manager.Config {
+	return manager.Config{
+		Timeout: c.Timeout,
+		Retries: c.Retries,
+		Backoff: manager.Backoff{MaxElapsed: c.RetryMaxElapsed},
+		MaxConcurrency: c.MaxConcurrency,
+	}
+}
+
+// Result represents the result of a authentication operation
+type Result struct {
+	Status        string    `json:"status"`
+	ProcessedAt   time.Time `json:"processed_at"`
+	DataSize      int       `json:"data_size"`
+	ProcessingTime time.Duration `json:"processing_time"`
+	Message       string    `json:"message,omitempty"`
+	OperationID   OperationID `json:"operation_id,omitempty"`
+	Principal     *Principal `json:"principal,omitempty"`
+	Risk          *RiskAssessment `json:"risk,omitempty"`
+	Session       *Session  `json:"session,omitempty"`
+}
+
+// Manager provides professional authentication management functionality
+type Manager struct {
+	*manager.Manager[interface{}, *Result]
+	config    *Config
+	mu        sync.RWMutex
+	logger    *logging.Logger
+	challenge Challenge
+	tracker   *attemptTracker
+	enrichers []enricherEntry
+	licenses  *LicenseVerifier
+	risk      RiskScorer
+	queue     *workQueue
+	queueOnce sync.Once
+	sessions  SessionStore
+	credentials credentialChecker
+}
+
+// ManagerInterface defines the interface for authentication operations
+type ManagerInterface interface {
+	Process(ctx context.Context, data interface{}) (*Result, error)
+	ProcessAsync(ctx context.Context, data interface{}, opts ...AsyncOption) (<-chan *Result, error)
+	Validate(data interface{}) error
+	GetStatus() Status
+	Reset()
+	Close() error
+}
+
+// NewManager creates a new authentication manager instance
+func NewManager(config *Config) *Manager {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	
+	m := &Manager{
+		config:    config,
+		logger:    logging.New("authentication", "[AUTHENTICATION] "),
+		tracker:   newAttemptTracker(),
+		queue:     newWorkQueue(config.AsyncQueueSize),
+	}
+	m.Manager = manager.New(manager.Options{Name: "authentication", Logger: m.logger}, manager.Operation[interface{}, *Result]{
+		Config: func(ctx context.Context) (manager.Config, error) {
+			m.mu.RLock()
+			defer m.mu.RUnlock()
+			return m.config.core(), nil
+		},
+		Validate: func(ctx context.Context, data interface{}) error {
+			return m.Validate(data)
+		},
+		Execute: m.authenticate,
+		Complete: func(id OperationID, result *Result, elapsed time.Duration) {
+			result.OperationID = id
+			result.ProcessingTime = elapsed
+		},
+	})
+	
+	m.setupLogging()
+	return m
+}
+
+// setupLogging configures logging for the manager; an empty LogLevel
+// follows the process-wide level set by the configuration manager
+func (m *Manager) setupLogging() {
+	if err := m.logger.Configure(m.config.LogLevel); err != nil {
+		m.logger.Warnf("Ignoring log level: %v", err)
+	}
+	m.logger.Printf("Initialized authentication manager with configuration")
+}
+
+// attemptSetup is the configuration and hooks one attempt runs with
+type attemptSetup struct {
+	config    Config
+	challenge Challenge
+	risk      RiskScorer
+	enrichers []enricherEntry
+	sessions  SessionStore
+}
+
+// setup copies the configuration and hooks under the read lock, so an
+// attempt sees them consistently without holding the lock while it calls
+// out to scorers, challenges, enrichers and session stores
+func (m *Manager) setup() *attemptSetup {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return &attemptSetup{
+		config:    *m.config,
+		challenge: m.challenge,
+		risk:      m.risk,
+		enrichers: append([]enricherEntry(nil), m.enrichers...),
+		sessions:  m.sessions,
+	}
+}
+
+// authenticate screens, processes and completes one login attempt with
+// the configuration and hooks in place when it started
+func (m *Manager) authenticate(ctx context.Context, data interface{}) (*Result, error) {
+	s := m.setup()
+	
+	// Score risk and challenge suspicious login attempts before completing
+	attempt, _ := asAttempt(data)
+	risk, err := m.screenAttempt(ctx, s, attempt)
+	if err != nil {
+		m.tracker.recordFailure(attempt, s.config.ChallengeWindow)
+		m.observeRisk(s, attempt, false)
+		return nil, err
+	}
+	
+	// Execute processing with context cancellation support
+	result, err := m.executeProcessing(ctx, data)
+	if err != nil {
+		m.tracker.recordFailure(attempt, s.config.ChallengeWindow)
+		m.observeRisk(s, attempt, false)
+		return nil, err
+	}
+	m.tracker.recordSuccess(attempt, s.config.ChallengeWindow)
+	m.observeRisk(s, attempt, true)
+	result.Risk = risk
+	
+	// Augment the authenticated principal with external attributes
+	if attempt != nil {
+		principal := &Principal{Subject: attempt.Subject, Attributes: make(map[string]interface{})}
+		if err := m.enrich(ctx, s, principal); err != nil {
+			return nil, fmt.Errorf("enrichment failed: %w", err)
+		}
+		result.Principal = principal
+		
+		session, err := m.startSession(s, attempt)
+		if err != nil {
+			return nil, fmt.Errorf("session creation failed: %w", err)
+		}
+		result.Session = session
+	}
+	
+	return result, nil
+}
+
+// Validate validates input data according to business rules
+func (m *Manager) Validate(data interface{}) error {
+	if data == nil {
+		m.logger.Warnf("Validation failed: data is nil")
+		return fmt.Errorf("data cannot be nil")
+	}
+	
+	// Check submitted credentials against the shared credential rules
+	if credentials, ok := asCredentials(data); ok {
+		if err := m.ValidateCredentials(context.Background(), *credentials); err != nil {
+			m.logger.Warnf("Validation failed: invalid credentials")
+			return err
+		}
+	}
+	
+	m.logger.Debugf("Data validation passed")
+	return nil
+}
+
+// executeProcessing performs the core processing logic
+func (m *Manager) executeProcessing(ctx context.Context, data interface{}) (*Result, error) {
+	// Simulate processing with context cancellation support
+	select {
+	case <-time.After(100 * time.Millisecond):
+		// Processing completed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	
+	dataStr := fmt.Sprintf("%v", data)
+	
+	result := &Result{
+		Status:      "success",
+		ProcessedAt: time.Now(),
+		DataSize:    len(dataStr),
+		Message:     "Authentication processing completed successfully",
+	}
+	
+	return result, nil
+}
+
+// GetConfig returns a copy of the current configuration; changes to the
+// copy do not affect the manager
+func (m *Manager) GetConfig() *Config {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	config := *m.config
+	return &config
+}
+
+// SetConfig replaces the configuration used by subsequent operations
+func (m *Manager) SetConfig(config *Config) {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	
+	m.config = config
+	if err := m.logger.Configure(config.LogLevel); err != nil {
+		m.logger.Warnf("Ignoring log level: %v", err)
+	}
+	m.logger.Printf("Authentication manager reconfigured")
+}
+
+// Close stops the async workers, then closes the manager
+func (m *Manager) Close() error {
+	m.queue.stop()
+	return m.Manager.Close()
+}
+
+// Factory function to create authentication manager with default configuration
+func CreateAuthenticationManager() *Manager {
+	return NewManager(DefaultConfig())
+}
+
+// Factory function to create authentication manager with custom configuration
+func CreateAuthenticationManagerWithConfig(config *Config) *Manager {
+	return NewManager(config)
+}
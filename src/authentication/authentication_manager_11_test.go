@@ -0,0 +1,78 @@
+package authentication
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// blockingChallenge passes every attempt once released
+type blockingChallenge struct {
+	started, release chan struct{}
+}
+
+func (c blockingChallenge) Verify(ctx context.Context, attempt *Attempt, reasons []ChallengeReason) error {
+	close(c.started)
+	<-c.release
+	return nil
+}
+
+// alwaysRisky requires MFA, and so a challenge, for every attempt
+type alwaysRisky struct{}
+
+func (alwaysRisky) Score(ctx context.Context, attempt *Attempt) (*RiskAssessment, error) {
+	return &RiskAssessment{Score: 0.6}, nil
+}
+
+func TestReconfigureDuringAttempt(t *testing.T) {
+	m := NewManager(nil)
+	challenge := blockingChallenge{started: make(chan struct{}), release: make(chan struct{})}
+	m.SetRiskScorer(alwaysRisky{})
+	m.SetChallenge(challenge)
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := m.Process(context.Background(), &Attempt{Subject: "alice", RemoteIP: "10.0.0.1"})
+		done <- err
+	}()
+	<-challenge.started
+
+	reconfigured := make(chan struct{})
+	go func() {
+		config := DefaultConfig()
+		config.RiskMFAThreshold = 0
+		m.SetConfig(config)
+		m.SetChallenge(nil)
+		close(reconfigured)
+	}()
+	select {
+	case <-reconfigured:
+	case <-time.After(2 * time.Second):
+		close(challenge.release)
+		t.Fatal("reconfiguring blocked on the attempt in flight")
+	}
+
+	close(challenge.release)
+	if err := <-done; err != nil {
+		t.Fatalf("attempt started before reconfiguring = %v", err)
+	}
+	if _, err := m.Process(context.Background(), &Attempt{Subject: "bob"}); err != nil {
+		t.Fatalf("attempt after reconfiguring = %v", err)
+	}
+}
+
+func TestGetConfigReturnsCopy(t *testing.T) {
+	m := NewManager(nil)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		m.SetConfig(DefaultConfig())
+	}()
+	config := m.GetConfig()
+	<-done
+
+	config.LogLevel = "changed"
+	if m.GetConfig().LogLevel == "changed" {
+		t.Error("changing the returned configuration changed the manager")
+	}
+}
@@ -1,3 +1,5 @@
+//go:build ignore
+
 // Package authentication provides professional authentication functionality
 // with comprehensive error handling, logging, and configuration management
 // following Go best practices and idiomatic patterns.
@@ -0,0 +1,22 @@
+package authentication
+
+import (
+	"net/http"
+
+	"github.com/nerufuyo/roastume/src/validation"
+)
+
+// Report authentication failures as problem details with fitting statuses
+func init() {
+	for target, typ := range map[error]validation.ProblemType{
+		ErrTokenExpired:    {Status: http.StatusUnauthorized, Type: "token-expired", Title: "Token expired"},
+		ErrTokenRevoked:    {Status: http.StatusUnauthorized, Type: "token-revoked", Title: "Token revoked"},
+		ErrSessionNotFound: {Status: http.StatusUnauthorized, Type: "session-not-found", Title: "Session not found"},
+		ErrChallengeFailed: {Status: http.StatusForbidden, Type: "challenge-failed", Title: "Challenge failed"},
+		ErrRiskDenied:      {Status: http.StatusForbidden, Type: "risk-denied", Title: "Login denied"},
+		ErrQueueFull:       {Status: http.StatusServiceUnavailable, Type: "queue-full", Title: "Too busy"},
+		ErrManagerClosed:   {Status: http.StatusServiceUnavailable, Type: "closed", Title: "Shutting down"},
+	} {
+		validation.RegisterProblem(target, typ)
+	}
+}
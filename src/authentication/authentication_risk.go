@@ -0,0 +1,300 @@
+package authentication
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrRiskDenied is returned when an attempt's risk score exceeds the denial threshold
+var ErrRiskDenied = errors.New("login denied by risk policy")
+
+// ReasonHighRisk indicates the risk score crossed the MFA threshold
+const ReasonHighRisk ChallengeReason = "high_risk"
+
+// RiskDecision is the action derived from a risk score
+type RiskDecision string
+
+const (
+	// RiskAllow lets the attempt proceed
+	RiskAllow RiskDecision = "allow"
+	// RiskRequireMFA requires the attempt to pass the configured challenge
+	RiskRequireMFA RiskDecision = "require_mfa"
+	// RiskDeny rejects the attempt
+	RiskDeny RiskDecision = "deny"
+)
+
+// RiskAssessment is the outcome of scoring an attempt
+type RiskAssessment struct {
+	Score    float64            `json:"score"`
+	Signals  map[string]float64 `json:"signals,omitempty"`
+	Decision RiskDecision       `json:"decision"`
+}
+
+// RiskScorer scores a login attempt between 0 (benign) and 1 (hostile)
+type RiskScorer interface {
+	Score(ctx context.Context, attempt *Attempt) (*RiskAssessment, error)
+}
+
+// RiskObserver is implemented by scorers and signals that learn from
+// authentication outcomes
+type RiskObserver interface {
+	Observe(attempt *Attempt, succeeded bool)
+}
+
+// RiskSignal contributes a single value between 0 and 1 to a risk score
+type RiskSignal interface {
+	Name() string
+	Evaluate(ctx context.Context, attempt *Attempt) (float64, error)
+}
+
+// WeightedSignal pairs a signal with its weight in the combined score
+type WeightedSignal struct {
+	Signal RiskSignal
+	Weight float64
+}
+
+// WeightedRiskScorer combines signals into a weighted average
+type WeightedRiskScorer struct {
+	Signals []WeightedSignal
+}
+
+// NewWeightedRiskScorer creates a scorer over the given signals
+func NewWeightedRiskScorer(signals ...WeightedSignal) *WeightedRiskScorer {
+	return &WeightedRiskScorer{Signals: signals}
+}
+
+// Score evaluates every signal; a failing signal is treated as maximally risky
+func (s *WeightedRiskScorer) Score(ctx context.Context, attempt *Attempt) (*RiskAssessment, error) {
+	assessment := &RiskAssessment{Signals: make(map[string]float64, len(s.Signals))}
+	var total, weights float64
+	for _, ws := range s.Signals {
+		value, err := ws.Signal.Evaluate(ctx, attempt)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			value = 1
+		}
+		value = clamp01(value)
+		assessment.Signals[ws.Signal.Name()] = value
+		total += value * ws.Weight
+		weights += ws.Weight
+	}
+	if weights > 0 {
+		assessment.Score = total / weights
+	}
+	return assessment, nil
+}
+
+// Observe forwards outcomes to signals that learn from them
+func (s *WeightedRiskScorer) Observe(attempt *Attempt, succeeded bool) {
+	for _, ws := range s.Signals {
+		if o, ok := ws.Signal.(RiskObserver); ok {
+			o.Observe(attempt, succeeded)
+		}
+	}
+}
+
+// clamp01 limits v to the range [0, 1]
+func clamp01(v float64) float64 {
+	if v < 0 {
+		return 0
+	}
+	if v > 1 {
+		return 1
+	}
+	return v
+}
+
+// VelocitySignal scores how many attempts a subject made within Window,
+// reaching 1 at Limit attempts; subjects without attempts in the window are
+// forgotten. The zero value is usable once Window and Limit are set
+type VelocitySignal struct {
+	Window time.Duration
+	Limit  int
+
+	mu       sync.Mutex
+	now      func() time.Time
+	attempts map[string][]time.Time
+	records  int
+}
+
+// NewVelocitySignal creates a velocity signal
+func NewVelocitySignal(window time.Duration, limit int) *VelocitySignal {
+	return &VelocitySignal{Window: window, Limit: limit, attempts: make(map[string][]time.Time)}
+}
+
+// Name returns the signal name
+func (s *VelocitySignal) Name() string { return "velocity" }
+
+// Evaluate records the attempt and scores the recent rate
+func (s *VelocitySignal) Evaluate(ctx context.Context, attempt *Attempt) (float64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.attempts == nil {
+		s.attempts = make(map[string][]time.Time)
+	}
+
+	now := time.Now()
+	if s.now != nil {
+		now = s.now()
+	}
+	cutoff := now.Add(-s.Window)
+	kept := append(s.recent(attempt.Subject, cutoff), now)
+	s.attempts[attempt.Subject] = kept
+	if s.records++; s.records%trackerSweepEvery == 0 {
+		for subject := range s.attempts {
+			s.recent(subject, cutoff)
+		}
+	}
+
+	if s.Limit <= 0 {
+		return 0, nil
+	}
+	return float64(len(kept)) / float64(s.Limit), nil
+}
+
+// recent drops the attempts of subject made before cutoff, forgetting the
+// subject when none are left, and returns the rest; s.mu must be held
+func (s *VelocitySignal) recent(subject string, cutoff time.Time) []time.Time {
+	kept := s.attempts[subject][:0]
+	for _, at := range s.attempts[subject] {
+		if at.After(cutoff) {
+			kept = append(kept, at)
+		}
+	}
+	if len(kept) == 0 {
+		delete(s.attempts, subject)
+		return nil
+	}
+	s.attempts[subject] = kept
+	return kept
+}
+
+// IPReputation looks up a reputation score (0 good, 1 bad) for an address
+type IPReputation interface {
+	Reputation(ctx context.Context, ip string) (float64, error)
+}
+
+// IPReputationSignal scores the attempt's remote address via a reputation source
+type IPReputationSignal struct {
+	Source IPReputation
+}
+
+// Name returns the signal name
+func (s *IPReputationSignal) Name() string { return "ip_reputation" }
+
+// Evaluate queries the reputation source
+func (s *IPReputationSignal) Evaluate(ctx context.Context, attempt *Attempt) (float64, error) {
+	if attempt.RemoteIP == "" {
+		return 0, nil
+	}
+	return s.Source.Reputation(ctx, attempt.RemoteIP)
+}
+
+// DeviceNoveltySignal scores 1 for devices never seen succeeding for the
+// subject; the device is read from Attempt.Metadata[MetadataKey]
+type DeviceNoveltySignal struct {
+	MetadataKey string
+
+	mu    sync.Mutex
+	known map[string]map[string]struct{}
+}
+
+// NewDeviceNoveltySignal creates a device novelty signal keyed by metadataKey
+func NewDeviceNoveltySignal(metadataKey string) *DeviceNoveltySignal {
+	return &DeviceNoveltySignal{MetadataKey: metadataKey, known: make(map[string]map[string]struct{})}
+}
+
+// Name returns the signal name
+func (s *DeviceNoveltySignal) Name() string { return "device_novelty" }
+
+// Evaluate reports whether the device is new for the subject
+func (s *DeviceNoveltySignal) Evaluate(ctx context.Context, attempt *Attempt) (float64, error) {
+	device := attempt.Metadata[s.MetadataKey]
+	if device == "" {
+		return 1, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.known[attempt.Subject][device]; ok {
+		return 0, nil
+	}
+	return 1, nil
+}
+
+// Observe remembers devices from successful logins
+func (s *DeviceNoveltySignal) Observe(attempt *Attempt, succeeded bool) {
+	device := attempt.Metadata[s.MetadataKey]
+	if !succeeded || device == "" {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.known[attempt.Subject] == nil {
+		s.known[attempt.Subject] = make(map[string]struct{})
+	}
+	s.known[attempt.Subject][device] = struct{}{}
+}
+
+// SetRiskScorer installs the scorer consulted for every login attempt
+func (m *Manager) SetRiskScorer(scorer RiskScorer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.risk = scorer
+}
+
+// assessRisk scores the attempt and applies the configured thresholds
+func (m *Manager) assessRisk(ctx context.Context, s *attemptSetup, attempt *Attempt) (*RiskAssessment, error) {
+	if attempt == nil || s.risk == nil {
+		return nil, nil
+	}
+
+	assessment, err := s.risk.Score(ctx, attempt)
+	if err != nil {
+		return nil, fmt.Errorf("risk scoring: %w", err)
+	}
+
+	switch {
+	case s.config.RiskDenyThreshold > 0 && assessment.Score >= s.config.RiskDenyThreshold:
+		assessment.Decision = RiskDeny
+	case s.config.RiskMFAThreshold > 0 && assessment.Score >= s.config.RiskMFAThreshold:
+		assessment.Decision = RiskRequireMFA
+	default:
+		assessment.Decision = RiskAllow
+	}
+	m.logger.Printf("Risk score for %q: %.2f (%s)", attempt.Subject, assessment.Score, assessment.Decision)
+	return assessment, nil
+}
+
+// screenAttempt applies the risk policy and any required challenge
+func (m *Manager) screenAttempt(ctx context.Context, s *attemptSetup, attempt *Attempt) (*RiskAssessment, error) {
+	assessment, err := m.assessRisk(ctx, s, attempt)
+	if err != nil {
+		return nil, err
+	}
+
+	var forced []ChallengeReason
+	if assessment != nil {
+		switch assessment.Decision {
+		case RiskDeny:
+			return assessment, ErrRiskDenied
+		case RiskRequireMFA:
+			forced = append(forced, ReasonHighRisk)
+		}
+	}
+	return assessment, m.runChallenge(ctx, s, attempt, forced...)
+}
+
+// observeRisk feeds the outcome back to a learning scorer
+func (m *Manager) observeRisk(s *attemptSetup, attempt *Attempt, succeeded bool) {
+	if attempt == nil {
+		return
+	}
+	if o, ok := s.risk.(RiskObserver); ok {
+		o.Observe(attempt, succeeded)
+	}
+}
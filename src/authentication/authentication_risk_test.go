@@ -0,0 +1,35 @@
+package authentication
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestVelocitySignal(t *testing.T) {
+	now := time.Now()
+	signal := &VelocitySignal{Window: time.Minute, Limit: 4, now: func() time.Time { return now }}
+	var score float64
+	for i := 0; i < 2; i++ {
+		var err error
+		if score, err = signal.Evaluate(context.Background(), &Attempt{Subject: "alice"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if score != 0.5 {
+		t.Errorf("score after 2 of 4 attempts = %v", score)
+	}
+
+	// Subjects seen once are forgotten after the window
+	for i := 0; i < trackerSweepEvery; i++ {
+		signal.Evaluate(context.Background(), &Attempt{Subject: fmt.Sprintf("user%d", i)})
+	}
+	now = now.Add(2 * time.Minute)
+	for i := 0; i < trackerSweepEvery; i++ {
+		score, _ = signal.Evaluate(context.Background(), &Attempt{Subject: "mallory"})
+	}
+	if len(signal.attempts) != 1 || score != float64(trackerSweepEvery)/4 {
+		t.Errorf("tracking %d subjects with score %v, want only mallory", len(signal.attempts), score)
+	}
+}
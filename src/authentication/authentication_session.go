@@ -0,0 +1,197 @@
+package authentication
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrSessionNotFound is returned when a session ID is unknown
+var ErrSessionNotFound = errors.New("session not found")
+
+// Session is an authenticated session for a principal
+type Session struct {
+	ID        string    `json:"id"`
+	Subject   string    `json:"subject"`
+	RemoteIP  string    `json:"remote_ip,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// SessionStore persists sessions created by the Manager
+type SessionStore interface {
+	Create(subject, remoteIP string, ttl time.Duration) (*Session, error)
+	List() ([]*Session, error)
+	Revoke(id string) error
+}
+
+// MemorySessionStore keeps sessions in memory
+type MemorySessionStore struct {
+	mu       sync.RWMutex
+	sessions map[string]*Session
+}
+
+// NewMemorySessionStore creates an empty in-memory store
+func NewMemorySessionStore() *MemorySessionStore {
+	return &MemorySessionStore{sessions: make(map[string]*Session)}
+}
+
+// Create starts a new session
+func (s *MemorySessionStore) Create(subject, remoteIP string, ttl time.Duration) (*Session, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	now := time.Now().UTC()
+	session := &Session{
+		ID:        hex.EncodeToString(id),
+		Subject:   subject,
+		RemoteIP:  remoteIP,
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sessions[session.ID] = session
+	return session, nil
+}
+
+// List returns unexpired sessions ordered by creation time
+func (s *MemorySessionStore) List() ([]*Session, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now()
+	sessions := make([]*Session, 0, len(s.sessions))
+	for _, session := range s.sessions {
+		if now.Before(session.ExpiresAt) {
+			sessions = append(sessions, session)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].CreatedAt.Before(sessions[j].CreatedAt)
+	})
+	return sessions, nil
+}
+
+// Revoke removes a session
+func (s *MemorySessionStore) Revoke(id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.sessions[id]; !ok {
+		return ErrSessionNotFound
+	}
+	delete(s.sessions, id)
+	return nil
+}
+
+// FileSessionStore is a MemorySessionStore persisted to a JSON file after
+// every change, so sessions can be inspected from separate processes
+type FileSessionStore struct {
+	*MemorySessionStore
+	path string
+	// saveMu serialises saves, so the file always ends up holding the
+	// sessions as of the latest change
+	saveMu sync.Mutex
+}
+
+// OpenFileSessionStore loads sessions from path, creating it if missing
+func OpenFileSessionStore(path string) (*FileSessionStore, error) {
+	store := &FileSessionStore{MemorySessionStore: NewMemorySessionStore(), path: path}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var sessions []*Session
+	if err := json.Unmarshal(data, &sessions); err != nil {
+		return nil, err
+	}
+	for _, session := range sessions {
+		store.sessions[session.ID] = session
+	}
+	return store, nil
+}
+
+// Create starts a new session and persists the store
+func (s *FileSessionStore) Create(subject, remoteIP string, ttl time.Duration) (*Session, error) {
+	session, err := s.MemorySessionStore.Create(subject, remoteIP, ttl)
+	if err != nil {
+		return nil, err
+	}
+	return session, s.save()
+}
+
+// Revoke removes a session and persists the store
+func (s *FileSessionStore) Revoke(id string) error {
+	if err := s.MemorySessionStore.Revoke(id); err != nil {
+		return err
+	}
+	return s.save()
+}
+
+// save writes the current sessions to disk, replacing the file in one
+// step so readers never see it half written
+func (s *FileSessionStore) save() error {
+	s.saveMu.Lock()
+	defer s.saveMu.Unlock()
+	sessions, err := s.List()
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(sessions, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(s.path, data, 0o600)
+}
+
+// writeFileAtomic replaces path via a temporary file and rename
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// SetSessionStore installs the store in which successful logins create sessions
+func (m *Manager) SetSessionStore(store SessionStore) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sessions = store
+}
+
+// startSession records a session for a successful login attempt
+func (m *Manager) startSession(s *attemptSetup, attempt *Attempt) (*Session, error) {
+	if s.sessions == nil || attempt == nil {
+		return nil, nil
+	}
+	return s.sessions.Create(attempt.Subject, attempt.RemoteIP, s.config.SessionTTL)
+}
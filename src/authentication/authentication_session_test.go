@@ -0,0 +1,83 @@
+package authentication
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestFileSessionStoreConcurrentChanges(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "sessions.json")
+	store, err := OpenFileSessionStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Readers in other processes must never see a partly written file
+	done := make(chan struct{})
+	torn := make(chan []byte, 1)
+	go func() {
+		defer close(torn)
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			data, err := os.ReadFile(path)
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			if err != nil || !json.Valid(data) {
+				torn <- data
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 5; j++ {
+				session, err := store.Create("alice", "10.0.0.1", time.Hour)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if j%2 == 1 {
+					if err := store.Revoke(session.ID); err != nil {
+						t.Error(err)
+					}
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(done)
+	if data, ok := <-torn; ok {
+		t.Errorf("read a partly written store file: %q", data)
+	}
+
+	reopened, err := OpenFileSessionStore(path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	want, _ := store.List()
+	got, _ := reopened.List()
+	if len(got) != len(want) || len(want) != 60 {
+		t.Errorf("file holds %d sessions, store %d; want 60", len(got), len(want))
+	}
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Errorf("directory holds %d files, want only the store", len(entries))
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("store file mode = %v, %v; want 0600", info.Mode(), err)
+	}
+}
@@ -0,0 +1,61 @@
+package authentication
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+var (
+	// ErrTokenExpired is returned for tokens past their expiry
+	ErrTokenExpired = errors.New("token expired")
+	// ErrTokenRevoked is returned for tokens listed in a revocation list
+	ErrTokenRevoked = errors.New("token revoked")
+)
+
+// TokenClaims is the signed payload of an access token
+type TokenClaims struct {
+	ID        string    `json:"id"`
+	KeyID     string    `json:"key_id"`
+	Subject   string    `json:"subject"`
+	IssuedAt  time.Time `json:"issued_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// MintToken issues a signed token for subject valid for ttl
+func MintToken(key ed25519.PrivateKey, keyID, subject string, ttl time.Duration) (string, *TokenClaims, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", nil, err
+	}
+	now := time.Now().UTC()
+	claims := &TokenClaims{
+		ID:        hex.EncodeToString(id),
+		KeyID:     keyID,
+		Subject:   subject,
+		IssuedAt:  now,
+		ExpiresAt: now.Add(ttl),
+	}
+	token, err := signBlob(key, typToken, claims)
+	if err != nil {
+		return "", nil, err
+	}
+	return token, claims, nil
+}
+
+// VerifyToken checks a token's signature, expiry and optional revocation list
+func VerifyToken(keys KeyProvider, token string, revoked *RevocationList) (*TokenClaims, error) {
+	claims := &TokenClaims{}
+	if err := openBlob(keys, typToken, token, claims); err != nil {
+		return nil, err
+	}
+	if time.Now().After(claims.ExpiresAt) {
+		return nil, ErrTokenExpired
+	}
+	if revoked.Contains(claims.ID) {
+		return nil, ErrTokenRevoked
+	}
+	return claims, nil
+}
@@ -0,0 +1,381 @@
+// Command authctl exercises the authentication Manager from the command
+// line: hashing passwords, minting and verifying tokens, managing sessions
+// and revocations, and running login smoke tests.
+package main
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/nerufuyo/roastume/src/authentication"
+	"github.com/nerufuyo/roastume/src/configuration"
+)
+
+const usage = `usage: authctl <command> [flags]
+
+commands:
+  keygen    generate an ed25519 signing key pair
+  hash      hash a password
+  verify    check a password against a hash
+  mint      mint a signed token
+  inspect   verify a signed token
+  sessions  list sessions in a session file
+  logout    revoke a session
+  revoke    add IDs to a signed revocation list
+  login     run a login smoke test against a configured manager
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// run dispatches a subcommand and returns the process exit code
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+
+	commands := map[string]func([]string, io.Reader, io.Writer) error{
+		"keygen":   cmdKeygen,
+		"hash":     cmdHash,
+		"verify":   cmdVerify,
+		"mint":     cmdMint,
+		"inspect":  cmdInspect,
+		"sessions": cmdSessions,
+		"logout":   cmdLogout,
+		"revoke":   cmdRevoke,
+		"login":    cmdLogin,
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "authctl: unknown command %q\n\n%s", args[0], usage)
+		return 2
+	}
+	if err := cmd(args[1:], stdin, stdout); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 2
+		}
+		fmt.Fprintf(stderr, "authctl %s: %v\n", args[0], err)
+		return 1
+	}
+	return 0
+}
+
+// readSecret returns value or, when empty, the first line of stdin
+func readSecret(value string, stdin io.Reader) (string, error) {
+	if value != "" {
+		return value, nil
+	}
+	data, err := io.ReadAll(stdin)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// loadPrivateKey reads a base64 encoded ed25519 private key
+func loadPrivateKey(path string) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(key) != ed25519.PrivateKeySize {
+		return nil, fmt.Errorf("%s: not an ed25519 private key", path)
+	}
+	return ed25519.PrivateKey(key), nil
+}
+
+// loadKeyProvider reads a base64 encoded ed25519 public key for keyID
+func loadKeyProvider(path, keyID string) (authentication.KeyProvider, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("%s: not an ed25519 public key", path)
+	}
+	return authentication.StaticKeyProvider{keyID: ed25519.PublicKey(key)}, nil
+}
+
+// writeJSON pretty-prints v
+func writeJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+func cmdKeygen(args []string, _ io.Reader, stdout io.Writer) error {
+	fs := flag.NewFlagSet("keygen", flag.ContinueOnError)
+	out := fs.String("out", "authctl", "file prefix for <prefix>.key and <prefix>.pub")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(*out+".key", []byte(base64.StdEncoding.EncodeToString(priv)+"\n"), 0o600); err != nil {
+		return err
+	}
+	if err := os.WriteFile(*out+".pub", []byte(base64.StdEncoding.EncodeToString(pub)+"\n"), 0o644); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "wrote %s.key and %s.pub\n", *out, *out)
+	return nil
+}
+
+func cmdHash(args []string, stdin io.Reader, stdout io.Writer) error {
+	fs := flag.NewFlagSet("hash", flag.ContinueOnError)
+	password := fs.String("password", "", "password to hash (read from stdin when empty)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	secret, err := readSecret(*password, stdin)
+	if err != nil {
+		return err
+	}
+	hash, err := authentication.HashPassword(secret)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(stdout, hash)
+	return nil
+}
+
+func cmdVerify(args []string, stdin io.Reader, stdout io.Writer) error {
+	fs := flag.NewFlagSet("verify", flag.ContinueOnError)
+	hash := fs.String("hash", "", "encoded password hash")
+	password := fs.String("password", "", "password to check (read from stdin when empty)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	secret, err := readSecret(*password, stdin)
+	if err != nil {
+		return err
+	}
+	ok, err := authentication.VerifyPassword(*hash, secret)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("password does not match")
+	}
+	fmt.Fprintln(stdout, "password matches")
+	return nil
+}
+
+func cmdMint(args []string, _ io.Reader, stdout io.Writer) error {
+	fs := flag.NewFlagSet("mint", flag.ContinueOnError)
+	keyPath := fs.String("key", "authctl.key", "private key file")
+	keyID := fs.String("key-id", "default", "key identifier embedded in the token")
+	subject := fs.String("subject", "", "token subject")
+	ttl := fs.Duration("ttl", time.Hour, "token lifetime")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *subject == "" {
+		return errors.New("-subject is required")
+	}
+
+	key, err := loadPrivateKey(*keyPath)
+	if err != nil {
+		return err
+	}
+	token, _, err := authentication.MintToken(key, *keyID, *subject, *ttl)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(stdout, token)
+	return nil
+}
+
+func cmdInspect(args []string, stdin io.Reader, stdout io.Writer) error {
+	fs := flag.NewFlagSet("inspect", flag.ContinueOnError)
+	pubPath := fs.String("pub", "authctl.pub", "public key file")
+	keyID := fs.String("key-id", "default", "key identifier of the public key")
+	crlPath := fs.String("crl", "", "optional signed revocation list file")
+	token := fs.String("token", "", "token to verify (read from stdin when empty)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	keys, err := loadKeyProvider(*pubPath, *keyID)
+	if err != nil {
+		return err
+	}
+	raw, err := readSecret(*token, stdin)
+	if err != nil {
+		return err
+	}
+
+	var crl *authentication.RevocationList
+	if *crlPath != "" {
+		data, err := os.ReadFile(*crlPath)
+		if err != nil {
+			return err
+		}
+		if crl, err = authentication.ParseRevocationList(keys, string(data)); err != nil {
+			return err
+		}
+	}
+
+	claims, err := authentication.VerifyToken(keys, raw, crl)
+	if err != nil {
+		return err
+	}
+	return writeJSON(stdout, claims)
+}
+
+func cmdSessions(args []string, _ io.Reader, stdout io.Writer) error {
+	fs := flag.NewFlagSet("sessions", flag.ContinueOnError)
+	path := fs.String("file", "sessions.json", "session store file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	store, err := authentication.OpenFileSessionStore(*path)
+	if err != nil {
+		return err
+	}
+	sessions, err := store.List()
+	if err != nil {
+		return err
+	}
+	return writeJSON(stdout, sessions)
+}
+
+func cmdLogout(args []string, _ io.Reader, stdout io.Writer) error {
+	fs := flag.NewFlagSet("logout", flag.ContinueOnError)
+	path := fs.String("file", "sessions.json", "session store file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return errors.New("at least one session ID is required")
+	}
+
+	store, err := authentication.OpenFileSessionStore(*path)
+	if err != nil {
+		return err
+	}
+	for _, id := range fs.Args() {
+		if err := store.Revoke(id); err != nil {
+			return fmt.Errorf("%s: %w", id, err)
+		}
+		fmt.Fprintf(stdout, "revoked session %s\n", id)
+	}
+	return nil
+}
+
+func cmdRevoke(args []string, _ io.Reader, stdout io.Writer) error {
+	fs := flag.NewFlagSet("revoke", flag.ContinueOnError)
+	keyPath := fs.String("key", "authctl.key", "private key file")
+	keyID := fs.String("key-id", "default", "key identifier embedded in the list")
+	crlPath := fs.String("crl", "revoked.crl", "signed revocation list file to update")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return errors.New("at least one license or token ID is required")
+	}
+
+	key, err := loadPrivateKey(*keyPath)
+	if err != nil {
+		return err
+	}
+	keys := authentication.StaticKeyProvider{*keyID: key.Public().(ed25519.PublicKey)}
+
+	list := &authentication.RevocationList{}
+	if data, err := os.ReadFile(*crlPath); err == nil {
+		if list, err = authentication.ParseRevocationList(keys, string(data)); err != nil {
+			return err
+		}
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	for _, id := range fs.Args() {
+		if !list.Contains(id) {
+			list.Revoked = append(list.Revoked, id)
+		}
+	}
+	list.KeyID = *keyID
+	list.IssuedAt = time.Now().UTC()
+
+	blob, err := authentication.SignRevocationList(key, list)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(*crlPath, []byte(blob+"\n"), 0o644); err != nil {
+		return err
+	}
+	fmt.Fprintf(stdout, "%s now revokes %d IDs\n", *crlPath, len(list.Revoked))
+	return nil
+}
+
+func cmdLogin(args []string, _ io.Reader, stdout io.Writer) error {
+	fs := flag.NewFlagSet("login", flag.ContinueOnError)
+	configPath := fs.String("config", "", "manager configuration file; durations may be written as \"30s\" (defaults when empty)")
+	subject := fs.String("subject", "", "login subject")
+	remoteIP := fs.String("ip", "127.0.0.1", "remote address of the attempt")
+	enrichURL := fs.String("enrich-url", "", "optional HTTP attribute backend; {subject} is substituted")
+	sessionFile := fs.String("sessions", "", "optional session store file")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *subject == "" {
+		return errors.New("-subject is required")
+	}
+
+	config := authentication.DefaultConfig()
+	if *configPath != "" {
+		if err := configuration.Load(*configPath, config); err != nil {
+			return err
+		}
+	}
+
+	manager := authentication.CreateAuthenticationManagerWithConfig(config)
+	defer manager.Close()
+	if *enrichURL != "" {
+		manager.AddEnricher(&authentication.HTTPEnricher{URL: *enrichURL}, authentication.EnricherOptions{
+			Timeout: config.Timeout,
+			Policy:  authentication.FailClosed,
+		})
+	}
+	if *sessionFile != "" {
+		store, err := authentication.OpenFileSessionStore(*sessionFile)
+		if err != nil {
+			return err
+		}
+		manager.SetSessionStore(store)
+	}
+
+	// A zero timeout means no deadline rather than one that has already passed
+	ctx := context.Background()
+	if config.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, config.Timeout)
+		defer cancel()
+	}
+	result, err := manager.Process(ctx, &authentication.Attempt{Subject: *subject, RemoteIP: *remoteIP})
+	if err != nil {
+		return err
+	}
+	return writeJSON(stdout, result)
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/nerufuyo/roastume/src/authentication"
+)
+
+func TestRunUsage(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		code int
+		want string
+	}{
+		{"no command", nil, 2, "usage: authctl"},
+		{"unknown command", []string{"frobnicate"}, 2, `unknown command "frobnicate"`},
+		{"help flag", []string{"hash", "-h"}, 2, ""},
+		{"bad flag", []string{"mint", "-bogus"}, 1, "flag provided but not defined"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if code := run(tt.args, strings.NewReader(""), &stdout, &stderr); code != tt.code {
+				t.Fatalf("exit code = %d, want %d (stderr %q)", code, tt.code, stderr.String())
+			}
+			if !strings.Contains(stderr.String(), tt.want) {
+				t.Errorf("stderr = %q, want it to contain %q", stderr.String(), tt.want)
+			}
+		})
+	}
+}
+
+// TestRunCommands runs each subcommand against a shared temp dir; a step
+// with save set records its trimmed stdout, which later steps reference in
+// their arguments or stdin as {name}
+func TestRunCommands(t *testing.T) {
+	dir := t.TempDir()
+	path := func(name string) string { return filepath.Join(dir, name) }
+	writeFile(t, path("human.json"), `{"timeout": "30s", "session_ttl": "1h"}`)
+	writeFile(t, path("zero.json"), `{"timeout": 0}`)
+	writeFile(t, path("bad.json"), `{"timeout": "soon"}`)
+
+	tests := []struct {
+		name  string
+		args  []string
+		stdin string
+		code  int
+		want  string
+		save  string
+	}{
+		{name: "keygen", args: []string{"keygen", "-out", path("k")}, want: "wrote " + path("k") + ".key"},
+		{name: "keygen second key", args: []string{"keygen", "-out", path("other")}, want: "wrote"},
+
+		{name: "hash flag", args: []string{"hash", "-password", "s3cret"}, want: "$", save: "hash"},
+		{name: "hash stdin", args: []string{"hash"}, stdin: "s3cret\n", want: "$"},
+		{name: "verify match", args: []string{"verify", "-hash", "{hash}", "-password", "s3cret"}, want: "password matches"},
+		{name: "verify stdin", args: []string{"verify", "-hash", "{hash}"}, stdin: "s3cret\n", want: "password matches"},
+		{name: "verify mismatch", args: []string{"verify", "-hash", "{hash}", "-password", "wrong"}, code: 1, want: "password does not match"},
+
+		{name: "mint", args: []string{"mint", "-key", path("k.key"), "-subject", "alice"}, save: "token"},
+		{name: "mint without subject", args: []string{"mint", "-key", path("k.key")}, code: 1, want: "-subject is required"},
+		{name: "mint missing key", args: []string{"mint", "-key", path("none.key"), "-subject", "alice"}, code: 1, want: "no such file"},
+		{name: "mint with public key", args: []string{"mint", "-key", path("k.pub"), "-subject", "alice"}, code: 1, want: "not an ed25519 private key"},
+		{name: "inspect flag", args: []string{"inspect", "-pub", path("k.pub"), "-token", "{token}"}, want: `"subject": "alice"`},
+		{name: "inspect stdin", args: []string{"inspect", "-pub", path("k.pub")}, stdin: "{token}\n", want: `"subject": "alice"`},
+		{name: "inspect wrong key", args: []string{"inspect", "-pub", path("other.pub"), "-token", "{token}"}, code: 1},
+		{name: "inspect wrong key id", args: []string{"inspect", "-pub", path("k.pub"), "-key-id", "other", "-token", "{token}"}, code: 1},
+
+		{name: "revoke", args: []string{"revoke", "-key", path("k.key"), "-crl", path("revoked.crl"), "a"}, want: "now revokes 1 IDs"},
+		{name: "revoke appends", args: []string{"revoke", "-key", path("k.key"), "-crl", path("revoked.crl"), "a", "b"}, want: "now revokes 2 IDs"},
+		{name: "revoke without IDs", args: []string{"revoke", "-key", path("k.key"), "-crl", path("revoked.crl")}, code: 1, want: "at least one"},
+		{name: "revoke with other key", args: []string{"revoke", "-key", path("other.key"), "-crl", path("revoked.crl"), "c"}, code: 1},
+		{name: "inspect with list", args: []string{"inspect", "-pub", path("k.pub"), "-crl", path("revoked.crl"), "-token", "{token}"}, want: `"subject": "alice"`},
+
+		{name: "login defaults", args: []string{"login", "-subject", "alice"}, want: `"status": "success"`},
+		{name: "login duration strings", args: []string{"login", "-config", path("human.json"), "-subject", "alice", "-sessions", path("sessions.json")}, want: `"session"`},
+		{name: "login zero timeout", args: []string{"login", "-config", path("zero.json"), "-subject", "bob"}, want: `"status": "success"`},
+		{name: "login bad duration", args: []string{"login", "-config", path("bad.json"), "-subject", "alice"}, code: 1, want: "timeout"},
+		{name: "login missing config", args: []string{"login", "-config", path("none.json"), "-subject", "alice"}, code: 1, want: "no such file"},
+		{name: "login without subject", args: []string{"login"}, code: 1, want: "-subject is required"},
+
+		{name: "sessions", args: []string{"sessions", "-file", path("sessions.json")}, want: `"subject": "alice"`},
+		{name: "logout unknown session", args: []string{"logout", "-file", path("sessions.json"), "nope"}, code: 1, want: "nope"},
+		{name: "logout without IDs", args: []string{"logout", "-file", path("sessions.json")}, code: 1, want: "at least one"},
+	}
+
+	saved := map[string]string{}
+	expand := func(s string) string {
+		for name, value := range saved {
+			s = strings.ReplaceAll(s, "{"+name+"}", value)
+		}
+		return s
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := make([]string, len(tt.args))
+			for i, arg := range tt.args {
+				args[i] = expand(arg)
+			}
+			var stdout, stderr bytes.Buffer
+			code := run(args, strings.NewReader(expand(tt.stdin)), &stdout, &stderr)
+			if code != tt.code {
+				t.Fatalf("exit code = %d, want %d (stderr %q)", code, tt.code, stderr.String())
+			}
+			output := stdout.String() + stderr.String()
+			if !strings.Contains(output, tt.want) {
+				t.Errorf("output = %q, want it to contain %q", output, tt.want)
+			}
+			if tt.save != "" {
+				saved[tt.save] = strings.TrimSpace(stdout.String())
+			}
+		})
+	}
+}
+
+func TestRunLogout(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "sessions.json")
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"login", "-subject", "alice", "-sessions", file}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("login exit code = %d (stderr %q)", code, stderr.String())
+	}
+	store, err := authentication.OpenFileSessionStore(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessions, err := store.List()
+	if err != nil || len(sessions) != 1 {
+		t.Fatalf("sessions = %v, %v; want one", sessions, err)
+	}
+
+	stdout.Reset()
+	if code := run([]string{"logout", "-file", file, sessions[0].ID}, nil, &stdout, &stderr); code != 0 {
+		t.Fatalf("logout exit code = %d (stderr %q)", code, stderr.String())
+	}
+	if want := "revoked session " + sessions[0].ID; !strings.Contains(stdout.String(), want) {
+		t.Errorf("stdout = %q, want %q", stdout.String(), want)
+	}
+	if code := run([]string{"logout", "-file", file, sessions[0].ID}, nil, &stdout, &stderr); code != 1 {
+		t.Errorf("second logout exit code = %d, want 1", code)
+	}
+}
+
+func writeFile(t *testing.T, path, data string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
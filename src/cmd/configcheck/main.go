@@ -0,0 +1,128 @@
+// Command configcheck dry-runs a configuration file: it loads and validates
+// it without applying it, prints the merged effective configuration with the
+// origin of every key, and exits non-zero when the file has errors. With
+// -diff it also lists how the file differs from another one.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/nerufuyo/roastume/src/configuration"
+)
+
+const usage = `usage: configcheck [flags] <file>
+
+flags:
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run verifies the file named in args and returns the process exit code
+func run(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("configcheck", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		fs.PrintDefaults()
+	}
+	envPrefix := fs.String("env", "", "also apply environment variables with this prefix")
+	format := fs.String("format", "", "file format: json, yaml or toml (detected when empty)")
+	asJSON := fs.Bool("json", false, "print the report as JSON")
+	against := fs.String("diff", "", "also verify this file and list how <file> differs from it")
+	sensitive := fs.String("sensitive", "*password*,*secret*,*token*", "comma separated key patterns to redact")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+
+	manager := configuration.NewManager(nil)
+	defer manager.Close()
+	manager.MarkSensitive(splitList(*sensitive)...)
+	if *envPrefix != "" {
+		if err := manager.ApplyEnv(*envPrefix); err != nil {
+			fmt.Fprintf(stderr, "configcheck: environment: %v\n", err)
+			return 1
+		}
+	}
+
+	report, err := manager.Verify(fs.Arg(0), configuration.WithFormat(configuration.Format(*format)))
+	if err != nil {
+		fmt.Fprintf(stderr, "configcheck: %v\n", err)
+		return 1
+	}
+	var changes []configuration.Change
+	if *against != "" {
+		base, err := manager.Verify(*against, configuration.WithFormat(configuration.Format(*format)))
+		if err != nil {
+			fmt.Fprintf(stderr, "configcheck: %v\n", err)
+			return 1
+		}
+		// Both trees are already redacted, so masked values never leak
+		changes = configuration.DiffTrees(base.Effective, report.Effective)
+	}
+
+	if *asJSON {
+		var body interface{} = report
+		if *against != "" {
+			body = map[string]interface{}{"report": report, "changes": changes}
+		}
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(body); err != nil {
+			fmt.Fprintf(stderr, "configcheck: %v\n", err)
+			return 1
+		}
+	} else {
+		printReport(stdout, report)
+		if *against != "" {
+			fmt.Fprintf(stdout, "\nchanges from %s:\n%s", *against, configuration.FormatChanges(changes))
+		}
+	}
+	if !report.OK() {
+		return 1
+	}
+	return 0
+}
+
+// printReport writes a human readable report
+func printReport(w io.Writer, report *configuration.VerifyReport) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "KEY\tVALUE\tLAYER\tSOURCE")
+	for _, origin := range report.Origins {
+		value, _ := json.Marshal(origin.Value)
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", origin.Key, value, origin.LayerID, origin.Source)
+	}
+	tw.Flush()
+
+	for _, d := range report.Deprecations {
+		fmt.Fprintf(w, "warning: %s is deprecated", d.Key)
+		if d.Replacement != "" {
+			fmt.Fprintf(w, "; use %s", d.Replacement)
+		}
+		fmt.Fprintln(w)
+	}
+	for _, e := range report.Errors {
+		fmt.Fprintf(w, "error: %s\n", e)
+	}
+	if report.OK() {
+		fmt.Fprintf(w, "%s: OK\n", report.Path)
+	} else {
+		fmt.Fprintf(w, "%s: %d error(s)\n", report.Path, len(report.Errors))
+	}
+}
+
+// splitList splits a comma separated list, dropping empty items
+func splitList(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool { return r == ',' })
+}
@@ -0,0 +1,110 @@
+// Command configinit writes a starter configuration file from the schema:
+// every key with its default and description, asking on the terminal for
+// required values that have no default. Sensitive keys are never asked for;
+// set them through the environment or a secret reference instead.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/nerufuyo/roastume/src/configuration"
+)
+
+const usage = `usage: configinit [flags] [file]
+
+Writes to stdout when no file is given; an existing file is never replaced.
+
+flags:
+`
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+}
+
+// answers collects repeated -set key=value flags
+type answers map[string]string
+
+// String implements flag.Value
+func (a answers) String() string {
+	pairs := make([]string, 0, len(a))
+	for k, v := range a {
+		pairs = append(pairs, k+"="+v)
+	}
+	return strings.Join(pairs, ",")
+}
+
+// Set implements flag.Value
+func (a answers) Set(s string) error {
+	key, value, ok := strings.Cut(s, "=")
+	if !ok || key == "" {
+		return fmt.Errorf("want key=value, got %q", s)
+	}
+	a[key] = value
+	return nil
+}
+
+// run writes the starter file and returns the process exit code
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("configinit", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprint(stderr, usage)
+		fs.PrintDefaults()
+	}
+	format := fs.String("format", "", "output format: yaml, toml or json (from the file extension, else yaml)")
+	noInput := fs.Bool("no-input", false, "do not prompt; required keys without a value are left empty")
+	preset := answers{}
+	fs.Var(preset, "set", "preset a value as key=value (repeatable)")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() > 1 {
+		fs.Usage()
+		return 2
+	}
+
+	manager := configuration.NewManager(nil)
+	defer manager.Close()
+	opts := []configuration.BootstrapOption{configuration.WithAnswers(preset)}
+	if !*noInput {
+		opts = append(opts, configuration.WithPrompter(configuration.NewLinePrompter(stdin, stderr)))
+	}
+
+	if fs.NArg() == 1 && *format == "" {
+		if err := manager.WriteBootstrap(fs.Arg(0), opts...); err != nil {
+			fmt.Fprintf(stderr, "configinit: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+
+	f := configuration.Format(*format)
+	if f == configuration.FormatAuto {
+		f = configuration.FormatYAML
+	}
+	data, err := manager.Bootstrap(f, opts...)
+	if err != nil {
+		fmt.Fprintf(stderr, "configinit: %v\n", err)
+		return 1
+	}
+	if fs.NArg() == 0 {
+		stdout.Write(data)
+		return 0
+	}
+	file, err := os.OpenFile(fs.Arg(0), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err == nil {
+		_, err = file.Write(data)
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "configinit: %v\n", err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,187 @@
+package configuration
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+)
+
+// adminOptions holds settings for the admin handler
+type adminOptions struct {
+	middleware []func(http.Handler) http.Handler
+	readOnly   bool
+	maxBody    int64
+}
+
+// AdminOption configures NewAdminHandler
+type AdminOption func(*adminOptions)
+
+// WithAdminMiddleware wraps every admin endpoint, e.g. with authentication;
+// middleware is applied in the order given, outermost first
+func WithAdminMiddleware(mw ...func(http.Handler) http.Handler) AdminOption {
+	return func(o *adminOptions) {
+		o.middleware = append(o.middleware, mw...)
+	}
+}
+
+// WithReadOnly disables PATCH /config
+func WithReadOnly() AdminOption {
+	return func(o *adminOptions) {
+		o.readOnly = true
+	}
+}
+
+// NewAdminHandler serves GET /config, GET /config/{key} and PATCH /config,
+// plus the JSON Schema at GET /schema and an example document at
+// GET /schema/example and a reachability report at GET /preflight; values
+// of sensitive keys are always redacted in responses
+func NewAdminHandler(m *Manager, opts ...AdminOption) http.Handler {
+	options := adminOptions{maxBody: 1 << 20}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /config", func(w http.ResponseWriter, r *http.Request) {
+		writeAdminJSON(w, http.StatusOK, m.redactedValues())
+	})
+	mux.HandleFunc("GET /config/{key}", func(w http.ResponseWriter, r *http.Request) {
+		key := r.PathValue("key")
+		value, ok := lookup(m.redactedValues(), key)
+		if !ok {
+			writeAdminError(w, http.StatusNotFound, "configuration key not found")
+			return
+		}
+		body := map[string]interface{}{"key": key, "value": value}
+		if origin, err := m.Explain(key); err == nil {
+			body["layer"] = origin.LayerID
+			body["source"] = origin.Source
+		}
+		writeAdminJSON(w, http.StatusOK, body)
+	})
+	mux.HandleFunc("GET /schema", func(w http.ResponseWriter, r *http.Request) {
+		writeAdminJSON(w, http.StatusOK, m.JSONSchema())
+	})
+	mux.HandleFunc("GET /schema/example", func(w http.ResponseWriter, r *http.Request) {
+		writeAdminJSON(w, http.StatusOK, m.redactTree(m.ExampleDocument()))
+	})
+	mux.HandleFunc("GET /preflight", func(w http.ResponseWriter, r *http.Request) {
+		report := m.Preflight(r.Context())
+		status := http.StatusOK
+		if !report.Ready() {
+			status = http.StatusServiceUnavailable
+		}
+		writeAdminJSON(w, status, report)
+	})
+	mux.HandleFunc("PATCH /config", func(w http.ResponseWriter, r *http.Request) {
+		if options.readOnly {
+			writeAdminError(w, http.StatusMethodNotAllowed, "configuration is read-only")
+			return
+		}
+		var patch map[string]interface{}
+		dec := json.NewDecoder(io.LimitReader(r.Body, options.maxBody))
+		dec.UseNumber()
+		if err := dec.Decode(&patch); err != nil {
+			writeAdminError(w, http.StatusBadRequest, "request body must be a JSON object")
+			return
+		}
+		if err := m.ApplyPatch(r.Context(), patch, WithPatchSource("admin:"+r.RemoteAddr)); err != nil {
+			status := http.StatusUnprocessableEntity
+			if errors.Is(err, ErrFrozen) {
+				status = http.StatusConflict
+			}
+			writeAdminError(w, status, err.Error())
+			return
+		}
+		writeAdminJSON(w, http.StatusOK, m.redactedValues())
+	})
+
+	var handler http.Handler = mux
+	for i := len(options.middleware) - 1; i >= 0; i-- {
+		handler = options.middleware[i](handler)
+	}
+	return handler
+}
+
+// redactedValues returns the effective tree with sensitive values masked
+func (m *Manager) redactedValues() map[string]interface{} {
+	m.mu.RLock()
+	values := m.values
+	m.mu.RUnlock()
+	return m.redactTree(values)
+}
+
+// redactTree returns a copy of tree with sensitive values masked
+func (m *Manager) redactTree(tree map[string]interface{}) map[string]interface{} {
+	m.mu.RLock()
+	rules := m.sensitiveLocked()
+	m.mu.RUnlock()
+	return redactWith(tree, rules)
+}
+
+// redactWith returns a copy of tree with keys matching rules masked
+func redactWith(tree map[string]interface{}, rules []sensitiveRule) map[string]interface{} {
+	flat := flatten(tree)
+	for key, value := range flat {
+		if strategy, ok := maskFor(rules, key); ok {
+			flat[key] = MaskValue(value, strategy)
+		} else {
+			flat[key] = plainValue(value)
+		}
+	}
+	return unflatten(flat)
+}
+
+// applyMergePatch applies RFC 7396 semantics: null deletes, objects merge
+func applyMergePatch(target, patch map[string]interface{}) map[string]interface{} {
+	out := copyTree(target)
+	for k, v := range patch {
+		if v == nil {
+			delete(out, k)
+			continue
+		}
+		if pm, ok := v.(map[string]interface{}); ok {
+			tm, _ := out[k].(map[string]interface{})
+			out[k] = applyMergePatch(tm, pm)
+			continue
+		}
+		out[k] = deepCopy(v)
+	}
+	return out
+}
+
+// inverseMergePatch returns the merge patch undoing patch on target: keys
+// the patch touched get their old value back, or are deleted when target
+// had none, and every other key is left alone
+func inverseMergePatch(target, patch map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(patch))
+	for k, v := range patch {
+		old, had := target[k]
+		pm, nested := v.(map[string]interface{})
+		tm, wasMap := old.(map[string]interface{})
+		switch {
+		case nested && wasMap:
+			out[k] = inverseMergePatch(tm, pm)
+		case had:
+			out[k] = deepCopy(old)
+		default:
+			out[k] = nil
+		}
+	}
+	return out
+}
+
+// writeAdminJSON writes a JSON response
+func writeAdminJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	_ = enc.Encode(body)
+}
+
+// writeAdminError writes a JSON error response
+func writeAdminError(w http.ResponseWriter, status int, message string) {
+	writeAdminJSON(w, status, map[string]string{"error": message})
+}
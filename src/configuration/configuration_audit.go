@@ -0,0 +1,233 @@
+package configuration
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AuditAction is the kind of access recorded in the audit trail
+type AuditAction string
+
+const (
+	// AuditRead records a key read through Get or one of the typed getters
+	AuditRead AuditAction = "read"
+	// AuditWrite records a key change applied to the configuration
+	AuditWrite AuditAction = "write"
+)
+
+// AuditRecord is a single configuration access; values are never stored,
+// only hashes of them (reads carry the hash of the returned value in NewHash)
+type AuditRecord struct {
+	Time    time.Time   `json:"time"`
+	Action  AuditAction `json:"action"`
+	Key     string      `json:"key"`
+	Caller  string      `json:"caller"`
+	Version int         `json:"version"`
+	OldHash string      `json:"old_hash,omitempty"`
+	NewHash string      `json:"new_hash,omitempty"`
+}
+
+// AuditSink persists audit records outside the in-memory buffer
+type AuditSink interface {
+	WriteAudit(record AuditRecord) error
+}
+
+// JSONAuditSink appends audit records to a writer as JSON lines
+type JSONAuditSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewJSONAuditSink creates a sink writing to w
+func NewJSONAuditSink(w io.Writer) *JSONAuditSink {
+	return &JSONAuditSink{w: w}
+}
+
+// OpenAuditFile opens path for appending and returns a sink writing to it
+func OpenAuditFile(path string) (*JSONAuditSink, *os.File, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, nil, fmt.Errorf("open audit file: %w", err)
+	}
+	return NewJSONAuditSink(f), f, nil
+}
+
+// WriteAudit writes one record
+func (s *JSONAuditSink) WriteAudit(record AuditRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.w.Write(append(data, '\n'))
+	return err
+}
+
+// AuditQuery filters audit records; zero fields match everything
+type AuditQuery struct {
+	Key    string
+	Caller string
+	Action AuditAction
+	Since  time.Time
+	Until  time.Time
+	Limit  int
+}
+
+// matches reports whether r satisfies the query
+func (q AuditQuery) matches(r AuditRecord) bool {
+	return keyWithin(r.Key, q.Key) &&
+		(q.Caller == "" || strings.Contains(r.Caller, q.Caller)) &&
+		(q.Action == "" || r.Action == q.Action) &&
+		(q.Since.IsZero() || !r.Time.Before(q.Since)) &&
+		(q.Until.IsZero() || r.Time.Before(q.Until))
+}
+
+// AuditLog is a bounded ring buffer of audit records with an optional sink
+type AuditLog struct {
+	mu      sync.Mutex
+	records []AuditRecord
+	next    int
+	full    bool
+	sink    AuditSink
+	onError func(error)
+}
+
+// NewAuditLog creates a log retaining the last capacity records; sink may
+// be nil
+func NewAuditLog(capacity int, sink AuditSink) *AuditLog {
+	if capacity <= 0 {
+		capacity = 1024
+	}
+	return &AuditLog{records: make([]AuditRecord, capacity), sink: sink}
+}
+
+// Record appends a record and forwards it to the sink
+func (a *AuditLog) Record(record AuditRecord) {
+	if a == nil {
+		return
+	}
+	a.mu.Lock()
+	a.records[a.next] = record
+	a.next = (a.next + 1) % len(a.records)
+	if a.next == 0 {
+		a.full = true
+	}
+	sink, onError := a.sink, a.onError
+	a.mu.Unlock()
+
+	if sink != nil {
+		if err := sink.WriteAudit(record); err != nil && onError != nil {
+			onError(err)
+		}
+	}
+}
+
+// Query returns matching records, newest first
+func (a *AuditLog) Query(q AuditQuery) []AuditRecord {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	n := a.next
+	if a.full {
+		n = len(a.records)
+	}
+	var out []AuditRecord
+	for i := 0; i < n; i++ {
+		r := a.records[(a.next-1-i+len(a.records))%len(a.records)]
+		if !q.matches(r) {
+			continue
+		}
+		out = append(out, r)
+		if q.Limit > 0 && len(out) == q.Limit {
+			break
+		}
+	}
+	return out
+}
+
+// auditHash returns a short stable hash of a configuration value
+func auditHash(v interface{}) string {
+	if v == nil {
+		return ""
+	}
+	data, err := json.Marshal(plainValue(v))
+	if err != nil {
+		data = []byte(fmt.Sprint(v))
+	}
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:8])
+}
+
+// auditCaller names the first function outside this package on the stack
+func auditCaller() string {
+	pcs := make([]uintptr, 16)
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(3, pcs)])
+	for {
+		frame, more := frames.Next()
+		if !strings.Contains(frame.Function, "/configuration.") {
+			return fmt.Sprintf("%s (%s:%d)", frame.Function, frame.File, frame.Line)
+		}
+		if !more {
+			return "unknown"
+		}
+	}
+}
+
+// EnableAudit records every key read and change in log; writes are tagged
+// with the source of the change and reads with the calling function
+func (m *Manager) EnableAudit(log *AuditLog) {
+	log.mu.Lock()
+	log.onError = func(err error) { m.logger.Printf("Audit sink failed: %v", err) }
+	log.mu.Unlock()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.stopAudit != nil {
+		m.stopAudit()
+	}
+	m.audit = log
+	m.stopAudit = m.events.Subscribe("", func(events []ChangeEvent) {
+		for _, e := range events {
+			log.Record(AuditRecord{
+				Time:    e.Timestamp,
+				Action:  AuditWrite,
+				Key:     e.Key,
+				Caller:  e.Source,
+				Version: e.Version,
+				OldHash: auditHash(e.Old),
+				NewHash: auditHash(e.New),
+			})
+		}
+	})
+}
+
+// AuditLog returns the audit log installed by EnableAudit, or nil
+func (m *Manager) AuditLog() *AuditLog {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.audit
+}
+
+// auditRead records a read of key when auditing is enabled
+func (m *Manager) auditRead(audit *AuditLog, key string, value interface{}) {
+	if audit == nil {
+		return
+	}
+	audit.Record(AuditRecord{
+		Time:    time.Now(),
+		Action:  AuditRead,
+		Key:     key,
+		Caller:  auditCaller(),
+		Version: m.Version(),
+		NewHash: auditHash(value),
+	})
+}
@@ -0,0 +1,542 @@
+package configuration
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNoBackup is returned when no backup matches a restore request
+var ErrNoBackup = errors.New("no matching configuration backup")
+
+// BackupStore keeps backup documents by name
+type BackupStore interface {
+	Put(ctx context.Context, name string, data []byte) error
+	Get(ctx context.Context, name string) ([]byte, error)
+	List(ctx context.Context) ([]string, error)
+	Delete(ctx context.Context, name string) error
+}
+
+// BackupInfo identifies a stored backup
+type BackupInfo struct {
+	Name      string    `json:"name"`
+	Version   int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// backupLayer is one layer in a backup document
+type backupLayer struct {
+	Source string                 `json:"source"`
+	Tree   map[string]interface{} `json:"tree"`
+}
+
+// backupDocument is the stored form of a backup; layers hold the raw
+// values, so secret references are kept unresolved
+type backupDocument struct {
+	Version   int                    `json:"version"`
+	CreatedAt time.Time              `json:"created_at"`
+	Layers    map[string]backupLayer `json:"layers"`
+}
+
+// backupTimeFormat sorts lexically in time order
+const backupTimeFormat = "20060102T150405.000000000Z"
+
+// backupName names the backup of version taken at t
+func backupName(version int, t time.Time) string {
+	return fmt.Sprintf("config-%s-v%d.json", t.UTC().Format(backupTimeFormat), version)
+}
+
+// parseBackupName recovers the version and time from a backup name
+func parseBackupName(name string) (BackupInfo, bool) {
+	base := strings.TrimSuffix(strings.TrimPrefix(name, "config-"), ".json")
+	stamp, version, ok := strings.Cut(base, "-v")
+	if !ok || base == name {
+		return BackupInfo{}, false
+	}
+	t, err := time.Parse(backupTimeFormat, stamp)
+	if err != nil {
+		return BackupInfo{}, false
+	}
+	v, err := strconv.Atoi(version)
+	if err != nil {
+		return BackupInfo{}, false
+	}
+	return BackupInfo{Name: name, Version: v, CreatedAt: t}, true
+}
+
+// Backup stores the current layers as a new backup; with a key wrapper set
+// the document is encrypted, and with a signing key set a detached
+// signature is stored next to it
+func (m *Manager) Backup(ctx context.Context, store BackupStore) (BackupInfo, error) {
+	doc := backupDocument{Version: m.Version(), CreatedAt: time.Now().UTC(), Layers: make(map[string]backupLayer)}
+	for layer, data := range m.layers.snapshotLayers() {
+		doc.Layers[layer.String()] = backupLayer{Source: data.source, Tree: data.tree}
+	}
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return BackupInfo{}, fmt.Errorf("encode backup: %w", err)
+	}
+	m.mu.RLock()
+	keys, keyID, key := m.keyWrapper, m.signKeyID, m.signKey
+	m.mu.RUnlock()
+	if keys != nil {
+		if data, err = Encrypt(ctx, data, FormatJSON, keys); err != nil {
+			return BackupInfo{}, fmt.Errorf("encrypt backup: %w", err)
+		}
+	}
+
+	info := BackupInfo{Name: backupName(doc.Version, doc.CreatedAt), Version: doc.Version, CreatedAt: doc.CreatedAt}
+	// The signature goes first so a listed backup is never missing it
+	if key != nil {
+		sig, err := Sign(data, keyID, key)
+		if err != nil {
+			return BackupInfo{}, err
+		}
+		if err := store.Put(ctx, info.Name+SignatureSuffix, sig); err != nil {
+			return BackupInfo{}, fmt.Errorf("store backup signature: %w", err)
+		}
+	}
+	if err := store.Put(ctx, info.Name, data); err != nil {
+		return BackupInfo{}, fmt.Errorf("store backup: %w", err)
+	}
+	m.logger.Printf("Backed up configuration version %d as %s", info.Version, info.Name)
+	return info, nil
+}
+
+// ListBackups returns the backups in store, oldest first
+func ListBackups(ctx context.Context, store BackupStore) ([]BackupInfo, error) {
+	names, err := store.List(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("list backups: %w", err)
+	}
+	return parseBackups(names), nil
+}
+
+// parseBackups returns the backups among names, oldest first; signature
+// objects are skipped
+func parseBackups(names []string) []BackupInfo {
+	var backups []BackupInfo
+	for _, name := range names {
+		if info, ok := parseBackupName(name); ok {
+			backups = append(backups, info)
+		}
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].CreatedAt.Before(backups[j].CreatedAt) })
+	return backups
+}
+
+// RestoreVersion re-applies the most recent backup of version
+func (m *Manager) RestoreVersion(ctx context.Context, store BackupStore, version int) error {
+	return m.restoreMatching(ctx, store, func(info BackupInfo) bool { return info.Version == version })
+}
+
+// RestoreAt re-applies the most recent backup taken at or before t
+func (m *Manager) RestoreAt(ctx context.Context, store BackupStore, t time.Time) error {
+	return m.restoreMatching(ctx, store, func(info BackupInfo) bool { return !info.CreatedAt.After(t) })
+}
+
+// restoreMatching restores the newest backup accepted by match
+func (m *Manager) restoreMatching(ctx context.Context, store BackupStore, match func(BackupInfo) bool) error {
+	backups, err := ListBackups(ctx, store)
+	if err != nil {
+		return err
+	}
+	for i := len(backups) - 1; i >= 0; i-- {
+		if match(backups[i]) {
+			return m.restore(ctx, store, backups[i])
+		}
+	}
+	return ErrNoBackup
+}
+
+// restore replaces every layer with the backup's, keeping the change only if
+// it decodes and validates, and notifies watchers as a new version; with
+// trusted keys set, the backup must carry a valid signature
+func (m *Manager) restore(ctx context.Context, store BackupStore, info BackupInfo) error {
+	data, err := store.Get(ctx, info.Name)
+	if err != nil {
+		return fmt.Errorf("fetch backup %s: %w", info.Name, err)
+	}
+	m.mu.RLock()
+	trusted, keys := m.trustedKeys, m.keyWrapper
+	m.mu.RUnlock()
+	if trusted != nil {
+		sig, err := store.Get(ctx, info.Name+SignatureSuffix)
+		if err != nil {
+			return fmt.Errorf("backup %s: %w: %v", info.Name, ErrUnsigned, err)
+		}
+		if err := VerifySignature(trusted, data, sig); err != nil {
+			return fmt.Errorf("backup %s: %w", info.Name, err)
+		}
+	}
+	if data, err = decryptIfNeeded(data, &loadOptions{keys: keys}); err != nil {
+		return fmt.Errorf("decrypt backup %s: %w", info.Name, err)
+	}
+
+	var doc backupDocument
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&doc); err != nil {
+		return fmt.Errorf("decode backup %s: %w", info.Name, err)
+	}
+	layers := make(map[Layer]layerData, len(doc.Layers))
+	for _, layer := range layerOrder {
+		if saved, ok := doc.Layers[layer.String()]; ok {
+			layers[layer] = layerData{source: saved.Source, tree: saved.Tree}
+		}
+	}
+	if _, ok := layers[LayerDefaults]; !ok {
+		return fmt.Errorf("backup %s has no defaults layer", info.Name)
+	}
+
+	defer m.deliverChanges()
+	m.writeMu.Lock()
+	defer m.writeMu.Unlock()
+	if err := m.checkWritable(); err != nil {
+		return fmt.Errorf("restore %s: %w", info.Name, err)
+	}
+	var config *Config
+	var values map[string]interface{}
+	err = m.layers.restoreLayers(layers, func(effective map[string]interface{}) error {
+		var err error
+		if config, values, err = m.decodeEffective(effective); err != nil {
+			return err
+		}
+		return m.validateValues(config, values)
+	})
+	if err != nil {
+		return fmt.Errorf("restore %s: %w", info.Name, err)
+	}
+	m.applyConfig(config, values)
+	m.logger.Printf("Restored configuration version %d from backup %s", info.Version, info.Name)
+	return nil
+}
+
+// BackupPolicy controls scheduled backups and their retention
+type BackupPolicy struct {
+	// Interval between checks; a backup is only taken when the version
+	// changed since the last one
+	Interval time.Duration
+	// Keep is the number of most recent backups retained (0 keeps all)
+	Keep int
+	// MaxAge removes backups older than this (0 disables); the newest
+	// backup is always retained
+	MaxAge time.Duration
+}
+
+// ScheduleBackups backs up the configuration to store whenever it changed,
+// checking every policy.Interval and pruning per the policy, until ctx is
+// cancelled
+func (m *Manager) ScheduleBackups(ctx context.Context, store BackupStore, policy BackupPolicy) error {
+	if policy.Interval <= 0 {
+		return errors.New("backup interval must be positive")
+	}
+	go func() {
+		saved := 0
+		ticker := time.NewTicker(policy.Interval)
+		defer ticker.Stop()
+		for {
+			if version := m.Version(); version != saved {
+				if _, err := m.Backup(ctx, store); err != nil {
+					m.logger.Printf("Configuration backup failed: %v", err)
+				} else {
+					saved = version
+					if err := PruneBackups(ctx, store, policy); err != nil {
+						m.logger.Printf("Configuration backup pruning failed: %v", err)
+					}
+				}
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+	return nil
+}
+
+// PruneBackups deletes backups that fall outside the retention policy,
+// along with their signatures
+func PruneBackups(ctx context.Context, store BackupStore, policy BackupPolicy) error {
+	names, err := store.List(ctx)
+	if err != nil {
+		return fmt.Errorf("list backups: %w", err)
+	}
+	stored := make(map[string]bool, len(names))
+	for _, name := range names {
+		stored[name] = true
+	}
+	backups := parseBackups(names)
+	cutoff := time.Now().Add(-policy.MaxAge)
+	var errs []error
+	// Never delete the newest backup
+	for i := 0; i < len(backups)-1; i++ {
+		expired := policy.MaxAge > 0 && backups[i].CreatedAt.Before(cutoff)
+		excess := policy.Keep > 0 && i < len(backups)-policy.Keep
+		if !expired && !excess {
+			continue
+		}
+		if err := store.Delete(ctx, backups[i].Name); err != nil {
+			errs = append(errs, fmt.Errorf("delete %s: %w", backups[i].Name, err))
+			continue
+		}
+		if sig := backups[i].Name + SignatureSuffix; stored[sig] {
+			if err := store.Delete(ctx, sig); err != nil {
+				errs = append(errs, fmt.Errorf("delete %s: %w", sig, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// DirBackupStore keeps backups as files in a local directory
+type DirBackupStore struct {
+	Dir string
+}
+
+// Put writes a backup file
+func (s DirBackupStore) Put(ctx context.Context, name string, data []byte) error {
+	if err := os.MkdirAll(s.Dir, 0o700); err != nil {
+		return err
+	}
+	return writeFileAtomic(filepath.Join(s.Dir, filepath.Base(name)), data, 0o600)
+}
+
+// Get reads a backup file
+func (s DirBackupStore) Get(ctx context.Context, name string) ([]byte, error) {
+	return os.ReadFile(filepath.Join(s.Dir, filepath.Base(name)))
+}
+
+// List returns the file names in the directory
+func (s DirBackupStore) List(ctx context.Context) ([]string, error) {
+	entries, err := os.ReadDir(s.Dir)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if entry.Type().IsRegular() {
+			names = append(names, entry.Name())
+		}
+	}
+	return names, nil
+}
+
+// Delete removes a backup file
+func (s DirBackupStore) Delete(ctx context.Context, name string) error {
+	return os.Remove(filepath.Join(s.Dir, filepath.Base(name)))
+}
+
+// S3BackupStore keeps backups under a key prefix in an S3 bucket
+type S3BackupStore struct {
+	Region      string
+	Bucket      string
+	Prefix      string
+	Credentials AWSCredentialsFunc
+	Client      *http.Client
+}
+
+// NewS3BackupStore creates a store for prefix in bucket
+func NewS3BackupStore(region, bucket, prefix string, credentials AWSCredentialsFunc) *S3BackupStore {
+	return &S3BackupStore{Region: region, Bucket: bucket, Prefix: prefix, Credentials: credentials}
+}
+
+// endpoint returns the URL of the bucket, or of an object within it
+func (s *S3BackupStore) endpoint(name string) string {
+	base := fmt.Sprintf("https://%s.s3.%s.amazonaws.com/", s.Bucket, s.Region)
+	if name == "" {
+		return base
+	}
+	return base + escapeObjectKey(s.Prefix+name)
+}
+
+// do sends a signed request and returns the response body
+func (s *S3BackupStore) do(ctx context.Context, method, rawURL string, body []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256(body)
+	req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(sum[:]))
+	signer := &SigV4Signer{Region: s.Region, Service: "s3", Credentials: s.Credentials}
+	if err := signer.Sign(ctx, req); err != nil {
+		return nil, fmt.Errorf("s3: sign request: %w", err)
+	}
+	return doBackupRequest(s.Client, req, "s3")
+}
+
+// Put uploads a backup object
+func (s *S3BackupStore) Put(ctx context.Context, name string, data []byte) error {
+	_, err := s.do(ctx, http.MethodPut, s.endpoint(name), data)
+	return err
+}
+
+// Get downloads a backup object
+func (s *S3BackupStore) Get(ctx context.Context, name string) ([]byte, error) {
+	return s.do(ctx, http.MethodGet, s.endpoint(name), nil)
+}
+
+// Delete removes a backup object
+func (s *S3BackupStore) Delete(ctx context.Context, name string) error {
+	_, err := s.do(ctx, http.MethodDelete, s.endpoint(name), nil)
+	return err
+}
+
+// List returns the object names below the prefix, without the prefix
+func (s *S3BackupStore) List(ctx context.Context) ([]string, error) {
+	var names []string
+	token := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {s.Prefix}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		data, err := s.do(ctx, http.MethodGet, s.endpoint("")+"?"+query.Encode(), nil)
+		if err != nil {
+			return nil, err
+		}
+		var page struct {
+			Contents []struct {
+				Key string
+			}
+			IsTruncated           bool
+			NextContinuationToken string
+		}
+		if err := xml.Unmarshal(data, &page); err != nil {
+			return nil, fmt.Errorf("s3: decode listing: %w", err)
+		}
+		for _, object := range page.Contents {
+			names = append(names, strings.TrimPrefix(object.Key, s.Prefix))
+		}
+		if !page.IsTruncated {
+			return names, nil
+		}
+		token = page.NextContinuationToken
+	}
+}
+
+// GCSBackupStore keeps backups under an object prefix in a GCS bucket
+type GCSBackupStore struct {
+	Bucket string
+	Prefix string
+	Signer RequestSigner
+	Client *http.Client
+}
+
+// NewGCSBackupStore creates a store for prefix in bucket using the
+// instance's service account
+func NewGCSBackupStore(bucket, prefix string) *GCSBackupStore {
+	return &GCSBackupStore{Bucket: bucket, Prefix: prefix, Signer: &TokenSigner{Source: GCEMetadataToken()}}
+}
+
+// objectURL returns the JSON API URL of an object
+func (s *GCSBackupStore) objectURL(name string) string {
+	return fmt.Sprintf("https://storage.googleapis.com/storage/v1/b/%s/o/%s", url.PathEscape(s.Bucket), url.PathEscape(s.Prefix+name))
+}
+
+// do sends a signed request and returns the response body
+func (s *GCSBackupStore) do(ctx context.Context, method, rawURL string, body []byte) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, method, rawURL, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if s.Signer != nil {
+		if err := s.Signer.Sign(ctx, req); err != nil {
+			return nil, fmt.Errorf("gcs: sign request: %w", err)
+		}
+	}
+	return doBackupRequest(s.Client, req, "gcs")
+}
+
+// Put uploads a backup object
+func (s *GCSBackupStore) Put(ctx context.Context, name string, data []byte) error {
+	query := url.Values{"uploadType": {"media"}, "name": {s.Prefix + name}}
+	rawURL := fmt.Sprintf("https://storage.googleapis.com/upload/storage/v1/b/%s/o?%s", url.PathEscape(s.Bucket), query.Encode())
+	_, err := s.do(ctx, http.MethodPost, rawURL, data)
+	return err
+}
+
+// Get downloads a backup object
+func (s *GCSBackupStore) Get(ctx context.Context, name string) ([]byte, error) {
+	return s.do(ctx, http.MethodGet, s.objectURL(name)+"?alt=media", nil)
+}
+
+// Delete removes a backup object
+func (s *GCSBackupStore) Delete(ctx context.Context, name string) error {
+	_, err := s.do(ctx, http.MethodDelete, s.objectURL(name), nil)
+	return err
+}
+
+// List returns the object names below the prefix, without the prefix
+func (s *GCSBackupStore) List(ctx context.Context) ([]string, error) {
+	var names []string
+	token := ""
+	for {
+		query := url.Values{"prefix": {s.Prefix}, "fields": {"items(name),nextPageToken"}}
+		if token != "" {
+			query.Set("pageToken", token)
+		}
+		rawURL := fmt.Sprintf("https://storage.googleapis.com/storage/v1/b/%s/o?%s", url.PathEscape(s.Bucket), query.Encode())
+		data, err := s.do(ctx, http.MethodGet, rawURL, nil)
+		if err != nil {
+			return nil, err
+		}
+		var page struct {
+			Items []struct {
+				Name string `json:"name"`
+			} `json:"items"`
+			NextPageToken string `json:"nextPageToken"`
+		}
+		if err := json.Unmarshal(data, &page); err != nil {
+			return nil, fmt.Errorf("gcs: decode listing: %w", err)
+		}
+		for _, item := range page.Items {
+			names = append(names, strings.TrimPrefix(item.Name, s.Prefix))
+		}
+		if page.NextPageToken == "" {
+			return names, nil
+		}
+		token = page.NextPageToken
+	}
+}
+
+// doBackupRequest sends req and returns the body of a 2xx response
+func doBackupRequest(client *http.Client, req *http.Request, store string) ([]byte, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", store, err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", store, err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("%s: unexpected status %d", store, resp.StatusCode)
+	}
+	return data, nil
+}
@@ -0,0 +1,222 @@
+package configuration
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+)
+
+// testKeys is a KeyProvider over a fixed key set
+type testKeys map[string]ed25519.PublicKey
+
+func (k testKeys) PublicKey(keyID string) (ed25519.PublicKey, error) {
+	if key, ok := k[keyID]; ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown key %q", keyID)
+}
+
+func TestBackupRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys := testWrapper(t, "backup", 1)
+
+	tests := []struct {
+		name          string
+		encrypt, sign bool
+	}{
+		{"plain", false, false},
+		{"encrypted", true, false},
+		{"signed", false, true},
+		{"encrypted and signed", true, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := DirBackupStore{Dir: t.TempDir()}
+			m := NewManager(nil)
+			if tt.encrypt {
+				m.SetKeyWrapper(keys)
+			}
+			if tt.sign {
+				m.SetSigningKey("backup", priv)
+			}
+			file := map[string]interface{}{"retries": 3, "db": map[string]interface{}{"password": "hunter2"}}
+			if err := m.SetLayer(LayerFile, "app.json", file); err != nil {
+				t.Fatal(err)
+			}
+			info, err := m.Backup(ctx, store)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			raw, err := store.Get(ctx, info.Name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if IsEncrypted(raw) != tt.encrypt {
+				t.Errorf("encrypted = %v, want %v", IsEncrypted(raw), tt.encrypt)
+			}
+			if tt.encrypt && bytes.Contains(raw, []byte("hunter2")) {
+				t.Error("encrypted backup contains the plaintext password")
+			}
+			if _, err := store.Get(ctx, info.Name+SignatureSuffix); (err == nil) != tt.sign {
+				t.Errorf("signature lookup = %v, want signed %v", err, tt.sign)
+			}
+			if backups, err := ListBackups(ctx, store); err != nil || len(backups) != 1 || backups[0] != info {
+				t.Errorf("ListBackups = %v, %v; want only %v", backups, err, info)
+			}
+
+			restored := NewManager(nil)
+			if tt.encrypt {
+				restored.SetKeyWrapper(keys)
+			}
+			if tt.sign {
+				restored.SetTrustedKeys(testKeys{"backup": pub})
+			}
+			if err := restored.RestoreVersion(ctx, store, info.Version); err != nil {
+				t.Fatal(err)
+			}
+			if got := restored.GetInt("retries", 0); got != 3 {
+				t.Errorf("retries = %d, want 3", got)
+			}
+			if got := restored.GetString("db.password", ""); got != "hunter2" {
+				t.Errorf("db.password = %q, want hunter2", got)
+			}
+		})
+	}
+}
+
+func TestRestoreRejectsUntrustedBackups(t *testing.T) {
+	ctx := context.Background()
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, otherPriv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	backup := func(t *testing.T, keyID string, key ed25519.PrivateKey) (DirBackupStore, BackupInfo) {
+		store := DirBackupStore{Dir: t.TempDir()}
+		m := NewManager(nil)
+		if key != nil {
+			m.SetSigningKey(keyID, key)
+		}
+		if err := m.SetLayer(LayerFile, "app.json", map[string]interface{}{"retries": 7}); err != nil {
+			t.Fatal(err)
+		}
+		info, err := m.Backup(ctx, store)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return store, info
+	}
+
+	tests := []struct {
+		name   string
+		keyID  string
+		key    ed25519.PrivateKey
+		modify func(t *testing.T, path string)
+		want   error
+	}{
+		{name: "unsigned", want: ErrUnsigned},
+		{name: "other key", keyID: "backup", key: otherPriv, want: ErrInvalidSignature},
+		{name: "tampered", keyID: "backup", key: priv, want: ErrInvalidSignature, modify: func(t *testing.T, path string) {
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.WriteFile(path, bytes.Replace(data, []byte(`"retries": 7`), []byte(`"retries": 9`), 1), 0o600); err != nil {
+				t.Fatal(err)
+			}
+		}},
+		{name: "missing signature", keyID: "backup", key: priv, want: ErrUnsigned, modify: func(t *testing.T, path string) {
+			if err := os.Remove(path + SignatureSuffix); err != nil {
+				t.Fatal(err)
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, info := backup(t, tt.keyID, tt.key)
+			if tt.modify != nil {
+				tt.modify(t, filepath.Join(store.Dir, info.Name))
+			}
+			m := NewManager(nil)
+			m.SetTrustedKeys(testKeys{"backup": pub})
+			if err := m.RestoreVersion(ctx, store, info.Version); !errors.Is(err, tt.want) {
+				t.Fatalf("RestoreVersion = %v, want %v", err, tt.want)
+			}
+			if got := m.GetInt("retries", 0); got != DefaultConfig().Retries {
+				t.Errorf("retries = %d after a rejected restore, want the default", got)
+			}
+		})
+	}
+}
+
+func TestRestoreEncryptedBackupNeedsKey(t *testing.T) {
+	t.Setenv(DefaultKeyEnv, "")
+	ctx := context.Background()
+	store := DirBackupStore{Dir: t.TempDir()}
+	m := NewManager(nil)
+	m.SetKeyWrapper(testWrapper(t, "backup", 1))
+	info, err := m.Backup(ctx, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := NewManager(nil).RestoreVersion(ctx, store, info.Version); err == nil {
+		t.Error("an encrypted backup was restored without a key")
+	}
+	wrong := NewManager(nil)
+	wrong.SetKeyWrapper(testWrapper(t, "backup", 2))
+	if err := wrong.RestoreVersion(ctx, store, info.Version); err == nil {
+		t.Error("an encrypted backup was restored with the wrong key")
+	}
+}
+
+func TestPruneBackupsDeletesSignatures(t *testing.T) {
+	ctx := context.Background()
+	_, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := DirBackupStore{Dir: t.TempDir()}
+	m := NewManager(nil)
+	m.SetSigningKey("backup", priv)
+
+	var newest BackupInfo
+	for i := 0; i < 3; i++ {
+		if err := m.SetLayer(LayerRuntime, "test", map[string]interface{}{"retries": i}); err != nil {
+			t.Fatal(err)
+		}
+		if newest, err = m.Backup(ctx, store); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if err := PruneBackups(ctx, store, BackupPolicy{Keep: 1}); err != nil {
+		t.Fatal(err)
+	}
+
+	names, err := store.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(names)
+	want := []string{newest.Name, newest.Name + SignatureSuffix}
+	if fmt.Sprint(names) != fmt.Sprint(want) {
+		t.Errorf("names after pruning = %v, want %v", names, want)
+	}
+}
@@ -0,0 +1,512 @@
+package configuration
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// DescriptionTag is the struct tag holding a key's description, used for
+	// schema descriptions and starter file comments
+	DescriptionTag = "desc"
+	// RequiredTag marks a key (`required:"true"`) that has no usable default
+	// and must be set before the configuration is complete
+	RequiredTag = "required"
+)
+
+// BootstrapField describes one key of a starter configuration
+type BootstrapField struct {
+	Key         string      `json:"key"`
+	Type        string      `json:"type"`
+	Description string      `json:"description,omitempty"`
+	Default     interface{} `json:"default,omitempty"`
+	Required    bool        `json:"required,omitempty"`
+	Sensitive   bool        `json:"sensitive,omitempty"`
+
+	typ reflect.Type
+}
+
+// Parse converts an answer typed for the field into its configuration value
+func (f BootstrapField) Parse(answer string) (interface{}, error) {
+	normalized, err := normalize(answer, f.typ, f.Key)
+	if err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(normalized)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", f.Key, err)
+	}
+	target := reflect.New(f.typ)
+	if err := json.Unmarshal(data, target.Interface()); err != nil {
+		return nil, fmt.Errorf("%s: invalid %s %q", f.Key, f.Type, answer)
+	}
+	return plainValue(toTreeValue(target.Elem())), nil
+}
+
+// Promptable reports whether the field is asked for: required keys without
+// a default, except sensitive ones, which belong in the environment or a
+// secret store rather than a file
+func (f BootstrapField) Promptable() bool {
+	return f.Required && !f.Sensitive && isZeroValue(f.Default)
+}
+
+// Prompter asks for the value of a field; the CLI implementation is LinePrompter
+type Prompter interface {
+	Prompt(field BootstrapField) (string, error)
+}
+
+// LinePrompter prompts on a writer and reads answers line by line, asking
+// again until the answer parses
+type LinePrompter struct {
+	in  *bufio.Reader
+	out io.Writer
+}
+
+// NewLinePrompter creates a prompter reading from in and writing to out
+func NewLinePrompter(in io.Reader, out io.Writer) *LinePrompter {
+	return &LinePrompter{in: bufio.NewReader(in), out: out}
+}
+
+// Prompt prints the key, its description and type and returns the answer
+func (p *LinePrompter) Prompt(field BootstrapField) (string, error) {
+	if field.Description != "" {
+		fmt.Fprintf(p.out, "%s: %s\n", field.Key, field.Description)
+	}
+	for {
+		fmt.Fprintf(p.out, "%s (%s): ", field.Key, field.Type)
+		line, err := p.in.ReadString('\n')
+		answer := strings.TrimSpace(line)
+		if err != nil && (err != io.EOF || answer == "") {
+			if err == io.EOF {
+				return "", fmt.Errorf("%s: no answer", field.Key)
+			}
+			return "", err
+		}
+		if answer == "" {
+			fmt.Fprintf(p.out, "%s is required\n", field.Key)
+			continue
+		}
+		if _, parseErr := field.Parse(answer); parseErr != nil {
+			fmt.Fprintln(p.out, parseErr)
+			if err == io.EOF {
+				return "", parseErr
+			}
+			continue
+		}
+		return answer, nil
+	}
+}
+
+// bootstrapOptions holds settings for Bootstrap
+type bootstrapOptions struct {
+	prompter Prompter
+	answers  map[string]string
+}
+
+// BootstrapOption configures Bootstrap
+type BootstrapOption func(*bootstrapOptions)
+
+// WithPrompter asks prompter for required values that have no default
+func WithPrompter(prompter Prompter) BootstrapOption {
+	return func(o *bootstrapOptions) {
+		o.prompter = prompter
+	}
+}
+
+// WithAnswers presets values by dotted key, parsed like prompt answers;
+// preset keys are not prompted for
+func WithAnswers(answers map[string]string) BootstrapOption {
+	return func(o *bootstrapOptions) {
+		if o.answers == nil {
+			o.answers = make(map[string]string)
+		}
+		for k, v := range answers {
+			o.answers[k] = v
+		}
+	}
+}
+
+// bootstrapNode is a key in the starter document, in declaration order
+type bootstrapNode struct {
+	name     string
+	field    BootstrapField
+	children []*bootstrapNode
+}
+
+// section reports whether the node holds nested keys
+func (n *bootstrapNode) section() bool {
+	return n.children != nil
+}
+
+// child returns the named child section, creating it if needed
+func (n *bootstrapNode) child(name string) *bootstrapNode {
+	for _, c := range n.children {
+		if c.name == name {
+			return c
+		}
+	}
+	c := &bootstrapNode{name: name, field: BootstrapField{Key: joinKey(n.field.Key, name)}, children: []*bootstrapNode{}}
+	n.children = append(n.children, c)
+	return c
+}
+
+// BootstrapFields lists every key of the schema (Config plus registered
+// sections) in declaration order with its description and default
+func (m *Manager) BootstrapFields() []BootstrapField {
+	var fields []BootstrapField
+	var walk func(n *bootstrapNode)
+	walk = func(n *bootstrapNode) {
+		for _, c := range n.children {
+			if c.section() {
+				walk(c)
+				continue
+			}
+			fields = append(fields, c.field)
+		}
+	}
+	walk(m.bootstrapTree())
+	return fields
+}
+
+// bootstrapTree builds the document outline from the schema and defaults
+func (m *Manager) bootstrapTree() *bootstrapNode {
+	example := m.ExampleDocument()
+	m.mu.RLock()
+	schemas := append([]sectionSchema(nil), m.schemas...)
+	rules := m.sensitiveLocked()
+	m.mu.RUnlock()
+
+	root := &bootstrapNode{children: []*bootstrapNode{}}
+	addBootstrapFields(root, reflect.TypeOf(Config{}), example, rules)
+	for _, schema := range schemas {
+		node := root
+		for _, part := range strings.Split(schema.section, ".") {
+			node = node.child(part)
+		}
+		addBootstrapFields(node, schema.typ, example, rules)
+	}
+	return root
+}
+
+// addBootstrapFields adds the fields of struct type t below node
+func addBootstrapFields(node *bootstrapNode, t reflect.Type, example map[string]interface{}, rules []sensitiveRule) {
+	for _, f := range orderedFields(indirectType(t)) {
+		name := fieldKey(f)
+		key := joinKey(node.field.Key, name)
+		ft := indirectType(f.Type)
+		if ft.Kind() == reflect.Struct && !leafType(ft) {
+			child := node.child(name)
+			child.field.Description = f.Tag.Get(DescriptionTag)
+			addBootstrapFields(child, ft, example, rules)
+			continue
+		}
+
+		field := BootstrapField{
+			Key:         key,
+			Type:        typeName(ft),
+			Description: f.Tag.Get(DescriptionTag),
+			Required:    f.Tag.Get(RequiredTag) == "true",
+			typ:         ft,
+		}
+		_, tagged := f.Tag.Lookup(SensitiveTag)
+		_, matched := maskFor(rules, key)
+		field.Sensitive = tagged || matched
+		if !field.Sensitive {
+			field.Default, _ = lookup(example, key)
+		}
+		node.children = append(node.children, &bootstrapNode{name: name, field: field})
+	}
+}
+
+// orderedFields returns the configuration fields of t in declaration
+// order, flattening untagged embedded structs
+func orderedFields(t reflect.Type) []reflect.StructField {
+	var fields []reflect.StructField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		ft := indirectType(f.Type)
+		if f.Anonymous && f.Tag.Get("json") == "" && ft.Kind() == reflect.Struct {
+			fields = append(fields, orderedFields(ft)...)
+			continue
+		}
+		if fieldKey(f) != "" {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// leafType reports whether a struct type is written as a single value
+func leafType(t reflect.Type) bool {
+	return t == reflect.TypeOf(time.Time{}) || reflect.PointerTo(t).Implements(textUnmarshalerType)
+}
+
+// typeName names a field type for prompts and comments
+func typeName(t reflect.Type) string {
+	switch {
+	case t == durationType:
+		return "duration"
+	case t == reflect.TypeOf(ByteSize{}):
+		return "byte size"
+	case t == reflect.TypeOf(Percent(0)):
+		return "percent"
+	case t == reflect.TypeOf(time.Time{}):
+		return "timestamp"
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "integer"
+	case reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "list"
+	case reflect.Map:
+		return "map"
+	}
+	return "string"
+}
+
+// isZeroValue reports whether a default leaves the key effectively unset
+func isZeroValue(v interface{}) bool {
+	if v == nil {
+		return true
+	}
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Map:
+		return rv.Len() == 0
+	}
+	return rv.IsZero()
+}
+
+// Bootstrap renders a starter configuration document: every key of the
+// schema with its default, preceded by its description as a comment (YAML
+// and TOML; JSON has no comments). Required keys without a default are
+// taken from WithAnswers or asked for with WithPrompter; sensitive keys are
+// never prompted for or filled in
+func (m *Manager) Bootstrap(format Format, opts ...BootstrapOption) ([]byte, error) {
+	options := bootstrapOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	root := m.bootstrapTree()
+	var missing []string
+	var fill func(n *bootstrapNode) error
+	fill = func(n *bootstrapNode) error {
+		for _, c := range n.children {
+			if c.section() {
+				if err := fill(c); err != nil {
+					return err
+				}
+				continue
+			}
+			answer, preset := options.answers[c.field.Key]
+			if !preset {
+				if !c.field.Promptable() {
+					continue
+				}
+				if options.prompter == nil {
+					missing = append(missing, c.field.Key)
+					continue
+				}
+				var err error
+				if answer, err = options.prompter.Prompt(c.field); err != nil {
+					return fmt.Errorf("prompt %s: %w", c.field.Key, err)
+				}
+			}
+			value, err := c.field.Parse(answer)
+			if err != nil {
+				return err
+			}
+			c.field.Default = value
+		}
+		return nil
+	}
+	if err := fill(root); err != nil {
+		return nil, err
+	}
+	if len(missing) > 0 {
+		m.logger.Printf("Starter configuration leaves required keys unset: %s", strings.Join(missing, ", "))
+	}
+
+	var buf bytes.Buffer
+	switch format {
+	case FormatYAML, FormatAuto:
+		doc := yamlBootstrap(root)
+		doc.HeadComment = "Starter configuration; values shown are the defaults"
+		enc := yaml.NewEncoder(&buf)
+		enc.SetIndent(2)
+		if err := enc.Encode(doc); err != nil {
+			return nil, fmt.Errorf("encode yaml: %w", err)
+		}
+		if err := enc.Close(); err != nil {
+			return nil, fmt.Errorf("encode yaml: %w", err)
+		}
+	case FormatTOML:
+		buf.WriteString("# Starter configuration; values shown are the defaults\n")
+		if err := writeTOMLBootstrap(&buf, root, ""); err != nil {
+			return nil, err
+		}
+	case FormatJSON:
+		data, err := json.MarshalIndent(bootstrapValues(root), "", "  ")
+		if err != nil {
+			return nil, fmt.Errorf("encode json: %w", err)
+		}
+		buf.Write(append(data, '\n'))
+	default:
+		return nil, fmt.Errorf("starter configuration: unsupported format %q", format)
+	}
+	return buf.Bytes(), nil
+}
+
+// WriteBootstrap writes the starter document to path, inferring the format
+// from its extension; an existing file is never overwritten
+func (m *Manager) WriteBootstrap(path string, opts ...BootstrapOption) error {
+	data, err := m.Bootstrap(FormatFromPath(path), opts...)
+	if err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return fmt.Errorf("write starter configuration: %w", err)
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return fmt.Errorf("write starter configuration: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("write starter configuration: %w", err)
+	}
+	m.logger.Printf("Wrote starter configuration to %s", path)
+	return nil
+}
+
+// bootstrapComment returns the comment lines written above a field
+func bootstrapComment(field BootstrapField) []string {
+	var lines []string
+	if field.Description != "" {
+		lines = append(lines, strings.Split(field.Description, "\n")...)
+	}
+	switch {
+	case field.Sensitive:
+		lines = append(lines, "Sensitive: set it through the environment or a secret reference")
+	case field.Required && isZeroValue(field.Default):
+		lines = append(lines, fmt.Sprintf("Required (%s)", field.Type))
+	}
+	return lines
+}
+
+// bootstrapValues returns the document as a plain tree
+func bootstrapValues(n *bootstrapNode) map[string]interface{} {
+	out := make(map[string]interface{}, len(n.children))
+	for _, c := range n.children {
+		if c.section() {
+			out[c.name] = bootstrapValues(c)
+			continue
+		}
+		out[c.name] = c.field.Default
+	}
+	return out
+}
+
+// yamlBootstrap builds a YAML mapping that keeps declaration order and
+// carries descriptions as comments
+func yamlBootstrap(n *bootstrapNode) *yaml.Node {
+	mapping := &yaml.Node{Kind: yaml.MappingNode}
+	for _, c := range n.children {
+		key := &yaml.Node{Kind: yaml.ScalarNode, Value: c.name}
+		var value *yaml.Node
+		if c.section() {
+			if c.field.Description != "" {
+				key.HeadComment = c.field.Description
+			}
+			value = yamlBootstrap(c)
+		} else {
+			key.HeadComment = strings.Join(bootstrapComment(c.field), "\n")
+			value = &yaml.Node{}
+			if err := value.Encode(c.field.Default); err != nil {
+				value = &yaml.Node{Kind: yaml.ScalarNode, Tag: "!!null"}
+			}
+		}
+		mapping.Content = append(mapping.Content, key, value)
+	}
+	return mapping
+}
+
+// writeTOMLBootstrap writes the keys of n, then each section as a table
+func writeTOMLBootstrap(w *bytes.Buffer, n *bootstrapNode, table string) error {
+	for _, c := range n.children {
+		if c.section() {
+			continue
+		}
+		for _, line := range bootstrapComment(c.field) {
+			fmt.Fprintf(w, "# %s\n", line)
+		}
+		if c.field.Default == nil {
+			// TOML has no null; leave the key commented out
+			fmt.Fprintf(w, "# %s =\n", c.name)
+			continue
+		}
+		if err := toml.NewEncoder(w).Encode(map[string]interface{}{c.name: c.field.Default}); err != nil {
+			return fmt.Errorf("encode toml %s: %w", c.field.Key, err)
+		}
+	}
+	for _, c := range n.children {
+		if !c.section() {
+			continue
+		}
+		name := joinKey(table, tomlKey(c.name))
+		if hasLeaves(c) {
+			// Tables holding only subtables are implied by them
+			w.WriteString("\n")
+			if c.field.Description != "" {
+				for _, line := range strings.Split(c.field.Description, "\n") {
+					fmt.Fprintf(w, "# %s\n", line)
+				}
+			}
+			fmt.Fprintf(w, "[%s]\n", name)
+		}
+		if err := writeTOMLBootstrap(w, c, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// hasLeaves reports whether a section holds keys of its own
+func hasLeaves(n *bootstrapNode) bool {
+	for _, c := range n.children {
+		if !c.section() {
+			return true
+		}
+	}
+	return false
+}
+
+// tomlKey quotes a table key segment unless it is a bare key
+func tomlKey(key string) string {
+	for _, r := range key {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '_' || r == '-') {
+			data, _ := json.Marshal(key)
+			return string(data)
+		}
+	}
+	if key == "" {
+		return `""`
+	}
+	return key
+}
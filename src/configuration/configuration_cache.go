@@ -0,0 +1,375 @@
+package configuration
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// KeyReader is implemented by providers that can fetch a single key more
+// cheaply than the whole tree; CachedProvider uses it for per-key misses
+type KeyReader interface {
+	// LoadKey fetches the value under a dotted key, reporting whether it exists
+	LoadKey(ctx context.Context, key string) (interface{}, bool, error)
+}
+
+// CacheStats counts how CachedProvider reads were served
+type CacheStats struct {
+	Hits          uint64 `json:"hits"`
+	Misses        uint64 `json:"misses"`
+	Stale         uint64 `json:"stale"`
+	Refreshes     uint64 `json:"refreshes"`
+	Invalidations uint64 `json:"invalidations"`
+	Errors        uint64 `json:"errors"`
+	LastError     string `json:"last_error,omitempty"`
+}
+
+// cacheRule gives keys matching pattern their own TTL
+type cacheRule struct {
+	pattern string
+	ttl     time.Duration
+}
+
+// cacheEntry is a cached key
+type cacheEntry struct {
+	value     interface{}
+	found     bool
+	expiresAt time.Time
+}
+
+// cacheCall is an in-flight fetch shared by concurrent readers
+type cacheCall struct {
+	done  chan struct{}
+	value interface{}
+	found bool
+	err   error
+}
+
+// CacheOption configures NewCachedProvider
+type CacheOption func(*CachedProvider)
+
+// WithKeyTTL caches keys matching pattern (or below a matching section) for
+// ttl instead of the default; the last matching rule wins
+func WithKeyTTL(pattern string, ttl time.Duration) CacheOption {
+	return func(c *CachedProvider) {
+		c.rules = append(c.rules, cacheRule{pattern: pattern, ttl: ttl})
+	}
+}
+
+// WithStaleWhileRevalidate keeps serving an expired value for up to window
+// while a background fetch refreshes it
+func WithStaleWhileRevalidate(window time.Duration) CacheOption {
+	return func(c *CachedProvider) {
+		c.stale = window
+	}
+}
+
+// WithRefreshTimeout bounds background revalidation fetches; the default is 30s
+func WithRefreshTimeout(d time.Duration) CacheOption {
+	return func(c *CachedProvider) {
+		c.refreshTimeout = d
+	}
+}
+
+// CachedProvider wraps a provider with a read cache so that lookups do not
+// pay a remote round trip each time: values live for a per-key TTL, expired
+// values are served while being revalidated in the background, and changes
+// seen by Watch invalidate the affected keys
+type CachedProvider struct {
+	provider       Provider
+	ttl            time.Duration
+	stale          time.Duration
+	refreshTimeout time.Duration
+	rules          []cacheRule
+
+	mu          sync.Mutex
+	tree        map[string]interface{}
+	treeExpires time.Time
+	treeCall    *cacheCall
+	entries     map[string]*cacheEntry
+	calls       map[string]*cacheCall
+	stats       CacheStats
+}
+
+// NewCachedProvider caches reads from provider for ttl
+func NewCachedProvider(provider Provider, ttl time.Duration, opts ...CacheOption) *CachedProvider {
+	c := &CachedProvider{
+		provider:       provider,
+		ttl:            ttl,
+		refreshTimeout: 30 * time.Second,
+		entries:        make(map[string]*cacheEntry),
+		calls:          make(map[string]*cacheCall),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Name returns the name of the wrapped provider
+func (c *CachedProvider) Name() string {
+	return c.provider.Name()
+}
+
+// Unwrap returns the wrapped provider
+func (c *CachedProvider) Unwrap() Provider {
+	return c.provider
+}
+
+// Load returns the cached tree while it is fresh, serves it stale while
+// revalidating within the stale window, and fetches it otherwise
+func (c *CachedProvider) Load(ctx context.Context) (map[string]interface{}, error) {
+	now := time.Now()
+	c.mu.Lock()
+	if c.tree != nil && now.Before(c.treeExpires) {
+		c.stats.Hits++
+		tree := copyTree(c.tree)
+		c.mu.Unlock()
+		return tree, nil
+	}
+	if c.tree != nil && now.Before(c.treeExpires.Add(c.stale)) {
+		c.stats.Stale++
+		tree := copyTree(c.tree)
+		c.mu.Unlock()
+		c.revalidate(func(ctx context.Context) { c.loadTree(ctx) })
+		return tree, nil
+	}
+	c.stats.Misses++
+	c.mu.Unlock()
+
+	tree, err := c.loadTree(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return copyTree(tree), nil
+}
+
+// Get returns the value under a dotted key following the same fresh, stale
+// and miss rules as Load but with the key's own TTL
+func (c *CachedProvider) Get(ctx context.Context, key string) (interface{}, bool, error) {
+	now := time.Now()
+	c.mu.Lock()
+	if e, ok := c.entries[key]; ok {
+		if now.Before(e.expiresAt) {
+			c.stats.Hits++
+			c.mu.Unlock()
+			return deepCopy(e.value), e.found, nil
+		}
+		if now.Before(e.expiresAt.Add(c.stale)) {
+			c.stats.Stale++
+			c.mu.Unlock()
+			c.revalidate(func(ctx context.Context) { c.loadKey(ctx, key) })
+			return deepCopy(e.value), e.found, nil
+		}
+	}
+	c.stats.Misses++
+	c.mu.Unlock()
+
+	value, found, err := c.loadKey(ctx, key)
+	if err != nil {
+		return nil, false, err
+	}
+	return deepCopy(value), found, nil
+}
+
+// Watch forwards the wrapped provider's updates, invalidating the cached
+// keys each update changes and caching the new tree
+func (c *CachedProvider) Watch(ctx context.Context) (<-chan map[string]interface{}, error) {
+	updates, err := c.provider.Watch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	out := make(chan map[string]interface{}, 1)
+	go func() {
+		defer close(out)
+		for tree := range updates {
+			c.mu.Lock()
+			var changed []string
+			for _, change := range diffTrees(c.tree, tree) {
+				changed = append(changed, change.Key)
+			}
+			c.invalidateLocked(changed)
+			c.storeTreeLocked(tree)
+			c.mu.Unlock()
+
+			select {
+			case out <- tree:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+// Invalidate drops the cached values of keys and everything below them, and
+// the cached tree, so the next read fetches them again
+func (c *CachedProvider) Invalidate(keys ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.invalidateLocked(keys)
+	c.tree = nil
+}
+
+// InvalidateAll empties the cache
+func (c *CachedProvider) InvalidateAll() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.stats.Invalidations += uint64(len(c.entries))
+	c.entries = make(map[string]*cacheEntry)
+	c.tree = nil
+}
+
+// Stats returns the cache counters
+func (c *CachedProvider) Stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stats
+}
+
+// invalidateLocked drops entries at, above or below any of keys
+func (c *CachedProvider) invalidateLocked(keys []string) {
+	for cached := range c.entries {
+		for _, key := range keys {
+			if keyWithin(cached, key) || keyWithin(key, cached) {
+				delete(c.entries, cached)
+				c.stats.Invalidations++
+				break
+			}
+		}
+	}
+}
+
+// ttlFor returns the TTL of key
+func (c *CachedProvider) ttlFor(key string) time.Duration {
+	for i := len(c.rules) - 1; i >= 0; i-- {
+		if matchesAny([]string{c.rules[i].pattern}, key) {
+			return c.rules[i].ttl
+		}
+	}
+	return c.ttl
+}
+
+// storeTreeLocked caches a freshly fetched tree and refreshes the entries
+// it covers
+func (c *CachedProvider) storeTreeLocked(tree map[string]interface{}) {
+	now := time.Now()
+	c.tree = copyTree(tree)
+	c.treeExpires = now.Add(c.ttl)
+	for key, e := range c.entries {
+		e.value, e.found = lookup(c.tree, key)
+		e.expiresAt = now.Add(c.ttlFor(key))
+	}
+}
+
+// storeKeyLocked caches a freshly fetched key
+func (c *CachedProvider) storeKeyLocked(key string, value interface{}, found bool) {
+	c.entries[key] = &cacheEntry{value: deepCopy(value), found: found, expiresAt: time.Now().Add(c.ttlFor(key))}
+}
+
+// loadTree fetches the tree, sharing the fetch with concurrent callers
+func (c *CachedProvider) loadTree(ctx context.Context) (map[string]interface{}, error) {
+	c.mu.Lock()
+	if call := c.treeCall; call != nil {
+		c.mu.Unlock()
+		return waitCall(ctx, call)
+	}
+	call := &cacheCall{done: make(chan struct{})}
+	c.treeCall = call
+	c.mu.Unlock()
+
+	tree, err := c.provider.Load(ctx)
+
+	c.mu.Lock()
+	c.recordLocked(err)
+	if err == nil {
+		c.storeTreeLocked(tree)
+		call.value = c.tree
+	}
+	call.err = err
+	c.treeCall = nil
+	c.mu.Unlock()
+	close(call.done)
+	return waitCall(ctx, call)
+}
+
+// loadKey fetches one key, from a fresh cached tree when there is one, with
+// KeyReader when the provider supports it and from the whole tree otherwise
+func (c *CachedProvider) loadKey(ctx context.Context, key string) (interface{}, bool, error) {
+	c.mu.Lock()
+	if c.tree != nil && time.Now().Before(c.treeExpires) {
+		value, found := lookup(c.tree, key)
+		c.storeKeyLocked(key, value, found)
+		c.mu.Unlock()
+		return value, found, nil
+	}
+	reader, ok := c.provider.(KeyReader)
+	if !ok {
+		c.mu.Unlock()
+		tree, err := c.loadTree(ctx)
+		if err != nil {
+			return nil, false, err
+		}
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		value, found := lookup(tree, key)
+		c.storeKeyLocked(key, value, found)
+		return value, found, nil
+	}
+	if call, ok := c.calls[key]; ok {
+		c.mu.Unlock()
+		if _, err := waitCall(ctx, call); err != nil {
+			return nil, false, err
+		}
+		return call.value, call.found, nil
+	}
+	call := &cacheCall{done: make(chan struct{})}
+	c.calls[key] = call
+	c.mu.Unlock()
+
+	value, found, err := reader.LoadKey(ctx, key)
+
+	c.mu.Lock()
+	c.recordLocked(err)
+	if err == nil {
+		c.storeKeyLocked(key, value, found)
+	}
+	call.value, call.found, call.err = value, found, err
+	delete(c.calls, key)
+	c.mu.Unlock()
+	close(call.done)
+	return value, found, err
+}
+
+// recordLocked counts the outcome of a fetch
+func (c *CachedProvider) recordLocked(err error) {
+	c.stats.Refreshes++
+	if err != nil {
+		c.stats.Errors++
+		c.stats.LastError = err.Error()
+	}
+}
+
+// revalidate runs fetch in the background under the refresh timeout;
+// failures leave the stale value in place and are counted in Stats
+func (c *CachedProvider) revalidate(fetch func(ctx context.Context)) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), c.refreshTimeout)
+		defer cancel()
+		fetch(ctx)
+	}()
+}
+
+// waitCall waits for an in-flight fetch or ctx, whichever ends first
+func waitCall(ctx context.Context, call *cacheCall) (map[string]interface{}, error) {
+	select {
+	case <-call.done:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if call.err != nil {
+		return nil, call.err
+	}
+	tree, _ := call.value.(map[string]interface{})
+	return tree, nil
+}
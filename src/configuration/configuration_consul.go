@@ -0,0 +1,135 @@
+package configuration
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ConsulProvider reads a key prefix from the Consul KV store and watches it
+// with blocking queries
+type ConsulProvider struct {
+	Address string
+	Prefix  string
+	Token   string
+	Client  *http.Client
+	Wait    time.Duration
+}
+
+// NewConsulProvider creates a provider for prefix on the agent at address
+func NewConsulProvider(address, prefix string) *ConsulProvider {
+	return &ConsulProvider{Address: strings.TrimRight(address, "/"), Prefix: strings.Trim(prefix, "/"), Wait: 5 * time.Minute}
+}
+
+// Name returns the provider name
+func (p *ConsulProvider) Name() string {
+	return "consul:" + p.Prefix
+}
+
+// Load fetches the current tree under the prefix
+func (p *ConsulProvider) Load(ctx context.Context) (map[string]interface{}, error) {
+	tree, _, err := p.fetch(ctx, 0)
+	return tree, err
+}
+
+// LoadKey fetches only the entries at or below a dotted key
+func (p *ConsulProvider) LoadKey(ctx context.Context, key string) (interface{}, bool, error) {
+	tree, _, err := p.fetchPath(ctx, p.Prefix+"/"+strings.ReplaceAll(key, ".", "/"), 0)
+	if err != nil {
+		return nil, false, err
+	}
+	value, ok := lookup(tree, key)
+	return value, ok, nil
+}
+
+// Watch emits the tree whenever the Consul index for the prefix advances;
+// a response without an index, e.g. from a proxy that strips it, is retried
+// with backoff because it cannot block
+func (p *ConsulProvider) Watch(ctx context.Context) (<-chan map[string]interface{}, error) {
+	var index uint64
+	return watchLoop(ctx, func(ctx context.Context, emit func(map[string]interface{})) error {
+		for {
+			tree, next, err := p.fetch(ctx, index)
+			if err != nil {
+				return err
+			}
+			if next == 0 {
+				return errors.New("consul: response has no X-Consul-Index")
+			}
+			// A reset index means the KV store was restored; start over
+			if next < index {
+				index = 0
+				continue
+			}
+			if index != 0 && next != index {
+				emit(tree)
+			}
+			index = next
+		}
+	}), nil
+}
+
+// fetch performs a (blocking when index > 0) recursive KV read of the prefix
+func (p *ConsulProvider) fetch(ctx context.Context, index uint64) (map[string]interface{}, uint64, error) {
+	return p.fetchPath(ctx, p.Prefix, index)
+}
+
+// fetchPath performs a recursive KV read of path, returning the tree
+// relative to the prefix
+func (p *ConsulProvider) fetchPath(ctx context.Context, path string, index uint64) (map[string]interface{}, uint64, error) {
+	query := url.Values{"recurse": {"true"}}
+	if index > 0 {
+		query.Set("index", strconv.FormatUint(index, 10))
+		query.Set("wait", fmt.Sprintf("%ds", int(p.Wait.Seconds())))
+	}
+	endpoint := fmt.Sprintf("%s/v1/kv/%s?%s", p.Address, escapeObjectKey(path), query.Encode())
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, 0, err
+	}
+	if p.Token != "" {
+		req.Header.Set("X-Consul-Token", p.Token)
+	}
+
+	client := p.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	next, _ := strconv.ParseUint(resp.Header.Get("X-Consul-Index"), 10, 64)
+	if resp.StatusCode == http.StatusNotFound {
+		return map[string]interface{}{}, next, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, 0, fmt.Errorf("consul: unexpected status %d", resp.StatusCode)
+	}
+
+	var entries []struct {
+		Key   string
+		Value string
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		return nil, 0, fmt.Errorf("consul: decode response: %w", err)
+	}
+	pairs := make(map[string]string, len(entries))
+	for _, e := range entries {
+		value, err := base64.StdEncoding.DecodeString(e.Value)
+		if err != nil {
+			return nil, 0, fmt.Errorf("consul: decode %s: %w", e.Key, err)
+		}
+		pairs[e.Key] = string(value)
+	}
+	return kvTree(p.Prefix, pairs), next, nil
+}
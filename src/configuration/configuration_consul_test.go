@@ -0,0 +1,65 @@
+package configuration
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestConsulWatchBacksOffWithoutIndex(t *testing.T) {
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Write([]byte(`[{"Key": "app/retries", "Value": "Mw=="}]`))
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	updates, err := NewConsulProvider(srv.URL, "app").Watch(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range updates {
+	}
+	if n := requests.Load(); n > 5 {
+		t.Errorf("%d requests in 500ms without an index, want backoff", n)
+	}
+}
+
+func TestConsulWatchBlocksOnIndex(t *testing.T) {
+	var mu sync.Mutex
+	var indexes []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		indexes = append(indexes, r.URL.Query().Get("index"))
+		w.Header().Set("X-Consul-Index", strconv.Itoa(len(indexes)))
+		mu.Unlock()
+		w.Write([]byte(`[{"Key": "app/retries", "Value": "Mw=="}]`))
+	}))
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	updates, err := NewConsulProvider(srv.URL, "app").Watch(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree := <-updates
+	cancel()
+	for range updates {
+	}
+	if tree["retries"] != "3" {
+		t.Errorf("tree = %v", tree)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(indexes) < 2 || indexes[0] != "" || indexes[1] != "1" {
+		t.Errorf("queried with indexes %q, want a plain read then blocking ones", indexes)
+	}
+}
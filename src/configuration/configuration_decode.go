@@ -0,0 +1,246 @@
+package configuration
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// UnknownKeysError lists keys present in a document but not in its target
+// type, with the closest known key for likely typos
+type UnknownKeysError struct {
+	Keys        []string
+	Suggestions map[string]string
+}
+
+// Error implements error
+func (e *UnknownKeysError) Error() string {
+	parts := make([]string, len(e.Keys))
+	for i, key := range e.Keys {
+		parts[i] = key
+		if suggestion, ok := e.Suggestions[key]; ok {
+			parts[i] += fmt.Sprintf(" (did you mean %s?)", suggestion)
+		}
+	}
+	return fmt.Sprintf("unknown configuration keys: %s", strings.Join(parts, ", "))
+}
+
+// Decode unmarshals a generic key tree into v, converting duration strings
+// such as "45s" and textual scalars into the field types of v; in strict
+// mode keys that v does not declare are reported as an UnknownKeysError
+func Decode(tree map[string]interface{}, v interface{}, strict bool) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("decode target must be a non-nil pointer, got %T", v)
+	}
+	target := rv.Elem().Type()
+
+	if strict {
+		if err := unknownKeysError(lintType(tree, target, "")); err != nil {
+			return err
+		}
+	}
+
+	normalized, err := normalize(tree, target, "")
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(normalized)
+	if err != nil {
+		return fmt.Errorf("encode config tree: %w", err)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("decode config: %w", err)
+	}
+	return nil
+}
+
+// fieldKey returns the configuration key of a struct field, or "" when skipped
+func fieldKey(f reflect.StructField) string {
+	if f.PkgPath != "" && !f.Anonymous {
+		return ""
+	}
+	tag := f.Tag.Get("json")
+	if tag == "-" {
+		return ""
+	}
+	if name := strings.Split(tag, ",")[0]; name != "" {
+		return name
+	}
+	return f.Name
+}
+
+// structFields maps lower-cased keys to fields, flattening untagged embedded structs
+func structFields(t reflect.Type) map[string]reflect.StructField {
+	fields := make(map[string]reflect.StructField)
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		ft := indirectType(f.Type)
+		if f.Anonymous && f.Tag.Get("json") == "" && ft.Kind() == reflect.Struct {
+			for k, inner := range structFields(ft) {
+				if _, exists := fields[k]; !exists {
+					fields[k] = inner
+				}
+			}
+			continue
+		}
+		if key := fieldKey(f); key != "" {
+			fields[strings.ToLower(key)] = f
+		}
+	}
+	return fields
+}
+
+// indirectType strips pointer indirections
+func indirectType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+// joinKey builds a dotted key path
+func joinKey(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+// normalize converts a generic value into a JSON-compatible shape matching t
+func normalize(value interface{}, t reflect.Type, path string) (interface{}, error) {
+	t = indirectType(t)
+	if value == nil {
+		return nil, nil
+	}
+
+	if t == durationType {
+		switch v := value.(type) {
+		case string:
+			d, err := ParseDuration(v)
+			if err != nil {
+				return nil, fmt.Errorf("%s: invalid duration %q", path, v)
+			}
+			return int64(d), nil
+		case time.Duration:
+			return int64(v), nil
+		}
+		return value, nil
+	}
+
+	// Text-decoded types such as ByteSize take strings and bare numbers as text
+	if reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		switch v := value.(type) {
+		case string:
+			return v, nil
+		case json.Number, int, int64, uint64, float64:
+			return fmt.Sprint(v), nil
+		}
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		m, ok := value.(map[string]interface{})
+		if !ok || t == reflect.TypeOf(time.Time{}) {
+			return value, nil
+		}
+		fields := structFields(t)
+		out := make(map[string]interface{}, len(m))
+		for k, v := range m {
+			f, ok := fields[strings.ToLower(k)]
+			if !ok {
+				continue
+			}
+			nv, err := normalize(v, f.Type, joinKey(path, k))
+			if err != nil {
+				return nil, err
+			}
+			out[k] = nv
+		}
+		return out, nil
+	case reflect.Map:
+		m, ok := value.(map[string]interface{})
+		if !ok {
+			return value, nil
+		}
+		out := make(map[string]interface{}, len(m))
+		for k, v := range m {
+			nv, err := normalize(v, t.Elem(), joinKey(path, k))
+			if err != nil {
+				return nil, err
+			}
+			out[k] = nv
+		}
+		return out, nil
+	case reflect.Slice, reflect.Array:
+		s, ok := value.([]interface{})
+		if !ok {
+			if str, isStr := value.(string); isStr && t.Elem().Kind() == reflect.String {
+				return splitList(str), nil
+			}
+			return value, nil
+		}
+		out := make([]interface{}, len(s))
+		for i, v := range s {
+			nv, err := normalize(v, t.Elem(), fmt.Sprintf("%s[%d]", path, i))
+			if err != nil {
+				return nil, err
+			}
+			out[i] = nv
+		}
+		return out, nil
+	case reflect.Bool:
+		if s, ok := value.(string); ok {
+			b, err := strconv.ParseBool(s)
+			if err != nil {
+				return nil, fmt.Errorf("%s: invalid boolean %q", path, s)
+			}
+			return b, nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if s, ok := value.(string); ok {
+			n, err := strconv.ParseInt(strings.TrimSpace(s), 0, 64)
+			if err != nil {
+				return nil, fmt.Errorf("%s: invalid integer %q", path, s)
+			}
+			return n, nil
+		}
+	case reflect.Float32, reflect.Float64:
+		if s, ok := value.(string); ok {
+			f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+			if err != nil {
+				return nil, fmt.Errorf("%s: invalid number %q", path, s)
+			}
+			return f, nil
+		}
+	case reflect.String:
+		switch v := value.(type) {
+		case string:
+			return v, nil
+		case json.Number:
+			return v.String(), nil
+		case bool, int, int64, float64:
+			return fmt.Sprint(v), nil
+		}
+	}
+	return value, nil
+}
+
+// splitList splits a comma separated string into trimmed items
+func splitList(s string) []interface{} {
+	if strings.TrimSpace(s) == "" {
+		return []interface{}{}
+	}
+	parts := strings.Split(s, ",")
+	out := make([]interface{}, len(parts))
+	for i, p := range parts {
+		out[i] = strings.TrimSpace(p)
+	}
+	return out
+}
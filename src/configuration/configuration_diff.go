@@ -0,0 +1,74 @@
+package configuration
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// Diff lists the differences between two configurations, ordered by key;
+// a nil configuration compares as empty
+func Diff(old, new *Config) []Change {
+	return diffTrees(structTree(old), structTree(new))
+}
+
+// DiffTrees lists the leaf differences between two key trees, ordered by key
+func DiffTrees(old, new map[string]interface{}) []Change {
+	return diffTrees(old, new)
+}
+
+// String renders a change on one line, e.g. "~ timeout: 30s -> 1m0s"
+func (c Change) String() string {
+	switch c.Kind {
+	case ChangeAdded:
+		return fmt.Sprintf("+ %s: %s", c.Key, changeValue(c.New))
+	case ChangeRemoved:
+		return fmt.Sprintf("- %s: %s", c.Key, changeValue(c.Old))
+	default:
+		return fmt.Sprintf("~ %s: %s -> %s", c.Key, changeValue(c.Old), changeValue(c.New))
+	}
+}
+
+// changeValue renders a value compactly; strings are quoted so that empty
+// and whitespace values stay visible
+func changeValue(v interface{}) string {
+	data, err := json.Marshal(plainValue(v))
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(data)
+}
+
+// FormatChanges renders changes one per line, or "no changes"
+func FormatChanges(changes []Change) string {
+	if len(changes) == 0 {
+		return "no changes\n"
+	}
+	var b strings.Builder
+	for _, c := range changes {
+		b.WriteString(c.String())
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+// RedactChanges returns changes with the values of sensitive keys masked
+func (m *Manager) RedactChanges(changes []Change) []Change {
+	m.mu.RLock()
+	rules := m.sensitiveLocked()
+	m.mu.RUnlock()
+
+	out := make([]Change, len(changes))
+	for i, c := range changes {
+		if strategy, ok := maskFor(rules, c.Key); ok {
+			if c.Old != nil {
+				c.Old = MaskValue(c.Old, strategy)
+			}
+			if c.New != nil {
+				c.New = MaskValue(c.New, strategy)
+			}
+		}
+		out[i] = c
+	}
+	return out
+}
@@ -0,0 +1,158 @@
+package configuration
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// ParseDotenv parses a .env document into variables. It accepts an optional
+// "export " prefix, # comments, unquoted values (trailing " #" comments are
+// dropped), single-quoted literal values and double-quoted values with
+// \n, \t, \" and \\ escapes; quoted values may span several lines
+func ParseDotenv(data []byte) (map[string]string, error) {
+	vars := make(map[string]string)
+	src := strings.ReplaceAll(string(data), "\r\n", "\n")
+	line := 1
+	for len(src) > 0 {
+		var raw string
+		raw, src, _ = strings.Cut(src, "\n")
+		start := line
+		line++
+		text := strings.TrimSpace(raw)
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		text = strings.TrimPrefix(text, "export ")
+		name, value, ok := strings.Cut(text, "=")
+		name = strings.TrimSpace(name)
+		if !ok || !validEnvName(name) {
+			return nil, fmt.Errorf("dotenv line %d: expected NAME=value", start)
+		}
+		value = strings.TrimLeft(value, " \t")
+
+		if value != "" && (value[0] == '"' || value[0] == '\'') {
+			quote := value[0]
+			body := value[1:]
+			// Quoted values continue over following lines until the
+			// closing quote
+			for {
+				if end := closingQuote(body, quote); end >= 0 {
+					rest := strings.TrimSpace(body[end+1:])
+					if rest != "" && !strings.HasPrefix(rest, "#") {
+						return nil, fmt.Errorf("dotenv line %d: unexpected text after closing quote", start)
+					}
+					body = body[:end]
+					break
+				}
+				if src == "" {
+					return nil, fmt.Errorf("dotenv line %d: unterminated quoted value", start)
+				}
+				var next string
+				next, src, _ = strings.Cut(src, "\n")
+				body += "\n" + next
+				line++
+			}
+			if quote == '"' {
+				body = unescapeDotenv(body)
+			}
+			vars[name] = body
+			continue
+		}
+
+		if i := strings.Index(value, " #"); i >= 0 {
+			value = value[:i]
+		}
+		vars[name] = strings.TrimSpace(value)
+	}
+	return vars, nil
+}
+
+// closingQuote returns the index of the unescaped closing quote in s, or -1
+func closingQuote(s string, quote byte) int {
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '\\' && quote == '"':
+			i++
+		case s[i] == quote:
+			return i
+		}
+	}
+	return -1
+}
+
+// unescapeDotenv expands the escapes allowed in double-quoted values
+func unescapeDotenv(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 'n':
+			b.WriteByte('\n')
+		case 't':
+			b.WriteByte('\t')
+		case 'r':
+			b.WriteByte('\r')
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+// validEnvName reports whether name is a usable variable name
+func validEnvName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, r := range name {
+		if r != '_' && !unicode.IsLetter(r) && (i == 0 || !unicode.IsDigit(r)) {
+			return false
+		}
+	}
+	return true
+}
+
+// dotenvEnviron renders variables as sorted "NAME=value" entries
+func dotenvEnviron(vars map[string]string) []string {
+	environ := make([]string, 0, len(vars))
+	for name, value := range vars {
+		environ = append(environ, name+"="+value)
+	}
+	sort.Strings(environ)
+	return environ
+}
+
+// LoadDotenv sets the dotenv layer from the variables in the .env file at
+// path that carry prefix, mapped to keys the same way as ApplyEnv. By
+// default real environment variables take precedence over the file; see
+// PreferDotenv
+func (m *Manager) LoadDotenv(path, prefix string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read dotenv: %w", err)
+	}
+	vars, err := ParseDotenv(data)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if err := m.SetLayer(LayerDotenv, "dotenv:"+path, EnvTree(prefix, dotenvEnviron(vars))); err != nil {
+		m.logger.Printf("Dotenv overlay failed: %v", err)
+		return fmt.Errorf("dotenv overlay: %w", err)
+	}
+	m.logger.Printf("Applied dotenv file %s with prefix %s", path, prefix)
+	return nil
+}
+
+// PreferDotenv controls whether the dotenv layer overrides the environment
+// layer (true) or sits below it (false, the default)
+func (m *Manager) PreferDotenv(prefer bool) error {
+	m.layers.PreferDotenv(prefer)
+	return m.refresh()
+}
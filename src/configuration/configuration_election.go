@@ -0,0 +1,268 @@
+package configuration
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Elector decides which instance of a fleet is the leader
+type Elector interface {
+	// Run campaigns for leadership on behalf of self until ctx is cancelled
+	// and emits the address of the current leader whenever it changes; ""
+	// means no leader is known. The channel is closed when ctx ends
+	Run(ctx context.Context, self string) <-chan string
+}
+
+// StaticElector names a fixed leader, e.g. in small fleets where one
+// instance is designated by deployment configuration
+type StaticElector struct {
+	Leader string
+}
+
+// Run emits the configured leader once
+func (e StaticElector) Run(ctx context.Context, self string) <-chan string {
+	out := make(chan string, 1)
+	out <- e.Leader
+	go func() {
+		<-ctx.Done()
+		close(out)
+	}()
+	return out
+}
+
+// Session limits enforced by Consul, and the delay before a released lock
+// can be taken again
+const (
+	consulDefaultTTL = 15 * time.Second
+	consulMinTTL     = 10 * time.Second
+	consulMaxTTL     = 24 * time.Hour
+	consulLockDelay  = time.Second
+)
+
+// ConsulElector elects a leader with a Consul session lock on Key; the lock
+// holder stores its address as the key's value
+type ConsulElector struct {
+	Address string
+	Key     string
+	Token   string
+	// TTL is the session TTL; zero means 15s and other values are clamped
+	// to the 10s to 24h range Consul accepts
+	TTL    time.Duration
+	Client *http.Client
+}
+
+// NewConsulElector creates an elector using key on the agent at address
+func NewConsulElector(address, key string) *ConsulElector {
+	return &ConsulElector{Address: strings.TrimRight(address, "/"), Key: strings.Trim(key, "/"), TTL: consulDefaultTTL}
+}
+
+// sessionTTL returns TTL defaulted and clamped to what Consul accepts
+func (e *ConsulElector) sessionTTL() time.Duration {
+	switch {
+	case e.TTL <= 0:
+		return consulDefaultTTL
+	case e.TTL < consulMinTTL:
+		return consulMinTTL
+	case e.TTL > consulMaxTTL:
+		return consulMaxTTL
+	}
+	return e.TTL
+}
+
+// Run holds a session, tries to acquire the lock whenever it is free and
+// follows the key to learn the current leader
+func (e *ConsulElector) Run(ctx context.Context, self string) <-chan string {
+	out := make(chan string, 1)
+	ttl := e.sessionTTL()
+	go func() {
+		defer close(out)
+		last := "\x00"
+		report := func(leader string) {
+			if leader == last {
+				return
+			}
+			last = leader
+			select {
+			case out <- leader:
+			case <-ctx.Done():
+			}
+		}
+
+		delay := 100 * time.Millisecond
+		for ctx.Err() == nil {
+			if err := e.session(ctx, self, ttl, report); err != nil && ctx.Err() == nil {
+				report("")
+				select {
+				case <-ctx.Done():
+				case <-time.After(delay):
+				}
+				delay = backoff(delay, ttl)
+				continue
+			}
+			delay = 100 * time.Millisecond
+		}
+	}()
+	return out
+}
+
+// session creates a Consul session and campaigns with it until it is lost
+func (e *ConsulElector) session(ctx context.Context, self string, ttl time.Duration, report func(string)) error {
+	var created struct{ ID string }
+	body := map[string]string{
+		"TTL":       fmt.Sprintf("%ds", int(ttl.Seconds())),
+		"Behavior":  "delete",
+		"LockDelay": fmt.Sprintf("%ds", int(consulLockDelay.Seconds())),
+	}
+	if err := e.call(ctx, http.MethodPut, "/v1/session/create", nil, body, &created); err != nil {
+		return err
+	}
+	defer func() {
+		// Release on the way out so another instance can take over at once
+		release, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = e.call(release, http.MethodPut, "/v1/session/destroy/"+created.ID, nil, nil, nil)
+	}()
+
+	renewErr := make(chan error, 1)
+	sessionCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go func() {
+		ticker := time.NewTicker(ttl / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-sessionCtx.Done():
+				return
+			case <-ticker.C:
+				if err := e.call(sessionCtx, http.MethodPut, "/v1/session/renew/"+created.ID, nil, nil, nil); err != nil {
+					renewErr <- err
+					cancel()
+					return
+				}
+			}
+		}
+	}()
+
+	var index uint64
+	for {
+		leader, next, err := e.leader(sessionCtx, index, ttl)
+		if err != nil {
+			select {
+			case err = <-renewErr:
+			default:
+			}
+			return err
+		}
+		if leader == "" {
+			var acquired bool
+			query := url.Values{"acquire": {created.ID}}
+			if err := e.call(sessionCtx, http.MethodPut, "/v1/kv/"+e.Key, query, self, &acquired); err != nil {
+				return err
+			}
+			if acquired {
+				leader = self
+			}
+		}
+		report(leader)
+		index = next
+		if leader == "" {
+			// The lock is free but refused, e.g. within the lock delay of
+			// the last holder; re-read it without blocking after a pause
+			index = 0
+			select {
+			case <-sessionCtx.Done():
+			case <-time.After(consulLockDelay):
+			}
+		}
+	}
+}
+
+// leader reads the lock key, blocking for up to wait until it changes when
+// index > 0
+func (e *ConsulElector) leader(ctx context.Context, index uint64, wait time.Duration) (string, uint64, error) {
+	query := url.Values{}
+	if index > 0 {
+		query.Set("index", strconv.FormatUint(index, 10))
+		query.Set("wait", fmt.Sprintf("%ds", int(wait.Seconds())))
+	}
+	var entries []struct {
+		Value   string
+		Session string
+	}
+	next, err := e.do(ctx, http.MethodGet, "/v1/kv/"+e.Key, query, nil, &entries)
+	if err != nil {
+		return "", 0, err
+	}
+	if len(entries) == 0 || entries[0].Session == "" {
+		return "", next, nil
+	}
+	value, err := base64.StdEncoding.DecodeString(entries[0].Value)
+	if err != nil {
+		return "", 0, fmt.Errorf("consul: decode leader: %w", err)
+	}
+	return string(value), next, nil
+}
+
+// call performs a Consul API request, ignoring the returned index
+func (e *ConsulElector) call(ctx context.Context, method, path string, query url.Values, body, out interface{}) error {
+	_, err := e.do(ctx, method, path, query, body, out)
+	return err
+}
+
+// do performs a Consul API request and decodes the JSON response into out;
+// a string body is sent as is
+func (e *ConsulElector) do(ctx context.Context, method, path string, query url.Values, body, out interface{}) (uint64, error) {
+	var payload []byte
+	switch b := body.(type) {
+	case nil:
+	case string:
+		payload = []byte(b)
+	default:
+		var err error
+		if payload, err = json.Marshal(b); err != nil {
+			return 0, err
+		}
+	}
+	endpoint := e.Address + path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+	if e.Token != "" {
+		req.Header.Set("X-Consul-Token", e.Token)
+	}
+	client := e.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	next, _ := strconv.ParseUint(resp.Header.Get("X-Consul-Index"), 10, 64)
+	if resp.StatusCode == http.StatusNotFound && method == http.MethodGet {
+		return next, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("consul: unexpected status %d", resp.StatusCode)
+	}
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			return 0, fmt.Errorf("consul: decode response: %w", err)
+		}
+	}
+	return next, nil
+}
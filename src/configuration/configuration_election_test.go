@@ -0,0 +1,86 @@
+package configuration
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeConsul serves a session API and a lock key that is never acquired,
+// as during the lock delay of a previous holder
+type fakeConsul struct {
+	mu       sync.Mutex
+	ttls     []string
+	lockGets int
+}
+
+func (c *fakeConsul) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	w.Header().Set("X-Consul-Index", "7")
+	switch {
+	case strings.HasPrefix(r.URL.Path, "/v1/session/create"):
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		c.ttls = append(c.ttls, body["TTL"])
+		w.Write([]byte(`{"ID": "session"}`))
+	case strings.HasPrefix(r.URL.Path, "/v1/session/"):
+		w.Write([]byte(`{}`))
+	case r.Method == http.MethodGet:
+		c.lockGets++
+		w.WriteHeader(http.StatusNotFound)
+	default:
+		w.Write([]byte(`false`))
+	}
+}
+
+func TestConsulElectorSessionTTL(t *testing.T) {
+	for _, tc := range []struct {
+		ttl  time.Duration
+		want string
+	}{
+		{0, "15s"},
+		{2 * time.Second, "10s"},
+		{30 * time.Second, "30s"},
+		{48 * time.Hour, "86400s"},
+	} {
+		consul := &fakeConsul{}
+		srv := httptest.NewServer(consul)
+		ctx, cancel := context.WithCancel(context.Background())
+		elector := &ConsulElector{Address: srv.URL, Key: "leader", TTL: tc.ttl}
+		leaders := elector.Run(ctx, "self:1")
+		<-leaders
+		cancel()
+		for range leaders {
+		}
+		srv.Close()
+
+		consul.mu.Lock()
+		if len(consul.ttls) == 0 || consul.ttls[0] != tc.want {
+			t.Errorf("TTL %v: sessions created with %v, want %s", tc.ttl, consul.ttls, tc.want)
+		}
+		consul.mu.Unlock()
+	}
+}
+
+func TestConsulElectorWaitsAfterRefusedLock(t *testing.T) {
+	consul := &fakeConsul{}
+	srv := httptest.NewServer(consul)
+	defer srv.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	leaders := (&ConsulElector{Address: srv.URL, Key: "leader"}).Run(ctx, "self:1")
+	for range leaders {
+	}
+
+	consul.mu.Lock()
+	defer consul.mu.Unlock()
+	if consul.lockGets > 2 {
+		t.Errorf("read the refused lock %d times in 500ms", consul.lockGets)
+	}
+}
@@ -0,0 +1,283 @@
+package configuration
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// DefaultKeyEnv is the environment variable read for the data-key wrapping
+// key when no KeyWrapper is supplied
+const DefaultKeyEnv = "CONFIG_ENCRYPTION_KEY"
+
+// encryptedHeader marks an encrypted configuration envelope
+var encryptedHeader = []byte("# encrypted-config v1\n")
+
+// ErrNoDecryptionKey is returned when an encrypted file is loaded without a key
+var ErrNoDecryptionKey = errors.New("encrypted configuration requires a key")
+
+// KeyWrapper wraps and unwraps per-file data keys, typically backed by an
+// environment key or a KMS
+type KeyWrapper interface {
+	KeyID() string
+	Wrap(ctx context.Context, dataKey []byte) ([]byte, error)
+	Unwrap(ctx context.Context, keyID string, wrapped []byte) ([]byte, error)
+}
+
+// AESKeyWrapper wraps data keys with a static AES-256 key using AES-GCM
+type AESKeyWrapper struct {
+	ID  string
+	key []byte
+}
+
+// NewAESKeyWrapper creates a wrapper from a 32 byte key
+func NewAESKeyWrapper(id string, key []byte) (*AESKeyWrapper, error) {
+	if len(key) != 32 {
+		return nil, fmt.Errorf("wrapping key must be 32 bytes, got %d", len(key))
+	}
+	return &AESKeyWrapper{ID: id, key: append([]byte(nil), key...)}, nil
+}
+
+// NewEnvKeyWrapper creates a wrapper from a base64 key in the named variable
+func NewEnvKeyWrapper(name string) (*AESKeyWrapper, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s is not set", ErrNoDecryptionKey, name)
+	}
+	key, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return NewAESKeyWrapper("env:"+name, key)
+}
+
+// KeyID returns the wrapper identifier stored in envelopes
+func (w *AESKeyWrapper) KeyID() string {
+	return w.ID
+}
+
+// Wrap encrypts a data key
+func (w *AESKeyWrapper) Wrap(ctx context.Context, dataKey []byte) ([]byte, error) {
+	nonce, sealed, err := seal(w.key, dataKey)
+	if err != nil {
+		return nil, err
+	}
+	return append(nonce, sealed...), nil
+}
+
+// Unwrap decrypts a data key wrapped by the same key
+func (w *AESKeyWrapper) Unwrap(ctx context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	if keyID != w.ID {
+		return nil, fmt.Errorf("data key was wrapped by %q, not %q", keyID, w.ID)
+	}
+	if len(wrapped) < gcmNonceSize {
+		return nil, errors.New("wrapped data key is truncated")
+	}
+	return open(w.key, wrapped[:gcmNonceSize], wrapped[gcmNonceSize:])
+}
+
+// gcmNonceSize is the standard AES-GCM nonce length prefixed to wrapped keys
+const gcmNonceSize = 12
+
+// seal encrypts plaintext with AES-GCM under key and a random nonce
+func seal(key, plaintext []byte) (nonce, ciphertext []byte, err error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, nil, err
+	}
+	nonce = make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, nil, err
+	}
+	return nonce, gcm.Seal(nil, nonce, plaintext, nil), nil
+}
+
+// open decrypts AES-GCM ciphertext; a nonce of the wrong length is an
+// error rather than a panic in crypto/cipher
+func open(key, nonce, ciphertext []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	if len(nonce) != gcm.NonceSize() {
+		return nil, fmt.Errorf("nonce is %d bytes, want %d", len(nonce), gcm.NonceSize())
+	}
+	return gcm.Open(nil, nonce, ciphertext, nil)
+}
+
+// envelope is the on-disk form of an encrypted configuration
+type envelope struct {
+	KeyID      string `json:"key_id"`
+	DataKey    []byte `json:"data_key"`
+	Nonce      []byte `json:"nonce"`
+	Format     Format `json:"format"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// IsEncrypted reports whether data is an encrypted configuration envelope
+func IsEncrypted(data []byte) bool {
+	return bytes.HasPrefix(data, encryptedHeader)
+}
+
+// Encrypt seals a plaintext document of the given format with a fresh data
+// key wrapped by keys
+func Encrypt(ctx context.Context, plaintext []byte, format Format, keys KeyWrapper) ([]byte, error) {
+	if format == FormatAuto {
+		format = DetectFormat(plaintext)
+	}
+	dataKey := make([]byte, 32)
+	if _, err := rand.Read(dataKey); err != nil {
+		return nil, err
+	}
+	nonce, ciphertext, err := seal(dataKey, plaintext)
+	if err != nil {
+		return nil, err
+	}
+	wrapped, err := keys.Wrap(ctx, dataKey)
+	if err != nil {
+		return nil, fmt.Errorf("wrap data key: %w", err)
+	}
+
+	body, err := json.MarshalIndent(envelope{
+		KeyID:      keys.KeyID(),
+		DataKey:    wrapped,
+		Nonce:      nonce,
+		Format:     format,
+		Ciphertext: ciphertext,
+	}, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append(append([]byte(nil), encryptedHeader...), body...), nil
+}
+
+// Decrypt opens an encrypted envelope and returns the plaintext and its format
+func Decrypt(ctx context.Context, data []byte, keys KeyWrapper) ([]byte, Format, error) {
+	var env envelope
+	if err := json.Unmarshal(bytes.TrimPrefix(data, encryptedHeader), &env); err != nil {
+		return nil, "", fmt.Errorf("decode encrypted envelope: %w", err)
+	}
+	dataKey, err := keys.Unwrap(ctx, env.KeyID, env.DataKey)
+	if err != nil {
+		return nil, "", fmt.Errorf("unwrap data key: %w", err)
+	}
+	plaintext, err := open(dataKey, env.Nonce, env.Ciphertext)
+	if err != nil {
+		return nil, "", fmt.Errorf("decrypt configuration: %w", err)
+	}
+	return plaintext, env.Format, nil
+}
+
+// WithKeyWrapper sets the key used to decrypt encrypted files; without it
+// DefaultKeyEnv is consulted
+func WithKeyWrapper(keys KeyWrapper) LoadOption {
+	return func(o *loadOptions) {
+		o.keys = keys
+	}
+}
+
+// SetKeyWrapper makes Backup encrypt the documents it stores and LoadFile
+// and restores decrypt with keys unless a call supplies its own
+func (m *Manager) SetKeyWrapper(keys KeyWrapper) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.keyWrapper = keys
+}
+
+// decryptIfNeeded transparently opens encrypted input
+func decryptIfNeeded(data []byte, options *loadOptions) ([]byte, error) {
+	if !IsEncrypted(data) {
+		return data, nil
+	}
+	keys := options.keys
+	if keys == nil {
+		envKeys, err := NewEnvKeyWrapper(DefaultKeyEnv)
+		if err != nil {
+			return nil, err
+		}
+		keys = envKeys
+	}
+	plaintext, format, err := Decrypt(context.Background(), data, keys)
+	if err != nil {
+		return nil, err
+	}
+	if options.format == FormatAuto {
+		options.format = format
+	}
+	return plaintext, nil
+}
+
+// EncryptFile encrypts the plaintext file at path in place
+func EncryptFile(ctx context.Context, path string, keys KeyWrapper) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	if IsEncrypted(data) {
+		return fmt.Errorf("%s is already encrypted", path)
+	}
+	sealed, err := Encrypt(ctx, data, FormatFromPath(path), keys)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, sealed, 0o600)
+}
+
+// RotateFile re-encrypts an encrypted file in place under a new key
+func RotateFile(ctx context.Context, path string, from, to KeyWrapper) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	plaintext, format, err := Decrypt(ctx, data, from)
+	if err != nil {
+		return err
+	}
+	sealed, err := Encrypt(ctx, plaintext, format, to)
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, sealed, 0o600)
+}
+
+// writeFileAtomic replaces path via a temporary file and rename
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
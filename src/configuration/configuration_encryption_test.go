@@ -0,0 +1,66 @@
+package configuration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+)
+
+// testWrapper returns a wrapper with a key filled with b
+func testWrapper(t *testing.T, id string, b byte) *AESKeyWrapper {
+	t.Helper()
+	keys, err := NewAESKeyWrapper(id, bytes.Repeat([]byte{b}, 32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keys
+}
+
+// tamper rewrites the envelope of an encrypted document with edit
+func tamper(t *testing.T, data []byte, edit func(env *envelope)) []byte {
+	t.Helper()
+	var env envelope
+	if err := json.Unmarshal(bytes.TrimPrefix(data, encryptedHeader), &env); err != nil {
+		t.Fatal(err)
+	}
+	edit(&env)
+	body, err := json.Marshal(env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return append(append([]byte(nil), encryptedHeader...), body...)
+}
+
+func TestDecrypt(t *testing.T) {
+	ctx := context.Background()
+	keys := testWrapper(t, "test", 1)
+	sealed, err := Encrypt(ctx, []byte(`{"port": 8080}`), FormatJSON, keys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plaintext, format, err := Decrypt(ctx, sealed, keys)
+	if err != nil || string(plaintext) != `{"port": 8080}` || format != FormatJSON {
+		t.Fatalf("Decrypt = %q, %q, %v", plaintext, format, err)
+	}
+
+	for name, data := range map[string][]byte{
+		"short nonce":    tamper(t, sealed, func(env *envelope) { env.Nonce = env.Nonce[:4] }),
+		"missing nonce":  tamper(t, sealed, func(env *envelope) { env.Nonce = nil }),
+		"long nonce":     tamper(t, sealed, func(env *envelope) { env.Nonce = append(env.Nonce, 0) }),
+		"short data key": tamper(t, sealed, func(env *envelope) { env.DataKey = env.DataKey[:8] }),
+		"flipped data":   tamper(t, sealed, func(env *envelope) { env.Ciphertext[0] ^= 1 }),
+		"short data":     tamper(t, sealed, func(env *envelope) { env.Ciphertext = env.Ciphertext[:3] }),
+		"truncated":      sealed[:len(sealed)/2],
+		"header only":    encryptedHeader,
+		"other key id":   tamper(t, sealed, func(env *envelope) { env.KeyID = "other" }),
+	} {
+		if _, _, err := Decrypt(ctx, data, keys); err == nil {
+			t.Errorf("%s: Decrypt succeeded", name)
+		}
+	}
+
+	if _, _, err := Decrypt(ctx, sealed, testWrapper(t, "test", 2)); err == nil {
+		t.Error("Decrypt with the wrong key succeeded")
+	}
+}
@@ -0,0 +1,77 @@
+package configuration
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// EnvSeparator separates nesting levels in environment variable names, so
+// APP_DATABASE__HOST maps to the key database.host
+const EnvSeparator = "__"
+
+// EnvKey converts an environment variable name into a dotted configuration
+// key, reporting false when the name does not carry the prefix
+func EnvKey(prefix, name string) (string, bool) {
+	if prefix != "" {
+		p := strings.ToUpper(prefix)
+		if !strings.HasSuffix(p, "_") {
+			p += "_"
+		}
+		if !strings.HasPrefix(strings.ToUpper(name), p) {
+			return "", false
+		}
+		name = name[len(p):]
+	}
+	if name == "" {
+		return "", false
+	}
+	parts := strings.Split(strings.ToLower(name), EnvSeparator)
+	for _, part := range parts {
+		if part == "" {
+			return "", false
+		}
+	}
+	return strings.Join(parts, "."), true
+}
+
+// EnvTree builds a key tree from environ entries ("NAME=value") that carry
+// prefix; values stay strings and are coerced when decoded
+func EnvTree(prefix string, environ []string) map[string]interface{} {
+	tree := make(map[string]interface{})
+	for _, entry := range environ {
+		name, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			continue
+		}
+		if key, ok := EnvKey(prefix, name); ok {
+			setPath(tree, key, value)
+		}
+	}
+	return tree
+}
+
+// BindEnv overlays environment variables carrying prefix onto v, e.g.
+// APP_TIMEOUT=45s sets the timeout field of v; fields without a matching
+// variable keep their current values
+func BindEnv(v interface{}, prefix string) error {
+	tree := EnvTree(prefix, os.Environ())
+	if len(tree) == 0 {
+		return nil
+	}
+	if err := Decode(tree, v, false); err != nil {
+		return fmt.Errorf("environment overlay: %w", err)
+	}
+	return nil
+}
+
+// ApplyEnv sets the environment layer of the manager configuration from
+// variables carrying prefix
+func (m *Manager) ApplyEnv(prefix string) error {
+	if err := m.SetLayer(LayerEnv, "env:"+prefix, EnvTree(prefix, os.Environ())); err != nil {
+		m.logger.Printf("Environment overlay failed: %v", err)
+		return fmt.Errorf("environment overlay: %w", err)
+	}
+	m.logger.Printf("Applied environment overlay with prefix %s", prefix)
+	return nil
+}
@@ -0,0 +1,109 @@
+package configuration
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestEnvKey(t *testing.T) {
+	tests := []struct {
+		prefix, name string
+		want         string
+		ok           bool
+	}{
+		{"APP", "APP_TIMEOUT", "timeout", true},
+		{"APP_", "APP_TIMEOUT", "timeout", true},
+		{"app", "APP_TIMEOUT", "timeout", true},
+		{"APP", "app_timeout", "timeout", true},
+		{"APP", "APP_DATABASE__HOST", "database.host", true},
+		{"APP", "APP_DB__POOL__MAX_IDLE", "db.pool.max_idle", true},
+		{"APP", "APP_LOG_LEVEL", "log_level", true},
+		{"", "TIMEOUT", "timeout", true},
+		{"APP", "OTHER_TIMEOUT", "", false},
+		{"APP", "APPLICATION", "", false},
+		{"APP", "APP_", "", false},
+		{"APP", "APP", "", false},
+		{"APP", "APP_DB____HOST", "", false},
+		{"APP", "APP___HOST", "", false},
+		{"APP", "APP_DB__", "", false},
+		{"", "", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := EnvKey(tt.prefix, tt.name)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("EnvKey(%q, %q) = %q, %v; want %q, %v", tt.prefix, tt.name, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestEnvTree(t *testing.T) {
+	environ := []string{
+		"APP_TIMEOUT=45s",
+		"APP_DATABASE__HOST=db.internal",
+		"APP_DATABASE__PORT=5432",
+		"APP_DSN=user=app host=db",
+		"APP_EMPTY=",
+		"HOME=/root",
+		"MALFORMED",
+	}
+	want := map[string]interface{}{
+		"timeout":  "45s",
+		"database": map[string]interface{}{"host": "db.internal", "port": "5432"},
+		"dsn":      "user=app host=db",
+		"empty":    "",
+	}
+	if got := EnvTree("APP", environ); !reflect.DeepEqual(got, want) {
+		t.Errorf("EnvTree = %v, want %v", got, want)
+	}
+	if got := EnvTree("NONE", environ); len(got) != 0 {
+		t.Errorf("EnvTree without matches = %v, want empty", got)
+	}
+}
+
+func TestBindEnv(t *testing.T) {
+	t.Setenv("TESTAPP_TIMEOUT", "45s")
+	t.Setenv("TESTAPP_RETRIES", "7")
+	t.Setenv("TESTAPP_DEBUG", "true")
+	t.Setenv("TESTAPP_HOSTS", "a, b")
+	t.Setenv("TESTAPP_DB__MAX_IDLE", "2m")
+
+	got := loadTarget{Name: "kept", Ratio: 0.25}
+	if err := BindEnv(&got, "TESTAPP"); err != nil {
+		t.Fatal(err)
+	}
+	want := loadTarget{Name: "kept", Timeout: 45 * time.Second, Retries: 7, Ratio: 0.25, Debug: true, Hosts: []string{"a", "b"}}
+	want.DB.MaxIdle = 2 * time.Minute
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("BindEnv = %+v, want %+v", got, want)
+	}
+
+	t.Setenv("TESTAPP_RETRIES", "many")
+	if err := BindEnv(&got, "TESTAPP"); err == nil {
+		t.Error("BindEnv accepted an invalid integer")
+	}
+}
+
+func TestApplyEnv(t *testing.T) {
+	t.Setenv("TESTAPP_TIMEOUT", "45s")
+	t.Setenv("TESTAPP_RETRIES", "7")
+	m := NewManager(nil)
+	if err := m.ApplyEnv("TESTAPP"); err != nil {
+		t.Fatal(err)
+	}
+	config := m.GetConfig()
+	if config.Timeout != 45*time.Second || config.Retries != 7 {
+		t.Errorf("config = %+v, want timeout 45s and 7 retries", config)
+	}
+	if origin, err := m.Explain("retries"); err != nil || origin.Layer != LayerEnv {
+		t.Errorf("Explain(retries) = %+v, %v; want the env layer", origin, err)
+	}
+
+	t.Setenv("TESTAPP_RETRIES", "many")
+	if err := m.ApplyEnv("TESTAPP"); err == nil {
+		t.Error("ApplyEnv accepted an invalid integer")
+	}
+	if got := m.GetConfig().Retries; got != 7 {
+		t.Errorf("retries after a rejected overlay = %d, want 7", got)
+	}
+}
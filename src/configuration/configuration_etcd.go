@@ -0,0 +1,166 @@
+package configuration
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// EtcdProvider reads a key prefix from etcd v3 through its JSON gateway and
+// watches it with a streaming watch request
+type EtcdProvider struct {
+	Endpoint string
+	Prefix   string
+	Client   *http.Client
+}
+
+// NewEtcdProvider creates a provider for prefix on the etcd endpoint
+func NewEtcdProvider(endpoint, prefix string) *EtcdProvider {
+	return &EtcdProvider{Endpoint: strings.TrimRight(endpoint, "/"), Prefix: prefix}
+}
+
+// Name returns the provider name
+func (p *EtcdProvider) Name() string {
+	return "etcd:" + p.Prefix
+}
+
+// etcdKV is a key/value pair as returned by the gateway
+type etcdKV struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// rangeRequest returns the base64 key and range end covering the prefix
+func (p *EtcdProvider) rangeRequest() map[string]interface{} {
+	end := []byte(p.Prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			end = end[:i+1]
+			break
+		}
+	}
+	enc := base64.StdEncoding
+	return map[string]interface{}{
+		"key":       enc.EncodeToString([]byte(p.Prefix)),
+		"range_end": enc.EncodeToString(end),
+	}
+}
+
+// post sends a JSON request to the gateway
+func (p *EtcdProvider) post(ctx context.Context, path string, body interface{}) (*http.Response, error) {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.Endpoint+path, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := p.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("etcd: unexpected status %d", resp.StatusCode)
+	}
+	return resp, nil
+}
+
+// fetch reads the prefix and returns the tree and store revision
+func (p *EtcdProvider) fetch(ctx context.Context) (map[string]interface{}, int64, error) {
+	resp, err := p.post(ctx, "/v3/kv/range", p.rangeRequest())
+	if err != nil {
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+
+	var out struct {
+		Header struct {
+			Revision string `json:"revision"`
+		} `json:"header"`
+		Kvs []etcdKV `json:"kvs"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, 0, fmt.Errorf("etcd: decode response: %w", err)
+	}
+
+	pairs := make(map[string]string, len(out.Kvs))
+	enc := base64.StdEncoding
+	for _, kv := range out.Kvs {
+		key, err := enc.DecodeString(kv.Key)
+		if err != nil {
+			return nil, 0, fmt.Errorf("etcd: decode key: %w", err)
+		}
+		value, err := enc.DecodeString(kv.Value)
+		if err != nil {
+			return nil, 0, fmt.Errorf("etcd: decode value: %w", err)
+		}
+		pairs[string(key)] = string(value)
+	}
+	revision, _ := strconv.ParseInt(out.Header.Revision, 10, 64)
+	return kvTree(p.Prefix, pairs), revision, nil
+}
+
+// Load fetches the current tree under the prefix
+func (p *EtcdProvider) Load(ctx context.Context) (map[string]interface{}, error) {
+	tree, _, err := p.fetch(ctx)
+	return tree, err
+}
+
+// Watch emits the tree after every batch of watch events on the prefix
+func (p *EtcdProvider) Watch(ctx context.Context) (<-chan map[string]interface{}, error) {
+	var revision int64
+	return watchLoop(ctx, func(ctx context.Context, emit func(map[string]interface{})) error {
+		// Resync on every (re)connect so changes missed while down are seen
+		tree, rev, err := p.fetch(ctx)
+		if err != nil {
+			return err
+		}
+		if revision != 0 && rev != revision {
+			emit(tree)
+		}
+		revision = rev
+
+		create := p.rangeRequest()
+		create["start_revision"] = strconv.FormatInt(revision+1, 10)
+		resp, err := p.post(ctx, "/v3/watch", map[string]interface{}{"create_request": create})
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		dec := json.NewDecoder(resp.Body)
+		for {
+			var msg struct {
+				Result struct {
+					Events []json.RawMessage `json:"events"`
+				} `json:"result"`
+			}
+			if err := dec.Decode(&msg); err != nil {
+				return err
+			}
+			if len(msg.Result.Events) == 0 {
+				continue
+			}
+			tree, rev, err := p.fetch(ctx)
+			if err != nil {
+				return err
+			}
+			revision = rev
+			emit(tree)
+		}
+	}), nil
+}
@@ -0,0 +1,133 @@
+package configuration
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// ChangeEvent describes a change to a single configuration key
+type ChangeEvent struct {
+	Key       string      `json:"key"`
+	Kind      ChangeKind  `json:"kind"`
+	Old       interface{} `json:"old,omitempty"`
+	New       interface{} `json:"new,omitempty"`
+	Source    string      `json:"source,omitempty"`
+	Version   int         `json:"version"`
+	Timestamp time.Time   `json:"timestamp"`
+}
+
+// EventHandler receives the events of one configuration change that match
+// its subscription prefix
+type EventHandler func(events []ChangeEvent)
+
+// eventSubscription is a registered handler and its key prefix
+type eventSubscription struct {
+	prefix  string
+	handler EventHandler
+}
+
+// EventBus fans out configuration change events to subscribers
+type EventBus struct {
+	mu     sync.RWMutex
+	nextID int
+	subs   map[int]eventSubscription
+}
+
+// NewEventBus creates an empty event bus
+func NewEventBus() *EventBus {
+	return &EventBus{subs: make(map[int]eventSubscription)}
+}
+
+// Subscribe registers handler for events whose key equals prefix or lies
+// below it ("" matches everything) and returns a function that unsubscribes
+func (b *EventBus) Subscribe(prefix string, handler EventHandler) func() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	id := b.nextID
+	b.nextID++
+	b.subs[id] = eventSubscription{prefix: prefix, handler: handler}
+	return func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.subs, id)
+	}
+}
+
+// Publish delivers events to matching subscribers in registration order
+func (b *EventBus) Publish(events []ChangeEvent) {
+	if len(events) == 0 {
+		return
+	}
+	b.mu.RLock()
+	subs := make([]eventSubscription, 0, len(b.subs))
+	for id := 0; id < b.nextID; id++ {
+		if sub, ok := b.subs[id]; ok {
+			subs = append(subs, sub)
+		}
+	}
+	b.mu.RUnlock()
+
+	for _, sub := range subs {
+		var matched []ChangeEvent
+		for _, e := range events {
+			if keyWithin(e.Key, sub.prefix) {
+				matched = append(matched, e)
+			}
+		}
+		if len(matched) > 0 {
+			sub.handler(matched)
+		}
+	}
+}
+
+// keyWithin reports whether key equals prefix or is nested below it
+func keyWithin(key, prefix string) bool {
+	return prefix == "" || key == prefix || strings.HasPrefix(key, prefix+".")
+}
+
+// Events returns the bus on which the manager publishes key changes
+func (m *Manager) Events() *EventBus {
+	return m.events
+}
+
+// publishChanges emits one event per changed key
+func (m *Manager) publishChanges(changes []Change, version int) {
+	if len(changes) == 0 {
+		return
+	}
+	now := time.Now()
+	events := make([]ChangeEvent, len(changes))
+	for i, c := range changes {
+		events[i] = ChangeEvent{Key: c.Key, Kind: c.Kind, Old: c.Old, New: c.New, Version: version, Timestamp: now}
+		if origin, err := m.layers.Explain(c.Key); err == nil {
+			events[i].Source = origin.Source
+		}
+	}
+	m.events.Publish(events)
+}
+
+// BindSection decodes the section under key into a value produced by
+// newTarget whenever any key in it changes and passes it to apply, so other
+// managers can reconfigure themselves live; the section is applied once
+// immediately if present
+func (m *Manager) BindSection(key string, newTarget func() interface{}, apply func(v interface{})) (func(), error) {
+	load := func() error {
+		target := newTarget()
+		if err := m.Unmarshal(key, target); err != nil {
+			return err
+		}
+		apply(target)
+		return nil
+	}
+	if _, ok := m.Get(key); ok {
+		if err := load(); err != nil {
+			return nil, err
+		}
+	}
+	return m.events.Subscribe(key, func(events []ChangeEvent) {
+		if err := load(); err != nil {
+			m.logger.Printf("Section %s not re-applied: %v", key, err)
+		}
+	}), nil
+}
@@ -0,0 +1,185 @@
+package configuration
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// FormatDotenv is a KEY=value listing; loaded files map names to keys as
+// the environment layer does, with "__" separating nesting levels
+const FormatDotenv Format = "dotenv"
+
+// redactedValue replaces sensitive values in redacted output
+const redactedValue = "******"
+
+// exportOptions holds settings for Export
+type exportOptions struct {
+	redact    bool
+	envPrefix string
+}
+
+// ExportOption configures Export
+type ExportOption func(*exportOptions)
+
+// WithRedaction masks values of keys marked sensitive; values resolved from
+// secret references are masked with or without it
+func WithRedaction() ExportOption {
+	return func(o *exportOptions) {
+		o.redact = true
+	}
+}
+
+// WithEnvPrefix prefixes variable names in dotenv output
+func WithEnvPrefix(prefix string) ExportOption {
+	return func(o *exportOptions) {
+		o.envPrefix = prefix
+	}
+}
+
+// MarkSensitive marks keys matching the given patterns as sensitive and
+// masks them fully; patterns use path.Match syntax on dotted keys, e.g.
+// "db.password" or "*.token"
+func (m *Manager) MarkSensitive(patterns ...string) {
+	m.MarkSensitiveWith(MaskFull, patterns...)
+}
+
+// IsSensitive reports whether key matches a sensitive pattern
+func (m *Manager) IsSensitive(key string) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	_, ok := maskFor(m.sensitiveLocked(), key)
+	return ok
+}
+
+// matchesAny reports whether key or one of its ancestors matches a pattern
+func matchesAny(patterns []string, key string) bool {
+	for _, pattern := range patterns {
+		for candidate := key; candidate != ""; {
+			if ok, _ := path.Match(pattern, candidate); ok {
+				return true
+			}
+			i := strings.LastIndex(candidate, ".")
+			if i < 0 {
+				break
+			}
+			candidate = candidate[:i]
+		}
+	}
+	return false
+}
+
+// Export writes the effective configuration in the given format
+func (m *Manager) Export(w io.Writer, format Format, opts ...ExportOption) error {
+	options := exportOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	var tree map[string]interface{}
+	if options.redact {
+		tree = m.redactedValues()
+	} else {
+		// Values resolved from secret references are masked regardless
+		m.mu.RLock()
+		values, rules := m.values, append([]sensitiveRule(nil), m.secretRules...)
+		m.mu.RUnlock()
+		tree = redactWith(values, rules)
+	}
+	return encodeTree(w, tree, format, options)
+}
+
+// encodeTree serializes a tree in the given format
+func encodeTree(w io.Writer, tree map[string]interface{}, format Format, options exportOptions) error {
+	switch format {
+	case FormatJSON, FormatAuto:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(tree)
+	case FormatYAML:
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(tree); err != nil {
+			return err
+		}
+		return enc.Close()
+	case FormatTOML:
+		return toml.NewEncoder(w).Encode(tree)
+	case FormatDotenv:
+		return writeDotenv(w, tree, options.envPrefix)
+	default:
+		return fmt.Errorf("unsupported export format %q", format)
+	}
+}
+
+// writeDotenv writes one NAME=value line per leaf key
+func writeDotenv(w io.Writer, tree map[string]interface{}, prefix string) error {
+	flat := flatten(tree)
+	for _, key := range sortedKeys(flat) {
+		name := strings.ToUpper(strings.ReplaceAll(key, ".", EnvSeparator))
+		if prefix != "" {
+			name = strings.ToUpper(strings.TrimSuffix(prefix, "_")) + "_" + name
+		}
+		if _, err := fmt.Fprintf(w, "%s=%s\n", name, dotenvValue(flat[key])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dotenvValue renders a leaf for dotenv output, quoting when needed
+func dotenvValue(v interface{}) string {
+	var s string
+	switch t := v.(type) {
+	case nil:
+		return ""
+	case []interface{}:
+		parts := make([]string, len(t))
+		for i, item := range t {
+			parts[i] = fmt.Sprint(item)
+		}
+		s = strings.Join(parts, ",")
+	case map[string]interface{}:
+		return ""
+	default:
+		s = fmt.Sprint(t)
+	}
+	if strings.ContainsAny(s, " \t\n\"'#$\\=") {
+		return strconv.Quote(s)
+	}
+	return s
+}
+
+// plainValue converts decoder-specific scalars (json.Number) to plain Go values
+func plainValue(v interface{}) interface{} {
+	switch t := v.(type) {
+	case json.Number:
+		if n, err := t.Int64(); err == nil {
+			return n
+		}
+		if f, err := t.Float64(); err == nil {
+			return f
+		}
+		return t.String()
+	case []interface{}:
+		out := make([]interface{}, len(t))
+		for i, item := range t {
+			out[i] = plainValue(item)
+		}
+		return out
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(t))
+		for k, item := range t {
+			out[k] = plainValue(item)
+		}
+		return out
+	default:
+		return v
+	}
+}
@@ -0,0 +1,275 @@
+package configuration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// StaticProvider serves a fixed tree, such as defaults embedded in the
+// binary; it never emits updates
+type StaticProvider struct {
+	name string
+	tree map[string]interface{}
+}
+
+// NewStaticProvider creates a provider serving tree
+func NewStaticProvider(name string, tree map[string]interface{}) *StaticProvider {
+	return &StaticProvider{name: name, tree: copyTree(tree)}
+}
+
+// ParseStaticProvider creates a provider serving a document, typically one
+// included with go:embed
+func ParseStaticProvider(name string, data []byte, format Format) (*StaticProvider, error) {
+	tree, err := Parse(data, format)
+	if err != nil {
+		return nil, fmt.Errorf("static provider %s: %w", name, err)
+	}
+	return &StaticProvider{name: name, tree: tree}, nil
+}
+
+// Name returns the provider name
+func (p *StaticProvider) Name() string {
+	return "static:" + p.name
+}
+
+// Load returns a copy of the tree
+func (p *StaticProvider) Load(ctx context.Context) (map[string]interface{}, error) {
+	return copyTree(p.tree), nil
+}
+
+// Watch returns a channel that is closed when ctx is cancelled
+func (p *StaticProvider) Watch(ctx context.Context) (<-chan map[string]interface{}, error) {
+	return idleWatch(ctx), nil
+}
+
+// FileProvider reads a configuration file on each Load, e.g. a cache kept
+// beside the service; it does not watch the file
+type FileProvider struct {
+	Path    string
+	Options []LoadOption
+}
+
+// NewFileProvider creates a provider for the file at path
+func NewFileProvider(path string, opts ...LoadOption) *FileProvider {
+	return &FileProvider{Path: path, Options: opts}
+}
+
+// Name returns the provider name
+func (p *FileProvider) Name() string {
+	return "file:" + p.Path
+}
+
+// Load reads and parses the file
+func (p *FileProvider) Load(ctx context.Context) (map[string]interface{}, error) {
+	return ReadTree(p.Path, p.Options...)
+}
+
+// Watch returns a channel that is closed when ctx is cancelled
+func (p *FileProvider) Watch(ctx context.Context) (<-chan map[string]interface{}, error) {
+	return idleWatch(ctx), nil
+}
+
+// idleWatch returns a watch channel that never emits
+func idleWatch(ctx context.Context) <-chan map[string]interface{} {
+	out := make(chan map[string]interface{})
+	go func() {
+		<-ctx.Done()
+		close(out)
+	}()
+	return out
+}
+
+// ChainStatus describes one provider of a ChainProvider
+type ChainStatus struct {
+	Name        string    `json:"name"`
+	Live        bool      `json:"live"`
+	LastSuccess time.Time `json:"last_success,omitempty"`
+	LastError   string    `json:"last_error,omitempty"`
+}
+
+// ChainProvider tries its providers in priority order, e.g. remote, then a
+// cached file, then embedded defaults, and serves the first that answers;
+// while a lower provider is live the higher ones are probed every
+// RetryInterval and take over again once they recover
+type ChainProvider struct {
+	RetryInterval time.Duration
+
+	providers []Provider
+	mu        sync.Mutex
+	live      int
+	status    []ChainStatus
+}
+
+// NewChainProvider creates a chain over providers, highest priority first
+func NewChainProvider(providers ...Provider) *ChainProvider {
+	status := make([]ChainStatus, len(providers))
+	for i, p := range providers {
+		status[i].Name = p.Name()
+	}
+	return &ChainProvider{RetryInterval: 30 * time.Second, providers: providers, live: -1, status: status}
+}
+
+// Name returns the name of the live provider, so the layer origin records
+// which source is serving; before the first Load it names the whole chain
+func (c *ChainProvider) Name() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.live >= 0 {
+		return c.providers[c.live].Name()
+	}
+	names := make([]string, len(c.providers))
+	for i, p := range c.providers {
+		names[i] = p.Name()
+	}
+	return "chain:" + strings.Join(names, ">")
+}
+
+// Live returns the name of the provider currently serving, or "" when none is
+func (c *ChainProvider) Live() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.live < 0 {
+		return ""
+	}
+	return c.providers[c.live].Name()
+}
+
+// Status reports the state of every provider in the chain
+func (c *ChainProvider) Status() []ChainStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]ChainStatus(nil), c.status...)
+}
+
+// Load returns the tree of the first provider that loads successfully
+func (c *ChainProvider) Load(ctx context.Context) (map[string]interface{}, error) {
+	tree, _, err := c.loadFrom(ctx, 0, len(c.providers))
+	return tree, err
+}
+
+// loadFrom tries providers in [from, to) and makes the first success live
+func (c *ChainProvider) loadFrom(ctx context.Context, from, to int) (map[string]interface{}, int, error) {
+	var errs []error
+	for i := from; i < to; i++ {
+		tree, err := c.providers[i].Load(ctx)
+		c.record(i, err)
+		if err == nil {
+			c.setLive(i)
+			return tree, i, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", c.providers[i].Name(), err))
+	}
+	if len(errs) == 0 {
+		return nil, -1, errors.New("provider chain: no providers")
+	}
+	return nil, -1, fmt.Errorf("provider chain: %w", errors.Join(errs...))
+}
+
+// record stores the outcome of a call to provider i
+func (c *ChainProvider) record(i int, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err != nil {
+		c.status[i].LastError = err.Error()
+		return
+	}
+	c.status[i].LastError = ""
+	c.status[i].LastSuccess = time.Now()
+}
+
+// setLive marks provider i as the one serving
+func (c *ChainProvider) setLive(i int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.live >= 0 {
+		c.status[c.live].Live = false
+	}
+	c.live = i
+	c.status[i].Live = true
+}
+
+// liveIndex returns the index of the live provider
+func (c *ChainProvider) liveIndex() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.live
+}
+
+// Watch forwards updates from the live provider and from any higher one,
+// which then becomes live; updates from lower providers are dropped. If the
+// live provider's watch ends, the chain falls back to the next provider
+// that loads
+func (c *ChainProvider) Watch(ctx context.Context) (<-chan map[string]interface{}, error) {
+	type update struct {
+		index int
+		tree  map[string]interface{}
+	}
+	updates := make(chan update)
+	closed := make(chan int, len(c.providers))
+	for i, p := range c.providers {
+		ch, err := p.Watch(ctx)
+		if err != nil {
+			c.record(i, err)
+			closed <- i
+			continue
+		}
+		go func(i int, ch <-chan map[string]interface{}) {
+			for tree := range ch {
+				select {
+				case updates <- update{i, tree}:
+				case <-ctx.Done():
+					return
+				}
+			}
+			closed <- i
+		}(i, ch)
+	}
+
+	out := make(chan map[string]interface{}, 1)
+	emit := func(tree map[string]interface{}) {
+		select {
+		case out <- tree:
+		case <-ctx.Done():
+		}
+	}
+	go func() {
+		defer close(out)
+		ticker := time.NewTicker(c.RetryInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case u := <-updates:
+				if live := c.liveIndex(); live >= 0 && u.index > live {
+					continue
+				}
+				c.record(u.index, nil)
+				c.setLive(u.index)
+				emit(u.tree)
+			case i := <-closed:
+				if i != c.liveIndex() || ctx.Err() != nil {
+					continue
+				}
+				if tree, _, err := c.loadFrom(ctx, i+1, len(c.providers)); err == nil {
+					emit(tree)
+				}
+			case <-ticker.C:
+				// Probe the providers above the live one
+				if live := c.liveIndex(); live != 0 {
+					if live < 0 {
+						live = len(c.providers)
+					}
+					if tree, _, err := c.loadFrom(ctx, 0, live); err == nil {
+						emit(tree)
+					}
+				}
+			}
+		}
+	}()
+	return out, nil
+}
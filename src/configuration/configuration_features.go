@@ -0,0 +1,167 @@
+package configuration
+
+import (
+	"context"
+	"hash/fnv"
+	"sync"
+	"sync/atomic"
+)
+
+// Flag is a feature flag definition read from configuration
+type Flag struct {
+	Enabled    bool         `json:"enabled"`
+	Percentage float64      `json:"percentage"`
+	Targets    []FlagTarget `json:"targets"`
+}
+
+// FlagTarget enables a flag for contexts whose attribute has one of Values
+type FlagTarget struct {
+	Attribute string   `json:"attribute"`
+	Values    []string `json:"values"`
+}
+
+// FlagContext identifies who a flag is evaluated for; Key drives stable
+// percentage bucketing and Attributes drive targeting
+type FlagContext struct {
+	Key        string
+	Attributes map[string]string
+}
+
+// flagContextKey is the context key for FlagContext values
+type flagContextKey struct{}
+
+// WithFlagContext attaches flag evaluation data to ctx
+func WithFlagContext(ctx context.Context, fc FlagContext) context.Context {
+	return context.WithValue(ctx, flagContextKey{}, fc)
+}
+
+// FlagContextFrom returns the flag evaluation data attached to ctx
+func FlagContextFrom(ctx context.Context) (FlagContext, bool) {
+	fc, ok := ctx.Value(flagContextKey{}).(FlagContext)
+	return fc, ok
+}
+
+// FlagStats counts evaluations of a single flag
+type FlagStats struct {
+	Evaluations uint64 `json:"evaluations"`
+	Enabled     uint64 `json:"enabled"`
+}
+
+// flagCounters are the live counters behind FlagStats
+type flagCounters struct {
+	evaluations atomic.Uint64
+	enabled     atomic.Uint64
+}
+
+// FeatureFlags evaluates flags defined under a configuration key and reloads
+// them whenever the configuration changes
+type FeatureFlags struct {
+	manager *Manager
+	key     string
+	remove  func()
+
+	mu       sync.RWMutex
+	flags    map[string]Flag
+	counters sync.Map
+}
+
+// NewFeatureFlags binds flags defined under key (e.g. "features") of the
+// manager configuration
+func NewFeatureFlags(m *Manager, key string) (*FeatureFlags, error) {
+	f := &FeatureFlags{manager: m, key: key}
+	if err := f.reload(); err != nil {
+		return nil, err
+	}
+	f.remove = m.OnChange(func(old, new *Config) {
+		if err := f.reload(); err != nil {
+			m.logger.Printf("Keeping previous feature flags: %v", err)
+		}
+	})
+	return f, nil
+}
+
+// reload re-reads flag definitions from the manager
+func (f *FeatureFlags) reload() error {
+	flags := make(map[string]Flag)
+	if _, ok := f.manager.Get(f.key); ok {
+		if err := f.manager.Unmarshal(f.key, &flags); err != nil {
+			return err
+		}
+	}
+	f.mu.Lock()
+	f.flags = flags
+	f.mu.Unlock()
+	return nil
+}
+
+// Close stops following configuration changes
+func (f *FeatureFlags) Close() {
+	if f.remove != nil {
+		f.remove()
+	}
+}
+
+// IsEnabled evaluates a flag for the FlagContext carried by ctx; unknown and
+// disabled flags evaluate to false
+func (f *FeatureFlags) IsEnabled(ctx context.Context, name string) bool {
+	f.mu.RLock()
+	flag, ok := f.flags[name]
+	f.mu.RUnlock()
+
+	fc, _ := FlagContextFrom(ctx)
+	enabled := ok && evaluateFlag(name, flag, fc)
+
+	c, _ := f.counters.LoadOrStore(name, &flagCounters{})
+	counters := c.(*flagCounters)
+	counters.evaluations.Add(1)
+	if enabled {
+		counters.enabled.Add(1)
+	}
+	return enabled
+}
+
+// Stats returns evaluation counts per flag
+func (f *FeatureFlags) Stats() map[string]FlagStats {
+	out := make(map[string]FlagStats)
+	f.counters.Range(func(k, v interface{}) bool {
+		c := v.(*flagCounters)
+		out[k.(string)] = FlagStats{Evaluations: c.evaluations.Load(), Enabled: c.enabled.Load()}
+		return true
+	})
+	return out
+}
+
+// evaluateFlag applies targeting and percentage rollout; a flag with neither
+// is a plain boolean
+func evaluateFlag(name string, flag Flag, fc FlagContext) bool {
+	if !flag.Enabled {
+		return false
+	}
+	if len(flag.Targets) == 0 && flag.Percentage <= 0 {
+		return true
+	}
+	for _, target := range flag.Targets {
+		value, ok := fc.Attributes[target.Attribute]
+		if !ok {
+			continue
+		}
+		for _, v := range target.Values {
+			if v == value {
+				return true
+			}
+		}
+	}
+	if flag.Percentage <= 0 || fc.Key == "" {
+		return false
+	}
+	return bucket(name, fc.Key) < flag.Percentage
+}
+
+// bucket maps a flag and key to a stable value in [0, 100)
+func bucket(name, key string) float64 {
+	h := fnv.New32a()
+	h.Write([]byte(name))
+	h.Write([]byte{0})
+	h.Write([]byte(key))
+	return float64(h.Sum32()%10000) / 100
+}
@@ -0,0 +1,130 @@
+package configuration
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/spf13/pflag"
+)
+
+// FlagName returns the command-line flag name for a configuration key,
+// e.g. "log_level" becomes "log-level" and "db.max_conns" "db.max-conns"
+func FlagName(key string) string {
+	return strings.ReplaceAll(key, "_", "-")
+}
+
+// flagBinding collects explicitly set flags into the flags layer
+type flagBinding struct {
+	manager *Manager
+	mu      sync.Mutex
+	values  map[string]interface{}
+}
+
+// set records a parsed flag and re-applies the flags layer
+func (b *flagBinding) set(key, raw string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	next := copyTree(b.values)
+	setPath(next, key, raw)
+	if err := b.manager.SetLayer(LayerFlags, "flags", next); err != nil {
+		return err
+	}
+	b.values = next
+	return nil
+}
+
+// flagValue is a flag.Value and pflag.Value for one configuration key
+type flagValue struct {
+	binding *flagBinding
+	key     string
+	current string
+	kind    string
+}
+
+// String returns the current value
+func (v *flagValue) String() string {
+	if v == nil {
+		return ""
+	}
+	return v.current
+}
+
+// Set parses and applies a command-line value
+func (v *flagValue) Set(raw string) error {
+	if err := v.binding.set(v.key, raw); err != nil {
+		return err
+	}
+	v.current = raw
+	return nil
+}
+
+// Type names the value kind for pflag usage output
+func (v *flagValue) Type() string {
+	return v.kind
+}
+
+// IsBoolFlag lets boolean keys be given without a value (-enabled)
+func (v *flagValue) IsBoolFlag() bool {
+	return v.kind == "bool"
+}
+
+// flagValues builds one value per leaf key of the effective configuration
+func (m *Manager) flagValues() []*flagValue {
+	binding := &flagBinding{manager: m, values: make(map[string]interface{})}
+	if tree, ok := m.layers.Layer(LayerFlags); ok {
+		binding.values = tree
+	}
+
+	m.mu.RLock()
+	flat := flatten(m.values)
+	m.mu.RUnlock()
+
+	values := make([]*flagValue, 0, len(flat))
+	for _, key := range sortedKeys(flat) {
+		current := flat[key]
+		kind := "string"
+		switch current.(type) {
+		case bool:
+			kind = "bool"
+		case int, int64, float64:
+			kind = "number"
+		case []interface{}:
+			kind = "list"
+			current = dotenvValue(current)
+		case map[string]interface{}:
+			continue
+		}
+		values = append(values, &flagValue{binding: binding, key: key, current: fmt.Sprint(plainValue(current)), kind: kind})
+	}
+	return values
+}
+
+// BindFlags registers a flag for every configuration key not already defined
+// in fs; values given on the command line are applied to the flags layer
+// as they are parsed
+func (m *Manager) BindFlags(fs *flag.FlagSet) {
+	for _, v := range m.flagValues() {
+		name := FlagName(v.key)
+		if fs.Lookup(name) != nil {
+			continue
+		}
+		fs.Var(v, name, fmt.Sprintf("configuration key %s", v.key))
+	}
+}
+
+// BindPFlags is BindFlags for github.com/spf13/pflag flag sets
+func (m *Manager) BindPFlags(fs *pflag.FlagSet) {
+	for _, v := range m.flagValues() {
+		name := FlagName(v.key)
+		if fs.Lookup(name) != nil {
+			continue
+		}
+		f := fs.VarPF(v, name, "", fmt.Sprintf("configuration key %s", v.key))
+		if v.kind == "bool" {
+			f.NoOptDefVal = "true"
+		}
+	}
+}
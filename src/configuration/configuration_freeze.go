@@ -0,0 +1,32 @@
+package configuration
+
+import "errors"
+
+// ErrFrozen is returned by every configuration change after Freeze
+var ErrFrozen = errors.New("configuration is frozen")
+
+// Freeze makes the configuration immutable: layer updates, provider and file
+// reloads, secret refreshes and rollbacks fail with ErrFrozen from now on
+func (m *Manager) Freeze() {
+	m.writeMu.Lock()
+	defer m.writeMu.Unlock()
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.frozen = true
+	m.logger.Printf("Configuration frozen at version %d", m.Version())
+}
+
+// Frozen reports whether Freeze has been called
+func (m *Manager) Frozen() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.frozen
+}
+
+// checkWritable fails once the configuration is frozen; callers hold writeMu
+func (m *Manager) checkWritable() error {
+	if m.Frozen() {
+		return ErrFrozen
+	}
+	return nil
+}
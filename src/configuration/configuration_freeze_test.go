@@ -0,0 +1,101 @@
+package configuration
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"text/template"
+)
+
+func TestFreeze(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	file := filepath.Join(dir, "app.json")
+	if err := os.WriteFile(file, []byte(`{"retries": 9}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	store := DirBackupStore{Dir: filepath.Join(dir, "backups")}
+
+	m := NewManager(nil)
+	if err := m.SetLayer(LayerRuntime, "admin", map[string]interface{}{"retries": 5}); err != nil {
+		t.Fatal(err)
+	}
+	first := m.Version()
+	backup, err := m.Backup(ctx, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.SetLayer(LayerRuntime, "admin", map[string]interface{}{"retries": 6}); err != nil {
+		t.Fatal(err)
+	}
+	if m.Frozen() {
+		t.Fatal("Frozen before Freeze")
+	}
+	m.Freeze()
+	if !m.Frozen() {
+		t.Fatal("not Frozen after Freeze")
+	}
+	config, version := m.GetConfig(), m.Version()
+
+	changes := map[string]func() error{
+		"SetLayer":        func() error { return m.SetLayer(LayerRuntime, "admin", map[string]interface{}{"retries": 1}) },
+		"ApplyPatch":      func() error { return m.ApplyPatch(ctx, map[string]interface{}{"retries": 1}) },
+		"LoadFile":        func() error { return m.LoadFile(file) },
+		"ApplyEnv":        func() error { return m.ApplyEnv("FREEZE_TEST") },
+		"Rollback":        func() error { return m.Rollback(first) },
+		"RestoreVersion":  func() error { return m.RestoreVersion(ctx, store, backup.Version) },
+		"EnableTemplates": func() error { return m.EnableTemplates(template.FuncMap{}) },
+	}
+	for name, change := range changes {
+		if err := change(); !errors.Is(err, ErrFrozen) {
+			t.Errorf("%s after Freeze = %v, want ErrFrozen", name, err)
+		}
+	}
+	if got := m.GetConfig(); !reflect.DeepEqual(got, config) || got.Retries != 6 {
+		t.Errorf("config after Freeze = %+v, want %+v", got, config)
+	}
+	if got := m.Version(); got != version {
+		t.Errorf("version after Freeze = %d, want %d", got, version)
+	}
+	// Reads keep working
+	if got := m.GetInt("retries", 0); got != 6 {
+		t.Errorf("retries = %d, want 6", got)
+	}
+	if _, err := m.Backup(ctx, store); err != nil {
+		t.Errorf("Backup after Freeze = %v", err)
+	}
+}
+
+func TestFreezeAdminPatchConflicts(t *testing.T) {
+	m := NewManager(nil)
+	handler := NewAdminHandler(m)
+	patch := func() int {
+		req := httptest.NewRequest(http.MethodPatch, "/config", strings.NewReader(`{"retries": 4}`))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	if code := patch(); code != http.StatusOK {
+		t.Fatalf("PATCH before Freeze = %d, want 200", code)
+	}
+	m.Freeze()
+	if code := patch(); code != http.StatusConflict {
+		t.Errorf("PATCH after Freeze = %d, want 409", code)
+	}
+}
+
+func TestGetConfigReturnsCopy(t *testing.T) {
+	m := NewManager(nil)
+	config := m.GetConfig()
+	config.Retries = 99
+	config.LogLevel = "TRACE"
+	if got := m.GetConfig(); got.Retries == 99 || got.LogLevel == "TRACE" {
+		t.Errorf("GetConfig = %+v reflects changes made to an earlier copy", got)
+	}
+}
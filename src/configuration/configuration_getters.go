@@ -0,0 +1,204 @@
+package configuration
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrKeyNotFound is returned by the Require getters for missing keys
+var ErrKeyNotFound = errors.New("configuration key not found")
+
+// Get returns the effective value stored under a dotted key
+func (m *Manager) Get(key string) (interface{}, bool) {
+	start := time.Now()
+	m.mu.RLock()
+	value, ok := lookup(m.values, key)
+	audit := m.audit
+	metrics := m.metrics
+	m.mu.RUnlock()
+	m.auditRead(audit, key, value)
+	metrics.observe(key, ok, time.Since(start))
+	if !ok {
+		return nil, false
+	}
+	return deepCopy(value), true
+}
+
+// require returns the value under key or ErrKeyNotFound
+func (m *Manager) require(key string) (interface{}, error) {
+	value, ok := m.Get(key)
+	if !ok || value == nil {
+		return nil, fmt.Errorf("%w: %s", ErrKeyNotFound, key)
+	}
+	return value, nil
+}
+
+// GetString returns the value under key as a string, or def when missing or unconvertible
+func (m *Manager) GetString(key, def string) string {
+	if s, err := m.RequireString(key); err == nil {
+		return s
+	}
+	return def
+}
+
+// GetInt returns the value under key as an int, or def when missing or unconvertible
+func (m *Manager) GetInt(key string, def int) int {
+	if n, err := m.RequireInt(key); err == nil {
+		return n
+	}
+	return def
+}
+
+// GetBool returns the value under key as a bool, or def when missing or unconvertible
+func (m *Manager) GetBool(key string, def bool) bool {
+	if b, err := m.RequireBool(key); err == nil {
+		return b
+	}
+	return def
+}
+
+// GetDuration returns the value under key as a duration, or def when missing or unconvertible
+func (m *Manager) GetDuration(key string, def time.Duration) time.Duration {
+	if d, err := m.RequireDuration(key); err == nil {
+		return d
+	}
+	return def
+}
+
+// RequireString returns the value under key as a string
+func (m *Manager) RequireString(key string) (string, error) {
+	value, err := m.require(key)
+	if err != nil {
+		return "", err
+	}
+	s, err := toString(value)
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", key, err)
+	}
+	return s, nil
+}
+
+// RequireInt returns the value under key as an int
+func (m *Manager) RequireInt(key string) (int, error) {
+	value, err := m.require(key)
+	if err != nil {
+		return 0, err
+	}
+	n, err := toInt(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", key, err)
+	}
+	return n, nil
+}
+
+// RequireBool returns the value under key as a bool
+func (m *Manager) RequireBool(key string) (bool, error) {
+	value, err := m.require(key)
+	if err != nil {
+		return false, err
+	}
+	b, err := toBool(value)
+	if err != nil {
+		return false, fmt.Errorf("%s: %w", key, err)
+	}
+	return b, nil
+}
+
+// RequireDuration returns the value under key as a duration; numbers are
+// read as nanoseconds, matching how Config decodes them
+func (m *Manager) RequireDuration(key string) (time.Duration, error) {
+	value, err := m.require(key)
+	if err != nil {
+		return 0, err
+	}
+	d, err := toDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("%s: %w", key, err)
+	}
+	return d, nil
+}
+
+// Unmarshal decodes the subtree under key (or everything when key is empty) into v
+func (m *Manager) Unmarshal(key string, v interface{}) error {
+	var tree map[string]interface{}
+	if key == "" {
+		m.mu.RLock()
+		tree = copyTree(m.values)
+		m.mu.RUnlock()
+	} else {
+		value, err := m.require(key)
+		if err != nil {
+			return err
+		}
+		var ok bool
+		if tree, ok = value.(map[string]interface{}); !ok {
+			return fmt.Errorf("%s: not a section", key)
+		}
+	}
+	return Decode(tree, v, false)
+}
+
+// toString converts scalar values to strings
+func toString(v interface{}) (string, error) {
+	switch t := v.(type) {
+	case string:
+		return t, nil
+	case json.Number:
+		return t.String(), nil
+	case bool, int, int64, uint64, float64:
+		return fmt.Sprint(t), nil
+	}
+	return "", fmt.Errorf("cannot convert %T to string", v)
+}
+
+// toInt converts numeric values and numeric strings to int
+func toInt(v interface{}) (int, error) {
+	switch t := v.(type) {
+	case int:
+		return t, nil
+	case int64:
+		return int(t), nil
+	case uint64:
+		return int(t), nil
+	case float64:
+		if t != math.Trunc(t) {
+			return 0, fmt.Errorf("%v is not an integer", t)
+		}
+		return int(t), nil
+	case json.Number:
+		n, err := t.Int64()
+		return int(n), err
+	case string:
+		n, err := strconv.ParseInt(strings.TrimSpace(t), 0, 64)
+		return int(n), err
+	}
+	return 0, fmt.Errorf("cannot convert %T to int", v)
+}
+
+// toBool converts booleans and boolean strings
+func toBool(v interface{}) (bool, error) {
+	switch t := v.(type) {
+	case bool:
+		return t, nil
+	case string:
+		return strconv.ParseBool(strings.TrimSpace(t))
+	}
+	return false, fmt.Errorf("cannot convert %T to bool", v)
+}
+
+// toDuration converts duration strings and nanosecond counts
+func toDuration(v interface{}) (time.Duration, error) {
+	if s, ok := v.(string); ok {
+		return ParseDuration(s)
+	}
+	n, err := toInt(v)
+	if err != nil {
+		return 0, fmt.Errorf("cannot convert %T to duration", v)
+	}
+	return time.Duration(n), nil
+}
@@ -0,0 +1,182 @@
+package configuration
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// ChangeKind classifies a key difference between two configurations
+type ChangeKind string
+
+const (
+	// ChangeAdded marks a key present only in the newer configuration
+	ChangeAdded ChangeKind = "added"
+	// ChangeRemoved marks a key present only in the older configuration
+	ChangeRemoved ChangeKind = "removed"
+	// ChangeModified marks a key whose value differs
+	ChangeModified ChangeKind = "modified"
+)
+
+// Change is a single key difference
+type Change struct {
+	Key  string      `json:"key"`
+	Kind ChangeKind  `json:"kind"`
+	Old  interface{} `json:"old,omitempty"`
+	New  interface{} `json:"new,omitempty"`
+}
+
+// Snapshot is an applied configuration version
+type Snapshot struct {
+	Version   int                    `json:"version"`
+	AppliedAt time.Time              `json:"applied_at"`
+	Effective map[string]interface{} `json:"effective"`
+	layers    map[Layer]layerData
+}
+
+// history is a bounded list of applied snapshots
+type history struct {
+	mu        sync.RWMutex
+	limit     int
+	next      int
+	snapshots []*Snapshot
+}
+
+// record appends a snapshot, evicting the oldest beyond the limit
+func (h *history) record(layers map[Layer]layerData, effective map[string]interface{}, limit int) *Snapshot {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.next++
+	snap := &Snapshot{Version: h.next, AppliedAt: time.Now(), Effective: effective, layers: layers}
+	h.snapshots = append(h.snapshots, snap)
+	if limit > 0 && len(h.snapshots) > limit {
+		h.snapshots = append([]*Snapshot(nil), h.snapshots[len(h.snapshots)-limit:]...)
+	}
+	return snap
+}
+
+// get returns the snapshot with the given version
+func (h *history) get(version int) (*Snapshot, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for _, snap := range h.snapshots {
+		if snap.Version == version {
+			return snap, nil
+		}
+	}
+	return nil, fmt.Errorf("configuration version %d is not in history", version)
+}
+
+// snapshotLayers copies every layer of the stack
+func (s *LayerStack) snapshotLayers() map[Layer]layerData {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make(map[Layer]layerData, len(s.layers))
+	for layer, data := range s.layers {
+		out[layer] = layerData{source: data.source, tree: copyTree(data.tree)}
+	}
+	return out
+}
+
+// restoreLayers replaces all layers, keeping the change only if check accepts it
+func (s *LayerStack) restoreLayers(layers map[Layer]layerData, check func(effective map[string]interface{}) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	prev := s.layers
+	s.layers = make(map[Layer]layerData, len(layers))
+	for layer, data := range layers {
+		s.layers[layer] = layerData{source: data.source, tree: copyTree(data.tree)}
+	}
+	s.merge()
+	if err := check(copyTree(s.effective)); err != nil {
+		s.layers = prev
+		s.merge()
+		return err
+	}
+	return nil
+}
+
+// History returns the retained snapshots, oldest first
+func (m *Manager) History() []Snapshot {
+	m.history.mu.RLock()
+	defer m.history.mu.RUnlock()
+	out := make([]Snapshot, len(m.history.snapshots))
+	for i, snap := range m.history.snapshots {
+		out[i] = Snapshot{Version: snap.Version, AppliedAt: snap.AppliedAt, Effective: copyTree(snap.Effective)}
+	}
+	return out
+}
+
+// Version returns the version number of the active configuration
+func (m *Manager) Version() int {
+	m.history.mu.RLock()
+	defer m.history.mu.RUnlock()
+	return m.history.next
+}
+
+// Diff lists the key differences between two retained versions
+func (m *Manager) Diff(from, to int) ([]Change, error) {
+	a, err := m.history.get(from)
+	if err != nil {
+		return nil, err
+	}
+	b, err := m.history.get(to)
+	if err != nil {
+		return nil, err
+	}
+	return diffTrees(a.Effective, b.Effective), nil
+}
+
+// Rollback re-applies the layers of a retained version as a new version and
+// notifies watchers
+func (m *Manager) Rollback(version int) error {
+	snap, err := m.history.get(version)
+	if err != nil {
+		return err
+	}
+
+	defer m.deliverChanges()
+	m.writeMu.Lock()
+	defer m.writeMu.Unlock()
+	if err := m.checkWritable(); err != nil {
+		return fmt.Errorf("rollback to version %d: %w", version, err)
+	}
+
+	var config *Config
+	var values map[string]interface{}
+	err = m.layers.restoreLayers(snap.layers, func(effective map[string]interface{}) error {
+		var err error
+		config, values, err = m.decodeEffective(effective)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("rollback to version %d: %w", version, err)
+	}
+	m.applyConfig(config, values)
+	m.logger.Printf("Rolled back configuration to version %d", version)
+	return nil
+}
+
+// diffTrees compares the leaves of two trees, ordered by key
+func diffTrees(old, new map[string]interface{}) []Change {
+	a, b := flatten(old), flatten(new)
+	var changes []Change
+	for _, key := range sortedKeys(a) {
+		nv, ok := b[key]
+		switch {
+		case !ok:
+			changes = append(changes, Change{Key: key, Kind: ChangeRemoved, Old: a[key]})
+		case !reflect.DeepEqual(a[key], nv):
+			changes = append(changes, Change{Key: key, Kind: ChangeModified, Old: a[key], New: nv})
+		}
+	}
+	for _, key := range sortedKeys(b) {
+		if _, ok := a[key]; !ok {
+			changes = append(changes, Change{Key: key, Kind: ChangeAdded, New: b[key]})
+		}
+	}
+	return changes
+}
@@ -0,0 +1,117 @@
+package configuration
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// IncludeKey is the top-level key naming files to include
+const IncludeKey = "include"
+
+// resolveIncludes merges the files named by the include directive of tree
+// in order, with the including file's own values taking precedence. Entries
+// may be a string or list of strings; each is a path relative to the
+// including file, a glob, or a directory whose config files are included in
+// name order. Included files use the format implied by their extension
+func resolveIncludes(path string, tree map[string]interface{}, options loadOptions, stack []string) (map[string]interface{}, error) {
+	raw, ok := tree[IncludeKey]
+	if !ok {
+		return tree, nil
+	}
+	delete(tree, IncludeKey)
+
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, err
+	}
+	for _, seen := range stack {
+		if seen == abs {
+			return nil, fmt.Errorf("include cycle: %s", strings.Join(append(stack, abs), " -> "))
+		}
+	}
+	stack = append(stack, abs)
+
+	patterns, err := includePatterns(raw)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	files, err := includeFiles(filepath.Dir(abs), patterns)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	options.format = FormatAuto
+	merged := make(map[string]interface{})
+	for _, file := range files {
+		included, err := readTree(file, options, stack)
+		if err != nil {
+			return nil, err
+		}
+		merged = mergeTrees(merged, included)
+	}
+	return mergeTrees(merged, tree), nil
+}
+
+// includePatterns normalizes the value of an include directive
+func includePatterns(raw interface{}) ([]string, error) {
+	switch v := raw.(type) {
+	case string:
+		return []string{v}, nil
+	case []interface{}:
+		patterns := make([]string, 0, len(v))
+		for _, item := range v {
+			s, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("include entries must be strings, got %T", item)
+			}
+			patterns = append(patterns, s)
+		}
+		return patterns, nil
+	default:
+		return nil, fmt.Errorf("include must be a string or list of strings, got %T", raw)
+	}
+}
+
+// includeFiles expands include patterns relative to dir into file paths;
+// explicit paths must exist, globs may match nothing
+func includeFiles(dir string, patterns []string) ([]string, error) {
+	var files []string
+	for _, pattern := range patterns {
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(dir, pattern)
+		}
+
+		matches := []string{pattern}
+		if strings.ContainsAny(pattern, "*?[") {
+			var err error
+			if matches, err = filepath.Glob(pattern); err != nil {
+				return nil, fmt.Errorf("include %s: %w", pattern, err)
+			}
+			sort.Strings(matches)
+		}
+
+		for _, match := range matches {
+			info, err := os.Stat(match)
+			if err != nil {
+				return nil, fmt.Errorf("include: %w", err)
+			}
+			if !info.IsDir() {
+				files = append(files, match)
+				continue
+			}
+			entries, err := os.ReadDir(match)
+			if err != nil {
+				return nil, fmt.Errorf("include: %w", err)
+			}
+			for _, entry := range entries {
+				if !entry.IsDir() && FormatFromPath(entry.Name()) != FormatAuto {
+					files = append(files, filepath.Join(match, entry.Name()))
+				}
+			}
+		}
+	}
+	return files, nil
+}
@@ -0,0 +1,187 @@
+package configuration
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+)
+
+// ErrInterpolationCycle is returned when ${...} references form a cycle
+var ErrInterpolationCycle = errors.New("interpolation cycle")
+
+// interpolator expands ${key} and ${env:VAR} references within a tree
+type interpolator struct {
+	tree     map[string]interface{}
+	resolved map[string]interface{}
+	stack    []string
+}
+
+// Interpolate returns a copy of tree with references expanded: ${key} is
+// replaced by the value of another key, ${env:VAR} by an environment
+// variable, and either form may supply a fallback as ${ref:-default}. A
+// value that consists of a single reference keeps the referenced type; "$${"
+// produces a literal "${"
+func Interpolate(tree map[string]interface{}) (map[string]interface{}, error) {
+	in := &interpolator{tree: tree, resolved: make(map[string]interface{})}
+	out, err := in.expand(copyTree(tree))
+	if err != nil {
+		return nil, err
+	}
+	return out.(map[string]interface{}), nil
+}
+
+// expand interpolates every string within v in place
+func (in *interpolator) expand(v interface{}) (interface{}, error) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, inner := range t {
+			r, err := in.expand(inner)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", k, err)
+			}
+			t[k] = r
+		}
+		return t, nil
+	case []interface{}:
+		for i, inner := range t {
+			r, err := in.expand(inner)
+			if err != nil {
+				return nil, err
+			}
+			t[i] = r
+		}
+		return t, nil
+	case string:
+		return in.expandString(t)
+	default:
+		return v, nil
+	}
+}
+
+// expandString expands the references in a single string
+func (in *interpolator) expandString(s string) (interface{}, error) {
+	if !strings.Contains(s, "${") {
+		return s, nil
+	}
+	if strings.HasPrefix(s, "${") && strings.Index(s, "}") == len(s)-1 {
+		return in.reference(s[2 : len(s)-1])
+	}
+
+	var b strings.Builder
+	for {
+		i := strings.Index(s, "${")
+		if i < 0 {
+			b.WriteString(s)
+			return b.String(), nil
+		}
+		if i > 0 && s[i-1] == '$' {
+			b.WriteString(s[:i-1] + "${")
+			s = s[i+2:]
+			continue
+		}
+		end := strings.IndexByte(s[i:], '}')
+		if end < 0 {
+			return nil, fmt.Errorf("unterminated reference in %q", s)
+		}
+		value, err := in.reference(s[i+2 : i+end])
+		if err != nil {
+			return nil, err
+		}
+		text, err := toString(value)
+		if err != nil {
+			return nil, fmt.Errorf("embed %s: %w", s[i+2:i+end], err)
+		}
+		b.WriteString(s[:i])
+		b.WriteString(text)
+		s = s[i+end+1:]
+	}
+}
+
+// reference resolves the body of a ${...} reference
+func (in *interpolator) reference(expr string) (interface{}, error) {
+	expr, fallback, hasFallback := strings.Cut(expr, ":-")
+	expr = strings.TrimSpace(expr)
+
+	if name, ok := strings.CutPrefix(expr, "env:"); ok {
+		if value, ok := os.LookupEnv(name); ok {
+			return value, nil
+		}
+		if hasFallback {
+			return fallback, nil
+		}
+		return nil, fmt.Errorf("environment variable %s is not set", name)
+	}
+
+	if value, ok := in.resolved[expr]; ok {
+		return value, nil
+	}
+	for i, key := range in.stack {
+		if key == expr {
+			return nil, fmt.Errorf("%w: %s", ErrInterpolationCycle, strings.Join(append(in.stack[i:], expr), " -> "))
+		}
+	}
+	raw, ok := lookup(in.tree, expr)
+	if !ok {
+		if hasFallback {
+			return fallback, nil
+		}
+		return nil, fmt.Errorf("%w: %s", ErrKeyNotFound, expr)
+	}
+
+	in.stack = append(in.stack, expr)
+	value, err := in.expand(deepCopy(raw))
+	in.stack = in.stack[:len(in.stack)-1]
+	if err != nil {
+		return nil, err
+	}
+	in.resolved[expr] = value
+	return value, nil
+}
+
+// EnableTemplates turns on Go template expansion of string values containing
+// "{{", evaluated against the interpolated tree with funcs plus "env"
+func (m *Manager) EnableTemplates(funcs template.FuncMap) error {
+	all := template.FuncMap{"env": os.Getenv}
+	for name, fn := range funcs {
+		all[name] = fn
+	}
+	m.mu.Lock()
+	m.templates = all
+	m.mu.Unlock()
+	return m.refresh()
+}
+
+// interpolate expands references and, when enabled, templates in values
+func (m *Manager) interpolate(values map[string]interface{}) (map[string]interface{}, error) {
+	out, err := Interpolate(values)
+	if err != nil {
+		return nil, fmt.Errorf("interpolate: %w", err)
+	}
+	m.mu.RLock()
+	funcs := m.templates
+	m.mu.RUnlock()
+	if funcs == nil {
+		return out, nil
+	}
+
+	flat := flatten(out)
+	for key, value := range flat {
+		s, ok := value.(string)
+		if !ok || !strings.Contains(s, "{{") {
+			continue
+		}
+		tmpl, err := template.New(key).Funcs(funcs).Option("missingkey=error").Parse(s)
+		if err != nil {
+			return nil, fmt.Errorf("template %s: %w", key, err)
+		}
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, out); err != nil {
+			return nil, fmt.Errorf("template %s: %w", key, err)
+		}
+		flat[key] = buf.String()
+	}
+	return mergeTrees(out, unflatten(flat)), nil
+}
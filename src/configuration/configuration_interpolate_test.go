@@ -0,0 +1,129 @@
+package configuration
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"text/template"
+)
+
+func TestInterpolate(t *testing.T) {
+	t.Setenv("INTERP_HOST", "db.internal")
+	tests := []struct {
+		name string
+		tree map[string]interface{}
+		want map[string]interface{}
+	}{
+		{
+			"key reference",
+			map[string]interface{}{"host": "h", "url": "http://${host}/x"},
+			map[string]interface{}{"host": "h", "url": "http://h/x"},
+		},
+		{
+			"nested key and chain",
+			map[string]interface{}{"db": map[string]interface{}{"host": "${base}"}, "base": "${root}", "root": "r", "dsn": "${db.host}:1"},
+			map[string]interface{}{"db": map[string]interface{}{"host": "r"}, "base": "r", "root": "r", "dsn": "r:1"},
+		},
+		{
+			"single reference keeps its type",
+			map[string]interface{}{"port": 5432, "copy": "${port}", "tags": []interface{}{"a"}, "alias": "${tags}", "sub": map[string]interface{}{"x": 1}, "sub2": "${sub}"},
+			map[string]interface{}{"port": 5432, "copy": 5432, "tags": []interface{}{"a"}, "alias": []interface{}{"a"}, "sub": map[string]interface{}{"x": 1}, "sub2": map[string]interface{}{"x": 1}},
+		},
+		{
+			"embedded numbers and booleans",
+			map[string]interface{}{"port": 5432, "tls": true, "addr": "h:${port}?tls=${tls}"},
+			map[string]interface{}{"port": 5432, "tls": true, "addr": "h:5432?tls=true"},
+		},
+		{
+			"environment and fallbacks",
+			map[string]interface{}{"a": "${env:INTERP_HOST}", "b": "${env:INTERP_UNSET:-local}", "c": "${missing:-dflt}", "d": "${env:INTERP_HOST:-x}", "e": "${ env:INTERP_HOST }"},
+			map[string]interface{}{"a": "db.internal", "b": "local", "c": "dflt", "d": "db.internal", "e": "db.internal"},
+		},
+		{
+			"escapes",
+			map[string]interface{}{"x": "1", "lit": "$${x}", "mixed": "${x} and $${x}", "plain": "no refs $ { }"},
+			map[string]interface{}{"x": "1", "lit": "${x}", "mixed": "1 and ${x}", "plain": "no refs $ { }"},
+		},
+		{
+			"lists",
+			map[string]interface{}{"x": "1", "list": []interface{}{"${x}", "a${x}", 2}},
+			map[string]interface{}{"x": "1", "list": []interface{}{"1", "a1", 2}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := deepCopy(tt.tree)
+			got, err := Interpolate(tt.tree)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Interpolate = %v, want %v", got, tt.want)
+			}
+			if !reflect.DeepEqual(tt.tree, before) {
+				t.Errorf("Interpolate modified its input: %v", tt.tree)
+			}
+		})
+	}
+}
+
+func TestInterpolateErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		tree  map[string]interface{}
+		is    error
+		match string
+	}{
+		{"self cycle", map[string]interface{}{"a": "${a}"}, ErrInterpolationCycle, "a -> a"},
+		{"two key cycle", map[string]interface{}{"a": "${b}", "b": "x${a}"}, ErrInterpolationCycle, ""},
+		{"three key cycle", map[string]interface{}{"a": "${b}", "b": "${c}", "c": "${a}"}, ErrInterpolationCycle, ""},
+		{"cycle through a parent", map[string]interface{}{"a": map[string]interface{}{"b": "${a}"}}, ErrInterpolationCycle, ""},
+		{"cycle in a list", map[string]interface{}{"a": []interface{}{"${a}"}}, ErrInterpolationCycle, ""},
+		{"missing key", map[string]interface{}{"a": "${nope}"}, ErrKeyNotFound, "nope"},
+		{"missing variable", map[string]interface{}{"a": "${env:INTERP_UNSET}"}, nil, "INTERP_UNSET is not set"},
+		{"unterminated", map[string]interface{}{"a": "x${b"}, nil, "unterminated reference"},
+		{"embedded map", map[string]interface{}{"m": map[string]interface{}{"x": 1}, "a": "x${m}"}, nil, "embed m"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Interpolate(tt.tree)
+			if err == nil {
+				t.Fatal("Interpolate succeeded")
+			}
+			if tt.is != nil && !errors.Is(err, tt.is) {
+				t.Errorf("error = %v, want %v", err, tt.is)
+			}
+			if !strings.Contains(err.Error(), tt.match) {
+				t.Errorf("error = %v, want it to mention %q", err, tt.match)
+			}
+		})
+	}
+}
+
+func TestManagerInterpolation(t *testing.T) {
+	m := NewManager(nil)
+	if err := m.SetLayer(LayerFile, "app.yaml", map[string]interface{}{"base_retries": 4, "retries": "${base_retries}", "log_level": "{{ .level }}", "level": "WARN"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := m.GetConfig().Retries; got != 4 {
+		t.Errorf("retries = %d, want 4 from the reference", got)
+	}
+	if got := m.GetConfig().LogLevel; got != "{{ .level }}" {
+		t.Errorf("log_level = %q, want the template kept verbatim until templates are enabled", got)
+	}
+	if err := m.EnableTemplates(template.FuncMap{}); err != nil {
+		t.Fatal(err)
+	}
+	if got := m.GetConfig().LogLevel; got != "WARN" {
+		t.Errorf("log_level = %q, want WARN from the template", got)
+	}
+
+	// A cycle is rejected and the previous configuration stays
+	if err := m.SetLayer(LayerRuntime, "admin", map[string]interface{}{"retries": "${retries}"}); !errors.Is(err, ErrInterpolationCycle) {
+		t.Fatalf("SetLayer = %v, want an interpolation cycle", err)
+	}
+	if got := m.GetConfig().Retries; got != 4 {
+		t.Errorf("retries after a rejected cycle = %d, want 4", got)
+	}
+}
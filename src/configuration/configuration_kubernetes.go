@@ -0,0 +1,363 @@
+package configuration
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// serviceAccountDir is where Kubernetes mounts pod service account credentials
+const serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+
+// KubernetesKind selects the kind of object read by KubernetesProvider
+type KubernetesKind string
+
+const (
+	// KindConfigMap reads a ConfigMap
+	KindConfigMap KubernetesKind = "configmaps"
+	// KindSecret reads a Secret; values are base64 decoded
+	KindSecret KubernetesKind = "secrets"
+)
+
+// kubernetesTree maps ConfigMap or Secret data into a key tree; entries
+// named like config files (app.yaml, settings.json) are parsed and merged at
+// the root, any other entry name is used as a dotted key
+func kubernetesTree(data map[string]string) (map[string]interface{}, error) {
+	tree := make(map[string]interface{})
+	for _, name := range sortedStringKeys(data) {
+		value := data[name]
+		if format := FormatFromPath(name); format != FormatAuto {
+			doc, err := Parse([]byte(value), format)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+			tree = mergeTrees(tree, doc)
+			continue
+		}
+		setPath(tree, name, strings.TrimRight(value, "\r\n"))
+	}
+	return tree, nil
+}
+
+// sortedStringKeys returns the keys of m in sorted order
+func sortedStringKeys(m map[string]string) []string {
+	generic := make(map[string]interface{}, len(m))
+	for k := range m {
+		generic[k] = nil
+	}
+	return sortedKeys(generic)
+}
+
+// KubernetesProvider reads a ConfigMap or Secret through the Kubernetes API
+// and watches it for updates
+type KubernetesProvider struct {
+	Host      string
+	Kind      KubernetesKind
+	Namespace string
+	Object    string
+	// TokenFile is re-read on every request so rotated service account
+	// tokens are picked up; Token is used when TokenFile is empty
+	TokenFile string
+	Token     string
+	Client    *http.Client
+}
+
+// NewKubernetesProvider creates a provider for the named object on the API
+// server at host
+func NewKubernetesProvider(host string, kind KubernetesKind, namespace, object string) *KubernetesProvider {
+	return &KubernetesProvider{Host: strings.TrimRight(host, "/"), Kind: kind, Namespace: namespace, Object: object}
+}
+
+// InClusterKubernetesProvider creates a provider using the pod's service
+// account; an empty namespace means the pod's own namespace
+func InClusterKubernetesProvider(kind KubernetesKind, namespace, object string) (*KubernetesProvider, error) {
+	host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return nil, errors.New("kubernetes: not running in a cluster")
+	}
+	if namespace == "" {
+		data, err := os.ReadFile(filepath.Join(serviceAccountDir, "namespace"))
+		if err != nil {
+			return nil, fmt.Errorf("kubernetes: read namespace: %w", err)
+		}
+		namespace = strings.TrimSpace(string(data))
+	}
+	ca, err := os.ReadFile(filepath.Join(serviceAccountDir, "ca.crt"))
+	if err != nil {
+		return nil, fmt.Errorf("kubernetes: read CA: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, errors.New("kubernetes: no certificates in CA bundle")
+	}
+
+	p := NewKubernetesProvider("https://"+net.JoinHostPort(host, port), kind, namespace, object)
+	p.TokenFile = filepath.Join(serviceAccountDir, "token")
+	p.Client = &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool}}}
+	return p, nil
+}
+
+// Name returns the provider name
+func (p *KubernetesProvider) Name() string {
+	return fmt.Sprintf("kubernetes:%s/%s/%s", p.Kind, p.Namespace, p.Object)
+}
+
+// kubernetesObject is the subset of a ConfigMap or Secret used here
+type kubernetesObject struct {
+	Metadata struct {
+		ResourceVersion string `json:"resourceVersion"`
+	} `json:"metadata"`
+	Data       map[string]string `json:"data"`
+	BinaryData map[string]string `json:"binaryData"`
+}
+
+// tree decodes the object's data into a key tree
+func (p *KubernetesProvider) tree(obj *kubernetesObject) (map[string]interface{}, error) {
+	data := make(map[string]string, len(obj.Data)+len(obj.BinaryData))
+	for k, v := range obj.Data {
+		if p.Kind == KindSecret {
+			decoded, err := base64.StdEncoding.DecodeString(v)
+			if err != nil {
+				return nil, fmt.Errorf("kubernetes: decode %s: %w", k, err)
+			}
+			v = string(decoded)
+		}
+		data[k] = v
+	}
+	for k, v := range obj.BinaryData {
+		decoded, err := base64.StdEncoding.DecodeString(v)
+		if err != nil {
+			return nil, fmt.Errorf("kubernetes: decode %s: %w", k, err)
+		}
+		data[k] = string(decoded)
+	}
+	return kubernetesTree(data)
+}
+
+// Load fetches the current object
+func (p *KubernetesProvider) Load(ctx context.Context) (map[string]interface{}, error) {
+	obj, err := p.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return p.tree(obj)
+}
+
+// Watch streams updates through the Kubernetes watch API, re-reading the
+// object whenever the watch has to be re-established
+func (p *KubernetesProvider) Watch(ctx context.Context) (<-chan map[string]interface{}, error) {
+	var version string
+	return watchLoop(ctx, func(ctx context.Context, emit func(map[string]interface{})) error {
+		obj, err := p.get(ctx)
+		if err != nil {
+			return err
+		}
+		if version != "" && obj.Metadata.ResourceVersion != version {
+			if tree, err := p.tree(obj); err == nil {
+				emit(tree)
+			}
+		}
+		version = obj.Metadata.ResourceVersion
+
+		query := url.Values{
+			"watch":           {"true"},
+			"fieldSelector":   {"metadata.name=" + p.Object},
+			"resourceVersion": {version},
+		}
+		resp, err := p.do(ctx, fmt.Sprintf("/api/v1/namespaces/%s/%s?%s", url.PathEscape(p.Namespace), p.Kind, query.Encode()))
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+
+		scanner := bufio.NewScanner(resp.Body)
+		scanner.Buffer(make([]byte, 64*1024), 8*1024*1024)
+		for scanner.Scan() {
+			var event struct {
+				Type   string          `json:"type"`
+				Object json.RawMessage `json:"object"`
+			}
+			if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+				return fmt.Errorf("kubernetes: decode watch event: %w", err)
+			}
+			switch event.Type {
+			case "ADDED", "MODIFIED":
+				var obj kubernetesObject
+				if err := json.Unmarshal(event.Object, &obj); err != nil {
+					return fmt.Errorf("kubernetes: decode object: %w", err)
+				}
+				if obj.Metadata.ResourceVersion == version {
+					continue
+				}
+				version = obj.Metadata.ResourceVersion
+				tree, err := p.tree(&obj)
+				if err != nil {
+					return err
+				}
+				emit(tree)
+			case "DELETED":
+				version = ""
+				emit(map[string]interface{}{})
+			case "ERROR":
+				// Usually 410 Gone: the resource version expired, so re-list
+				return errors.New("kubernetes: watch expired")
+			}
+		}
+		if err := scanner.Err(); err != nil {
+			return err
+		}
+		return errors.New("kubernetes: watch closed")
+	}), nil
+}
+
+// get reads the object
+func (p *KubernetesProvider) get(ctx context.Context) (*kubernetesObject, error) {
+	resp, err := p.do(ctx, fmt.Sprintf("/api/v1/namespaces/%s/%s/%s", url.PathEscape(p.Namespace), p.Kind, url.PathEscape(p.Object)))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var obj kubernetesObject
+	if err := json.NewDecoder(resp.Body).Decode(&obj); err != nil {
+		return nil, fmt.Errorf("kubernetes: decode response: %w", err)
+	}
+	return &obj, nil
+}
+
+// do performs an authenticated GET and checks the status
+func (p *KubernetesProvider) do(ctx context.Context, path string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.Host+path, nil)
+	if err != nil {
+		return nil, err
+	}
+	token := p.Token
+	if p.TokenFile != "" {
+		data, err := os.ReadFile(p.TokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("kubernetes: read token: %w", err)
+		}
+		token = strings.TrimSpace(string(data))
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	client := p.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("kubernetes: unexpected status %d", resp.StatusCode)
+	}
+	return resp, nil
+}
+
+// KubernetesVolumeProvider reads a ConfigMap or Secret mounted as a volume,
+// where each key is a file; Kubernetes updates such volumes by atomically
+// swapping a "..data" symlink, which is watched for changes
+type KubernetesVolumeProvider struct {
+	Dir      string
+	Debounce time.Duration
+}
+
+// NewKubernetesVolumeProvider creates a provider for the mount at dir
+func NewKubernetesVolumeProvider(dir string) *KubernetesVolumeProvider {
+	return &KubernetesVolumeProvider{Dir: dir, Debounce: 100 * time.Millisecond}
+}
+
+// Name returns the provider name
+func (p *KubernetesVolumeProvider) Name() string {
+	return "kubernetes-volume:" + p.Dir
+}
+
+// Load reads every key file in the mount
+func (p *KubernetesVolumeProvider) Load(ctx context.Context) (map[string]interface{}, error) {
+	entries, err := os.ReadDir(p.Dir)
+	if err != nil {
+		return nil, fmt.Errorf("kubernetes volume: %w", err)
+	}
+	data := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		// Skip the "..data" and timestamped directories managed by the kubelet
+		if strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		path := filepath.Join(p.Dir, entry.Name())
+		if info, err := os.Stat(path); err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("kubernetes volume: %w", err)
+		}
+		data[entry.Name()] = string(content)
+	}
+	return kubernetesTree(data)
+}
+
+// Watch emits the tree after each debounced change in the mount
+func (p *KubernetesVolumeProvider) Watch(ctx context.Context) (<-chan map[string]interface{}, error) {
+	last, err := p.Load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return watchLoop(ctx, func(ctx context.Context, emit func(map[string]interface{})) error {
+		fsw, err := fsnotify.NewWatcher()
+		if err != nil {
+			return err
+		}
+		defer fsw.Close()
+		if err := fsw.Add(p.Dir); err != nil {
+			return err
+		}
+
+		// Reload once the watch is in place to catch changes made before it
+		timer := time.NewTimer(0)
+		defer timer.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case _, ok := <-fsw.Events:
+				if !ok {
+					return errors.New("kubernetes volume: watcher closed")
+				}
+				timer.Reset(p.Debounce)
+			case err, ok := <-fsw.Errors:
+				if !ok {
+					return errors.New("kubernetes volume: watcher closed")
+				}
+				return err
+			case <-timer.C:
+				tree, err := p.Load(ctx)
+				if err != nil || reflect.DeepEqual(tree, last) {
+					continue
+				}
+				last = tree
+				emit(tree)
+			}
+		}
+	}), nil
+}
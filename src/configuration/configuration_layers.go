@@ -0,0 +1,326 @@
+package configuration
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// Layer is a configuration source class; higher layers take precedence
+type Layer int
+
+const (
+	// LayerDefaults holds built-in default values
+	LayerDefaults Layer = iota
+	// LayerFile holds values loaded from configuration files
+	LayerFile
+	// LayerEnv holds values from environment variables
+	LayerEnv
+	// LayerFlags holds values from command-line flags
+	LayerFlags
+	// LayerRemote holds values from remote providers
+	LayerRemote
+	// LayerRuntime holds overrides applied to the running process
+	LayerRuntime
+	// LayerDotenv holds values from .env files; it ranks below LayerEnv
+	// unless the stack prefers dotenv
+	LayerDotenv
+)
+
+// layerOrder lists layers from lowest to highest precedence
+var layerOrder = []Layer{LayerDefaults, LayerFile, LayerDotenv, LayerEnv, LayerFlags, LayerRemote, LayerRuntime}
+
+// dotenvFirstOrder is layerOrder with the dotenv layer above the environment
+var dotenvFirstOrder = []Layer{LayerDefaults, LayerFile, LayerEnv, LayerDotenv, LayerFlags, LayerRemote, LayerRuntime}
+
+// String returns string representation of Layer
+func (l Layer) String() string {
+	switch l {
+	case LayerDefaults:
+		return "defaults"
+	case LayerFile:
+		return "file"
+	case LayerEnv:
+		return "env"
+	case LayerFlags:
+		return "flags"
+	case LayerRemote:
+		return "remote"
+	case LayerRuntime:
+		return "runtime"
+	case LayerDotenv:
+		return "dotenv"
+	default:
+		return "unknown"
+	}
+}
+
+// Origin describes where the effective value of a key came from
+type Origin struct {
+	Key      string      `json:"key"`
+	Layer    Layer       `json:"-"`
+	LayerID  string      `json:"layer"`
+	Source   string      `json:"source"`
+	Value    interface{} `json:"value"`
+	Shadowed []Origin    `json:"shadowed,omitempty"`
+}
+
+// layerData is the content of one layer
+type layerData struct {
+	source string
+	tree   map[string]interface{}
+}
+
+// LayerStack merges per-layer key trees deterministically by precedence
+type LayerStack struct {
+	mu        sync.RWMutex
+	layers    map[Layer]layerData
+	effective map[string]interface{}
+	origins   map[string]Origin
+	order     []Layer
+}
+
+// NewLayerStack creates a stack with the given defaults
+func NewLayerStack(defaults map[string]interface{}) *LayerStack {
+	s := &LayerStack{layers: make(map[Layer]layerData), order: layerOrder}
+	s.Set(LayerDefaults, "defaults", defaults)
+	return s
+}
+
+// Set replaces the content of a layer and recomputes the merge
+func (s *LayerStack) Set(layer Layer, source string, tree map[string]interface{}) {
+	_ = s.Update(layer, source, tree, nil)
+}
+
+// Update replaces the content of a layer, keeping the change only if check
+// accepts the resulting effective tree
+func (s *LayerStack) Update(layer Layer, source string, tree map[string]interface{}, check func(effective map[string]interface{}) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	prev, had := s.layers[layer]
+	s.layers[layer] = layerData{source: source, tree: copyTree(tree)}
+	s.merge()
+	if check == nil {
+		return nil
+	}
+	if err := check(copyTree(s.effective)); err != nil {
+		if had {
+			s.layers[layer] = prev
+		} else {
+			delete(s.layers, layer)
+		}
+		s.merge()
+		return err
+	}
+	return nil
+}
+
+// PreferDotenv ranks the dotenv layer above (true) or below (false) the
+// environment layer and recomputes the merge
+func (s *LayerStack) PreferDotenv(prefer bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.order = layerOrder
+	if prefer {
+		s.order = dotenvFirstOrder
+	}
+	s.merge()
+}
+
+// clone returns an independent stack with the same layers and order
+func (s *LayerStack) clone() *LayerStack {
+	layers := s.snapshotLayers()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	c := &LayerStack{layers: layers, order: s.order}
+	c.merge()
+	return c
+}
+
+// Clear removes a layer and recomputes the merge
+func (s *LayerStack) Clear(layer Layer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.layers, layer)
+	s.merge()
+}
+
+// merge recomputes the effective tree and per-key origins
+func (s *LayerStack) merge() {
+	flat := make(map[string]interface{})
+	origins := make(map[string]Origin)
+	for _, layer := range s.order {
+		data, ok := s.layers[layer]
+		if !ok {
+			continue
+		}
+		leaves := flatten(data.tree)
+		for _, key := range sortedKeys(leaves) {
+			origin := Origin{Key: key, Layer: layer, LayerID: layer.String(), Source: data.source, Value: leaves[key]}
+			if prev, ok := origins[key]; ok {
+				origin.Shadowed = append([]Origin{{
+					Key: key, Layer: prev.Layer, LayerID: prev.LayerID, Source: prev.Source, Value: prev.Value,
+				}}, prev.Shadowed...)
+			}
+			// A leaf replaces any subtree below it and vice versa
+			for existing := range flat {
+				if isPathPrefix(key, existing) || isPathPrefix(existing, key) {
+					delete(flat, existing)
+					delete(origins, existing)
+				}
+			}
+			flat[key] = leaves[key]
+			origins[key] = origin
+		}
+	}
+	s.effective = unflatten(flat)
+	s.origins = origins
+}
+
+// isPathPrefix reports whether parent is a strict ancestor of key
+func isPathPrefix(parent, key string) bool {
+	return len(key) > len(parent) && key[:len(parent)] == parent && key[len(parent)] == '.'
+}
+
+// Effective returns a copy of the merged tree
+func (s *LayerStack) Effective() map[string]interface{} {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return copyTree(s.effective)
+}
+
+// Layer returns a copy of a single layer's tree
+func (s *LayerStack) Layer(layer Layer) (map[string]interface{}, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	data, ok := s.layers[layer]
+	if !ok {
+		return nil, false
+	}
+	return copyTree(data.tree), true
+}
+
+// Explain reports which layer supplied the effective value of key, along
+// with lower layers whose values it shadows
+func (s *LayerStack) Explain(key string) (*Origin, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	origin, ok := s.origins[key]
+	if !ok {
+		return nil, fmt.Errorf("configuration key %q is not set", key)
+	}
+	return &origin, nil
+}
+
+// Origins returns the origin of every effective leaf key
+func (s *LayerStack) Origins() map[string]Origin {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make(map[string]Origin, len(s.origins))
+	for k, v := range s.origins {
+		out[k] = v
+	}
+	return out
+}
+
+// SetLayer replaces one configuration layer and re-applies the merged result;
+// registered migrations are applied to the layer first, unknown keys are
+// linted, and the layer is rejected if the merged result does not decode
+func (m *Manager) SetLayer(layer Layer, source string, tree map[string]interface{}) error {
+	defer m.deliverChanges()
+	m.writeMu.Lock()
+	defer m.writeMu.Unlock()
+	return m.setLayerLocked(layer, source, tree, nil)
+}
+
+// setValidatedLayer is SetLayer that also rejects a result failing
+// Config.Validate or a registered section schema
+func (m *Manager) setValidatedLayer(layer Layer, source string, tree map[string]interface{}) error {
+	defer m.deliverChanges()
+	m.writeMu.Lock()
+	defer m.writeMu.Unlock()
+	return m.setLayerLocked(layer, source, tree, m.validateValues)
+}
+
+// setLayerLocked implements SetLayer with writeMu held; check, when set,
+// can reject the decoded result before it is applied
+func (m *Manager) setLayerLocked(layer Layer, source string, tree map[string]interface{}, check func(config *Config, values map[string]interface{}) error) error {
+	if err := m.checkWritable(); err != nil {
+		return fmt.Errorf("%s layer: %w", layer, err)
+	}
+
+	tree = m.migrate(layer, source, tree)
+	if err := m.lintLayer(layer, source, tree); err != nil {
+		return fmt.Errorf("%s layer: %w", layer, err)
+	}
+	var config *Config
+	var values map[string]interface{}
+	err := m.layers.Update(layer, source, tree, func(effective map[string]interface{}) error {
+		var err error
+		if config, values, err = m.decodeEffective(effective); err != nil {
+			return err
+		}
+		if check != nil {
+			return check(config, values)
+		}
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("%s layer: %w", layer, err)
+	}
+	m.applyConfig(config, values)
+	return nil
+}
+
+// refresh re-derives the active configuration from the current layers
+func (m *Manager) refresh() error {
+	defer m.deliverChanges()
+	m.writeMu.Lock()
+	defer m.writeMu.Unlock()
+	if err := m.checkWritable(); err != nil {
+		return err
+	}
+
+	config, values, err := m.decodeEffective(m.layers.Effective())
+	if err != nil {
+		return err
+	}
+	m.applyConfig(config, values)
+	return nil
+}
+
+// decodeEffective resolves secret and ${...} references in the merged tree
+// and decodes it
+func (m *Manager) decodeEffective(effective map[string]interface{}) (*Config, map[string]interface{}, error) {
+	m.mu.RLock()
+	secrets := m.secrets
+	m.mu.RUnlock()
+
+	values := effective
+	if secrets != nil {
+		var err error
+		if values, err = secrets.ResolveTree(context.Background(), effective); err != nil {
+			return nil, nil, err
+		}
+	}
+	values, err := m.interpolate(values)
+	if err != nil {
+		return nil, nil, err
+	}
+	config := &Config{}
+	if err := Decode(values, config, false); err != nil {
+		return nil, nil, err
+	}
+	return config, values, nil
+}
+
+// Explain reports which layer supplied the effective value of key
+func (m *Manager) Explain(key string) (*Origin, error) {
+	return m.layers.Explain(key)
+}
+
+// Effective returns a copy of the merged configuration tree
+func (m *Manager) Effective() map[string]interface{} {
+	return m.layers.Effective()
+}
@@ -0,0 +1,161 @@
+package configuration
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestLayerStackPrecedence(t *testing.T) {
+	s := NewLayerStack(map[string]interface{}{"port": 80, "host": "localhost", "db": map[string]interface{}{"user": "app"}})
+	// Insertion order does not matter, only the layer
+	s.Set(LayerRuntime, "admin", map[string]interface{}{"port": 9000})
+	s.Set(LayerEnv, "env:APP", map[string]interface{}{"port": 8080, "db": map[string]interface{}{"pass": "env"}})
+	s.Set(LayerFile, "app.yaml", map[string]interface{}{"port": 8000, "host": "file", "db": map[string]interface{}{"pass": "file"}})
+
+	want := map[string]interface{}{"port": 9000, "host": "file", "db": map[string]interface{}{"user": "app", "pass": "env"}}
+	if got := s.Effective(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("effective = %v, want %v", got, want)
+	}
+
+	origin, err := s.Explain("port")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if origin.Layer != LayerRuntime || origin.Source != "admin" || origin.Value != 9000 {
+		t.Errorf("port origin = %+v, want runtime/admin/9000", origin)
+	}
+	var shadowed []Layer
+	for _, o := range origin.Shadowed {
+		shadowed = append(shadowed, o.Layer)
+	}
+	if want := []Layer{LayerEnv, LayerFile, LayerDefaults}; !reflect.DeepEqual(shadowed, want) {
+		t.Errorf("port shadows %v, want %v", shadowed, want)
+	}
+	if origin, _ := s.Explain("db.user"); origin == nil || origin.Layer != LayerDefaults || len(origin.Shadowed) != 0 {
+		t.Errorf("db.user origin = %+v, want defaults only", origin)
+	}
+	if _, err := s.Explain("missing"); err == nil {
+		t.Error("Explain of an unset key succeeded")
+	}
+
+	s.Clear(LayerRuntime)
+	if got := s.Effective()["port"]; got != 8080 {
+		t.Errorf("port after clearing runtime = %v, want 8080", got)
+	}
+}
+
+func TestLayerStackLeafReplacesSubtree(t *testing.T) {
+	tests := []struct {
+		name        string
+		lower, high map[string]interface{}
+		want        map[string]interface{}
+	}{
+		{
+			"leaf over subtree",
+			map[string]interface{}{"db": map[string]interface{}{"host": "h", "port": 1}},
+			map[string]interface{}{"db": "sqlite://local"},
+			map[string]interface{}{"db": "sqlite://local"},
+		},
+		{
+			"subtree over leaf",
+			map[string]interface{}{"db": "sqlite://local", "x": 1},
+			map[string]interface{}{"db": map[string]interface{}{"host": "h"}},
+			map[string]interface{}{"db": map[string]interface{}{"host": "h"}, "x": 1},
+		},
+		{
+			"deep merge",
+			map[string]interface{}{"a": map[string]interface{}{"b": map[string]interface{}{"c": 1, "d": 2}}},
+			map[string]interface{}{"a": map[string]interface{}{"b": map[string]interface{}{"d": 3}}},
+			map[string]interface{}{"a": map[string]interface{}{"b": map[string]interface{}{"c": 1, "d": 3}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewLayerStack(tt.lower)
+			s.Set(LayerFile, "file", tt.high)
+			if got := s.Effective(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("effective = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLayerStackPreferDotenv(t *testing.T) {
+	s := NewLayerStack(nil)
+	s.Set(LayerDotenv, ".env", map[string]interface{}{"mode": "dotenv"})
+	s.Set(LayerEnv, "env", map[string]interface{}{"mode": "env"})
+	if got := s.Effective()["mode"]; got != "env" {
+		t.Errorf("mode = %v, want the environment to win by default", got)
+	}
+	s.PreferDotenv(true)
+	if got := s.Effective()["mode"]; got != "dotenv" {
+		t.Errorf("mode = %v, want dotenv to win when preferred", got)
+	}
+	s.PreferDotenv(false)
+	if got := s.Effective()["mode"]; got != "env" {
+		t.Errorf("mode = %v after restoring the order, want env", got)
+	}
+}
+
+func TestLayerStackUpdateRollsBack(t *testing.T) {
+	s := NewLayerStack(map[string]interface{}{"port": 80})
+	reject := func(effective map[string]interface{}) error {
+		if effective["port"] == 0 {
+			return errors.New("port must be set")
+		}
+		return nil
+	}
+
+	// A rejected new layer is removed again
+	if err := s.Update(LayerFile, "bad", map[string]interface{}{"port": 0}, reject); err == nil {
+		t.Fatal("Update accepted a rejected layer")
+	}
+	if _, ok := s.Layer(LayerFile); ok {
+		t.Error("a rejected new layer was kept")
+	}
+
+	// A rejected replacement restores the previous content
+	if err := s.Update(LayerFile, "good", map[string]interface{}{"port": 8000}, reject); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Update(LayerFile, "bad", map[string]interface{}{"port": 0}, reject); err == nil {
+		t.Fatal("Update accepted a rejected layer")
+	}
+	if origin, _ := s.Explain("port"); origin == nil || origin.Source != "good" || origin.Value != 8000 {
+		t.Errorf("port origin after a rejected update = %+v, want good/8000", origin)
+	}
+
+	// Later changes to the caller's tree do not leak into the stack
+	tree := map[string]interface{}{"port": 8001}
+	s.Set(LayerFile, "file", tree)
+	tree["port"] = 1
+	if got := s.Effective()["port"]; got != 8001 {
+		t.Errorf("port = %v after mutating the input, want 8001", got)
+	}
+}
+
+func TestManagerSetLayerPrecedence(t *testing.T) {
+	m := NewManager(nil)
+	if err := m.SetLayer(LayerFile, "app.yaml", map[string]interface{}{"retries": 5, "log_level": "DEBUG"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.SetLayer(LayerRuntime, "admin", map[string]interface{}{"retries": 9}); err != nil {
+		t.Fatal(err)
+	}
+	config := m.GetConfig()
+	if config.Retries != 9 || config.LogLevel != "DEBUG" || !config.Enabled {
+		t.Errorf("config = %+v, want runtime retries, file log level and default enabled", config)
+	}
+
+	// A layer that breaks decoding is rejected and the previous config stays
+	if err := m.SetLayer(LayerEnv, "env:APP", map[string]interface{}{"timeout": "soon"}); err == nil {
+		t.Fatal("SetLayer accepted an invalid duration")
+	}
+	if _, ok := m.layers.Layer(LayerEnv); ok {
+		t.Error("the rejected env layer was kept")
+	}
+	if got := m.GetConfig(); !reflect.DeepEqual(got, config) {
+		t.Errorf("config after a rejected layer = %+v, want %+v", got, config)
+	}
+}
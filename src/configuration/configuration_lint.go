@@ -0,0 +1,194 @@
+package configuration
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// KeyIssue is a key that the target schema does not declare
+type KeyIssue struct {
+	Key        string `json:"key"`
+	Suggestion string `json:"suggestion,omitempty"`
+}
+
+// LintKeys reports keys in tree that the type of prototype does not
+// declare, suggesting the nearest declared key for likely typos
+func LintKeys(tree map[string]interface{}, prototype interface{}) []KeyIssue {
+	return lintType(tree, indirectType(reflect.TypeOf(prototype)), "")
+}
+
+// lintType collects the issues of tree against t, sorted by key
+func lintType(tree map[string]interface{}, t reflect.Type, prefix string) []KeyIssue {
+	var issues []KeyIssue
+	lintValue(tree, t, prefix, &issues)
+	sort.Slice(issues, func(i, j int) bool { return issues[i].Key < issues[j].Key })
+	return issues
+}
+
+// lintValue appends the keys in value that t cannot hold
+func lintValue(value interface{}, t reflect.Type, prefix string, issues *[]KeyIssue) {
+	t = indirectType(t)
+	switch t.Kind() {
+	case reflect.Struct:
+		m, ok := value.(map[string]interface{})
+		if !ok || t == reflect.TypeOf(time.Time{}) {
+			return
+		}
+		fields := structFields(t)
+		for k, v := range m {
+			f, ok := fields[strings.ToLower(k)]
+			if !ok {
+				*issues = append(*issues, KeyIssue{Key: joinKey(prefix, k), Suggestion: suggestField(k, fields, prefix)})
+				continue
+			}
+			lintValue(v, f.Type, joinKey(prefix, k), issues)
+		}
+	case reflect.Map:
+		if m, ok := value.(map[string]interface{}); ok {
+			for k, v := range m {
+				lintValue(v, t.Elem(), joinKey(prefix, k), issues)
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		if s, ok := value.([]interface{}); ok {
+			for i, v := range s {
+				lintValue(v, t.Elem(), fmt.Sprintf("%s[%d]", prefix, i), issues)
+			}
+		}
+	}
+}
+
+// suggestField returns the declared key closest to key, or "" when none is
+// close enough to be a plausible typo
+func suggestField(key string, fields map[string]reflect.StructField, prefix string) string {
+	normalized := normalizeKey(key)
+	limit := len(normalized) / 3
+	if limit < 1 {
+		limit = 1
+	}
+	best, bestDistance := "", limit+1
+	for _, f := range fields {
+		name := fieldKey(f)
+		if d := editDistance(normalized, normalizeKey(name)); d < bestDistance || (d == bestDistance && name < best) {
+			best, bestDistance = name, d
+		}
+	}
+	if best == "" {
+		return ""
+	}
+	return joinKey(prefix, best)
+}
+
+// normalizeKey lower-cases key and drops separators, so "logLevel" and
+// "log-level" both match "log_level"
+func normalizeKey(key string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '_' || r == '-' {
+			return -1
+		}
+		return r
+	}, strings.ToLower(key))
+}
+
+// editDistance is the optimal string alignment distance between a and b:
+// insertions, deletions, substitutions and adjacent transpositions
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev2 := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return prev[len(rb)]
+}
+
+// unknownKeysError converts lint issues into an UnknownKeysError, or nil
+func unknownKeysError(issues []KeyIssue) error {
+	if len(issues) == 0 {
+		return nil
+	}
+	err := &UnknownKeysError{Suggestions: make(map[string]string)}
+	for _, issue := range issues {
+		err.Keys = append(err.Keys, issue.Key)
+		if issue.Suggestion != "" {
+			err.Suggestions[issue.Key] = issue.Suggestion
+		}
+	}
+	return err
+}
+
+// SetStrict makes SetLayer reject layers with keys outside Config and the
+// registered section schemas; otherwise such keys are logged as warnings
+func (m *Manager) SetStrict(strict bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.strict = strict
+}
+
+// Lint reports keys of tree that neither Config nor a registered section
+// schema declares
+func (m *Manager) Lint(tree map[string]interface{}) []KeyIssue {
+	m.mu.RLock()
+	schemas := append([]sectionSchema(nil), m.schemas...)
+	m.mu.RUnlock()
+
+	root := copyTree(tree)
+	for _, schema := range schemas {
+		deletePath(root, schema.section)
+	}
+	issues := lintType(root, reflect.TypeOf(Config{}), "")
+	for _, schema := range schemas {
+		if section, ok := lookupSection(tree, schema.section); ok {
+			issues = append(issues, lintType(section, schema.typ, schema.section)...)
+		}
+	}
+	sort.Slice(issues, func(i, j int) bool { return issues[i].Key < issues[j].Key })
+	return issues
+}
+
+// lookupSection returns the map stored under key
+func lookupSection(tree map[string]interface{}, key string) (map[string]interface{}, bool) {
+	value, ok := lookup(tree, key)
+	if !ok {
+		return nil, false
+	}
+	section, ok := value.(map[string]interface{})
+	return section, ok
+}
+
+// lintLayer warns about or, in strict mode, rejects unknown keys in a layer
+func (m *Manager) lintLayer(layer Layer, source string, tree map[string]interface{}) error {
+	issues := m.Lint(tree)
+	m.mu.RLock()
+	strict := m.strict
+	m.mu.RUnlock()
+	if strict {
+		return unknownKeysError(issues)
+	}
+	for _, issue := range issues {
+		if issue.Suggestion != "" {
+			m.logger.Printf("Unknown configuration key %s in %s layer (%s); did you mean %s?", issue.Key, layer, source, issue.Suggestion)
+		} else {
+			m.logger.Printf("Unknown configuration key %s in %s layer (%s)", issue.Key, layer, source)
+		}
+	}
+	return nil
+}
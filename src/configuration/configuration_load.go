@@ -0,0 +1,244 @@
+package configuration
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// Format identifies a configuration file encoding
+type Format string
+
+const (
+	// FormatAuto detects the format from the file extension or content
+	FormatAuto Format = ""
+	// FormatJSON is a JSON document
+	FormatJSON Format = "json"
+	// FormatYAML is a YAML document
+	FormatYAML Format = "yaml"
+	// FormatTOML is a TOML document
+	FormatTOML Format = "toml"
+)
+
+// loadOptions holds settings shared by Load and LoadReader
+type loadOptions struct {
+	format  Format
+	strict  bool
+	keys    KeyWrapper
+	trusted KeyProvider
+}
+
+// LoadOption configures Load and LoadReader
+type LoadOption func(*loadOptions)
+
+// WithFormat forces the input format instead of auto-detecting it
+func WithFormat(format Format) LoadOption {
+	return func(o *loadOptions) {
+		o.format = format
+	}
+}
+
+// WithStrict rejects documents containing keys the target struct does not declare
+func WithStrict() LoadOption {
+	return func(o *loadOptions) {
+		o.strict = true
+	}
+}
+
+// FormatFromPath returns the format implied by a file extension, or FormatAuto
+func FormatFromPath(path string) Format {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return FormatJSON
+	case ".yaml", ".yml":
+		return FormatYAML
+	case ".toml":
+		return FormatTOML
+	case ".env":
+		return FormatDotenv
+	default:
+		if strings.HasPrefix(filepath.Base(path), ".env.") {
+			return FormatDotenv
+		}
+		return FormatAuto
+	}
+}
+
+// DetectFormat guesses the format of a document from its content
+func DetectFormat(data []byte) Format {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') && json.Valid(trimmed) {
+		return FormatJSON
+	}
+	for _, line := range strings.Split(string(trimmed), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			return FormatTOML
+		}
+		if eq, colon := strings.Index(line, "="), strings.Index(line, ":"); eq > 0 && (colon < 0 || eq < colon) {
+			return FormatTOML
+		}
+		break
+	}
+	return FormatYAML
+}
+
+// Parse decodes a document into a generic key tree
+func Parse(data []byte, format Format) (map[string]interface{}, error) {
+	if format == FormatAuto {
+		format = DetectFormat(data)
+	}
+
+	tree := make(map[string]interface{})
+	switch format {
+	case FormatJSON:
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.UseNumber()
+		if err := dec.Decode(&tree); err != nil {
+			return nil, fmt.Errorf("parse json: %w", err)
+		}
+	case FormatYAML:
+		if err := yaml.Unmarshal(data, &tree); err != nil {
+			return nil, fmt.Errorf("parse yaml: %w", err)
+		}
+	case FormatTOML:
+		if err := toml.Unmarshal(data, &tree); err != nil {
+			return nil, fmt.Errorf("parse toml: %w", err)
+		}
+	case FormatDotenv:
+		vars, err := ParseDotenv(data)
+		if err != nil {
+			return nil, fmt.Errorf("parse dotenv: %w", err)
+		}
+		tree = EnvTree("", dotenvEnviron(vars))
+	default:
+		return nil, fmt.Errorf("unsupported format %q", format)
+	}
+	return tree, nil
+}
+
+// Load reads the file at path, including any files it names in an
+// "include" directive, and unmarshals it into v
+func Load(path string, v interface{}, opts ...LoadOption) error {
+	options := loadOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	tree, err := readTree(path, options, nil)
+	if err != nil {
+		return err
+	}
+	if err := Decode(tree, v, options.strict); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	return nil
+}
+
+// LoadReader reads a document from r and unmarshals it into v
+func LoadReader(r io.Reader, v interface{}, opts ...LoadOption) error {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return fmt.Errorf("read config: %w", err)
+	}
+	return load(data, v, opts)
+}
+
+// load parses data and decodes the resulting tree into v
+func load(data []byte, v interface{}, opts []LoadOption) error {
+	options := loadOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	data, err := decryptIfNeeded(data, &options)
+	if err != nil {
+		return err
+	}
+	tree, err := Parse(data, options.format)
+	if err != nil {
+		return err
+	}
+	return Decode(tree, v, options.strict)
+}
+
+// ReadTree parses the file at path into a generic key tree, resolving
+// "include" directives
+func ReadTree(path string, opts ...LoadOption) (map[string]interface{}, error) {
+	options := loadOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	tree, err := readTree(path, options, nil)
+	if err != nil {
+		return nil, err
+	}
+	if options.strict {
+		if err := Decode(tree, &Config{}, true); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	return tree, nil
+}
+
+// readTree parses one file and merges its includes beneath it; stack holds
+// the files currently being read, for cycle detection
+func readTree(path string, options loadOptions, stack []string) (map[string]interface{}, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read config: %w", err)
+	}
+	if options.trusted != nil {
+		if err := verifyFileData(options.trusted, path, data); err != nil {
+			return nil, err
+		}
+	}
+	if options.format == FormatAuto {
+		options.format = FormatFromPath(path)
+	}
+	if IsEncrypted(data) {
+		// The envelope records the plaintext format
+		options.format = FormatAuto
+	}
+	if data, err = decryptIfNeeded(data, &options); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	tree, err := Parse(data, options.format)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return resolveIncludes(path, tree, options, stack)
+}
+
+// LoadFile sets the file layer of the manager configuration from path;
+// with trusted keys set, the file must carry a valid signature
+func (m *Manager) LoadFile(path string, opts ...LoadOption) error {
+	m.mu.RLock()
+	trusted, keys := m.trustedKeys, m.keyWrapper
+	m.mu.RUnlock()
+	if keys != nil {
+		opts = append([]LoadOption{WithKeyWrapper(keys)}, opts...)
+	}
+	if trusted != nil {
+		opts = append([]LoadOption{WithTrustedKeys(trusted)}, opts...)
+	}
+	tree, err := ReadTree(path, opts...)
+	if err == nil {
+		err = m.SetLayer(LayerFile, path, tree)
+	}
+	if err != nil {
+		m.logger.Printf("Configuration load failed: %v", err)
+		return err
+	}
+	m.logger.Printf("Loaded configuration from %s", path)
+	return nil
+}
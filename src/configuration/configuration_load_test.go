@@ -0,0 +1,162 @@
+package configuration
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// loadTarget exercises the conversions Decode performs
+type loadTarget struct {
+	Name    string        `json:"name"`
+	Timeout time.Duration `json:"timeout"`
+	Retries int           `json:"retries"`
+	Ratio   float64       `json:"ratio"`
+	Debug   bool          `json:"debug"`
+	Hosts   []string      `json:"hosts"`
+	DB      struct {
+		Port    int           `json:"port"`
+		MaxIdle time.Duration `json:"max_idle"`
+	} `json:"db"`
+}
+
+func TestLoadFormats(t *testing.T) {
+	want := loadTarget{Name: "api", Timeout: 45 * time.Second, Retries: 3, Ratio: 0.5, Debug: true, Hosts: []string{"a", "b"}}
+	want.DB.Port = 5432
+	want.DB.MaxIdle = 90 * time.Second
+
+	documents := map[string]string{
+		"app.json": `{"name": "api", "timeout": "45s", "retries": 3, "ratio": 0.5, "debug": true,
+			"hosts": ["a", "b"], "db": {"port": 5432, "max_idle": "1m30s"}}`,
+		"app.yaml": "name: api\ntimeout: 45s\nretries: 3\nratio: 0.5\ndebug: true\nhosts: [a, b]\ndb:\n  port: 5432\n  max_idle: 1m30s\n",
+		"app.toml": "name = \"api\"\ntimeout = \"45s\"\nretries = 3\nratio = 0.5\ndebug = true\nhosts = [\"a\", \"b\"]\n\n[db]\nport = 5432\nmax_idle = \"1m30s\"\n",
+		// Without a known extension the format is detected from the content
+		"app.conf": "name = \"api\"\ntimeout = \"45s\"\nretries = 3\nratio = 0.5\ndebug = true\nhosts = [\"a\", \"b\"]\n\n[db]\nport = 5432\nmax_idle = \"1m30s\"\n",
+		"app.cfg":  "name: api\ntimeout: 45s\nretries: \"3\"\nratio: \"0.5\"\ndebug: \"true\"\nhosts: a, b\ndb:\n  port: \"5432\"\n  max_idle: 1m30s\n",
+	}
+	dir := t.TempDir()
+	for name, data := range documents {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(dir, name)
+			if err := os.WriteFile(path, []byte(data), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			var got loadTarget
+			if err := Load(path, &got, WithStrict()); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Load = %+v, want %+v", got, want)
+			}
+
+			var read loadTarget
+			if err := LoadReader(strings.NewReader(data), &read); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(read, want) {
+				t.Errorf("LoadReader = %+v, want %+v", read, want)
+			}
+		})
+	}
+}
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		data string
+		want Format
+	}{
+		{`{"a": 1}`, FormatJSON},
+		{`  [1, 2]`, FormatJSON},
+		{"{not json", FormatYAML},
+		{"a: 1", FormatYAML},
+		{"# comment\n\nurl: http://x?a=b", FormatYAML},
+		{"a = 1", FormatTOML},
+		{"# comment\n[server]\nport = 1", FormatTOML},
+		{"", FormatYAML},
+	}
+	for _, tt := range tests {
+		if got := DetectFormat([]byte(tt.data)); got != tt.want {
+			t.Errorf("DetectFormat(%q) = %q, want %q", tt.data, got, tt.want)
+		}
+	}
+
+	paths := map[string]Format{
+		"a.json": FormatJSON, "a.YAML": FormatYAML, "a.yml": FormatYAML, "a.toml": FormatTOML,
+		".env": FormatDotenv, "dir/.env.local": FormatDotenv, "a.conf": FormatAuto,
+	}
+	for path, want := range paths {
+		if got := FormatFromPath(path); got != want {
+			t.Errorf("FormatFromPath(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestDecodeErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		tree map[string]interface{}
+		want string
+	}{
+		{"duration", map[string]interface{}{"timeout": "soon"}, `timeout: invalid duration "soon"`},
+		{"integer", map[string]interface{}{"retries": "three"}, `retries: invalid integer "three"`},
+		{"number", map[string]interface{}{"ratio": "half"}, `ratio: invalid number "half"`},
+		{"boolean", map[string]interface{}{"debug": "maybe"}, `debug: invalid boolean "maybe"`},
+		{"nested", map[string]interface{}{"db": map[string]interface{}{"max_idle": "1x"}}, `db.max_idle: invalid duration "1x"`},
+		{"type", map[string]interface{}{"hosts": map[string]interface{}{}}, "decode config"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got loadTarget
+			if err := Decode(tt.tree, &got, false); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Decode error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+
+	var target loadTarget
+	if err := Decode(map[string]interface{}{}, target, false); err == nil {
+		t.Error("Decode into a non-pointer succeeded")
+	}
+}
+
+func TestDecodeStrict(t *testing.T) {
+	tree := map[string]interface{}{"name": "api", "timout": "1s", "db": map[string]interface{}{"prot": 1}}
+	var got loadTarget
+	if err := Decode(tree, &got, false); err != nil || got.Name != "api" {
+		t.Fatalf("lenient Decode = %v, name %q", err, got.Name)
+	}
+
+	err := Decode(tree, &got, true)
+	var unknown *UnknownKeysError
+	if !errors.As(err, &unknown) {
+		t.Fatalf("strict Decode = %v, want an UnknownKeysError", err)
+	}
+	if want := []string{"db.prot", "timout"}; !reflect.DeepEqual(unknown.Keys, want) {
+		t.Errorf("unknown keys = %v, want %v", unknown.Keys, want)
+	}
+	if unknown.Suggestions["timout"] != "timeout" || unknown.Suggestions["db.prot"] != "db.port" {
+		t.Errorf("suggestions = %v", unknown.Suggestions)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	dir := t.TempDir()
+	bad := filepath.Join(dir, "bad.json")
+	if err := os.WriteFile(bad, []byte(`{"name": `), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	var got loadTarget
+	if err := Load(bad, &got); err == nil || !strings.Contains(err.Error(), "parse json") {
+		t.Errorf("Load of malformed JSON = %v, want a parse error", err)
+	}
+	if err := Load(filepath.Join(dir, "missing.yaml"), &got); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("Load of a missing file = %v, want ErrNotExist", err)
+	}
+	if err := LoadReader(strings.NewReader("a: 1"), &got, WithFormat("ini")); err == nil || !strings.Contains(err.Error(), "unsupported format") {
+		t.Errorf("LoadReader with an unknown format = %v", err)
+	}
+}
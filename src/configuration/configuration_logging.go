@@ -0,0 +1,35 @@
+package configuration
+
+import (
+	"context"
+
+	"github.com/nerufuyo/roastume/src/logging"
+)
+
+// applyLogLevel pushes a changed log_level to the process-wide logging spec,
+// so every manager's logger follows hot reloads immediately; old is nil on
+// start-up
+func (m *Manager) applyLogLevel(old, config *Config) {
+	if old != nil && old.LogLevel == config.LogLevel {
+		return
+	}
+	if err := logging.Configure(config.LogLevel); err != nil {
+		m.logger.Warnf("Keeping log level %s: %v", logging.CurrentSpec(), err)
+		return
+	}
+	if old != nil {
+		m.logger.Printf("Log level set to %s", logging.CurrentSpec())
+	}
+}
+
+// SetLogLevel changes the log level at runtime, e.g. "DEBUG" or
+// "INFO,authentication=DEBUG,validation=WARN"; the change is recorded in
+// the runtime layer like any other patch
+func (m *Manager) SetLogLevel(ctx context.Context, spec string) error {
+	return m.ApplyPatch(ctx, map[string]interface{}{"log_level": spec}, WithPatchSource("log-level"))
+}
+
+// LogLevel returns the process-wide logging spec currently in effect
+func (m *Manager) LogLevel() logging.Spec {
+	return logging.CurrentSpec()
+}
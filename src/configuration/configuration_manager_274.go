@@ -0,0 +1,260 @@
+// Package configuration provides professional configuration functionality
+// with comprehensive error handling, logging, and configuration management
+// following Go best practices and idiomatic patterns.
+//
+// Author: Automation System
+// Version: 1.0.0
+// Created: 2025-07-04T14:04:26.600245
+package configuration
+
+import (
+	"context"
+	"crypto/ed25519"
+	"fmt"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/nerufuyo/roastume/src/logging"
+	"github.com/nerufuyo/roastume/src/manager"
+	"github.com/nerufuyo/roastume/src/validation"
+)
+
+// Status represents the current state of configuration operations
+type Status = manager.Status
+
+const (
+	// StatusPending indicates operation is pending
+	StatusPending = manager.StatusPending
+	// StatusProcessing indicates operation is in progress
+	StatusProcessing = manager.StatusProcessing
+	// StatusCompleted indicates operation completed successfully
+	StatusCompleted = manager.StatusCompleted
+	// StatusFailed indicates operation failed
+	StatusFailed = manager.StatusFailed
+	// StatusCanceled indicates operation was canceled before it completed
+	StatusCanceled = manager.StatusCanceled
+)
+
+// OperationID identifies one Process call, see Result.OperationID
+type OperationID = manager.OperationID
+
+// ErrTimeout is returned by Process when an operation outlasts Config.Timeout
+var ErrTimeout = manager.ErrTimeout
+
+// Config holds configuration settings for configuration operations
+type Config struct {
+	Enabled   bool          `json:"enabled" desc:"Whether processing is enabled"`
+	Timeout   time.Duration `json:"timeout" desc:"Deadline for processing, retries included, when the caller sets none"`
+	Retries   int           `json:"retries" desc:"Attempts after the first failure"`
+	RetryMaxElapsed time.Duration `json:"retry_max_elapsed" desc:"Time after which failures are no longer retried; 0 retries until Retries or Timeout runs out"`
+	MaxConcurrency  int           `json:"max_concurrency" desc:"Operations processed at once; 0 is unlimited"`
+	LogLevel  string        `json:"log_level" desc:"Log level with optional per-package overrides, e.g. INFO,authentication=DEBUG"`
+	HistoryLimit int           `json:"history_limit" desc:"Number of configuration versions kept for rollback"`
+}
+
+// DefaultConfig returns a default configuration
+func DefaultConfig() *Config {
+	return &Config{
+		Enabled:  true,
+		Timeout:  30 * time.Second,
+		Retries:  3,
+		LogLevel: "INFO",
+		HistoryLimit: 20,
+	}
+}
+
+// core returns the settings the manager core enforces
+func (c *Config) core() manager.Config {
+	return manager.Config{
+		Timeout: c.Timeout,
+		Retries: c.Retries,
+		Backoff: manager.Backoff{MaxElapsed: c.RetryMaxElapsed},
+		MaxConcurrency: c.MaxConcurrency,
+	}
+}
+
+// Result represents the result of a configuration operation
+type Result struct {
+	Status        string    `json:"status"`
+	ProcessedAt   time.Time `json:"processed_at"`
+	DataSize      int       `json:"data_size"`
+	ProcessingTime time.Duration `json:"processing_time"`
+	Message       string    `json:"message,omitempty"`
+	OperationID   OperationID `json:"operation_id,omitempty"`
+}
+
+// Manager provides professional configuration management functionality
+type Manager struct {
+	*manager.Manager[interface{}, *Result]
+	config    *Config
+	mu        sync.RWMutex
+	logger    *logging.Logger
+	subscribers subscriptions
+	layers      *LayerStack
+	writeMu     sync.Mutex
+	notices     notices
+	values      map[string]interface{}
+	secrets     *Secrets
+	history     history
+	sensitive   []sensitiveRule
+	secretRules []sensitiveRule
+	events      *EventBus
+	templates   template.FuncMap
+	snapshotPath string
+	staleSince   time.Time
+	audit        *AuditLog
+	stopAudit    func()
+	migrations   []Migration
+	deprecations map[Layer][]DeprecationWarning
+	frozen       bool
+	schemas      []sectionSchema
+	scheduled    schedule
+	instance     Instance
+	rollouts     map[string]*RolloutStatus
+	tenants      tenants
+	strict       bool
+	metrics      *ReadMetrics
+	signKeyID    string
+	signKey      ed25519.PrivateKey
+	keyWrapper   KeyWrapper
+	trustedKeys  KeyProvider
+	providers    []Provider
+	validation   *validation.Manager
+	sectionRules map[string][]*validation.RuleSet
+}
+
+// ManagerInterface defines the interface for configuration operations
+type ManagerInterface interface {
+	Process(ctx context.Context, data interface{}) (*Result, error)
+	ProcessAsync(ctx context.Context, data interface{}) <-chan *Result
+	Validate(data interface{}) error
+	GetStatus() Status
+	Reset()
+	Close() error
+}
+
+// NewManager creates a new configuration manager instance
+func NewManager(config *Config) *Manager {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	
+	m := &Manager{
+		config:    config,
+		logger:    logging.New("configuration", "[CONFIGURATION] "),
+		layers:    NewLayerStack(structTree(config)),
+		events:    NewEventBus(),
+	}
+	m.Manager = manager.New(manager.Options{Name: "configuration", Logger: m.logger}, manager.Operation[interface{}, *Result]{
+		// Request-scoped overrides carried by ctx take precedence
+		Config: func(ctx context.Context) (manager.Config, error) {
+			m.mu.RLock()
+			config, err := withContextOverrides(ctx, m.config)
+			m.mu.RUnlock()
+			if err != nil {
+				return manager.Config{}, err
+			}
+			return config.core(), nil
+		},
+		Validate: func(ctx context.Context, data interface{}) error {
+			return m.Validate(data)
+		},
+		Execute: m.executeProcessing,
+		Complete: func(id OperationID, result *Result, elapsed time.Duration) {
+			result.OperationID = id
+			result.ProcessingTime = elapsed
+		},
+	})
+	m.values = m.layers.Effective()
+	m.history.record(m.layers.snapshotLayers(), m.values, config.HistoryLimit)
+	
+	m.setupLogging()
+	return m
+}
+
+// setupLogging configures logging for the manager; LogLevel sets the
+// process-wide level of every manager's logger
+func (m *Manager) setupLogging() {
+	m.applyLogLevel(nil, m.config)
+	m.logger.Printf("Initialized configuration manager with configuration")
+}
+
+// ProcessAsync executes configuration processing asynchronously
+func (m *Manager) ProcessAsync(ctx context.Context, data interface{}) <-chan *Result {
+	resultChan := make(chan *Result, 1)
+	
+	go func() {
+		defer close(resultChan)
+		
+		result, err := m.Process(ctx, data)
+		if err != nil {
+			id, _ := manager.OperationIDOf(err)
+			result = &Result{
+				Status:      "error",
+				Message:     err.Error(),
+				OperationID: id,
+			}
+		}
+		
+		select {
+		case resultChan <- result:
+		case <-ctx.Done():
+			return
+		}
+	}()
+	
+	return resultChan
+}
+
+// Validate validates input data according to business rules
+func (m *Manager) Validate(data interface{}) error {
+	if data == nil {
+		m.logger.Warnf("Validation failed: data is nil")
+		return fmt.Errorf("data cannot be nil")
+	}
+	
+	m.logger.Debugf("Data validation passed")
+	return nil
+}
+
+// executeProcessing performs the core processing logic
+func (m *Manager) executeProcessing(ctx context.Context, data interface{}) (*Result, error) {
+	// Simulate processing with context cancellation support
+	select {
+	case <-time.After(100 * time.Millisecond):
+		// Processing completed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	
+	dataStr := fmt.Sprintf("%v", data)
+	
+	result := &Result{
+		Status:      "success",
+		ProcessedAt: time.Now(),
+		DataSize:    len(dataStr),
+		Message:     "Configuration processing completed successfully",
+	}
+	
+	return result, nil
+}
+
+// GetConfig returns a copy of the current configuration; changes to the
+// copy do not affect the manager
+func (m *Manager) GetConfig() *Config {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	config := *m.config
+	return &config
+}
+
+// Factory function to create configuration manager with default configuration
+func CreateConfigurationManager() *Manager {
+	return NewManager(DefaultConfig())
+}
+
+// Factory function to create configuration manager with custom configuration
+func CreateConfigurationManagerWithConfig(config *Config) *Manager {
+	return NewManager(config)
+}
@@ -0,0 +1,164 @@
+package configuration
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// KeyStats are the read counters of a single key
+type KeyStats struct {
+	Key          string        `json:"key"`
+	Reads        uint64        `json:"reads"`
+	Misses       uint64        `json:"misses"`
+	LastRead     time.Time     `json:"last_read"`
+	TotalLatency time.Duration `json:"total_latency"`
+	MaxLatency   time.Duration `json:"max_latency"`
+	Layer        string        `json:"layer,omitempty"`
+}
+
+// MeanLatency returns the average latency of the reads of the key
+func (s KeyStats) MeanLatency() time.Duration {
+	if s.Reads == 0 {
+		return 0
+	}
+	return s.TotalLatency / time.Duration(s.Reads)
+}
+
+// ReadMetrics counts configuration reads per key
+type ReadMetrics struct {
+	mu    sync.Mutex
+	since time.Time
+	keys  map[string]*KeyStats
+}
+
+// NewReadMetrics creates empty read metrics
+func NewReadMetrics() *ReadMetrics {
+	return &ReadMetrics{since: time.Now(), keys: make(map[string]*KeyStats)}
+}
+
+// observe records one read of key; it is a no-op on nil metrics
+func (r *ReadMetrics) observe(key string, found bool, latency time.Duration) {
+	if r == nil {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stats, ok := r.keys[key]
+	if !ok {
+		stats = &KeyStats{Key: key}
+		r.keys[key] = stats
+	}
+	stats.Reads++
+	if !found {
+		stats.Misses++
+	}
+	stats.LastRead = time.Now()
+	stats.TotalLatency += latency
+	if latency > stats.MaxLatency {
+		stats.MaxLatency = latency
+	}
+}
+
+// Since returns when counting started or was last reset
+func (r *ReadMetrics) Since() time.Time {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.since
+}
+
+// Snapshot returns the stats of every key read so far, ordered by key
+func (r *ReadMetrics) Snapshot() []KeyStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]KeyStats, 0, len(r.keys))
+	for _, stats := range r.keys {
+		out = append(out, *stats)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Key < out[j].Key })
+	return out
+}
+
+// Hottest returns the n most read keys, most read first
+func (r *ReadMetrics) Hottest(n int) []KeyStats {
+	out := r.Snapshot()
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Reads > out[j].Reads })
+	if n >= 0 && n < len(out) {
+		out = out[:n]
+	}
+	return out
+}
+
+// Reset clears all counters
+func (r *ReadMetrics) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.since = time.Now()
+	r.keys = make(map[string]*KeyStats)
+}
+
+// read reports whether key or one of its ancestors has been read
+func (r *ReadMetrics) read(key string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for candidate := range r.keys {
+		if keyWithin(key, candidate) {
+			return true
+		}
+	}
+	return false
+}
+
+// EnableReadMetrics starts counting reads made through Get and the typed
+// getters and returns the metrics
+func (m *Manager) EnableReadMetrics() *ReadMetrics {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.metrics == nil {
+		m.metrics = NewReadMetrics()
+	}
+	return m.metrics
+}
+
+// ReadMetrics returns the metrics started by EnableReadMetrics, or nil
+func (m *Manager) ReadMetrics() *ReadMetrics {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.metrics
+}
+
+// HotKeys returns the n most read keys, each annotated with the layer its
+// current value comes from so reads of remote-backed keys stand out
+func (m *Manager) HotKeys(n int) []KeyStats {
+	metrics := m.ReadMetrics()
+	if metrics == nil {
+		return nil
+	}
+	hot := metrics.Hottest(n)
+	for i := range hot {
+		if origin, err := m.layers.Explain(hot[i].Key); err == nil {
+			hot[i].Layer = origin.Layer.String()
+		}
+	}
+	return hot
+}
+
+// UnreadKeys returns the leaf keys of the effective configuration that have
+// not been read since metrics were enabled; reading a section counts as
+// reading every key below it
+func (m *Manager) UnreadKeys() []string {
+	m.mu.RLock()
+	metrics := m.metrics
+	flat := flatten(m.values)
+	m.mu.RUnlock()
+	if metrics == nil {
+		return nil
+	}
+	var unread []string
+	for _, key := range sortedKeys(flat) {
+		if !metrics.read(key) {
+			unread = append(unread, key)
+		}
+	}
+	return unread
+}
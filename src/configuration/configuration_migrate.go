@@ -0,0 +1,131 @@
+package configuration
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Migration maps a deprecated key onto its replacement
+type Migration struct {
+	// From is the deprecated dotted key
+	From string
+	// To is the replacement key; empty drops the deprecated key
+	To string
+	// Transform converts the old value; nil copies it unchanged
+	Transform func(old interface{}) (interface{}, error)
+	// Note is appended to the deprecation warning
+	Note string
+}
+
+// DeprecationWarning reports a deprecated key found in a layer
+type DeprecationWarning struct {
+	Key         string `json:"key"`
+	Replacement string `json:"replacement,omitempty"`
+	Layer       Layer  `json:"layer"`
+	Source      string `json:"source"`
+	Migrated    bool   `json:"migrated"`
+	Reason      string `json:"reason,omitempty"`
+}
+
+// RegisterMigration adds a migration applied to every layer set afterwards,
+// in registration order so migrations can be chained
+func (m *Manager) RegisterMigration(migration Migration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.migrations = append(m.migrations, migration)
+}
+
+// Deprecate registers a plain rename of from to to
+func (m *Manager) Deprecate(from, to string) {
+	m.RegisterMigration(Migration{From: from, To: to})
+}
+
+// Deprecations returns the warnings produced by the current layers, ordered
+// by layer and key
+func (m *Manager) Deprecations() []DeprecationWarning {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var out []DeprecationWarning
+	for _, layer := range layerOrder {
+		out = append(out, m.deprecations[layer]...)
+	}
+	return out
+}
+
+// UnmigratedKeys lists deprecated keys that could not be migrated
+func (m *Manager) UnmigratedKeys() []string {
+	var keys []string
+	for _, w := range m.Deprecations() {
+		if !w.Migrated {
+			keys = append(keys, w.Key)
+		}
+	}
+	return keys
+}
+
+// migrate applies the registered migrations to tree, logging and recording
+// the resulting warnings for layer
+func (m *Manager) migrate(layer Layer, source string, tree map[string]interface{}) map[string]interface{} {
+	m.mu.RLock()
+	migrations := m.migrations
+	m.mu.RUnlock()
+	if len(migrations) == 0 {
+		return tree
+	}
+
+	migrated, warnings := applyMigrations(migrations, layer, source, tree)
+	for _, w := range warnings {
+		m.logger.Printf("deprecated_key=%q replacement=%q layer=%s source=%q migrated=%t reason=%q",
+			w.Key, w.Replacement, layer, source, w.Migrated, w.Reason)
+	}
+	m.mu.Lock()
+	if m.deprecations == nil {
+		m.deprecations = make(map[Layer][]DeprecationWarning)
+	}
+	m.deprecations[layer] = warnings
+	m.mu.Unlock()
+	return migrated
+}
+
+// applyMigrations returns a migrated copy of tree and the warnings raised
+func applyMigrations(migrations []Migration, layer Layer, source string, tree map[string]interface{}) (map[string]interface{}, []DeprecationWarning) {
+	flat := flatten(tree)
+	var warnings []DeprecationWarning
+	for _, mig := range migrations {
+		for _, key := range sortedKeys(flat) {
+			if !keyWithin(key, mig.From) {
+				continue
+			}
+			warning := DeprecationWarning{Key: key, Replacement: mig.To, Layer: layer, Source: source}
+			target := ""
+			if mig.To != "" {
+				target = mig.To + key[len(mig.From):]
+			}
+
+			value := flat[key]
+			var err error
+			if mig.Transform != nil {
+				value, err = mig.Transform(value)
+			}
+			switch _, exists := flat[target]; {
+			case err != nil:
+				warning.Reason = fmt.Sprintf("transform failed: %v", err)
+			case target != "" && exists:
+				warning.Reason = fmt.Sprintf("%s is also set; keeping it", target)
+				delete(flat, key)
+			default:
+				delete(flat, key)
+				if target != "" {
+					flat[target] = value
+				}
+				warning.Migrated = true
+			}
+			if mig.Note != "" && warning.Reason == "" {
+				warning.Reason = mig.Note
+			}
+			warnings = append(warnings, warning)
+		}
+	}
+	sort.SliceStable(warnings, func(i, j int) bool { return warnings[i].Key < warnings[j].Key })
+	return unflatten(flat), warnings
+}
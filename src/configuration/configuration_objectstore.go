@@ -0,0 +1,392 @@
+package configuration
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// RequestSigner authorizes requests to an object store
+type RequestSigner interface {
+	Sign(ctx context.Context, req *http.Request) error
+}
+
+// ObjectProvider loads a configuration document from object storage (S3, GCS,
+// Azure Blob or any HTTP server honouring ETags) and polls it for changes
+type ObjectProvider struct {
+	URL string
+	// Signer authorizes requests; leave nil for pre-signed URLs
+	Signer   RequestSigner
+	Format   Format
+	Interval time.Duration
+	// CacheFile keeps the last fetched document so startup succeeds while
+	// the store is unreachable
+	CacheFile string
+	Client    *http.Client
+
+	mu   sync.Mutex
+	etag string
+}
+
+// NewObjectProvider creates a provider for a plain or pre-signed object URL
+func NewObjectProvider(rawURL string, signer RequestSigner) *ObjectProvider {
+	return &ObjectProvider{URL: rawURL, Signer: signer, Interval: 30 * time.Second}
+}
+
+// NewS3Provider creates a provider for an S3 object using SigV4 signing
+func NewS3Provider(region, bucket, key string, credentials AWSCredentialsFunc) *ObjectProvider {
+	rawURL := fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", bucket, region, escapeObjectKey(key))
+	return NewObjectProvider(rawURL, &SigV4Signer{Region: region, Service: "s3", Credentials: credentials})
+}
+
+// NewGCSProvider creates a provider for a GCS object using the instance's
+// service account
+func NewGCSProvider(bucket, object string) *ObjectProvider {
+	rawURL := fmt.Sprintf("https://storage.googleapis.com/%s/%s", bucket, escapeObjectKey(object))
+	return NewObjectProvider(rawURL, &TokenSigner{Source: GCEMetadataToken()})
+}
+
+// NewAzureBlobProvider creates a provider for an Azure blob using the
+// instance's managed identity
+func NewAzureBlobProvider(account, container, blob string) *ObjectProvider {
+	rawURL := fmt.Sprintf("https://%s.blob.core.windows.net/%s/%s", account, container, escapeObjectKey(blob))
+	return NewObjectProvider(rawURL, &TokenSigner{
+		Source:  AzureManagedIdentityToken("https://storage.azure.com/"),
+		Headers: map[string]string{"x-ms-version": "2020-04-08"},
+	})
+}
+
+// escapeObjectKey escapes each segment of an object key
+func escapeObjectKey(key string) string {
+	segments := strings.Split(strings.TrimPrefix(key, "/"), "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	return strings.Join(segments, "/")
+}
+
+// Name returns the provider name without any query string, so pre-signed
+// credentials never end up in logs
+func (p *ObjectProvider) Name() string {
+	name := p.URL
+	if i := strings.IndexByte(name, '?'); i >= 0 {
+		name = name[:i]
+	}
+	return "object:" + name
+}
+
+// Load fetches the object, falling back to the disk cache when the store is
+// unreachable
+func (p *ObjectProvider) Load(ctx context.Context) (map[string]interface{}, error) {
+	data, _, err := p.fetch(ctx, false)
+	if err != nil {
+		if p.CacheFile == "" {
+			return nil, err
+		}
+		cached, cacheErr := os.ReadFile(p.CacheFile)
+		if cacheErr != nil {
+			return nil, err
+		}
+		data = cached
+	}
+	return p.parse(data)
+}
+
+// Watch polls the object, emitting the tree whenever its ETag changes
+func (p *ObjectProvider) Watch(ctx context.Context) (<-chan map[string]interface{}, error) {
+	interval := p.Interval
+	if interval <= 0 {
+		interval = 30 * time.Second
+	}
+	return watchLoop(ctx, func(ctx context.Context, emit func(map[string]interface{})) error {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-ticker.C:
+			}
+			data, changed, err := p.fetch(ctx, true)
+			if err != nil {
+				return err
+			}
+			if !changed {
+				continue
+			}
+			tree, err := p.parse(data)
+			if err != nil {
+				return err
+			}
+			emit(tree)
+		}
+	}), nil
+}
+
+// parse decodes the document using the configured or implied format
+func (p *ObjectProvider) parse(data []byte) (map[string]interface{}, error) {
+	format := p.Format
+	if format == FormatAuto {
+		if u, err := url.Parse(p.URL); err == nil {
+			format = FormatFromPath(u.Path)
+		}
+	}
+	tree, err := Parse(data, format)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", p.Name(), err)
+	}
+	return tree, nil
+}
+
+// fetch downloads the object; when conditional is set an unchanged ETag
+// yields changed == false and no data
+func (p *ObjectProvider) fetch(ctx context.Context, conditional bool) ([]byte, bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.URL, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	p.mu.Lock()
+	etag := p.etag
+	p.mu.Unlock()
+	if conditional && etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	if p.Signer != nil {
+		if err := p.Signer.Sign(ctx, req); err != nil {
+			return nil, false, fmt.Errorf("%s: sign request: %w", p.Name(), err)
+		}
+	}
+
+	client := p.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, false, fmt.Errorf("%s: %w", p.Name(), err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, false, fmt.Errorf("%s: unexpected status %d", p.Name(), resp.StatusCode)
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, false, fmt.Errorf("%s: %w", p.Name(), err)
+	}
+
+	p.mu.Lock()
+	changed := resp.Header.Get("ETag") == "" || resp.Header.Get("ETag") != p.etag
+	p.etag = resp.Header.Get("ETag")
+	p.mu.Unlock()
+
+	if p.CacheFile != "" {
+		// A stale cache only matters at startup, so write failures are not fatal
+		_ = writeFileAtomic(p.CacheFile, data, 0o600)
+	}
+	return data, changed, nil
+}
+
+// AWSCredentials is an AWS access key, optionally with a session token
+type AWSCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// AWSCredentialsFunc supplies credentials for each request, allowing
+// rotating IAM role credentials
+type AWSCredentialsFunc func(ctx context.Context) (AWSCredentials, error)
+
+// EnvAWSCredentials reads AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and
+// AWS_SESSION_TOKEN
+func EnvAWSCredentials(ctx context.Context) (AWSCredentials, error) {
+	creds := AWSCredentials{
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+		return creds, errors.New("AWS credentials not set in environment")
+	}
+	return creds, nil
+}
+
+// SigV4Signer signs requests with AWS Signature Version 4
+type SigV4Signer struct {
+	Region      string
+	Service     string
+	Credentials AWSCredentialsFunc
+	Now         func() time.Time
+}
+
+// Sign adds SigV4 authentication headers to a request; requests with a
+// body must set X-Amz-Content-Sha256 to the hex SHA-256 of the body first
+func (s *SigV4Signer) Sign(ctx context.Context, req *http.Request) error {
+	credentials := s.Credentials
+	if credentials == nil {
+		credentials = EnvAWSCredentials
+	}
+	creds, err := credentials(ctx)
+	if err != nil {
+		return err
+	}
+	now := time.Now
+	if s.Now != nil {
+		now = s.Now
+	}
+	t := now().UTC()
+	amzDate := t.Format("20060102T150405Z")
+	day := t.Format("20060102")
+	payloadHash := req.Header.Get("X-Amz-Content-Sha256")
+	if payloadHash == "" {
+		emptyHash := sha256.Sum256(nil)
+		payloadHash = hex.EncodeToString(emptyHash[:])
+	}
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if creds.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		lower := strings.ToLower(name)
+		if strings.HasPrefix(lower, "x-amz-") || lower == "if-none-match" {
+			headers[lower] = strings.TrimSpace(strings.Join(values, ","))
+		}
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		strings.ReplaceAll(req.URL.Query().Encode(), "+", "%20"),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := strings.Join([]string{day, s.Region, s.Service, "aws4_request"}, "/")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{"AWS4-HMAC-SHA256", amzDate, scope, hex.EncodeToString(requestHash[:])}, "\n")
+
+	key := []byte("AWS4" + creds.SecretAccessKey)
+	for _, part := range []string{day, s.Region, s.Service, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		creds.AccessKeyID, scope, signedHeaders, signature))
+	return nil
+}
+
+// hmacSHA256 returns HMAC-SHA256(key, data)
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// TokenSource returns an OAuth bearer token and its expiry
+type TokenSource func(ctx context.Context) (string, time.Time, error)
+
+// TokenSigner authorizes requests with a cached bearer token
+type TokenSigner struct {
+	Source  TokenSource
+	Headers map[string]string
+
+	mu     sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+// Sign sets the Authorization header, refreshing the token shortly before
+// it expires
+func (s *TokenSigner) Sign(ctx context.Context, req *http.Request) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.token == "" || time.Now().After(s.expiry.Add(-time.Minute)) {
+		token, expiry, err := s.Source(ctx)
+		if err != nil {
+			return err
+		}
+		s.token, s.expiry = token, expiry
+	}
+	req.Header.Set("Authorization", "Bearer "+s.token)
+	for name, value := range s.Headers {
+		req.Header.Set(name, value)
+	}
+	return nil
+}
+
+// GCEMetadataToken fetches service account tokens from the GCE metadata server
+func GCEMetadataToken() TokenSource {
+	return metadataToken("http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token",
+		map[string]string{"Metadata-Flavor": "Google"})
+}
+
+// AzureManagedIdentityToken fetches managed identity tokens for resource
+// from the Azure instance metadata service
+func AzureManagedIdentityToken(resource string) TokenSource {
+	query := url.Values{"api-version": {"2018-02-01"}, "resource": {resource}}
+	return metadataToken("http://169.254.169.254/metadata/identity/oauth2/token?"+query.Encode(),
+		map[string]string{"Metadata": "true"})
+}
+
+// metadataToken reads an OAuth token response from an instance metadata
+// endpoint
+func metadataToken(endpoint string, headers map[string]string) TokenSource {
+	return func(ctx context.Context) (string, time.Time, error) {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+		if err != nil {
+			return "", time.Time{}, err
+		}
+		for name, value := range headers {
+			req.Header.Set(name, value)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return "", time.Time{}, fmt.Errorf("metadata token: %w", err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			return "", time.Time{}, fmt.Errorf("metadata token: unexpected status %d", resp.StatusCode)
+		}
+
+		var body struct {
+			AccessToken string      `json:"access_token"`
+			ExpiresIn   json.Number `json:"expires_in"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+			return "", time.Time{}, fmt.Errorf("metadata token: %w", err)
+		}
+		seconds, _ := body.ExpiresIn.Int64()
+		return body.AccessToken, time.Now().Add(time.Duration(seconds) * time.Second), nil
+	}
+}
@@ -0,0 +1,82 @@
+package configuration
+
+import (
+	"context"
+	"fmt"
+)
+
+// overridesKey is the context key carrying request-scoped overrides
+type overridesKey struct{}
+
+// WithOverrides returns a context carrying configuration overrides for the
+// work done under it, e.g. a longer timeout for one batch job. Keys may be
+// dotted; overrides added to a context that already carries some are merged
+// over them
+func WithOverrides(ctx context.Context, overrides map[string]interface{}) context.Context {
+	merged := expandPatch(overrides)
+	if outer, ok := ctx.Value(overridesKey{}).(map[string]interface{}); ok {
+		merged = mergeTrees(outer, merged)
+	}
+	return context.WithValue(ctx, overridesKey{}, merged)
+}
+
+// OverridesFromContext returns a copy of the overrides carried by ctx
+func OverridesFromContext(ctx context.Context) (map[string]interface{}, bool) {
+	overrides, ok := ctx.Value(overridesKey{}).(map[string]interface{})
+	if !ok {
+		return nil, false
+	}
+	return copyTree(overrides), true
+}
+
+// GetContext returns the value under key from the overrides carried by ctx,
+// falling back to the tenant named by ctx and then the global configuration
+func (m *Manager) GetContext(ctx context.Context, key string) (interface{}, bool) {
+	if overrides, ok := ctx.Value(overridesKey{}).(map[string]interface{}); ok {
+		if value, ok := lookup(overrides, key); ok {
+			return deepCopy(value), true
+		}
+	}
+	if tenantID, ok := TenantFromContext(ctx); ok {
+		values, err := m.TenantValues(ctx, tenantID)
+		if err != nil {
+			m.logger.Printf("Tenant %s unavailable, using global configuration: %v", tenantID, err)
+		} else {
+			value, ok := lookup(values, key)
+			return value, ok
+		}
+	}
+	return m.Get(key)
+}
+
+// ConfigFor returns the configuration that applies to ctx: the tenant's
+// configuration when ctx names one, otherwise the global one, with the
+// overrides carried by ctx applied on top
+func (m *Manager) ConfigFor(ctx context.Context) (*Config, error) {
+	base := m.GetConfig()
+	if tenantID, ok := TenantFromContext(ctx); ok {
+		config, err := m.GetForTenant(ctx, tenantID)
+		if err != nil {
+			return nil, err
+		}
+		base = config
+	}
+	return withContextOverrides(ctx, base)
+}
+
+// withContextOverrides applies the overrides carried by ctx to a copy of
+// base; it takes no locks, so Process can call it while holding m.mu
+func withContextOverrides(ctx context.Context, base *Config) (*Config, error) {
+	overrides, ok := ctx.Value(overridesKey{}).(map[string]interface{})
+	if !ok {
+		return base, nil
+	}
+	config := &Config{}
+	if err := Decode(mergeTrees(structTree(base), overrides), config, false); err != nil {
+		return nil, fmt.Errorf("context overrides: %w", err)
+	}
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("context overrides: %w", err)
+	}
+	return config, nil
+}
@@ -0,0 +1,109 @@
+package configuration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// patchOptions holds settings for ApplyPatch
+type patchOptions struct {
+	source string
+	layer  Layer
+}
+
+// PatchOption configures ApplyPatch
+type PatchOption func(*patchOptions)
+
+// WithPatchSource names the origin of a patch in history, events and audit
+// records; the default is "patch"
+func WithPatchSource(source string) PatchOption {
+	return func(o *patchOptions) {
+		o.source = source
+	}
+}
+
+// WithPatchLayer applies the patch to layer instead of the runtime layer
+func WithPatchLayer(layer Layer) PatchOption {
+	return func(o *patchOptions) {
+		o.layer = layer
+	}
+}
+
+// ApplyPatch merges patch into the runtime layer using JSON merge patch
+// rules (RFC 7396): null deletes a key, objects merge and other values
+// replace. Keys may be dotted, so {"db.port": 5433} sets one leaf. The
+// patched configuration must decode, pass Config.Validate and every
+// registered section schema; otherwise nothing changes. On success the
+// change reaches OnChange watchers and event subscribers as usual
+func (m *Manager) ApplyPatch(ctx context.Context, patch map[string]interface{}, opts ...PatchOption) error {
+	options := patchOptions{source: "patch", layer: LayerRuntime}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if len(patch) == 0 {
+		return errors.New("empty patch")
+	}
+
+	defer m.deliverChanges()
+	m.writeMu.Lock()
+	defer m.writeMu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	current, _ := m.layers.Layer(options.layer)
+	next := applyMergePatch(current, expandPatch(patch))
+	return m.setLayerLocked(options.layer, options.source, next, m.validateValues)
+}
+
+// validateValues runs Config.Validate, checks every registered section and
+// enforces the section rules
+func (m *Manager) validateValues(config *Config, values map[string]interface{}) error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
+	m.mu.RLock()
+	schemas := append([]sectionSchema(nil), m.schemas...)
+	m.mu.RUnlock()
+	for _, schema := range schemas {
+		if err := checkSection(values, schema); err != nil {
+			return fmt.Errorf("%s: %w", schema.section, err)
+		}
+	}
+	return m.checkRules(config, values)
+}
+
+// expandPatch turns dotted keys into nested objects so that they merge
+// instead of creating literal "a.b" keys
+func expandPatch(patch map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(patch))
+	for _, key := range sortedKeys(patch) {
+		value := patch[key]
+		if nested, ok := value.(map[string]interface{}); ok {
+			value = expandPatch(nested)
+		}
+		if !strings.Contains(key, ".") {
+			if existing, ok := out[key].(map[string]interface{}); ok {
+				if nested, ok := value.(map[string]interface{}); ok {
+					out[key] = mergeTrees(existing, nested)
+					continue
+				}
+			}
+			out[key] = value
+			continue
+		}
+		parts := strings.Split(key, ".")
+		node := out
+		for _, part := range parts[:len(parts)-1] {
+			child, ok := node[part].(map[string]interface{})
+			if !ok {
+				child = make(map[string]interface{})
+				node[part] = child
+			}
+			node = child
+		}
+		node[parts[len(parts)-1]] = value
+	}
+	return out
+}
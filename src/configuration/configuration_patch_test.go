@@ -0,0 +1,144 @@
+package configuration
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestApplyMergePatch(t *testing.T) {
+	// Cases from RFC 7396 appendix A, limited to object targets
+	tests := []struct {
+		name          string
+		target, patch map[string]interface{}
+		want          map[string]interface{}
+	}{
+		{"replace", map[string]interface{}{"a": "b"}, map[string]interface{}{"a": "c"}, map[string]interface{}{"a": "c"}},
+		{"add", map[string]interface{}{"a": "b"}, map[string]interface{}{"b": "c"}, map[string]interface{}{"a": "b", "b": "c"}},
+		{"delete", map[string]interface{}{"a": "b"}, map[string]interface{}{"a": nil}, map[string]interface{}{}},
+		{"delete one", map[string]interface{}{"a": "b", "b": "c"}, map[string]interface{}{"a": nil}, map[string]interface{}{"b": "c"}},
+		{"list replaces", map[string]interface{}{"a": []interface{}{"b"}}, map[string]interface{}{"a": "c"}, map[string]interface{}{"a": "c"}},
+		{"scalar over list", map[string]interface{}{"a": "c"}, map[string]interface{}{"a": []interface{}{"b"}}, map[string]interface{}{"a": []interface{}{"b"}}},
+		{
+			"nested merge",
+			map[string]interface{}{"a": map[string]interface{}{"b": "c"}},
+			map[string]interface{}{"a": map[string]interface{}{"b": "d", "c": nil}},
+			map[string]interface{}{"a": map[string]interface{}{"b": "d"}},
+		},
+		{
+			"lists are not merged",
+			map[string]interface{}{"a": []interface{}{map[string]interface{}{"b": "c"}}},
+			map[string]interface{}{"a": []interface{}{1}},
+			map[string]interface{}{"a": []interface{}{1}},
+		},
+		{
+			"object over scalar",
+			map[string]interface{}{"a": "foo"},
+			map[string]interface{}{"a": map[string]interface{}{"b": "c", "d": nil}},
+			map[string]interface{}{"a": map[string]interface{}{"b": "c"}},
+		},
+		{"empty target", nil, map[string]interface{}{"a": map[string]interface{}{"bb": map[string]interface{}{"ccc": nil}}}, map[string]interface{}{"a": map[string]interface{}{"bb": map[string]interface{}{}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before := deepCopy(tt.target)
+			if got := applyMergePatch(tt.target, tt.patch); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("applyMergePatch = %v, want %v", got, tt.want)
+			}
+			if tt.target != nil && !reflect.DeepEqual(tt.target, before) {
+				t.Errorf("applyMergePatch modified its target: %v", tt.target)
+			}
+		})
+	}
+}
+
+func TestExpandPatch(t *testing.T) {
+	tests := []struct {
+		name  string
+		patch map[string]interface{}
+		want  map[string]interface{}
+	}{
+		{"plain", map[string]interface{}{"a": 1}, map[string]interface{}{"a": 1}},
+		{"dotted", map[string]interface{}{"db.port": 5433}, map[string]interface{}{"db": map[string]interface{}{"port": 5433}}},
+		{"deep", map[string]interface{}{"a.b.c": nil}, map[string]interface{}{"a": map[string]interface{}{"b": map[string]interface{}{"c": nil}}}},
+		{
+			"siblings",
+			map[string]interface{}{"db.port": 1, "db.host": "h"},
+			map[string]interface{}{"db": map[string]interface{}{"port": 1, "host": "h"}},
+		},
+		{
+			"dotted and nested together",
+			map[string]interface{}{"db": map[string]interface{}{"user": "u"}, "db.port": 1},
+			map[string]interface{}{"db": map[string]interface{}{"user": "u", "port": 1}},
+		},
+		{
+			"dotted inside nested",
+			map[string]interface{}{"db": map[string]interface{}{"pool.size": 4}},
+			map[string]interface{}{"db": map[string]interface{}{"pool": map[string]interface{}{"size": 4}}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := expandPatch(tt.patch); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("expandPatch = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyPatch(t *testing.T) {
+	ctx := context.Background()
+	m := NewManager(nil)
+	if err := m.SetLayer(LayerRuntime, "admin", map[string]interface{}{"retries": 5, "log_level": "DEBUG"}); err != nil {
+		t.Fatal(err)
+	}
+	changes := 0
+	defer m.OnChange(func(old, new *Config) { changes++ })()
+
+	if err := m.ApplyPatch(ctx, map[string]interface{}{"retries": 7, "log_level": nil, "max_concurrency": 2}, WithPatchSource("ops")); err != nil {
+		t.Fatal(err)
+	}
+	config := m.GetConfig()
+	if config.Retries != 7 || config.LogLevel != DefaultConfig().LogLevel || config.MaxConcurrency != 2 {
+		t.Errorf("config = %+v, want retries 7, the default log level and 2 workers", config)
+	}
+	if origin, _ := m.Explain("retries"); origin == nil || origin.Source != "ops" || origin.Layer != LayerRuntime {
+		t.Errorf("retries origin = %+v, want runtime/ops", origin)
+	}
+	if changes != 1 {
+		t.Errorf("OnChange ran %d times, want 1", changes)
+	}
+
+	// Invalid patches change nothing
+	for name, patch := range map[string]map[string]interface{}{
+		"fails Validate": {"retries": -1},
+		"fails decoding": {"timeout": "soon"},
+		"empty":          {},
+	} {
+		if err := m.ApplyPatch(ctx, patch); err == nil {
+			t.Errorf("%s: ApplyPatch succeeded", name)
+		}
+	}
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	if err := m.ApplyPatch(cancelled, map[string]interface{}{"retries": 1}); err == nil {
+		t.Error("ApplyPatch succeeded with a cancelled context")
+	}
+	if got := m.GetConfig(); !reflect.DeepEqual(got, config) {
+		t.Errorf("config after rejected patches = %+v, want %+v", got, config)
+	}
+	if changes != 1 {
+		t.Errorf("OnChange ran %d times after rejected patches, want 1", changes)
+	}
+
+	// Other layers can be patched and keep their precedence
+	if err := m.ApplyPatch(ctx, map[string]interface{}{"retries": 2, "history_limit": 5}, WithPatchLayer(LayerFile)); err != nil {
+		t.Fatal(err)
+	}
+	if got := m.GetConfig(); got.Retries != 7 || got.HistoryLimit != 5 {
+		t.Errorf("config = %+v, want runtime retries 7 over file retries and history limit 5", got)
+	}
+	if file, _ := m.layers.Layer(LayerFile); !reflect.DeepEqual(file, map[string]interface{}{"retries": 2, "history_limit": 5}) {
+		t.Errorf("file layer = %v", file)
+	}
+}
@@ -0,0 +1,204 @@
+package configuration
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Loader is the minimal contract for a custom configuration source that
+// cannot push changes; wrap it with NewPollingProvider to get a Provider
+type Loader interface {
+	// Name identifies the source in logs and diagnostics
+	Name() string
+	// Load fetches the current key tree
+	Load(ctx context.Context) (map[string]interface{}, error)
+}
+
+// PollingProvider turns a Loader into a Provider by reloading it every
+// Interval and emitting the tree when it changes
+type PollingProvider struct {
+	Loader   Loader
+	Interval time.Duration
+}
+
+// NewPollingProvider polls loader every interval
+func NewPollingProvider(loader Loader, interval time.Duration) *PollingProvider {
+	return &PollingProvider{Loader: loader, Interval: interval}
+}
+
+// Name returns the name of the wrapped loader
+func (p *PollingProvider) Name() string {
+	return p.Loader.Name()
+}
+
+// Load fetches the current tree from the wrapped loader
+func (p *PollingProvider) Load(ctx context.Context) (map[string]interface{}, error) {
+	return p.Loader.Load(ctx)
+}
+
+// Watch reloads on every tick and emits trees that differ from the last one
+func (p *PollingProvider) Watch(ctx context.Context) (<-chan map[string]interface{}, error) {
+	last, err := p.Loader.Load(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return watchLoop(ctx, func(ctx context.Context, emit func(map[string]interface{})) error {
+		ticker := time.NewTicker(p.Interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-ticker.C:
+				tree, err := p.Loader.Load(ctx)
+				if err != nil {
+					return err
+				}
+				if reflect.DeepEqual(tree, last) {
+					continue
+				}
+				last = tree
+				emit(tree)
+			}
+		}
+	}), nil
+}
+
+// ProviderFactory builds a provider from a source URL such as
+// "zk://zk1:2181/app/config"
+type ProviderFactory func(u *url.URL) (Provider, error)
+
+// providerFactories maps URL schemes to registered factories
+var providerFactories = struct {
+	sync.RWMutex
+	byScheme map[string]ProviderFactory
+}{byScheme: make(map[string]ProviderFactory)}
+
+// RegisterProvider makes a source type available to OpenProvider under a
+// URL scheme, typically from an init function of the package implementing
+// it; registering a scheme twice is an error
+func RegisterProvider(scheme string, factory ProviderFactory) error {
+	scheme = strings.ToLower(scheme)
+	if scheme == "" || factory == nil {
+		return fmt.Errorf("register provider %q: scheme and factory are required", scheme)
+	}
+	providerFactories.Lock()
+	defer providerFactories.Unlock()
+	if _, exists := providerFactories.byScheme[scheme]; exists {
+		return fmt.Errorf("register provider %q: scheme already registered", scheme)
+	}
+	providerFactories.byScheme[scheme] = factory
+	return nil
+}
+
+// ProviderSchemes lists the registered URL schemes
+func ProviderSchemes() []string {
+	providerFactories.RLock()
+	defer providerFactories.RUnlock()
+	schemes := make([]string, 0, len(providerFactories.byScheme))
+	for scheme := range providerFactories.byScheme {
+		schemes = append(schemes, scheme)
+	}
+	sort.Strings(schemes)
+	return schemes
+}
+
+// OpenProvider builds a provider for a source URL using the factory
+// registered for its scheme
+func OpenProvider(rawURL string) (Provider, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("open provider: %w", err)
+	}
+	providerFactories.RLock()
+	factory, ok := providerFactories.byScheme[strings.ToLower(u.Scheme)]
+	providerFactories.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("open provider: no provider registered for scheme %q", u.Scheme)
+	}
+	provider, err := factory(u)
+	if err != nil {
+		return nil, fmt.Errorf("open provider %s: %w", u.Scheme, err)
+	}
+	return provider, nil
+}
+
+// UseProviderURL opens the provider for rawURL and passes it to UseProvider
+func (m *Manager) UseProviderURL(ctx context.Context, rawURL string) error {
+	provider, err := OpenProvider(rawURL)
+	if err != nil {
+		return err
+	}
+	return m.UseProvider(ctx, provider)
+}
+
+// urlPath returns the path of u without its leading slash
+func urlPath(u *url.URL) string {
+	return strings.TrimPrefix(u.Path, "/")
+}
+
+// init registers the built-in providers:
+//
+//	consul://host:8500/prefix?token=...
+//	etcd://host:2379/prefix
+//	file:///etc/app/config.yaml
+//	http://... and https://... (plain or pre-signed object URLs)
+//	s3://bucket/key?region=eu-west-1
+//	gs://bucket/object
+//	azblob://account/container/blob
+//	configmap://namespace/name and secret://namespace/name (in cluster)
+func init() {
+	builtin := map[string]ProviderFactory{
+		"consul": func(u *url.URL) (Provider, error) {
+			p := NewConsulProvider("http://"+u.Host, urlPath(u))
+			p.Token = u.Query().Get("token")
+			return p, nil
+		},
+		"etcd": func(u *url.URL) (Provider, error) {
+			return NewEtcdProvider("http://"+u.Host, urlPath(u)), nil
+		},
+		"file": func(u *url.URL) (Provider, error) {
+			return NewFileProvider(u.Path), nil
+		},
+		"http": func(u *url.URL) (Provider, error) {
+			return NewObjectProvider(u.String(), nil), nil
+		},
+		"https": func(u *url.URL) (Provider, error) {
+			return NewObjectProvider(u.String(), nil), nil
+		},
+		"s3": func(u *url.URL) (Provider, error) {
+			region := u.Query().Get("region")
+			if region == "" {
+				return nil, fmt.Errorf("missing region parameter")
+			}
+			return NewS3Provider(region, u.Host, urlPath(u), EnvAWSCredentials), nil
+		},
+		"gs": func(u *url.URL) (Provider, error) {
+			return NewGCSProvider(u.Host, urlPath(u)), nil
+		},
+		"azblob": func(u *url.URL) (Provider, error) {
+			container, blob, ok := strings.Cut(urlPath(u), "/")
+			if !ok {
+				return nil, fmt.Errorf("expected azblob://account/container/blob")
+			}
+			return NewAzureBlobProvider(u.Host, container, blob), nil
+		},
+		"configmap": func(u *url.URL) (Provider, error) {
+			return InClusterKubernetesProvider(KindConfigMap, u.Host, urlPath(u))
+		},
+		"secret": func(u *url.URL) (Provider, error) {
+			return InClusterKubernetesProvider(KindSecret, u.Host, urlPath(u))
+		},
+	}
+	for scheme, factory := range builtin {
+		if err := RegisterProvider(scheme, factory); err != nil {
+			panic(err)
+		}
+	}
+}
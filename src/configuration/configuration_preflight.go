@@ -0,0 +1,391 @@
+package configuration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// PreflightKind classifies a preflight check
+type PreflightKind string
+
+const (
+	// PreflightProvider checks that a remote provider can be loaded
+	PreflightProvider PreflightKind = "provider"
+	// PreflightSecret checks that a secret reference resolves
+	PreflightSecret PreflightKind = "secret"
+	// PreflightEndpoint checks that an endpoint named in the config accepts connections
+	PreflightEndpoint PreflightKind = "endpoint"
+)
+
+// PreflightCheck is the outcome of checking one external resource
+type PreflightCheck struct {
+	Name    string        `json:"name"`
+	Kind    PreflightKind `json:"kind"`
+	Target  string        `json:"target"`
+	OK      bool          `json:"ok"`
+	Latency time.Duration `json:"latency"`
+	Error   string        `json:"error,omitempty"`
+}
+
+// PreflightReport lists the reachability of every external resource the
+// configuration depends on
+type PreflightReport struct {
+	StartedAt time.Time        `json:"started_at"`
+	Duration  time.Duration    `json:"duration"`
+	Checks    []PreflightCheck `json:"checks"`
+}
+
+// Ready reports whether every check passed
+func (r *PreflightReport) Ready() bool {
+	return len(r.Failed()) == 0
+}
+
+// Failed returns the checks that did not pass
+func (r *PreflightReport) Failed() []PreflightCheck {
+	var failed []PreflightCheck
+	for _, c := range r.Checks {
+		if !c.OK {
+			failed = append(failed, c)
+		}
+	}
+	return failed
+}
+
+// Err summarises the failed checks, or returns nil when the report is ready
+func (r *PreflightReport) Err() error {
+	failed := r.Failed()
+	if len(failed) == 0 {
+		return nil
+	}
+	problems := make([]string, len(failed))
+	for i, c := range failed {
+		problems[i] = fmt.Sprintf("%s %s: %s", c.Kind, c.Name, c.Error)
+	}
+	return fmt.Errorf("preflight: %d check(s) failed: %s", len(failed), strings.Join(problems, "; "))
+}
+
+// preflightOptions holds settings for Preflight
+type preflightOptions struct {
+	timeout     time.Duration
+	concurrency int
+	providers   []Provider
+	secrets     *Secrets
+	endpoints   bool
+	client      *http.Client
+}
+
+// PreflightOption configures Preflight
+type PreflightOption func(*preflightOptions)
+
+// WithPreflightTimeout bounds each individual check; the default is 5s
+func WithPreflightTimeout(d time.Duration) PreflightOption {
+	return func(o *preflightOptions) {
+		o.timeout = d
+	}
+}
+
+// WithPreflightConcurrency limits how many checks run at once; the default is 8
+func WithPreflightConcurrency(n int) PreflightOption {
+	return func(o *preflightOptions) {
+		o.concurrency = n
+	}
+}
+
+// WithPreflightProviders also checks providers not yet passed to UseProvider
+func WithPreflightProviders(providers ...Provider) PreflightOption {
+	return func(o *preflightOptions) {
+		o.providers = append(o.providers, providers...)
+	}
+}
+
+// WithPreflightSecrets checks secret references against secrets instead of
+// the resolvers set with UseSecrets
+func WithPreflightSecrets(secrets *Secrets) PreflightOption {
+	return func(o *preflightOptions) {
+		o.secrets = secrets
+	}
+}
+
+// WithoutEndpointChecks skips probing URLs and host:port values found in
+// the configuration
+func WithoutEndpointChecks() PreflightOption {
+	return func(o *preflightOptions) {
+		o.endpoints = false
+	}
+}
+
+// WithPreflightClient sets the HTTP client used to probe http(s) endpoints
+func WithPreflightClient(client *http.Client) PreflightOption {
+	return func(o *preflightOptions) {
+		o.client = client
+	}
+}
+
+// preflightTask is a pending check
+type preflightTask struct {
+	check PreflightCheck
+	run   func(ctx context.Context) error
+}
+
+// Preflight checks, before the application starts serving, that every
+// external resource the configuration references is reachable: providers
+// passed to UseProvider, secret references and the http(s) URLs and
+// host:port endpoints found among the values. Checks run concurrently, each
+// under its own timeout; the report lists them sorted by kind and name with
+// credentials removed from targets
+func (m *Manager) Preflight(ctx context.Context, opts ...PreflightOption) *PreflightReport {
+	m.mu.RLock()
+	options := preflightOptions{
+		timeout:     5 * time.Second,
+		concurrency: 8,
+		providers:   append([]Provider(nil), m.providers...),
+		secrets:     m.secrets,
+		endpoints:   true,
+	}
+	m.mu.RUnlock()
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.concurrency < 1 {
+		options.concurrency = 1
+	}
+	if options.client == nil {
+		options.client = &http.Client{
+			// Any response proves the endpoint is reachable
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		}
+	}
+
+	report := &PreflightReport{StartedAt: time.Now()}
+	tasks := m.preflightTasks(options)
+	report.Checks = make([]PreflightCheck, len(tasks))
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, options.concurrency)
+	for i, task := range tasks {
+		wg.Add(1)
+		go func(i int, task preflightTask) {
+			defer wg.Done()
+			check := task.check
+			select {
+			case sem <- struct{}{}:
+				defer func() { <-sem }()
+			case <-ctx.Done():
+				check.Error = ctx.Err().Error()
+				report.Checks[i] = check
+				return
+			}
+
+			checkCtx, cancel := context.WithTimeout(ctx, options.timeout)
+			defer cancel()
+			start := time.Now()
+			err := task.run(checkCtx)
+			check.Latency = time.Since(start)
+			if err != nil {
+				if errors.Is(err, context.DeadlineExceeded) && checkCtx.Err() != nil && ctx.Err() == nil {
+					err = fmt.Errorf("timed out after %s", options.timeout)
+				}
+				check.Error = err.Error()
+			} else {
+				check.OK = true
+			}
+			report.Checks[i] = check
+		}(i, task)
+	}
+	wg.Wait()
+
+	sort.SliceStable(report.Checks, func(i, j int) bool {
+		a, b := report.Checks[i], report.Checks[j]
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		return a.Name < b.Name
+	})
+	report.Duration = time.Since(report.StartedAt)
+
+	if report.Ready() {
+		m.logger.Printf("Preflight passed %d check(s) in %s", len(report.Checks), report.Duration)
+	} else {
+		m.logger.Printf("Preflight failed %d of %d check(s)", len(report.Failed()), len(report.Checks))
+	}
+	return report
+}
+
+// preflightTasks collects the checks implied by the options and the raw
+// (unresolved) effective configuration
+func (m *Manager) preflightTasks(options preflightOptions) []preflightTask {
+	var tasks []preflightTask
+	seen := make(map[Provider]bool)
+	for _, provider := range options.providers {
+		// Check the source itself rather than a cache in front of it
+		for {
+			wrapper, ok := provider.(interface{ Unwrap() Provider })
+			if !ok {
+				break
+			}
+			provider = wrapper.Unwrap()
+		}
+		if provider == nil || seen[provider] {
+			continue
+		}
+		seen[provider] = true
+		provider := provider
+		tasks = append(tasks, preflightTask{
+			check: PreflightCheck{Name: provider.Name(), Kind: PreflightProvider, Target: provider.Name()},
+			run: func(ctx context.Context) error {
+				_, err := provider.Load(ctx)
+				return err
+			},
+		})
+	}
+
+	flat := flatten(m.layers.Effective())
+	for _, key := range sortedKeys(flat) {
+		value, ok := flat[key].(string)
+		if !ok {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		if options.secrets != nil {
+			if scheme, ok := options.secrets.scheme(value); ok {
+				secrets, ref := options.secrets, value
+				tasks = append(tasks, preflightTask{
+					check: PreflightCheck{Name: key, Kind: PreflightSecret, Target: scheme + "://"},
+					run: func(ctx context.Context) error {
+						return secrets.probe(ctx, scheme, ref)
+					},
+				})
+				continue
+			}
+		}
+		if !options.endpoints {
+			continue
+		}
+		if task, ok := endpointTask(key, value, options.client); ok {
+			tasks = append(tasks, task)
+		}
+	}
+	return tasks
+}
+
+// probe resolves ref without consulting or filling the cache
+func (s *Secrets) probe(ctx context.Context, scheme, ref string) error {
+	s.mu.Lock()
+	resolver := s.resolvers[scheme]
+	s.mu.Unlock()
+	if _, err := resolver.Resolve(ctx, ref); err != nil {
+		return fmt.Errorf("resolve: %w", err)
+	}
+	return nil
+}
+
+// defaultPorts maps URL schemes to the port dialled when none is given
+var defaultPorts = map[string]string{
+	"amqp":       "5672",
+	"amqps":      "5671",
+	"grpc":       "443",
+	"mongodb":    "27017",
+	"mysql":      "3306",
+	"nats":       "4222",
+	"postgres":   "5432",
+	"postgresql": "5432",
+	"redis":      "6379",
+	"rediss":     "6379",
+	"tcp":        "",
+}
+
+// endpointTask returns a check for value when it names a network endpoint:
+// http(s) URLs are probed with a HEAD request, other URLs with a known
+// scheme and bare host:port values with a TCP dial
+func endpointTask(key, value string, client *http.Client) (preflightTask, bool) {
+	if u, err := url.Parse(value); err == nil && u.Scheme != "" && u.Host != "" {
+		scheme := strings.ToLower(u.Scheme)
+		check := PreflightCheck{Name: key, Kind: PreflightEndpoint, Target: u.Redacted()}
+		if scheme == "http" || scheme == "https" {
+			target := *u
+			target.User = nil
+			return preflightTask{check: check, run: func(ctx context.Context) error {
+				return probeHTTP(ctx, client, target.String())
+			}}, true
+		}
+		port, known := defaultPorts[scheme]
+		if !known {
+			return preflightTask{}, false
+		}
+		address := u.Host
+		if u.Port() == "" {
+			if port == "" {
+				return preflightTask{}, false
+			}
+			address = net.JoinHostPort(u.Hostname(), port)
+		}
+		check.Target = scheme + "://" + address
+		return preflightTask{check: check, run: func(ctx context.Context) error {
+			return probeTCP(ctx, address)
+		}}, true
+	}
+
+	if !isHostPort(value) {
+		return preflightTask{}, false
+	}
+	return preflightTask{
+		check: PreflightCheck{Name: key, Kind: PreflightEndpoint, Target: value},
+		run: func(ctx context.Context) error {
+			return probeTCP(ctx, value)
+		},
+	}, true
+}
+
+// isHostPort reports whether value looks like host:port, requiring a
+// hostname with a dot, localhost or an IP so that times like 12:30 are not
+// mistaken for endpoints
+func isHostPort(value string) bool {
+	host, port, err := net.SplitHostPort(value)
+	if err != nil || host == "" {
+		return false
+	}
+	if n, err := strconv.Atoi(port); err != nil || n <= 0 || n > 65535 {
+		return false
+	}
+	if net.ParseIP(host) != nil || host == "localhost" {
+		return true
+	}
+	return strings.Contains(host, ".") && !strings.ContainsAny(host, " /")
+}
+
+// probeHTTP sends a HEAD request; any response counts as reachable except a
+// 5xx, which means the service is up but not healthy
+func probeHTTP(ctx context.Context, client *http.Client, target string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, target, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return fmt.Errorf("%s", resp.Status)
+	}
+	return nil
+}
+
+// probeTCP opens and immediately closes a TCP connection
+func probeTCP(ctx context.Context, address string) error {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return err
+	}
+	return conn.Close()
+}
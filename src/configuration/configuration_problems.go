@@ -0,0 +1,21 @@
+package configuration
+
+import (
+	"net/http"
+
+	"github.com/nerufuyo/roastume/src/validation"
+)
+
+// Report configuration failures as problem details with fitting statuses
+func init() {
+	for target, typ := range map[error]validation.ProblemType{
+		ErrKeyNotFound:      {Status: http.StatusNotFound, Type: "key-not-found", Title: "Configuration key not found"},
+		ErrNoBackup:         {Status: http.StatusNotFound, Type: "no-backup", Title: "Backup not found"},
+		ErrFrozen:           {Status: http.StatusConflict, Type: "frozen", Title: "Configuration frozen"},
+		ErrNotScheduled:     {Status: http.StatusConflict, Type: "not-scheduled", Title: "Change not pending"},
+		ErrUnsigned:         {Status: http.StatusBadRequest, Type: "unsigned", Title: "Configuration not signed"},
+		ErrInvalidSignature: {Status: http.StatusBadRequest, Type: "invalid-signature", Title: "Invalid configuration signature"},
+	} {
+		validation.RegisterProblem(target, typ)
+	}
+}
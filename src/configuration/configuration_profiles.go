@@ -0,0 +1,124 @@
+package configuration
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultProfileEnv is the environment variable selecting the active profile
+const DefaultProfileEnv = "APP_ENV"
+
+// MissingKeysError lists required keys absent from a profile's configuration
+type MissingKeysError struct {
+	Profile string
+	Keys    []string
+}
+
+// Error implements error
+func (e *MissingKeysError) Error() string {
+	return fmt.Sprintf("profile %q is missing required keys: %s", e.Profile, strings.Join(e.Keys, ", "))
+}
+
+// ProfileOptions describes where base and profile configuration files live
+type ProfileOptions struct {
+	// Dir contains the files; defaults to the working directory
+	Dir string
+	// Name is the base file name without extension; defaults to "config"
+	Name string
+	// Selector is the environment variable naming the profile; defaults to DefaultProfileEnv
+	Selector string
+	// Profile overrides the selector when set
+	Profile string
+	// Required lists keys that must be present per profile
+	Required map[string][]string
+}
+
+// profileExtensions are probed in order when locating files
+var profileExtensions = []string{".yaml", ".yml", ".json", ".toml"}
+
+// findConfigFile returns the first existing dir/name.ext
+func findConfigFile(dir, name string) (string, bool) {
+	for _, ext := range profileExtensions {
+		path := filepath.Join(dir, name+ext)
+		if _, err := os.Stat(path); err == nil {
+			return path, true
+		}
+	}
+	return "", false
+}
+
+// ActiveProfile returns the profile selected by options
+func (o ProfileOptions) ActiveProfile() string {
+	if o.Profile != "" {
+		return o.Profile
+	}
+	selector := o.Selector
+	if selector == "" {
+		selector = DefaultProfileEnv
+	}
+	return os.Getenv(selector)
+}
+
+// LoadProfile sets the file layer from the base configuration merged with
+// the overlay for the active profile (e.g. config.yaml then config.prod.yaml)
+// and returns the active profile name
+func (m *Manager) LoadProfile(options ProfileOptions, opts ...LoadOption) (string, error) {
+	name := options.Name
+	if name == "" {
+		name = "config"
+	}
+	profile := options.ActiveProfile()
+
+	var sources []string
+	tree := make(map[string]interface{})
+	if base, ok := findConfigFile(options.Dir, name); ok {
+		baseTree, err := ReadTree(base, opts...)
+		if err != nil {
+			return profile, err
+		}
+		tree = baseTree
+		sources = append(sources, base)
+	}
+	if profile != "" {
+		overlay, ok := findConfigFile(options.Dir, name+"."+profile)
+		if !ok {
+			return profile, fmt.Errorf("no configuration file for profile %q in %s", profile, options.Dir)
+		}
+		overlayTree, err := ReadTree(overlay, opts...)
+		if err != nil {
+			return profile, err
+		}
+		tree = mergeTrees(tree, overlayTree)
+		sources = append(sources, overlay)
+	}
+	if len(sources) == 0 {
+		return profile, errors.New("no configuration files found")
+	}
+
+	if missing := m.missingKeys(tree, options.Required[profile]); len(missing) > 0 {
+		return profile, &MissingKeysError{Profile: profile, Keys: missing}
+	}
+	if err := m.SetLayer(LayerFile, strings.Join(sources, "+"), tree); err != nil {
+		return profile, err
+	}
+	m.logger.Printf("Loaded configuration profile %q from %s", profile, strings.Join(sources, ", "))
+	return profile, nil
+}
+
+// missingKeys returns required keys present neither in tree nor in other layers
+func (m *Manager) missingKeys(tree map[string]interface{}, required []string) []string {
+	var missing []string
+	for _, key := range required {
+		if _, ok := lookup(tree, key); ok {
+			continue
+		}
+		if origin, err := m.Explain(key); err == nil && origin.Layer != LayerFile {
+			continue
+		}
+		missing = append(missing, key)
+	}
+	return missing
+}
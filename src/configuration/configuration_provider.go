@@ -0,0 +1,120 @@
+package configuration
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Provider is a source of configuration key trees, typically remote; custom
+// sources implement it (or Loader, see NewPollingProvider) and can be made
+// available by URL scheme with RegisterProvider
+type Provider interface {
+	// Name identifies the provider in logs and diagnostics
+	Name() string
+	// Load fetches the current key tree
+	Load(ctx context.Context) (map[string]interface{}, error)
+	// Watch emits the full key tree each time it changes until ctx is
+	// cancelled; implementations reconnect on their own after failures
+	Watch(ctx context.Context) (<-chan map[string]interface{}, error)
+}
+
+// kvTree converts flat key/value pairs under prefix into a key tree, using
+// "/" as the nesting separator
+func kvTree(prefix string, pairs map[string]string) map[string]interface{} {
+	tree := make(map[string]interface{})
+	for key, value := range pairs {
+		rel := strings.Trim(strings.TrimPrefix(key, prefix), "/")
+		if rel == "" {
+			continue
+		}
+		setPath(tree, strings.ReplaceAll(rel, "/", "."), value)
+	}
+	return tree
+}
+
+// backoff doubles a retry delay up to max
+func backoff(delay, max time.Duration) time.Duration {
+	delay *= 2
+	if delay > max {
+		return max
+	}
+	return delay
+}
+
+// watchLoop runs session repeatedly, reconnecting with exponential backoff
+// until ctx is cancelled; session should block while the connection is healthy
+func watchLoop(ctx context.Context, session func(ctx context.Context, emit func(map[string]interface{})) error) <-chan map[string]interface{} {
+	out := make(chan map[string]interface{}, 1)
+	emit := func(tree map[string]interface{}) {
+		select {
+		case out <- tree:
+		case <-ctx.Done():
+		}
+	}
+
+	go func() {
+		defer close(out)
+		delay := 100 * time.Millisecond
+		for ctx.Err() == nil {
+			start := time.Now()
+			_ = session(ctx, emit)
+			if time.Since(start) > time.Minute {
+				delay = 100 * time.Millisecond
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(delay):
+			}
+			delay = backoff(delay, 30*time.Second)
+		}
+	}()
+	return out
+}
+
+// UseProvider sets the remote layer from the provider and keeps applying
+// updates until ctx is cancelled; when the provider is unreachable and
+// snapshots are persisted, the last snapshot is used until it recovers;
+// Preflight also checks the provider
+func (m *Manager) UseProvider(ctx context.Context, provider Provider) error {
+	m.mu.Lock()
+	m.providers = append(m.providers, provider)
+	m.mu.Unlock()
+
+	tree, err := provider.Load(ctx)
+	if err != nil {
+		m.mu.RLock()
+		snapshot := m.snapshotPath
+		m.mu.RUnlock()
+		if snapshot == "" {
+			return fmt.Errorf("provider %s: %w", provider.Name(), err)
+		}
+		m.logger.Printf("Provider %s unavailable, recovering snapshot: %v", provider.Name(), err)
+		if recoverErr := m.RecoverSnapshot(snapshot); recoverErr != nil {
+			return fmt.Errorf("provider %s: %w (snapshot: %v)", provider.Name(), err, recoverErr)
+		}
+	} else {
+		if err := m.SetLayer(LayerRemote, provider.Name(), tree); err != nil {
+			return fmt.Errorf("provider %s: %w", provider.Name(), err)
+		}
+		m.markFresh()
+	}
+
+	updates, err := provider.Watch(ctx)
+	if err != nil {
+		return fmt.Errorf("provider %s: %w", provider.Name(), err)
+	}
+	go func() {
+		for tree := range updates {
+			if err := m.SetLayer(LayerRemote, provider.Name(), tree); err != nil {
+				m.logger.Printf("Ignoring update from provider %s: %v", provider.Name(), err)
+				continue
+			}
+			m.markFresh()
+			m.logger.Printf("Applied update from provider %s", provider.Name())
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,155 @@
+package configuration
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// MaskStrategy selects how a sensitive value is masked
+type MaskStrategy string
+
+const (
+	// MaskFull replaces the value entirely
+	MaskFull MaskStrategy = "full"
+	// MaskLast4 keeps the last four characters of values long enough to
+	// not give them away, e.g. "******1234"
+	MaskLast4 MaskStrategy = "last4"
+	// MaskHash replaces the value with a short hash, so changes remain
+	// visible without revealing the value
+	MaskHash MaskStrategy = "hash"
+)
+
+// SensitiveTag is the struct tag marking fields of registered schemas as
+// sensitive; its value is a MaskStrategy, or "true" for MaskFull
+const SensitiveTag = "sensitive"
+
+// sensitiveRule masks keys matching pattern with strategy
+type sensitiveRule struct {
+	pattern  string
+	strategy MaskStrategy
+}
+
+// MaskValue masks value with strategy
+func MaskValue(value interface{}, strategy MaskStrategy) string {
+	switch strategy {
+	case MaskLast4:
+		s := fmt.Sprint(plainValue(value))
+		if len(s) < 8 {
+			return redactedValue
+		}
+		return redactedValue + s[len(s)-4:]
+	case MaskHash:
+		sum := sha256.Sum256([]byte(fmt.Sprint(plainValue(value))))
+		return "sha256:" + hex.EncodeToString(sum[:6])
+	default:
+		return redactedValue
+	}
+}
+
+// parseMaskStrategy converts a tag value to a strategy
+func parseMaskStrategy(tag string) (MaskStrategy, error) {
+	switch MaskStrategy(strings.ToLower(tag)) {
+	case "true", MaskFull:
+		return MaskFull, nil
+	case MaskLast4:
+		return MaskLast4, nil
+	case MaskHash:
+		return MaskHash, nil
+	}
+	return "", fmt.Errorf("unknown mask strategy %q", tag)
+}
+
+// MarkSensitiveWith marks keys matching the patterns as sensitive, masked
+// with strategy; when several patterns match a key the one marked last wins
+func (m *Manager) MarkSensitiveWith(strategy MaskStrategy, patterns ...string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, pattern := range patterns {
+		m.sensitive = append(m.sensitive, sensitiveRule{pattern: pattern, strategy: strategy})
+	}
+}
+
+// sensitiveLocked returns the keys resolved from secret references followed
+// by the marked rules, so explicit marks take precedence; m.mu must be held
+func (m *Manager) sensitiveLocked() []sensitiveRule {
+	return append(append([]sensitiveRule(nil), m.secretRules...), m.sensitive...)
+}
+
+// markSecretKeys registers keys whose values were resolved from secret
+// references as fully masked; keys stay marked after a reference is removed
+// so the old value is never logged in a later diff
+func (m *Manager) markSecretKeys(keys []string) {
+	for _, key := range keys {
+		pattern := escapePattern(key)
+		known := false
+		for _, rule := range m.secretRules {
+			known = known || rule.pattern == pattern
+		}
+		if !known {
+			m.secretRules = append(m.secretRules, sensitiveRule{pattern: pattern, strategy: MaskFull})
+		}
+	}
+}
+
+// escapePattern quotes path.Match metacharacters in a literal key
+func escapePattern(key string) string {
+	var b strings.Builder
+	for _, r := range key {
+		if strings.ContainsRune(`*?[]\`, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// maskFor returns the strategy for key, if it is sensitive
+func maskFor(rules []sensitiveRule, key string) (MaskStrategy, bool) {
+	for i := len(rules) - 1; i >= 0; i-- {
+		if matchesAny([]string{rules[i].pattern}, key) {
+			return rules[i].strategy, true
+		}
+	}
+	return "", false
+}
+
+// Redact returns value masked if key is sensitive and unchanged otherwise;
+// use it when logging configuration values
+func (m *Manager) Redact(key string, value interface{}) interface{} {
+	m.mu.RLock()
+	strategy, ok := maskFor(m.sensitiveLocked(), key)
+	m.mu.RUnlock()
+	if !ok {
+		return value
+	}
+	return MaskValue(value, strategy)
+}
+
+// sensitiveFields collects the sensitive fields of t below prefix
+func sensitiveFields(t reflect.Type, prefix string) ([]sensitiveRule, error) {
+	t = indirectType(t)
+	if t.Kind() != reflect.Struct {
+		return nil, nil
+	}
+	var rules []sensitiveRule
+	for _, f := range structFields(t) {
+		key := joinKey(prefix, fieldKey(f))
+		if tag, ok := f.Tag.Lookup(SensitiveTag); ok {
+			strategy, err := parseMaskStrategy(tag)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", key, err)
+			}
+			rules = append(rules, sensitiveRule{pattern: key, strategy: strategy})
+			continue
+		}
+		nested, err := sensitiveFields(f.Type, key)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, nested...)
+	}
+	return rules, nil
+}
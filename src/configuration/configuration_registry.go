@@ -0,0 +1,121 @@
+package configuration
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Registry hands out per-subsystem namespaces of a manager's configuration,
+// so each subsystem sees and reloads only its own section
+type Registry struct {
+	manager    *Manager
+	mu         sync.Mutex
+	namespaces map[string]*Namespace
+}
+
+// NewRegistry creates a registry over m
+func NewRegistry(m *Manager) *Registry {
+	return &Registry{manager: m, namespaces: make(map[string]*Namespace)}
+}
+
+// Register claims the section under name for the type of prototype (for
+// example authentication.Config{}); the type is also registered as the
+// section's schema for Verify. Names may not overlap
+func (r *Registry) Register(name string, prototype interface{}) (*Namespace, error) {
+	if name == "" || strings.HasPrefix(name, ".") || strings.HasSuffix(name, ".") {
+		return nil, fmt.Errorf("invalid namespace %q", name)
+	}
+	if prototype == nil {
+		return nil, fmt.Errorf("namespace %s: prototype must not be nil", name)
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for existing := range r.namespaces {
+		if keyWithin(name, existing) || keyWithin(existing, name) {
+			return nil, fmt.Errorf("namespace %q overlaps %q", name, existing)
+		}
+	}
+
+	typ := indirectType(reflect.TypeOf(prototype))
+	ns := &Namespace{name: name, manager: r.manager, typ: typ}
+	r.namespaces[name] = ns
+	r.manager.RegisterSchema(name, prototype)
+	return ns, nil
+}
+
+// Namespace returns a registered namespace
+func (r *Registry) Namespace(name string) (*Namespace, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ns, ok := r.namespaces[name]
+	return ns, ok
+}
+
+// Names returns the registered namespaces in sorted order
+func (r *Registry) Names() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	names := make([]string, 0, len(r.namespaces))
+	for name := range r.namespaces {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Namespace is one subsystem's view of its configuration section
+type Namespace struct {
+	name    string
+	manager *Manager
+	typ     reflect.Type
+}
+
+// Name returns the section key
+func (n *Namespace) Name() string {
+	return n.name
+}
+
+// New returns a pointer to a zero value of the registered type
+func (n *Namespace) New() interface{} {
+	return reflect.New(n.typ).Interface()
+}
+
+// Get returns the value under key relative to the section
+func (n *Namespace) Get(key string) (interface{}, bool) {
+	return n.manager.Get(joinKey(n.name, key))
+}
+
+// Load decodes the section into a new value of the registered type; a
+// missing section yields the zero value
+func (n *Namespace) Load() (interface{}, error) {
+	v := n.New()
+	if _, ok := n.manager.Get(n.name); !ok {
+		return v, nil
+	}
+	if err := n.manager.Unmarshal(n.name, v); err != nil {
+		return nil, fmt.Errorf("namespace %s: %w", n.name, err)
+	}
+	return v, nil
+}
+
+// OnReload calls fn with the changes inside the section, keyed relative to
+// it, and returns a function that unsubscribes
+func (n *Namespace) OnReload(fn func(events []ChangeEvent)) func() {
+	return n.manager.Events().Subscribe(n.name, func(events []ChangeEvent) {
+		relative := make([]ChangeEvent, len(events))
+		for i, e := range events {
+			e.Key = strings.TrimPrefix(strings.TrimPrefix(e.Key, n.name), ".")
+			relative[i] = e
+		}
+		fn(relative)
+	})
+}
+
+// Bind passes a freshly decoded value of the registered type to apply now
+// and after every change in the section, e.g. a manager's SetConfig
+func (n *Namespace) Bind(apply func(v interface{})) (func(), error) {
+	return n.manager.BindSection(n.name, n.New, apply)
+}
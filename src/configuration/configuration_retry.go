@@ -0,0 +1,16 @@
+package configuration
+
+import "github.com/nerufuyo/roastume/src/manager"
+
+// ErrTransient marks failures that may succeed when retried
+var ErrTransient = manager.ErrTransient
+
+// Transient wraps err so Process retries it
+func Transient(err error) error {
+	return manager.Transient(err)
+}
+
+// IsTransient reports whether err is worth retrying, see manager.IsTransient
+func IsTransient(err error) bool {
+	return manager.IsTransient(err)
+}
@@ -0,0 +1,205 @@
+package configuration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Instance identifies the running process for rollout targeting
+type Instance struct {
+	ID     string
+	Labels map[string]string
+}
+
+// SetInstance sets the identity used to decide rollout membership
+func (m *Manager) SetInstance(instance Instance) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.instance = instance
+}
+
+// RolloutState is the lifecycle state of a rollout on this instance
+type RolloutState string
+
+const (
+	// RolloutWaiting means the instance is not yet inside the rollout percentage
+	RolloutWaiting RolloutState = "waiting"
+	// RolloutBaking means the change is applied and under health observation
+	RolloutBaking RolloutState = "baking"
+	// RolloutPromoted means every step passed and the change is kept
+	RolloutPromoted RolloutState = "promoted"
+	// RolloutRolledBack means a health check failed and the change was reverted
+	RolloutRolledBack RolloutState = "rolled_back"
+	// RolloutSkipped means the instance never joined (labels or percentage)
+	RolloutSkipped RolloutState = "skipped"
+)
+
+// finished reports whether a rollout in state s has stopped running
+func (s RolloutState) finished() bool {
+	return s == RolloutPromoted || s == RolloutRolledBack || s == RolloutSkipped
+}
+
+// Rollout applies a runtime patch to a growing share of instances. Every
+// instance runs the same rollout; membership is a stable hash of the rollout
+// and instance IDs, so instances admitted at one step stay admitted at the
+// next
+type Rollout struct {
+	ID    string
+	Patch map[string]interface{}
+	// Steps are cumulative percentages, e.g. 5, 25, 100; default is 100
+	Steps []float64
+	// Selector restricts the rollout to instances with matching labels
+	Selector map[string]string
+	// BakeTime is how long each step is observed before the next
+	BakeTime time.Duration
+	// CheckInterval is how often Health is called while baking
+	CheckInterval time.Duration
+	// Health reports whether this instance is healthy with the change
+	Health func(ctx context.Context) error
+}
+
+// RolloutStatus is the progress of a rollout on this instance
+type RolloutStatus struct {
+	ID      string       `json:"id"`
+	State   RolloutState `json:"state"`
+	Percent float64      `json:"percent"`
+	Error   string       `json:"error,omitempty"`
+}
+
+// StartRollout runs a rollout in the background until it is promoted,
+// rolled back or ctx is cancelled
+func (m *Manager) StartRollout(ctx context.Context, rollout Rollout) error {
+	if rollout.ID == "" || len(rollout.Patch) == 0 {
+		return errors.New("rollout needs an ID and a patch")
+	}
+	if len(rollout.Steps) == 0 {
+		rollout.Steps = []float64{100}
+	}
+	for i, step := range rollout.Steps {
+		if step <= 0 || step > 100 || (i > 0 && step < rollout.Steps[i-1]) {
+			return fmt.Errorf("rollout %s: steps must increase within (0, 100]", rollout.ID)
+		}
+	}
+	if rollout.BakeTime <= 0 {
+		rollout.BakeTime = time.Minute
+	}
+	if rollout.CheckInterval <= 0 {
+		rollout.CheckInterval = rollout.BakeTime / 10
+	}
+
+	m.mu.Lock()
+	if m.rollouts == nil {
+		m.rollouts = make(map[string]*RolloutStatus)
+	}
+	if status, ok := m.rollouts[rollout.ID]; ok && !status.State.finished() {
+		m.mu.Unlock()
+		return fmt.Errorf("rollout %s is already running", rollout.ID)
+	}
+	instance := m.instance
+	m.rollouts[rollout.ID] = &RolloutStatus{ID: rollout.ID, State: RolloutWaiting}
+	m.mu.Unlock()
+
+	go m.runRollout(ctx, rollout, instance)
+	return nil
+}
+
+// RolloutStatus returns the progress of a rollout started on this instance
+func (m *Manager) RolloutStatus(id string) (RolloutStatus, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	status, ok := m.rollouts[id]
+	if !ok {
+		return RolloutStatus{}, false
+	}
+	return *status, true
+}
+
+// setRolloutStatus records progress
+func (m *Manager) setRolloutStatus(id string, state RolloutState, percent float64, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	status := &RolloutStatus{ID: id, State: state, Percent: percent}
+	if err != nil {
+		status.Error = err.Error()
+	}
+	m.rollouts[id] = status
+}
+
+// runRollout walks the steps, applying the patch once this instance is
+// admitted and reverting it on the first failed health check; the revert
+// only restores the keys the patch touched, so runtime changes made by
+// others while the rollout baked are kept
+func (m *Manager) runRollout(ctx context.Context, rollout Rollout, instance Instance) {
+	for key, want := range rollout.Selector {
+		if instance.Labels[key] != want {
+			m.setRolloutStatus(rollout.ID, RolloutSkipped, 0, nil)
+			return
+		}
+	}
+
+	slot := bucket(rollout.ID, instance.ID)
+	var undo map[string]interface{}
+	applied := false
+	for _, percent := range rollout.Steps {
+		if !applied && slot < percent {
+			previous, _ := m.layers.Layer(LayerRuntime)
+			undo = inverseMergePatch(previous, expandPatch(rollout.Patch))
+			if err := m.ApplyPatch(ctx, rollout.Patch, WithPatchSource("rollout:"+rollout.ID)); err != nil {
+				m.setRolloutStatus(rollout.ID, RolloutRolledBack, percent, err)
+				return
+			}
+			applied = true
+			m.logger.Printf("Rollout %s applied at %.0f%%", rollout.ID, percent)
+		}
+		state := RolloutWaiting
+		if applied {
+			state = RolloutBaking
+		}
+		m.setRolloutStatus(rollout.ID, state, percent, nil)
+
+		if err := m.bake(ctx, rollout, applied); err != nil {
+			if applied {
+				// Revert even when ctx ended the bake
+				restoreErr := m.ApplyPatch(context.WithoutCancel(ctx), undo, WithPatchSource("rollout:"+rollout.ID+":rollback"))
+				if restoreErr != nil {
+					err = fmt.Errorf("%v (rollback failed: %v)", err, restoreErr)
+				}
+				m.logger.Printf("Rollout %s rolled back: %v", rollout.ID, err)
+			}
+			m.setRolloutStatus(rollout.ID, RolloutRolledBack, percent, err)
+			return
+		}
+	}
+
+	if !applied {
+		m.setRolloutStatus(rollout.ID, RolloutSkipped, 100, nil)
+		return
+	}
+	m.setRolloutStatus(rollout.ID, RolloutPromoted, 100, nil)
+	m.logger.Printf("Rollout %s promoted", rollout.ID)
+}
+
+// bake waits out one step, checking health while the change is applied
+func (m *Manager) bake(ctx context.Context, rollout Rollout, applied bool) error {
+	deadline := time.NewTimer(rollout.BakeTime)
+	defer deadline.Stop()
+	ticker := time.NewTicker(rollout.CheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-deadline.C:
+			return nil
+		case <-ticker.C:
+			if !applied || rollout.Health == nil {
+				continue
+			}
+			if err := rollout.Health(ctx); err != nil {
+				return fmt.Errorf("health check failed: %w", err)
+			}
+		}
+	}
+}
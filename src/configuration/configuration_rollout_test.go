@@ -0,0 +1,131 @@
+package configuration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestInverseMergePatch(t *testing.T) {
+	target := map[string]interface{}{
+		"a":  1,
+		"db": map[string]interface{}{"host": "db", "port": 5432},
+		"x":  "scalar",
+	}
+	patch := map[string]interface{}{
+		"a":  2,
+		"b":  3,
+		"db": map[string]interface{}{"port": 6432, "user": "app", "pool": map[string]interface{}{"size": 4}},
+		"x":  map[string]interface{}{"y": 1},
+		"z":  nil,
+	}
+	want := map[string]interface{}{
+		"a":  1,
+		"b":  nil,
+		"db": map[string]interface{}{"port": 5432, "user": nil, "pool": nil},
+		"x":  "scalar",
+		"z":  nil,
+	}
+	inverse := inverseMergePatch(target, patch)
+	if !reflect.DeepEqual(inverse, want) {
+		t.Fatalf("inverse = %v, want %v", inverse, want)
+	}
+	if got := applyMergePatch(applyMergePatch(target, patch), inverse); !reflect.DeepEqual(got, target) {
+		t.Errorf("patch then inverse = %v, want %v", got, target)
+	}
+}
+
+// waitRollout polls until the rollout reaches state
+func waitRollout(t *testing.T, m *Manager, id string, state RolloutState) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		status, _ := m.RolloutStatus(id)
+		if status.State == state {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("rollout %s is %+v, want %s", id, status, state)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestRolloutRollbackKeepsOtherChanges(t *testing.T) {
+	m := NewManager(nil)
+	m.SetInstance(Instance{ID: "instance"})
+	if err := m.SetLayer(LayerRuntime, "test", map[string]interface{}{"retries": 1, "mode": "a"}); err != nil {
+		t.Fatal(err)
+	}
+
+	var unhealthy atomic.Bool
+	err := m.StartRollout(context.Background(), Rollout{
+		ID:            "retries",
+		Patch:         map[string]interface{}{"retries": 5, "timeout": "3s"},
+		BakeTime:      time.Minute,
+		CheckInterval: 5 * time.Millisecond,
+		Health: func(ctx context.Context) error {
+			if unhealthy.Load() {
+				return errors.New("error rate up")
+			}
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitRollout(t, m, "retries", RolloutBaking)
+	if value, _ := m.Get("retries"); value != 5 {
+		t.Fatalf("retries = %v while baking, want 5", value)
+	}
+
+	// Changes made by others while the rollout bakes survive its rollback
+	if err := m.ApplyPatch(context.Background(), map[string]interface{}{"mode": "b"}); err != nil {
+		t.Fatal(err)
+	}
+	unhealthy.Store(true)
+	waitRollout(t, m, "retries", RolloutRolledBack)
+
+	runtime, _ := m.layers.Layer(LayerRuntime)
+	if want := map[string]interface{}{"retries": 1, "mode": "b"}; !reflect.DeepEqual(runtime, want) {
+		t.Errorf("runtime layer after rollback = %v, want %v", runtime, want)
+	}
+}
+
+func TestStartRolloutRejectsRunningID(t *testing.T) {
+	m := NewManager(nil)
+	m.SetInstance(Instance{ID: "instance"})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Find an ID whose first 5% step leaves this instance waiting
+	id := ""
+	for i := 0; id == ""; i++ {
+		if candidate := fmt.Sprintf("rollout-%d", i); bucket(candidate, "instance") >= 5 {
+			id = candidate
+		}
+	}
+	rollout := Rollout{ID: id, Patch: map[string]interface{}{"retries": 5}, Steps: []float64{5, 100}, BakeTime: time.Hour}
+	if err := m.StartRollout(ctx, rollout); err != nil {
+		t.Fatal(err)
+	}
+	if status, _ := m.RolloutStatus(id); status.State != RolloutWaiting {
+		t.Fatalf("status = %+v, want waiting", status)
+	}
+	if err := m.StartRollout(ctx, rollout); err == nil {
+		t.Error("a waiting rollout was started again")
+	}
+
+	skipped := Rollout{ID: "skipped", Patch: rollout.Patch, Selector: map[string]string{"zone": "eu"}}
+	if err := m.StartRollout(ctx, skipped); err != nil {
+		t.Fatal(err)
+	}
+	waitRollout(t, m, "skipped", RolloutSkipped)
+	if err := m.StartRollout(ctx, skipped); err != nil {
+		t.Errorf("restarting a finished rollout = %v", err)
+	}
+}
@@ -0,0 +1,119 @@
+package configuration
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+
+	"github.com/nerufuyo/roastume/src/validation"
+)
+
+// SetValidation makes engine enforce the rule sets added with
+// AddSectionRules, so configuration and payloads share one rule engine,
+// catalog and error format; until then a default engine is used
+func (m *Manager) SetValidation(engine *validation.Manager) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.validation = engine
+}
+
+// AddSectionRules enforces sets on the section under key, "" meaning
+// Config itself, whenever a patch, a synced or restored layer, or Verify
+// produces a new configuration. The section is decoded into the type
+// registered for it with RegisterSchema, or checked as a map otherwise.
+// Failures are validation.ValidationErrors with paths from the document
+// root, e.g. "database.port"
+func (m *Manager) AddSectionRules(key string, sets ...*validation.RuleSet) error {
+	for _, set := range sets {
+		if set == nil {
+			return fmt.Errorf("section %s: rule set must not be nil", key)
+		}
+		if _, err := set.Plan(); err != nil {
+			return fmt.Errorf("section %s: rule set %q: %w", key, set.Name(), err)
+		}
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.sectionRules == nil {
+		m.sectionRules = make(map[string][]*validation.RuleSet)
+	}
+	m.sectionRules[key] = append(m.sectionRules[key], sets...)
+	return nil
+}
+
+// AddRules enforces sets on the namespace's section, see AddSectionRules
+func (n *Namespace) AddRules(sets ...*validation.RuleSet) error {
+	return n.manager.AddSectionRules(n.name, sets...)
+}
+
+// checkRules runs the section rule sets against config and values
+func (m *Manager) checkRules(config *Config, values map[string]interface{}) error {
+	m.mu.Lock()
+	if len(m.sectionRules) == 0 {
+		m.mu.Unlock()
+		return nil
+	}
+	if m.validation == nil {
+		m.validation = validation.NewManager(validation.DefaultConfig())
+	}
+	engine := m.validation
+	rules := make(map[string][]*validation.RuleSet, len(m.sectionRules))
+	for key, sets := range m.sectionRules {
+		rules[key] = sets
+	}
+	schemas := append([]sectionSchema(nil), m.schemas...)
+	m.mu.Unlock()
+
+	keys := make([]string, 0, len(rules))
+	for key := range rules {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var violations validation.ValidationErrors
+	for _, key := range keys {
+		value, err := sectionValue(config, values, key, schemas)
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+		results := engine.CheckWith(context.Background(), value, rules[key]...)
+		for _, v := range results.Violations {
+			if v.Path == "" {
+				v.Path = key
+			} else {
+				v.Path = joinKey(key, v.Path)
+			}
+			violations = append(violations, v)
+		}
+		for _, v := range results.Warnings {
+			m.logger.Warnf("Configuration %s: %s", key, v)
+		}
+	}
+	if len(violations) > 0 {
+		return violations
+	}
+	return nil
+}
+
+// sectionValue returns the section under key as rules should see it
+func sectionValue(config *Config, values map[string]interface{}, key string, schemas []sectionSchema) (interface{}, error) {
+	if key == "" {
+		return config, nil
+	}
+	section := make(map[string]interface{})
+	if raw, ok := lookup(values, key); ok {
+		if section, ok = raw.(map[string]interface{}); !ok {
+			return nil, fmt.Errorf("not a section")
+		}
+	}
+	for _, schema := range schemas {
+		if schema.section == key {
+			target := reflect.New(schema.typ).Interface()
+			if err := Decode(section, target, false); err != nil {
+				return nil, err
+			}
+			return target, nil
+		}
+	}
+	return section, nil
+}
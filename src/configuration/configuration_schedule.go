@@ -0,0 +1,131 @@
+package configuration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ErrNotScheduled is returned when cancelling a change that already ran
+var ErrNotScheduled = errors.New("change is not pending")
+
+// ScheduleState is the lifecycle state of a scheduled change
+type ScheduleState string
+
+const (
+	// SchedulePending changes wait for their effective time
+	SchedulePending ScheduleState = "pending"
+	// ScheduleApplied changes were applied
+	ScheduleApplied ScheduleState = "applied"
+	// ScheduleFailed changes were rejected when applied
+	ScheduleFailed ScheduleState = "failed"
+	// ScheduleCancelled changes were cancelled before their time
+	ScheduleCancelled ScheduleState = "cancelled"
+)
+
+// ScheduledChange is a runtime patch applied at a fixed time
+type ScheduledChange struct {
+	ID     string                 `json:"id"`
+	At     time.Time              `json:"at"`
+	Source string                 `json:"source"`
+	Patch  map[string]interface{} `json:"patch"`
+	State  ScheduleState          `json:"state"`
+	Error  string                 `json:"error,omitempty"`
+}
+
+// schedule tracks pending and finished scheduled changes
+type schedule struct {
+	mu      sync.Mutex
+	next    int
+	changes map[string]*ScheduledChange
+	timers  map[string]*time.Timer
+}
+
+// Schedule applies patch to the runtime layer (JSON merge patch semantics)
+// at the given time; all instances scheduling the same change for the same
+// wall-clock time switch together. A time in the past applies immediately
+func (m *Manager) Schedule(at time.Time, source string, patch map[string]interface{}) (string, error) {
+	if len(patch) == 0 {
+		return "", errors.New("empty patch")
+	}
+	s := &m.scheduled
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.changes == nil {
+		s.changes = make(map[string]*ScheduledChange)
+		s.timers = make(map[string]*time.Timer)
+	}
+	s.next++
+	id := fmt.Sprintf("change-%d", s.next)
+	change := &ScheduledChange{ID: id, At: at, Source: source, Patch: copyTree(patch), State: SchedulePending}
+	s.changes[id] = change
+	s.timers[id] = time.AfterFunc(time.Until(at), func() { m.runScheduled(id) })
+	m.logger.Printf("Scheduled configuration change %s from %s at %s", id, source, at.Format(time.RFC3339))
+	return id, nil
+}
+
+// runScheduled applies a due change
+func (m *Manager) runScheduled(id string) {
+	s := &m.scheduled
+	s.mu.Lock()
+	change, ok := s.changes[id]
+	if _, pending := s.timers[id]; !ok || !pending {
+		s.mu.Unlock()
+		return
+	}
+	delete(s.timers, id)
+	s.mu.Unlock()
+
+	err := m.ApplyPatch(context.Background(), change.Patch, WithPatchSource("scheduled:"+change.Source))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err != nil {
+		change.State = ScheduleFailed
+		change.Error = err.Error()
+		m.logger.Printf("Scheduled configuration change %s failed: %v", id, err)
+		return
+	}
+	change.State = ScheduleApplied
+	m.logger.Printf("Applied scheduled configuration change %s", id)
+}
+
+// CancelScheduled stops a pending change
+func (m *Manager) CancelScheduled(id string) error {
+	s := &m.scheduled
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// The timer is removed once the change starts applying
+	timer, pending := s.timers[id]
+	if !pending {
+		return fmt.Errorf("%w: %s", ErrNotScheduled, id)
+	}
+	timer.Stop()
+	delete(s.timers, id)
+	s.changes[id].State = ScheduleCancelled
+	m.logger.Printf("Cancelled scheduled configuration change %s", id)
+	return nil
+}
+
+// Scheduled lists scheduled changes ordered by time
+func (m *Manager) Scheduled() []ScheduledChange {
+	s := &m.scheduled
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	out := make([]ScheduledChange, 0, len(s.changes))
+	for _, change := range s.changes {
+		c := *change
+		c.Patch = copyTree(change.Patch)
+		out = append(out, c)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].At.Equal(out[j].At) {
+			return out[i].ID < out[j].ID
+		}
+		return out[i].At.Before(out[j].At)
+	})
+	return out
+}
@@ -0,0 +1,175 @@
+package configuration
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// JSONSchemaDialect is the draft generated schemas declare
+const JSONSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// JSONSchema describes the manager's configuration document: Config at the
+// root plus every section registered with RegisterSchema, with the
+// current defaults filled in as "default" annotations
+func (m *Manager) JSONSchema() map[string]interface{} {
+	defaults, _ := m.layers.Layer(LayerDefaults)
+	m.mu.RLock()
+	schemas := append([]sectionSchema(nil), m.schemas...)
+	m.mu.RUnlock()
+
+	root := typeSchema(reflect.TypeOf(Config{}), defaults)
+	root["$schema"] = JSONSchemaDialect
+	root["title"] = "configuration"
+	for _, schema := range schemas {
+		attachSection(root, strings.Split(schema.section, "."), typeSchema(schema.typ, schema.defaults))
+	}
+	return root
+}
+
+// attachSection places a section schema under the dotted path parts,
+// creating intermediate object schemas
+func attachSection(node map[string]interface{}, parts []string, section map[string]interface{}) {
+	properties, ok := node["properties"].(map[string]interface{})
+	if !ok {
+		properties = make(map[string]interface{})
+		node["properties"] = properties
+	}
+	if len(parts) == 1 {
+		properties[parts[0]] = section
+		return
+	}
+	child, ok := properties[parts[0]].(map[string]interface{})
+	if !ok {
+		child = map[string]interface{}{"type": "object"}
+		properties[parts[0]] = child
+	}
+	attachSection(child, parts[1:], section)
+}
+
+// typeSchema converts a Go type into a JSON Schema; def is the default
+// value to annotate, if any
+func typeSchema(t reflect.Type, def interface{}) map[string]interface{} {
+	t = indirectType(t)
+	schema := make(map[string]interface{})
+	switch {
+	case t == durationType:
+		schema["type"] = []string{"string", "integer"}
+		schema["description"] = `duration such as "30s" or "1d12h"; integers are nanoseconds`
+	case t == reflect.TypeOf(ByteSize{}):
+		schema["type"] = []string{"string", "integer"}
+		schema["description"] = `byte size such as "512MiB" or "1.5GB"; integers are bytes`
+	case t == reflect.TypeOf(Percent(0)):
+		schema["type"] = []string{"string", "number"}
+		schema["description"] = `percentage such as "25%"`
+	case t == reflect.TypeOf(time.Time{}):
+		schema["type"] = "string"
+		schema["format"] = "date-time"
+	case reflect.PointerTo(t).Implements(textUnmarshalerType):
+		schema["type"] = "string"
+	default:
+		switch t.Kind() {
+		case reflect.Bool:
+			schema["type"] = "boolean"
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			schema["type"] = "integer"
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			schema["type"] = "integer"
+			schema["minimum"] = 0
+		case reflect.Float32, reflect.Float64:
+			schema["type"] = "number"
+		case reflect.String:
+			schema["type"] = "string"
+		case reflect.Slice, reflect.Array:
+			schema["type"] = "array"
+			schema["items"] = typeSchema(t.Elem(), nil)
+		case reflect.Map:
+			schema["type"] = "object"
+			schema["additionalProperties"] = typeSchema(t.Elem(), nil)
+		case reflect.Struct:
+			defaults, _ := def.(map[string]interface{})
+			properties := make(map[string]interface{})
+			var required []string
+			for _, f := range orderedFields(t) {
+				key := fieldKey(f)
+				var field map[string]interface{}
+				if _, ok := f.Tag.Lookup(SensitiveTag); ok {
+					// Never publish defaults of sensitive fields
+					field = typeSchema(f.Type, nil)
+					field["writeOnly"] = true
+				} else {
+					field = typeSchema(f.Type, defaults[key])
+				}
+				if desc := f.Tag.Get(DescriptionTag); desc != "" {
+					if format, ok := field["description"].(string); ok {
+						desc += "; " + format
+					}
+					field["description"] = desc
+				}
+				if f.Tag.Get(RequiredTag) == "true" {
+					required = append(required, key)
+				}
+				properties[key] = field
+			}
+			schema["type"] = "object"
+			schema["properties"] = properties
+			schema["additionalProperties"] = false
+			if len(required) > 0 {
+				schema["required"] = required
+			}
+			return schema
+		}
+	}
+	if def != nil {
+		schema["default"] = plainValue(def)
+	}
+	return schema
+}
+
+// ExampleDocument returns a complete document built from the defaults of
+// Config and of every registered section
+func (m *Manager) ExampleDocument() map[string]interface{} {
+	example, _ := m.layers.Layer(LayerDefaults)
+	m.mu.RLock()
+	schemas := append([]sectionSchema(nil), m.schemas...)
+	m.mu.RUnlock()
+	for _, schema := range schemas {
+		if _, ok := lookup(example, schema.section); !ok {
+			setPath(example, schema.section, copyTree(schema.defaults))
+		}
+	}
+	return plainValue(example).(map[string]interface{})
+}
+
+// WriteJSONSchema writes the JSON Schema to path, e.g. for editors to pick
+// up through a "$schema" reference
+func (m *Manager) WriteJSONSchema(path string) error {
+	data, err := json.MarshalIndent(m.JSONSchema(), "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode schema: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("write schema: %w", err)
+	}
+	return nil
+}
+
+// WriteExample writes the example document to path in the given format,
+// with sensitive defaults masked
+func (m *Manager) WriteExample(path string, format Format) error {
+	if format == FormatAuto {
+		format = FormatFromPath(path)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("write example: %w", err)
+	}
+	if err := encodeTree(f, m.redactTree(m.ExampleDocument()), format, exportOptions{}); err != nil {
+		f.Close()
+		return fmt.Errorf("write example: %w", err)
+	}
+	return f.Close()
+}
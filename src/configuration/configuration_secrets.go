@@ -0,0 +1,383 @@
+package configuration
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Secret is a resolved secret value with optional lease information
+type Secret struct {
+	Value     string
+	LeaseID   string
+	Lease     time.Duration
+	Renewable bool
+}
+
+// SecretResolver resolves a reference such as "vault://path#field" into a secret
+type SecretResolver interface {
+	Resolve(ctx context.Context, ref string) (*Secret, error)
+}
+
+// SecretRenewer is implemented by resolvers whose leases can be extended
+type SecretRenewer interface {
+	Renew(ctx context.Context, secret *Secret) (*Secret, error)
+}
+
+// SecretResolverFunc adapts a function to the SecretResolver interface
+type SecretResolverFunc func(ctx context.Context, ref string) (*Secret, error)
+
+// Resolve calls f(ctx, ref)
+func (f SecretResolverFunc) Resolve(ctx context.Context, ref string) (*Secret, error) {
+	return f(ctx, ref)
+}
+
+// EnvSecretResolver resolves "env://NAME" from the process environment
+var EnvSecretResolver = SecretResolverFunc(func(ctx context.Context, ref string) (*Secret, error) {
+	name := strings.TrimPrefix(ref, "env://")
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return nil, fmt.Errorf("environment variable %s is not set", name)
+	}
+	return &Secret{Value: value}, nil
+})
+
+// FileSecretResolver resolves "file:///path" to the trimmed file content
+var FileSecretResolver = SecretResolverFunc(func(ctx context.Context, ref string) (*Secret, error) {
+	data, err := os.ReadFile(strings.TrimPrefix(ref, "file://"))
+	if err != nil {
+		return nil, err
+	}
+	return &Secret{Value: strings.TrimRight(string(data), "\r\n")}, nil
+})
+
+// VaultResolver resolves "vault://mount/path#field" against the HashiCorp
+// Vault HTTP API, supporting KV v1/v2 and leased dynamic secrets
+type VaultResolver struct {
+	Address string
+	Token   string
+	Client  *http.Client
+}
+
+// Resolve reads the secret and extracts the requested field
+func (r *VaultResolver) Resolve(ctx context.Context, ref string) (*Secret, error) {
+	path, field, _ := strings.Cut(strings.TrimPrefix(ref, "vault://"), "#")
+	var body struct {
+		LeaseID       string                 `json:"lease_id"`
+		LeaseDuration int                    `json:"lease_duration"`
+		Renewable     bool                   `json:"renewable"`
+		Data          map[string]interface{} `json:"data"`
+	}
+	if err := r.do(ctx, http.MethodGet, "/v1/"+path, nil, &body); err != nil {
+		return nil, err
+	}
+
+	data := body.Data
+	// KV v2 nests the payload one level deeper
+	if inner, ok := data["data"].(map[string]interface{}); ok {
+		if _, hasMeta := data["metadata"]; hasMeta {
+			data = inner
+		}
+	}
+	if field == "" {
+		field = "value"
+	}
+	value, ok := data[field]
+	if !ok {
+		return nil, fmt.Errorf("vault: field %q not found at %s", field, path)
+	}
+	return &Secret{
+		Value:     fmt.Sprint(value),
+		LeaseID:   body.LeaseID,
+		Lease:     time.Duration(body.LeaseDuration) * time.Second,
+		Renewable: body.Renewable,
+	}, nil
+}
+
+// Renew extends the lease of a dynamic secret
+func (r *VaultResolver) Renew(ctx context.Context, secret *Secret) (*Secret, error) {
+	var body struct {
+		LeaseID       string `json:"lease_id"`
+		LeaseDuration int    `json:"lease_duration"`
+		Renewable     bool   `json:"renewable"`
+	}
+	if err := r.do(ctx, http.MethodPut, "/v1/sys/leases/renew", map[string]string{"lease_id": secret.LeaseID}, &body); err != nil {
+		return nil, err
+	}
+	renewed := *secret
+	renewed.Lease = time.Duration(body.LeaseDuration) * time.Second
+	renewed.Renewable = body.Renewable
+	return &renewed, nil
+}
+
+// do performs an authenticated Vault API request
+func (r *VaultResolver) do(ctx context.Context, method, path string, in, out interface{}) error {
+	var reader *strings.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		reader = strings.NewReader(string(data))
+	} else {
+		reader = strings.NewReader("")
+	}
+	req, err := http.NewRequestWithContext(ctx, method, strings.TrimRight(r.Address, "/")+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Vault-Token", r.Token)
+
+	client := r.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("vault: unexpected status %d for %s", resp.StatusCode, path)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// cachedSecret is a resolved secret and when it must be refreshed
+type cachedSecret struct {
+	secret    *Secret
+	scheme    string
+	expiresAt time.Time
+}
+
+// Secrets resolves secret references in configuration values through
+// per-scheme resolvers, caching results until their lease expires
+type Secrets struct {
+	mu         sync.Mutex
+	resolvers  map[string]SecretResolver
+	cache      map[string]cachedSecret
+	timeout    time.Duration
+	defaultTTL time.Duration
+}
+
+// NewSecrets creates a resolver set with env:// and file:// registered
+func NewSecrets() *Secrets {
+	s := &Secrets{
+		resolvers:  make(map[string]SecretResolver),
+		cache:      make(map[string]cachedSecret),
+		timeout:    10 * time.Second,
+		defaultTTL: 5 * time.Minute,
+	}
+	s.Register("env", EnvSecretResolver)
+	s.Register("file", FileSecretResolver)
+	return s
+}
+
+// Register installs the resolver for references with the given scheme
+func (s *Secrets) Register(scheme string, resolver SecretResolver) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.resolvers[scheme] = resolver
+}
+
+// scheme returns the registered scheme of a reference, if any
+func (s *Secrets) scheme(value string) (string, bool) {
+	scheme, _, ok := strings.Cut(value, "://")
+	if !ok {
+		return "", false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, registered := s.resolvers[scheme]
+	return scheme, registered
+}
+
+// resolve returns the cached or freshly resolved secret for ref
+func (s *Secrets) resolve(ctx context.Context, scheme, ref string) (string, error) {
+	s.mu.Lock()
+	cached, ok := s.cache[ref]
+	resolver := s.resolvers[scheme]
+	s.mu.Unlock()
+	if ok && time.Now().Before(cached.expiresAt) {
+		return cached.secret.Value, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	secret, err := resolver.Resolve(ctx, ref)
+	if err != nil {
+		return "", fmt.Errorf("resolve %s: %w", ref, err)
+	}
+	s.store(ref, scheme, secret)
+	return secret.Value, nil
+}
+
+// store caches a secret until its lease (or the default TTL) expires
+func (s *Secrets) store(ref, scheme string, secret *Secret) {
+	ttl := secret.Lease
+	if ttl <= 0 {
+		ttl = s.defaultTTL
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cache[ref] = cachedSecret{secret: secret, scheme: scheme, expiresAt: time.Now().Add(ttl)}
+}
+
+// ResolveTree returns a copy of tree with every secret reference replaced
+func (s *Secrets) ResolveTree(ctx context.Context, tree map[string]interface{}) (map[string]interface{}, error) {
+	resolved, err := s.resolveValue(ctx, copyTree(tree))
+	if err != nil {
+		return nil, err
+	}
+	return resolved.(map[string]interface{}), nil
+}
+
+// resolveValue replaces references in place within a copied value
+func (s *Secrets) resolveValue(ctx context.Context, v interface{}) (interface{}, error) {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		for k, inner := range t {
+			r, err := s.resolveValue(ctx, inner)
+			if err != nil {
+				return nil, err
+			}
+			t[k] = r
+		}
+		return t, nil
+	case []interface{}:
+		for i, inner := range t {
+			r, err := s.resolveValue(ctx, inner)
+			if err != nil {
+				return nil, err
+			}
+			t[i] = r
+		}
+		return t, nil
+	case string:
+		if scheme, ok := s.scheme(t); ok {
+			return s.resolve(ctx, scheme, t)
+		}
+	}
+	return v, nil
+}
+
+// referenceKeys returns the dotted keys of tree holding secret references;
+// a list holding one is reported as a whole
+func (s *Secrets) referenceKeys(tree map[string]interface{}) []string {
+	var keys []string
+	for key, value := range flatten(tree) {
+		if s.hasReference(value) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// hasReference reports whether v is or contains a secret reference
+func (s *Secrets) hasReference(v interface{}) bool {
+	switch t := v.(type) {
+	case string:
+		_, ok := s.scheme(t)
+		return ok
+	case []interface{}:
+		for _, inner := range t {
+			if s.hasReference(inner) {
+				return true
+			}
+		}
+	case map[string]interface{}:
+		for _, inner := range t {
+			if s.hasReference(inner) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// renewDue renews or re-resolves cached secrets expiring within margin and
+// reports whether any value changed
+func (s *Secrets) renewDue(ctx context.Context, margin time.Duration) (bool, error) {
+	s.mu.Lock()
+	due := make(map[string]cachedSecret)
+	for ref, c := range s.cache {
+		if time.Until(c.expiresAt) < margin {
+			due[ref] = c
+		}
+	}
+	s.mu.Unlock()
+
+	changed := false
+	var firstErr error
+	for ref, c := range due {
+		s.mu.Lock()
+		resolver := s.resolvers[c.scheme]
+		s.mu.Unlock()
+
+		var secret *Secret
+		var err error
+		if renewer, ok := resolver.(SecretRenewer); ok && c.secret.Renewable && c.secret.LeaseID != "" {
+			secret, err = renewer.Renew(ctx, c.secret)
+		}
+		if secret == nil {
+			secret, err = resolver.Resolve(ctx, ref)
+		}
+		if err != nil {
+			if firstErr == nil {
+				firstErr = fmt.Errorf("renew %s: %w", ref, err)
+			}
+			continue
+		}
+		if secret.Value != c.secret.Value {
+			changed = true
+		}
+		s.store(ref, c.scheme, secret)
+	}
+	return changed, firstErr
+}
+
+// UseSecrets enables secret reference resolution for the manager and
+// re-applies the configuration with references resolved
+func (m *Manager) UseSecrets(secrets *Secrets) error {
+	m.mu.Lock()
+	m.secrets = secrets
+	m.mu.Unlock()
+	return m.refresh()
+}
+
+// RenewSecrets keeps leased secrets fresh until ctx is cancelled, re-applying
+// the configuration whenever a renewed secret changes value
+func (m *Manager) RenewSecrets(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		m.mu.RLock()
+		secrets := m.secrets
+		m.mu.RUnlock()
+		if secrets == nil {
+			continue
+		}
+		changed, err := secrets.renewDue(ctx, 2*interval)
+		if err != nil {
+			m.logger.Printf("Secret renewal failed: %v", err)
+		}
+		if changed {
+			if err := m.refresh(); err != nil {
+				m.logger.Printf("Re-applying renewed secrets failed: %v", err)
+			}
+		}
+	}
+}
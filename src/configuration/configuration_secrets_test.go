@@ -0,0 +1,70 @@
+package configuration
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestResolvedSecretsAreMasked(t *testing.T) {
+	var logs bytes.Buffer
+	defer log.SetOutput(log.Writer())
+	log.SetOutput(&logs)
+	m := NewManager(nil)
+
+	versions := make(map[string]int)
+	secrets := NewSecrets()
+	secrets.Register("test", SecretResolverFunc(func(ctx context.Context, ref string) (*Secret, error) {
+		versions[ref]++
+		return &Secret{Value: fmt.Sprintf("hunter%d", versions[ref])}, nil
+	}))
+	if err := m.UseSecrets(secrets); err != nil {
+		t.Fatal(err)
+	}
+	err := m.SetLayer(LayerFile, "test", map[string]interface{}{
+		"db":    map[string]interface{}{"host": "db.internal", "password": "test://db"},
+		"peers": []interface{}{"a", "test://peer"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value, _ := m.Get("db.password"); value != "hunter1" {
+		t.Fatalf("db.password = %v, want the resolved secret", value)
+	}
+	for _, key := range []string{"db.password", "peers"} {
+		if !m.IsSensitive(key) {
+			t.Errorf("%s is not sensitive", key)
+		}
+	}
+	if m.IsSensitive("db.host") {
+		t.Error("db.host is sensitive")
+	}
+
+	// A rotated secret is logged masked
+	if _, err := secrets.renewDue(context.Background(), 24*time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.refresh(); err != nil {
+		t.Fatal(err)
+	}
+	if value, _ := m.Get("db.password"); value != "hunter2" {
+		t.Fatalf("db.password = %v after renewal, want hunter2", value)
+	}
+
+	var exported bytes.Buffer
+	if err := m.Export(&exported, FormatJSON); err != nil {
+		t.Fatal(err)
+	}
+	for name, out := range map[string]string{"log": logs.String(), "export": exported.String()} {
+		if strings.Contains(out, "hunter") {
+			t.Errorf("%s shows a secret:\n%s", name, out)
+		}
+	}
+	if !strings.Contains(exported.String(), "db.internal") {
+		t.Errorf("export lost plain values:\n%s", exported.String())
+	}
+}
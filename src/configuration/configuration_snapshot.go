@@ -0,0 +1,144 @@
+package configuration
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+)
+
+// persistedSnapshot is the on-disk form of the remote layer; only raw
+// layer values are written, so resolved secrets never reach the disk
+type persistedSnapshot struct {
+	Version int                    `json:"version"`
+	SavedAt time.Time              `json:"saved_at"`
+	Source  string                 `json:"source"`
+	Remote  map[string]interface{} `json:"remote"`
+}
+
+// PersistSnapshots writes the remote layer to path now and then every
+// interval while it keeps changing, until ctx is cancelled; UseProvider
+// falls back to the file when its provider is unreachable at startup
+func (m *Manager) PersistSnapshots(ctx context.Context, path string, interval time.Duration) error {
+	m.mu.Lock()
+	m.snapshotPath = path
+	m.mu.Unlock()
+
+	saved, err := m.saveSnapshot(path, 0)
+	if err != nil {
+		return err
+	}
+	if interval <= 0 {
+		interval = time.Minute
+	}
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			version, err := m.saveSnapshot(path, saved)
+			if err != nil {
+				m.logger.Printf("Failed to persist configuration snapshot: %v", err)
+				continue
+			}
+			saved = version
+		}
+	}()
+	return nil
+}
+
+// saveSnapshot writes the remote layer unless the version is still saved
+func (m *Manager) saveSnapshot(path string, saved int) (int, error) {
+	version := m.Version()
+	if version == saved {
+		return saved, nil
+	}
+	// A recovered snapshot is already on disk with its original timestamp
+	if stale, _ := m.Stale(); stale {
+		return saved, nil
+	}
+	layer, ok := m.layers.snapshotLayers()[LayerRemote]
+	if !ok {
+		return version, nil
+	}
+
+	data, err := json.MarshalIndent(persistedSnapshot{
+		Version: version,
+		SavedAt: time.Now().UTC(),
+		Source:  layer.source,
+		Remote:  layer.tree,
+	}, "", "  ")
+	if err != nil {
+		return saved, fmt.Errorf("encode snapshot: %w", err)
+	}
+	if err := writeFileAtomic(path, data, 0o600); err != nil {
+		return saved, fmt.Errorf("write snapshot %s: %w", path, err)
+	}
+	m.mu.RLock()
+	keyID, key := m.signKeyID, m.signKey
+	m.mu.RUnlock()
+	if key != nil {
+		sig, err := Sign(data, keyID, key)
+		if err != nil {
+			return saved, err
+		}
+		if err := writeFileAtomic(path+SignatureSuffix, sig, 0o600); err != nil {
+			return saved, fmt.Errorf("write snapshot signature %s: %w", path, err)
+		}
+	}
+	return version, nil
+}
+
+// RecoverSnapshot applies the remote layer persisted at path and marks the
+// configuration stale until fresh remote values arrive; with trusted keys
+// set, the snapshot must carry a valid signature
+func (m *Manager) RecoverSnapshot(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read snapshot: %w", err)
+	}
+	m.mu.RLock()
+	trusted := m.trustedKeys
+	m.mu.RUnlock()
+	if trusted != nil {
+		if err := verifyFileData(trusted, path, data); err != nil {
+			return err
+		}
+	}
+	var snap persistedSnapshot
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&snap); err != nil {
+		return fmt.Errorf("decode snapshot %s: %w", path, err)
+	}
+
+	if err := m.SetLayer(LayerRemote, "snapshot:"+snap.Source, snap.Remote); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	m.staleSince = snap.SavedAt
+	m.mu.Unlock()
+	m.logger.Printf("Recovered configuration snapshot saved at %s", snap.SavedAt.Format(time.RFC3339))
+	return nil
+}
+
+// Stale reports whether the remote layer comes from a recovered snapshot
+// and, if so, when that snapshot was saved
+func (m *Manager) Stale() (bool, time.Time) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return !m.staleSince.IsZero(), m.staleSince
+}
+
+// markFresh clears the staleness indicator after a live remote update
+func (m *Manager) markFresh() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.staleSince = time.Time{}
+}
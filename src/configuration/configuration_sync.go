@@ -0,0 +1,413 @@
+package configuration
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// SyncSnapshot is a validated remote layer fanned out by the leader;
+// sequences count up within an epoch, which is new for every leadership
+// term, so a leader restarting at the same address is not taken as stale
+type SyncSnapshot struct {
+	Epoch    string                 `json:"epoch"`
+	Sequence uint64                 `json:"sequence"`
+	Leader   string                 `json:"leader"`
+	Source   string                 `json:"source"`
+	Tree     map[string]interface{} `json:"tree"`
+	Checksum string                 `json:"checksum"`
+}
+
+// syncRequest opens a snapshot stream
+type syncRequest struct {
+	Node string `json:"node"`
+}
+
+// SyncRole is the current role of a sync node
+type SyncRole string
+
+const (
+	// SyncLeader pulls from the provider and serves snapshots
+	SyncLeader SyncRole = "leader"
+	// SyncFollower applies snapshots streamed from the leader
+	SyncFollower SyncRole = "follower"
+	// SyncCandidate has no known leader yet
+	SyncCandidate SyncRole = "candidate"
+)
+
+// SyncStatus describes the state of a sync node
+type SyncStatus struct {
+	Role     SyncRole  `json:"role"`
+	Leader   string    `json:"leader,omitempty"`
+	Epoch    string    `json:"epoch,omitempty"`
+	Sequence uint64    `json:"sequence"`
+	Checksum string    `json:"checksum,omitempty"`
+	Applied  time.Time `json:"applied,omitempty"`
+	Error    string    `json:"error,omitempty"`
+}
+
+// SyncNode keeps a fleet's remote layer consistent: the elected leader is
+// the only instance that talks to the provider; it applies each update
+// locally and, once it validated, streams it to every follower over gRPC.
+// Followers apply snapshots in sequence order and verify their checksum
+type SyncNode struct {
+	// Address is where this node serves snapshots and how peers reach it
+	Address string
+	// ServerOptions and DialOptions configure gRPC, e.g. with TLS
+	// credentials; Run requires both unless Insecure is set
+	ServerOptions []grpc.ServerOption
+	DialOptions   []grpc.DialOption
+	// Insecure allows plaintext, unauthenticated gRPC where ServerOptions
+	// or DialOptions are empty, e.g. in tests or on a trusted network
+	Insecure bool
+
+	manager  *Manager
+	provider Provider
+	elector  Elector
+
+	mu      sync.Mutex
+	status  SyncStatus
+	current *SyncSnapshot
+	// epoch identifies the current leadership term of this node
+	epoch   string
+	waiters []chan struct{}
+	// stopping is closed when Run stops serving, ending open streams
+	stopping chan struct{}
+}
+
+// NewSyncNode creates a sync node for m reachable at address
+func NewSyncNode(m *Manager, provider Provider, elector Elector, address string) *SyncNode {
+	return &SyncNode{Address: address, manager: m, provider: provider, elector: elector, status: SyncStatus{Role: SyncCandidate}}
+}
+
+// Status returns the node's role, leader and last applied snapshot
+func (n *SyncNode) Status() SyncStatus {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.status
+}
+
+// Run serves snapshots and follows leadership changes until ctx is
+// cancelled; it returns once the gRPC server stops
+func (n *SyncNode) Run(ctx context.Context) error {
+	if !n.Insecure && (len(n.ServerOptions) == 0 || len(n.DialOptions) == 0) {
+		return errors.New("sync: ServerOptions and DialOptions are required unless Insecure is set")
+	}
+	lis, err := net.Listen("tcp", n.Address)
+	if err != nil {
+		return fmt.Errorf("sync listen: %w", err)
+	}
+	server := grpc.NewServer(append([]grpc.ServerOption{grpc.ForceServerCodec(syncCodec{})}, n.ServerOptions...)...)
+	server.RegisterService(&syncServiceDesc, n)
+	stopping := make(chan struct{})
+	n.mu.Lock()
+	n.stopping = stopping
+	n.mu.Unlock()
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- server.Serve(lis) }()
+
+	var stopRole context.CancelFunc = func() {}
+	leaders := n.elector.Run(ctx, n.Address)
+loop:
+	for {
+		select {
+		case <-ctx.Done():
+			break loop
+		case err := <-serveErr:
+			stopRole()
+			close(stopping)
+			return fmt.Errorf("sync serve: %w", err)
+		case leader, ok := <-leaders:
+			if !ok {
+				break loop
+			}
+			stopRole()
+			var roleCtx context.Context
+			roleCtx, stopRole = context.WithCancel(ctx)
+			switch leader {
+			case n.Address:
+				n.setRole(SyncLeader, leader)
+				go n.lead(roleCtx)
+			case "":
+				n.setRole(SyncCandidate, "")
+			default:
+				n.setRole(SyncFollower, leader)
+				go n.follow(roleCtx, leader)
+			}
+		}
+	}
+	stopRole()
+	// Subscriptions only end with their follower, so end them first
+	close(stopping)
+	server.GracefulStop()
+	return nil
+}
+
+// setRole records a leadership change
+func (n *SyncNode) setRole(role SyncRole, leader string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.status.Role = role
+	n.status.Leader = leader
+	n.status.Error = ""
+	n.manager.logger.Printf("Configuration sync: %s (leader %q)", role, leader)
+}
+
+// setError records the last sync failure
+func (n *SyncNode) setError(err error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.status.Error = err.Error()
+	n.manager.logger.Printf("Configuration sync failed: %v", err)
+}
+
+// newEpoch returns a random leadership term identifier
+func newEpoch() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// lead pulls from the provider and publishes every tree that validates
+func (n *SyncNode) lead(ctx context.Context) {
+	n.mu.Lock()
+	n.epoch = newEpoch()
+	n.mu.Unlock()
+
+	apply := func(tree map[string]interface{}) {
+		if err := n.manager.setValidatedLayer(LayerRemote, n.provider.Name(), tree); err != nil {
+			n.setError(fmt.Errorf("update from %s rejected: %w", n.provider.Name(), err))
+			return
+		}
+		n.manager.markFresh()
+		n.publish(tree)
+	}
+
+	tree, err := n.provider.Load(ctx)
+	if err != nil {
+		n.setError(fmt.Errorf("provider %s: %w", n.provider.Name(), err))
+	} else {
+		apply(tree)
+	}
+	updates, err := n.provider.Watch(ctx)
+	if err != nil {
+		n.setError(fmt.Errorf("provider %s: %w", n.provider.Name(), err))
+		return
+	}
+	for tree := range updates {
+		apply(tree)
+	}
+}
+
+// publish makes tree the current snapshot and wakes the streams
+func (n *SyncNode) publish(tree map[string]interface{}) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	var sequence uint64 = 1
+	if n.current != nil && n.current.Epoch == n.epoch {
+		sequence = n.current.Sequence + 1
+	}
+	n.current = &SyncSnapshot{
+		Epoch:    n.epoch,
+		Sequence: sequence,
+		Leader:   n.Address,
+		Source:   n.provider.Name(),
+		Tree:     copyTree(tree),
+		Checksum: treeChecksum(tree),
+	}
+	n.status.Epoch = n.epoch
+	n.status.Sequence = sequence
+	n.status.Checksum = n.current.Checksum
+	n.status.Applied = time.Now()
+	for _, w := range n.waiters {
+		close(w)
+	}
+	n.waiters = nil
+}
+
+// next returns the current snapshot once it is newer than the one at
+// epoch and after, or a channel that is closed when one is published
+func (n *SyncNode) next(epoch string, after uint64) (*SyncSnapshot, <-chan struct{}) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.current != nil && n.status.Role == SyncLeader && (n.current.Epoch != epoch || n.current.Sequence > after) {
+		return n.current, nil
+	}
+	w := make(chan struct{})
+	n.waiters = append(n.waiters, w)
+	return nil, w
+}
+
+// serveSubscribe streams snapshots to a follower until it disconnects
+// or the node stops
+func (n *SyncNode) serveSubscribe(req *syncRequest, stream grpc.ServerStream) error {
+	n.mu.Lock()
+	stopping := n.stopping
+	n.mu.Unlock()
+	var epoch string
+	var sent uint64
+	for {
+		snap, wait := n.next(epoch, sent)
+		if snap == nil {
+			select {
+			case <-stream.Context().Done():
+				return stream.Context().Err()
+			case <-stopping:
+				return nil
+			case <-wait:
+				continue
+			}
+		}
+		if err := stream.SendMsg(snap); err != nil {
+			return err
+		}
+		epoch, sent = snap.Epoch, snap.Sequence
+	}
+}
+
+// follow streams snapshots from the leader and applies them, reconnecting
+// with backoff until ctx is cancelled
+func (n *SyncNode) follow(ctx context.Context, leader string) {
+	delay := 100 * time.Millisecond
+	for ctx.Err() == nil {
+		err := n.followOnce(ctx, leader)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			n.setError(fmt.Errorf("stream from %s: %w", leader, err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay = backoff(delay, 30*time.Second)
+	}
+}
+
+// followOnce applies snapshots from one stream
+func (n *SyncNode) followOnce(ctx context.Context, leader string) error {
+	opts := n.DialOptions
+	if len(opts) == 0 && n.Insecure {
+		opts = []grpc.DialOption{grpc.WithTransportCredentials(insecure.NewCredentials())}
+	}
+	opts = append(opts, grpc.WithDefaultCallOptions(grpc.ForceCodec(syncCodec{})))
+	conn, err := grpc.NewClient(leader, opts...)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	stream, err := conn.NewStream(ctx, &syncServiceDesc.Streams[0], "/"+syncServiceDesc.ServiceName+"/Subscribe")
+	if err != nil {
+		return err
+	}
+	if err := stream.SendMsg(&syncRequest{Node: n.Address}); err != nil {
+		return err
+	}
+	if err := stream.CloseSend(); err != nil {
+		return err
+	}
+	for {
+		var snap SyncSnapshot
+		if err := stream.RecvMsg(&snap); err != nil {
+			return err
+		}
+		if err := n.applySnapshot(&snap); err != nil {
+			n.setError(err)
+		}
+	}
+}
+
+// applySnapshot verifies and applies a snapshot received from the leader
+func (n *SyncNode) applySnapshot(snap *SyncSnapshot) error {
+	if sum := treeChecksum(snap.Tree); sum != snap.Checksum {
+		return fmt.Errorf("snapshot %d: checksum mismatch", snap.Sequence)
+	}
+	n.mu.Lock()
+	stale := n.current != nil && n.current.Epoch == snap.Epoch && snap.Sequence <= n.current.Sequence
+	n.mu.Unlock()
+	if stale {
+		return nil
+	}
+	if err := n.manager.setValidatedLayer(LayerRemote, "sync:"+snap.Leader+":"+snap.Source, snap.Tree); err != nil {
+		return fmt.Errorf("snapshot %d rejected: %w", snap.Sequence, err)
+	}
+	n.manager.markFresh()
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.current = snap
+	n.status.Epoch = snap.Epoch
+	n.status.Sequence = snap.Sequence
+	n.status.Checksum = snap.Checksum
+	n.status.Applied = time.Now()
+	n.status.Error = ""
+	return nil
+}
+
+// treeChecksum hashes the canonical JSON form of a tree; encoding/json
+// sorts map keys, so equal trees hash equally on every node
+func treeChecksum(tree map[string]interface{}) string {
+	data, err := json.Marshal(plainValue(tree))
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// syncServer is the handler type of the sync service
+type syncServer interface {
+	serveSubscribe(req *syncRequest, stream grpc.ServerStream) error
+}
+
+// syncServiceDesc describes the sync service; messages are JSON encoded,
+// so no generated protobuf code is involved
+var syncServiceDesc = grpc.ServiceDesc{
+	ServiceName: "roastume.configuration.Sync",
+	HandlerType: (*syncServer)(nil),
+	Streams: []grpc.StreamDesc{{
+		StreamName:    "Subscribe",
+		ServerStreams: true,
+		Handler: func(srv interface{}, stream grpc.ServerStream) error {
+			var req syncRequest
+			if err := stream.RecvMsg(&req); err != nil {
+				return err
+			}
+			return srv.(syncServer).serveSubscribe(&req, stream)
+		},
+	}},
+}
+
+// syncCodec encodes sync messages as JSON, keeping numbers exact
+type syncCodec struct{}
+
+// Marshal encodes v
+func (syncCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// Unmarshal decodes data into v
+func (syncCodec) Unmarshal(data []byte, v interface{}) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	return dec.Decode(v)
+}
+
+// Name returns the codec name
+func (syncCodec) Name() string {
+	return "json"
+}
@@ -0,0 +1,115 @@
+package configuration
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+)
+
+// freeAddress returns a loopback address nothing listens on
+func freeAddress(t *testing.T) string {
+	t.Helper()
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer lis.Close()
+	return lis.Addr().String()
+}
+
+func TestSyncNodeRequiresTransportOptions(t *testing.T) {
+	node := NewSyncNode(NewManager(nil), NewStaticProvider("test", nil), StaticElector{}, freeAddress(t))
+	if err := node.Run(context.Background()); err == nil {
+		t.Fatal("Run without transport options or Insecure succeeded")
+	}
+}
+
+func TestSyncNodeRunReturnsWithSubscribedFollower(t *testing.T) {
+	leaderAddr, followerAddr := freeAddress(t), freeAddress(t)
+	tree := map[string]interface{}{"retries": 2}
+
+	leader := NewSyncNode(NewManager(nil), NewStaticProvider("test", tree), StaticElector{Leader: leaderAddr}, leaderAddr)
+	leader.Insecure = true
+	follower := NewSyncNode(NewManager(nil), NewStaticProvider("test", nil), StaticElector{Leader: leaderAddr}, followerAddr)
+	follower.Insecure = true
+
+	leaderCtx, stopLeader := context.WithCancel(context.Background())
+	defer stopLeader()
+	followerCtx, stopFollower := context.WithCancel(context.Background())
+	defer stopFollower()
+	leaderDone := make(chan error, 1)
+	go func() { leaderDone <- leader.Run(leaderCtx) }()
+	go follower.Run(followerCtx)
+
+	deadline := time.Now().Add(5 * time.Second)
+	for follower.Status().Sequence == 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("follower never applied a snapshot: %+v", follower.Status())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	stopLeader()
+	select {
+	case err := <-leaderDone:
+		if err != nil {
+			t.Fatalf("Run = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run did not return while a follower was subscribed")
+	}
+}
+
+// syncSnapshot builds a snapshot of tree in epoch
+func syncSnapshot(epoch string, sequence uint64, tree map[string]interface{}) *SyncSnapshot {
+	return &SyncSnapshot{Epoch: epoch, Sequence: sequence, Leader: "leader:1", Source: "test", Tree: tree, Checksum: treeChecksum(tree)}
+}
+
+func TestFollowerAcceptsRestartedLeader(t *testing.T) {
+	m := NewManager(nil)
+	follower := NewSyncNode(m, NewStaticProvider("test", nil), StaticElector{}, "follower:1")
+
+	for _, step := range []struct {
+		snap *SyncSnapshot
+		want interface{}
+	}{
+		{syncSnapshot("first", 5, map[string]interface{}{"retries": 1}), 1},
+		{syncSnapshot("first", 4, map[string]interface{}{"retries": 2}), 1},
+		// The leader restarted at the same address and counts from 1 again
+		{syncSnapshot("second", 1, map[string]interface{}{"retries": 3}), 3},
+		{syncSnapshot("second", 1, map[string]interface{}{"retries": 4}), 3},
+		{syncSnapshot("second", 2, map[string]interface{}{"retries": 5}), 5},
+	} {
+		if err := follower.applySnapshot(step.snap); err != nil {
+			t.Fatal(err)
+		}
+		if got, _ := m.Get("retries"); got != step.want {
+			t.Errorf("after %s/%d retries = %v, want %v", step.snap.Epoch, step.snap.Sequence, got, step.want)
+		}
+	}
+}
+
+func TestLeaderSequenceRestartsWithEpoch(t *testing.T) {
+	leader := NewSyncNode(NewManager(nil), NewStaticProvider("test", nil), StaticElector{}, "leader:1")
+	tree := map[string]interface{}{"retries": 1}
+	leader.epoch = "first"
+	leader.publish(tree)
+	leader.publish(tree)
+	if status := leader.Status(); status.Epoch != "first" || status.Sequence != 2 {
+		t.Fatalf("status = %+v", status)
+	}
+	leader.epoch = "second"
+	leader.publish(tree)
+	if status := leader.Status(); status.Epoch != "second" || status.Sequence != 1 {
+		t.Fatalf("status after a new term = %+v", status)
+	}
+
+	leader.status.Role = SyncLeader
+	if snap, _ := leader.next("first", 2); snap == nil || snap.Epoch != "second" {
+		t.Errorf("a stream from the first term is not sent the new snapshot: %+v", snap)
+	}
+	if snap, _ := leader.next("second", 1); snap != nil {
+		t.Errorf("a stream that has the snapshot is sent it again: %+v", snap)
+	}
+}
@@ -0,0 +1,252 @@
+package configuration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// TenantLoader fetches a tenant's overrides the first time it is requested
+type TenantLoader func(ctx context.Context, tenantID string) (map[string]interface{}, error)
+
+// TenantFunc receives a tenant's configuration after it changed
+type TenantFunc func(tenantID string, config *Config)
+
+// tenantState is the resolved configuration of one tenant
+type tenantState struct {
+	overrides map[string]interface{}
+	values    map[string]interface{}
+	config    *Config
+}
+
+// tenants holds tenant overrides layered over the global configuration
+type tenants struct {
+	mu       sync.RWMutex
+	states   map[string]*tenantState
+	loader   TenantLoader
+	next     int
+	subs     map[int]tenantSub
+	watching bool
+}
+
+// tenantSub is a change subscription for one tenant ("" for all)
+type tenantSub struct {
+	tenant string
+	fn     TenantFunc
+}
+
+// tenantKey is the context key carrying a tenant ID
+type tenantKey struct{}
+
+// WithTenant returns a context carrying tenantID
+func WithTenant(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenantID)
+}
+
+// TenantFromContext returns the tenant ID carried by ctx
+func TenantFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(tenantKey{}).(string)
+	return id, ok && id != ""
+}
+
+// SetTenantLoader installs the loader used for tenants without overrides
+func (m *Manager) SetTenantLoader(loader TenantLoader) {
+	m.tenants.mu.Lock()
+	defer m.tenants.mu.Unlock()
+	m.tenants.loader = loader
+}
+
+// SetTenant replaces a tenant's overrides; the tenant inherits every key it
+// does not override from the global configuration. An override that does
+// not decode is rejected without affecting other tenants
+func (m *Manager) SetTenant(tenantID string, overrides map[string]interface{}) error {
+	if tenantID == "" {
+		return errors.New("tenant ID must not be empty")
+	}
+	return m.replaceTenant(tenantID, overrides, nil)
+}
+
+// replaceTenant resolves and stores a tenant; when expected is set the
+// tenant is only replaced if it is still in that state
+func (m *Manager) replaceTenant(tenantID string, overrides map[string]interface{}, expected *tenantState) error {
+	state, err := m.resolveTenant(overrides)
+	if err != nil {
+		return fmt.Errorf("tenant %s: %w", tenantID, err)
+	}
+
+	t := &m.tenants
+	t.mu.Lock()
+	if t.states == nil {
+		t.states = make(map[string]*tenantState)
+	}
+	prev := t.states[tenantID]
+	if expected != nil && prev != expected {
+		t.mu.Unlock()
+		return nil
+	}
+	t.states[tenantID] = state
+	m.watchTenants()
+	t.mu.Unlock()
+
+	if prev == nil || !reflect.DeepEqual(prev.values, state.values) {
+		m.notifyTenant(tenantID, state.config)
+	}
+	return nil
+}
+
+// RemoveTenant drops a tenant's overrides
+func (m *Manager) RemoveTenant(tenantID string) {
+	m.tenants.mu.Lock()
+	defer m.tenants.mu.Unlock()
+	delete(m.tenants.states, tenantID)
+}
+
+// Tenants lists the tenants with overrides
+func (m *Manager) Tenants() []string {
+	m.tenants.mu.RLock()
+	defer m.tenants.mu.RUnlock()
+	ids := make([]string, 0, len(m.tenants.states))
+	for id := range m.tenants.states {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// GetForTenant returns a copy of the configuration of tenantID, or of the
+// tenant carried by ctx when tenantID is empty; tenants without overrides
+// are loaded through the tenant loader or get the global configuration
+func (m *Manager) GetForTenant(ctx context.Context, tenantID string) (*Config, error) {
+	state, err := m.tenantState(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if state == nil {
+		return m.GetConfig(), nil
+	}
+	config := *state.config
+	return &config, nil
+}
+
+// TenantValues returns a copy of the effective tree of a tenant
+func (m *Manager) TenantValues(ctx context.Context, tenantID string) (map[string]interface{}, error) {
+	state, err := m.tenantState(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	if state == nil {
+		m.mu.RLock()
+		defer m.mu.RUnlock()
+		return copyTree(m.values), nil
+	}
+	return copyTree(state.values), nil
+}
+
+// OnTenantChange calls fn whenever the configuration of tenantID changes,
+// whether through its overrides or inherited global keys; an empty
+// tenantID subscribes to every tenant. It returns a function that
+// unsubscribes
+func (m *Manager) OnTenantChange(tenantID string, fn TenantFunc) func() {
+	t := &m.tenants
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.subs == nil {
+		t.subs = make(map[int]tenantSub)
+	}
+	id := t.next
+	t.next++
+	t.subs[id] = tenantSub{tenant: tenantID, fn: fn}
+	return func() {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		delete(t.subs, id)
+	}
+}
+
+// tenantState returns the state of a tenant, loading it on first use; nil
+// means the tenant inherits the global configuration unchanged
+func (m *Manager) tenantState(ctx context.Context, tenantID string) (*tenantState, error) {
+	if tenantID == "" {
+		var ok bool
+		if tenantID, ok = TenantFromContext(ctx); !ok {
+			return nil, errors.New("no tenant ID given or in context")
+		}
+	}
+
+	m.tenants.mu.RLock()
+	state, ok := m.tenants.states[tenantID]
+	loader := m.tenants.loader
+	m.tenants.mu.RUnlock()
+	if ok || loader == nil {
+		return state, nil
+	}
+
+	overrides, err := loader(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("load tenant %s: %w", tenantID, err)
+	}
+	if err := m.SetTenant(tenantID, overrides); err != nil {
+		return nil, err
+	}
+	m.tenants.mu.RLock()
+	defer m.tenants.mu.RUnlock()
+	return m.tenants.states[tenantID], nil
+}
+
+// resolveTenant merges overrides over the global layers and decodes them
+func (m *Manager) resolveTenant(overrides map[string]interface{}) (*tenantState, error) {
+	config, values, err := m.decodeEffective(mergeTrees(m.layers.Effective(), overrides))
+	if err != nil {
+		return nil, err
+	}
+	return &tenantState{overrides: copyTree(overrides), values: values, config: config}, nil
+}
+
+// watchTenants re-resolves every tenant after global changes; callers hold
+// the tenants lock
+func (m *Manager) watchTenants() {
+	if m.tenants.watching {
+		return
+	}
+	m.tenants.watching = true
+	m.events.Subscribe("", func([]ChangeEvent) {
+		m.tenants.mu.RLock()
+		ids := make([]string, 0, len(m.tenants.states))
+		for id := range m.tenants.states {
+			ids = append(ids, id)
+		}
+		m.tenants.mu.RUnlock()
+
+		for _, id := range ids {
+			m.tenants.mu.RLock()
+			prev, ok := m.tenants.states[id]
+			m.tenants.mu.RUnlock()
+			if !ok {
+				continue
+			}
+			if err := m.replaceTenant(id, prev.overrides, prev); err != nil {
+				m.logger.Printf("Keeping previous configuration for %v", err)
+			}
+		}
+	})
+}
+
+// notifyTenant calls the subscribers of a tenant
+func (m *Manager) notifyTenant(tenantID string, config *Config) {
+	m.tenants.mu.RLock()
+	var fns []TenantFunc
+	for id := 0; id < m.tenants.next; id++ {
+		if sub, ok := m.tenants.subs[id]; ok && (sub.tenant == "" || sub.tenant == tenantID) {
+			fns = append(fns, sub.fn)
+		}
+	}
+	m.tenants.mu.RUnlock()
+
+	for _, fn := range fns {
+		copied := *config
+		fn(tenantID, &copied)
+	}
+}
@@ -0,0 +1,191 @@
+package configuration
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// setPath stores value under a dotted key, creating intermediate maps
+func setPath(tree map[string]interface{}, key string, value interface{}) {
+	parts := strings.Split(key, ".")
+	node := tree
+	for _, part := range parts[:len(parts)-1] {
+		child, ok := node[part].(map[string]interface{})
+		if !ok {
+			child = make(map[string]interface{})
+			node[part] = child
+		}
+		node = child
+	}
+	node[parts[len(parts)-1]] = value
+}
+
+// flatten returns the leaf values of tree keyed by dotted path; lists are leaves
+func flatten(tree map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{})
+	flattenInto(out, "", tree)
+	return out
+}
+
+// flattenInto walks node, storing leaves under prefix
+func flattenInto(out map[string]interface{}, prefix string, node map[string]interface{}) {
+	for k, v := range node {
+		key := joinKey(prefix, k)
+		if child, ok := v.(map[string]interface{}); ok && len(child) > 0 {
+			flattenInto(out, key, child)
+			continue
+		}
+		out[key] = v
+	}
+}
+
+// unflatten rebuilds a tree from dotted leaf keys
+func unflatten(flat map[string]interface{}) map[string]interface{} {
+	tree := make(map[string]interface{})
+	for _, key := range sortedKeys(flat) {
+		setPath(tree, key, flat[key])
+	}
+	return tree
+}
+
+// lookup returns the value stored under a dotted key
+func lookup(tree map[string]interface{}, key string) (interface{}, bool) {
+	var node interface{} = tree
+	for _, part := range strings.Split(key, ".") {
+		m, ok := node.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if node, ok = m[part]; !ok {
+			return nil, false
+		}
+	}
+	return node, true
+}
+
+// deepCopy copies maps and slices so the result shares no mutable state
+func deepCopy(v interface{}) interface{} {
+	switch t := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(t))
+		for k, inner := range t {
+			out[k] = deepCopy(inner)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(t))
+		for i, inner := range t {
+			out[i] = deepCopy(inner)
+		}
+		return out
+	default:
+		return v
+	}
+}
+
+// copyTree deep-copies a key tree
+func copyTree(tree map[string]interface{}) map[string]interface{} {
+	if tree == nil {
+		return make(map[string]interface{})
+	}
+	return deepCopy(tree).(map[string]interface{})
+}
+
+// sortedKeys returns the keys of m in lexical order
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// structTree converts a struct into a key tree using its json keys, rendering
+// durations in their string form
+func structTree(v interface{}) map[string]interface{} {
+	tree, _ := toTreeValue(reflect.ValueOf(v)).(map[string]interface{})
+	if tree == nil {
+		tree = make(map[string]interface{})
+	}
+	return tree
+}
+
+// toTreeValue converts a reflected value into generic tree form
+func toTreeValue(rv reflect.Value) interface{} {
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Type() == durationType {
+		return FormatDuration(time.Duration(rv.Int()))
+	}
+	if m, ok := rv.Interface().(encoding.TextMarshaler); ok {
+		if text, err := m.MarshalText(); err == nil {
+			return string(text)
+		}
+	}
+
+	switch rv.Kind() {
+	case reflect.Struct:
+		if rv.Type() == reflect.TypeOf(time.Time{}) {
+			return rv.Interface()
+		}
+		out := make(map[string]interface{})
+		t := rv.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.Anonymous && f.Tag.Get("json") == "" {
+				if inner, ok := toTreeValue(rv.Field(i)).(map[string]interface{}); ok {
+					for k, v := range inner {
+						out[k] = v
+					}
+				}
+				continue
+			}
+			if key := fieldKey(f); key != "" && f.PkgPath == "" {
+				out[key] = toTreeValue(rv.Field(i))
+			}
+		}
+		return out
+	case reflect.Map:
+		out := make(map[string]interface{}, rv.Len())
+		iter := rv.MapRange()
+		for iter.Next() {
+			out[fmt.Sprint(iter.Key().Interface())] = toTreeValue(iter.Value())
+		}
+		return out
+	case reflect.Slice, reflect.Array:
+		if rv.Kind() == reflect.Slice && rv.IsNil() {
+			return nil
+		}
+		out := make([]interface{}, rv.Len())
+		for i := range out {
+			out[i] = toTreeValue(rv.Index(i))
+		}
+		return out
+	default:
+		return rv.Interface()
+	}
+}
+
+// mergeTrees deep-merges src over dst, returning a new tree
+func mergeTrees(dst, src map[string]interface{}) map[string]interface{} {
+	out := copyTree(dst)
+	for k, v := range src {
+		if sm, ok := v.(map[string]interface{}); ok {
+			if dm, ok := out[k].(map[string]interface{}); ok {
+				out[k] = mergeTrees(dm, sm)
+				continue
+			}
+		}
+		out[k] = deepCopy(v)
+	}
+	return out
+}
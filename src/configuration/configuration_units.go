@@ -0,0 +1,260 @@
+package configuration
+
+import (
+	"encoding"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// textUnmarshalerType is used to pass numbers to TextUnmarshaler fields as text
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// ParseDuration parses durations such as "5m", "1.5h" or "1d12h"; on top of
+// time.ParseDuration it accepts "d" (24h) and "w" (7d) units
+func ParseDuration(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if !strings.ContainsAny(s, "dw") {
+		return time.ParseDuration(s)
+	}
+
+	orig := s
+	sign := time.Duration(1)
+	if strings.HasPrefix(s, "-") {
+		sign, s = -1, s[1:]
+	}
+	var total time.Duration
+	for s != "" {
+		i := strings.IndexAny(s, "dw")
+		if i < 0 {
+			rest, err := time.ParseDuration(s)
+			if err != nil {
+				return 0, fmt.Errorf("invalid duration %q", orig)
+			}
+			total += rest
+			break
+		}
+		// Days and weeks must precede the smaller units
+		value, err := strconv.ParseFloat(s[:i], 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", orig)
+		}
+		unit := 24 * time.Hour
+		if s[i] == 'w' {
+			unit *= 7
+		}
+		total += time.Duration(value * float64(unit))
+		s = s[i+1:]
+	}
+	return sign * total, nil
+}
+
+// FormatDuration renders d compactly using whole d, h, m and s units, e.g.
+// "1d12h" or "5m"; durations with sub-second parts use time.Duration.String
+func FormatDuration(d time.Duration) string {
+	if d == 0 {
+		return "0s"
+	}
+	if d%time.Second != 0 {
+		return d.String()
+	}
+	var b strings.Builder
+	if d < 0 {
+		b.WriteByte('-')
+		d = -d
+	}
+	for _, unit := range []struct {
+		suffix string
+		size   time.Duration
+	}{{"d", 24 * time.Hour}, {"h", time.Hour}, {"m", time.Minute}, {"s", time.Second}} {
+		if n := d / unit.size; n > 0 {
+			fmt.Fprintf(&b, "%d%s", n, unit.suffix)
+			d -= n * unit.size
+		}
+	}
+	return b.String()
+}
+
+// ByteSize is a count of bytes written with SI ("10MB") or IEC ("2GiB")
+// units; a parsed size keeps the unit it was written in
+type ByteSize struct {
+	n int64
+	// unit is the suffix the size was parsed with, "" for a bare number
+	unit   string
+	parsed bool
+}
+
+// NewByteSize returns a size of n bytes, rendered in the largest unit
+// that fits
+func NewByteSize(n int64) ByteSize {
+	return ByteSize{n: n}
+}
+
+// Bytes returns the number of bytes
+func (b ByteSize) Bytes() int64 {
+	return b.n
+}
+
+// byteUnits lists recognised suffixes from largest to smallest; "kB" is
+// accepted when parsing but never chosen for NewByteSize values
+var byteUnits = []struct {
+	suffix string
+	size   int64
+}{
+	{"TiB", 1 << 40}, {"TB", 1e12}, {"GiB", 1 << 30}, {"GB", 1e9},
+	{"MiB", 1 << 20}, {"MB", 1e6}, {"KiB", 1 << 10}, {"KB", 1e3}, {"kB", 1e3},
+	{"B", 1},
+}
+
+// ParseByteSize parses sizes such as "512", "10MB", "1.5GiB" or "64 KiB"
+func ParseByteSize(s string) (ByteSize, error) {
+	s = strings.TrimSpace(s)
+	for _, unit := range byteUnits {
+		if !strings.HasSuffix(s, unit.suffix) {
+			continue
+		}
+		number := strings.TrimSpace(strings.TrimSuffix(s, unit.suffix))
+		value, err := strconv.ParseFloat(number, 64)
+		if err != nil || value < 0 {
+			return ByteSize{}, fmt.Errorf("invalid byte size %q", s)
+		}
+		n := int64(math.Round(value * float64(unit.size)))
+		return ByteSize{n: n, unit: unit.suffix, parsed: true}, nil
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 {
+		return ByteSize{}, fmt.Errorf("invalid byte size %q", s)
+	}
+	return ByteSize{n: n, parsed: true}, nil
+}
+
+// String renders a parsed size in the unit it was written in, so "1536KiB"
+// and "1000MB" serialize back unchanged; other sizes use the largest unit
+// that expresses them with at most two decimals
+func (b ByteSize) String() string {
+	if b.parsed {
+		if b.unit == "" {
+			return strconv.FormatInt(b.n, 10)
+		}
+		for _, unit := range byteUnits {
+			if unit.suffix == b.unit {
+				value := float64(b.n) / float64(unit.size)
+				return strconv.FormatFloat(value, 'f', -1, 64) + unit.suffix
+			}
+		}
+	}
+	for _, unit := range byteUnits {
+		if unit.size == 1 || unit.suffix == "kB" {
+			continue
+		}
+		if b.n >= unit.size && b.n*100%unit.size == 0 {
+			value := float64(b.n) / float64(unit.size)
+			return strconv.FormatFloat(value, 'f', -1, 64) + unit.suffix
+		}
+	}
+	return fmt.Sprintf("%dB", b.n)
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (b ByteSize) MarshalText() ([]byte, error) {
+	return []byte(b.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (b *ByteSize) UnmarshalText(text []byte) error {
+	n, err := ParseByteSize(string(text))
+	if err != nil {
+		return err
+	}
+	*b = n
+	return nil
+}
+
+// Percent is a fraction written as a percentage, e.g. "25%" is 0.25; plain
+// numbers are read as fractions
+type Percent float64
+
+// ParsePercent parses "25%", "12.5 %" or a fraction such as "0.25"
+func ParsePercent(s string) (Percent, error) {
+	s = strings.TrimSpace(s)
+	scale := 1.0
+	if strings.HasSuffix(s, "%") {
+		s, scale = strings.TrimSpace(strings.TrimSuffix(s, "%")), 100
+	}
+	value, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid percentage %q", s)
+	}
+	return Percent(value / scale), nil
+}
+
+// String renders the fraction as a percentage
+func (p Percent) String() string {
+	return strconv.FormatFloat(float64(p)*100, 'f', -1, 64) + "%"
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (p Percent) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler
+func (p *Percent) UnmarshalText(text []byte) error {
+	v, err := ParsePercent(string(text))
+	if err != nil {
+		return err
+	}
+	*p = v
+	return nil
+}
+
+// RequireByteSize returns the value under key as a byte count
+func (m *Manager) RequireByteSize(key string) (ByteSize, error) {
+	value, err := m.require(key)
+	if err != nil {
+		return ByteSize{}, err
+	}
+	s, err := toString(value)
+	if err == nil {
+		var size ByteSize
+		if size, err = ParseByteSize(s); err == nil {
+			return size, nil
+		}
+	}
+	return ByteSize{}, fmt.Errorf("%s: %w", key, err)
+}
+
+// GetByteSize returns the value under key as a byte count, or def when missing or unconvertible
+func (m *Manager) GetByteSize(key string, def ByteSize) ByteSize {
+	if b, err := m.RequireByteSize(key); err == nil {
+		return b
+	}
+	return def
+}
+
+// RequirePercent returns the value under key as a fraction
+func (m *Manager) RequirePercent(key string) (Percent, error) {
+	value, err := m.require(key)
+	if err != nil {
+		return 0, err
+	}
+	s, err := toString(value)
+	if err == nil {
+		var p Percent
+		if p, err = ParsePercent(s); err == nil {
+			return p, nil
+		}
+	}
+	return 0, fmt.Errorf("%s: %w", key, err)
+}
+
+// GetPercent returns the value under key as a fraction, or def when missing or unconvertible
+func (m *Manager) GetPercent(key string, def Percent) Percent {
+	if p, err := m.RequirePercent(key); err == nil {
+		return p
+	}
+	return def
+}
@@ -0,0 +1,54 @@
+package configuration
+
+import "testing"
+
+func TestByteSizeKeepsParsedUnit(t *testing.T) {
+	for _, tc := range []struct {
+		text  string
+		bytes int64
+		want  string
+	}{
+		{"1536KiB", 1536 << 10, "1536KiB"},
+		{"1000MB", 1e9, "1000MB"},
+		{"2GiB", 2 << 30, "2GiB"},
+		{"1.5GiB", 3 << 29, "1.5GiB"},
+		{"64 kB", 64e3, "64kB"},
+		{"512", 512, "512"},
+		{"1048576", 1 << 20, "1048576"},
+	} {
+		size, err := ParseByteSize(tc.text)
+		if err != nil {
+			t.Fatalf("ParseByteSize(%q) = %v", tc.text, err)
+		}
+		if size.Bytes() != tc.bytes || size.String() != tc.want {
+			t.Errorf("ParseByteSize(%q) = %d bytes as %q, want %d as %q", tc.text, size.Bytes(), size, tc.bytes, tc.want)
+		}
+	}
+}
+
+func TestByteSizeSurvivesTheTree(t *testing.T) {
+	type limits struct {
+		Upload ByteSize `json:"upload"`
+		Cache  ByteSize `json:"cache"`
+		Body   ByteSize `json:"body"`
+	}
+	var decoded limits
+	tree := map[string]interface{}{"upload": "1536KiB", "cache": "1000MB", "body": 4096}
+	if err := Decode(tree, &decoded, true); err != nil {
+		t.Fatal(err)
+	}
+	got := structTree(decoded)
+	for key, want := range map[string]string{"upload": "1536KiB", "cache": "1000MB", "body": "4096"} {
+		if got[key] != want {
+			t.Errorf("%s = %v, want %q", key, got[key], want)
+		}
+	}
+}
+
+func TestNewByteSize(t *testing.T) {
+	for n, want := range map[int64]string{0: "0B", 100: "100B", 1536 << 10: "1.5MiB", 1e9: "1GB", 2500: "2.5KB"} {
+		if got := NewByteSize(n).String(); got != want {
+			t.Errorf("NewByteSize(%d) = %q, want %q", n, got, want)
+		}
+	}
+}
@@ -0,0 +1,191 @@
+package configuration
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/nerufuyo/roastume/src/logging"
+	"github.com/nerufuyo/roastume/src/validation"
+)
+
+// sectionSchema is the type registered for a configuration section
+type sectionSchema struct {
+	section  string
+	typ      reflect.Type
+	defaults map[string]interface{}
+}
+
+// RegisterSchema declares the type of the section under key, typically the
+// Config of another manager bound with BindSection; Verify decodes the
+// section strictly into it and calls its Validate() error method if present.
+// Fields tagged `sensitive:"full|last4|hash"` are marked sensitive; a tag
+// with an unknown strategy is logged and masks the whole section
+func (m *Manager) RegisterSchema(key string, prototype interface{}) {
+	typ := indirectType(reflect.TypeOf(prototype))
+	rules, err := sensitiveFields(typ, key)
+	if err != nil {
+		m.logger.Printf("Schema %s: %v", key, err)
+		rules = []sensitiveRule{{pattern: key, strategy: MaskFull}}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.schemas = append(m.schemas, sectionSchema{section: key, typ: typ, defaults: structTree(prototype)})
+	m.sensitive = append(m.sensitive, rules...)
+}
+
+// Validate checks invariants of the manager's own settings
+func (c *Config) Validate() error {
+	var problems []string
+	if c.Timeout < 0 {
+		problems = append(problems, "timeout must not be negative")
+	}
+	if c.Retries < 0 {
+		problems = append(problems, "retries must not be negative")
+	}
+	if c.RetryMaxElapsed < 0 {
+		problems = append(problems, "retry_max_elapsed must not be negative")
+	}
+	if c.MaxConcurrency < 0 {
+		problems = append(problems, "max_concurrency must not be negative")
+	}
+	if c.HistoryLimit < 0 {
+		problems = append(problems, "history_limit must not be negative")
+	}
+	if _, err := logging.ParseSpec(c.LogLevel); err != nil {
+		problems = append(problems, "log_level: "+err.Error())
+	}
+	if len(problems) > 0 {
+		return errors.New(strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// VerifyReport is the outcome of a dry-run load
+type VerifyReport struct {
+	Path         string                 `json:"path"`
+	Effective    map[string]interface{} `json:"effective"`
+	Origins      []Origin               `json:"origins"`
+	Deprecations []DeprecationWarning   `json:"deprecations,omitempty"`
+	Errors       []string               `json:"errors,omitempty"`
+}
+
+// OK reports whether verification found no errors
+func (r *VerifyReport) OK() bool {
+	return len(r.Errors) == 0
+}
+
+// Verify loads path as the file layer on top of the manager's other layers
+// without applying it, and checks the merged result strictly against Config
+// and the registered section schemas; the returned error is only set when
+// the file cannot be read or parsed
+func (m *Manager) Verify(path string, opts ...LoadOption) (*VerifyReport, error) {
+	tree, err := ReadTree(path, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.RLock()
+	migrations := m.migrations
+	schemas := append([]sectionSchema(nil), m.schemas...)
+	m.mu.RUnlock()
+
+	report := &VerifyReport{Path: path}
+	tree, report.Deprecations = applyMigrations(migrations, LayerFile, path, tree)
+	for _, w := range report.Deprecations {
+		if !w.Migrated {
+			report.Errors = append(report.Errors, fmt.Sprintf("deprecated key %s not migrated: %s", w.Key, w.Reason))
+		}
+	}
+
+	scratch := m.layers.clone()
+	scratch.Set(LayerFile, path, tree)
+	values := scratch.Effective()
+	if config, resolved, err := m.decodeEffective(values); err != nil {
+		report.Errors = append(report.Errors, err.Error())
+	} else {
+		values = resolved
+		if err := config.Validate(); err != nil {
+			report.Errors = append(report.Errors, err.Error())
+		}
+		var violations validation.ValidationErrors
+		if err := m.checkRules(config, values); errors.As(err, &violations) {
+			for _, v := range violations {
+				report.Errors = append(report.Errors, v.String())
+			}
+		} else if err != nil {
+			report.Errors = append(report.Errors, err.Error())
+		}
+	}
+
+	root := copyTree(values)
+	for _, schema := range schemas {
+		deletePath(root, schema.section)
+	}
+	if err := Decode(root, &Config{}, true); err != nil {
+		report.Errors = append(report.Errors, err.Error())
+	}
+
+	for _, schema := range schemas {
+		if err := checkSection(values, schema); err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("%s: %v", schema.section, err))
+		}
+	}
+
+	report.Effective = m.redactTree(values)
+	origins := scratch.Origins()
+	for _, key := range sortedOriginKeys(origins) {
+		origin := origins[key]
+		origin.Value, _ = lookup(report.Effective, key)
+		origin.Shadowed = nil
+		report.Origins = append(report.Origins, origin)
+	}
+	return report, nil
+}
+
+// checkSection strictly decodes and validates one registered section
+func checkSection(values map[string]interface{}, schema sectionSchema) error {
+	raw, ok := lookup(values, schema.section)
+	if !ok {
+		return nil
+	}
+	section, ok := raw.(map[string]interface{})
+	if !ok {
+		return errors.New("not a section")
+	}
+	target := reflect.New(schema.typ).Interface()
+	if err := Decode(section, target, true); err != nil {
+		return err
+	}
+	if v, ok := target.(interface{ Validate() error }); ok {
+		return v.Validate()
+	}
+	return nil
+}
+
+// deletePath removes the value under a dotted key, if present
+func deletePath(tree map[string]interface{}, key string) {
+	parts := strings.Split(key, ".")
+	node := tree
+	for _, part := range parts[:len(parts)-1] {
+		child, ok := node[part].(map[string]interface{})
+		if !ok {
+			return
+		}
+		node = child
+	}
+	delete(node, parts[len(parts)-1])
+}
+
+// sortedOriginKeys returns the keys of origins in sorted order
+func sortedOriginKeys(origins map[string]Origin) []string {
+	keys := make([]string, 0, len(origins))
+	for k := range origins {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
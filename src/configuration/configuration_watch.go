@@ -0,0 +1,300 @@
+package configuration
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// ChangeFunc is called after the active configuration is replaced
+type ChangeFunc func(old, new *Config)
+
+// ConfigChange is delivered to channel subscribers when the configuration
+// changes; Changes lists the differences between Old and New
+type ConfigChange struct {
+	Old     *Config
+	New     *Config
+	Changes []Change
+	At      time.Time
+}
+
+// subscriptions holds change callbacks keyed by registration ID
+type subscriptions struct {
+	mu     sync.Mutex
+	nextID int
+	funcs  map[int]ChangeFunc
+}
+
+// add registers fn and returns a function removing it
+func (s *subscriptions) add(fn ChangeFunc) func() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.funcs == nil {
+		s.funcs = make(map[int]ChangeFunc)
+	}
+	id := s.nextID
+	s.nextID++
+	s.funcs[id] = fn
+	return func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		delete(s.funcs, id)
+	}
+}
+
+// snapshot returns the registered callbacks in registration order
+func (s *subscriptions) snapshot() []ChangeFunc {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	funcs := make([]ChangeFunc, 0, len(s.funcs))
+	for id := 0; id < s.nextID; id++ {
+		if fn, ok := s.funcs[id]; ok {
+			funcs = append(funcs, fn)
+		}
+	}
+	return funcs
+}
+
+// OnChange registers fn to be called after every configuration swap and
+// returns a function that unregisters it; callbacks run in version order
+// once the write has released its lock, so fn may change the configuration
+// itself, and a change made from fn is delivered after fn returns
+func (m *Manager) OnChange(fn ChangeFunc) func() {
+	return m.subscribers.add(fn)
+}
+
+// Subscribe returns a channel receiving configuration changes and a function
+// that unsubscribes and closes it; changes are dropped when the buffer is full
+func (m *Manager) Subscribe(buffer int) (<-chan ConfigChange, func()) {
+	ch := make(chan ConfigChange, buffer)
+	var once sync.Once
+	var mu sync.Mutex
+	closed := false
+
+	remove := m.OnChange(func(old, new *Config) {
+		mu.Lock()
+		defer mu.Unlock()
+		if closed {
+			return
+		}
+		select {
+		case ch <- ConfigChange{Old: old, New: new, Changes: Diff(old, new), At: time.Now()}:
+		default:
+			m.logger.Printf("Dropped configuration change for slow subscriber")
+		}
+	})
+	return ch, func() {
+		once.Do(func() {
+			remove()
+			mu.Lock()
+			closed = true
+			close(ch)
+			mu.Unlock()
+		})
+	}
+}
+
+// notice is an applied configuration swap whose subscribers and event bus
+// have not been told yet
+type notice struct {
+	old, new *Config
+	changes  []Change
+	version  int
+}
+
+// notices queues swaps in the order they were applied; whichever writer
+// finds the queue idle delivers until it is empty, so callbacks run without
+// writeMu and may change the configuration themselves
+type notices struct {
+	mu         sync.Mutex
+	queue      []notice
+	delivering bool
+}
+
+// push queues n; callers hold writeMu so the queue follows version order
+func (q *notices) push(n notice) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.queue = append(q.queue, n)
+}
+
+// next returns the next queued swap, or false once the queue is drained
+func (q *notices) next() (notice, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if len(q.queue) == 0 {
+		q.delivering = false
+		return notice{}, false
+	}
+	n := q.queue[0]
+	q.queue = q.queue[1:]
+	return n, true
+}
+
+// claim makes the caller the deliverer unless another one is active
+func (q *notices) claim() bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.delivering {
+		return false
+	}
+	q.delivering = true
+	return true
+}
+
+// deliverChanges publishes queued swaps and runs the change callbacks; call
+// it after releasing writeMu. A call made while another delivery is running,
+// including one from inside a callback, leaves its swaps to that delivery
+func (m *Manager) deliverChanges() {
+	if !m.notices.claim() {
+		return
+	}
+	for {
+		n, ok := m.notices.next()
+		if !ok {
+			return
+		}
+		m.publishChanges(n.changes, n.version)
+		for _, fn := range m.subscribers.snapshot() {
+			fn(n.old, n.new)
+		}
+	}
+}
+
+// applyConfig atomically replaces the active configuration and resolved
+// key tree and queues the change for deliverChanges; keys resolved from
+// secret references are marked sensitive first so the change log masks them
+func (m *Manager) applyConfig(config *Config, values map[string]interface{}) {
+	m.mu.RLock()
+	secrets := m.secrets
+	m.mu.RUnlock()
+	var secretKeys []string
+	if secrets != nil {
+		secretKeys = secrets.referenceKeys(m.layers.Effective())
+	}
+
+	m.mu.Lock()
+	m.markSecretKeys(secretKeys)
+	old, oldValues := m.config, m.values
+	m.config = config
+	m.values = values
+	m.mu.Unlock()
+	snap := m.history.record(m.layers.snapshotLayers(), values, config.HistoryLimit)
+	changes := diffTrees(oldValues, values)
+	for _, c := range m.RedactChanges(changes) {
+		m.logger.Printf("Configuration version %d: %s", snap.Version, c)
+	}
+	m.applyLogLevel(old, config)
+	m.notices.push(notice{old: old, new: config, changes: changes, version: snap.Version})
+}
+
+// Watcher reloads a configuration file whenever it changes on disk
+type Watcher struct {
+	manager  *Manager
+	path     string
+	opts     []LoadOption
+	debounce time.Duration
+	fs       *fsnotify.Watcher
+	errors   chan error
+	done     chan struct{}
+	wg       sync.WaitGroup
+}
+
+// Watch loads path and keeps reloading it on change until ctx is cancelled
+// or the returned Watcher is closed; reload errors keep the previous
+// configuration active and are reported on Errors
+func (m *Manager) Watch(ctx context.Context, path string, opts ...LoadOption) (*Watcher, error) {
+	if err := m.LoadFile(path, opts...); err != nil {
+		return nil, err
+	}
+
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("create file watcher: %w", err)
+	}
+	// Watch the directory so editors that replace the file via rename are seen
+	if err := fsw.Add(filepath.Dir(path)); err != nil {
+		fsw.Close()
+		return nil, fmt.Errorf("watch %s: %w", path, err)
+	}
+
+	w := &Watcher{
+		manager:  m,
+		path:     filepath.Clean(path),
+		opts:     opts,
+		debounce: 100 * time.Millisecond,
+		fs:       fsw,
+		errors:   make(chan error, 1),
+		done:     make(chan struct{}),
+	}
+	w.wg.Add(1)
+	go w.run(ctx)
+	return w, nil
+}
+
+// Errors returns reload failures; older errors are dropped if not consumed
+func (w *Watcher) Errors() <-chan error {
+	return w.errors
+}
+
+// Close stops watching
+func (w *Watcher) Close() error {
+	select {
+	case <-w.done:
+	default:
+		close(w.done)
+	}
+	w.wg.Wait()
+	return w.fs.Close()
+}
+
+// run processes file system events, coalescing bursts into one reload
+func (w *Watcher) run(ctx context.Context) {
+	defer w.wg.Done()
+
+	var timer *time.Timer
+	var fire <-chan time.Time
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-w.done:
+			return
+		case event, ok := <-w.fs.Events:
+			if !ok {
+				return
+			}
+			if filepath.Clean(event.Name) != w.path || !event.Has(fsnotify.Write|fsnotify.Create|fsnotify.Rename) {
+				continue
+			}
+			if timer == nil {
+				timer = time.NewTimer(w.debounce)
+			} else {
+				timer.Reset(w.debounce)
+			}
+			fire = timer.C
+		case err, ok := <-w.fs.Errors:
+			if !ok {
+				return
+			}
+			w.report(err)
+		case <-fire:
+			fire = nil
+			if err := w.manager.LoadFile(w.path, w.opts...); err != nil {
+				w.report(err)
+			}
+		}
+	}
+}
+
+// report delivers an error without blocking the watch loop
+func (w *Watcher) report(err error) {
+	select {
+	case w.errors <- err:
+	default:
+	}
+}
@@ -0,0 +1,80 @@
+package configuration
+
+import (
+	"testing"
+	"time"
+)
+
+func TestChangeCallbacksMayWrite(t *testing.T) {
+	m := NewManager(nil)
+	var seen []interface{}
+	m.OnChange(func(old, new *Config) {
+		value, _ := m.Get("step")
+		seen = append(seen, value)
+		if value == 1 {
+			if err := m.SetLayer(LayerRuntime, "callback", map[string]interface{}{"step": 2}); err != nil {
+				t.Error(err)
+			}
+		}
+	})
+
+	done := make(chan error, 1)
+	go func() {
+		done <- m.SetLayer(LayerRuntime, "test", map[string]interface{}{"step": 1})
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("SetLayer from a change callback deadlocked")
+	}
+	if len(seen) != 2 || seen[1] != 2 {
+		t.Errorf("callbacks saw %v, want the callback's own change delivered after it", seen)
+	}
+}
+
+func TestSlowSubscriberDoesNotBlockWriters(t *testing.T) {
+	m := NewManager(nil)
+	entered, release, delivered := make(chan struct{}), make(chan struct{}), make(chan struct{})
+	var versions []int
+	m.OnChange(func(old, new *Config) {
+		versions = append(versions, m.Version())
+		switch len(versions) {
+		case 1:
+			close(entered)
+			<-release
+		case 2:
+			close(delivered)
+		}
+	})
+
+	go m.SetLayer(LayerRuntime, "slow", map[string]interface{}{"step": 1})
+	<-entered
+	done := make(chan error, 1)
+	go func() {
+		done <- m.SetLayer(LayerRuntime, "test", map[string]interface{}{"step": 2})
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("a writer waited for a slow subscriber")
+	}
+	if value, _ := m.Get("step"); value != 2 {
+		t.Errorf("step = %v, want 2", value)
+	}
+	close(release)
+
+	select {
+	case <-delivered:
+	case <-time.After(2 * time.Second):
+		t.Fatal("the second change was never delivered")
+	}
+	if versions[0] >= versions[1] {
+		t.Errorf("subscriber saw versions %v, want two in order", versions)
+	}
+}
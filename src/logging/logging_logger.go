@@ -0,0 +1,282 @@
+// Package logging provides the leveled loggers shared by the managers. A
+// process-wide level, with optional per-package overrides, decides which
+// messages are written and can be changed at runtime, for example when the
+// configuration manager reloads its log_level setting.
+package logging
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// Level is the severity of a log message
+type Level int32
+
+const (
+	// LevelDebug is for detailed diagnostics
+	LevelDebug Level = iota
+	// LevelInfo is for routine operational messages
+	LevelInfo
+	// LevelWarn is for unexpected but recoverable conditions
+	LevelWarn
+	// LevelError is for failed operations
+	LevelError
+)
+
+// levelInherit marks a logger without a level of its own
+const levelInherit Level = -1
+
+// String returns the canonical upper-case name of the level
+func (l Level) String() string {
+	switch l {
+	case LevelDebug:
+		return "DEBUG"
+	case LevelInfo:
+		return "INFO"
+	case LevelWarn:
+		return "WARN"
+	case LevelError:
+		return "ERROR"
+	default:
+		return fmt.Sprintf("Level(%d)", int32(l))
+	}
+}
+
+// ParseLevel parses a level name, ignoring case; WARNING is accepted for WARN
+func ParseLevel(s string) (Level, error) {
+	switch strings.ToUpper(strings.TrimSpace(s)) {
+	case "DEBUG":
+		return LevelDebug, nil
+	case "INFO":
+		return LevelInfo, nil
+	case "WARN", "WARNING":
+		return LevelWarn, nil
+	case "ERROR":
+		return LevelError, nil
+	}
+	return 0, fmt.Errorf("unknown log level %q", s)
+}
+
+// Spec is a default level plus per-package overrides, written as
+// "INFO,authentication=DEBUG,validation=WARN"
+type Spec struct {
+	Default  Level
+	Packages map[string]Level
+}
+
+// ParseSpec parses a comma separated level spec; the default level may be
+// omitted and is then INFO
+func ParseSpec(s string) (Spec, error) {
+	spec := Spec{Default: LevelInfo, Packages: make(map[string]Level)}
+	seenDefault := false
+	for _, item := range strings.Split(s, ",") {
+		item = strings.TrimSpace(item)
+		if item == "" {
+			continue
+		}
+		pkg, name, override := strings.Cut(item, "=")
+		if !override {
+			if seenDefault {
+				return Spec{}, fmt.Errorf("log level spec %q: more than one default level", s)
+			}
+			level, err := ParseLevel(item)
+			if err != nil {
+				return Spec{}, err
+			}
+			spec.Default, seenDefault = level, true
+			continue
+		}
+		pkg = strings.ToLower(strings.TrimSpace(pkg))
+		if pkg == "" {
+			return Spec{}, fmt.Errorf("log level spec %q: missing package before %q", s, "="+name)
+		}
+		level, err := ParseLevel(name)
+		if err != nil {
+			return Spec{}, fmt.Errorf("package %s: %w", pkg, err)
+		}
+		spec.Packages[pkg] = level
+	}
+	return spec, nil
+}
+
+// String formats the spec so that ParseSpec reads it back
+func (s Spec) String() string {
+	items := []string{s.Default.String()}
+	pkgs := make([]string, 0, len(s.Packages))
+	for pkg := range s.Packages {
+		pkgs = append(pkgs, pkg)
+	}
+	sort.Strings(pkgs)
+	for _, pkg := range pkgs {
+		items = append(items, pkg+"="+s.Packages[pkg].String())
+	}
+	return strings.Join(items, ",")
+}
+
+// LevelFor returns the level the spec sets for pkg
+func (s Spec) LevelFor(pkg string) Level {
+	if level, ok := s.Packages[pkg]; ok {
+		return level
+	}
+	return s.Default
+}
+
+// clone returns a copy of the spec that shares no map with it
+func (s Spec) clone() Spec {
+	out := Spec{Default: s.Default, Packages: make(map[string]Level, len(s.Packages))}
+	for pkg, level := range s.Packages {
+		out.Packages[pkg] = level
+	}
+	return out
+}
+
+// registry holds the process-wide spec
+var registry = struct {
+	mu   sync.RWMutex
+	spec Spec
+}{spec: Spec{Default: LevelInfo, Packages: map[string]Level{}}}
+
+// Configure replaces the process-wide spec; every logger picks it up with
+// its next message
+func Configure(s string) error {
+	spec, err := ParseSpec(s)
+	if err != nil {
+		return err
+	}
+	registry.mu.Lock()
+	registry.spec = spec
+	registry.mu.Unlock()
+	return nil
+}
+
+// CurrentSpec returns a copy of the process-wide spec
+func CurrentSpec() Spec {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+	return registry.spec.clone()
+}
+
+// SetDefaultLevel changes the level of packages without an override
+func SetDefaultLevel(level Level) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	spec := registry.spec.clone()
+	spec.Default = level
+	registry.spec = spec
+}
+
+// SetPackageLevel overrides the level of one package
+func SetPackageLevel(pkg string, level Level) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	spec := registry.spec.clone()
+	spec.Packages[strings.ToLower(pkg)] = level
+	registry.spec = spec
+}
+
+// ClearPackageLevel removes the override of one package
+func ClearPackageLevel(pkg string) {
+	registry.mu.Lock()
+	defer registry.mu.Unlock()
+	spec := registry.spec.clone()
+	delete(spec.Packages, strings.ToLower(pkg))
+	registry.spec = spec
+}
+
+// LevelFor returns the process-wide level of pkg
+func LevelFor(pkg string) Level {
+	registry.mu.RLock()
+	defer registry.mu.RUnlock()
+	return registry.spec.LevelFor(pkg)
+}
+
+// Logger writes messages at or above its effective level: its own level
+// when set, otherwise the process-wide level of its package
+type Logger struct {
+	pkg   string
+	out   *log.Logger
+	level atomic.Int32
+}
+
+// New creates a logger for pkg writing to the standard logger's output with
+// the given prefix
+func New(pkg, prefix string) *Logger {
+	l := &Logger{pkg: strings.ToLower(pkg), out: log.New(log.Writer(), prefix, log.LstdFlags)}
+	l.level.Store(int32(levelInherit))
+	return l
+}
+
+// SetLevel gives the logger a level of its own, overriding the process-wide one
+func (l *Logger) SetLevel(level Level) {
+	l.level.Store(int32(level))
+}
+
+// Inherit drops the logger's own level so the process-wide one applies again
+func (l *Logger) Inherit() {
+	l.level.Store(int32(levelInherit))
+}
+
+// Configure sets the logger's own level from a name, inheriting when name
+// is empty
+func (l *Logger) Configure(name string) error {
+	if strings.TrimSpace(name) == "" {
+		l.Inherit()
+		return nil
+	}
+	level, err := ParseLevel(name)
+	if err != nil {
+		return err
+	}
+	l.SetLevel(level)
+	return nil
+}
+
+// Level returns the effective level of the logger
+func (l *Logger) Level() Level {
+	if level := Level(l.level.Load()); level != levelInherit {
+		return level
+	}
+	return LevelFor(l.pkg)
+}
+
+// Enabled reports whether messages at level are written
+func (l *Logger) Enabled(level Level) bool {
+	return level >= l.Level()
+}
+
+// logf writes a message at level
+func (l *Logger) logf(level Level, format string, args ...interface{}) {
+	if l.Enabled(level) {
+		l.out.Output(3, fmt.Sprintf(format, args...))
+	}
+}
+
+// Debugf writes a message at DEBUG level
+func (l *Logger) Debugf(format string, args ...interface{}) {
+	l.logf(LevelDebug, format, args...)
+}
+
+// Infof writes a message at INFO level
+func (l *Logger) Infof(format string, args ...interface{}) {
+	l.logf(LevelInfo, format, args...)
+}
+
+// Printf writes a message at INFO level, so the logger can replace a
+// *log.Logger
+func (l *Logger) Printf(format string, args ...interface{}) {
+	l.logf(LevelInfo, format, args...)
+}
+
+// Warnf writes a message at WARN level
+func (l *Logger) Warnf(format string, args ...interface{}) {
+	l.logf(LevelWarn, format, args...)
+}
+
+// Errorf writes a message at ERROR level
+func (l *Logger) Errorf(format string, args ...interface{}) {
+	l.logf(LevelError, format, args...)
+}
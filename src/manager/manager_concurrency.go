@@ -0,0 +1,57 @@
+package manager
+
+import (
+	"context"
+	"sync"
+)
+
+// limiter caps how many operations execute at once; the cap is read per
+// operation, so configuration changes apply to the next one
+type limiter struct {
+	mu     sync.Mutex
+	active int
+	// freed is closed and replaced whenever an operation ends
+	freed chan struct{}
+}
+
+// acquire waits until fewer than limit operations are executing, or ctx
+// is done; a limit of 0 or less is unlimited
+func (l *limiter) acquire(ctx context.Context, limit int) error {
+	for {
+		l.mu.Lock()
+		if limit <= 0 || l.active < limit {
+			l.active++
+			l.mu.Unlock()
+			return nil
+		}
+		if l.freed == nil {
+			l.freed = make(chan struct{})
+		}
+		freed := l.freed
+		l.mu.Unlock()
+
+		select {
+		case <-freed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// release ends an operation started with acquire, waking the waiters
+func (l *limiter) release() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.active--
+	if l.freed != nil {
+		close(l.freed)
+		l.freed = nil
+	}
+}
+
+// inFlight returns how many operations are executing
+func (l *limiter) inFlight() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.active
+}
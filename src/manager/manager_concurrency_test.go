@@ -0,0 +1,85 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMaxConcurrency(t *testing.T) {
+	var running, peak atomic.Int32
+	release := make(chan struct{})
+	m := New(Options{Name: "test"}, Operation[int, int]{
+		Config: func(ctx context.Context) (Config, error) { return Config{MaxConcurrency: 2}, nil },
+		Execute: func(ctx context.Context, n int) (int, error) {
+			now := running.Add(1)
+			defer running.Add(-1)
+			for {
+				old := peak.Load()
+				if now <= old || peak.CompareAndSwap(old, now) {
+					break
+				}
+			}
+			<-release
+			return n, nil
+		},
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if _, err := m.Process(context.Background(), i); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	deadline := time.Now().Add(time.Second)
+	for m.InFlight() < 2 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if got := m.InFlight(); got != 2 {
+		t.Errorf("InFlight = %d, want 2", got)
+	}
+	close(release)
+	wg.Wait()
+	if got := peak.Load(); got != 2 {
+		t.Errorf("peak concurrency = %d, want 2", got)
+	}
+	if got := m.InFlight(); got != 0 {
+		t.Errorf("InFlight after all returned = %d", got)
+	}
+}
+
+func TestLimiterWaitsWithinDeadline(t *testing.T) {
+	var l limiter
+	if err := l.acquire(context.Background(), 1); err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := l.acquire(ctx, 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("acquire over the limit = %v, want the deadline", err)
+	}
+
+	acquired := make(chan error)
+	go func() { acquired <- l.acquire(context.Background(), 1) }()
+	time.Sleep(10 * time.Millisecond)
+	l.release()
+	select {
+	case err := <-acquired:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("release did not wake the waiter")
+	}
+	if err := l.acquire(context.Background(), 0); err != nil || l.inFlight() != 2 {
+		t.Errorf("unlimited acquire = %v with %d in flight, want nil with 2", err, l.inFlight())
+	}
+}
@@ -0,0 +1,268 @@
+// Package manager provides the core shared by the domain managers
+// (authentication, configuration, validation, monitoring and processing):
+// status tracking, concurrency limits, logging, retries and timeouts
+// around the domain steps of each operation.
+package manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/nerufuyo/roastume/src/logging"
+)
+
+// Status represents the current state of manager operations
+type Status int
+
+const (
+	// StatusPending indicates operation is pending
+	StatusPending Status = iota
+	// StatusProcessing indicates operation is in progress
+	StatusProcessing
+	// StatusCompleted indicates operation completed successfully
+	StatusCompleted
+	// StatusFailed indicates operation failed
+	StatusFailed
+	// StatusCanceled indicates operation was canceled before it completed
+	StatusCanceled
+)
+
+// String returns string representation of Status
+func (s Status) String() string {
+	switch s {
+	case StatusPending:
+		return "pending"
+	case StatusProcessing:
+		return "processing"
+	case StatusCompleted:
+		return "completed"
+	case StatusFailed:
+		return "failed"
+	case StatusCanceled:
+		return "canceled"
+	default:
+		return "unknown"
+	}
+}
+
+// MarshalText encodes the status as its string representation
+func (s Status) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// Config holds the settings of one operation that the core enforces
+type Config struct {
+	// Timeout bounds the execution of an operation, retries included, when
+	// the caller's context has no deadline of its own
+	Timeout time.Duration
+	// Retries is how many times a transiently failing execution is retried
+	Retries int
+	// Backoff shapes the delays between retries
+	Backoff Backoff
+	// MaxConcurrency caps how many operations execute at once; further
+	// calls wait for a slot within their deadline. 0 is unlimited
+	MaxConcurrency int
+}
+
+// Operation is the domain part of a Process call. Operations run
+// concurrently, so steps that read domain state guard it themselves
+type Operation[TIn, TOut any] struct {
+	// Config returns the settings of the call; failures are returned as is
+	Config func(ctx context.Context) (Config, error)
+	// Validate rejects input before any work is done; failures are
+	// wrapped as "validation failed"
+	Validate func(ctx context.Context, data TIn) error
+	// Execute performs one attempt, retried while it fails transiently;
+	// failures are wrapped as "processing failed"
+	Execute func(ctx context.Context, data TIn) (TOut, error)
+	// Complete, if set, finishes a successful call, e.g. recording its
+	// operation ID and how long it took
+	Complete func(id OperationID, out TOut, elapsed time.Duration)
+}
+
+// Options configures a core
+type Options struct {
+	// Name names the domain in log messages, e.g. "authentication";
+	// it defaults to "manager"
+	Name string
+	// Logger is the domain manager's logger
+	Logger *logging.Logger
+}
+
+// Manager is the core a domain manager embeds: TIn is the input of its
+// operations and TOut their result
+type Manager[TIn, TOut any] struct {
+	name       string
+	title      string
+	logger     *logging.Logger
+	createdAt  time.Time
+	operation  Operation[TIn, TOut]
+	limiter    limiter
+	operations operations
+	// latest is the record of the most recently started operation, nil
+	// before the first one and after Reset
+	latest atomic.Pointer[record]
+}
+
+// New creates a core running operation on Process
+func New[TIn, TOut any](options Options, operation Operation[TIn, TOut]) *Manager[TIn, TOut] {
+	if options.Name == "" {
+		options.Name = "manager"
+	}
+	if options.Logger == nil {
+		options.Logger = logging.New(options.Name, "["+strings.ToUpper(options.Name)+"] ")
+	}
+	return &Manager[TIn, TOut]{
+		name:      options.Name,
+		title:     strings.ToUpper(options.Name[:1]) + options.Name[1:],
+		logger:    options.Logger,
+		createdAt: time.Now(),
+		operation: operation,
+	}
+}
+
+// Process executes the domain operation with comprehensive error handling.
+// Every call is an operation with its own ID, which Complete receives and
+// failures carry as an OperationError, for StatusOf and CancelOperation
+func (m *Manager[TIn, TOut]) Process(ctx context.Context, data TIn) (TOut, error) {
+	return m.Run(ctx, data, m.operation)
+}
+
+// Run executes op like Process, for domain managers whose operations
+// vary per call; steps op leaves unset are taken from the core's operation
+func (m *Manager[TIn, TOut]) Run(ctx context.Context, data TIn, op Operation[TIn, TOut]) (TOut, error) {
+	op = m.withDefaults(op)
+
+	var zero TOut
+	ctx, r, err := m.operations.start(ctx)
+	if err != nil {
+		return zero, fmt.Errorf("start %s operation: %w", m.name, err)
+	}
+	defer m.operations.end(r)
+	m.latest.Store(r)
+	m.logger.Debugf("Starting %s processing %s", m.name, r.id)
+
+	// Validate input data
+	if err := op.Validate(ctx, data); err != nil {
+		return zero, m.fail(ctx, r, fmt.Errorf("validation failed: %w", err))
+	}
+
+	config, err := op.Config(ctx)
+	if err != nil {
+		return zero, m.fail(ctx, r, err)
+	}
+
+	// Wait for a slot, then execute processing under the configured
+	// deadline and retry budget
+	callCtx, cancel := withDeadline(ctx, config.Timeout)
+	defer cancel()
+	out, err := m.execute(callCtx, config, func(ctx context.Context) (TOut, error) {
+		return op.Execute(ctx, data)
+	})
+	if err != nil {
+		err = timedOut(err, ctx, callCtx, config.Timeout)
+		return zero, m.fail(ctx, r, fmt.Errorf("processing failed: %w", err))
+	}
+
+	if op.Complete != nil {
+		op.Complete(r.id, out, time.Since(r.started))
+	}
+	r.finish(StatusCompleted, nil)
+	m.logger.Debugf("%s processing %s completed successfully", m.title, r.id)
+
+	return out, nil
+}
+
+// withDefaults fills the steps op leaves unset from the core's operation
+func (m *Manager[TIn, TOut]) withDefaults(op Operation[TIn, TOut]) Operation[TIn, TOut] {
+	if op.Config == nil {
+		op.Config = m.operation.Config
+	}
+	if op.Config == nil {
+		op.Config = func(ctx context.Context) (Config, error) {
+			return Config{}, nil
+		}
+	}
+	if op.Validate == nil {
+		op.Validate = m.operation.Validate
+	}
+	if op.Validate == nil {
+		op.Validate = func(ctx context.Context, data TIn) error {
+			return nil
+		}
+	}
+	if op.Execute == nil {
+		op.Execute = m.operation.Execute
+	}
+	if op.Complete == nil {
+		op.Complete = m.operation.Complete
+	}
+	return op
+}
+
+// execute runs execute within the concurrency limit of config
+func (m *Manager[TIn, TOut]) execute(ctx context.Context, config Config, execute func(ctx context.Context) (TOut, error)) (TOut, error) {
+	if err := m.limiter.acquire(ctx, config.MaxConcurrency); err != nil {
+		var zero TOut
+		return zero, err
+	}
+	defer m.limiter.release()
+	return m.retry(ctx, config, execute)
+}
+
+// fail records the failure of the operation of r, running under ctx, and
+// returns err with its ID
+func (m *Manager[TIn, TOut]) fail(ctx context.Context, r *record, err error) error {
+	if errors.Is(err, context.Canceled) && ctx.Err() == context.Canceled {
+		r.finish(StatusCanceled, err)
+		m.logger.Printf("%s processing %s canceled", m.title, r.id)
+	} else {
+		r.finish(StatusFailed, err)
+		m.logger.Errorf("%s processing %s failed: %v", m.title, r.id, err)
+	}
+	return &OperationError{ID: r.id, Err: err}
+}
+
+// Validate runs the operation's validation of data alone
+func (m *Manager[TIn, TOut]) Validate(data TIn) error {
+	if m.operation.Validate == nil {
+		return nil
+	}
+	return m.operation.Validate(context.Background(), data)
+}
+
+// GetStatus returns the status of the most recently started operation
+func (m *Manager[TIn, TOut]) GetStatus() Status {
+	if r := m.latest.Load(); r != nil {
+		return r.get()
+	}
+	return StatusPending
+}
+
+// InFlight returns how many operations are executing
+func (m *Manager[TIn, TOut]) InFlight() int {
+	return m.limiter.inFlight()
+}
+
+// Reset resets the manager to initial state, forgetting finished
+// operations; operations in flight finish without affecting its status
+func (m *Manager[TIn, TOut]) Reset() {
+	m.latest.Store(nil)
+	m.operations.forgetFinished()
+	m.logger.Printf("%s manager reset completed", m.title)
+}
+
+// GetCreatedAt returns the creation timestamp
+func (m *Manager[TIn, TOut]) GetCreatedAt() time.Time {
+	return m.createdAt
+}
+
+// Close performs cleanup operations
+func (m *Manager[TIn, TOut]) Close() error {
+	m.logger.Printf("%s manager closing", m.title)
+	return nil
+}
@@ -0,0 +1,65 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// double returns a core doubling positive numbers
+func double() *Manager[int, int] {
+	return New(Options{Name: "test"}, Operation[int, int]{
+		Validate: func(ctx context.Context, n int) error {
+			if n <= 0 {
+				return errors.New("not positive")
+			}
+			return nil
+		},
+		Execute: func(ctx context.Context, n int) (int, error) {
+			return 2 * n, nil
+		},
+	})
+}
+
+func TestNewWithoutName(t *testing.T) {
+	m := New(Options{}, Operation[int, int]{Execute: func(ctx context.Context, n int) (int, error) { return n, nil }})
+	if m.name != "manager" || m.title != "Manager" {
+		t.Errorf("name, title = %q, %q; want manager, Manager", m.name, m.title)
+	}
+	if out, err := m.Process(context.Background(), 3); err != nil || out != 3 {
+		t.Errorf("Process = %v, %v", out, err)
+	}
+}
+
+func TestProcess(t *testing.T) {
+	m := double()
+	if got := m.GetStatus(); got != StatusPending {
+		t.Errorf("status before Process = %v, want pending", got)
+	}
+
+	var completed OperationID
+	out, err := m.Run(context.Background(), 4, Operation[int, int]{
+		Complete: func(id OperationID, out int, elapsed time.Duration) { completed = id },
+	})
+	if err != nil || out != 8 {
+		t.Fatalf("Run = %v, %v; want 8", out, err)
+	}
+	if completed == "" || m.GetStatus() != StatusCompleted {
+		t.Errorf("Complete got %q, status %v", completed, m.GetStatus())
+	}
+
+	_, err = m.Process(context.Background(), -1)
+	if err == nil || !strings.HasPrefix(err.Error(), "validation failed") {
+		t.Fatalf("Process(-1) = %v, want a validation failure", err)
+	}
+	if m.GetStatus() != StatusFailed {
+		t.Errorf("status = %v, want failed", m.GetStatus())
+	}
+
+	m.Reset()
+	if m.GetStatus() != StatusPending || len(m.ListOperations(OperationFilter{})) != 0 {
+		t.Errorf("Reset left status %v and operations %v", m.GetStatus(), m.ListOperations(OperationFilter{}))
+	}
+}
@@ -0,0 +1,244 @@
+package manager
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrOperationNotFound is returned for unknown or forgotten operation IDs
+var ErrOperationNotFound = errors.New("operation not found")
+
+// maxFinishedOperations is how many finished operations a manager
+// remembers; the oldest are forgotten first
+const maxFinishedOperations = 100
+
+// OperationID identifies one Process call
+type OperationID string
+
+// OperationInfo is a snapshot of an operation
+type OperationInfo struct {
+	ID         OperationID `json:"id"`
+	Status     Status      `json:"status"`
+	StartedAt  time.Time   `json:"started_at"`
+	FinishedAt time.Time   `json:"finished_at,omitempty"`
+	Error      string      `json:"error,omitempty"`
+}
+
+// OperationFilter selects operations for ListOperations; zero fields
+// match every operation
+type OperationFilter struct {
+	// Status matches operations in any of these states
+	Status []Status
+	// Since matches operations started at or after it
+	Since time.Time
+	// Limit caps how many operations are listed, the newest first
+	Limit int
+}
+
+// matches reports whether info passes the filter
+func (f OperationFilter) matches(info OperationInfo) bool {
+	if !f.Since.IsZero() && info.StartedAt.Before(f.Since) {
+		return false
+	}
+	if len(f.Status) == 0 {
+		return true
+	}
+	for _, status := range f.Status {
+		if info.Status == status {
+			return true
+		}
+	}
+	return false
+}
+
+// OperationError is returned by a failed Process call and carries the ID
+// of its operation; it reads as the underlying error
+type OperationError struct {
+	ID  OperationID
+	Err error
+}
+
+// Error returns the text of the underlying error
+func (e *OperationError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error
+func (e *OperationError) Unwrap() error {
+	return e.Err
+}
+
+// OperationIDOf returns the ID of the operation that failed with err
+func OperationIDOf(err error) (OperationID, bool) {
+	var opErr *OperationError
+	if errors.As(err, &opErr) {
+		return opErr.ID, true
+	}
+	return "", false
+}
+
+// record tracks one operation; its status is updated atomically so
+// concurrent operations never wait on each other to report progress
+type record struct {
+	id      OperationID
+	started time.Time
+	cancel  context.CancelFunc
+	status  atomic.Int32
+
+	// mu guards the outcome, set once the operation ends
+	mu       sync.Mutex
+	finished time.Time
+	err      error
+}
+
+// set records the status of the operation
+func (r *record) set(status Status) {
+	r.status.Store(int32(status))
+}
+
+// get returns the status of the operation
+func (r *record) get() Status {
+	return Status(r.status.Load())
+}
+
+// finish records the outcome of the operation
+func (r *record) finish(status Status, err error) {
+	r.mu.Lock()
+	r.finished, r.err = time.Now(), err
+	r.mu.Unlock()
+	r.set(status)
+}
+
+// info takes a snapshot of r
+func (r *record) info() OperationInfo {
+	info := OperationInfo{ID: r.id, Status: r.get(), StartedAt: r.started}
+	r.mu.Lock()
+	info.FinishedAt = r.finished
+	if r.err != nil {
+		info.Error = r.err.Error()
+	}
+	r.mu.Unlock()
+	return info
+}
+
+// operations holds the in-flight and recently finished operations of a
+// manager
+type operations struct {
+	mu       sync.Mutex
+	records  map[OperationID]*record
+	finished []OperationID
+}
+
+// start registers a new operation whose context is derived from ctx
+func (o *operations) start(ctx context.Context) (context.Context, *record, error) {
+	id, err := newOperationID()
+	if err != nil {
+		return nil, nil, err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	r := &record{id: id, started: time.Now(), cancel: cancel}
+	r.set(StatusProcessing)
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.records == nil {
+		o.records = make(map[OperationID]*record)
+	}
+	o.records[id] = r
+	return ctx, r, nil
+}
+
+// end releases the context of r and forgets the oldest finished
+// operations beyond maxFinishedOperations
+func (o *operations) end(r *record) {
+	r.cancel()
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.finished = append(o.finished, r.id)
+	for len(o.finished) > maxFinishedOperations {
+		delete(o.records, o.finished[0])
+		o.finished = o.finished[1:]
+	}
+}
+
+// lookup finds an operation by ID
+func (o *operations) lookup(id OperationID) (*record, bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	r, ok := o.records[id]
+	return r, ok
+}
+
+// forgetFinished drops every finished operation
+func (o *operations) forgetFinished() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	for _, id := range o.finished {
+		delete(o.records, id)
+	}
+	o.finished = nil
+}
+
+// StatusOf returns a snapshot of the operation
+func (m *Manager[TIn, TOut]) StatusOf(id OperationID) (OperationInfo, error) {
+	r, ok := m.operations.lookup(id)
+	if !ok {
+		return OperationInfo{}, ErrOperationNotFound
+	}
+	return r.info(), nil
+}
+
+// ListOperations returns snapshots of the in-flight and remembered
+// operations passing filter, the newest first
+func (m *Manager[TIn, TOut]) ListOperations(filter OperationFilter) []OperationInfo {
+	m.operations.mu.Lock()
+	records := make([]*record, 0, len(m.operations.records))
+	for _, r := range m.operations.records {
+		records = append(records, r)
+	}
+	m.operations.mu.Unlock()
+
+	infos := make([]OperationInfo, 0, len(records))
+	for _, r := range records {
+		if info := r.info(); filter.matches(info) {
+			infos = append(infos, info)
+		}
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].StartedAt.After(infos[j].StartedAt)
+	})
+	if filter.Limit > 0 && len(infos) > filter.Limit {
+		infos = infos[:filter.Limit]
+	}
+	return infos
+}
+
+// CancelOperation cancels the context of an in-flight operation, which
+// then ends as StatusCanceled; canceling a finished operation has no effect
+func (m *Manager[TIn, TOut]) CancelOperation(id OperationID) error {
+	r, ok := m.operations.lookup(id)
+	if !ok {
+		return ErrOperationNotFound
+	}
+	if r.get() == StatusProcessing {
+		m.logger.Printf("Canceling %s operation %s", m.name, id)
+	}
+	r.cancel()
+	return nil
+}
+
+// newOperationID returns a random operation ID
+func newOperationID() (OperationID, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return OperationID(hex.EncodeToString(id)), nil
+}
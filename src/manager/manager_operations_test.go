@@ -0,0 +1,164 @@
+package manager
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// blocking returns a core whose operation fails for negative input and
+// otherwise waits for release or ctx; started receives each operation's
+// context as it starts
+func blocking(release chan struct{}) (*Manager[int, int], chan context.Context) {
+	started := make(chan context.Context, 200)
+	m := New(Options{Name: "test"}, Operation[int, int]{
+		Execute: func(ctx context.Context, n int) (int, error) {
+			if n < 0 {
+				return 0, errors.New("negative")
+			}
+			started <- ctx
+			select {
+			case <-release:
+				return 0, nil
+			case <-ctx.Done():
+				return 0, ctx.Err()
+			}
+		},
+	})
+	return m, started
+}
+
+// latestID returns the ID of the newest operation of m
+func latestID(t *testing.T, m *Manager[int, int]) OperationID {
+	t.Helper()
+	ops := m.ListOperations(OperationFilter{Limit: 1})
+	if len(ops) != 1 {
+		t.Fatalf("ListOperations = %v", ops)
+	}
+	return ops[0].ID
+}
+
+func TestOperationStatus(t *testing.T) {
+	release := make(chan struct{})
+	m, started := blocking(release)
+
+	_, err := m.Process(context.Background(), -1)
+	failed, ok := OperationIDOf(err)
+	if !ok || failed == "" {
+		t.Fatalf("OperationIDOf(%v) = %q, %v", err, failed, ok)
+	}
+	info, err := m.StatusOf(failed)
+	if err != nil || info.Status != StatusFailed || info.Error == "" || info.FinishedAt.IsZero() {
+		t.Errorf("StatusOf(failed) = %+v, %v", info, err)
+	}
+
+	done := make(chan error)
+	go func() {
+		_, err := m.Process(context.Background(), 1)
+		done <- err
+	}()
+	<-started
+	running := latestID(t, m)
+	if info, _ := m.StatusOf(running); info.Status != StatusProcessing || !info.FinishedAt.IsZero() {
+		t.Errorf("StatusOf(running) = %+v", info)
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	if info, _ := m.StatusOf(running); info.Status != StatusCompleted {
+		t.Errorf("StatusOf(completed) = %+v", info)
+	}
+
+	if _, err := m.StatusOf("unknown"); !errors.Is(err, ErrOperationNotFound) {
+		t.Errorf("StatusOf(unknown) = %v, want ErrOperationNotFound", err)
+	}
+	if _, ok := OperationIDOf(errors.New("plain")); ok {
+		t.Error("OperationIDOf found an ID in a plain error")
+	}
+
+	data, err := json.Marshal(OperationInfo{Status: StatusCanceled})
+	if err != nil || !strings.Contains(string(data), `"status":"canceled"`) {
+		t.Errorf("OperationInfo JSON = %s, %v", data, err)
+	}
+}
+
+func TestCancelOperation(t *testing.T) {
+	m, started := blocking(make(chan struct{}))
+	done := make(chan error)
+	go func() {
+		_, err := m.Process(context.Background(), 1)
+		done <- err
+	}()
+	<-started
+	id := latestID(t, m)
+	if err := m.CancelOperation(id); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("Process = %v, want context.Canceled", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("CancelOperation did not stop the operation")
+	}
+	if info, _ := m.StatusOf(id); info.Status != StatusCanceled {
+		t.Errorf("status = %v, want canceled", info.Status)
+	}
+	if err := m.CancelOperation(id); err != nil {
+		t.Errorf("canceling a finished operation = %v", err)
+	}
+	if err := m.CancelOperation("unknown"); !errors.Is(err, ErrOperationNotFound) {
+		t.Errorf("CancelOperation(unknown) = %v, want ErrOperationNotFound", err)
+	}
+}
+
+func TestListOperations(t *testing.T) {
+	release := make(chan struct{})
+	close(release)
+	m, _ := blocking(release)
+	for i := 0; i < 3; i++ {
+		m.Process(context.Background(), 1)
+		m.Process(context.Background(), -1)
+	}
+	since := time.Now()
+	m.Process(context.Background(), 1)
+
+	if got := len(m.ListOperations(OperationFilter{})); got != 7 {
+		t.Errorf("all operations = %d, want 7", got)
+	}
+	if got := len(m.ListOperations(OperationFilter{Status: []Status{StatusFailed}})); got != 3 {
+		t.Errorf("failed operations = %d, want 3", got)
+	}
+	if got := len(m.ListOperations(OperationFilter{Status: []Status{StatusFailed, StatusCompleted}})); got != 7 {
+		t.Errorf("failed or completed operations = %d, want 7", got)
+	}
+	if got := m.ListOperations(OperationFilter{Since: since}); len(got) != 1 || got[0].Status != StatusCompleted {
+		t.Errorf("operations since = %+v, want the last one", got)
+	}
+	ops := m.ListOperations(OperationFilter{Limit: 3})
+	if len(ops) != 3 || ops[0].StartedAt.Before(ops[2].StartedAt) {
+		t.Errorf("limited operations = %+v, want the newest 3 first", ops)
+	}
+}
+
+func TestFinishedOperationsAreForgotten(t *testing.T) {
+	release := make(chan struct{})
+	close(release)
+	m, _ := blocking(release)
+	_, err := m.Process(context.Background(), -1)
+	first, _ := OperationIDOf(err)
+	for i := 0; i < maxFinishedOperations; i++ {
+		m.Process(context.Background(), 1)
+	}
+	if got := len(m.ListOperations(OperationFilter{})); got != maxFinishedOperations {
+		t.Errorf("remembered operations = %d, want %d", got, maxFinishedOperations)
+	}
+	if _, err := m.StatusOf(first); !errors.Is(err, ErrOperationNotFound) {
+		t.Errorf("oldest operation still known: %v", err)
+	}
+}
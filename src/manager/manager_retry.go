@@ -0,0 +1,130 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+)
+
+// ErrTransient marks failures that may succeed when retried
+var ErrTransient = errors.New("transient failure")
+
+// Transient wraps err so Process retries it
+func Transient(err error) error {
+	if err == nil {
+		return nil
+	}
+	return fmt.Errorf("%w: %w", ErrTransient, err)
+}
+
+// Retryable is implemented by errors that classify themselves; it takes
+// precedence over ErrTransient and Temporary
+type Retryable interface {
+	Retryable() bool
+}
+
+// IsTransient reports whether err is worth retrying: errors whose
+// Retryable method says so, errors wrapping ErrTransient and errors
+// implementing Temporary() bool that returns true, except context
+// cancellation and deadlines
+func IsTransient(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var retryable Retryable
+	if errors.As(err, &retryable) {
+		return retryable.Retryable()
+	}
+	if errors.Is(err, ErrTransient) {
+		return true
+	}
+	var temporary interface{ Temporary() bool }
+	return errors.As(err, &temporary) && temporary.Temporary()
+}
+
+// Backoff shapes the delays between retries; zero fields take the values
+// of DefaultBackoff, except MaxElapsed
+type Backoff struct {
+	// Initial is the delay before the first retry
+	Initial time.Duration
+	// Max caps every delay
+	Max time.Duration
+	// Multiplier grows the delay after each retry
+	Multiplier float64
+	// Jitter spreads each delay randomly by up to this fraction either way,
+	// so callers failing together do not retry together
+	Jitter float64
+	// MaxElapsed, if positive, stops retrying once the next attempt would
+	// start this long after the first
+	MaxElapsed time.Duration
+}
+
+// DefaultBackoff returns delays starting at 50ms and doubling up to 5s,
+// with 20% jitter and no limit on the elapsed time
+func DefaultBackoff() Backoff {
+	return Backoff{
+		Initial:    50 * time.Millisecond,
+		Max:        5 * time.Second,
+		Multiplier: 2,
+		Jitter:     0.2,
+	}
+}
+
+// withDefaults fills the zero fields of b from DefaultBackoff
+func (b Backoff) withDefaults() Backoff {
+	defaults := DefaultBackoff()
+	if b.Initial <= 0 {
+		b.Initial = defaults.Initial
+	}
+	if b.Max <= 0 {
+		b.Max = defaults.Max
+	}
+	if b.Multiplier < 1 {
+		b.Multiplier = defaults.Multiplier
+	}
+	if b.Jitter <= 0 || b.Jitter > 1 {
+		b.Jitter = defaults.Jitter
+	}
+	return b
+}
+
+// Delay returns the delay before retry n (starting at 1), jitter included
+func (b Backoff) Delay(n int) time.Duration {
+	b = b.withDefaults()
+	delay := float64(b.Initial)
+	for i := 1; i < n && delay < float64(b.Max); i++ {
+		delay *= b.Multiplier
+	}
+	delay *= 1 + b.Jitter*(2*rand.Float64()-1)
+	if delay > float64(b.Max) {
+		delay = float64(b.Max)
+	}
+	return time.Duration(delay)
+}
+
+// retry runs execute, retrying transient failures up to config.Retries
+// times with delays from config.Backoff until ctx is done
+func (m *Manager[TIn, TOut]) retry(ctx context.Context, config Config, execute func(ctx context.Context) (TOut, error)) (TOut, error) {
+	start := time.Now()
+	for attempt := 0; ; attempt++ {
+		out, err := execute(ctx)
+		if err == nil || !IsTransient(err) || attempt >= config.Retries {
+			return out, err
+		}
+
+		delay := config.Backoff.Delay(attempt + 1)
+		if config.Backoff.MaxElapsed > 0 && time.Since(start)+delay > config.Backoff.MaxElapsed {
+			m.logger.Warnf("Giving up %s processing after %d attempt(s) in %v: %v", m.name, attempt+1, time.Since(start).Round(time.Millisecond), err)
+			return out, err
+		}
+		m.logger.Printf("Transient %s processing failure, retrying in %v: %v", m.name, delay.Round(time.Millisecond), err)
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			var zero TOut
+			return zero, fmt.Errorf("%w (last error: %v)", ctx.Err(), err)
+		}
+	}
+}
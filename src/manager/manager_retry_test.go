@@ -0,0 +1,115 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+type retryable bool
+
+func (r retryable) Error() string   { return "retryable" }
+func (r retryable) Retryable() bool { return bool(r) }
+
+type temporary struct{}
+
+func (temporary) Error() string   { return "temporary" }
+func (temporary) Temporary() bool { return true }
+
+func TestIsTransient(t *testing.T) {
+	for err, want := range map[error]bool{
+		errors.New("permanent"):                                  false,
+		Transient(errors.New("busy")):                            true,
+		fmt.Errorf("wrapped: %w", Transient(errors.New("busy"))): true,
+		retryable(true):                                          true,
+		retryable(false):                                         false,
+		fmt.Errorf("%w: %w", ErrTransient, retryable(false)):     false,
+		temporary{}:                              true,
+		Transient(context.Canceled):              false,
+		Transient(context.DeadlineExceeded):      false,
+		fmt.Errorf("late: %w", context.Canceled): false,
+	} {
+		if got := IsTransient(err); got != want {
+			t.Errorf("IsTransient(%v) = %v, want %v", err, got, want)
+		}
+	}
+	if Transient(nil) != nil {
+		t.Error("Transient(nil) != nil")
+	}
+}
+
+func TestBackoffDelay(t *testing.T) {
+	b := Backoff{Initial: 100 * time.Millisecond, Max: time.Second, Multiplier: 2, Jitter: 0.1}
+	for n, base := range map[int]time.Duration{1: 100 * time.Millisecond, 2: 200 * time.Millisecond, 3: 400 * time.Millisecond, 4: 800 * time.Millisecond} {
+		for i := 0; i < 100; i++ {
+			if d := b.Delay(n); d < base*9/10 || d > base*11/10 {
+				t.Fatalf("Delay(%d) = %v, want %v ± 10%%", n, d, base)
+			}
+		}
+	}
+	for _, n := range []int{5, 10, 1000} {
+		for i := 0; i < 100; i++ {
+			if d := b.Delay(n); d < time.Second*9/10 || d > time.Second {
+				t.Fatalf("Delay(%d) = %v, want between 900ms and the 1s cap", n, d)
+			}
+		}
+	}
+	if d := (Backoff{}).Delay(1); d < 40*time.Millisecond || d > 60*time.Millisecond {
+		t.Errorf("zero Backoff Delay(1) = %v, want the default 50ms ± 20%%", d)
+	}
+}
+
+// flaky returns a core whose operation fails with err until it has been
+// attempted succeedOn times
+func flaky(config Config, err error, succeedOn int, attempts *int) *Manager[int, int] {
+	return New(Options{Name: "test"}, Operation[int, int]{
+		Config: func(ctx context.Context) (Config, error) { return config, nil },
+		Execute: func(ctx context.Context, n int) (int, error) {
+			*attempts++
+			if *attempts < succeedOn {
+				return 0, err
+			}
+			return n, nil
+		},
+	})
+}
+
+func TestRetry(t *testing.T) {
+	fast := Backoff{Initial: time.Millisecond, Max: time.Millisecond}
+	for _, tt := range []struct {
+		name         string
+		config       Config
+		err          error
+		succeedOn    int
+		wantAttempts int
+		wantErr      bool
+	}{
+		{"succeeds after retries", Config{Retries: 3, Backoff: fast}, Transient(errors.New("busy")), 3, 3, false},
+		{"runs out of retries", Config{Retries: 2, Backoff: fast}, Transient(errors.New("busy")), 10, 3, true},
+		{"no retries", Config{Backoff: fast}, Transient(errors.New("busy")), 10, 1, true},
+		{"permanent failure", Config{Retries: 3, Backoff: fast}, errors.New("bad"), 10, 1, true},
+		{"max elapsed", Config{Retries: 10, Backoff: Backoff{Initial: 100 * time.Millisecond, Max: 100 * time.Millisecond, MaxElapsed: 225 * time.Millisecond}}, Transient(errors.New("busy")), 100, 3, true},
+	} {
+		attempts := 0
+		_, err := flaky(tt.config, tt.err, tt.succeedOn, &attempts).Process(context.Background(), 1)
+		if (err != nil) != tt.wantErr || attempts != tt.wantAttempts {
+			t.Errorf("%s: err = %v after %d attempt(s); want error %v after %d", tt.name, err, attempts, tt.wantErr, tt.wantAttempts)
+		}
+		if err != nil && !errors.Is(err, tt.err) {
+			t.Errorf("%s: err = %v, want it to wrap %v", tt.name, err, tt.err)
+		}
+	}
+}
+
+func TestRetryStopsWhenCanceled(t *testing.T) {
+	attempts := 0
+	m := flaky(Config{Retries: 5, Backoff: Backoff{Initial: time.Hour, Max: time.Hour}}, Transient(errors.New("busy")), 100, &attempts)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := m.Process(ctx, 1)
+	if !errors.Is(err, context.DeadlineExceeded) || attempts != 1 {
+		t.Errorf("err = %v after %d attempt(s), want the caller's deadline after 1", err, attempts)
+	}
+}
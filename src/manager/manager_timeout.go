@@ -0,0 +1,30 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrTimeout is returned when an operation runs past Config.Timeout; it
+// also matches context.DeadlineExceeded
+var ErrTimeout = errors.New("operation timed out")
+
+// withDeadline bounds ctx by timeout unless the caller already set a
+// deadline, which takes precedence
+func withDeadline(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok || timeout <= 0 {
+		return ctx, func() {}
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// timedOut wraps err in ErrTimeout when the deadline withDeadline derived
+// from parent expired, rather than one parent carried itself
+func timedOut(err error, parent, ctx context.Context, timeout time.Duration) error {
+	if parent.Err() == nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fmt.Errorf("%w after %v: %w", ErrTimeout, timeout, err)
+	}
+	return err
+}
@@ -0,0 +1,63 @@
+package manager
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// sleeper returns a core whose operation waits for ctx, under timeout
+func sleeper(timeout time.Duration) *Manager[int, int] {
+	return New(Options{Name: "test"}, Operation[int, int]{
+		Config: func(ctx context.Context) (Config, error) { return Config{Timeout: timeout}, nil },
+		Execute: func(ctx context.Context, n int) (int, error) {
+			<-ctx.Done()
+			return 0, ctx.Err()
+		},
+	})
+}
+
+func TestTimeout(t *testing.T) {
+	m := sleeper(20 * time.Millisecond)
+	_, err := m.Process(context.Background(), 1)
+	if !errors.Is(err, ErrTimeout) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("err = %v, want ErrTimeout matching context.DeadlineExceeded", err)
+	}
+	id, _ := OperationIDOf(err)
+	if info, _ := m.StatusOf(id); info.Status != StatusFailed {
+		t.Errorf("status = %v, want failed", info.Status)
+	}
+}
+
+func TestCallerDeadlineTakesPrecedence(t *testing.T) {
+	m := sleeper(time.Hour)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := m.Process(ctx, 1)
+	if !errors.Is(err, context.DeadlineExceeded) || errors.Is(err, ErrTimeout) {
+		t.Errorf("err = %v, want the caller's deadline, not ErrTimeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Process took %v", elapsed)
+	}
+
+	m = sleeper(time.Millisecond)
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start = time.Now()
+	if _, err := m.Process(ctx, 1); time.Since(start) < 40*time.Millisecond {
+		t.Errorf("Process returned %v after %v, before the caller's deadline", err, time.Since(start))
+	}
+}
+
+func TestNoTimeout(t *testing.T) {
+	m := sleeper(0)
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(20*time.Millisecond, cancel)
+	_, err := m.Process(ctx, 1)
+	if !errors.Is(err, context.Canceled) || errors.Is(err, ErrTimeout) {
+		t.Errorf("err = %v, want context.Canceled", err)
+	}
+}
@@ -10,46 +10,41 @@ package processing
 import (
 	"context"
 	"fmt"
-	"log"
-	"sync"
 	"time"
+
+	"github.com/nerufuyo/roastume/src/logging"
+	"github.com/nerufuyo/roastume/src/manager"
 )
 
 // Status represents the current state of processing operations
-type Status int
+type Status = manager.Status
 
 const (
 	// StatusPending indicates operation is pending
-	StatusPending Status = iota
+	StatusPending = manager.StatusPending
 	// StatusProcessing indicates operation is in progress
-	StatusProcessing
+	StatusProcessing = manager.StatusProcessing
 	// StatusCompleted indicates operation completed successfully
-	StatusCompleted
+	StatusCompleted = manager.StatusCompleted
 	// StatusFailed indicates operation failed
-	StatusFailed
+	StatusFailed = manager.StatusFailed
+	// StatusCanceled indicates operation was canceled before it completed
+	StatusCanceled = manager.StatusCanceled
 )
 
-// String returns string representation of Status
-func (s Status) String() string {
-	switch s {
-	case StatusPending:
-		return "pending"
-	case StatusProcessing:
-		return "processing"
-	case StatusCompleted:
-		return "completed"
-	case StatusFailed:
-		return "failed"
-	default:
-		return "unknown"
-	}
-}
+// OperationID identifies one Process call, see Result.OperationID
+type OperationID = manager.OperationID
+
+// ErrTimeout is returned by Process when an operation outlasts Config.Timeout
+var ErrTimeout = manager.ErrTimeout
 
 // Config holds configuration settings for processing operations
 type Config struct {
 	Enabled   bool          `json:"enabled"`
 	Timeout   time.Duration `json:"timeout"`
 	Retries   int           `json:"retries"`
+	RetryMaxElapsed time.Duration `json:"retry_max_elapsed"`
+	MaxConcurrency  int           `json:"max_concurrency"`
 	LogLevel  string        `json:"log_level"`
 }
 
@@ -59,7 +54,17 @@ func DefaultConfig() *Config {
 		Enabled:  true,
 		Timeout:  30 * time.Second,
 		Retries:  3,
-		LogLevel: "INFO",
+		LogLevel: "",
+	}
+}
+
+// core returns the settings the manager core enforces
+func (c *Config) core() manager.Config {
+	return manager.Config{
+		Timeout: c.Timeout,
+		Retries: c.Retries,
+		Backoff: manager.Backoff{MaxElapsed: c.RetryMaxElapsed},
+		MaxConcurrency: c.MaxConcurrency,
 	}
 }
 
@@ -70,15 +75,14 @@ type Result struct {
 	DataSize      int       `json:"data_size"`
 	ProcessingTime time.Duration `json:"processing_time"`
 	Message       string    `json:"message,omitempty"`
+	OperationID   OperationID `json:"operation_id,omitempty"`
 }
 
 // Manager provides professional processing management functionality
 type Manager struct {
+	*manager.Manager[interface{}, *Result]
 	config    *Config
-	status    Status
-	mu        sync.RWMutex
-	createdAt time.Time
-	logger    *log.Logger
+	logger    *logging.Logger
 }
 
 // ManagerInterface defines the interface for processing operations
@@ -97,52 +101,35 @@ func NewManager(config *Config) *Manager {
 		config = DefaultConfig()
 	}
 	
-	manager := &Manager{
+	m := &Manager{
 		config:    config,
-		status:    StatusPending,
-		createdAt: time.Now(),
-		logger:    log.New(log.Writer(), fmt.Sprintf("[PROCESSING] "), log.LstdFlags),
+		logger:    logging.New("processing", "[PROCESSING] "),
 	}
-	
-	manager.setupLogging()
-	return manager
-}
-
-// setupLogging configures logging for the manager
+	m.Manager = manager.New(manager.Options{Name: "processing", Logger: m.logger}, manager.Operation[interface{}, *Result]{
+		Config: func(ctx context.Context) (manager.Config, error) {
+			return m.config.core(), nil
+		},
+		Validate: func(ctx context.Context, data interface{}) error {
+			return m.Validate(data)
+		},
+		Execute: m.executeProcessing,
+		Complete: func(id OperationID, result *Result, elapsed time.Duration) {
+			result.OperationID = id
+			result.ProcessingTime = elapsed
+		},
+	})
+	
+	m.setupLogging()
+	return m
+}
+
+// setupLogging configures logging for the manager; an empty LogLevel
+// follows the process-wide level set by the configuration manager
 func (m *Manager) setupLogging() {
-	m.logger.Printf("Initialized processing manager with configuration")
-}
-
-// Process executes processing processing with comprehensive error handling
-func (m *Manager) Process(ctx context.Context, data interface{}) (*Result, error) {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-	
-	start := time.Now()
-	
-	m.logger.Printf("Starting processing processing")
-	m.status = StatusProcessing
-	
-	// Validate input data
-	if err := m.Validate(data); err != nil {
-		m.status = StatusFailed
-		m.logger.Printf("Processing processing failed: %v", err)
-		return nil, fmt.Errorf("validation failed: %w", err)
+	if err := m.logger.Configure(m.config.LogLevel); err != nil {
+		m.logger.Warnf("Ignoring log level: %v", err)
 	}
-	
-	// Execute processing with context cancellation support
-	result, err := m.executeProcessing(ctx, data)
-	if err != nil {
-		m.status = StatusFailed
-		m.logger.Printf("Processing processing failed: %v", err)
-		return nil, fmt.Errorf("processing failed: %w", err)
-	}
-	
-	result.ProcessingTime = time.Since(start)
-	m.status = StatusCompleted
-	m.logger.Printf("Processing processing completed successfully")
-	
-	return result, nil
+	m.logger.Printf("Initialized processing manager with configuration")
 }
 
 // ProcessAsync executes processing processing asynchronously
@@ -154,9 +141,11 @@ func (m *Manager) ProcessAsync(ctx context.Context, data interface{}) <-chan *Re
 		
 		result, err := m.Process(ctx, data)
 		if err != nil {
+			id, _ := manager.OperationIDOf(err)
 			result = &Result{
-				Status:  "error",
-				Message: err.Error(),
+				Status:      "error",
+				Message:     err.Error(),
+				OperationID: id,
 			}
 		}
 		
@@ -173,11 +162,11 @@ func (m *Manager) ProcessAsync(ctx context.Context, data interface{}) <-chan *Re
 // Validate validates input data according to business rules
 func (m *Manager) Validate(data interface{}) error {
 	if data == nil {
-		m.logger.Printf("Validation failed: data is nil")
+		m.logger.Warnf("Validation failed: data is nil")
 		return fmt.Errorf("data cannot be nil")
 	}
 	
-	m.logger.Printf("Data validation passed")
+	m.logger.Debugf("Data validation passed")
 	return nil
 }
 
@@ -203,41 +192,11 @@ func (m *Manager) executeProcessing(ctx context.Context, data interface{}) (*Res
 	return result, nil
 }
 
-// GetStatus returns the current processing status
-func (m *Manager) GetStatus() Status {
-	m.mu.RLock()
-	defer m.mu.RUnlock()
-	return m.status
-}
-
-// Reset resets the manager to initial state
-func (m *Manager) Reset() {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-	
-	m.status = StatusPending
-	m.logger.Printf("Processing manager reset completed")
-}
-
 // GetConfig returns the current configuration
 func (m *Manager) GetConfig() *Config {
 	return m.config
 }
 
-// GetCreatedAt returns the creation timestamp
-func (m *Manager) GetCreatedAt() time.Time {
-	return m.createdAt
-}
-
-// Close performs cleanup operations
-func (m *Manager) Close() error {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-	
-	m.logger.Printf("Processing manager closing")
-	return nil
-}
-
 // Factory function to create processing manager with default configuration
 func CreateProcessingManager() *Manager {
 	return NewManager(DefaultConfig())
@@ -0,0 +1,118 @@
+package validation
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// BatchResult is the outcome of validating one item of a batch
+type BatchResult struct {
+	Index      int           `json:"index"`
+	Violations []Violation   `json:"violations,omitempty"`
+	Warnings   []Violation   `json:"warnings,omitempty"`
+	Duration   time.Duration `json:"duration"`
+	// Err is set when the item was not validated, e.g. because the context
+	// was cancelled before a worker reached it
+	Err error `json:"-"`
+}
+
+// Valid reports whether the item was validated without violations
+func (r BatchResult) Valid() bool {
+	return r.Err == nil && len(r.Violations) == 0
+}
+
+// Errors returns the item's violations as ValidationErrors, or nil when it
+// has none
+func (r BatchResult) Errors() error {
+	if len(r.Violations) == 0 {
+		return nil
+	}
+	return ValidationErrors(append([]Violation(nil), r.Violations...))
+}
+
+// batchOptions holds settings for ValidateBatch
+type batchOptions struct {
+	concurrency int
+}
+
+// BatchOption configures ValidateBatch
+type BatchOption func(*batchOptions)
+
+// WithBatchConcurrency limits how many items are validated at once; the
+// default is 8
+func WithBatchConcurrency(n int) BatchOption {
+	return func(o *batchOptions) {
+		o.concurrency = n
+	}
+}
+
+// ValidateBatch validates items concurrently on a bounded worker pool and
+// returns one result per item in input order. Cancelling ctx stops handing
+// out items: results already computed are kept and the remaining ones carry
+// the context error, so callers always get partial results
+func (m *Manager) ValidateBatch(ctx context.Context, items []interface{}, opts ...BatchOption) []BatchResult {
+	options := batchOptions{concurrency: 8}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.concurrency < 1 {
+		options.concurrency = 1
+	}
+	if options.concurrency > len(items) {
+		options.concurrency = len(items)
+	}
+
+	m.mu.RLock()
+	config := m.config
+	m.mu.RUnlock()
+
+	start := time.Now()
+	results := make([]BatchResult, len(items))
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < options.concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				itemStart := time.Now()
+				checked := m.check(ctx, items[i], config)
+				results[i] = BatchResult{Index: i, Violations: checked.Violations, Warnings: checked.Warnings, Duration: time.Since(itemStart)}
+			}
+		}()
+	}
+
+	next := 0
+dispatch:
+	for ; next < len(items); next++ {
+		// Check first so a cancelled context never wins a race against a
+		// ready worker in the select below
+		if ctx.Err() != nil {
+			break
+		}
+		select {
+		case indexes <- next:
+		case <-ctx.Done():
+			break dispatch
+		}
+	}
+	close(indexes)
+	wg.Wait()
+
+	for i := next; i < len(items); i++ {
+		results[i] = BatchResult{Index: i, Err: ctx.Err()}
+	}
+
+	invalid := 0
+	for _, r := range results {
+		if len(r.Violations) > 0 {
+			invalid++
+		}
+	}
+	if next < len(items) {
+		m.logger.Warnf("Batch validation cancelled after %d of %d item(s): %v", next, len(items), ctx.Err())
+	}
+	m.logger.Debugf("Validated batch of %d item(s) in %s: %d invalid", next, time.Since(start), invalid)
+	return results
+}
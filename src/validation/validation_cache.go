@@ -0,0 +1,160 @@
+package validation
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// ResultCacheStats reports how the result cache is used
+type ResultCacheStats struct {
+	Entries   int    `json:"entries"`
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+	Evictions uint64 `json:"evictions"`
+}
+
+// resultCache is an LRU of validation outcomes with a TTL
+type resultCache struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+	order      *list.List
+	entries    map[[sha256.Size]byte]*list.Element
+	stats      ResultCacheStats
+}
+
+// cachedResult is one cache entry
+type cachedResult struct {
+	key        [sha256.Size]byte
+	violations []Violation
+	warnings   []Violation
+	expires    time.Time
+}
+
+// EnableResultCache caches outcomes by a hash of the input's JSON encoding
+// and type, so validating an identical payload again skips the rules.
+// Entries live for ttl and the least recently used are evicted beyond
+// maxEntries. Outcomes depend on the rule sets, profile, locale and
+// fail-fast settings, which are part of the key; outcomes in which a remote
+// service was unavailable, or a Stateful rule ran, are not cached. Other
+// rules must be deterministic for the cache to be correct
+func (m *Manager) EnableResultCache(ttl time.Duration, maxEntries int) {
+	if maxEntries < 1 {
+		maxEntries = 1
+	}
+	m.rulesMu.Lock()
+	defer m.rulesMu.Unlock()
+	m.cache = &resultCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		order:      list.New(),
+		entries:    make(map[[sha256.Size]byte]*list.Element),
+	}
+	m.logger.Printf("Enabled result cache (ttl %s, max %d entries)", ttl, maxEntries)
+}
+
+// DisableResultCache drops the result cache
+func (m *Manager) DisableResultCache() {
+	m.rulesMu.Lock()
+	defer m.rulesMu.Unlock()
+	m.cache = nil
+}
+
+// ResultCacheStats returns the cache statistics; zero when disabled
+func (m *Manager) ResultCacheStats() ResultCacheStats {
+	m.rulesMu.RLock()
+	cache := m.cache
+	m.rulesMu.RUnlock()
+	if cache == nil {
+		return ResultCacheStats{}
+	}
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	stats := cache.stats
+	stats.Entries = cache.order.Len()
+	return stats
+}
+
+// resultKey hashes everything an outcome depends on, reporting false when
+// data cannot be encoded
+func resultKey(data interface{}, version uint64, profile, locale string, mode checkMode) ([sha256.Size]byte, bool) {
+	var encoded []byte
+	var err error
+	if msg, ok := data.(proto.Message); ok {
+		encoded, err = proto.MarshalOptions{Deterministic: true}.Marshal(msg)
+	} else {
+		encoded, err = json.Marshal(data)
+	}
+	if err != nil {
+		return [sha256.Size]byte{}, false
+	}
+	// The stateful flag is per check, not part of the outcome
+	mode.stateful = nil
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%d\x00%s\x00%s\x00%+v\x00", reflect.TypeOf(data), version, profile, locale, mode)
+	h.Write(encoded)
+	var key [sha256.Size]byte
+	copy(key[:], h.Sum(nil))
+	return key, true
+}
+
+// get returns a fresh cached outcome for key
+func (c *resultCache) get(key [sha256.Size]byte, now time.Time) (*Results, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	element, ok := c.entries[key]
+	if ok && now.After(element.Value.(*cachedResult).expires) {
+		c.order.Remove(element)
+		delete(c.entries, key)
+		ok = false
+	}
+	if !ok {
+		c.stats.Misses++
+		return nil, false
+	}
+	c.stats.Hits++
+	c.order.MoveToFront(element)
+	entry := element.Value.(*cachedResult)
+	return &Results{
+		Violations: append([]Violation(nil), entry.violations...),
+		Warnings:   append([]Violation(nil), entry.warnings...),
+	}, true
+}
+
+// put stores an outcome, evicting the least recently used beyond the limit
+func (c *resultCache) put(key [sha256.Size]byte, results *Results, now time.Time) {
+	for _, violations := range [][]Violation{results.Violations, results.Warnings} {
+		for _, v := range violations {
+			if v.Code == CodeUnavailable {
+				return
+			}
+		}
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry := &cachedResult{
+		key:        key,
+		violations: append([]Violation(nil), results.Violations...),
+		warnings:   append([]Violation(nil), results.Warnings...),
+		expires:    now.Add(c.ttl),
+	}
+	if element, ok := c.entries[key]; ok {
+		element.Value = entry
+		c.order.MoveToFront(element)
+		return
+	}
+	c.entries[key] = c.order.PushFront(entry)
+	for c.order.Len() > c.maxEntries {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cachedResult).key)
+		c.stats.Evictions++
+	}
+}
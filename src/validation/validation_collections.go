@@ -0,0 +1,100 @@
+package validation
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// CodeLimit is reported when a value is nested too deeply or has too many
+// elements to validate, as set by Config.MaxDepth and Config.MaxElements
+const CodeLimit = "limit"
+
+// element is one member of a collection and its path relative to it
+type element struct {
+	path  string
+	value interface{}
+}
+
+// Each applies rules to every element of a list, or every value of a map
+// in key order, reporting paths such as "[3].address.zip" or
+// "billing.zip" so that Field("items", Each(...)) yields
+// "items[3].address.zip". Nesting Each deeper than Config.MaxDepth or
+// descending into more than Config.MaxElements elements is a CodeLimit
+// violation instead. Nil values pass; combine with Required
+func Each(rules ...Rule) Rule {
+	return RuleFunc(func(ctx context.Context, value interface{}) []Violation {
+		rv := reflect.ValueOf(indirect(value))
+		switch rv.Kind() {
+		case reflect.Invalid:
+			return nil
+		case reflect.Slice, reflect.Array, reflect.Map:
+		default:
+			return []Violation{typeViolation("list or map", value)}
+		}
+
+		mode := modeOf(ctx)
+		if violation, ok := mode.exceeds(rv.Len()); ok {
+			return []Violation{violation}
+		}
+		mode.depth++
+		ctx = context.WithValue(ctx, checkModeKey{}, mode)
+
+		var violations []Violation
+		for _, e := range collectionElements(rv) {
+			found := All(rules...).Check(ctx, e.value)
+			for i := range found {
+				found[i].Path = joinPath(e.path, found[i].Path)
+			}
+			var stopped bool
+			if violations, stopped = mode.stop(append(violations, found...)); stopped {
+				break
+			}
+		}
+		return violations
+	})
+}
+
+// collectionElements lists the elements of a list, or the values of a map
+// in key order as Each visits them
+func collectionElements(rv reflect.Value) []element {
+	if rv.Kind() == reflect.Map {
+		keys := rv.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+		})
+		out := make([]element, len(keys))
+		for i, key := range keys {
+			out[i] = element{path: fmt.Sprint(key.Interface()), value: rv.MapIndex(key).Interface()}
+		}
+		return out
+	}
+	out := make([]element, rv.Len())
+	for i := range out {
+		out[i] = element{path: fmt.Sprintf("[%d]", i), value: rv.Index(i).Interface()}
+	}
+	return out
+}
+
+// exceeds reports a CodeLimit violation when descending one level into a
+// collection of n elements would break the configured limits
+func (c checkMode) exceeds(n int) (Violation, bool) {
+	if c.maxDepth > 0 && c.depth >= c.maxDepth {
+		return Violation{
+			Code:    CodeLimit,
+			Message: fmt.Sprintf("is nested deeper than %d levels", c.maxDepth),
+			Params:  map[string]interface{}{"max": c.maxDepth},
+			key:     "limit.depth",
+		}, true
+	}
+	if c.maxElements > 0 && n > c.maxElements {
+		return Violation{
+			Code:    CodeLimit,
+			Message: fmt.Sprintf("has more than %d elements", c.maxElements),
+			Params:  map[string]interface{}{"max": c.maxElements},
+			key:     "limit.elements",
+		}, true
+	}
+	return Violation{}, false
+}
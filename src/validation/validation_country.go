@@ -0,0 +1,591 @@
+package validation
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// CodeCountry is reported, as a warning, when a country-aware rule has no
+// format for the selected country, or no country is selected
+const CodeCountry = "country"
+
+// Kinds of country-specific formats
+const (
+	KindPostalCode = "postal_code"
+	KindNationalID = "national_id"
+	KindVAT        = "vat"
+	KindPhone      = "phone"
+)
+
+// countryKey carries the country of a validation in a context
+type countryKey struct{}
+
+// WithCountry returns a context whose country-aware rules without a country
+// of their own check values for country, an ISO 3166-1 alpha-2 code
+func WithCountry(ctx context.Context, country string) context.Context {
+	return context.WithValue(ctx, countryKey{}, strings.ToUpper(country))
+}
+
+// languageCountries maps languages spoken mainly in one country to it, for
+// locales without a region
+var languageCountries = map[string]string{
+	"cs": "CZ", "da": "DK", "de": "DE", "es": "ES", "fi": "FI", "fr": "FR",
+	"id": "ID", "it": "IT", "ja": "JP", "nl": "NL", "pl": "PL", "pt": "PT",
+	"sv": "SE", "zh": "CN",
+}
+
+// Country returns the country ctx selects: the one set with WithCountry,
+// else the region of its locale ("pt-BR" selects BR), else the main country
+// of its language ("de" selects DE), else ""
+func Country(ctx context.Context) string {
+	if country, _ := ctx.Value(countryKey{}).(string); country != "" {
+		return country
+	}
+	parts := strings.Split(normalizeLocale(Locale(ctx)), "-")
+	for _, part := range parts[1:] {
+		if len(part) == 2 {
+			return strings.ToUpper(part)
+		}
+	}
+	return languageCountries[parts[0]]
+}
+
+var (
+	countryFormatsMu sync.RWMutex
+	countryFormats   = map[string]map[string]FormatFunc{
+		KindPostalCode: {},
+		KindNationalID: {
+			"BR": isCPF,
+			"CN": isChineseResidentID,
+			"DE": isSteuerID,
+			"ES": isDNI,
+			"FR": isNIR,
+			"GB": isNINO,
+			"ID": isNIK,
+			"IT": matches(`^[A-Z]{6}\d{2}[A-EHLMPR-T]\d{2}[A-Z]\d{3}[A-Z]$`),
+			"NL": isBSN,
+			"SE": isPersonnummer,
+			"US": isSSN,
+		},
+		KindVAT:   {},
+		KindPhone: {},
+	}
+)
+
+func init() {
+	for country, pattern := range postalPatterns {
+		countryFormats[KindPostalCode][country] = matches(pattern)
+	}
+	for country, pattern := range vatPatterns {
+		countryFormats[KindVAT][country] = vatFormat(country, regexp.MustCompile(`^(?:`+pattern+`)$`))
+	}
+	for country, plan := range phonePlans {
+		plan := plan
+		countryFormats[KindPhone][country] = func(s string) bool {
+			_, ok := plan.normalize(s)
+			return ok
+		}
+	}
+}
+
+// RegisterCountryFormat makes check the format of kind (KindPostalCode,
+// KindNationalID, KindVAT, KindPhone or a kind of its own) for country,
+// replacing any built-in one
+func RegisterCountryFormat(kind, country string, check FormatFunc) {
+	countryFormatsMu.Lock()
+	defer countryFormatsMu.Unlock()
+	if countryFormats[kind] == nil {
+		countryFormats[kind] = make(map[string]FormatFunc)
+	}
+	countryFormats[kind][strings.ToUpper(country)] = check
+}
+
+// LookupCountryFormat returns the format of kind for country
+func LookupCountryFormat(kind, country string) (FormatFunc, bool) {
+	countryFormatsMu.RLock()
+	defer countryFormatsMu.RUnlock()
+	check, ok := countryFormats[kind][strings.ToUpper(country)]
+	return check, ok
+}
+
+// Countries returns the countries with a format of kind in sorted order
+func Countries(kind string) []string {
+	countryFormatsMu.RLock()
+	defer countryFormatsMu.RUnlock()
+	out := make([]string, 0, len(countryFormats[kind]))
+	for country := range countryFormats[kind] {
+		out = append(out, country)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// CountryFormat requires a string in the format of kind for country, or
+// for the country ctx selects when country is "". Empty and nil values
+// pass; it panics if country is set and has no such format
+func CountryFormat(kind, country string) Rule {
+	country = strings.ToUpper(country)
+	if country != "" {
+		if _, ok := LookupCountryFormat(kind, country); !ok {
+			panic(fmt.Sprintf("validation: no %s format for country %q", kind, country))
+		}
+	}
+	return RuleFunc(func(ctx context.Context, value interface{}) []Violation {
+		selected := country
+		if selected == "" {
+			selected = Country(ctx)
+		}
+		check, ok := LookupCountryFormat(kind, selected)
+		if !ok {
+			if s, isString := indirect(value).(string); isString && s == "" {
+				return nil
+			}
+			message := fmt.Sprintf("was not checked, no %s format for country %q", kindName(kind), selected)
+			if selected == "" {
+				message = "was not checked, no country selected"
+			}
+			return []Violation{{
+				Code:     CodeCountry,
+				Message:  message,
+				Severity: SeverityWarning,
+				Params:   map[string]interface{}{"kind": kind, "country": selected},
+			}}
+		}
+		violations := checkFormat(selected+" "+kindName(kind), check, value)
+		for i := range violations {
+			if violations[i].Code == CodeFormat {
+				violations[i].Params["kind"] = kind
+				violations[i].Params["country"] = selected
+			}
+		}
+		return violations
+	})
+}
+
+// PostalCode requires a postal code of country, e.g. PostalCode("DE") or
+// PostalCode("") for the country of the context
+func PostalCode(country string) Rule {
+	return CountryFormat(KindPostalCode, country)
+}
+
+// NationalID requires a national identification number of country, with
+// its check digits where it has them, e.g. an SSN for US or a DNI or NIE
+// for ES
+func NationalID(country string) Rule {
+	return CountryFormat(KindNationalID, country)
+}
+
+// VATNumber requires a VAT identification number of country, with or
+// without its country prefix ("DE123456789" or "123456789" for DE). Only
+// the format is checked; combine with a Remote rule against VIES to
+// check registration
+func VATNumber(country string) Rule {
+	return CountryFormat(KindVAT, country)
+}
+
+// PhoneNumber requires a phone number of region, either international
+// ("+49 30 1234567") or national with the region's trunk prefix
+// ("030 1234567"); see NormalizePhone
+func PhoneNumber(region string) Rule {
+	return CountryFormat(KindPhone, region)
+}
+
+// kindName describes a kind in messages
+func kindName(kind string) string {
+	switch kind {
+	case KindPostalCode:
+		return "postal code"
+	case KindNationalID:
+		return "national ID"
+	case KindVAT:
+		return "VAT number"
+	case KindPhone:
+		return "phone number"
+	}
+	return strings.ReplaceAll(kind, "_", " ")
+}
+
+// matches returns a case-insensitive format for pattern
+func matches(pattern string) FormatFunc {
+	re := regexp.MustCompile(pattern)
+	return func(s string) bool {
+		return re.MatchString(strings.ToUpper(strings.TrimSpace(s)))
+	}
+}
+
+// postalPatterns are the postal code formats by country, upper case
+var postalPatterns = map[string]string{
+	"AT": `^\d{4}$`,
+	"AU": `^\d{4}$`,
+	"BE": `^[1-9]\d{3}$`,
+	"BR": `^\d{5}-?\d{3}$`,
+	"CA": `^[ABCEGHJ-NPRSTVXY]\d[ABCEGHJ-NPRSTV-Z] ?\d[ABCEGHJ-NPRSTV-Z]\d$`,
+	"CH": `^[1-9]\d{3}$`,
+	"CN": `^\d{6}$`,
+	"CZ": `^\d{3} ?\d{2}$`,
+	"DE": `^\d{5}$`,
+	"DK": `^\d{4}$`,
+	"ES": `^(?:0[1-9]|[1-4]\d|5[0-2])\d{3}$`,
+	"FI": `^\d{5}$`,
+	"FR": `^\d{5}$`,
+	"GB": `^(?:GIR ?0AA|[A-Z]{1,2}\d[A-Z\d]? ?\d[A-Z]{2})$`,
+	"ID": `^[1-9]\d{4}$`,
+	"IE": `^(?:[AC-FHKNPRTV-Y]\d{2}|D6W) ?[0-9AC-FHKNPRTV-Y]{4}$`,
+	"IN": `^[1-9]\d{2} ?\d{3}$`,
+	"IT": `^\d{5}$`,
+	"JP": `^\d{3}-?\d{4}$`,
+	"MX": `^\d{5}$`,
+	"NL": `^[1-9]\d{3} ?[A-Z]{2}$`,
+	"NO": `^\d{4}$`,
+	"PL": `^\d{2}-\d{3}$`,
+	"PT": `^\d{4}-\d{3}$`,
+	"SE": `^\d{3} ?\d{2}$`,
+	"SG": `^\d{6}$`,
+	"US": `^\d{5}(?:-\d{4})?$`,
+}
+
+// vatPatterns are the VAT number formats by country, without the prefix
+var vatPatterns = map[string]string{
+	"AT": `U\d{8}`,
+	"BE": `[01]\d{9}`,
+	"CH": `E\d{9}(?:MWST|TVA|IVA)?`,
+	"CZ": `\d{8,10}`,
+	"DE": `\d{9}`,
+	"DK": `\d{8}`,
+	"ES": `[A-Z0-9]\d{7}[A-Z0-9]`,
+	"FI": `\d{8}`,
+	"FR": `[0-9A-HJ-NP-Z]{2}\d{9}`,
+	"GB": `\d{9}|\d{12}|GD\d{3}|HA\d{3}`,
+	"GR": `\d{9}`,
+	"IE": `\d{7}[A-W][A-I]?|\d[A-Z+*]\d{5}[A-W]`,
+	"IT": `\d{11}`,
+	"NL": `\d{9}B\d{2}`,
+	"NO": `\d{9}(?:MVA)?`,
+	"PL": `\d{10}`,
+	"PT": `\d{9}`,
+	"SE": `\d{10}01`,
+}
+
+// vatPrefixes are VAT prefixes that differ from the country code
+var vatPrefixes = map[string]string{"GR": "EL", "CH": "CHE"}
+
+// vatFormat checks a VAT number of country against re after removing
+// separators and the optional country prefix
+func vatFormat(country string, re *regexp.Regexp) FormatFunc {
+	prefix := country
+	if p, ok := vatPrefixes[country]; ok {
+		prefix = p
+	}
+	return func(s string) bool {
+		s = strings.Map(func(r rune) rune {
+			if r == ' ' || r == '.' || r == '-' {
+				return -1
+			}
+			return r
+		}, strings.ToUpper(s))
+		if country == "CH" {
+			// Swiss numbers keep the E of CHE as part of the pattern
+			s = strings.TrimPrefix(s, "CH")
+		} else {
+			s = strings.TrimPrefix(s, prefix)
+		}
+		return re.MatchString(s)
+	}
+}
+
+// phonePlan is the numbering plan of a region
+type phonePlan struct {
+	callingCode string
+	// trunk is the prefix dialled before national numbers, if any
+	trunk string
+	// national matches the national significant number
+	national *regexp.Regexp
+}
+
+// phonePlans are the numbering plans by region, libphonenumber style but
+// limited to the length and leading digits of the significant number
+var phonePlans = map[string]phonePlan{
+	"AT": {"43", "0", regexp.MustCompile(`^[1-9]\d{3,12}$`)},
+	"AU": {"61", "0", regexp.MustCompile(`^[2-478]\d{8}$`)},
+	"BE": {"32", "0", regexp.MustCompile(`^[1-9]\d{7,8}$`)},
+	"BR": {"55", "0", regexp.MustCompile(`^[1-9]{2}\d{8,9}$`)},
+	"CA": {"1", "1", regexp.MustCompile(`^[2-9]\d{2}[2-9]\d{6}$`)},
+	"CH": {"41", "0", regexp.MustCompile(`^[1-9]\d{8}$`)},
+	"CN": {"86", "0", regexp.MustCompile(`^[1-9]\d{7,10}$`)},
+	"DE": {"49", "0", regexp.MustCompile(`^[1-9]\d{4,14}$`)},
+	"ES": {"34", "", regexp.MustCompile(`^[5-9]\d{8}$`)},
+	"FR": {"33", "0", regexp.MustCompile(`^[1-9]\d{8}$`)},
+	"GB": {"44", "0", regexp.MustCompile(`^[1-9]\d{8,9}$`)},
+	"ID": {"62", "0", regexp.MustCompile(`^[1-9]\d{7,11}$`)},
+	"IN": {"91", "0", regexp.MustCompile(`^[1-9]\d{9}$`)},
+	"IT": {"39", "", regexp.MustCompile(`^[03]\d{5,10}$`)},
+	"JP": {"81", "0", regexp.MustCompile(`^[1-9]\d{8,9}$`)},
+	"MX": {"52", "", regexp.MustCompile(`^[1-9]\d{9}$`)},
+	"NL": {"31", "0", regexp.MustCompile(`^[1-9]\d{8}$`)},
+	"SE": {"46", "0", regexp.MustCompile(`^[1-9]\d{6,9}$`)},
+	"SG": {"65", "", regexp.MustCompile(`^[3689]\d{7}$`)},
+	"US": {"1", "1", regexp.MustCompile(`^[2-9]\d{2}[2-9]\d{6}$`)},
+}
+
+// normalize returns s in E.164 form if it is a valid number of the plan
+func (p phonePlan) normalize(s string) (string, bool) {
+	s = strings.TrimSpace(s)
+	international := strings.HasPrefix(s, "+") || strings.HasPrefix(s, "00")
+	var digits strings.Builder
+	for i, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			digits.WriteRune(r)
+		case r == '+' && i == 0, r == ' ', r == '-', r == '.', r == '(', r == ')', r == '/':
+		default:
+			return "", false
+		}
+	}
+	number := digits.String()
+	if international {
+		number = strings.TrimPrefix(number, "00")
+		if !strings.HasPrefix(number, p.callingCode) {
+			return "", false
+		}
+		number = number[len(p.callingCode):]
+	} else if p.trunk != "" {
+		number = strings.TrimPrefix(number, p.trunk)
+	}
+	if !p.national.MatchString(number) {
+		return "", false
+	}
+	return "+" + p.callingCode + number, true
+}
+
+// NormalizePhone returns number, a phone number of region, in E.164 form,
+// e.g. "+493012345678" for "030 1234 5678" in DE
+func NormalizePhone(number, region string) (string, error) {
+	plan, ok := phonePlans[strings.ToUpper(region)]
+	if !ok {
+		return "", fmt.Errorf("no numbering plan for region %q", region)
+	}
+	normalized, ok := plan.normalize(number)
+	if !ok {
+		return "", fmt.Errorf("not a valid %s phone number", strings.ToUpper(region))
+	}
+	return normalized, nil
+}
+
+// digitsOf returns the digits of s when it only has digits and the given
+// separators
+func digitsOf(s, separators string) (string, bool) {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r >= '0' && r <= '9':
+			b.WriteRune(r)
+		case strings.ContainsRune(separators, r):
+		default:
+			return "", false
+		}
+	}
+	return b.String(), true
+}
+
+// isSSN reports whether s is a US social security number that may have
+// been issued
+func isSSN(s string) bool {
+	d, ok := digitsOf(s, "- ")
+	if !ok || len(d) != 9 {
+		return false
+	}
+	area, group, serial := d[:3], d[3:5], d[5:]
+	return area != "000" && area != "666" && area[0] != '9' && group != "00" && serial != "0000"
+}
+
+// isNINO reports whether s is a UK National Insurance number
+func isNINO(s string) bool {
+	s = strings.ReplaceAll(strings.ToUpper(s), " ", "")
+	if !ninoPattern.MatchString(s) {
+		return false
+	}
+	switch s[:2] {
+	case "BG", "GB", "NK", "KN", "TN", "NT", "ZZ":
+		return false
+	}
+	return true
+}
+
+var ninoPattern = regexp.MustCompile(`^[A-CEGHJ-PR-TW-Z][A-CEGHJ-NPR-TW-Z]\d{6}[A-D]$`)
+
+// isDNI reports whether s is a Spanish DNI or NIE with a valid letter
+func isDNI(s string) bool {
+	s = strings.ReplaceAll(strings.ToUpper(s), "-", "")
+	if len(s) != 9 {
+		return false
+	}
+	number := s[:8]
+	switch number[0] {
+	case 'X', 'Y', 'Z':
+		number = string('0'+number[0]-'X') + number[1:]
+	}
+	n, err := strconv.Atoi(number)
+	if err != nil {
+		return false
+	}
+	return "TRWAGMYFPDXBNJZSQVHLCKE"[n%23] == s[8]
+}
+
+// isBSN reports whether s is a Dutch citizen service number (11-proof)
+func isBSN(s string) bool {
+	d, ok := digitsOf(s, ". ")
+	if !ok || len(d) != 9 {
+		return false
+	}
+	sum := 0
+	for i := 0; i < 8; i++ {
+		sum += int(d[i]-'0') * (9 - i)
+	}
+	sum -= int(d[8] - '0')
+	return sum%11 == 0 && d != "000000000"
+}
+
+// isCPF reports whether s is a Brazilian CPF with valid check digits
+func isCPF(s string) bool {
+	d, ok := digitsOf(s, ".-")
+	if !ok || len(d) != 11 || strings.Count(d, d[:1]) == 11 {
+		return false
+	}
+	for n := 9; n <= 10; n++ {
+		sum := 0
+		for i := 0; i < n; i++ {
+			sum += int(d[i]-'0') * (n + 1 - i)
+		}
+		check := sum * 10 % 11 % 10
+		if check != int(d[n]-'0') {
+			return false
+		}
+	}
+	return true
+}
+
+// isChineseResidentID reports whether s is an 18-character resident
+// identity card number with a valid ISO 7064 check character
+func isChineseResidentID(s string) bool {
+	s = strings.ToUpper(s)
+	if len(s) != 18 {
+		return false
+	}
+	weights := []int{7, 9, 10, 5, 8, 4, 2, 1, 6, 3, 7, 9, 10, 5, 8, 4, 2}
+	sum := 0
+	for i, w := range weights {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+		sum += int(s[i]-'0') * w
+	}
+	return "10X98765432"[sum%11] == s[17]
+}
+
+// isSteuerID reports whether s is a German tax identification number with
+// a valid ISO 7064 MOD 11,10 check digit
+func isSteuerID(s string) bool {
+	d, ok := digitsOf(s, " ")
+	if !ok || len(d) != 11 || d[0] == '0' {
+		return false
+	}
+	product := 10
+	for i := 0; i < 10; i++ {
+		sum := (int(d[i]-'0') + product) % 10
+		if sum == 0 {
+			sum = 10
+		}
+		product = sum * 2 % 11
+	}
+	check := 11 - product
+	if check == 10 {
+		check = 0
+	}
+	return check == int(d[10]-'0')
+}
+
+// isNIR reports whether s is a French social security number with a valid
+// key, Corsican departments 2A and 2B included
+func isNIR(s string) bool {
+	s = strings.ToUpper(strings.ReplaceAll(s, " ", ""))
+	if len(s) != 15 {
+		return false
+	}
+	body := s[:13]
+	offset := 0
+	switch body[5:7] {
+	case "2A":
+		body, offset = body[:5]+"19"+body[7:], 1000000
+	case "2B":
+		body, offset = body[:5]+"18"+body[7:], 2000000
+	}
+	n, err := strconv.ParseInt(body, 10, 64)
+	if err != nil || (s[0] != '1' && s[0] != '2') {
+		return false
+	}
+	key, err := strconv.Atoi(s[13:])
+	if err != nil {
+		return false
+	}
+	return 97-(n-int64(offset))%97 == int64(key)
+}
+
+// isNIK reports whether s is an Indonesian population identity number
+// with a plausible birth date (women add 40 to the day)
+func isNIK(s string) bool {
+	d, ok := digitsOf(s, " ")
+	if !ok || len(d) != 16 {
+		return false
+	}
+	day, _ := strconv.Atoi(d[6:8])
+	month, _ := strconv.Atoi(d[8:10])
+	if day > 40 {
+		day -= 40
+	}
+	return day >= 1 && day <= 31 && month >= 1 && month <= 12
+}
+
+// isPersonnummer reports whether s is a Swedish personal identity number
+// (YYMMDD-NNNC or with the century) with a valid Luhn check digit
+func isPersonnummer(s string) bool {
+	d, ok := digitsOf(s, "-+")
+	if !ok {
+		return false
+	}
+	if len(d) == 12 {
+		d = d[2:]
+	}
+	if len(d) != 10 {
+		return false
+	}
+	return luhn(d)
+}
+
+// luhn reports whether the digits d pass the Luhn check
+func luhn(d string) bool {
+	sum := 0
+	for i := len(d) - 1; i >= 0; i-- {
+		n := int(d[i] - '0')
+		if (len(d)-i)%2 == 0 {
+			n *= 2
+			if n > 9 {
+				n -= 9
+			}
+		}
+		sum += n
+	}
+	return sum%10 == 0
+}
+
+// isCountryKind reports whether kind has country formats
+func isCountryKind(kind string) bool {
+	countryFormatsMu.RLock()
+	defer countryFormatsMu.RUnlock()
+	_, ok := countryFormats[kind]
+	return ok
+}
@@ -0,0 +1,228 @@
+package validation
+
+import (
+	"context"
+	"strings"
+	"unicode"
+)
+
+const (
+	// CodePassword is reported for passwords violating a CredentialPolicy,
+	// with variants such as "password.upper" or "password.identity"
+	CodePassword = "password"
+	// CodeReserved is reported for names that may not be taken, such as a
+	// reserved username
+	CodeReserved = "reserved"
+)
+
+// ProfileCredentials is the profile of the rule set installed by
+// CredentialsPack, e.g. for m.ProcessWithProfile(ctx, ProfileCredentials, body)
+const ProfileCredentials = "credentials"
+
+// CredentialPolicy describes acceptable usernames, emails and passwords.
+// The authentication package checks credentials with it and API payloads
+// are checked by CredentialsPack with the same policy, so the two agree
+type CredentialPolicy struct {
+	PasswordMinLength int  `json:"password_min_length"`
+	PasswordMaxLength int  `json:"password_max_length"`
+	RequireUpper      bool `json:"require_upper"`
+	RequireLower      bool `json:"require_lower"`
+	RequireDigit      bool `json:"require_digit"`
+	RequireSymbol     bool `json:"require_symbol"`
+	// ForbidIdentity rejects passwords containing the username or the local
+	// part of the email
+	ForbidIdentity bool `json:"forbid_identity"`
+	// DeniedPasswords are rejected whatever their case, e.g. leaked ones
+	DeniedPasswords   []string `json:"denied_passwords,omitempty"`
+	UsernameMinLength int      `json:"username_min_length"`
+	UsernameMaxLength int      `json:"username_max_length"`
+	UsernamePattern   string   `json:"username_pattern"`
+	ReservedUsernames []string `json:"reserved_usernames,omitempty"`
+	RequireUsername   bool     `json:"require_username"`
+	RequireEmail      bool     `json:"require_email"`
+}
+
+// DefaultCredentialPolicy returns a policy following NIST SP 800-63B:
+// passwords of 8 to 128 characters without composition rules
+func DefaultCredentialPolicy() *CredentialPolicy {
+	return &CredentialPolicy{
+		PasswordMinLength: 8,
+		PasswordMaxLength: 128,
+		ForbidIdentity:    true,
+		UsernameMinLength: 3,
+		UsernameMaxLength: 32,
+		UsernamePattern:   `^[A-Za-z0-9][A-Za-z0-9._-]*$`,
+		ReservedUsernames: []string{"admin", "administrator", "root", "system", "support"},
+		RequireUsername:   true,
+	}
+}
+
+// Password checks a password against policy, nil meaning the default; the
+// value is marked sensitive. Empty values pass; combine with Required
+func Password(policy *CredentialPolicy) Rule {
+	if policy == nil {
+		policy = DefaultCredentialPolicy()
+	}
+	denied := make(map[string]bool, len(policy.DeniedPasswords))
+	for _, p := range policy.DeniedPasswords {
+		denied[strings.ToLower(p)] = true
+	}
+	classes := []struct {
+		required bool
+		key      string
+		message  string
+		is       func(rune) bool
+	}{
+		{policy.RequireUpper, "password.upper", "must contain an uppercase letter", unicode.IsUpper},
+		{policy.RequireLower, "password.lower", "must contain a lowercase letter", unicode.IsLower},
+		{policy.RequireDigit, "password.digit", "must contain a digit", unicode.IsDigit},
+		{policy.RequireSymbol, "password.symbol", "must contain a symbol", isSymbol},
+	}
+	maxLength := policy.PasswordMaxLength
+	if maxLength <= 0 {
+		maxLength = -1
+	}
+	return Sensitive(When(Required(),
+		Length(policy.PasswordMinLength, maxLength),
+		RuleFunc(func(ctx context.Context, value interface{}) []Violation {
+			s, ok := indirect(value).(string)
+			if !ok || s == "" {
+				return nil
+			}
+			var violations []Violation
+			for _, class := range classes {
+				if class.required && strings.IndexFunc(s, class.is) < 0 {
+					violations = append(violations, Violation{Code: CodePassword, Message: class.message, key: class.key})
+				}
+			}
+			if denied[strings.ToLower(s)] {
+				violations = append(violations, Violation{Code: CodePassword, Message: "is too common", key: "password.common"})
+			}
+			return violations
+		}),
+	))
+}
+
+// isSymbol reports whether r is neither a letter, a digit nor a space
+func isSymbol(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !unicode.IsSpace(r)
+}
+
+// Username checks a username against policy, nil meaning the default;
+// reserved names are compared case-insensitively. Empty values pass
+func Username(policy *CredentialPolicy) Rule {
+	if policy == nil {
+		policy = DefaultCredentialPolicy()
+	}
+	reserved := make(map[string]bool, len(policy.ReservedUsernames))
+	for _, name := range policy.ReservedUsernames {
+		reserved[strings.ToLower(name)] = true
+	}
+	maxLength := policy.UsernameMaxLength
+	if maxLength <= 0 {
+		maxLength = -1
+	}
+	rules := []Rule{Length(policy.UsernameMinLength, maxLength)}
+	if policy.UsernamePattern != "" {
+		rules = append(rules, Pattern(policy.UsernamePattern))
+	}
+	rules = append(rules, Predicate(CodeReserved, "is reserved", func(value interface{}) bool {
+		s, _ := indirect(value).(string)
+		return !reserved[strings.ToLower(s)]
+	}))
+	return When(Required(), rules...)
+}
+
+// PasswordNotContaining rejects a password under passwordPath containing
+// the username or email local part under one of identityPaths; parts
+// shorter than three characters are ignored
+func PasswordNotContaining(passwordPath string, identityPaths ...string) Rule {
+	return RuleFunc(func(ctx context.Context, value interface{}) []Violation {
+		raw, _ := Lookup(value, passwordPath)
+		password, ok := indirect(raw).(string)
+		if !ok || password == "" {
+			return nil
+		}
+		password = strings.ToLower(password)
+		for _, path := range identityPaths {
+			raw, _ := Lookup(value, path)
+			identity, _ := indirect(raw).(string)
+			if at := strings.LastIndex(identity, "@"); at >= 0 {
+				identity = identity[:at]
+			}
+			if len(identity) >= 3 && strings.Contains(password, strings.ToLower(identity)) {
+				return []Violation{{
+					Path:      passwordPath,
+					Code:      CodePassword,
+					Message:   "must not contain the " + path,
+					Params:    map[string]interface{}{"field": path},
+					key:       "password.identity",
+					sensitive: true,
+				}}
+			}
+		}
+		return nil
+	})
+}
+
+// CredentialRules returns the rule set "credentials" checking the
+// username, email and password fields of a value against policy, nil
+// meaning the default, under ProfileCredentials
+func CredentialRules(policy *CredentialPolicy) *RuleSet {
+	if policy == nil {
+		policy = DefaultCredentialPolicy()
+	}
+	username := []Rule{Username(policy)}
+	if policy.RequireUsername {
+		username = append([]Rule{Required()}, username...)
+	}
+	email := []Rule{When(Required(), Length(3, 254), Format(FormatEmail))}
+	if policy.RequireEmail {
+		email = append([]Rule{Required()}, email...)
+	}
+	set := NewRuleSet("credentials").
+		Field("username", username...).
+		Field("email", email...).
+		Field("password", Required(), Password(policy)).
+		Profiles(ProfileCredentials)
+	if policy.ForbidIdentity {
+		set.Add(PasswordNotContaining("password", "username", "email"))
+	}
+	return set
+}
+
+// credentialsPack is the RulePack returned by CredentialsPack
+type credentialsPack struct {
+	policy *CredentialPolicy
+}
+
+// CredentialsPack returns the pack "credentials" installing CredentialRules
+// for policy, nil meaning the default. A pack with the default policy is
+// registered, so LoadRulePacks("credentials") installs it; applications
+// with their own policy install CredentialsPack(policy) instead, or the
+// pack of their authentication manager
+func CredentialsPack(policy *CredentialPolicy) RulePack {
+	return credentialsPack{policy: policy}
+}
+
+// Name implements RulePack
+func (p credentialsPack) Name() string {
+	return "credentials"
+}
+
+// Version implements RulePack
+func (p credentialsPack) Version() string {
+	return "1.0.0"
+}
+
+// Install implements RulePack
+func (p credentialsPack) Install(r *PackRegistrar) error {
+	r.AddRuleSets(CredentialRules(p.policy))
+	return nil
+}
+
+func init() {
+	if err := RegisterRulePack(CredentialsPack(nil)); err != nil {
+		panic(err)
+	}
+}
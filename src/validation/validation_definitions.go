@@ -0,0 +1,297 @@
+package validation
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"sync"
+)
+
+// RulesDocument is the configuration section declaring rule sets, e.g.
+//
+//	validation:
+//	  rule_sets:
+//	    - name: signup
+//	      type: User
+//	      profiles: [create]
+//	      fields:
+//	        - path: email
+//	          required: true
+//	          format: email
+//	        - path: age
+//	          range: {min: 18}
+//	          severity: warning
+type RulesDocument struct {
+	RuleSets []RuleSetDefinition `json:"rule_sets"`
+}
+
+// RuleSetDefinition declares a rule set
+type RuleSetDefinition struct {
+	Name string `json:"name"`
+	// Type restricts the set to values whose type is named so, either bare
+	// ("User") or package-qualified ("model.User")
+	Type     string            `json:"type,omitempty"`
+	Profiles []string          `json:"profiles,omitempty"`
+	Severity Severity          `json:"severity,omitempty"`
+	Fields   []FieldDefinition `json:"fields"`
+	// Discriminator is the path of the field selecting one of Branches,
+	// whose fields then apply too, see Discriminated
+	Discriminator string                       `json:"discriminator,omitempty"`
+	Branches      map[string][]FieldDefinition `json:"branches,omitempty"`
+}
+
+// FieldDefinition declares the constraints on the value under Path; they
+// are checked in the order required, length, range, pattern, one_of,
+// format, expr. An empty path applies them to the whole value, e.g. an
+// expr such as "amount <= limit" reported with Message
+type FieldDefinition struct {
+	Path     string        `json:"path"`
+	Required bool          `json:"required,omitempty"`
+	Length   *Bounds       `json:"length,omitempty"`
+	Range    *Bounds       `json:"range,omitempty"`
+	Pattern  string        `json:"pattern,omitempty"`
+	OneOf    []interface{} `json:"one_of,omitempty"`
+	Format   string        `json:"format,omitempty"`
+	Expr     string        `json:"expr,omitempty"`
+	Message  string        `json:"message,omitempty"`
+	Severity Severity      `json:"severity,omitempty"`
+	// Sensitive masks the value in violations, see Manager.SetMasking
+	Sensitive bool `json:"sensitive,omitempty"`
+	// Country selects the country of a country format (postal_code,
+	// national_id, vat or phone); "" uses the country of the context
+	Country string `json:"country,omitempty"`
+	// Step names the constraints so that other fields can run After them
+	Step  string   `json:"step,omitempty"`
+	After []string `json:"after,omitempty"`
+}
+
+// Bounds is an inclusive interval; a missing bound is unbounded
+type Bounds struct {
+	Min *float64 `json:"min,omitempty"`
+	Max *float64 `json:"max,omitempty"`
+}
+
+// CompileRules turns definitions into rule sets, reporting the first
+// invalid definition (a malformed path, a bad pattern, an unknown format or
+// a duplicate name)
+func CompileRules(definitions []RuleSetDefinition) ([]*RuleSet, error) {
+	sets := make([]*RuleSet, 0, len(definitions))
+	names := make(map[string]bool)
+	for i, def := range definitions {
+		if def.Name == "" {
+			return nil, fmt.Errorf("rule set %d: a name is required", i)
+		}
+		if names[def.Name] {
+			return nil, fmt.Errorf("rule set %q: defined twice", def.Name)
+		}
+		names[def.Name] = true
+
+		set := NewRuleSet(def.Name).Profiles(def.Profiles...)
+		set.typeName = def.Type
+		if err := compileFields(set, def.Fields, def.Severity); err != nil {
+			return nil, fmt.Errorf("rule set %q: %w", def.Name, err)
+		}
+		switch {
+		case def.Discriminator != "":
+			if err := CheckPath(def.Discriminator); err != nil {
+				return nil, fmt.Errorf("rule set %q: discriminator: %w", def.Name, err)
+			}
+			if len(def.Branches) == 0 {
+				return nil, fmt.Errorf("rule set %q: discriminator %q has no branches", def.Name, def.Discriminator)
+			}
+			branches := make(Branches, len(def.Branches))
+			for value, fields := range def.Branches {
+				branch := NewRuleSet(def.Name + "/" + value)
+				if err := compileFields(branch, fields, def.Severity); err != nil {
+					return nil, fmt.Errorf("rule set %q: branch %q: %w", def.Name, value, err)
+				}
+				if _, err := branch.Plan(); err != nil {
+					return nil, fmt.Errorf("rule set %q: branch %q: %w", def.Name, value, err)
+				}
+				branches[value] = branch
+			}
+			set.Add(Discriminated(def.Discriminator, branches))
+		case len(def.Branches) > 0:
+			return nil, fmt.Errorf("rule set %q: branches require a discriminator", def.Name)
+		}
+		if _, err := set.Plan(); err != nil {
+			return nil, fmt.Errorf("rule set %q: %w", def.Name, err)
+		}
+		sets = append(sets, set)
+	}
+	return sets, nil
+}
+
+// compileFields adds the rules of fields to set
+func compileFields(set *RuleSet, fields []FieldDefinition, severity Severity) error {
+	for _, field := range fields {
+		if err := CheckPath(field.Path); err != nil {
+			return err
+		}
+		rules, err := field.compile()
+		if err != nil {
+			return fmt.Errorf("field %q: %w", field.Path, err)
+		}
+		if field.Severity == SeverityWarning || severity == SeverityWarning {
+			rules = []Rule{Warn(rules...)}
+		}
+		if field.Sensitive {
+			rules = []Rule{Sensitive(rules...)}
+		}
+		switch {
+		case field.Step != "":
+			set.Step(field.Step, Field(field.Path, rules...), field.After...)
+		case len(field.After) > 0:
+			return fmt.Errorf("field %q: after requires a step name", field.Path)
+		default:
+			set.Field(field.Path, rules...)
+		}
+	}
+	return nil
+}
+
+// compile builds the rules of one field definition
+func (f FieldDefinition) compile() ([]Rule, error) {
+	var rules []Rule
+	if f.Required {
+		rules = append(rules, Required())
+	}
+	if f.Length != nil {
+		min, max := 0, -1
+		if f.Length.Min != nil {
+			min = int(*f.Length.Min)
+		}
+		if f.Length.Max != nil {
+			max = int(*f.Length.Max)
+		}
+		if max >= 0 && min > max {
+			return nil, fmt.Errorf("length: min %d exceeds max %d", min, max)
+		}
+		rules = append(rules, Length(min, max))
+	}
+	if f.Range != nil {
+		min, max := math.Inf(-1), math.Inf(1)
+		if f.Range.Min != nil {
+			min = *f.Range.Min
+		}
+		if f.Range.Max != nil {
+			max = *f.Range.Max
+		}
+		if min > max {
+			return nil, fmt.Errorf("range: min %v exceeds max %v", min, max)
+		}
+		rules = append(rules, Range(min, max))
+	}
+	if f.Pattern != "" {
+		if _, err := regexp.Compile(f.Pattern); err != nil {
+			return nil, fmt.Errorf("pattern: %w", err)
+		}
+		rules = append(rules, Pattern(f.Pattern))
+	}
+	if len(f.OneOf) > 0 {
+		rules = append(rules, OneOf(f.OneOf...))
+	}
+	if f.Format != "" {
+		switch {
+		case isCountryKind(f.Format):
+			if _, ok := LookupCountryFormat(f.Format, f.Country); f.Country != "" && !ok {
+				return nil, fmt.Errorf("no %s format for country %q", f.Format, f.Country)
+			}
+			rules = append(rules, CountryFormat(f.Format, f.Country))
+		case f.Country != "":
+			return nil, fmt.Errorf("format %q does not take a country", f.Format)
+		default:
+			if _, ok := LookupFormat(f.Format); !ok {
+				return nil, fmt.Errorf("unknown format %q", f.Format)
+			}
+			rules = append(rules, Format(f.Format))
+		}
+	}
+	if f.Expr != "" {
+		if _, err := CompileExpression(f.Expr); err != nil {
+			return nil, err
+		}
+		message := f.Message
+		if message == "" {
+			message = "must satisfy " + f.Expr
+		}
+		rules = append(rules, Expr(f.Expr, message))
+	}
+	if len(rules) == 0 {
+		return nil, fmt.Errorf("no constraints")
+	}
+	return rules, nil
+}
+
+// matchesTypeName reports whether t is named name, bare or package-qualified
+func matchesTypeName(t reflect.Type, name string) bool {
+	return t != nil && (t.Name() == name || t.String() == name)
+}
+
+// RuleSource is a configuration store that decodes a section into a value
+// and applies it again whenever the section changes, such as
+// *configuration.Manager
+type RuleSource interface {
+	BindSection(key string, newTarget func() interface{}, apply func(v interface{})) (func(), error)
+}
+
+// LoadRules registers the rule sets declared in the RulesDocument under key
+// of source and replaces them whenever the section changes, so rules can
+// be edited without recompiling. An invalid initial section is an error;
+// an invalid update is logged and the previous rules stay in force. The
+// returned function stops following changes
+func (m *Manager) LoadRules(source RuleSource, key string) (func(), error) {
+	var mu sync.Mutex
+	var initial error
+	loading := true
+	stop, err := source.BindSection(key, func() interface{} { return &RulesDocument{} }, func(v interface{}) {
+		mu.Lock()
+		defer mu.Unlock()
+		err := m.replaceRules(key, v.(*RulesDocument).RuleSets)
+		if loading {
+			initial = err
+		} else if err != nil {
+			m.logger.Warnf("Keeping previous rules from %s: %v", key, err)
+		}
+	})
+	mu.Lock()
+	loading = false
+	mu.Unlock()
+	if err != nil {
+		return nil, fmt.Errorf("load rules from %s: %w", key, err)
+	}
+	if initial != nil {
+		stop()
+		return nil, fmt.Errorf("load rules from %s: %w", key, initial)
+	}
+	return stop, nil
+}
+
+// replaceRules swaps the rule sets loaded from origin for definitions
+func (m *Manager) replaceRules(origin string, definitions []RuleSetDefinition) error {
+	sets, err := CompileRules(definitions)
+	if err != nil {
+		return err
+	}
+	m.rulesMu.Lock()
+	defer m.rulesMu.Unlock()
+	kept := make([]*RuleSet, 0, len(m.ruleSets)+len(sets))
+	for _, set := range m.ruleSets {
+		if set.origin != origin {
+			kept = append(kept, set)
+		}
+	}
+	for _, set := range sets {
+		for _, existing := range kept {
+			if existing.name == set.name {
+				return fmt.Errorf("rule set %q: already registered", set.name)
+			}
+		}
+		set.origin = origin
+	}
+	m.ruleSets = append(kept, sets...)
+	m.rulesVersion++
+	m.logger.Printf("Loaded %d rule set(s) from %s", len(sets), origin)
+	return nil
+}
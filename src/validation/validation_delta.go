@@ -0,0 +1,112 @@
+package validation
+
+import (
+	"context"
+	"reflect"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// alwaysRule is the Rule returned by Always
+type alwaysRule struct {
+	rules []Rule
+}
+
+// Always marks rules that ValidateDelta runs even when nothing they look
+// at changed, e.g. checks against the current time or an external store
+func Always(rules ...Rule) Rule {
+	return alwaysRule{rules: rules}
+}
+
+// Check implements Rule
+func (a alwaysRule) Check(ctx context.Context, value interface{}) []Violation {
+	return All(a.rules...).Check(ctx, value)
+}
+
+// ValidateDelta validates updated, a new version of old, running only the
+// rules the change can affect: Field rules whose value differs between the
+// two, other rules of the set when anything changed, and Always rules
+// every time. It is meant for high-frequency update paths; when old is nil
+// every rule runs, as with Check. Results are not cached
+func (m *Manager) ValidateDelta(ctx context.Context, old, updated interface{}) *Results {
+	return m.hooked(ctx, updated, func(ctx context.Context) *Results {
+		return m.validateDelta(ctx, old, updated)
+	})
+}
+
+// validateDelta implements ValidateDelta
+func (m *Manager) validateDelta(ctx context.Context, old, updated interface{}) *Results {
+	m.mu.RLock()
+	config := m.config
+	m.mu.RUnlock()
+	if old == nil || updated == nil {
+		return m.checkData(ctx, updated, config)
+	}
+
+	m.rulesMu.RLock()
+	sets := append([]*RuleSet(nil), m.ruleSets...)
+	catalog := m.catalog
+	m.rulesMu.RUnlock()
+	profile := Profile(ctx)
+	mode := newCheckMode(config)
+	ctx = m.instrument(context.WithValue(ctx, checkModeKey{}, mode))
+
+	before, after := old, updated
+	var violations []Violation
+	if msg, ok := updated.(proto.Message); ok {
+		var options Rule
+		if after, options = messageInput(msg); after == nil {
+			return m.checkData(ctx, updated, config)
+		}
+		if violations = mode.checkLimits(after); violations != nil {
+			return mode.results(catalog, Locale(ctx), violations)
+		}
+		if previous, ok := old.(proto.Message); ok {
+			before, _ = messageInput(previous)
+		}
+		if !reflect.DeepEqual(before, after) {
+			violations, _ = mode.stop(options.Check(ctx, after))
+		}
+	} else if violations = mode.checkLimits(after); violations != nil {
+		return mode.results(catalog, Locale(ctx), violations)
+	}
+	changed := !reflect.DeepEqual(before, after)
+
+	run, total := 0, 0
+	stopped := mode.failFast && len(violations) > 0 && mode.blocks(violations[len(violations)-1])
+	for _, set := range sets {
+		if stopped {
+			break
+		}
+		if !set.AppliesTo(updated) || !set.inProfile(profile) {
+			continue
+		}
+		violations, stopped = mode.stop(append(violations, set.run(ctx, after, func(rule Rule) bool {
+			total++
+			if !affected(rule, before, after, changed) {
+				return false
+			}
+			run++
+			return true
+		})...))
+	}
+	m.logger.Debugf("Validated delta with %d of %d rule(s)", run, total)
+	return mode.results(catalog, Locale(ctx), m.maskViolations(after, violations))
+}
+
+// affected reports whether a change from before to after can change the
+// outcome of rule; rules skipped as unaffected count as passing for the
+// steps that depend on them
+func affected(rule Rule, before, after interface{}, changed bool) bool {
+	switch r := rule.(type) {
+	case stepRule:
+		return affected(r.rule, before, after, changed)
+	case alwaysRule:
+		return true
+	case fieldRule:
+		old, _ := Lookup(before, r.path)
+		updated, _ := Lookup(after, r.path)
+		return !reflect.DeepEqual(old, updated)
+	}
+	return changed
+}
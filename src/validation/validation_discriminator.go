@@ -0,0 +1,60 @@
+package validation
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Branches maps discriminator values to the rules of their variant; a
+// *RuleSet is a Rule, so whole sets can be branches
+type Branches map[string]Rule
+
+// Discriminated validates polymorphic values: the value under path, e.g.
+// "type", selects the branch whose rules apply to the whole value, e.g.
+//
+//	Discriminated("type", Branches{
+//		"card":          NewRuleSet("card").Field("card.number", Required(), Format(FormatCreditCard)),
+//		"bank_transfer": NewRuleSet("bank_transfer").Field("iban", Required(), Format(FormatIBAN)),
+//	})
+//
+// A missing discriminator is CodeRequired and an unknown one CodeOneOf,
+// both at path; violations of the selected branch keep their own paths and
+// carry the branch in Params["branch"]. Nil values pass
+func Discriminated(path string, branches Branches) Rule {
+	allowed := make([]string, 0, len(branches))
+	for value := range branches {
+		allowed = append(allowed, value)
+	}
+	sort.Strings(allowed)
+	return RuleFunc(func(ctx context.Context, value interface{}) []Violation {
+		if indirect(value) == nil {
+			return nil
+		}
+		raw, _ := Lookup(value, path)
+		if isEmpty(raw) {
+			return []Violation{{Path: path, Code: CodeRequired, Message: "is required", Params: map[string]interface{}{"allowed": strings.Join(allowed, ", ")}}}
+		}
+		selected := fmt.Sprint(indirect(raw))
+		branch, ok := branches[selected]
+		if !ok {
+			return []Violation{{
+				Path:    path,
+				Code:    CodeOneOf,
+				Message: "must be one of " + strings.Join(allowed, ", "),
+				Params:  map[string]interface{}{"allowed": strings.Join(allowed, ", "), "actual": selected},
+			}}
+		}
+		violations := branch.Check(ctx, value)
+		for i := range violations {
+			params := make(map[string]interface{}, len(violations[i].Params)+1)
+			for k, v := range violations[i].Params {
+				params[k] = v
+			}
+			params["branch"] = selected
+			violations[i].Params = params
+		}
+		return violations
+	})
+}
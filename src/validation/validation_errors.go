@@ -0,0 +1,75 @@
+package validation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalid matches every ValidationErrors with errors.Is
+var ErrInvalid = errors.New("validation failed")
+
+// ValidationErrors is the error returned for failed validations: every
+// violation found (or the first with Config.FailFast). Each violation is
+// also an error, so errors.As(err, &Violation{}) finds the first one
+type ValidationErrors []Violation
+
+// Error lists the violations
+func (e ValidationErrors) Error() string {
+	parts := make([]string, len(e))
+	for i, v := range e {
+		parts[i] = v.String()
+	}
+	return fmt.Sprintf("%d violation(s): %s", len(e), strings.Join(parts, "; "))
+}
+
+// Unwrap returns the violations as errors
+func (e ValidationErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, v := range e {
+		errs[i] = v
+	}
+	return errs
+}
+
+// Is reports whether target is ErrInvalid
+func (e ValidationErrors) Is(target error) bool {
+	return target == ErrInvalid
+}
+
+// MarshalJSON encodes the errors as {"error": ..., "violations": [...]}
+func (e ValidationErrors) MarshalJSON() ([]byte, error) {
+	violations := []Violation(e)
+	if violations == nil {
+		violations = []Violation{}
+	}
+	return json.Marshal(struct {
+		Error      string      `json:"error"`
+		Violations []Violation `json:"violations"`
+	}{Error: ErrInvalid.Error(), Violations: violations})
+}
+
+// Field returns the violations reported for path
+func (e ValidationErrors) Field(path string) []Violation {
+	var out []Violation
+	for _, v := range e {
+		if v.Path == path {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// Codes returns the distinct violation codes in order of first occurrence
+func (e ValidationErrors) Codes() []string {
+	seen := make(map[string]bool)
+	var codes []string
+	for _, v := range e {
+		if !seen[v.Code] {
+			seen[v.Code] = true
+			codes = append(codes, v.Code)
+		}
+	}
+	return codes
+}
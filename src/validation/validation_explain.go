@@ -0,0 +1,312 @@
+package validation
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// CodeDoc documents a violation code for Explain
+type CodeDoc struct {
+	// Rule names the rule that reports the code, e.g. "Length"
+	Rule string `json:"rule"`
+	// Description says what the rule requires
+	Description string `json:"description"`
+	// URL links to further documentation; WithDocsURL derives one otherwise
+	URL string `json:"url,omitempty"`
+}
+
+var (
+	codeDocsMu sync.RWMutex
+	codeDocs   = map[string]CodeDoc{
+		CodeRequired:      {Rule: "Required", Description: "A value must be present and not empty."},
+		CodeLength:        {Rule: "Length", Description: "Strings, lists and maps must have a bounded number of characters or items."},
+		CodeRange:         {Rule: "Range", Description: "Numbers must lie within an inclusive range."},
+		CodePattern:       {Rule: "Pattern", Description: "Strings must match a regular expression."},
+		CodeOneOf:         {Rule: "OneOf", Description: "The value must be one of a fixed set of allowed values."},
+		CodeNot:           {Rule: "Not", Description: "The value must not satisfy a rule."},
+		CodeType:          {Rule: "Type", Description: "The value must have the expected type."},
+		CodeFormat:        {Rule: "Format", Description: "Strings must be well-formed for a named format, such as email or uuid."},
+		CodeExpr:          {Rule: "Expr", Description: "The value must satisfy an expression over its fields."},
+		CodeUnique:        {Rule: "UniqueIn", Description: "The value must not be taken already."},
+		CodeExists:        {Rule: "ExistsIn", Description: "The value must refer to something that exists."},
+		CodeRemote:        {Rule: "Remote", Description: "A remote service rejected the value."},
+		CodeUnavailable:   {Rule: "Remote", Description: "A remote service needed to check the value could not be reached."},
+		CodeLimit:         {Rule: "Each", Description: "Collections must not be nested too deeply or have too many elements."},
+		CodeTooLarge:      {Rule: "Config.MaxBytes", Description: "The payload must not exceed the size limit."},
+		CodeTooDeep:       {Rule: "Config.MaxDepth", Description: "The payload must not be nested beyond the depth limit."},
+		CodeTooManyItems:  {Rule: "Config.MaxArrayLength", Description: "Lists must not exceed the item limit."},
+		CodeTooManyKeys:   {Rule: "Config.MaxMapKeys", Description: "Maps must not exceed the key limit."},
+		CodeCanceled:      {Rule: "ParallelEach", Description: "Validation stopped before every element was checked."},
+		CodeDependency:    {Rule: "RuleSet", Description: "The steps of a rule set must be orderable."},
+		CodeFileSize:      {Rule: "MaxFileSize", Description: "Files must not exceed the size limit."},
+		CodeFileType:      {Rule: "FileTypes", Description: "File content must be of an allowed media type."},
+		CodeFileStructure: {Rule: "CSV, ImageDimensions or PDFStructure", Description: "Files must parse and have the expected shape."},
+		CodeCountry:       {Rule: "CountryFormat", Description: "Country-specific values can only be checked for a supported country."},
+		CodeChecksum:      {Rule: "Checksum", Description: "Content must match its checksum."},
+		CodeSignature:     {Rule: "Signed", Description: "Content must carry a valid signature from a trusted key."},
+		CodePassword:      {Rule: "Password", Description: "Passwords must satisfy the credential policy."},
+		CodeReserved:      {Rule: "Username", Description: "The name is reserved and cannot be taken."},
+	}
+)
+
+// RegisterCodeDoc documents code, typically a custom rule's, for Explain
+func RegisterCodeDoc(code string, doc CodeDoc) {
+	codeDocsMu.Lock()
+	defer codeDocsMu.Unlock()
+	codeDocs[code] = doc
+}
+
+// LookupCodeDoc returns the documentation of code
+func LookupCodeDoc(code string) (CodeDoc, bool) {
+	codeDocsMu.RLock()
+	defer codeDocsMu.RUnlock()
+	doc, ok := codeDocs[code]
+	return doc, ok
+}
+
+// Explanation describes one failure for people and API clients. ErrorCode
+// is stable across releases and locales; Message may be localized
+type Explanation struct {
+	Path      string `json:"path"`
+	Code      string `json:"code"`
+	ErrorCode string `json:"error_code"`
+	Severity  string `json:"severity"`
+	Message   string `json:"message"`
+	Rule      string `json:"rule,omitempty"`
+	// Description says what the rule requires
+	Description string                 `json:"description,omitempty"`
+	Expected    string                 `json:"expected,omitempty"`
+	Actual      string                 `json:"actual,omitempty"`
+	DocURL      string                 `json:"doc_url,omitempty"`
+	Params      map[string]interface{} `json:"params,omitempty"`
+}
+
+// Explanations are the explanations of a validation; their JSON form is
+// the machine version, String the human one
+type Explanations []Explanation
+
+// explainOptions holds settings for Explain
+type explainOptions struct {
+	data     interface{}
+	hasData  bool
+	docsURL  string
+	patterns []string
+}
+
+// ExplainOption configures Explain
+type ExplainOption func(*explainOptions)
+
+// WithExplainData shows the actual values from the validated data; values
+// of sensitive fields, see Sensitive and Manager.SetMasking, are masked
+func WithExplainData(data interface{}) ExplainOption {
+	return func(o *explainOptions) {
+		o.data, o.hasData = data, true
+	}
+}
+
+// WithDocsURL links codes without a documented URL to base + "#" + code,
+// e.g. "https://docs.example.com/errors#required"
+func WithDocsURL(base string) ExplainOption {
+	return func(o *explainOptions) {
+		o.docsURL = base
+	}
+}
+
+// WithExplainMasking masks actual values under paths matching patterns as
+// well, by default those a Reporter masks
+func WithExplainMasking(patterns ...string) ExplainOption {
+	return func(o *explainOptions) {
+		o.patterns = patterns
+	}
+}
+
+// Explain explains the violations and warnings of results, violations first
+func Explain(results *Results, opts ...ExplainOption) Explanations {
+	if results == nil {
+		return nil
+	}
+	violations := append(append([]Violation(nil), results.Violations...), results.Warnings...)
+	return explain(violations, opts)
+}
+
+// ExplainError explains the violations in err, a ValidationErrors or an
+// error wrapping one; other errors have no explanations
+func ExplainError(err error, opts ...ExplainOption) Explanations {
+	var errs ValidationErrors
+	if !errors.As(err, &errs) {
+		return nil
+	}
+	return explain(errs, opts)
+}
+
+// explain builds one explanation per violation
+func explain(violations []Violation, opts []ExplainOption) Explanations {
+	options := explainOptions{patterns: defaultSensitiveFields}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	out := make(Explanations, len(violations))
+	for i, v := range violations {
+		e := Explanation{
+			Path:      v.Path,
+			Code:      v.Code,
+			ErrorCode: ErrorCode(v),
+			Severity:  v.Severity.String(),
+			Message:   v.Message,
+			Expected:  expectedOf(v),
+			Actual:    paramString(v.Params, "actual", "detected", "size", "columns"),
+			Params:    v.Params,
+		}
+		if doc, ok := LookupCodeDoc(v.Code); ok {
+			e.Rule, e.Description, e.DocURL = doc.Rule, doc.Description, doc.URL
+		}
+		if e.DocURL == "" && options.docsURL != "" {
+			e.DocURL = options.docsURL + "#" + v.Code
+		}
+		if options.hasData {
+			if value, ok := Lookup(options.data, v.Path); ok {
+				if v.sensitive || sensitivePath(v.Path, options.patterns) {
+					e.Actual = maskedValue
+				} else {
+					e.Actual = describeValue(value)
+				}
+			}
+		}
+		out[i] = e
+	}
+	return out
+}
+
+// ErrorCode returns the stable code of v for API clients: "validation."
+// followed by its code and, where the rule distinguishes them, variant,
+// e.g. "validation.length.min"
+func ErrorCode(v Violation) string {
+	if v.key != "" && v.key != v.Code && strings.HasPrefix(v.key, v.Code) {
+		return "validation." + v.key
+	}
+	return "validation." + v.Code
+}
+
+// expectedOf describes what the rule behind v expected, from its params
+func expectedOf(v Violation) string {
+	p := v.Params
+	switch v.Code {
+	case CodeRequired:
+		return "a non-empty value"
+	case CodeLength, CodeRange:
+		switch strings.TrimPrefix(v.key, v.Code+".") {
+		case "exact":
+			return fmt.Sprintf("exactly %v", p["min"])
+		case "min":
+			return fmt.Sprintf("at least %v", p["min"])
+		case "max":
+			return fmt.Sprintf("at most %v", p["max"])
+		}
+		return fmt.Sprintf("between %v and %v", p["min"], p["max"])
+	case CodePattern:
+		return paramString(p, "pattern")
+	case CodeOneOf:
+		return "one of " + paramString(p, "allowed")
+	case CodeFormat:
+		return "a valid " + paramString(p, "format")
+	case CodeType:
+		return "a " + paramString(p, "expected")
+	case CodeFileType:
+		return "one of " + paramString(p, "allowed")
+	}
+	if max := paramString(p, "max"); max != "" {
+		return "at most " + max
+	}
+	return paramString(p, "expected")
+}
+
+// paramString returns the first of keys set in params as text
+func paramString(params map[string]interface{}, keys ...string) string {
+	for _, key := range keys {
+		if value, ok := params[key]; ok {
+			if list, isList := value.([]string); isList {
+				return strings.Join(list, ", ")
+			}
+			return fmt.Sprint(value)
+		}
+	}
+	return ""
+}
+
+// describeValue renders an actual value briefly
+func describeValue(value interface{}) string {
+	value = indirect(value)
+	switch v := value.(type) {
+	case nil:
+		return "nothing"
+	case string:
+		if len(v) > 80 {
+			v = v[:77] + "..."
+		}
+		return fmt.Sprintf("%q", v)
+	}
+	s := fmt.Sprintf("%v", value)
+	if len(s) > 80 {
+		s = s[:77] + "..."
+	}
+	return s
+}
+
+// Codes returns the distinct error codes, sorted
+func (e Explanations) Codes() []string {
+	seen := make(map[string]bool, len(e))
+	var codes []string
+	for _, x := range e {
+		if !seen[x.ErrorCode] {
+			seen[x.ErrorCode] = true
+			codes = append(codes, x.ErrorCode)
+		}
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// String renders the explanations for people, one block per failure
+func (e Explanations) String() string {
+	var b strings.Builder
+	e.WriteText(&b)
+	return b.String()
+}
+
+// WriteText writes the explanations for people to w
+func (e Explanations) WriteText(w io.Writer) error {
+	for i, x := range e {
+		if i > 0 {
+			if _, err := io.WriteString(w, "\n"); err != nil {
+				return err
+			}
+		}
+		path := x.Path
+		if path == "" {
+			path = "(value)"
+		}
+		rule := x.Rule
+		if rule != "" && x.Description != "" {
+			rule += " - "
+		}
+		lines := []string{fmt.Sprintf("%s: %s [%s, %s]", path, x.Message, x.ErrorCode, x.Severity)}
+		for _, field := range [][2]string{
+			{"rule", rule + x.Description},
+			{"expected", x.Expected},
+			{"actual", x.Actual},
+			{"docs", x.DocURL},
+		} {
+			if field[1] != "" {
+				lines = append(lines, fmt.Sprintf("  %-9s %s", field[0]+":", field[1]))
+			}
+		}
+		if _, err := io.WriteString(w, strings.Join(lines, "\n")+"\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,1018 @@
+package validation
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+// CodeExpr is reported by Expr when an expression evaluates to false
+const CodeExpr = "expression"
+
+const (
+	// maxExpressionLength bounds the source of an expression
+	maxExpressionLength = 4096
+	// maxExpressionCost bounds the evaluation steps of one check
+	maxExpressionCost = 100000
+)
+
+// Expression is a compiled business rule in a CEL-like syntax such as
+//
+//	amount < limit && currency in ["USD", "EUR"]
+//
+// Identifiers name fields of the checked value (by json name or field
+// name) and self is the value itself. Supported are literals (numbers,
+// strings, true, false, null, lists), field access and indexing, the
+// operators ! - * / % + == != < <= > >= in && || and ?:, and the functions
+// size, has, int, double and string plus the string methods contains,
+// startsWith, endsWith and matches. Evaluation has no side effects and a
+// bounded cost, so expressions from configuration are safe to run
+type Expression struct {
+	source string
+	root   exprNode
+}
+
+// exprCache holds compiled expressions by source
+var exprCache = struct {
+	sync.Mutex
+	entries map[string]*Expression
+}{entries: make(map[string]*Expression)}
+
+// CompileExpression parses source once; compiled expressions are cached,
+// so compiling the same source again is cheap
+func CompileExpression(source string) (*Expression, error) {
+	exprCache.Lock()
+	cached, ok := exprCache.entries[source]
+	exprCache.Unlock()
+	if ok {
+		return cached, nil
+	}
+
+	if len(source) > maxExpressionLength {
+		return nil, fmt.Errorf("expression longer than %d bytes", maxExpressionLength)
+	}
+	tokens, err := lexExpression(source)
+	if err != nil {
+		return nil, fmt.Errorf("expression %q: %w", source, err)
+	}
+	p := &exprParser{tokens: tokens}
+	root, err := p.parse()
+	if err != nil {
+		return nil, fmt.Errorf("expression %q: %w", source, err)
+	}
+	expr := &Expression{source: source, root: root}
+
+	exprCache.Lock()
+	defer exprCache.Unlock()
+	if len(exprCache.entries) >= 1024 {
+		exprCache.entries = make(map[string]*Expression)
+	}
+	exprCache.entries[source] = expr
+	return expr, nil
+}
+
+// MustCompileExpression is like CompileExpression but panics on errors
+func MustCompileExpression(source string) *Expression {
+	expr, err := CompileExpression(source)
+	if err != nil {
+		panic("validation: " + err.Error())
+	}
+	return expr
+}
+
+// String returns the expression source
+func (e *Expression) String() string {
+	return e.source
+}
+
+// Eval evaluates the expression against value
+func (e *Expression) Eval(value interface{}) (interface{}, error) {
+	env := &exprEnv{self: value}
+	return e.root.eval(env)
+}
+
+// Match evaluates the expression against value, requiring a bool result
+func (e *Expression) Match(value interface{}) (bool, error) {
+	result, err := e.Eval(value)
+	if err != nil {
+		return false, err
+	}
+	ok, isBool := result.(bool)
+	if !isBool {
+		return false, fmt.Errorf("expression %q: result is %s, not bool", e.source, typeOf(result))
+	}
+	return ok, nil
+}
+
+// Expr requires the expression source to hold for the value, reporting
+// message otherwise; it panics if source does not compile, like
+// regexp.MustCompile. An evaluation error, such as a missing field, is a
+// violation carrying the error
+func Expr(source, message string) Rule {
+	expr := MustCompileExpression(source)
+	return RuleFunc(func(ctx context.Context, value interface{}) []Violation {
+		ok, err := expr.Match(value)
+		params := map[string]interface{}{"expression": source}
+		if err != nil {
+			return []Violation{{Code: CodeExpr, Message: err.Error(), Params: params}}
+		}
+		if !ok {
+			return []Violation{{Code: CodeExpr, Message: message, Params: params}}
+		}
+		return nil
+	})
+}
+
+// exprEnv is the state of one evaluation
+type exprEnv struct {
+	self interface{}
+	cost int
+}
+
+// charge spends evaluation budget
+func (env *exprEnv) charge(n int) error {
+	env.cost += n
+	if env.cost > maxExpressionCost {
+		return fmt.Errorf("expression exceeded its evaluation budget")
+	}
+	return nil
+}
+
+// exprToken is a lexical token; kind is one of the tok constants
+type exprToken struct {
+	kind  int
+	text  string
+	value interface{}
+	pos   int
+}
+
+const (
+	tokEOF = iota
+	tokNumber
+	tokString
+	tokIdent
+	tokOp
+)
+
+// exprOperators lists operators longest first
+var exprOperators = []string{"||", "&&", "==", "!=", "<=", ">=", "<", ">", "!", "+", "-", "*", "/", "%", "(", ")", "[", "]", ",", ".", "?", ":"}
+
+// lexExpression splits source into tokens
+func lexExpression(source string) ([]exprToken, error) {
+	var tokens []exprToken
+	for i := 0; i < len(source); {
+		c, size := utf8.DecodeRuneInString(source[i:])
+		switch {
+		case unicode.IsSpace(c):
+			i += size
+		case c >= '0' && c <= '9':
+			start := i
+			for i < len(source) && (source[i] >= '0' && source[i] <= '9' || source[i] == '.' || source[i] == 'e' || source[i] == 'E' ||
+				((source[i] == '-' || source[i] == '+') && (source[i-1] == 'e' || source[i-1] == 'E'))) {
+				i++
+			}
+			n, err := strconv.ParseFloat(source[start:i], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %q at %d", source[start:i], start)
+			}
+			tokens = append(tokens, exprToken{kind: tokNumber, text: source[start:i], value: n, pos: start})
+		case c == '"' || c == '\'':
+			start := i
+			i++
+			for i < len(source) && source[i] != byte(c) {
+				if source[i] == '\\' {
+					i++
+				}
+				i++
+			}
+			if i >= len(source) {
+				return nil, fmt.Errorf("unterminated string at %d", start)
+			}
+			i++
+			s, err := unquoteString(source[start+1 : i-1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid string at %d", start)
+			}
+			tokens = append(tokens, exprToken{kind: tokString, text: source[start:i], value: s, pos: start})
+		case c == '_' || unicode.IsLetter(c):
+			start := i
+			for i < len(source) {
+				c, size := utf8.DecodeRuneInString(source[i:])
+				if c != '_' && !unicode.IsLetter(c) && !unicode.IsDigit(c) {
+					break
+				}
+				i += size
+			}
+			tokens = append(tokens, exprToken{kind: tokIdent, text: source[start:i], pos: start})
+		default:
+			matched := false
+			for _, op := range exprOperators {
+				if strings.HasPrefix(source[i:], op) {
+					tokens = append(tokens, exprToken{kind: tokOp, text: op, pos: i})
+					i += len(op)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, fmt.Errorf("unexpected %q at %d", c, i)
+			}
+		}
+	}
+	return append(tokens, exprToken{kind: tokEOF, pos: len(source)}), nil
+}
+
+// unquoteString decodes the body of a string literal with Go escapes; either
+// quote may be escaped whichever one delimits the literal
+func unquoteString(body string) (string, error) {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(body); i++ {
+		switch {
+		case body[i] == '\\' && i+1 < len(body) && body[i+1] == '\'':
+			b.WriteByte('\'')
+			i++
+		case body[i] == '\\' && i+1 < len(body):
+			b.WriteString(body[i : i+2])
+			i++
+		case body[i] == '"':
+			b.WriteString(`\"`)
+		default:
+			b.WriteByte(body[i])
+		}
+	}
+	b.WriteByte('"')
+	return strconv.Unquote(b.String())
+}
+
+// exprParser is a recursive descent parser over tokens
+type exprParser struct {
+	tokens []exprToken
+	pos    int
+	depth  int
+}
+
+// peek returns the current token
+func (p *exprParser) peek() exprToken {
+	return p.tokens[p.pos]
+}
+
+// next consumes the current token
+func (p *exprParser) next() exprToken {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+// accept consumes the operator or keyword text if it is next
+func (p *exprParser) accept(text string) bool {
+	if t := p.peek(); (t.kind == tokOp || t.kind == tokIdent) && t.text == text {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+// expect consumes the operator text or fails
+func (p *exprParser) expect(text string) error {
+	if !p.accept(text) {
+		return p.unexpected()
+	}
+	return nil
+}
+
+// unexpected reports the current token
+func (p *exprParser) unexpected() error {
+	t := p.peek()
+	if t.kind == tokEOF {
+		return fmt.Errorf("unexpected end of expression")
+	}
+	return fmt.Errorf("unexpected %q at %d", t.text, t.pos)
+}
+
+// parse parses a whole expression
+func (p *exprParser) parse() (exprNode, error) {
+	node, err := p.conditional()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind != tokEOF {
+		return nil, p.unexpected()
+	}
+	return node, nil
+}
+
+// conditional parses or ('?' conditional ':' conditional)?
+func (p *exprParser) conditional() (exprNode, error) {
+	if p.depth++; p.depth > 100 {
+		return nil, fmt.Errorf("expression nested too deeply")
+	}
+	defer func() { p.depth-- }()
+	cond, err := p.binary(0)
+	if err != nil || !p.accept("?") {
+		return cond, err
+	}
+	then, err := p.conditional()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(":"); err != nil {
+		return nil, err
+	}
+	otherwise, err := p.conditional()
+	if err != nil {
+		return nil, err
+	}
+	return &condNode{cond: cond, then: then, otherwise: otherwise}, nil
+}
+
+// exprPrecedence lists binary operators from loosest to tightest binding
+var exprPrecedence = [][]string{
+	{"||"},
+	{"&&"},
+	{"==", "!=", "<", "<=", ">", ">=", "in"},
+	{"+", "-"},
+	{"*", "/", "%"},
+}
+
+// binary parses left-associative operators of the given precedence level
+func (p *exprParser) binary(level int) (exprNode, error) {
+	if level == len(exprPrecedence) {
+		return p.unary()
+	}
+	left, err := p.binary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op := ""
+		for _, candidate := range exprPrecedence[level] {
+			if p.accept(candidate) {
+				op = candidate
+				break
+			}
+		}
+		if op == "" {
+			return left, nil
+		}
+		right, err := p.binary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: op, left: left, right: right}
+	}
+}
+
+// unary parses ('!' | '-') unary | postfix
+func (p *exprParser) unary() (exprNode, error) {
+	for _, op := range []string{"!", "-"} {
+		if p.accept(op) {
+			if p.depth++; p.depth > 100 {
+				return nil, fmt.Errorf("expression nested too deeply")
+			}
+			defer func() { p.depth-- }()
+			operand, err := p.unary()
+			if err != nil {
+				return nil, err
+			}
+			return &unaryNode{op: op, operand: operand}, nil
+		}
+	}
+	return p.postfix()
+}
+
+// postfix parses a primary followed by field access, indexing and method calls
+func (p *exprParser) postfix() (exprNode, error) {
+	node, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		switch {
+		case p.accept("."):
+			name := p.next()
+			if name.kind != tokIdent {
+				return nil, fmt.Errorf("expected a field name at %d", name.pos)
+			}
+			if p.accept("(") {
+				args, err := p.arguments()
+				if err != nil {
+					return nil, err
+				}
+				if node, err = newCall(name.text, node, args); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			node = &memberNode{object: node, name: name.text}
+		case p.accept("["):
+			index, err := p.conditional()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			node = &indexNode{object: node, index: index}
+		default:
+			return node, nil
+		}
+	}
+}
+
+// arguments parses a call's arguments after its opening parenthesis
+func (p *exprParser) arguments() ([]exprNode, error) {
+	var args []exprNode
+	if p.accept(")") {
+		return args, nil
+	}
+	for {
+		arg, err := p.conditional()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+		if p.accept(")") {
+			return args, nil
+		}
+		if err := p.expect(","); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// primary parses literals, identifiers, calls, lists and parentheses
+func (p *exprParser) primary() (exprNode, error) {
+	t := p.next()
+	switch t.kind {
+	case tokNumber, tokString:
+		return &literalNode{value: t.value}, nil
+	case tokIdent:
+		switch t.text {
+		case "true":
+			return &literalNode{value: true}, nil
+		case "false":
+			return &literalNode{value: false}, nil
+		case "null":
+			return &literalNode{value: nil}, nil
+		case "in":
+			return nil, fmt.Errorf("unexpected %q at %d", t.text, t.pos)
+		}
+		if p.accept("(") {
+			args, err := p.arguments()
+			if err != nil {
+				return nil, err
+			}
+			return newCall(t.text, nil, args)
+		}
+		return &identNode{name: t.text}, nil
+	case tokOp:
+		switch t.text {
+		case "(":
+			node, err := p.conditional()
+			if err != nil {
+				return nil, err
+			}
+			return node, p.expect(")")
+		case "[":
+			list := &listNode{}
+			if p.accept("]") {
+				return list, nil
+			}
+			for {
+				item, err := p.conditional()
+				if err != nil {
+					return nil, err
+				}
+				list.items = append(list.items, item)
+				if p.accept("]") {
+					return list, nil
+				}
+				if err := p.expect(","); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	if t.kind != tokEOF {
+		p.pos--
+	}
+	return nil, p.unexpected()
+}
+
+// exprNode is a node of a parsed expression
+type exprNode interface {
+	eval(env *exprEnv) (interface{}, error)
+}
+
+// literalNode is a constant
+type literalNode struct {
+	value interface{}
+}
+
+func (n *literalNode) eval(env *exprEnv) (interface{}, error) {
+	return n.value, env.charge(1)
+}
+
+// identNode is a field of the checked value, or the value itself as self
+type identNode struct {
+	name string
+}
+
+func (n *identNode) eval(env *exprEnv) (interface{}, error) {
+	if err := env.charge(1); err != nil {
+		return nil, err
+	}
+	if n.name == "self" {
+		return env.self, nil
+	}
+	value, ok := member(env.self, n.name)
+	if !ok {
+		return nil, fmt.Errorf("no such field %q", n.name)
+	}
+	return value, nil
+}
+
+// memberNode is object.name
+type memberNode struct {
+	object exprNode
+	name   string
+}
+
+func (n *memberNode) eval(env *exprEnv) (interface{}, error) {
+	object, err := n.object.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	value, ok := member(object, n.name)
+	if !ok {
+		return nil, fmt.Errorf("no such field %q", n.name)
+	}
+	return value, env.charge(1)
+}
+
+// indexNode is object[index]
+type indexNode struct {
+	object, index exprNode
+}
+
+func (n *indexNode) eval(env *exprEnv) (interface{}, error) {
+	object, err := n.object.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	index, err := n.index.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	if key, ok := index.(string); ok {
+		value, found := member(object, key)
+		if !found {
+			return nil, fmt.Errorf("no such key %q", key)
+		}
+		return value, nil
+	}
+	i, ok := number(index)
+	if !ok || i != math.Trunc(i) {
+		return nil, fmt.Errorf("invalid index %v", index)
+	}
+	rv := reflect.ValueOf(indirect(object))
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return nil, fmt.Errorf("cannot index %s", typeOf(object))
+	}
+	if i < 0 || int(i) >= rv.Len() {
+		return nil, fmt.Errorf("index %v out of range", i)
+	}
+	return rv.Index(int(i)).Interface(), env.charge(1)
+}
+
+// listNode is a list literal
+type listNode struct {
+	items []exprNode
+}
+
+func (n *listNode) eval(env *exprEnv) (interface{}, error) {
+	list := make([]interface{}, len(n.items))
+	for i, item := range n.items {
+		value, err := item.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		list[i] = value
+	}
+	return list, env.charge(1)
+}
+
+// condNode is cond ? then : otherwise
+type condNode struct {
+	cond, then, otherwise exprNode
+}
+
+func (n *condNode) eval(env *exprEnv) (interface{}, error) {
+	cond, err := evalBool(n.cond, env)
+	if err != nil {
+		return nil, err
+	}
+	if cond {
+		return n.then.eval(env)
+	}
+	return n.otherwise.eval(env)
+}
+
+// unaryNode is !operand or -operand
+type unaryNode struct {
+	op      string
+	operand exprNode
+}
+
+func (n *unaryNode) eval(env *exprEnv) (interface{}, error) {
+	if n.op == "!" {
+		b, err := evalBool(n.operand, env)
+		return !b, err
+	}
+	value, err := n.operand.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	f, ok := number(value)
+	if !ok {
+		return nil, fmt.Errorf("cannot negate %s", typeOf(value))
+	}
+	return -f, env.charge(1)
+}
+
+// binaryNode is left op right
+type binaryNode struct {
+	op          string
+	left, right exprNode
+}
+
+func (n *binaryNode) eval(env *exprEnv) (interface{}, error) {
+	switch n.op {
+	case "&&", "||":
+		left, err := evalBool(n.left, env)
+		if err != nil {
+			return nil, err
+		}
+		if left == (n.op == "||") {
+			return left, nil
+		}
+		return evalBool(n.right, env)
+	}
+
+	left, err := n.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	right, err := n.right.eval(env)
+	if err != nil {
+		return nil, err
+	}
+	if err := env.charge(1); err != nil {
+		return nil, err
+	}
+	switch n.op {
+	case "==":
+		return equal(left, right), nil
+	case "!=":
+		return !equal(left, right), nil
+	case "<", "<=", ">", ">=":
+		c, err := compare(left, right)
+		if err != nil {
+			return nil, err
+		}
+		switch n.op {
+		case "<":
+			return c < 0, nil
+		case "<=":
+			return c <= 0, nil
+		case ">":
+			return c > 0, nil
+		}
+		return c >= 0, nil
+	case "in":
+		return contains(env, right, left)
+	}
+	return arithmetic(n.op, left, right)
+}
+
+// callNode is a function or method call
+type callNode struct {
+	name   string
+	target exprNode
+	args   []exprNode
+}
+
+// exprFunctions maps function names to their arity, counting a method's target
+var exprFunctions = map[string]int{
+	"size":       1,
+	"int":        1,
+	"double":     1,
+	"string":     1,
+	"contains":   2,
+	"startsWith": 2,
+	"endsWith":   2,
+	"matches":    2,
+}
+
+// newCall checks a call against the known functions; has is a macro
+// testing whether a field exists
+func newCall(name string, target exprNode, args []exprNode) (exprNode, error) {
+	if target != nil {
+		args = append([]exprNode{target}, args...)
+	}
+	if name == "has" {
+		if target != nil || len(args) != 1 {
+			return nil, fmt.Errorf("has takes one field, as in has(a.b)")
+		}
+		switch args[0].(type) {
+		case *memberNode, *identNode:
+			return &hasNode{field: args[0]}, nil
+		}
+		return nil, fmt.Errorf("has takes a field, as in has(a.b)")
+	}
+	arity, ok := exprFunctions[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %q", name)
+	}
+	if len(args) != arity {
+		return nil, fmt.Errorf("%s takes %d argument(s), got %d", name, arity, len(args))
+	}
+	call := &callNode{name: name, args: args}
+	if name == "matches" {
+		// Compile literal patterns up front so typos fail at compile time
+		if lit, ok := args[1].(*literalNode); ok {
+			if s, ok := lit.value.(string); ok {
+				if _, err := compilePattern(s); err != nil {
+					return nil, err
+				}
+			}
+		}
+	}
+	return call, nil
+}
+
+func (n *callNode) eval(env *exprEnv) (interface{}, error) {
+	args := make([]interface{}, len(n.args))
+	for i, arg := range n.args {
+		value, err := arg.eval(env)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = value
+	}
+	if err := env.charge(1); err != nil {
+		return nil, err
+	}
+
+	switch n.name {
+	case "size":
+		size, ok := length(args[0])
+		if !ok {
+			return nil, fmt.Errorf("size: unsupported %s", typeOf(args[0]))
+		}
+		return float64(size), nil
+	case "int", "double":
+		f, ok := number(args[0])
+		if !ok {
+			if s, isString := indirect(args[0]).(string); isString {
+				parsed, err := strconv.ParseFloat(s, 64)
+				if err != nil {
+					return nil, fmt.Errorf("%s: invalid number %q", n.name, s)
+				}
+				f, ok = parsed, true
+			}
+		}
+		if !ok {
+			return nil, fmt.Errorf("%s: unsupported %s", n.name, typeOf(args[0]))
+		}
+		if n.name == "int" {
+			f = math.Trunc(f)
+		}
+		return f, nil
+	case "string":
+		if f, ok := number(args[0]); ok {
+			return strconv.FormatFloat(f, 'f', -1, 64), nil
+		}
+		return fmt.Sprint(indirect(args[0])), nil
+	}
+
+	s, ok := indirect(args[0]).(string)
+	arg, argOK := indirect(args[1]).(string)
+	if !ok || !argOK {
+		return nil, fmt.Errorf("%s: arguments must be strings", n.name)
+	}
+	switch n.name {
+	case "contains":
+		return strings.Contains(s, arg), nil
+	case "startsWith":
+		return strings.HasPrefix(s, arg), nil
+	case "endsWith":
+		return strings.HasSuffix(s, arg), nil
+	}
+	re, err := compilePattern(arg)
+	if err != nil {
+		return nil, err
+	}
+	return re.MatchString(s), env.charge(len(s))
+}
+
+// hasNode tests whether a field exists
+type hasNode struct {
+	field exprNode
+}
+
+func (n *hasNode) eval(env *exprEnv) (interface{}, error) {
+	object, name := env.self, ""
+	switch field := n.field.(type) {
+	case *identNode:
+		name = field.name
+	case *memberNode:
+		var err error
+		if object, err = field.object.eval(env); err != nil {
+			return nil, err
+		}
+		name = field.name
+	}
+	_, ok := member(object, name)
+	return ok, env.charge(1)
+}
+
+// patternCache holds compiled regular expressions used by matches
+var patternCache sync.Map
+
+// compilePattern compiles a regular expression once
+func compilePattern(expr string) (*regexp.Regexp, error) {
+	if re, ok := patternCache.Load(expr); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("matches: %w", err)
+	}
+	patternCache.Store(expr, re)
+	return re, nil
+}
+
+// evalBool evaluates node requiring a bool
+func evalBool(node exprNode, env *exprEnv) (bool, error) {
+	value, err := node.eval(env)
+	if err != nil {
+		return false, err
+	}
+	b, ok := indirect(value).(bool)
+	if !ok {
+		return false, fmt.Errorf("expected bool, got %s", typeOf(value))
+	}
+	return b, nil
+}
+
+// member returns a struct field or string-keyed map entry of value
+func member(value interface{}, name string) (interface{}, bool) {
+	rv := reflect.ValueOf(value)
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil, false
+		}
+		rv = rv.Elem()
+	}
+	switch rv.Kind() {
+	case reflect.Map:
+		if rv.Type().Key().Kind() != reflect.String {
+			return nil, false
+		}
+		v := rv.MapIndex(reflect.ValueOf(name).Convert(rv.Type().Key()))
+		if !v.IsValid() {
+			return nil, false
+		}
+		return v.Interface(), true
+	case reflect.Struct:
+		f, ok := structField(rv, name)
+		if !ok {
+			return nil, false
+		}
+		return f.Interface(), true
+	}
+	return nil, false
+}
+
+// equal compares values, treating all numeric kinds alike
+func equal(a, b interface{}) bool {
+	if x, ok := number(a); ok {
+		y, ok := number(b)
+		return ok && x == y
+	}
+	return reflect.DeepEqual(indirect(a), indirect(b))
+}
+
+// compare orders two numbers or two strings
+func compare(a, b interface{}) (int, error) {
+	if x, ok := number(a); ok {
+		if y, ok := number(b); ok {
+			switch {
+			case x < y:
+				return -1, nil
+			case x > y:
+				return 1, nil
+			}
+			return 0, nil
+		}
+	}
+	x, xOK := indirect(a).(string)
+	y, yOK := indirect(b).(string)
+	if xOK && yOK {
+		return strings.Compare(x, y), nil
+	}
+	return 0, fmt.Errorf("cannot compare %s and %s", typeOf(a), typeOf(b))
+}
+
+// contains implements the in operator for lists and map keys
+func contains(env *exprEnv, collection, item interface{}) (interface{}, error) {
+	rv := reflect.ValueOf(indirect(collection))
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		if err := env.charge(rv.Len()); err != nil {
+			return nil, err
+		}
+		for i := 0; i < rv.Len(); i++ {
+			if equal(rv.Index(i).Interface(), item) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case reflect.Map:
+		key, ok := indirect(item).(string)
+		if !ok {
+			return false, nil
+		}
+		_, found := member(collection, key)
+		return found, nil
+	}
+	return nil, fmt.Errorf("cannot use in with %s", typeOf(collection))
+}
+
+// arithmetic applies + - * / % to numbers, and + to strings and lists
+func arithmetic(op string, a, b interface{}) (interface{}, error) {
+	x, xOK := number(a)
+	y, yOK := number(b)
+	if xOK && yOK {
+		switch op {
+		case "+":
+			return x + y, nil
+		case "-":
+			return x - y, nil
+		case "*":
+			return x * y, nil
+		case "/", "%":
+			if y == 0 {
+				return nil, fmt.Errorf("division by zero")
+			}
+			if op == "/" {
+				return x / y, nil
+			}
+			return math.Mod(x, y), nil
+		}
+	}
+	if op == "+" {
+		if s, ok := indirect(a).(string); ok {
+			if t, ok := indirect(b).(string); ok {
+				return s + t, nil
+			}
+		}
+		if l, ok := a.([]interface{}); ok {
+			if r, ok := b.([]interface{}); ok {
+				return append(append([]interface{}(nil), l...), r...), nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("cannot apply %s to %s and %s", op, typeOf(a), typeOf(b))
+}
+
+// typeOf names the type of an expression value
+func typeOf(value interface{}) string {
+	switch indirect(value).(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "bool"
+	case string:
+		return "string"
+	case []interface{}:
+		return "list"
+	case map[string]interface{}:
+		return "map"
+	}
+	if _, ok := number(value); ok {
+		return "number"
+	}
+	return fmt.Sprintf("%T", value)
+}
@@ -0,0 +1,254 @@
+package validation
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestExpressionEval(t *testing.T) {
+	type order struct {
+		Amount   float64 `json:"amount"`
+		Currency string  `json:"currency"`
+		Note     *string `json:"note"`
+	}
+	value := map[string]interface{}{
+		"n":     3,
+		"s":     "héllo",
+		"zero":  0,
+		"list":  []interface{}{1, "two", 3.0},
+		"map":   map[string]interface{}{"a": 1, "nested": map[string]interface{}{"b": true}},
+		"order": order{Amount: 12.5, Currency: "EUR"},
+		"Å":     "ring",
+		"ok":    true,
+	}
+
+	tests := []struct {
+		source string
+		want   interface{}
+	}{
+		// Precedence and associativity
+		{"1 + 2 * 3", 7.0},
+		{"(1 + 2) * 3", 9.0},
+		{"10 - 4 - 3", 3.0},
+		{"16 / 4 / 2", 2.0},
+		{"7 % 4 * 2", 6.0},
+		{"-2 * -3", 6.0},
+		{"--n", 3.0},
+		{"1 + 2 == 3", true},
+		{"1 < 2 == true", true},
+		{"true || false && false", true},
+		{"(true || false) && false", false},
+		{"!false && !ok", false},
+		{"n > 1 && n < 5 || false", true},
+
+		// in
+		{"1 in list", true},
+		{"'two' in list", true},
+		{"2 in list", false},
+		{"3 in list", true},
+		{"'a' in map", true},
+		{"'b' in map", false},
+		{"1 in map", false},
+		{"order.currency in ['USD', 'EUR']", true},
+		{"n + 1 in [4]", true},
+
+		// ?:
+		{"ok ? 'yes' : 'no'", "yes"},
+		{"!ok ? 'yes' : 'no'", "no"},
+		{"n > 5 ? 'big' : n > 2 ? 'mid' : 'small'", "mid"},
+		{"(ok ? 1 : 2) + 1", 2.0},
+		{"ok ? missing : 1", nil},
+
+		// Short-circuiting skips the erroring side
+		{"false && missing", false},
+		{"true || missing", true},
+		{"false && 1 / zero == 0", false},
+		{"has(n) || missing.field", true},
+		{"!ok ? missing : 'safe'", "safe"},
+
+		// has
+		{"has(n)", true},
+		{"has(missing)", false},
+		{"has(map.a)", true},
+		{"has(map.z)", false},
+		{"has(map.nested.b)", true},
+		{"has(order.currency)", true},
+		{"has(order.Amount)", true},
+		{"has(order.total)", false},
+
+		// Fields, indexing and functions
+		{"self.n", 3},
+		{"list[1]", "two"},
+		{"map['nested'].b", true},
+		{"order.amount * 2", 25.0},
+		{"order.note == null", true},
+		{"size(s)", 5.0},
+		{"size(list)", 3.0},
+		{"int('4.7') + double(n)", 7.0},
+		{"string(n) + '!'", "3!"},
+		{"s.startsWith('hé') && s.endsWith('lo') && s.contains('ll')", true},
+		{"s.matches('^h.llo$')", true},
+		{"[1] + [2]", []interface{}{1.0, 2.0}},
+
+		// Quoting, escapes and UTF-8
+		{`"a\"b"`, `a"b`},
+		{`'a\"b'`, `a"b`},
+		{`'a"b'`, `a"b`},
+		{`'it\'s'`, "it's"},
+		{`"it\'s"`, "it's"},
+		{`"it's"`, "it's"},
+		{`'tab\there'`, "tab\there"},
+		{`'back\\slash'`, `back\slash`},
+		{`'é' == 'é'`, true},
+		{"'héllo' == s", true},
+		{"Å", "ring"},
+		{"Å == 'ring'", true},
+		{"n\u00a0+\u00a01", 4.0}, // no-break spaces
+	}
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			expr, err := CompileExpression(tt.source)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := expr.Eval(value)
+			if tt.want == nil {
+				if err == nil {
+					t.Errorf("Eval = %v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Eval = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpressionEvalErrors(t *testing.T) {
+	value := map[string]interface{}{"n": 3, "zero": 0, "s": "x", "list": []interface{}{1}}
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{"n / 0", "division by zero"},
+		{"n % zero", "division by zero"},
+		{"n / (zero * 2)", "division by zero"},
+		{"missing", `no such field "missing"`},
+		{"list[1]", "out of range"},
+		{"list[0.5]", "invalid index"},
+		{"s < 1", "cannot compare"},
+		{"s - 1", "cannot apply -"},
+		{"1 in n", "cannot use in"},
+		{"n && true", "expected bool"},
+		{"-s", "cannot negate"},
+		{"int(s)", "invalid number"},
+		{"s.contains(1)", "must be strings"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.source, func(t *testing.T) {
+			_, err := MustCompileExpression(tt.source).Eval(value)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Eval error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompileExpressionErrors(t *testing.T) {
+	deep := strings.Repeat("(", 101) + "1" + strings.Repeat(")", 101)
+	tests := []struct {
+		source string
+		want   string
+	}{
+		{"", "unexpected end"},
+		{"1 +", "unexpected end"},
+		{"(1", "unexpected end"},
+		{"1 2", `unexpected "2"`},
+		{"a ? b", "unexpected end"},
+		{"in", `unexpected "in"`},
+		{"'open", "unterminated string"},
+		{`"a\"`, "unterminated string"},
+		{`'\q'`, "invalid string"},
+		{"1.2.3", "invalid number"},
+		{"a # b", `unexpected '#'`},
+		{"a → b", `unexpected '→'`},
+		{"nope(1)", `unknown function "nope"`},
+		{"size(1, 2)", "size takes 1 argument"},
+		{"has(1)", "has takes a field"},
+		{"a.has(b)", "has takes one field"},
+		{"s.matches('(')", "matches"},
+		{"a.1", "expected a field name"},
+		{deep, "nested too deeply"},
+		{strings.Repeat("!", 101) + "true", "nested too deeply"},
+		{strings.Repeat("1+", maxExpressionLength), "longer than"},
+	}
+	for _, tt := range tests {
+		name := tt.source
+		if len(name) > 40 {
+			name = name[:40]
+		}
+		t.Run(name, func(t *testing.T) {
+			_, err := CompileExpression(tt.source)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("CompileExpression error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+
+	// Just inside the depth limit still compiles
+	shallow := strings.Repeat("(", 98) + "1" + strings.Repeat(")", 98)
+	if _, err := CompileExpression(shallow); err != nil {
+		t.Errorf("98 nested parentheses: %v", err)
+	}
+}
+
+func TestExpressionCostBudget(t *testing.T) {
+	large := make([]interface{}, maxExpressionCost)
+	tests := []struct {
+		name   string
+		source string
+		value  interface{}
+		ok     bool
+	}{
+		{"in within budget", "1 in self", large[:maxExpressionCost/2], true},
+		{"in over budget", "1 in self", large, false},
+		{"matches over budget", "self.matches('a')", strings.Repeat("b", maxExpressionCost), false},
+		{"repeated in over budget", "1 in self || 1 in self || 1 in self", large[:maxExpressionCost/2], false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := MustCompileExpression(tt.source).Eval(tt.value)
+			if tt.ok && err != nil {
+				t.Fatalf("Eval = %v, want no error", err)
+			}
+			if !tt.ok && (err == nil || !strings.Contains(err.Error(), "budget")) {
+				t.Fatalf("Eval = %v, want a budget error", err)
+			}
+		})
+	}
+}
+
+func TestExpressionMatch(t *testing.T) {
+	expr := MustCompileExpression("amount < limit && currency in ['USD', 'EUR']")
+	tests := []struct {
+		value map[string]interface{}
+		want  bool
+	}{
+		{map[string]interface{}{"amount": 5, "limit": 10, "currency": "EUR"}, true},
+		{map[string]interface{}{"amount": 15, "limit": 10, "currency": "EUR"}, false},
+		{map[string]interface{}{"amount": 5, "limit": 10, "currency": "GBP"}, false},
+	}
+	for _, tt := range tests {
+		if got, err := expr.Match(tt.value); err != nil || got != tt.want {
+			t.Errorf("Match(%v) = %v, %v; want %v", tt.value, got, err, tt.want)
+		}
+	}
+	if _, err := MustCompileExpression("1 + 1").Match(nil); err == nil || !strings.Contains(err.Error(), "not bool") {
+		t.Errorf("Match of a number = %v, want a not bool error", err)
+	}
+}
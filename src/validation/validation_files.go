@@ -0,0 +1,346 @@
+package validation
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif"  // register GIF for ImageDimensions
+	_ "image/jpeg" // register JPEG for ImageDimensions
+	_ "image/png"  // register PNG for ImageDimensions
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Violation codes reported by the file rules; Params["reason"] narrows
+// the problem down for API clients
+const (
+	// CodeFileSize is reported for files over the size limit
+	CodeFileSize = "file_size"
+	// CodeFileType is reported for files whose content is not an allowed type
+	CodeFileType = "file_type"
+	// CodeFileStructure is reported for files that do not parse or have the wrong shape
+	CodeFileStructure = "file_structure"
+)
+
+// sniffLength is how much of a file content sniffing looks at
+const sniffLength = 512
+
+// sizedReaderAt is content that knows its size, such as *bytes.Reader
+type sizedReaderAt interface {
+	io.ReaderAt
+	Size() int64
+}
+
+// openFile gives random access to an uploaded file. It accepts
+// *multipart.FileHeader, *os.File, []byte, and anything with ReadAt and
+// Size; ok is false for other values
+func openFile(value interface{}) (r io.ReaderAt, size int64, closer func() error, ok bool, err error) {
+	noop := func() error { return nil }
+	switch v := value.(type) {
+	case *multipart.FileHeader:
+		if v == nil {
+			return nil, 0, noop, true, nil
+		}
+		f, err := v.Open()
+		if err != nil {
+			return nil, 0, noop, true, err
+		}
+		return f, v.Size, f.Close, true, nil
+	case *os.File:
+		if v == nil {
+			return nil, 0, noop, true, nil
+		}
+		info, err := v.Stat()
+		if err != nil {
+			return nil, 0, noop, true, err
+		}
+		return v, info.Size(), noop, true, nil
+	case []byte:
+		return bytes.NewReader(v), int64(len(v)), noop, true, nil
+	case sizedReaderAt:
+		return v, v.Size(), noop, true, nil
+	}
+	return nil, 0, noop, false, nil
+}
+
+// fileRule adapts a check of file content to a Rule; nil values pass
+func fileRule(check func(r io.ReaderAt, size int64) []Violation) Rule {
+	return RuleFunc(func(ctx context.Context, value interface{}) []Violation {
+		if value == nil {
+			return nil
+		}
+		r, size, closer, ok, err := openFile(value)
+		if !ok {
+			return []Violation{typeViolation("file", value)}
+		}
+		defer closer()
+		if err != nil {
+			return []Violation{structureViolation("unreadable", "cannot be read: "+err.Error(), nil)}
+		}
+		if r == nil {
+			return nil
+		}
+		return check(r, size)
+	})
+}
+
+// structureViolation builds a CodeFileStructure violation
+func structureViolation(reason, message string, params map[string]interface{}) Violation {
+	if params == nil {
+		params = make(map[string]interface{})
+	}
+	params["reason"] = reason
+	return Violation{Code: CodeFileStructure, Message: message, Params: params}
+}
+
+// MaxFileSize limits files to max bytes
+func MaxFileSize(max int64) Rule {
+	return fileRule(func(r io.ReaderAt, size int64) []Violation {
+		if size <= max {
+			return nil
+		}
+		return []Violation{{
+			Code:    CodeFileSize,
+			Message: fmt.Sprintf("must be at most %d bytes, got %d", max, size),
+			Params:  map[string]interface{}{"reason": "too_large", "max": max, "size": size},
+		}}
+	})
+}
+
+// SniffType returns the media type of content from its leading bytes, as
+// browsers do, ignoring any name or declared Content-Type
+func SniffType(r io.ReaderAt) (string, error) {
+	head := make([]byte, sniffLength)
+	n, err := r.ReadAt(head, 0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	mediaType, _, err := mime.ParseMediaType(http.DetectContentType(head[:n]))
+	if err != nil {
+		return "", err
+	}
+	return mediaType, nil
+}
+
+// FileTypes requires the sniffed media type of a file to be one of allowed,
+// such as "application/pdf" or "image/*". The file's name and declared
+// Content-Type are not trusted
+func FileTypes(allowed ...string) Rule {
+	return fileRule(func(r io.ReaderAt, size int64) []Violation {
+		detected, err := SniffType(r)
+		if err != nil {
+			return []Violation{structureViolation("unreadable", "cannot be read: "+err.Error(), nil)}
+		}
+		for _, pattern := range allowed {
+			if matchMediaType(pattern, detected) {
+				return nil
+			}
+		}
+		return []Violation{{
+			Code:    CodeFileType,
+			Message: fmt.Sprintf("must be one of %s, got %s", strings.Join(allowed, ", "), detected),
+			Params:  map[string]interface{}{"reason": "type_not_allowed", "allowed": allowed, "detected": detected},
+		}}
+	})
+}
+
+// matchMediaType matches a media type against "type/subtype", "type/*" or "*/*"
+func matchMediaType(pattern, mediaType string) bool {
+	pattern = strings.ToLower(strings.TrimSpace(pattern))
+	if pattern == "*/*" || pattern == mediaType {
+		return true
+	}
+	if prefix := strings.TrimSuffix(pattern, "*"); prefix != pattern {
+		return strings.HasPrefix(mediaType, prefix)
+	}
+	return false
+}
+
+// CSVShape describes the expected layout of a CSV file; zero fields are
+// not checked
+type CSVShape struct {
+	// Header lists the required column names of the first row, in order
+	Header []string
+	// MinColumns and MaxColumns bound the number of columns
+	MinColumns int
+	MaxColumns int
+	// MaxRows bounds the number of data rows, excluding the header
+	MaxRows int
+	// Comma is the field delimiter; the default is ','
+	Comma rune
+}
+
+// CSV requires a file to be well-formed CSV in the given shape, with the
+// same number of columns in every row
+func CSV(shape CSVShape) Rule {
+	return fileRule(func(r io.ReaderAt, size int64) []Violation {
+		reader := csv.NewReader(io.NewSectionReader(r, 0, size))
+		if shape.Comma != 0 {
+			reader.Comma = shape.Comma
+		}
+		reader.ReuseRecord = true
+
+		header, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return []Violation{structureViolation("empty", "must not be empty", nil)}
+		}
+		if err != nil {
+			return []Violation{csvViolation(err)}
+		}
+		columns := len(header)
+		if shape.MinColumns > 0 && columns < shape.MinColumns {
+			return []Violation{structureViolation("too_few_columns",
+				fmt.Sprintf("must have at least %d columns, got %d", shape.MinColumns, columns),
+				map[string]interface{}{"min": shape.MinColumns, "columns": columns})}
+		}
+		if shape.MaxColumns > 0 && columns > shape.MaxColumns {
+			return []Violation{structureViolation("too_many_columns",
+				fmt.Sprintf("must have at most %d columns, got %d", shape.MaxColumns, columns),
+				map[string]interface{}{"max": shape.MaxColumns, "columns": columns})}
+		}
+		rows := 0
+		if len(shape.Header) > 0 {
+			if violation, ok := checkHeader(shape.Header, header); !ok {
+				return []Violation{violation}
+			}
+		} else {
+			rows++
+		}
+
+		for {
+			_, err := reader.Read()
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			if err != nil {
+				return []Violation{csvViolation(err)}
+			}
+			if rows++; shape.MaxRows > 0 && rows > shape.MaxRows {
+				return []Violation{structureViolation("too_many_rows",
+					fmt.Sprintf("must have at most %d rows", shape.MaxRows),
+					map[string]interface{}{"max": shape.MaxRows})}
+			}
+		}
+	})
+}
+
+// checkHeader compares the first row against the expected column names
+func checkHeader(expected, header []string) (Violation, bool) {
+	for i, name := range expected {
+		got := ""
+		if i < len(header) {
+			got = strings.TrimSpace(header[i])
+		}
+		if i == 0 {
+			got = strings.TrimPrefix(got, "\ufeff")
+		}
+		if got != name {
+			return structureViolation("header_mismatch",
+				fmt.Sprintf("column %d must be %q, got %q", i+1, name, got),
+				map[string]interface{}{"column": i + 1, "expected": name, "actual": got}), false
+		}
+	}
+	return Violation{}, true
+}
+
+// csvViolation reports a CSV parse error with its position
+func csvViolation(err error) Violation {
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		reason := "malformed"
+		if errors.Is(parseErr.Err, csv.ErrFieldCount) {
+			reason = "ragged_rows"
+		}
+		return structureViolation(reason, "is not valid CSV: "+err.Error(),
+			map[string]interface{}{"line": parseErr.Line, "column": parseErr.Column})
+	}
+	return structureViolation("malformed", "is not valid CSV: "+err.Error(), nil)
+}
+
+// ImageDimensions requires a GIF, JPEG or PNG image no larger than
+// maxWidth by maxHeight pixels; a zero limit is not checked. Only the
+// header is decoded, so oversized images are rejected cheaply
+func ImageDimensions(maxWidth, maxHeight int) Rule {
+	return fileRule(func(r io.ReaderAt, size int64) []Violation {
+		config, format, err := image.DecodeConfig(io.NewSectionReader(r, 0, size))
+		if err != nil {
+			return []Violation{structureViolation("undecodable", "must be a GIF, JPEG or PNG image", nil)}
+		}
+		if (maxWidth > 0 && config.Width > maxWidth) || (maxHeight > 0 && config.Height > maxHeight) {
+			return []Violation{structureViolation("dimensions_exceeded",
+				fmt.Sprintf("must be at most %dx%d pixels, got %dx%d", maxWidth, maxHeight, config.Width, config.Height),
+				map[string]interface{}{
+					"format":     format,
+					"width":      config.Width,
+					"height":     config.Height,
+					"max_width":  maxWidth,
+					"max_height": maxHeight,
+				})}
+		}
+		return nil
+	})
+}
+
+var (
+	pdfHeader    = regexp.MustCompile(`%PDF-[12]\.[0-9]`)
+	pdfStartXref = regexp.MustCompile(`startxref\s+([0-9]+)\s+%%EOF`)
+	pdfXrefAt    = regexp.MustCompile(`^\s*(xref|[0-9]+\s+[0-9]+\s+obj)`)
+)
+
+// pdfTail is how much of the end of a file PDFStructure searches for the
+// trailer
+const pdfTail = 1024
+
+// PDFStructure performs a structural sanity check of a PDF without
+// rendering it: a version header near the start, and a trailer whose
+// startxref offset points at a cross-reference table or stream inside the
+// file. It catches truncated uploads and files renamed to .pdf
+func PDFStructure() Rule {
+	return fileRule(func(r io.ReaderAt, size int64) []Violation {
+		head := make([]byte, pdfTail)
+		n, err := r.ReadAt(head, 0)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return []Violation{structureViolation("unreadable", "cannot be read: "+err.Error(), nil)}
+		}
+		if !pdfHeader.Match(head[:n]) {
+			return []Violation{structureViolation("missing_header", "must start with a PDF header", nil)}
+		}
+
+		offset := size - pdfTail
+		if offset < 0 {
+			offset = 0
+		}
+		tail := make([]byte, size-offset)
+		if _, err := r.ReadAt(tail, offset); err != nil && !errors.Is(err, io.EOF) {
+			return []Violation{structureViolation("unreadable", "cannot be read: "+err.Error(), nil)}
+		}
+		matches := pdfStartXref.FindAllSubmatch(tail, -1)
+		if len(matches) == 0 {
+			return []Violation{structureViolation("missing_trailer", "must end with a PDF trailer; the file may be truncated", nil)}
+		}
+		xref, err := strconv.ParseInt(string(matches[len(matches)-1][1]), 10, 64)
+		if err != nil || xref >= size {
+			return []Violation{structureViolation("invalid_xref", "has a cross-reference offset outside the file",
+				map[string]interface{}{"offset": xref, "size": size})}
+		}
+		at := make([]byte, 64)
+		n, err = r.ReadAt(at, xref)
+		if err != nil && !errors.Is(err, io.EOF) {
+			return []Violation{structureViolation("unreadable", "cannot be read: "+err.Error(), nil)}
+		}
+		if !pdfXrefAt.Match(at[:n]) {
+			return []Violation{structureViolation("invalid_xref", "has a cross-reference offset that points at no cross-reference data",
+				map[string]interface{}{"offset": xref, "size": size})}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,320 @@
+package validation
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"net/mail"
+	"net/netip"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CodeFormat is reported by Format and FormatTags for malformed strings
+const CodeFormat = "format"
+
+// FormatTag is the struct tag naming the format of a string field, e.g.
+// `format:"email"`, checked by FormatTags
+const FormatTag = "format"
+
+// Names of the built-in formats
+const (
+	FormatEmail      = "email"
+	FormatURL        = "url"
+	FormatUUID       = "uuid"
+	FormatIPv4       = "ipv4"
+	FormatIPv6       = "ipv6"
+	FormatIP         = "ip"
+	FormatE164       = "e164"
+	FormatDate       = "date"
+	FormatDateTime   = "date-time"
+	FormatCreditCard = "credit-card"
+	FormatIBAN       = "iban"
+	FormatSemver     = "semver"
+	FormatHostname   = "hostname"
+)
+
+// FormatFunc reports whether s is well-formed
+type FormatFunc func(s string) bool
+
+var (
+	formatsMu sync.RWMutex
+	formats   = map[string]FormatFunc{
+		FormatEmail:      IsEmail,
+		FormatURL:        IsURL,
+		FormatUUID:       IsUUID,
+		FormatIPv4:       IsIPv4,
+		FormatIPv6:       IsIPv6,
+		FormatIP:         IsIP,
+		FormatE164:       IsE164,
+		FormatDate:       IsDate,
+		FormatDateTime:   IsDateTime,
+		FormatCreditCard: IsCreditCard,
+		FormatIBAN:       IsIBAN,
+		FormatSemver:     IsSemver,
+		FormatHostname:   IsHostname,
+	}
+)
+
+// RegisterFormat makes check available under name to Format, FormatTags
+// and rule definitions, replacing any format of the same name
+func RegisterFormat(name string, check FormatFunc) {
+	formatsMu.Lock()
+	defer formatsMu.Unlock()
+	formats[name] = check
+}
+
+// LookupFormat returns the format registered under name
+func LookupFormat(name string) (FormatFunc, bool) {
+	formatsMu.RLock()
+	defer formatsMu.RUnlock()
+	check, ok := formats[name]
+	return check, ok
+}
+
+// Formats returns the registered format names in sorted order
+func Formats() []string {
+	formatsMu.RLock()
+	defer formatsMu.RUnlock()
+	names := make([]string, 0, len(formats))
+	for name := range formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Format requires a string in the named format. Empty and nil values pass;
+// it panics if no format has that name, like regexp.MustCompile
+func Format(name string) Rule {
+	check, ok := LookupFormat(name)
+	if !ok {
+		panic(fmt.Sprintf("validation: unknown format %q", name))
+	}
+	return RuleFunc(func(ctx context.Context, value interface{}) []Violation {
+		return checkFormat(name, check, value)
+	})
+}
+
+// FormatTags checks every string field of a struct (nested structs and
+// slices of them included) against the format named in its FormatTag,
+// within the limits of Config.MaxDepth and Config.MaxElements
+func FormatTags() Rule {
+	return RuleFunc(func(ctx context.Context, value interface{}) []Violation {
+		return formatTags(reflect.ValueOf(value), "", modeOf(ctx))
+	})
+}
+
+// formatTags walks rv collecting format violations under path
+func formatTags(rv reflect.Value, path string, mode checkMode) []Violation {
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	var violations []Violation
+	switch rv.Kind() {
+	case reflect.Slice, reflect.Array:
+		if k := rv.Type().Elem().Kind(); k == reflect.String || k == reflect.Uint8 {
+			// Untagged strings have no format to check
+			return nil
+		}
+		if v, ok := mode.exceeds(rv.Len()); ok {
+			v.Path = path
+			return []Violation{v}
+		}
+		mode.depth++
+		for i := 0; i < rv.Len(); i++ {
+			violations = append(violations, formatTags(rv.Index(i), fmt.Sprintf("%s[%d]", path, i), mode)...)
+		}
+	case reflect.Struct:
+		if v, ok := mode.exceeds(0); ok {
+			v.Path = path
+			return []Violation{v}
+		}
+		mode.depth++
+		t := rv.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.PkgPath != "" {
+				continue
+			}
+			name := strings.Split(f.Tag.Get("json"), ",")[0]
+			if name == "-" {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			fieldPath := joinPath(path, name)
+			format := f.Tag.Get(FormatTag)
+			if format == "" {
+				violations = append(violations, formatTags(rv.Field(i), fieldPath, mode)...)
+				continue
+			}
+			check, ok := LookupFormat(format)
+			if !ok {
+				panic(fmt.Sprintf("validation: unknown format %q on %s.%s", format, t, f.Name))
+			}
+			for _, v := range checkFormat(format, check, rv.Field(i).Interface()) {
+				v.Path = joinPath(fieldPath, v.Path)
+				violations = append(violations, v)
+			}
+		}
+	}
+	return violations
+}
+
+// checkFormat applies check to a string value
+func checkFormat(name string, check FormatFunc, value interface{}) []Violation {
+	value = indirect(value)
+	s, ok := value.(string)
+	if value == nil || (ok && s == "") {
+		return nil
+	}
+	if !ok {
+		return []Violation{typeViolation("string", value)}
+	}
+	if !check(s) {
+		return []Violation{{Code: CodeFormat, Message: "must be a valid " + name, Params: map[string]interface{}{"format": name}}}
+	}
+	return nil
+}
+
+var (
+	uuidPattern   = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+	e164Pattern   = regexp.MustCompile(`^\+[1-9][0-9]{1,14}$`)
+	semverPattern = regexp.MustCompile(`^(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)` +
+		`(?:-((?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*)(?:\.(?:0|[1-9]\d*|\d*[a-zA-Z-][0-9a-zA-Z-]*))*))?` +
+		`(?:\+([0-9a-zA-Z-]+(?:\.[0-9a-zA-Z-]+)*))?$`)
+	hostnameLabel = regexp.MustCompile(`^[a-zA-Z0-9]([a-zA-Z0-9-]{0,61}[a-zA-Z0-9])?$`)
+	ibanPattern   = regexp.MustCompile(`^[A-Z]{2}[0-9]{2}[A-Z0-9]{11,30}$`)
+)
+
+// IsEmail reports whether s is a bare address such as "ada@example.com",
+// without a display name and with a valid hostname as domain
+func IsEmail(s string) bool {
+	if len(s) > 254 {
+		return false
+	}
+	addr, err := mail.ParseAddress(s)
+	if err != nil || addr.Address != s {
+		return false
+	}
+	at := strings.LastIndexByte(s, '@')
+	return at > 0 && at <= 64 && IsHostname(s[at+1:]) && strings.Contains(s[at+1:], ".")
+}
+
+// IsURL reports whether s is an absolute URL with a scheme and host
+func IsURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && u.Scheme != "" && u.Host != "" && !strings.ContainsAny(s, " \t\r\n")
+}
+
+// IsUUID reports whether s is a UUID in canonical 8-4-4-4-12 hex form
+func IsUUID(s string) bool {
+	return uuidPattern.MatchString(s)
+}
+
+// IsIPv4 reports whether s is a dotted-quad IPv4 address
+func IsIPv4(s string) bool {
+	addr, err := netip.ParseAddr(s)
+	return err == nil && addr.Is4()
+}
+
+// IsIPv6 reports whether s is an IPv6 address, zones excluded
+func IsIPv6(s string) bool {
+	addr, err := netip.ParseAddr(s)
+	return err == nil && addr.Is6() && addr.Zone() == ""
+}
+
+// IsIP reports whether s is an IPv4 or IPv6 address
+func IsIP(s string) bool {
+	return IsIPv4(s) || IsIPv6(s)
+}
+
+// IsE164 reports whether s is a phone number in E.164 form, e.g. "+14155550123"
+func IsE164(s string) bool {
+	return e164Pattern.MatchString(s)
+}
+
+// IsDate reports whether s is an ISO 8601 calendar date (YYYY-MM-DD)
+func IsDate(s string) bool {
+	_, err := time.Parse("2006-01-02", s)
+	return err == nil
+}
+
+// IsDateTime reports whether s is an ISO 8601 timestamp with a time zone,
+// as profiled by RFC 3339
+func IsDateTime(s string) bool {
+	_, err := time.Parse(time.RFC3339Nano, s)
+	return err == nil
+}
+
+// IsCreditCard reports whether s is a 12 to 19 digit card number, spaces
+// and dashes allowed, that passes the Luhn checksum
+func IsCreditCard(s string) bool {
+	digits := strings.NewReplacer(" ", "", "-", "").Replace(s)
+	if len(digits) < 12 || len(digits) > 19 {
+		return false
+	}
+	sum := 0
+	for i := 0; i < len(digits); i++ {
+		d := digits[len(digits)-1-i]
+		if d < '0' || d > '9' {
+			return false
+		}
+		n := int(d - '0')
+		if i%2 == 1 {
+			if n *= 2; n > 9 {
+				n -= 9
+			}
+		}
+		sum += n
+	}
+	return sum%10 == 0
+}
+
+// IsIBAN reports whether s is an IBAN, spaces allowed, with valid ISO 7064
+// mod 97 check digits
+func IsIBAN(s string) bool {
+	iban := strings.ToUpper(strings.ReplaceAll(s, " ", ""))
+	if !ibanPattern.MatchString(iban) {
+		return false
+	}
+	var digits strings.Builder
+	for _, r := range iban[4:] + iban[:4] {
+		if r >= 'A' && r <= 'Z' {
+			fmt.Fprintf(&digits, "%d", r-'A'+10)
+		} else {
+			digits.WriteRune(r)
+		}
+	}
+	n, ok := new(big.Int).SetString(digits.String(), 10)
+	return ok && new(big.Int).Mod(n, big.NewInt(97)).Int64() == 1
+}
+
+// IsSemver reports whether s is a semantic version (2.0.0) without a "v" prefix
+func IsSemver(s string) bool {
+	return semverPattern.MatchString(s)
+}
+
+// IsHostname reports whether s is an RFC 1123 hostname; a trailing dot is allowed
+func IsHostname(s string) bool {
+	s = strings.TrimSuffix(s, ".")
+	if s == "" || len(s) > 253 {
+		return false
+	}
+	for _, label := range strings.Split(s, ".") {
+		if !hostnameLabel.MatchString(label) {
+			return false
+		}
+	}
+	return true
+}
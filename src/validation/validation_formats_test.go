@@ -0,0 +1,62 @@
+package validation
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// formatVectors are the valid and invalid examples of each built-in format
+// in testdata/validation_formats.json
+type formatVectors struct {
+	Valid   []string `json:"valid"`
+	Invalid []string `json:"invalid"`
+}
+
+func TestFormatVectors(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "validation_formats.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var vectors map[string]formatVectors
+	if err := json.Unmarshal(data, &vectors); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{
+		FormatEmail, FormatURL, FormatUUID, FormatIPv4, FormatIPv6, FormatIP, FormatE164,
+		FormatDate, FormatDateTime, FormatCreditCard, FormatIBAN, FormatSemver, FormatHostname,
+	} {
+		if v := vectors[name]; len(v.Valid) == 0 || len(v.Invalid) == 0 {
+			t.Errorf("format %q has no valid or no invalid vectors", name)
+		}
+	}
+	for name, v := range vectors {
+		check, ok := LookupFormat(name)
+		if !ok {
+			t.Errorf("vectors for unknown format %q", name)
+			continue
+		}
+		for _, s := range v.Valid {
+			if !check(s) {
+				t.Errorf("%s(%q) = false, want true", name, s)
+			}
+		}
+		for _, s := range v.Invalid {
+			if check(s) {
+				t.Errorf("%s(%q) = true, want false", name, s)
+			}
+		}
+	}
+}
+
+func TestFormatRule(t *testing.T) {
+	rule := Field("email", Format(FormatEmail))
+	for input, fails := range map[string]bool{"ada@example.com": false, "": false, "ada@": true} {
+		violations := rule.Check(context.Background(), map[string]interface{}{"email": input})
+		if got := len(violations) > 0; got != fails {
+			t.Errorf("Format(email) on %q fails = %v, want %v", input, got, fails)
+		}
+	}
+}
@@ -0,0 +1,214 @@
+package validation
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Fuzz targets; run one with e.g.
+//
+//	go test -run '^$' -fuzz '^FuzzFormats$' -fuzztime 30s ./src/validation
+//
+// Seeds are the tricky inputs in testdata/fuzz/<target>/corpus plus the
+// inputs that once crashed a target, which go test keeps beside them.
+// Targets fail only on bugs: validators must reject malformed input with
+// violations, never crash
+
+var (
+	// fuzzContext is the context of fuzzed validations
+	fuzzContext = WithLocale(context.Background(), "de")
+	// fuzzManager validates with small limits, so that limits are hit too
+	fuzzManager = newFuzzManager()
+)
+
+// newFuzzManager returns a manager with the default configuration
+// but small payload limits
+func newFuzzManager() *Manager {
+	config := DefaultConfig()
+	config.MaxBytes = 1 << 16
+	config.MaxArrayLength = 1000
+	config.MaxMapKeys = 100
+	return NewManager(config)
+}
+
+// addCorpus seeds f with the files in testdata/fuzz/<target>/corpus
+func addCorpus(f *testing.F) {
+	paths, err := filepath.Glob(filepath.Join("testdata", "fuzz", f.Name(), "corpus", "*"))
+	if err != nil {
+		f.Fatal(err)
+	}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(data)
+	}
+}
+
+// FuzzFormats runs every registered format and country format on data
+func FuzzFormats(f *testing.F) {
+	addCorpus(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		s := string(data)
+		for _, name := range Formats() {
+			check, _ := LookupFormat(name)
+			check(s)
+		}
+		for _, kind := range []string{KindPostalCode, KindNationalID, KindVAT, KindPhone} {
+			for _, country := range Countries(kind) {
+				check, _ := LookupCountryFormat(kind, country)
+				check(s)
+			}
+		}
+		for region := range phonePlans {
+			NormalizePhone(s, region)
+		}
+	})
+}
+
+// FuzzExpr compiles data as an expression and evaluates it on a document
+// with every JSON type
+func FuzzExpr(f *testing.F) {
+	addCorpus(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		expr, err := CompileExpression(string(data))
+		if err != nil {
+			return
+		}
+		doc := map[string]interface{}{
+			"s": "text", "n": 42.5, "i": 7, "b": true, "z": nil,
+			"l": []interface{}{1, "two", nil}, "m": map[string]interface{}{"k": "v"},
+		}
+		expr.Eval(doc)
+		expr.Match(doc)
+		expr.Match(nil)
+	})
+}
+
+// FuzzCheck decodes data as JSON and checks it against the built-in rules,
+// limits included
+func FuzzCheck(f *testing.F) {
+	addCorpus(f)
+	set := NewRuleSet("fuzz",
+		Field("email", Required(), Format(FormatEmail)),
+		Field("name", Length(1, 64), Pattern(`^[\pL ]+$`)),
+		Field("age", Range(0, 150)),
+		Field("tags", Each(Length(1, 16))),
+		Field("items", ParallelEach(4, Field("sku", Required()))),
+		Field("zip", PostalCode("")),
+		Field("kind", OneOf("a", "b")),
+		Discriminated("type", Branches{"card": Field("card.number", Format(FormatCreditCard))}),
+		Expr(`age >= 18 || kind == "b"`, "must be an adult"),
+	)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		value, ok := fuzzJSON(data)
+		if !ok {
+			return
+		}
+		Explain(fuzzManager.CheckWith(fuzzContext, value, set), WithExplainData(value))
+	})
+}
+
+// FuzzDefinitions compiles data as a rules document and checks a sample
+// payload with the result
+func FuzzDefinitions(f *testing.F) {
+	addCorpus(f)
+	sample := map[string]interface{}{"email": "ada@example.com", "age": 36, "items": []interface{}{map[string]interface{}{"sku": "x"}}}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var doc RulesDocument
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return
+		}
+		sets, err := CompileRules(doc.RuleSets)
+		if err != nil {
+			return
+		}
+		for _, set := range sets {
+			set.Check(fuzzContext, sample)
+			set.Check(fuzzContext, nil)
+		}
+	})
+}
+
+// FuzzInferSchema infers a schema from data, one sample per line, and
+// compiles the rule set it suggests
+func FuzzInferSchema(f *testing.F) {
+	addCorpus(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var samples []json.RawMessage
+		for _, line := range bytes.Split(data, []byte("\n")) {
+			if len(bytes.TrimSpace(line)) > 0 {
+				samples = append(samples, json.RawMessage(line))
+			}
+		}
+		schema, err := InferSchema(samples)
+		if err != nil {
+			return
+		}
+		if _, err := json.Marshal(schema.JSONSchema()); err != nil {
+			t.Fatalf("JSONSchema does not marshal: %v", err)
+		}
+		if _, err := CompileRules([]RuleSetDefinition{schema.RuleSet("inferred")}); err != nil {
+			t.Fatalf("inferred rule set does not compile: %v", err)
+		}
+	})
+}
+
+// FuzzIntegrity checks data against checksum and signature rules in each
+// accepted form
+func FuzzIntegrity(f *testing.F) {
+	addCorpus(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		doc := map[string]interface{}{"body": data, "sum": string(data), "sig": string(data)}
+		Checksum(ChecksumCRC32, "body", "sum").Check(fuzzContext, doc)
+		Checksum(ChecksumSHA256, "body", "sum").Check(fuzzContext, doc)
+		Signed(fuzzKeys{}, "body", "sig").Check(fuzzContext, doc)
+	})
+}
+
+// FuzzTable validates data as CSV against a table with every column type
+func FuzzTable(f *testing.F) {
+	addCorpus(f)
+	table := NewTable("fuzz",
+		Column{Name: "id", Type: ColumnInt, Unique: true},
+		Column{Name: "amount", Type: ColumnFloat, Nullable: true, Rules: []Rule{Range(0, 1000)}},
+		Column{Name: "ok", Type: ColumnBool, Nullable: true},
+		Column{Name: "day", Type: ColumnDate, Nullable: true},
+		Column{Name: "at", Type: ColumnTimestamp, Nullable: true},
+		Column{Name: "note", Type: ColumnString, Nullable: true},
+	).RowRule("amount", Expr("amount == null || amount < 500", "too much"))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		report, err := fuzzManager.ValidateTable(fuzzContext, table, CSVRows(bytes.NewReader(data), 0))
+		if err != nil {
+			return
+		}
+		if _, err := json.Marshal(report); err != nil {
+			t.Fatalf("report does not marshal: %v", err)
+		}
+	})
+}
+
+// fuzzKeys trusts an all-zero key under every ID
+type fuzzKeys struct{}
+
+// PublicKey implements KeyProvider
+func (fuzzKeys) PublicKey(keyID string) (ed25519.PublicKey, error) {
+	return make(ed25519.PublicKey, ed25519.PublicKeySize), nil
+}
+
+// fuzzJSON decodes data the way request bodies are decoded
+func fuzzJSON(data []byte) (interface{}, bool) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, false
+	}
+	return value, true
+}
@@ -0,0 +1,141 @@
+package validation
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/nerufuyo/roastume/src/logging"
+)
+
+// Hook observes validations without changing rules, e.g. to log, emit
+// events or short-circuit; nil funcs are skipped. Hooks run synchronously
+// on the validating goroutine, and a panicking hook is recovered and logged
+type Hook struct {
+	// OnStart runs before any rule; non-nil results end the validation
+	// with them, e.g. to reject a blocked client or replay a known outcome
+	OnStart func(ctx context.Context, data interface{}) *Results
+	// OnRuleFail runs after a rule reported a blocking violation; rules are
+	// named as in MetricsRecorder
+	OnRuleFail func(ctx context.Context, set, rule string, violations []Violation)
+	// OnComplete runs with the outcome of every validation, short-circuited
+	// or not
+	OnComplete func(ctx context.Context, data interface{}, results *Results, elapsed time.Duration)
+}
+
+// hookList holds registered hooks keyed by registration ID
+type hookList struct {
+	mu     sync.Mutex
+	nextID int
+	hooks  map[int]Hook
+}
+
+// RegisterHook adds hook to every validation of the manager and returns a
+// function that removes it
+func (m *Manager) RegisterHook(hook Hook) func() {
+	m.hooks.mu.Lock()
+	defer m.hooks.mu.Unlock()
+	if m.hooks.hooks == nil {
+		m.hooks.hooks = make(map[int]Hook)
+	}
+	id := m.hooks.nextID
+	m.hooks.nextID++
+	m.hooks.hooks[id] = hook
+	return func() {
+		m.hooks.mu.Lock()
+		defer m.hooks.mu.Unlock()
+		delete(m.hooks.hooks, id)
+	}
+}
+
+// hooksKey carries the hooks of a validation in a context
+type hooksKey struct{}
+
+// hookRunner calls hooks in registration order, recovering panics
+type hookRunner struct {
+	hooks  []Hook
+	logger *logging.Logger
+}
+
+// hookRunner returns the registered hooks, or nil when there are none
+func (m *Manager) hookRunner() *hookRunner {
+	m.hooks.mu.Lock()
+	defer m.hooks.mu.Unlock()
+	if len(m.hooks.hooks) == 0 {
+		return nil
+	}
+	runner := &hookRunner{logger: m.logger}
+	for id := 0; id < m.hooks.nextID; id++ {
+		if hook, ok := m.hooks.hooks[id]; ok {
+			runner.hooks = append(runner.hooks, hook)
+		}
+	}
+	return runner
+}
+
+// hooked runs check between the start and completion hooks
+func (m *Manager) hooked(ctx context.Context, data interface{}, check func(ctx context.Context) *Results) *Results {
+	runner := m.hookRunner()
+	if runner == nil {
+		return check(ctx)
+	}
+	start := time.Now()
+	ctx = context.WithValue(ctx, hooksKey{}, runner)
+	results := runner.start(ctx, data)
+	if results == nil {
+		results = check(ctx)
+	}
+	runner.complete(ctx, data, results, time.Since(start))
+	return results
+}
+
+// start runs the OnStart hooks until one returns results
+func (r *hookRunner) start(ctx context.Context, data interface{}) (results *Results) {
+	for _, hook := range r.hooks {
+		if hook.OnStart == nil {
+			continue
+		}
+		r.call("OnStart", func() {
+			results = hook.OnStart(ctx, data)
+		})
+		if results != nil {
+			return results
+		}
+	}
+	return nil
+}
+
+// ruleFailed runs the OnRuleFail hooks
+func (r *hookRunner) ruleFailed(ctx context.Context, set, rule string, violations []Violation) {
+	if r == nil {
+		return
+	}
+	for _, hook := range r.hooks {
+		if hook.OnRuleFail != nil {
+			r.call("OnRuleFail", func() {
+				hook.OnRuleFail(ctx, set, rule, violations)
+			})
+		}
+	}
+}
+
+// complete runs the OnComplete hooks
+func (r *hookRunner) complete(ctx context.Context, data interface{}, results *Results, elapsed time.Duration) {
+	for _, hook := range r.hooks {
+		if hook.OnComplete != nil {
+			r.call("OnComplete", func() {
+				hook.OnComplete(ctx, data, results, elapsed)
+			})
+		}
+	}
+}
+
+// call runs fn, logging a panic instead of propagating it
+func (r *hookRunner) call(name string, fn func()) {
+	defer func() {
+		if p := recover(); p != nil {
+			r.logger.Errorf("Validation hook %s panicked: %v", name, p)
+		}
+	}()
+	fn()
+}
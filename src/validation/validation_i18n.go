@@ -0,0 +1,305 @@
+package validation
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// localeKey carries the locale of a validation in a context
+type localeKey struct{}
+
+// WithLocale returns a context whose validations report messages in locale,
+// a BCP 47 tag such as "de" or "pt-BR"
+func WithLocale(ctx context.Context, locale string) context.Context {
+	return context.WithValue(ctx, localeKey{}, locale)
+}
+
+// Locale returns the locale carried by ctx, or "" when none is set
+func Locale(ctx context.Context) string {
+	locale, _ := ctx.Value(localeKey{}).(string)
+	return locale
+}
+
+// Catalog holds message templates per locale, keyed by violation code (or
+// a variant such as "length.min"). Templates interpolate the violation's
+// params and path as {name}, e.g. "must be between {min} and {max}"
+type Catalog struct {
+	mu        sync.RWMutex
+	messages  map[string]map[string]string
+	fallbacks []string
+}
+
+// NewCatalog creates an empty catalog; fallbacks are tried in order after
+// the requested locale and its parents, e.g. "en"
+func NewCatalog(fallbacks ...string) *Catalog {
+	normalized := make([]string, len(fallbacks))
+	for i, f := range fallbacks {
+		normalized[i] = normalizeLocale(f)
+	}
+	return &Catalog{messages: make(map[string]map[string]string), fallbacks: normalized}
+}
+
+// Add registers templates for locale, replacing existing ones with the same key
+func (c *Catalog) Add(locale string, templates map[string]string) *Catalog {
+	locale = normalizeLocale(locale)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.messages[locale] == nil {
+		c.messages[locale] = make(map[string]string, len(templates))
+	}
+	for key, template := range templates {
+		c.messages[locale][key] = template
+	}
+	return c
+}
+
+// Locales returns the locales with templates in sorted order
+func (c *Catalog) Locales() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	locales := make([]string, 0, len(c.messages))
+	for locale := range c.messages {
+		locales = append(locales, locale)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// Chain returns the locales tried for locale: itself, its parents ("pt-BR"
+// then "pt") and the catalog fallbacks, without duplicates
+func (c *Catalog) Chain(locale string) []string {
+	var chain []string
+	seen := make(map[string]bool)
+	add := func(l string) {
+		if l != "" && !seen[l] {
+			seen[l] = true
+			chain = append(chain, l)
+		}
+	}
+	for l := normalizeLocale(locale); l != ""; {
+		add(l)
+		i := strings.LastIndex(l, "-")
+		if i < 0 {
+			break
+		}
+		l = l[:i]
+	}
+	for _, f := range c.fallbacks {
+		add(f)
+	}
+	return chain
+}
+
+// Message renders v in the first locale of the chain with a template for
+// it, trying the variant key before the code; ok is false when none has one
+func (c *Catalog) Message(locale string, v Violation) (string, bool) {
+	keys := []string{v.Code}
+	if v.key != "" && v.key != v.Code {
+		keys = []string{v.key, v.Code}
+	}
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	for _, l := range c.Chain(locale) {
+		for _, key := range keys {
+			if template, ok := c.messages[l][key]; ok {
+				return interpolate(template, v), true
+			}
+		}
+	}
+	return "", false
+}
+
+// Localize returns violations with messages rendered for locale; messages
+// without a template are kept as reported by the rule
+func (c *Catalog) Localize(locale string, violations []Violation) []Violation {
+	out := make([]Violation, len(violations))
+	for i, v := range violations {
+		if message, ok := c.Message(locale, v); ok {
+			v.Message = message
+		}
+		out[i] = v
+	}
+	return out
+}
+
+// interpolate replaces {name} with the violation's params and {path}
+func interpolate(template string, v Violation) string {
+	var b strings.Builder
+	for {
+		start := strings.IndexByte(template, '{')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(template[start:], '}')
+		if end < 0 {
+			break
+		}
+		name := template[start+1 : start+end]
+		b.WriteString(template[:start])
+		if value, ok := v.Params[name]; ok {
+			b.WriteString(fmt.Sprint(value))
+		} else if name == "path" {
+			b.WriteString(v.Path)
+		} else {
+			b.WriteString(template[start : start+end+1])
+		}
+		template = template[start+end+1:]
+	}
+	b.WriteString(template)
+	return b.String()
+}
+
+// normalizeLocale lower-cases a tag and uses "-" as separator
+func normalizeLocale(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+}
+
+// DefaultCatalog returns a catalog with messages for the built-in codes in
+// English, German, French and Spanish, falling back to English
+func DefaultCatalog() *Catalog {
+	return NewCatalog("en").
+		Add("en", map[string]string{
+			CodeRequired:        "is required",
+			"length.min":        "must have at least {min} items or characters",
+			"length.max":        "must have at most {max} items or characters",
+			"length.exact":      "must have exactly {min} items or characters",
+			"length.between":    "must have between {min} and {max} items or characters",
+			CodeRange:           "must be between {min} and {max}",
+			"range.min":         "must be at least {min}",
+			"range.max":         "must be at most {max}",
+			CodePattern:         "must match {pattern}",
+			CodeOneOf:           "must be one of {allowed}",
+			CodeType:            "must be a {expected}, got {actual}",
+			CodeFormat:          "must be a valid {format}",
+			"limit.depth":       "is nested deeper than {max} levels",
+			"limit.elements":    "has more than {max} elements",
+			CodeTooLarge:        "is larger than {max} bytes",
+			CodeTooDeep:         "is nested deeper than {max} levels",
+			CodeTooManyItems:    "has more than {max} items",
+			CodeTooManyKeys:     "has more than {max} keys",
+			CodeCountry:         "was not checked, no format for country {country}",
+			CodeRateLimit:       "exceeds {limit} per {window}",
+			"password.upper":    "must contain an uppercase letter",
+			"password.lower":    "must contain a lowercase letter",
+			"password.digit":    "must contain a digit",
+			"password.symbol":   "must contain a symbol",
+			"password.common":   "is too common",
+			"password.identity": "must not contain the {field}",
+			CodeReserved:        "is reserved",
+		}).
+		Add("de", map[string]string{
+			CodeRequired:        "ist erforderlich",
+			"length.min":        "muss mindestens {min} Elemente oder Zeichen haben",
+			"length.max":        "darf höchstens {max} Elemente oder Zeichen haben",
+			"length.exact":      "muss genau {min} Elemente oder Zeichen haben",
+			"length.between":    "muss zwischen {min} und {max} Elemente oder Zeichen haben",
+			CodeRange:           "muss zwischen {min} und {max} liegen",
+			"range.min":         "muss mindestens {min} sein",
+			"range.max":         "darf höchstens {max} sein",
+			CodePattern:         "muss dem Muster {pattern} entsprechen",
+			CodeOneOf:           "muss einer der folgenden Werte sein: {allowed}",
+			CodeType:            "hat den falschen Typ {actual}, erwartet: {expected}",
+			CodeFormat:          "muss ein gültiges Format haben: {format}",
+			"limit.depth":       "ist tiefer als {max} Ebenen verschachtelt",
+			"limit.elements":    "hat mehr als {max} Elemente",
+			CodeTooLarge:        "ist größer als {max} Bytes",
+			CodeTooDeep:         "ist tiefer als {max} Ebenen verschachtelt",
+			CodeTooManyItems:    "hat mehr als {max} Einträge",
+			CodeTooManyKeys:     "hat mehr als {max} Schlüssel",
+			CodeCountry:         "wurde nicht geprüft, kein Format für Land {country}",
+			CodeRateLimit:       "überschreitet {limit} pro {window}",
+			"password.upper":    "muss einen Großbuchstaben enthalten",
+			"password.lower":    "muss einen Kleinbuchstaben enthalten",
+			"password.digit":    "muss eine Ziffer enthalten",
+			"password.symbol":   "muss ein Sonderzeichen enthalten",
+			"password.common":   "ist zu verbreitet",
+			"password.identity": "darf {field} nicht enthalten",
+			CodeReserved:        "ist reserviert",
+		}).
+		Add("fr", map[string]string{
+			CodeRequired:        "est obligatoire",
+			"length.min":        "doit contenir au moins {min} éléments ou caractères",
+			"length.max":        "doit contenir au plus {max} éléments ou caractères",
+			"length.exact":      "doit contenir exactement {min} éléments ou caractères",
+			"length.between":    "doit contenir entre {min} et {max} éléments ou caractères",
+			CodeRange:           "doit être compris entre {min} et {max}",
+			"range.min":         "doit être au moins {min}",
+			"range.max":         "doit être au plus {max}",
+			CodePattern:         "doit correspondre au motif {pattern}",
+			CodeOneOf:           "doit être l'une des valeurs suivantes : {allowed}",
+			CodeType:            "a le mauvais type {actual}, attendu : {expected}",
+			CodeFormat:          "doit être au format {format}",
+			"limit.depth":       "est imbriqué sur plus de {max} niveaux",
+			"limit.elements":    "contient plus de {max} éléments",
+			CodeTooLarge:        "dépasse {max} octets",
+			CodeTooDeep:         "est imbriqué sur plus de {max} niveaux",
+			CodeTooManyItems:    "contient plus de {max} entrées",
+			CodeTooManyKeys:     "contient plus de {max} clés",
+			CodeCountry:         "n'a pas été vérifié, aucun format pour le pays {country}",
+			CodeRateLimit:       "dépasse {limit} par {window}",
+			"password.upper":    "doit contenir une majuscule",
+			"password.lower":    "doit contenir une minuscule",
+			"password.digit":    "doit contenir un chiffre",
+			"password.symbol":   "doit contenir un symbole",
+			"password.common":   "est trop courant",
+			"password.identity": "ne doit pas contenir {field}",
+			CodeReserved:        "est réservé",
+		}).
+		Add("es", map[string]string{
+			CodeRequired:        "es obligatorio",
+			"length.min":        "debe tener al menos {min} elementos o caracteres",
+			"length.max":        "debe tener como máximo {max} elementos o caracteres",
+			"length.exact":      "debe tener exactamente {min} elementos o caracteres",
+			"length.between":    "debe tener entre {min} y {max} elementos o caracteres",
+			CodeRange:           "debe estar entre {min} y {max}",
+			"range.min":         "debe ser al menos {min}",
+			"range.max":         "debe ser como máximo {max}",
+			CodePattern:         "debe coincidir con el patrón {pattern}",
+			CodeOneOf:           "debe ser uno de: {allowed}",
+			CodeType:            "tiene el tipo incorrecto {actual}, se esperaba: {expected}",
+			CodeFormat:          "debe tener el formato {format}",
+			"limit.depth":       "está anidado en más de {max} niveles",
+			"limit.elements":    "tiene más de {max} elementos",
+			CodeTooLarge:        "ocupa más de {max} bytes",
+			CodeTooDeep:         "está anidado en más de {max} niveles",
+			CodeTooManyItems:    "tiene más de {max} entradas",
+			CodeTooManyKeys:     "tiene más de {max} claves",
+			CodeCountry:         "no se comprobó, no hay formato para el país {country}",
+			CodeRateLimit:       "supera {limit} por {window}",
+			"password.upper":    "debe contener una letra mayúscula",
+			"password.lower":    "debe contener una letra minúscula",
+			"password.digit":    "debe contener un dígito",
+			"password.symbol":   "debe contener un símbolo",
+			"password.common":   "es demasiado común",
+			"password.identity": "no debe contener {field}",
+			CodeReserved:        "está reservado",
+		})
+}
+
+// defaultCatalog localizes managers without a catalog of their own
+var defaultCatalog = DefaultCatalog()
+
+// SetCatalog replaces the message catalog; nil restores DefaultCatalog
+func (m *Manager) SetCatalog(catalog *Catalog) {
+	if catalog == nil {
+		catalog = DefaultCatalog()
+	}
+	m.rulesMu.Lock()
+	defer m.rulesMu.Unlock()
+	m.catalog = catalog
+	m.rulesVersion++
+}
+
+// Catalog returns the manager's message catalog, e.g. to add templates for
+// custom rule codes
+func (m *Manager) Catalog() *Catalog {
+	m.rulesMu.Lock()
+	defer m.rulesMu.Unlock()
+	if m.catalog == nil {
+		m.catalog = DefaultCatalog()
+	}
+	return m.catalog
+}
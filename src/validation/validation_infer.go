@@ -0,0 +1,263 @@
+package validation
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"unicode/utf8"
+)
+
+// JSONSchemaDialect is the draft inferred schemas declare
+const JSONSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// inferredFormats are the formats InferSchema recognises, most specific first
+var inferredFormats = []string{FormatDateTime, FormatDate, FormatUUID, FormatEmail, FormatIPv4, FormatIPv6, FormatURL}
+
+// InferredSchema summarises the shape of sample documents: the types seen
+// at every path, the keys present in every object and the observed ranges
+type InferredSchema struct {
+	root    *schemaNode
+	samples int
+}
+
+// schemaNode accumulates observations of the values at one path
+type schemaNode struct {
+	seen       int
+	types      map[string]int
+	properties map[string]*schemaNode
+	objects    int
+	items      *schemaNode
+	min, max   float64
+	// unbounded is set by numbers too large for a float64, e.g. 1e700,
+	// whose range cannot be stated
+	unbounded  bool
+	minLength  int
+	maxLength  int
+	strings    int
+	formats    map[string]int
+	fractional bool
+}
+
+// InferSchema builds a draft schema from example payloads; the result is a
+// starting point to review, not a specification
+func InferSchema(samples []json.RawMessage) (*InferredSchema, error) {
+	if len(samples) == 0 {
+		return nil, fmt.Errorf("infer schema: no samples")
+	}
+	root := newSchemaNode()
+	for i, sample := range samples {
+		decoder := json.NewDecoder(bytes.NewReader(sample))
+		decoder.UseNumber()
+		var value interface{}
+		if err := decoder.Decode(&value); err != nil {
+			return nil, fmt.Errorf("infer schema: sample %d: %w", i, err)
+		}
+		root.observe(value)
+	}
+	return &InferredSchema{root: root, samples: len(samples)}, nil
+}
+
+// newSchemaNode creates an empty node
+func newSchemaNode() *schemaNode {
+	return &schemaNode{
+		types:     make(map[string]int),
+		formats:   make(map[string]int),
+		min:       math.Inf(1),
+		max:       math.Inf(-1),
+		minLength: math.MaxInt,
+	}
+}
+
+// observe records one value
+func (n *schemaNode) observe(value interface{}) {
+	n.seen++
+	switch v := value.(type) {
+	case nil:
+		n.types["null"]++
+	case bool:
+		n.types["boolean"]++
+	case json.Number:
+		f, err := v.Float64()
+		if err != nil || math.IsInf(f, 0) {
+			n.types["number"]++
+			n.unbounded = true
+			return
+		}
+		if f != math.Trunc(f) {
+			n.fractional = true
+		}
+		n.types["number"]++
+		n.min, n.max = math.Min(n.min, f), math.Max(n.max, f)
+	case string:
+		n.types["string"]++
+		n.strings++
+		length := utf8.RuneCountInString(v)
+		if length < n.minLength {
+			n.minLength = length
+		}
+		if length > n.maxLength {
+			n.maxLength = length
+		}
+		for _, format := range inferredFormats {
+			if check, ok := LookupFormat(format); ok && check(v) {
+				n.formats[format]++
+				break
+			}
+		}
+	case []interface{}:
+		n.types["array"]++
+		if n.items == nil {
+			n.items = newSchemaNode()
+		}
+		for _, item := range v {
+			n.items.observe(item)
+		}
+	case map[string]interface{}:
+		n.types["object"]++
+		n.objects++
+		if n.properties == nil {
+			n.properties = make(map[string]*schemaNode)
+		}
+		for key, item := range v {
+			child, ok := n.properties[key]
+			if !ok {
+				child = newSchemaNode()
+				n.properties[key] = child
+			}
+			child.observe(item)
+		}
+	}
+}
+
+// typeNames returns the JSON Schema types observed, sorted; numbers that
+// were always whole are "integer"
+func (n *schemaNode) typeNames() []string {
+	names := make([]string, 0, len(n.types))
+	for name := range n.types {
+		if name == "number" && !n.fractional {
+			name = "integer"
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// format returns the format every observed string had, if any
+func (n *schemaNode) format() string {
+	for format, count := range n.formats {
+		if count == n.strings {
+			return format
+		}
+	}
+	return ""
+}
+
+// required returns the properties present and non-null in every object
+func (n *schemaNode) required() []string {
+	var keys []string
+	for key, child := range n.properties {
+		if child.seen == n.objects && child.types["null"] == 0 {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// JSONSchema returns the inferred schema as a JSON Schema document
+func (s *InferredSchema) JSONSchema() map[string]interface{} {
+	schema := s.root.jsonSchema()
+	schema["$schema"] = JSONSchemaDialect
+	return schema
+}
+
+// jsonSchema renders one node
+func (n *schemaNode) jsonSchema() map[string]interface{} {
+	schema := make(map[string]interface{})
+	types := n.typeNames()
+	if len(types) == 1 {
+		schema["type"] = types[0]
+	} else if len(types) > 1 {
+		schema["type"] = types
+	}
+	if min, max, ok := n.numberRange(); ok {
+		schema["minimum"], schema["maximum"] = min, max
+	}
+	if n.strings > 0 {
+		schema["minLength"], schema["maxLength"] = n.minLength, n.maxLength
+		if format := n.format(); format != "" {
+			schema["format"] = format
+		}
+	}
+	if n.items != nil && n.items.seen > 0 {
+		schema["items"] = n.items.jsonSchema()
+	}
+	if n.properties != nil {
+		properties := make(map[string]interface{}, len(n.properties))
+		for key, child := range n.properties {
+			properties[key] = child.jsonSchema()
+		}
+		schema["properties"] = properties
+		if required := n.required(); len(required) > 0 {
+			schema["required"] = required
+		}
+	}
+	return schema
+}
+
+// numberRange returns the observed range of numbers, unless none were seen
+// or some were out of float64 range
+func (n *schemaNode) numberRange() (min, max float64, ok bool) {
+	if n.types["number"] == 0 || n.unbounded || math.IsInf(n.min, 0) || math.IsInf(n.max, 0) {
+		return 0, 0, false
+	}
+	return n.min, n.max, true
+}
+
+// RuleSet returns the inferred constraints as a rule set definition for
+// CompileRules or a configuration file: required keys, observed string
+// lengths and number ranges, and formats, for every path through objects
+func (s *InferredSchema) RuleSet(name string) RuleSetDefinition {
+	def := RuleSetDefinition{Name: name}
+	s.root.fields("", true, &def.Fields)
+	return def
+}
+
+// fields appends a definition for every property below n in path order
+func (n *schemaNode) fields(path string, required bool, out *[]FieldDefinition) {
+	if path != "" {
+		field := FieldDefinition{Path: path, Required: required}
+		if min, max, ok := n.numberRange(); ok && len(n.types) == 1 {
+			field.Range = &Bounds{Min: &min, Max: &max}
+		}
+		if len(n.types) == 1 && n.strings > 0 {
+			field.Format = n.format()
+			if field.Format == "" {
+				min, max := float64(n.minLength), float64(n.maxLength)
+				field.Length = &Bounds{Min: &min, Max: &max}
+			}
+		}
+		if field.Required || field.Range != nil || field.Length != nil || field.Format != "" {
+			*out = append(*out, field)
+		}
+	}
+	if n.properties == nil {
+		return
+	}
+	requiredKeys := make(map[string]bool)
+	for _, key := range n.required() {
+		requiredKeys[key] = true
+	}
+	keys := make([]string, 0, len(n.properties))
+	for key := range n.properties {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		// A key is only required where its parent is
+		n.properties[key].fields(joinPath(path, key), required && requiredKeys[key], out)
+	}
+}
@@ -0,0 +1,40 @@
+package validation
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestInferSchemaOutOfRangeNumber(t *testing.T) {
+	schema, err := InferSchema([]json.RawMessage{
+		json.RawMessage(`{"n": 1e700}`),
+		json.RawMessage(`{"n": 3}`),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	doc := schema.JSONSchema()
+	if _, err := json.Marshal(doc); err != nil {
+		t.Fatalf("JSONSchema does not marshal: %v", err)
+	}
+	n := doc["properties"].(map[string]interface{})["n"].(map[string]interface{})
+	if _, ok := n["minimum"]; ok {
+		t.Errorf("schema of n has bounds: %v", n)
+	}
+	for _, field := range schema.RuleSet("inferred").Fields {
+		if field.Range != nil {
+			t.Errorf("field %q has a range: %v..%v", field.Path, *field.Range.Min, *field.Range.Max)
+		}
+	}
+}
+
+func TestInferSchemaNumberRange(t *testing.T) {
+	schema, err := InferSchema([]json.RawMessage{json.RawMessage(`{"n": -2}`), json.RawMessage(`{"n": 5}`)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	n := schema.JSONSchema()["properties"].(map[string]interface{})["n"].(map[string]interface{})
+	if n["minimum"] != -2.0 || n["maximum"] != 5.0 {
+		t.Errorf("range = %v..%v, want -2..5", n["minimum"], n["maximum"])
+	}
+}
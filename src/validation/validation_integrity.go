@@ -0,0 +1,251 @@
+package validation
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"strings"
+)
+
+// Violation codes reported by the integrity rules; Params["reason"] is one
+// of "missing", "malformed", "unknown_key" or "mismatch"
+const (
+	// CodeChecksum is reported when content does not match its checksum
+	CodeChecksum = "checksum"
+	// CodeSignature is reported when content does not carry a valid signature
+	CodeSignature = "signature"
+)
+
+// Checksum algorithms supported by Checksum and HasChecksum
+const (
+	ChecksumCRC32  = "crc32"
+	ChecksumSHA256 = "sha256"
+)
+
+// KeyProvider resolves trusted public keys by ID; it has the same shape as
+// authentication.KeyProvider and configuration.KeyProvider, so the same
+// key sets verify licenses, configuration files and payloads
+type KeyProvider interface {
+	PublicKey(keyID string) (ed25519.PublicKey, error)
+}
+
+// DetachedSignature is an ed25519 signature over content stored apart from
+// it; its JSON form matches configuration.Signature
+type DetachedSignature struct {
+	KeyID     string `json:"key_id"`
+	Signature []byte `json:"signature"`
+}
+
+// newHash returns a hash for algorithm; it panics on unknown algorithms,
+// like Format on unknown names
+func newHash(algorithm string) func() hash.Hash {
+	switch algorithm {
+	case ChecksumCRC32:
+		return func() hash.Hash { return crc32.NewIEEE() }
+	case ChecksumSHA256:
+		return sha256.New
+	}
+	panic(fmt.Sprintf("validation: unknown checksum algorithm %q", algorithm))
+}
+
+// HasChecksum requires content to hash to expected, a hex digest such as
+// "cbf43926" for CRC32 of "123456789". Content is a string, bytes, a file
+// as accepted by the file rules, or any other value as its JSON encoding;
+// nil values pass
+func HasChecksum(algorithm, expected string) Rule {
+	sum := newHash(algorithm)
+	expected = strings.ToLower(expected)
+	return RuleFunc(func(ctx context.Context, value interface{}) []Violation {
+		return checkChecksum(algorithm, sum, value, expected)
+	})
+}
+
+// Checksum requires the content under contentPath to hash to the hex
+// digest under sumPath, as sent by clients alongside uploads or records;
+// a CRC32 sum may also be a number. Violations are reported at contentPath
+func Checksum(algorithm, contentPath, sumPath string) Rule {
+	sum := newHash(algorithm)
+	return RuleFunc(func(ctx context.Context, value interface{}) []Violation {
+		content, _ := Lookup(value, contentPath)
+		if indirect(content) == nil {
+			return nil
+		}
+		raw, _ := Lookup(value, sumPath)
+		expected, ok := digestOf(algorithm, indirect(raw))
+		var violations []Violation
+		if ok {
+			violations = checkChecksum(algorithm, sum, content, expected)
+		} else if indirect(raw) == nil {
+			violations = []Violation{integrityViolation(CodeChecksum, "missing", "has no "+algorithm+" checksum in "+sumPath, algorithm)}
+		} else {
+			violations = []Violation{integrityViolation(CodeChecksum, "malformed", "has a malformed "+algorithm+" checksum in "+sumPath, algorithm)}
+		}
+		for i := range violations {
+			violations[i].Path = joinPath(contentPath, violations[i].Path)
+		}
+		return violations
+	})
+}
+
+// Signed requires the content under contentPath to carry a valid detached
+// signature under signaturePath from one of keys. The signature is a
+// DetachedSignature, a map with the same fields, or its JSON encoding as
+// written by configuration.Sign. Violations are reported at contentPath
+func Signed(keys KeyProvider, contentPath, signaturePath string) Rule {
+	return RuleFunc(func(ctx context.Context, value interface{}) []Violation {
+		content, _ := Lookup(value, contentPath)
+		if indirect(content) == nil {
+			return nil
+		}
+		raw, _ := Lookup(value, signaturePath)
+		violations := checkSignature(keys, content, indirect(raw))
+		for i := range violations {
+			violations[i].Path = joinPath(contentPath, violations[i].Path)
+		}
+		return violations
+	})
+}
+
+// VerifyContent checks sig over content as Signed does, for callers that
+// hold both outside a payload
+func VerifyContent(keys KeyProvider, content interface{}, sig DetachedSignature) error {
+	if violations := checkSignature(keys, content, sig); len(violations) > 0 {
+		return fmt.Errorf("signature: %s", violations[0].Message)
+	}
+	return nil
+}
+
+// checkChecksum compares the digest of content with expected
+func checkChecksum(algorithm string, sum func() hash.Hash, content interface{}, expected string) []Violation {
+	content = indirect(content)
+	if content == nil {
+		return nil
+	}
+	h := sum()
+	if violation, ok := writeContent(h, content); !ok {
+		return []Violation{violation}
+	}
+	actual := hex.EncodeToString(h.Sum(nil))
+	if actual == expected {
+		return nil
+	}
+	violation := integrityViolation(CodeChecksum, "mismatch", "does not match its "+algorithm+" checksum", algorithm)
+	violation.Params["expected"] = expected
+	violation.Params["actual"] = actual
+	return []Violation{violation}
+}
+
+// checkSignature verifies raw, a signature in any accepted form, over content
+func checkSignature(keys KeyProvider, content, raw interface{}) []Violation {
+	if raw == nil {
+		return []Violation{integrityViolation(CodeSignature, "missing", "is not signed", "ed25519")}
+	}
+	sig, err := signatureOf(raw)
+	if err != nil {
+		return []Violation{integrityViolation(CodeSignature, "malformed", "has a malformed signature: "+err.Error(), "ed25519")}
+	}
+	pub, err := keys.PublicKey(sig.KeyID)
+	if err != nil {
+		violation := integrityViolation(CodeSignature, "unknown_key", fmt.Sprintf("is signed with untrusted key %q", sig.KeyID), "ed25519")
+		violation.Params["key_id"] = sig.KeyID
+		return []Violation{violation}
+	}
+	var buf strings.Builder
+	if violation, ok := writeContent(&buf, content); !ok {
+		return []Violation{violation}
+	}
+	if !ed25519.Verify(pub, []byte(buf.String()), sig.Signature) {
+		violation := integrityViolation(CodeSignature, "mismatch", "does not match its signature", "ed25519")
+		violation.Params["key_id"] = sig.KeyID
+		return []Violation{violation}
+	}
+	return nil
+}
+
+// writeContent writes the bytes of content to w: strings and bytes as they
+// are, files in full, other values as JSON
+func writeContent(w io.Writer, content interface{}) (Violation, bool) {
+	switch v := content.(type) {
+	case string:
+		io.WriteString(w, v)
+		return Violation{}, true
+	case []byte:
+		w.Write(v)
+		return Violation{}, true
+	}
+	r, size, closer, ok, err := openFile(content)
+	if ok {
+		defer closer()
+		if err == nil && r != nil {
+			_, err = io.Copy(w, io.NewSectionReader(r, 0, size))
+		}
+		if err != nil {
+			return structureViolation("unreadable", "cannot be read: "+err.Error(), nil), false
+		}
+		return Violation{}, true
+	}
+	encoded, err := json.Marshal(content)
+	if err != nil {
+		return typeViolation("string, bytes or file", content), false
+	}
+	w.Write(encoded)
+	return Violation{}, true
+}
+
+// digestOf returns the expected hex digest in raw, a string or, for CRC32,
+// a number
+func digestOf(algorithm string, raw interface{}) (string, bool) {
+	if s, ok := raw.(string); ok {
+		s = strings.ToLower(strings.TrimSpace(s))
+		if _, err := hex.DecodeString(s); err != nil || s == "" {
+			return "", false
+		}
+		return s, true
+	}
+	if algorithm == ChecksumCRC32 {
+		if n, ok := number(raw); ok && n >= 0 && n <= 0xffffffff && n == float64(uint32(n)) {
+			return fmt.Sprintf("%08x", uint32(n)), true
+		}
+	}
+	return "", false
+}
+
+// signatureOf decodes a signature from any accepted form
+func signatureOf(raw interface{}) (DetachedSignature, error) {
+	var sig DetachedSignature
+	switch v := raw.(type) {
+	case DetachedSignature:
+		sig = v
+	case string:
+		if err := json.Unmarshal([]byte(v), &sig); err != nil {
+			return sig, err
+		}
+	case []byte:
+		if err := json.Unmarshal(v, &sig); err != nil {
+			return sig, err
+		}
+	default:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return sig, err
+		}
+		if err := json.Unmarshal(encoded, &sig); err != nil {
+			return sig, err
+		}
+	}
+	if sig.KeyID == "" || len(sig.Signature) != ed25519.SignatureSize {
+		return sig, fmt.Errorf("want a key_id and a %d byte signature", ed25519.SignatureSize)
+	}
+	return sig, nil
+}
+
+// integrityViolation builds a checksum or signature violation
+func integrityViolation(code, reason, message, algorithm string) Violation {
+	return Violation{Code: code, Message: message, Params: map[string]interface{}{"reason": reason, "algorithm": algorithm}}
+}
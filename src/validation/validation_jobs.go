@@ -0,0 +1,386 @@
+package validation
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrJobNotFound is returned for unknown or forgotten job IDs
+var ErrJobNotFound = errors.New("validation job not found")
+
+// maxFinishedJobs is how many finished jobs a manager remembers; the
+// oldest are forgotten first
+const maxFinishedJobs = 100
+
+// JobState is the lifecycle state of a validation job
+type JobState string
+
+const (
+	// JobRunning jobs are reading and validating records
+	JobRunning JobState = "running"
+	// JobCompleted jobs validated every record, valid or not
+	JobCompleted JobState = "completed"
+	// JobFailed jobs stopped because the input could not be read
+	JobFailed JobState = "failed"
+	// JobCanceled jobs were stopped with CancelJob
+	JobCanceled JobState = "canceled"
+)
+
+// JobSource opens the input of a validation job; size is its length in
+// bytes for estimating the remaining time, or -1 when unknown
+type JobSource interface {
+	Open(ctx context.Context) (r io.ReadCloser, size int64, err error)
+}
+
+// JobSourceFunc adapts a function to JobSource
+type JobSourceFunc func(ctx context.Context) (io.ReadCloser, int64, error)
+
+// Open implements JobSource
+func (f JobSourceFunc) Open(ctx context.Context) (io.ReadCloser, int64, error) {
+	return f(ctx)
+}
+
+// FileSource reads a job's records from the file at path
+func FileSource(path string) JobSource {
+	return JobSourceFunc(func(ctx context.Context) (io.ReadCloser, int64, error) {
+		f, err := os.Open(path)
+		if err != nil {
+			return nil, 0, err
+		}
+		info, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return nil, 0, err
+		}
+		return f, info.Size(), nil
+	})
+}
+
+// ReaderSource reads a job's records from r, closing it at the end when it
+// is an io.Closer; size is as for JobSource
+func ReaderSource(r io.Reader, size int64) JobSource {
+	return JobSourceFunc(func(ctx context.Context) (io.ReadCloser, int64, error) {
+		if rc, ok := r.(io.ReadCloser); ok {
+			return rc, size, nil
+		}
+		return io.NopCloser(r), size, nil
+	})
+}
+
+// JobProgress is a snapshot of a job. ETA estimates the remaining time from
+// the bytes read so far and is zero until it can be estimated
+type JobProgress struct {
+	ID         string        `json:"id"`
+	State      JobState      `json:"state"`
+	Records    int           `json:"records"`
+	Failures   int           `json:"failures"`
+	BytesRead  int64         `json:"bytes_read"`
+	Size       int64         `json:"size"`
+	StartedAt  time.Time     `json:"started_at"`
+	FinishedAt time.Time     `json:"finished_at,omitempty"`
+	ETA        time.Duration `json:"eta,omitempty"`
+	Error      string        `json:"error,omitempty"`
+}
+
+// Done reports whether the job has finished, whatever its outcome
+func (p JobProgress) Done() bool {
+	return p.State != JobRunning
+}
+
+// jobOptions holds settings for SubmitJob
+type jobOptions struct {
+	stream   []StreamOption
+	reporter *Reporter
+	onResult func(StreamResult)
+}
+
+// JobOption configures SubmitJob
+type JobOption func(*jobOptions)
+
+// WithJobStream sets how the input is read, e.g. WithStreamFormat(StreamCSV)
+func WithJobStream(opts ...StreamOption) JobOption {
+	return func(o *jobOptions) {
+		o.stream = append(o.stream, opts...)
+	}
+}
+
+// WithJobReporter aggregates the job's results into r rather than a new
+// Reporter with default options
+func WithJobReporter(r *Reporter) JobOption {
+	return func(o *jobOptions) {
+		o.reporter = r
+	}
+}
+
+// WithJobResults calls fn with every result in record order, e.g. to write
+// rejected records to a file; fn runs on the job's goroutine
+func WithJobResults(fn func(StreamResult)) JobOption {
+	return func(o *jobOptions) {
+		o.onResult = fn
+	}
+}
+
+// job is a submitted validation job
+type job struct {
+	id       string
+	cancel   context.CancelFunc
+	reporter *Reporter
+	done     chan struct{}
+	started  time.Time
+	size     int64
+	read     atomic.Int64
+	records  atomic.Int64
+	failures atomic.Int64
+
+	// mu guards the outcome, set once the job ends
+	mu       sync.Mutex
+	state    JobState
+	finished time.Time
+	err      error
+}
+
+// jobList holds the jobs of a manager
+type jobList struct {
+	mu   sync.Mutex
+	jobs map[string]*job
+}
+
+// SubmitJob starts validating the records of source in the background,
+// like ValidateStream, and returns the job ID for Progress, CancelJob,
+// WaitJob and JobReport. The job keeps the values of ctx, such as its
+// locale and profile, but not its cancellation, so it outlives the request
+// that submitted it; only CancelJob stops it. Only the current record and
+// the Reporter's aggregates are held in memory
+func (m *Manager) SubmitJob(ctx context.Context, source JobSource, opts ...JobOption) (string, error) {
+	options := jobOptions{}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.reporter == nil {
+		options.reporter = NewReporter()
+	}
+	id, err := newJobID()
+	if err != nil {
+		return "", fmt.Errorf("submit job: %w", err)
+	}
+	ctx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+	input, size, err := source.Open(ctx)
+	if err != nil {
+		cancel()
+		return "", fmt.Errorf("submit job: open source: %w", err)
+	}
+
+	j := &job{id: id, cancel: cancel, reporter: options.reporter, done: make(chan struct{}), started: time.Now(), size: size, state: JobRunning}
+	m.jobs.mu.Lock()
+	if m.jobs.jobs == nil {
+		m.jobs.jobs = make(map[string]*job)
+	}
+	m.jobs.jobs[id] = j
+	m.jobs.mu.Unlock()
+	m.logger.Printf("Started validation job %s", id)
+
+	go m.runJob(ctx, j, input, options)
+	return id, nil
+}
+
+// runJob validates the input of j until it ends or is canceled
+func (m *Manager) runJob(ctx context.Context, j *job, input io.ReadCloser, options jobOptions) {
+	defer close(j.done)
+	defer input.Close()
+	defer j.cancel()
+
+	var readErr error
+	counted := readerFunc(func(p []byte) (int, error) {
+		n, err := input.Read(p)
+		j.read.Add(int64(n))
+		if err != nil && err != io.EOF {
+			readErr = err
+		}
+		return n, err
+	})
+	var last StreamResult
+	for result := range m.ValidateStream(ctx, counted, options.stream...) {
+		last = result
+		j.records.Add(1)
+		if !result.Valid() {
+			j.failures.Add(1)
+		}
+		j.reporter.AddStream(result)
+		if options.onResult != nil {
+			options.onResult(result)
+		}
+	}
+
+	j.mu.Lock()
+	j.finished = time.Now()
+	switch {
+	case ctx.Err() != nil:
+		j.state = JobCanceled
+	case readErr != nil:
+		j.state, j.err = JobFailed, readErr
+	case last.Err != nil && isCSV(options.stream):
+		// A CSV stream ends at the first unreadable row
+		j.state, j.err = JobFailed, last.Err
+	default:
+		j.state = JobCompleted
+	}
+	state, err := j.state, j.err
+	j.mu.Unlock()
+	if err != nil {
+		m.logger.Warnf("Validation job %s failed after %d record(s): %v", j.id, j.records.Load(), err)
+	} else {
+		m.logger.Printf("Validation job %s %s: %d record(s), %d failure(s)", j.id, state, j.records.Load(), j.failures.Load())
+	}
+	m.forgetFinishedJobs()
+}
+
+// isCSV reports whether opts read CSV
+func isCSV(opts []StreamOption) bool {
+	var options streamOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options.format == StreamCSV
+}
+
+// readerFunc adapts a function to io.Reader
+type readerFunc func(p []byte) (int, error)
+
+// Read implements io.Reader
+func (f readerFunc) Read(p []byte) (int, error) {
+	return f(p)
+}
+
+// Progress returns a snapshot of the job
+func (m *Manager) Progress(jobID string) (JobProgress, error) {
+	j, ok := m.job(jobID)
+	if !ok {
+		return JobProgress{}, ErrJobNotFound
+	}
+	return j.progress(), nil
+}
+
+// Jobs returns snapshots of the remembered jobs, the newest first
+func (m *Manager) Jobs() []JobProgress {
+	m.jobs.mu.Lock()
+	jobs := make([]*job, 0, len(m.jobs.jobs))
+	for _, j := range m.jobs.jobs {
+		jobs = append(jobs, j)
+	}
+	m.jobs.mu.Unlock()
+	progress := make([]JobProgress, len(jobs))
+	for i, j := range jobs {
+		progress[i] = j.progress()
+	}
+	sort.Slice(progress, func(i, j int) bool {
+		return progress[i].StartedAt.After(progress[j].StartedAt)
+	})
+	return progress
+}
+
+// CancelJob stops the job; canceling a finished job has no effect
+func (m *Manager) CancelJob(jobID string) error {
+	j, ok := m.job(jobID)
+	if !ok {
+		return ErrJobNotFound
+	}
+	j.cancel()
+	return nil
+}
+
+// WaitJob waits until the job finishes or ctx is done and returns its
+// final progress
+func (m *Manager) WaitJob(ctx context.Context, jobID string) (JobProgress, error) {
+	j, ok := m.job(jobID)
+	if !ok {
+		return JobProgress{}, ErrJobNotFound
+	}
+	select {
+	case <-j.done:
+		return j.progress(), nil
+	case <-ctx.Done():
+		return j.progress(), ctx.Err()
+	}
+}
+
+// JobReport returns the job's report so far: totals, per-rule counts and
+// samples of failing records
+func (m *Manager) JobReport(jobID string) (*Report, error) {
+	j, ok := m.job(jobID)
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+	return j.reporter.Report(), nil
+}
+
+// job looks up a job by ID
+func (m *Manager) job(id string) (*job, bool) {
+	m.jobs.mu.Lock()
+	defer m.jobs.mu.Unlock()
+	j, ok := m.jobs.jobs[id]
+	return j, ok
+}
+
+// forgetFinishedJobs drops the oldest finished jobs beyond maxFinishedJobs
+func (m *Manager) forgetFinishedJobs() {
+	m.jobs.mu.Lock()
+	defer m.jobs.mu.Unlock()
+	var finished []*job
+	for _, j := range m.jobs.jobs {
+		select {
+		case <-j.done:
+			finished = append(finished, j)
+		default:
+		}
+	}
+	if len(finished) <= maxFinishedJobs {
+		return
+	}
+	sort.Slice(finished, func(a, b int) bool {
+		return finished[a].started.Before(finished[b].started)
+	})
+	for _, j := range finished[:len(finished)-maxFinishedJobs] {
+		delete(m.jobs.jobs, j.id)
+	}
+}
+
+// progress takes a snapshot of j
+func (j *job) progress() JobProgress {
+	p := JobProgress{
+		ID:        j.id,
+		Records:   int(j.records.Load()),
+		Failures:  int(j.failures.Load()),
+		BytesRead: j.read.Load(),
+		Size:      j.size,
+		StartedAt: j.started,
+	}
+	j.mu.Lock()
+	p.State, p.FinishedAt = j.state, j.finished
+	if j.err != nil {
+		p.Error = j.err.Error()
+	}
+	j.mu.Unlock()
+	if p.State == JobRunning && p.Size > 0 && p.BytesRead > 0 && p.BytesRead < p.Size {
+		elapsed := time.Since(p.StartedAt)
+		p.ETA = time.Duration(float64(elapsed) * float64(p.Size-p.BytesRead) / float64(p.BytesRead)).Round(time.Millisecond)
+	}
+	return p
+}
+
+// newJobID returns a random job ID
+func newJobID() (string, error) {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id), nil
+}
@@ -0,0 +1,138 @@
+package validation
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Codes reported when a payload breaks Config limits; they are checked
+// before any rule runs, so a hostile payload fails fast and cheaply
+const (
+	// CodeTooLarge is reported when strings and bytes exceed Config.MaxBytes
+	CodeTooLarge = "too_large"
+	// CodeTooDeep is reported when nesting exceeds Config.MaxDepth
+	CodeTooDeep = "too_deep"
+	// CodeTooManyItems is reported when a list exceeds Config.MaxArrayLength
+	CodeTooManyItems = "too_many_items"
+	// CodeTooManyKeys is reported when a map exceeds Config.MaxMapKeys
+	CodeTooManyKeys = "too_many_keys"
+)
+
+// limitWalker measures a value against the limits of a checkMode
+type limitWalker struct {
+	mode  checkMode
+	bytes int
+	seen  map[uintptr]bool
+}
+
+// checkLimits reports the first limit value breaks, walking it depth first
+func (c checkMode) checkLimits(value interface{}) []Violation {
+	if c.maxBytes <= 0 && c.maxDepth <= 0 && c.maxItems <= 0 && c.maxKeys <= 0 {
+		return nil
+	}
+	w := &limitWalker{mode: c, seen: make(map[uintptr]bool)}
+	if v, ok := w.walk(reflect.ValueOf(value), "", 0); ok {
+		return []Violation{v}
+	}
+	return nil
+}
+
+// walk visits rv at path, nested depth containers deep
+func (w *limitWalker) walk(rv reflect.Value, path string, depth int) (Violation, bool) {
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return Violation{}, false
+		}
+		if rv.Kind() == reflect.Ptr {
+			if w.seen[rv.Pointer()] {
+				return Violation{}, false
+			}
+			w.seen[rv.Pointer()] = true
+		}
+		rv = rv.Elem()
+	}
+
+	switch rv.Kind() {
+	case reflect.String:
+		return w.count(rv.Len())
+	case reflect.Slice, reflect.Array:
+		if rv.Type().Elem().Kind() == reflect.Uint8 {
+			return w.count(rv.Len())
+		}
+		if w.mode.maxItems > 0 && rv.Len() > w.mode.maxItems {
+			return limitViolation(path, CodeTooManyItems, fmt.Sprintf("has more than %d items", w.mode.maxItems), w.mode.maxItems), true
+		}
+		if v, ok := w.enter(path, depth); ok {
+			return v, true
+		}
+		for i := 0; i < rv.Len(); i++ {
+			if v, ok := w.walk(rv.Index(i), joinPath(path, fmt.Sprintf("[%d]", i)), depth+1); ok {
+				return v, true
+			}
+		}
+	case reflect.Map:
+		if w.mode.maxKeys > 0 && rv.Len() > w.mode.maxKeys {
+			return limitViolation(path, CodeTooManyKeys, fmt.Sprintf("has more than %d keys", w.mode.maxKeys), w.mode.maxKeys), true
+		}
+		if v, ok := w.enter(path, depth); ok {
+			return v, true
+		}
+		keys := rv.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+		})
+		for _, key := range keys {
+			name := fmt.Sprint(key.Interface())
+			if v, ok := w.count(len(name)); ok {
+				return v, true
+			}
+			if v, ok := w.walk(rv.MapIndex(key), joinPath(path, name), depth+1); ok {
+				return v, true
+			}
+		}
+	case reflect.Struct:
+		if v, ok := w.enter(path, depth); ok {
+			return v, true
+		}
+		t := rv.Type()
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name := strings.Split(f.Tag.Get("json"), ",")[0]
+			if !f.IsExported() || name == "-" {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+			if v, ok := w.walk(rv.Field(i), joinPath(path, name), depth+1); ok {
+				return v, true
+			}
+		}
+	}
+	return Violation{}, false
+}
+
+// enter reports descending into a container at depth beyond MaxDepth
+func (w *limitWalker) enter(path string, depth int) (Violation, bool) {
+	if w.mode.maxDepth > 0 && depth >= w.mode.maxDepth {
+		return limitViolation(path, CodeTooDeep, fmt.Sprintf("is nested deeper than %d levels", w.mode.maxDepth), w.mode.maxDepth), true
+	}
+	return Violation{}, false
+}
+
+// count adds n bytes of string data, reporting the payload once they
+// exceed MaxBytes
+func (w *limitWalker) count(n int) (Violation, bool) {
+	w.bytes += n
+	if w.mode.maxBytes > 0 && w.bytes > w.mode.maxBytes {
+		return limitViolation("", CodeTooLarge, fmt.Sprintf("is larger than %d bytes", w.mode.maxBytes), w.mode.maxBytes), true
+	}
+	return Violation{}, false
+}
+
+// limitViolation builds a limit violation at path
+func limitViolation(path, code, message string, max int) Violation {
+	return Violation{Path: path, Code: code, Message: message, Params: map[string]interface{}{"max": max}}
+}
@@ -0,0 +1,285 @@
+// Package validation provides professional validation functionality
+// with comprehensive error handling, logging, and configuration management
+// following Go best practices and idiomatic patterns.
+//
+// Author: Automation System
+// Version: 1.0.0
+// Created: 2025-07-04T14:04:26.600245
+package validation
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/nerufuyo/roastume/src/logging"
+	"github.com/nerufuyo/roastume/src/manager"
+)
+
+// Status represents the current state of validation operations
+type Status = manager.Status
+
+const (
+	// StatusPending indicates operation is pending
+	StatusPending = manager.StatusPending
+	// StatusProcessing indicates operation is in progress
+	StatusProcessing = manager.StatusProcessing
+	// StatusCompleted indicates operation completed successfully
+	StatusCompleted = manager.StatusCompleted
+	// StatusFailed indicates operation failed
+	StatusFailed = manager.StatusFailed
+	// StatusCanceled indicates operation was canceled before it completed
+	StatusCanceled = manager.StatusCanceled
+)
+
+// OperationID identifies one Process call, see Result.OperationID
+type OperationID = manager.OperationID
+
+// ErrTimeout is returned by Process when an operation outlasts Config.Timeout
+var ErrTimeout = manager.ErrTimeout
+
+// Config holds configuration settings for validation operations
+type Config struct {
+	Enabled   bool          `json:"enabled"`
+	Timeout   time.Duration `json:"timeout"`
+	Retries   int           `json:"retries"`
+	RetryMaxElapsed time.Duration `json:"retry_max_elapsed"`
+	MaxConcurrency  int           `json:"max_concurrency"`
+	LogLevel  string        `json:"log_level"`
+	FailFast  bool          `json:"fail_fast"`
+	FailOnWarnings bool     `json:"fail_on_warnings"`
+	MaxDepth  int           `json:"max_depth"`
+	MaxElements int         `json:"max_elements"`
+	MaxBytes  int           `json:"max_bytes"`
+	MaxArrayLength int      `json:"max_array_length"`
+	MaxMapKeys int          `json:"max_map_keys"`
+}
+
+// DefaultConfig returns a default configuration
+func DefaultConfig() *Config {
+	return &Config{
+		Enabled:  true,
+		Timeout:  30 * time.Second,
+		Retries:  3,
+		LogLevel: "",
+		MaxDepth: 32,
+		MaxElements: 10000,
+		MaxBytes: 10 << 20,
+		MaxArrayLength: 10000,
+		MaxMapKeys: 1000,
+	}
+}
+
+// core returns the settings the manager core enforces
+func (c *Config) core() manager.Config {
+	return manager.Config{
+		Timeout: c.Timeout,
+		Retries: c.Retries,
+		Backoff: manager.Backoff{MaxElapsed: c.RetryMaxElapsed},
+		MaxConcurrency: c.MaxConcurrency,
+	}
+}
+
+// Result represents the result of a validation operation
+type Result struct {
+	Status        string    `json:"status"`
+	ProcessedAt   time.Time `json:"processed_at"`
+	DataSize      int       `json:"data_size"`
+	ProcessingTime time.Duration `json:"processing_time"`
+	Message       string    `json:"message,omitempty"`
+	OperationID   OperationID `json:"operation_id,omitempty"`
+	Warnings      []Violation `json:"warnings,omitempty"`
+}
+
+// Manager provides professional validation management functionality
+type Manager struct {
+	*manager.Manager[interface{}, *Result]
+	config    *Config
+	mu        sync.RWMutex
+	logger    *logging.Logger
+	rulesMu   sync.RWMutex
+	ruleSets  []*RuleSet
+	catalog   *Catalog
+	cache     *resultCache
+	metrics   MetricsRecorder
+	masking   masking
+	hooks     hookList
+	// packs are the installed rule packs by name, guarded by rulesMu
+	packs     map[string]installedPack
+	jobs      jobList
+	// rulesVersion changes whenever the rule sets or catalog do
+	rulesVersion uint64
+}
+
+// ManagerInterface defines the interface for validation operations
+type ManagerInterface interface {
+	Process(ctx context.Context, data interface{}) (*Result, error)
+	ProcessAsync(ctx context.Context, data interface{}) <-chan *Result
+	Validate(data interface{}) error
+	GetStatus() Status
+	Reset()
+	Close() error
+}
+
+// NewManager creates a new validation manager instance
+func NewManager(config *Config) *Manager {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	
+	m := &Manager{
+		config:    config,
+		logger:    logging.New("validation", "[VALIDATION] "),
+	}
+	m.Manager = manager.New(manager.Options{Name: "validation", Logger: m.logger}, manager.Operation[interface{}, *Result]{
+		Config: func(ctx context.Context) (manager.Config, error) {
+			m.mu.RLock()
+			defer m.mu.RUnlock()
+			return m.config.core(), nil
+		},
+		Complete: func(id OperationID, result *Result, elapsed time.Duration) {
+			result.OperationID = id
+			result.ProcessingTime = elapsed
+		},
+	})
+	
+	m.setupLogging()
+	return m
+}
+
+// setupLogging configures logging for the manager; an empty LogLevel
+// follows the process-wide level set by the configuration manager
+func (m *Manager) setupLogging() {
+	if err := m.logger.Configure(m.config.LogLevel); err != nil {
+		m.logger.Warnf("Ignoring log level: %v", err)
+	}
+	m.logger.Printf("Initialized validation manager with configuration")
+}
+
+// Process executes validation processing with comprehensive error handling
+func (m *Manager) Process(ctx context.Context, data interface{}) (*Result, error) {
+	return m.process(ctx, data, func(config *Config) ([]Violation, error) {
+		return m.validate(ctx, data, config)
+	}, func() int {
+		return len(fmt.Sprintf("%v", data))
+	})
+}
+
+// process validates and processes one input through the manager core;
+// size reports its DataSize
+func (m *Manager) process(ctx context.Context, data interface{}, validate func(config *Config) ([]Violation, error), size func() int) (*Result, error) {
+	var warnings []Violation
+	return m.Run(ctx, data, manager.Operation[interface{}, *Result]{
+		Validate: func(ctx context.Context, data interface{}) error {
+			m.mu.RLock()
+			config := m.config
+			m.mu.RUnlock()
+			var err error
+			warnings, err = validate(config)
+			return err
+		},
+		Execute: func(ctx context.Context, data interface{}) (*Result, error) {
+			return m.executeProcessing(ctx, size)
+		},
+		Complete: func(id OperationID, result *Result, elapsed time.Duration) {
+			result.OperationID = id
+			result.ProcessingTime = elapsed
+			result.Warnings = warnings
+		},
+	})
+}
+
+// ProcessAsync executes validation processing asynchronously
+func (m *Manager) ProcessAsync(ctx context.Context, data interface{}) <-chan *Result {
+	resultChan := make(chan *Result, 1)
+	
+	go func() {
+		defer close(resultChan)
+		
+		result, err := m.Process(ctx, data)
+		if err != nil {
+			id, _ := manager.OperationIDOf(err)
+			result = &Result{
+				Status:      "error",
+				Message:     err.Error(),
+				OperationID: id,
+			}
+		}
+		
+		select {
+		case resultChan <- result:
+		case <-ctx.Done():
+			return
+		}
+	}()
+	
+	return resultChan
+}
+
+// Validate validates input data against the registered rule sets; a
+// failure is a ValidationErrors listing every violated rule (only the first
+// with Config.FailFast). Warnings are logged but only fail with
+// Config.FailOnWarnings
+func (m *Manager) Validate(data interface{}) error {
+	m.mu.RLock()
+	config := m.config
+	m.mu.RUnlock()
+	_, err := m.validate(context.Background(), data, config)
+	return err
+}
+
+// executeProcessing performs the core processing logic
+func (m *Manager) executeProcessing(ctx context.Context, size func() int) (*Result, error) {
+	// Simulate processing with context cancellation support
+	select {
+	case <-time.After(100 * time.Millisecond):
+		// Processing completed
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	
+	result := &Result{
+		Status:      "success",
+		ProcessedAt: time.Now(),
+		DataSize:    size(),
+		Message:     "Validation processing completed successfully",
+	}
+	
+	return result, nil
+}
+
+// GetConfig returns a copy of the current configuration; changes to the
+// copy do not affect the manager
+func (m *Manager) GetConfig() *Config {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	config := *m.config
+	return &config
+}
+
+// SetConfig replaces the configuration used by subsequent operations
+func (m *Manager) SetConfig(config *Config) {
+	if config == nil {
+		config = DefaultConfig()
+	}
+	
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	
+	m.config = config
+	if err := m.logger.Configure(config.LogLevel); err != nil {
+		m.logger.Warnf("Ignoring log level: %v", err)
+	}
+	m.logger.Printf("Validation manager reconfigured")
+}
+
+// Factory function to create validation manager with default configuration
+func CreateValidationManager() *Manager {
+	return NewManager(DefaultConfig())
+}
+
+// Factory function to create validation manager with custom configuration
+func CreateValidationManagerWithConfig(config *Config) *Manager {
+	return NewManager(config)
+}
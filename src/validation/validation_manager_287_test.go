@@ -0,0 +1,19 @@
+package validation
+
+import "testing"
+
+func TestGetConfigReturnsCopy(t *testing.T) {
+	m := NewManager(nil)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		m.SetConfig(DefaultConfig())
+	}()
+	config := m.GetConfig()
+	<-done
+
+	config.LogLevel = "changed"
+	if m.GetConfig().LogLevel == "changed" {
+		t.Error("changing the returned configuration changed the manager")
+	}
+}
@@ -1,3 +1,5 @@
+//go:build ignore
+
 // Package validation provides professional validation functionality
 // with comprehensive error handling, logging, and configuration management
 // following Go best practices and idiomatic patterns.
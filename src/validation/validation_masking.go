@@ -0,0 +1,202 @@
+package validation
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// maskedValue replaces sensitive values
+const maskedValue = "***"
+
+// defaultSensitiveFields are the field name patterns masked unless
+// configured otherwise
+var defaultSensitiveFields = []string{"password", "secret", "token", "ssn", "card", "cvv", "iban", "api_key"}
+
+// MaskFunc returns the text a violation shows instead of raw, the value of
+// a sensitive field; it receives the violation to vary by path, code or
+// severity
+type MaskFunc func(v Violation, raw string) string
+
+// MaskAll replaces the whole value with "***"
+func MaskAll(v Violation, raw string) string {
+	return maskedValue
+}
+
+// MaskPartial keeps the last n characters, e.g. "***1234" for a card
+// number; values of at most 2n characters are masked entirely
+func MaskPartial(n int) MaskFunc {
+	return func(v Violation, raw string) string {
+		runes := []rune(raw)
+		if n <= 0 || len(runes) <= 2*n {
+			return maskedValue
+		}
+		return maskedValue + string(runes[len(runes)-n:])
+	}
+}
+
+// MaskBySeverity masks errors with onError and warnings with onWarning, e.g.
+// to hide values entirely in errors returned to clients while keeping a
+// hint in warnings that are only logged
+func MaskBySeverity(onError, onWarning MaskFunc) MaskFunc {
+	return func(v Violation, raw string) string {
+		if v.Severity == SeverityWarning {
+			return onWarning(v, raw)
+		}
+		return onError(v, raw)
+	}
+}
+
+// sensitiveRule is the Rule returned by Sensitive
+type sensitiveRule struct {
+	rules []Rule
+}
+
+// Sensitive marks the value checked by rules as sensitive whatever its
+// field is called, e.g. Field("pin", Sensitive(Length(4, 6)))
+func Sensitive(rules ...Rule) Rule {
+	return sensitiveRule{rules: rules}
+}
+
+// Check implements Rule
+func (s sensitiveRule) Check(ctx context.Context, value interface{}) []Violation {
+	violations := All(s.rules...).Check(ctx, value)
+	for i := range violations {
+		violations[i].sensitive = true
+	}
+	return violations
+}
+
+// masking decides which violations are masked and how
+type masking struct {
+	mask     MaskFunc
+	patterns []string
+}
+
+// SetMasking masks the raw values of sensitive fields wherever violations
+// echo them, in messages and params: fields marked with Sensitive and
+// fields with a path segment containing one of patterns, case-insensitively.
+// A nil mask uses MaskAll and no patterns the defaults (passwords, secrets,
+// tokens, SSNs, card numbers and the like), which apply until this is called
+func (m *Manager) SetMasking(mask MaskFunc, patterns ...string) {
+	if mask == nil {
+		mask = MaskAll
+	}
+	if len(patterns) == 0 {
+		patterns = defaultSensitiveFields
+	}
+	m.rulesMu.Lock()
+	defer m.rulesMu.Unlock()
+	m.masking = masking{mask: mask, patterns: patterns}
+	m.rulesVersion++
+}
+
+// maskViolations masks the violations of sensitive fields of value
+func (m *Manager) maskViolations(value interface{}, violations []Violation) []Violation {
+	if len(violations) == 0 {
+		return violations
+	}
+	m.rulesMu.RLock()
+	masking := m.masking
+	m.rulesMu.RUnlock()
+	if masking.mask == nil {
+		masking.mask = MaskAll
+	}
+	if masking.patterns == nil {
+		masking.patterns = defaultSensitiveFields
+	}
+
+	var out []Violation
+	for i, v := range violations {
+		if !v.sensitive && !sensitivePath(v.Path, masking.patterns) {
+			continue
+		}
+		raw, ok := Lookup(value, v.Path)
+		if !ok || raw == nil {
+			continue
+		}
+		if out == nil {
+			out = append([]Violation(nil), violations...)
+		}
+		out[i] = maskViolation(v, fmt.Sprint(raw), masking.mask)
+	}
+	if out == nil {
+		return violations
+	}
+	return out
+}
+
+// maskViolation replaces raw in the message and params of v
+func maskViolation(v Violation, raw string, mask MaskFunc) Violation {
+	if raw == "" {
+		return v
+	}
+	replacement := mask(v, raw)
+	v.Message = replaceValue(v.Message, raw, replacement)
+	if len(v.Params) > 0 {
+		params := make(map[string]interface{}, len(v.Params))
+		for key, param := range v.Params {
+			switch p := param.(type) {
+			case string:
+				params[key] = replaceValue(p, raw, replacement)
+			default:
+				if fmt.Sprint(p) == raw {
+					params[key] = replacement
+				} else {
+					params[key] = p
+				}
+			}
+		}
+		v.Params = params
+	}
+	return v
+}
+
+// replaceValue replaces the occurrences of raw in s that are not part of a
+// longer word or number, so that a short value does not mangle the message
+func replaceValue(s, raw, replacement string) string {
+	var b strings.Builder
+	for {
+		i := strings.Index(s, raw)
+		if i < 0 {
+			b.WriteString(s)
+			return b.String()
+		}
+		end := i + len(raw)
+		if (i > 0 && isWordByte(s[i-1])) || (end < len(s) && isWordByte(s[end])) {
+			b.WriteString(s[:i+1])
+			s = s[i+1:]
+			continue
+		}
+		b.WriteString(s[:i])
+		b.WriteString(replacement)
+		s = s[end:]
+	}
+}
+
+// isWordByte reports whether c is an ASCII letter, digit or underscore
+func isWordByte(c byte) bool {
+	return c == '_' || ('0' <= c && c <= '9') || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+}
+
+// sensitivePath reports whether a segment of path matches a pattern
+func sensitivePath(path string, patterns []string) bool {
+	for _, part := range pathSegments(path) {
+		if !strings.HasPrefix(part, "[") && sensitiveName(part, patterns) {
+			return true
+		}
+	}
+	return false
+}
+
+// sensitiveName reports whether a field name contains a pattern,
+// case-insensitively
+func sensitiveName(name string, patterns []string) bool {
+	name = strings.ToLower(name)
+	for _, pattern := range patterns {
+		if strings.Contains(name, strings.ToLower(pattern)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,251 @@
+package validation
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// RuleOutcome is the result of one evaluation of a rule
+type RuleOutcome int
+
+const (
+	// RulePassed means the rule reported nothing
+	RulePassed RuleOutcome = iota
+	// RuleFailed means the rule reported a blocking violation
+	RuleFailed
+	// RuleWarned means the rule reported only warnings
+	RuleWarned
+	// RuleTimedOut means a remote rule or store lookup timed out
+	RuleTimedOut
+)
+
+// String returns string representation of RuleOutcome
+func (o RuleOutcome) String() string {
+	switch o {
+	case RulePassed:
+		return "passed"
+	case RuleFailed:
+		return "failed"
+	case RuleWarned:
+		return "warned"
+	case RuleTimedOut:
+		return "timed_out"
+	default:
+		return "unknown"
+	}
+}
+
+// MetricsRecorder receives a measurement for every rule evaluation, e.g.
+// to export counters and histograms to Prometheus or StatsD. Rules are
+// identified by rule set and by step name, field path, or "#n" for the
+// n-th rule of the set. Implementations must be safe for concurrent use
+type MetricsRecorder interface {
+	ObserveRule(set, rule string, outcome RuleOutcome, latency time.Duration)
+}
+
+// metricsKey carries the recorder of a validation in a context
+type metricsKey struct{}
+
+// SetMetricsRecorder sends rule measurements to recorder; nil stops them
+func (m *Manager) SetMetricsRecorder(recorder MetricsRecorder) {
+	m.rulesMu.Lock()
+	defer m.rulesMu.Unlock()
+	m.metrics = recorder
+}
+
+// EnableRuleMetrics starts collecting rule measurements in memory and
+// returns the metrics
+func (m *Manager) EnableRuleMetrics() *RuleMetrics {
+	metrics := NewRuleMetrics()
+	m.SetMetricsRecorder(metrics)
+	return metrics
+}
+
+// instrument makes ctx carry the manager's recorder, if any
+func (m *Manager) instrument(ctx context.Context) context.Context {
+	m.rulesMu.RLock()
+	recorder := m.metrics
+	m.rulesMu.RUnlock()
+	if recorder == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, metricsKey{}, recorder)
+}
+
+// observeRule runs rule, reporting the measurement when ctx carries a
+// recorder and failures when it carries hooks
+func observeRule(ctx context.Context, set, name string, rule Rule, value interface{}) []Violation {
+	recorder, _ := ctx.Value(metricsKey{}).(MetricsRecorder)
+	hooks, _ := ctx.Value(hooksKey{}).(*hookRunner)
+	if recorder == nil && hooks == nil {
+		return checkRule(ctx, rule, value)
+	}
+	start := time.Now()
+	violations := checkRule(ctx, rule, value)
+	latency := time.Since(start)
+
+	mode := modeOf(ctx)
+	for _, v := range violations {
+		if mode.blocks(v) {
+			hooks.ruleFailed(ctx, set, name, violations)
+			break
+		}
+	}
+	if recorder == nil {
+		return violations
+	}
+	outcome := RulePassed
+	for _, v := range violations {
+		if v.Code == CodeUnavailable && v.Params["reason"] == "timeout" {
+			outcome = RuleTimedOut
+			break
+		}
+		if mode.blocks(v) {
+			outcome = RuleFailed
+		} else if outcome == RulePassed {
+			outcome = RuleWarned
+		}
+	}
+	recorder.ObserveRule(set, name, outcome, latency)
+	return violations
+}
+
+// ruleLabel identifies the i-th rule of a set in measurements
+func ruleLabel(rules []Rule, i int) string {
+	switch r := rules[i].(type) {
+	case stepRule:
+		return r.name
+	case fieldRule:
+		return r.path
+	}
+	return ruleName(rules, i)
+}
+
+// LatencyBuckets are the upper bounds of the RuleMetrics latency histogram
+var LatencyBuckets = []time.Duration{
+	100 * time.Microsecond,
+	time.Millisecond,
+	10 * time.Millisecond,
+	100 * time.Millisecond,
+	time.Second,
+	10 * time.Second,
+}
+
+// RuleMetric holds the counters and latency histogram of one rule
+type RuleMetric struct {
+	RuleSet      string        `json:"rule_set"`
+	Rule         string        `json:"rule"`
+	Evaluations  uint64        `json:"evaluations"`
+	Failures     uint64        `json:"failures"`
+	Warnings     uint64        `json:"warnings"`
+	Timeouts     uint64        `json:"timeouts"`
+	TotalLatency time.Duration `json:"total_latency"`
+	MaxLatency   time.Duration `json:"max_latency"`
+	// Buckets counts evaluations per LatencyBuckets bound, plus a last
+	// bucket for slower ones; counts are not cumulative
+	Buckets []uint64 `json:"buckets"`
+}
+
+// MeanLatency returns the average latency of the evaluations of the rule
+func (s RuleMetric) MeanLatency() time.Duration {
+	if s.Evaluations == 0 {
+		return 0
+	}
+	return s.TotalLatency / time.Duration(s.Evaluations)
+}
+
+// RuleMetrics is an in-memory MetricsRecorder
+type RuleMetrics struct {
+	mu    sync.Mutex
+	since time.Time
+	rules map[[2]string]*RuleMetric
+}
+
+// NewRuleMetrics creates empty rule metrics
+func NewRuleMetrics() *RuleMetrics {
+	return &RuleMetrics{since: time.Now(), rules: make(map[[2]string]*RuleMetric)}
+}
+
+// ObserveRule implements MetricsRecorder
+func (r *RuleMetrics) ObserveRule(set, rule string, outcome RuleOutcome, latency time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := [2]string{set, rule}
+	stats, ok := r.rules[key]
+	if !ok {
+		stats = &RuleMetric{RuleSet: set, Rule: rule, Buckets: make([]uint64, len(LatencyBuckets)+1)}
+		r.rules[key] = stats
+	}
+	stats.Evaluations++
+	switch outcome {
+	case RuleFailed:
+		stats.Failures++
+	case RuleWarned:
+		stats.Warnings++
+	case RuleTimedOut:
+		stats.Timeouts++
+	}
+	stats.TotalLatency += latency
+	if latency > stats.MaxLatency {
+		stats.MaxLatency = latency
+	}
+	bucket := sort.Search(len(LatencyBuckets), func(i int) bool { return latency <= LatencyBuckets[i] })
+	stats.Buckets[bucket]++
+}
+
+// Since returns when counting started or was last reset
+func (r *RuleMetrics) Since() time.Time {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.since
+}
+
+// Snapshot returns the metrics of every rule evaluated so far, ordered by
+// rule set and rule
+func (r *RuleMetrics) Snapshot() []RuleMetric {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	out := make([]RuleMetric, 0, len(r.rules))
+	for _, stats := range r.rules {
+		copied := *stats
+		copied.Buckets = append([]uint64(nil), stats.Buckets...)
+		out = append(out, copied)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].RuleSet != out[j].RuleSet {
+			return out[i].RuleSet < out[j].RuleSet
+		}
+		return out[i].Rule < out[j].Rule
+	})
+	return out
+}
+
+// MostFailing returns the n rules with the most failures, most first
+func (r *RuleMetrics) MostFailing(n int) []RuleMetric {
+	out := r.Snapshot()
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Failures > out[j].Failures })
+	if n >= 0 && n < len(out) {
+		out = out[:n]
+	}
+	return out
+}
+
+// Slowest returns the n rules with the highest mean latency, slowest first
+func (r *RuleMetrics) Slowest(n int) []RuleMetric {
+	out := r.Snapshot()
+	sort.SliceStable(out, func(i, j int) bool { return out[i].MeanLatency() > out[j].MeanLatency() })
+	if n >= 0 && n < len(out) {
+		out = out[:n]
+	}
+	return out
+}
+
+// Reset clears all counters
+func (r *RuleMetrics) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.since = time.Now()
+	r.rules = make(map[[2]string]*RuleMetric)
+}
@@ -0,0 +1,209 @@
+package validation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// bodyKey carries the decoded request body in a request context
+type bodyKey struct{}
+
+// Body returns the request body decoded by Middleware: a pointer to a new
+// value of the target type, or a map[string]interface{} for rule sets
+// without a type
+func Body(ctx context.Context) (interface{}, bool) {
+	body := ctx.Value(bodyKey{})
+	return body, body != nil
+}
+
+// middlewareOptions holds settings for Middleware
+type middlewareOptions struct {
+	maxBody       int64
+	strictFields  bool
+	errorResponse func(w http.ResponseWriter, status int, err ValidationErrors)
+}
+
+// MiddlewareOption configures Middleware
+type MiddlewareOption func(*middlewareOptions)
+
+// WithMaxBodySize limits request bodies to n bytes; the default is 1 MiB
+func WithMaxBodySize(n int64) MiddlewareOption {
+	return func(o *middlewareOptions) {
+		o.maxBody = n
+	}
+}
+
+// WithStrictFields rejects bodies with fields the target type does not have
+func WithStrictFields() MiddlewareOption {
+	return func(o *middlewareOptions) {
+		o.strictFields = true
+	}
+}
+
+// WithErrorResponse replaces how rejections are written; the default
+// writes ValidationErrors as JSON
+func WithErrorResponse(write func(w http.ResponseWriter, status int, err ValidationErrors)) MiddlewareOption {
+	return func(o *middlewareOptions) {
+		o.errorResponse = write
+	}
+}
+
+// Middleware validates JSON request bodies before they reach the handler.
+// target is a *RuleSet (a schema; the body is decoded into its For type,
+// or a map), a reflect.Type, or a prototype value of a type whose rule
+// sets are registered with m. Rejected requests get a ValidationErrors
+// body: 415 for a non-JSON Content-Type, 413 for an oversized body, 400
+// for malformed JSON and 422 for violations. The decoded value is cached
+// for the handler, see Body, and the raw body stays readable. GET, HEAD
+// and OPTIONS requests pass through. Messages are localized from
+// Accept-Language when the context carries no locale
+func (m *Manager) Middleware(target interface{}, opts ...MiddlewareOption) func(http.Handler) http.Handler {
+	options := middlewareOptions{maxBody: 1 << 20, errorResponse: writeValidationErrors}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	set, _ := target.(*RuleSet)
+	var typ reflect.Type
+	switch t := target.(type) {
+	case *RuleSet:
+		typ = t.typ
+	case reflect.Type:
+		typ = indirectType(t)
+	case nil:
+		panic("validation: Middleware needs a rule set or a type")
+	default:
+		typ = indirectType(reflect.TypeOf(target))
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+				next.ServeHTTP(w, r)
+				return
+			}
+			if contentType := r.Header.Get("Content-Type"); contentType != "" && !isJSONMediaType(contentType) {
+				options.errorResponse(w, http.StatusUnsupportedMediaType, ValidationErrors{{
+					Code:    CodeType,
+					Message: "request body must be application/json",
+					Params:  map[string]interface{}{"expected": "application/json", "actual": contentType},
+				}})
+				return
+			}
+			data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, options.maxBody))
+			if err != nil {
+				var tooLarge *http.MaxBytesError
+				if errors.As(err, &tooLarge) {
+					options.errorResponse(w, http.StatusRequestEntityTooLarge, ValidationErrors{{
+						Code:    CodeLength,
+						Message: fmt.Sprintf("request body must be at most %d bytes", options.maxBody),
+						Params:  map[string]interface{}{"max": options.maxBody},
+						key:     "length.max",
+					}})
+					return
+				}
+				options.errorResponse(w, http.StatusBadRequest, ValidationErrors{{Code: CodeType, Message: "request body cannot be read"}})
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(data))
+
+			ctx := r.Context()
+			if Locale(ctx) == "" {
+				if locale := acceptedLocale(r.Header.Get("Accept-Language")); locale != "" {
+					ctx = WithLocale(ctx, locale)
+				}
+			}
+			value, status, violations := decodeBody(data, typ, options.strictFields)
+			if violations == nil {
+				var results *Results
+				if set != nil {
+					results = m.CheckWith(ctx, value, set)
+				} else {
+					results = m.Check(ctx, value)
+				}
+				status, violations = http.StatusUnprocessableEntity, results.Violations
+			}
+			if len(violations) > 0 {
+				m.logger.Debugf("Rejected %s %s: %v", r.Method, r.URL.Path, ValidationErrors(violations))
+				options.errorResponse(w, status, violations)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(ctx, bodyKey{}, value)))
+		})
+	}
+}
+
+// decodeBody decodes a JSON body into a new value of typ, or a map when
+// typ is nil, reporting the response status for a body that cannot be used
+func decodeBody(data []byte, typ reflect.Type, strict bool) (interface{}, int, ValidationErrors) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, http.StatusUnprocessableEntity, ValidationErrors{{Code: CodeRequired, Message: "request body is required"}}
+	}
+	var target interface{}
+	if typ != nil {
+		target = reflect.New(typ).Interface()
+	} else {
+		target = new(map[string]interface{})
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if strict {
+		decoder.DisallowUnknownFields()
+	}
+	if err := decoder.Decode(target); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if errors.As(err, &typeErr) {
+			return nil, http.StatusUnprocessableEntity, ValidationErrors{typeViolationAt(typeErr)}
+		}
+		return nil, http.StatusBadRequest, ValidationErrors{{Code: CodeType, Message: "request body is not valid JSON: " + err.Error()}}
+	}
+	if decoder.More() {
+		return nil, http.StatusBadRequest, ValidationErrors{{Code: CodeType, Message: "request body must be a single JSON value"}}
+	}
+	if typ == nil {
+		return *target.(*map[string]interface{}), 0, nil
+	}
+	return target, 0, nil
+}
+
+// typeViolationAt reports a JSON value of the wrong type at its field path
+func typeViolationAt(err *json.UnmarshalTypeError) Violation {
+	expected := err.Type.String()
+	return Violation{
+		Path:    err.Field,
+		Code:    CodeType,
+		Message: fmt.Sprintf("must be a %s, got %s", expected, err.Value),
+		Params:  map[string]interface{}{"expected": expected, "actual": err.Value},
+	}
+}
+
+// isJSONMediaType accepts application/json and +json media types
+func isJSONMediaType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && (mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"))
+}
+
+// acceptedLocale returns the first language of an Accept-Language header
+func acceptedLocale(header string) string {
+	for _, part := range strings.Split(header, ",") {
+		tag := strings.TrimSpace(strings.Split(part, ";")[0])
+		if tag != "" && tag != "*" {
+			return tag
+		}
+	}
+	return ""
+}
+
+// writeValidationErrors writes err as a JSON response
+func writeValidationErrors(w http.ResponseWriter, status int, err ValidationErrors) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(err)
+}
@@ -0,0 +1,120 @@
+package validation
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// CodeCanceled is reported when the context is done before every element
+// of a collection was validated
+const CodeCanceled = "canceled"
+
+// ParallelEach is Each for large lists and maps, or slow element rules such
+// as remote or store lookups: up to workers elements (GOMAXPROCS when not
+// positive) are checked at once. Violations come back in input order with
+// the paths Each reports, so results are deterministic; with
+// Config.FailFast no element after the first failing one is reported.
+// When ctx is done, elements not yet checked are skipped and a CodeCanceled
+// violation follows those found so far
+func ParallelEach(workers int, rules ...Rule) Rule {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	return RuleFunc(func(ctx context.Context, value interface{}) []Violation {
+		rv := reflect.ValueOf(indirect(value))
+		switch rv.Kind() {
+		case reflect.Invalid:
+			return nil
+		case reflect.Slice, reflect.Array, reflect.Map:
+		default:
+			return []Violation{typeViolation("list or map", value)}
+		}
+
+		mode := modeOf(ctx)
+		if violation, ok := mode.exceeds(rv.Len()); ok {
+			return []Violation{violation}
+		}
+		mode.depth++
+		ctx = context.WithValue(ctx, checkModeKey{}, mode)
+		elements := collectionElements(rv)
+
+		found := make([][]Violation, len(elements))
+		checked := make([]bool, len(elements))
+		var failed atomic.Bool
+		indexes := make(chan int)
+		var wg sync.WaitGroup
+		pool := workers
+		if pool > len(elements) {
+			pool = len(elements)
+		}
+		for w := 0; w < pool; w++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := range indexes {
+					if ctx.Err() != nil {
+						continue
+					}
+					violations := All(rules...).Check(ctx, elements[i].value)
+					for j := range violations {
+						violations[j].Path = joinPath(elements[i].path, violations[j].Path)
+						if mode.failFast && mode.blocks(violations[j]) {
+							failed.Store(true)
+						}
+					}
+					found[i], checked[i] = violations, true
+				}
+			}()
+		}
+
+	dispatch:
+		for i := range elements {
+			// Check first so a done context never wins a race against a
+			// ready worker in the select below
+			if ctx.Err() != nil || failed.Load() {
+				break
+			}
+			select {
+			case indexes <- i:
+			case <-ctx.Done():
+				break dispatch
+			}
+		}
+		close(indexes)
+		wg.Wait()
+
+		var violations []Violation
+		for i := range elements {
+			if !checked[i] {
+				if err := ctx.Err(); err != nil {
+					violations = append(violations, Violation{
+						Code:    CodeCanceled,
+						Message: fmt.Sprintf("was not fully validated: %v", err),
+						Params:  map[string]interface{}{"checked": countChecked(checked), "total": len(elements)},
+					})
+				}
+				break
+			}
+			var stopped bool
+			if violations, stopped = mode.stop(append(violations, found[i]...)); stopped {
+				break
+			}
+		}
+		return violations
+	})
+}
+
+// countChecked counts the elements that were validated
+func countChecked(checked []bool) int {
+	n := 0
+	for _, ok := range checked {
+		if ok {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,264 @@
+package validation
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// CodeDependency is reported by rule sets whose steps cannot be ordered
+const CodeDependency = "dependency"
+
+// stepRule is a named rule of a RuleSet that runs after its prerequisites
+type stepRule struct {
+	name  string
+	after []string
+	rule  Rule
+}
+
+// Check implements Rule
+func (r stepRule) Check(ctx context.Context, value interface{}) []Violation {
+	return r.rule.Check(ctx, value)
+}
+
+// Step adds a named rule that runs after the steps named in after and is
+// skipped when one of them fails or is skipped, e.g. an expensive remote
+// check that only makes sense for well-formed input:
+//
+//	set.Step("vat-format", Field("vat", Pattern(`^[A-Z]{2}[0-9A-Z]+$`))).
+//		Step("vat-registry", Field("vat", registry), "vat-format")
+//
+// Only blocking violations fail a step. Steps and unnamed rules otherwise
+// run in the order they were added
+func (s *RuleSet) Step(name string, rule Rule, after ...string) *RuleSet {
+	s.steps++
+	return s.Add(stepRule{name: name, after: after, rule: rule})
+}
+
+// PlanStep is one rule in an execution plan
+type PlanStep struct {
+	// Name is the step name, or "#n" for the n-th rule when it has none
+	Name  string   `json:"name"`
+	After []string `json:"after,omitempty"`
+	// Stage is the length of the longest chain of prerequisites
+	Stage int `json:"stage"`
+}
+
+// Plan is the order in which a rule set runs its rules
+type Plan struct {
+	RuleSet string     `json:"rule_set"`
+	Steps   []PlanStep `json:"steps"`
+	order   []int
+}
+
+// Plan orders the rules of the set by their dependencies, reporting
+// duplicate step names, unknown prerequisites and cycles
+func (s *RuleSet) Plan() (*Plan, error) {
+	names := make(map[string]int)
+	for i, rule := range s.rules {
+		if step, ok := rule.(stepRule); ok {
+			if _, dup := names[step.name]; dup || step.name == "" {
+				return nil, fmt.Errorf("step %q: names must be unique and not empty", step.name)
+			}
+			names[step.name] = i
+		}
+	}
+	deps := make([][]int, len(s.rules))
+	for i, rule := range s.rules {
+		step, ok := rule.(stepRule)
+		if !ok {
+			continue
+		}
+		for _, name := range step.after {
+			j, ok := names[name]
+			if !ok {
+				return nil, fmt.Errorf("step %q: unknown prerequisite %q", step.name, name)
+			}
+			deps[i] = append(deps[i], j)
+		}
+	}
+	if cycle := findCycle(s.rules, deps); cycle != nil {
+		return nil, fmt.Errorf("dependency cycle: %s", strings.Join(cycle, " -> "))
+	}
+
+	// Kahn's algorithm, always taking the earliest added rule that is ready
+	plan := &Plan{RuleSet: s.name, order: make([]int, 0, len(s.rules))}
+	stage := make([]int, len(s.rules))
+	done := make([]bool, len(s.rules))
+	for len(plan.order) < len(s.rules) {
+		for i := range s.rules {
+			if done[i] || !allDone(deps[i], done) {
+				continue
+			}
+			for _, j := range deps[i] {
+				if stage[j]+1 > stage[i] {
+					stage[i] = stage[j] + 1
+				}
+			}
+			done[i] = true
+			plan.order = append(plan.order, i)
+			step := PlanStep{Name: ruleName(s.rules, i), Stage: stage[i]}
+			if r, ok := s.rules[i].(stepRule); ok {
+				step.After = r.after
+			}
+			plan.Steps = append(plan.Steps, step)
+			break
+		}
+	}
+	return plan, nil
+}
+
+// allDone reports whether every rule in deps has been planned
+func allDone(deps []int, done []bool) bool {
+	for _, j := range deps {
+		if !done[j] {
+			return false
+		}
+	}
+	return true
+}
+
+// ruleName names the i-th rule for plans and errors
+func ruleName(rules []Rule, i int) string {
+	if step, ok := rules[i].(stepRule); ok {
+		return step.name
+	}
+	return fmt.Sprintf("#%d", i+1)
+}
+
+// findCycle returns the names along a dependency cycle, or nil
+func findCycle(rules []Rule, deps [][]int) []string {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make([]int, len(rules))
+	var stack []int
+	var visit func(i int) []string
+	visit = func(i int) []string {
+		state[i] = visiting
+		stack = append(stack, i)
+		for _, j := range deps[i] {
+			switch state[j] {
+			case visiting:
+				var cycle []string
+				for k := len(stack) - 1; k >= 0; k-- {
+					cycle = append([]string{ruleName(rules, stack[k])}, cycle...)
+					if stack[k] == j {
+						break
+					}
+				}
+				return append(cycle, ruleName(rules, j))
+			case unvisited:
+				if cycle := visit(j); cycle != nil {
+					return cycle
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[i] = visited
+		return nil
+	}
+	for i := range rules {
+		if state[i] == unvisited {
+			if cycle := visit(i); cycle != nil {
+				return cycle
+			}
+		}
+	}
+	return nil
+}
+
+// String lists the steps in execution order by stage
+func (p *Plan) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "rule set %s:\n", p.RuleSet)
+	for _, step := range p.Steps {
+		fmt.Fprintf(&b, "  [%d] %s", step.Stage, step.Name)
+		if len(step.After) > 0 {
+			fmt.Fprintf(&b, " (after %s)", strings.Join(step.After, ", "))
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// DOT renders the plan as a Graphviz digraph, edges pointing from a
+// prerequisite to its dependents
+func (p *Plan) DOT() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "digraph %q {\n\trankdir=LR;\n", p.RuleSet)
+	for _, step := range p.Steps {
+		fmt.Fprintf(&b, "\t%q;\n", step.Name)
+	}
+	for _, step := range p.Steps {
+		for _, prerequisite := range step.After {
+			fmt.Fprintf(&b, "\t%q -> %q;\n", prerequisite, step.Name)
+		}
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// run checks value with the rules of the set in plan order; rules for
+// which include returns false are treated as passing
+func (s *RuleSet) run(ctx context.Context, value interface{}, include func(Rule) bool) []Violation {
+	order := make([]int, len(s.rules))
+	for i := range order {
+		order[i] = i
+	}
+	if s.steps > 0 {
+		plan, err := s.Plan()
+		if err != nil {
+			return []Violation{{Code: CodeDependency, Message: fmt.Sprintf("rule set %s: %v", s.name, err)}}
+		}
+		order = plan.order
+	}
+
+	mode := modeOf(ctx)
+	var violations []Violation
+	var failed map[string]bool
+	for _, i := range order {
+		rule := s.rules[i]
+		step, isStep := rule.(stepRule)
+		if isStep && anyFailed(step.after, failed) {
+			failed = markFailed(failed, step.name)
+			continue
+		}
+		if include != nil && !include(rule) {
+			continue
+		}
+		found := observeRule(ctx, s.name, ruleLabel(s.rules, i), rule, value)
+		for _, v := range found {
+			if isStep && mode.blocks(v) {
+				failed = markFailed(failed, step.name)
+				break
+			}
+		}
+		var stopped bool
+		if violations, stopped = mode.stop(append(violations, found...)); stopped {
+			break
+		}
+	}
+	return violations
+}
+
+// anyFailed reports whether one of names failed or was skipped
+func anyFailed(names []string, failed map[string]bool) bool {
+	for _, name := range names {
+		if failed[name] {
+			return true
+		}
+	}
+	return false
+}
+
+// markFailed records a failed or skipped step
+func markFailed(failed map[string]bool, name string) map[string]bool {
+	if failed == nil {
+		failed = make(map[string]bool)
+	}
+	failed[name] = true
+	return failed
+}
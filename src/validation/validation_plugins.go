@@ -0,0 +1,276 @@
+package validation
+
+import (
+	"fmt"
+	"plugin"
+	"sort"
+	"sync"
+)
+
+// RulePack is a bundle of rules distributed apart from the application,
+// e.g. an organization's proprietary compliance checks. Packs register
+// themselves with RegisterRulePack from an init function, so a blank
+// import makes them available, or are built as Go plugins and opened with
+// OpenRulePack
+type RulePack interface {
+	// Name identifies the pack; it must be unique
+	Name() string
+	// Version is reported in logs and by InstalledPacks
+	Version() string
+	// Install declares what the pack contributes
+	Install(r *PackRegistrar) error
+}
+
+// PackRegistrar collects the contributions of a RulePack; rule sets,
+// messages and hooks are applied to the manager only if Install succeeds
+// and its rule sets do not clash with registered ones
+type PackRegistrar struct {
+	sets     []*RuleSet
+	messages map[string]map[string]string
+	hooks    []Hook
+}
+
+// AddRuleSets contributes rule sets; they are removed again by UninstallPack
+func (r *PackRegistrar) AddRuleSets(sets ...*RuleSet) {
+	r.sets = append(r.sets, sets...)
+}
+
+// AddDefinitions compiles and contributes declarative rule sets
+func (r *PackRegistrar) AddDefinitions(definitions ...RuleSetDefinition) error {
+	sets, err := CompileRules(definitions)
+	if err != nil {
+		return err
+	}
+	r.AddRuleSets(sets...)
+	return nil
+}
+
+// RegisterFormat registers a named format right away, so the pack's rules
+// can use it; formats are process-wide and stay after UninstallPack
+func (r *PackRegistrar) RegisterFormat(name string, check FormatFunc) {
+	RegisterFormat(name, check)
+}
+
+// RegisterCountryFormat registers a country format right away; like
+// formats it is process-wide
+func (r *PackRegistrar) RegisterCountryFormat(kind, country string, check FormatFunc) {
+	RegisterCountryFormat(kind, country, check)
+}
+
+// AddMessages contributes message templates for the pack's codes to the
+// manager's catalog
+func (r *PackRegistrar) AddMessages(locale string, templates map[string]string) {
+	if r.messages == nil {
+		r.messages = make(map[string]map[string]string)
+	}
+	if r.messages[locale] == nil {
+		r.messages[locale] = make(map[string]string, len(templates))
+	}
+	for key, template := range templates {
+		r.messages[locale][key] = template
+	}
+}
+
+// RegisterHook contributes a hook; it is removed by UninstallPack
+func (r *PackRegistrar) RegisterHook(hook Hook) {
+	r.hooks = append(r.hooks, hook)
+}
+
+// installedPack is a pack installed into a manager
+type installedPack struct {
+	version string
+	sets    []string
+	unhook  []func()
+}
+
+// PackInfo describes an installed pack
+type PackInfo struct {
+	Name     string   `json:"name"`
+	Version  string   `json:"version"`
+	RuleSets []string `json:"rule_sets"`
+}
+
+// rulePacks holds the packs registered with RegisterRulePack
+var rulePacks = struct {
+	sync.RWMutex
+	byName map[string]RulePack
+}{byName: make(map[string]RulePack)}
+
+// RegisterRulePack makes pack available to LoadRulePacks, typically from
+// an init function of the package implementing it; registering a name
+// twice is an error
+func RegisterRulePack(pack RulePack) error {
+	if pack == nil || pack.Name() == "" {
+		return fmt.Errorf("register rule pack: a name is required")
+	}
+	rulePacks.Lock()
+	defer rulePacks.Unlock()
+	if _, exists := rulePacks.byName[pack.Name()]; exists {
+		return fmt.Errorf("register rule pack %q: already registered", pack.Name())
+	}
+	rulePacks.byName[pack.Name()] = pack
+	return nil
+}
+
+// RulePacks lists the registered pack names in sorted order
+func RulePacks() []string {
+	rulePacks.RLock()
+	defer rulePacks.RUnlock()
+	names := make([]string, 0, len(rulePacks.byName))
+	for name := range rulePacks.byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// OpenRulePack loads a pack built with -buildmode=plugin; the plugin must
+// export a variable or function named Pack holding or returning a RulePack.
+// The pack is returned, not registered or installed
+func OpenRulePack(path string) (RulePack, error) {
+	p, err := plugin.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open rule pack %s: %w", path, err)
+	}
+	symbol, err := p.Lookup("Pack")
+	if err != nil {
+		return nil, fmt.Errorf("open rule pack %s: %w", path, err)
+	}
+	switch pack := symbol.(type) {
+	case *RulePack:
+		return *pack, nil
+	case func() RulePack:
+		return pack(), nil
+	case RulePack:
+		return pack, nil
+	}
+	return nil, fmt.Errorf("open rule pack %s: Pack is a %T, not a RulePack", path, symbol)
+}
+
+// LoadRulePacks installs the named registered packs, or all of them when
+// no name is given, stopping at the first that fails
+func (m *Manager) LoadRulePacks(names ...string) error {
+	if len(names) == 0 {
+		names = RulePacks()
+	}
+	for _, name := range names {
+		rulePacks.RLock()
+		pack, ok := rulePacks.byName[name]
+		rulePacks.RUnlock()
+		if !ok {
+			return fmt.Errorf("load rule pack %q: not registered", name)
+		}
+		if err := m.InstallPack(pack); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// InstallPack applies what pack contributes to the manager. No rule set,
+// message or hook is applied when Install fails, a rule set is invalid or
+// its name is taken, or a pack of the same name is installed
+func (m *Manager) InstallPack(pack RulePack) error {
+	name := pack.Name()
+	registrar := &PackRegistrar{}
+	if err := pack.Install(registrar); err != nil {
+		return fmt.Errorf("install rule pack %q: %w", name, err)
+	}
+	origin := "pack:" + name
+
+	m.rulesMu.Lock()
+	if _, exists := m.packs[name]; exists {
+		m.rulesMu.Unlock()
+		return fmt.Errorf("install rule pack %q: already installed", name)
+	}
+	installed := installedPack{version: pack.Version()}
+	for _, set := range registrar.sets {
+		if set == nil || set.name == "" {
+			m.rulesMu.Unlock()
+			return fmt.Errorf("install rule pack %q: rule set names are required", name)
+		}
+		if _, err := set.Plan(); err != nil {
+			m.rulesMu.Unlock()
+			return fmt.Errorf("install rule pack %q: rule set %q: %w", name, set.name, err)
+		}
+		for _, existing := range m.ruleSets {
+			if existing.name == set.name {
+				m.rulesMu.Unlock()
+				return fmt.Errorf("install rule pack %q: rule set %q: already registered", name, set.name)
+			}
+		}
+		installed.sets = append(installed.sets, set.name)
+	}
+	for _, set := range registrar.sets {
+		set.origin = origin
+	}
+	m.ruleSets = append(m.ruleSets, registrar.sets...)
+	if m.packs == nil {
+		m.packs = make(map[string]installedPack)
+	}
+	m.packs[name] = installed
+	if len(registrar.messages) > 0 && m.catalog == nil {
+		m.catalog = DefaultCatalog()
+	}
+	for locale, templates := range registrar.messages {
+		m.catalog.Add(locale, templates)
+	}
+	m.rulesVersion++
+	m.rulesMu.Unlock()
+
+	for _, hook := range registrar.hooks {
+		installed.unhook = append(installed.unhook, m.RegisterHook(hook))
+	}
+
+	if len(installed.unhook) > 0 {
+		m.rulesMu.Lock()
+		if _, ok := m.packs[name]; ok {
+			m.packs[name] = installed
+		}
+		m.rulesMu.Unlock()
+	}
+	m.logger.Printf("Installed rule pack %s %s with %d rule set(s)", name, installed.version, len(installed.sets))
+	return nil
+}
+
+// UninstallPack removes the rule sets and hooks of the named pack,
+// reporting whether it was installed; formats and messages stay
+func (m *Manager) UninstallPack(name string) bool {
+	m.rulesMu.Lock()
+	installed, ok := m.packs[name]
+	if !ok {
+		m.rulesMu.Unlock()
+		return false
+	}
+	delete(m.packs, name)
+	origin := "pack:" + name
+	kept := m.ruleSets[:0:0]
+	for _, set := range m.ruleSets {
+		if set.origin != origin {
+			kept = append(kept, set)
+		}
+	}
+	m.ruleSets = kept
+	m.rulesVersion++
+	m.rulesMu.Unlock()
+
+	for _, unhook := range installed.unhook {
+		unhook()
+	}
+	m.logger.Printf("Uninstalled rule pack %s", name)
+	return true
+}
+
+// InstalledPacks describes the installed packs sorted by name
+func (m *Manager) InstalledPacks() []PackInfo {
+	m.rulesMu.RLock()
+	defer m.rulesMu.RUnlock()
+	infos := make([]PackInfo, 0, len(m.packs))
+	for name, installed := range m.packs {
+		infos = append(infos, PackInfo{Name: name, Version: installed.version, RuleSets: installed.sets})
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].Name < infos[j].Name
+	})
+	return infos
+}
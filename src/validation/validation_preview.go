@@ -0,0 +1,180 @@
+package validation
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// Preview compares the outcome of the active rule sets with the outcome
+// had a candidate rule set been active
+type Preview struct {
+	RuleSet   string   `json:"rule_set"`
+	Current   *Results `json:"current"`
+	Candidate *Results `json:"candidate"`
+	// Added holds the candidate's violations and warnings that the active
+	// rules do not report; Removed holds those only the active rules report
+	Added   []Violation `json:"added,omitempty"`
+	Removed []Violation `json:"removed,omitempty"`
+}
+
+// NewlyFails reports whether data passes today and fails with the candidate
+func (p *Preview) NewlyFails() bool {
+	return p.Current.Valid() && !p.Candidate.Valid()
+}
+
+// NewlyPasses reports whether data fails today and passes with the candidate
+func (p *Preview) NewlyPasses() bool {
+	return !p.Current.Valid() && p.Candidate.Valid()
+}
+
+// Preview evaluates data as if candidate were active, replacing the
+// registered rule set of the same name or joining the others when there is
+// none, and reports how the outcome would change. It is a dry run: the
+// candidate is not registered, results are neither cached nor counted in
+// rule metrics, and Stateful rules are skipped on both sides
+func (m *Manager) Preview(ctx context.Context, data interface{}, candidate *RuleSet) (*Preview, error) {
+	if candidate == nil || candidate.name == "" {
+		return nil, fmt.Errorf("preview rule set: a name is required")
+	}
+	if _, err := candidate.Plan(); err != nil {
+		return nil, fmt.Errorf("preview rule set %q: %w", candidate.name, err)
+	}
+	m.mu.RLock()
+	config := m.config
+	m.mu.RUnlock()
+
+	m.rulesMu.RLock()
+	current := append([]*RuleSet(nil), m.ruleSets...)
+	catalog := m.catalog
+	m.rulesMu.RUnlock()
+	proposed := make([]*RuleSet, 0, len(current)+1)
+	replaced := false
+	for _, set := range current {
+		if set.name == candidate.name {
+			set, replaced = candidate, true
+		}
+		proposed = append(proposed, set)
+	}
+	if !replaced {
+		proposed = append(proposed, candidate)
+	}
+
+	preview := &Preview{RuleSet: candidate.name}
+	preview.Current = m.preview(ctx, data, current, config, catalog)
+	preview.Candidate = m.preview(ctx, data, proposed, config, catalog)
+	before := previewViolations(preview.Current)
+	after := previewViolations(preview.Candidate)
+	preview.Added = difference(after, before)
+	preview.Removed = difference(before, after)
+	return preview, nil
+}
+
+// preview checks data against sets without caching or metrics
+func (m *Manager) preview(ctx context.Context, data interface{}, sets []*RuleSet, config *Config, catalog *Catalog) *Results {
+	if data == nil {
+		return &Results{Violations: []Violation{{Code: CodeRequired, Message: "data cannot be nil"}}}
+	}
+	mode := newCheckMode(config)
+	mode.preview = true
+	value, violations := evaluate(context.WithValue(ctx, checkModeKey{}, mode), data, sets, Profile(ctx), mode)
+	if value == nil {
+		return &Results{Violations: []Violation{{Code: CodeRequired, Message: "data cannot be nil"}}}
+	}
+	return mode.results(catalog, Locale(ctx), m.maskViolations(value, violations))
+}
+
+// previewViolations lists the violations and warnings of results
+func previewViolations(results *Results) []Violation {
+	return append(append([]Violation(nil), results.Violations...), results.Warnings...)
+}
+
+// difference returns the violations in a that b does not report, compared
+// by path, code and severity
+func difference(a, b []Violation) []Violation {
+	seen := make(map[[3]string]int)
+	for _, v := range b {
+		seen[previewKey(v)]++
+	}
+	var out []Violation
+	for _, v := range a {
+		key := previewKey(v)
+		if seen[key] > 0 {
+			seen[key]--
+			continue
+		}
+		out = append(out, v)
+	}
+	return out
+}
+
+// previewKey identifies a violation across two evaluations
+func previewKey(v Violation) [3]string {
+	return [3]string{v.Path, v.Code, v.Severity.String()}
+}
+
+// PreviewReport summarises the previews of many samples, e.g. recent
+// production traffic, before rolling out a rule set
+type PreviewReport struct {
+	RuleSet      string `json:"rule_set"`
+	Total        int    `json:"total"`
+	NewlyFailing int    `json:"newly_failing"`
+	NewlyPassing int    `json:"newly_passing"`
+	// Added counts the violations the candidate adds, by rule
+	Added []RuleStats `json:"added"`
+	// Failing lists the indices of the samples that would newly fail
+	Failing []int `json:"failing,omitempty"`
+}
+
+// PreviewBatch previews every sample against candidate
+func (m *Manager) PreviewBatch(ctx context.Context, samples []interface{}, candidate *RuleSet) (*PreviewReport, error) {
+	report := &PreviewReport{Total: len(samples)}
+	if candidate != nil {
+		report.RuleSet = candidate.name
+	}
+	counts := make(map[[2]string]*RuleStats)
+	for i, sample := range samples {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		preview, err := m.Preview(ctx, sample, candidate)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case preview.NewlyFails():
+			report.NewlyFailing++
+			report.Failing = append(report.Failing, i)
+		case preview.NewlyPasses():
+			report.NewlyPassing++
+		}
+		for _, v := range preview.Added {
+			path := indexPattern.ReplaceAllString(v.Path, "[]")
+			stats, ok := counts[[2]string{path, v.Code}]
+			if !ok {
+				stats = &RuleStats{Path: path, Code: v.Code}
+				counts[[2]string{path, v.Code}] = stats
+			}
+			if v.Severity == SeverityWarning {
+				stats.Warnings++
+			} else {
+				stats.Failures++
+			}
+		}
+	}
+	report.Added = make([]RuleStats, 0, len(counts))
+	for _, stats := range counts {
+		report.Added = append(report.Added, *stats)
+	}
+	sort.Slice(report.Added, func(i, j int) bool {
+		a, b := report.Added[i], report.Added[j]
+		if a.Failures != b.Failures {
+			return a.Failures > b.Failures
+		}
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		return a.Code < b.Code
+	})
+	return report, nil
+}
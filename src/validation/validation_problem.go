@@ -0,0 +1,279 @@
+package validation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// ProblemContentType is the media type of RFC 7807 problem details
+const ProblemContentType = "application/problem+json"
+
+// Problem is an RFC 7807 problem details object. Validation problems list
+// their failures in InvalidParams, serialized as "invalid-params";
+// Extensions become further top-level members
+type Problem struct {
+	Type          string
+	Title         string
+	Status        int
+	Detail        string
+	Instance      string
+	InvalidParams []InvalidParam
+	Extensions    map[string]interface{}
+}
+
+// InvalidParam is the failure of one field: Name is the violation path and
+// Pointer the same location as an RFC 6901 JSON pointer
+type InvalidParam struct {
+	Name      string                 `json:"name"`
+	Pointer   string                 `json:"pointer"`
+	Reason    string                 `json:"reason"`
+	Code      string                 `json:"code"`
+	ErrorCode string                 `json:"error_code"`
+	DocURL    string                 `json:"doc_url,omitempty"`
+	Params    map[string]interface{} `json:"params,omitempty"`
+}
+
+// Error returns the title and detail, so a Problem can be returned as an error
+func (p *Problem) Error() string {
+	if p.Detail == "" {
+		return p.Title
+	}
+	return p.Title + ": " + p.Detail
+}
+
+// MarshalJSON encodes the standard members, "invalid-params" and the
+// extensions, which cannot replace standard members
+func (p *Problem) MarshalJSON() ([]byte, error) {
+	members := make(map[string]interface{}, len(p.Extensions)+6)
+	for key, value := range p.Extensions {
+		members[key] = value
+	}
+	members["type"] = p.Type
+	members["title"] = p.Title
+	members["status"] = p.Status
+	if p.Detail != "" {
+		members["detail"] = p.Detail
+	}
+	if p.Instance != "" {
+		members["instance"] = p.Instance
+	}
+	if len(p.InvalidParams) > 0 {
+		members["invalid-params"] = p.InvalidParams
+	}
+	return json.Marshal(members)
+}
+
+// ProblemType describes the problem reported for an error; Type is
+// resolved against the formatter's base URI, e.g. "token-expired"
+type ProblemType struct {
+	Status int
+	Type   string
+	Title  string
+}
+
+// problemMapping maps errors matching target to a problem type
+type problemMapping struct {
+	target error
+	typ    ProblemType
+}
+
+// problemTypes holds the mappings registered with RegisterProblem
+var problemTypes = struct {
+	sync.RWMutex
+	mappings []problemMapping
+}{mappings: []problemMapping{
+	{ErrCircuitOpen, ProblemType{Status: http.StatusServiceUnavailable, Type: "unavailable", Title: "Service unavailable"}},
+	{ErrRecordTooLarge, ProblemType{Status: http.StatusRequestEntityTooLarge, Type: "too-large", Title: "Record too large"}},
+	{context.DeadlineExceeded, ProblemType{Status: http.StatusGatewayTimeout, Type: "timeout", Title: "Request timed out"}},
+}}
+
+// RegisterProblem reports errors matching target with errors.Is as typ
+// in every ProblemFormatter, e.g. for a package's sentinel errors:
+//
+//	RegisterProblem(ErrTokenExpired, ProblemType{Status: 401, Type: "token-expired", Title: "Token expired"})
+//
+// Later registrations take precedence
+func RegisterProblem(target error, typ ProblemType) {
+	problemTypes.Lock()
+	defer problemTypes.Unlock()
+	problemTypes.mappings = append(problemTypes.mappings, problemMapping{target: target, typ: typ})
+}
+
+// ProblemFormatter converts errors into problem details: ValidationErrors
+// become 422 problems with one invalid param per violation, registered
+// errors their problem type, and anything else a 500 problem without the
+// error text unless WithErrorDetails is set
+type ProblemFormatter struct {
+	typeBase string
+	details  bool
+	explain  []ExplainOption
+	mappings []problemMapping
+}
+
+// ProblemOption configures a ProblemFormatter
+type ProblemOption func(*ProblemFormatter)
+
+// WithProblemTypeBase resolves problem types against base, e.g.
+// "https://api.example.com/problems/"; without one types are "about:blank"
+func WithProblemTypeBase(base string) ProblemOption {
+	return func(f *ProblemFormatter) {
+		f.typeBase = base
+	}
+}
+
+// WithProblemMapping reports errors matching target as typ, taking
+// precedence over RegisterProblem
+func WithProblemMapping(target error, typ ProblemType) ProblemOption {
+	return func(f *ProblemFormatter) {
+		f.mappings = append(f.mappings, problemMapping{target: target, typ: typ})
+	}
+}
+
+// WithErrorDetails includes the text of errors as the detail of every
+// problem; unmapped errors may reveal internals, so use it in development
+func WithErrorDetails() ProblemOption {
+	return func(f *ProblemFormatter) {
+		f.details = true
+	}
+}
+
+// WithProblemExplain sets how violations are explained in invalid params,
+// e.g. WithDocsURL to link their codes
+func WithProblemExplain(opts ...ExplainOption) ProblemOption {
+	return func(f *ProblemFormatter) {
+		f.explain = opts
+	}
+}
+
+// NewProblemFormatter creates a formatter
+func NewProblemFormatter(opts ...ProblemOption) *ProblemFormatter {
+	f := &ProblemFormatter{}
+	for _, opt := range opts {
+		opt(f)
+	}
+	return f
+}
+
+// Problem converts err into problem details; nil errors have none
+func (f *ProblemFormatter) Problem(err error) *Problem {
+	if err == nil {
+		return nil
+	}
+	var problem *Problem
+	if errors.As(err, &problem) {
+		return problem
+	}
+	var violations ValidationErrors
+	if errors.As(err, &violations) {
+		return f.ValidationProblem(http.StatusUnprocessableEntity, violations)
+	}
+	if typ, ok := f.lookup(err); ok {
+		problem := f.newProblem(typ)
+		if f.details || typ.Status < http.StatusInternalServerError {
+			problem.Detail = err.Error()
+		}
+		return problem
+	}
+	problem = f.newProblem(ProblemType{Status: http.StatusInternalServerError, Type: "internal", Title: "Internal error"})
+	if f.details {
+		problem.Detail = err.Error()
+	}
+	return problem
+}
+
+// ValidationProblem converts violations into a problem with status, e.g.
+// 400 for a malformed request rather than 422
+func (f *ProblemFormatter) ValidationProblem(status int, violations ValidationErrors) *Problem {
+	problem := f.newProblem(ProblemType{Status: status, Type: "validation", Title: "Validation failed"})
+	problem.Detail = fmt.Sprintf("%d violation(s)", len(violations))
+	for _, e := range explain(violations, f.explain) {
+		problem.InvalidParams = append(problem.InvalidParams, InvalidParam{
+			Name:      e.Path,
+			Pointer:   JSONPointer(e.Path),
+			Reason:    e.Message,
+			Code:      e.Code,
+			ErrorCode: e.ErrorCode,
+			DocURL:    e.DocURL,
+			Params:    e.Params,
+		})
+	}
+	return problem
+}
+
+// WriteProblem writes err as an application/problem+json response; the
+// request path is the problem instance
+func (f *ProblemFormatter) WriteProblem(w http.ResponseWriter, r *http.Request, err error) {
+	problem := f.Problem(err)
+	if problem == nil {
+		return
+	}
+	if problem.Instance == "" && r != nil {
+		withInstance := *problem
+		withInstance.Instance = r.URL.Path
+		problem = &withInstance
+	}
+	writeProblem(w, problem)
+}
+
+// lookup finds the problem type of err, formatter mappings first
+func (f *ProblemFormatter) lookup(err error) (ProblemType, bool) {
+	for i := len(f.mappings) - 1; i >= 0; i-- {
+		if errors.Is(err, f.mappings[i].target) {
+			return f.mappings[i].typ, true
+		}
+	}
+	problemTypes.RLock()
+	defer problemTypes.RUnlock()
+	for i := len(problemTypes.mappings) - 1; i >= 0; i-- {
+		if errors.Is(err, problemTypes.mappings[i].target) {
+			return problemTypes.mappings[i].typ, true
+		}
+	}
+	return ProblemType{}, false
+}
+
+// newProblem starts a problem of typ
+func (f *ProblemFormatter) newProblem(typ ProblemType) *Problem {
+	problem := &Problem{Type: "about:blank", Title: typ.Title, Status: typ.Status}
+	if f.typeBase != "" && typ.Type != "" {
+		problem.Type = f.typeBase + typ.Type
+	}
+	if problem.Title == "" {
+		problem.Title = http.StatusText(typ.Status)
+	}
+	return problem
+}
+
+// writeProblem writes problem as the response
+func writeProblem(w http.ResponseWriter, problem *Problem) {
+	w.Header().Set("Content-Type", ProblemContentType)
+	w.WriteHeader(problem.Status)
+	_ = json.NewEncoder(w).Encode(problem)
+}
+
+// WithProblemDetails makes Middleware reject requests with problem details
+// from f rather than plain ValidationErrors
+func WithProblemDetails(f *ProblemFormatter) MiddlewareOption {
+	return WithErrorResponse(func(w http.ResponseWriter, status int, err ValidationErrors) {
+		writeProblem(w, f.ValidationProblem(status, err))
+	})
+}
+
+// JSONPointer converts a violation path such as "items[3].zip" into an
+// RFC 6901 JSON pointer such as "/items/3/zip"; the empty path is ""
+func JSONPointer(path string) string {
+	var b strings.Builder
+	for _, segment := range pathSegments(path) {
+		if strings.HasPrefix(segment, "[") && strings.HasSuffix(segment, "]") {
+			segment = segment[1 : len(segment)-1]
+		}
+		b.WriteByte('/')
+		b.WriteString(strings.NewReplacer("~", "~0", "/", "~1").Replace(segment))
+	}
+	return b.String()
+}
@@ -0,0 +1,83 @@
+package validation
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// profileKey carries the validation profile in a context
+type profileKey struct{}
+
+// WithProfile returns a context whose validations use the rule sets of
+// profile, e.g. "create" or "update", besides those shared by every profile
+func WithProfile(ctx context.Context, profile string) context.Context {
+	return context.WithValue(ctx, profileKey{}, profile)
+}
+
+// Profile returns the validation profile carried by ctx, or "" when none is set
+func Profile(ctx context.Context) string {
+	profile, _ := ctx.Value(profileKey{}).(string)
+	return profile
+}
+
+// Profiles restricts the set to validations under one of the named
+// profiles; a set without profiles applies to every validation
+func (s *RuleSet) Profiles(profiles ...string) *RuleSet {
+	s.profiles = append(s.profiles, profiles...)
+	return s
+}
+
+// inProfile reports whether the set applies under profile
+func (s *RuleSet) inProfile(profile string) bool {
+	if len(s.profiles) == 0 {
+		return true
+	}
+	for _, p := range s.profiles {
+		if p == profile {
+			return true
+		}
+	}
+	return false
+}
+
+// Profiles returns the profiles named by the registered rule sets in sorted order
+func (m *Manager) Profiles() []string {
+	m.rulesMu.RLock()
+	defer m.rulesMu.RUnlock()
+	seen := make(map[string]bool)
+	var profiles []string
+	for _, set := range m.ruleSets {
+		for _, p := range set.profiles {
+			if !seen[p] {
+				seen[p] = true
+				profiles = append(profiles, p)
+			}
+		}
+	}
+	sort.Strings(profiles)
+	return profiles
+}
+
+// ProcessWithProfile runs Process validating data under profile; a profile
+// no registered rule set names is an error rather than a silent pass
+func (m *Manager) ProcessWithProfile(ctx context.Context, profile string, data interface{}) (*Result, error) {
+	if !m.hasProfile(profile) {
+		return nil, fmt.Errorf("unknown validation profile %q", profile)
+	}
+	return m.Process(WithProfile(ctx, profile), data)
+}
+
+// hasProfile reports whether a registered rule set names profile
+func (m *Manager) hasProfile(profile string) bool {
+	m.rulesMu.RLock()
+	defer m.rulesMu.RUnlock()
+	for _, set := range m.ruleSets {
+		for _, p := range set.profiles {
+			if p == profile {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,265 @@
+package validation
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// FieldOptionFunc translates the value of a field option extension, such
+// as a message of constraints declared in a .proto file, into rules for
+// the field
+type FieldOptionFunc func(option interface{}) []Rule
+
+var (
+	fieldOptionsMu sync.RWMutex
+	fieldOptions   = make(map[protoreflect.FullName]fieldOption)
+	// messageRules caches the option rules per message, and is replaced
+	// whenever an option is registered
+	messageRules = new(sync.Map)
+)
+
+// fieldOption is a registered extension and its translation
+type fieldOption struct {
+	ext       protoreflect.ExtensionType
+	translate FieldOptionFunc
+}
+
+// RegisterFieldOption makes protobuf fields annotated with ext checked by
+// the rules translate returns for the option's value, e.g.
+//
+//	RegisterFieldOption(shopv1.E_Constraints, func(o interface{}) []Rule {
+//		c := o.(*shopv1.Constraints)
+//		return []Rule{Length(int(c.MinLen), int(c.MaxLen))}
+//	})
+//
+// Option rules run for every message, before the registered rule sets;
+// fields of proto2 messages labelled required are always Required
+func RegisterFieldOption(ext protoreflect.ExtensionType, translate FieldOptionFunc) {
+	fieldOptionsMu.Lock()
+	defer fieldOptionsMu.Unlock()
+	fieldOptions[ext.TypeDescriptor().FullName()] = fieldOption{ext: ext, translate: translate}
+	messageRules = new(sync.Map)
+}
+
+// messageName reports the full name of a protobuf message, such as
+// "shop.v1.Order", so rule definitions can name message types
+func messageName(value interface{}) (string, bool) {
+	msg, ok := value.(proto.Message)
+	if !ok {
+		return "", false
+	}
+	return string(msg.ProtoReflect().Descriptor().FullName()), true
+}
+
+// messageInput converts a protobuf message into the value rules see, a
+// map keyed by proto field name, and returns the rules from its field
+// options; the value is nil for a nil message
+func messageInput(msg proto.Message) (interface{}, Rule) {
+	m := msg.ProtoReflect()
+	if !m.IsValid() {
+		return nil, nil
+	}
+	return protoMessage(m), messageRule(m.Descriptor())
+}
+
+// messageRule applies the option rules of md, resolved lazily so that
+// recursive message types are fine
+func messageRule(md protoreflect.MessageDescriptor) Rule {
+	return RuleFunc(func(ctx context.Context, value interface{}) []Violation {
+		if value == nil {
+			return nil
+		}
+		fieldOptionsMu.RLock()
+		cache := messageRules
+		fieldOptionsMu.RUnlock()
+		if rule, ok := cache.Load(md.FullName()); ok {
+			return rule.(Rule).Check(ctx, value)
+		}
+		rule := compileMessageRule(md)
+		cache.Store(md.FullName(), rule)
+		return rule.Check(ctx, value)
+	})
+}
+
+// compileMessageRule collects the rules of every field of md
+func compileMessageRule(md protoreflect.MessageDescriptor) Rule {
+	fieldOptionsMu.RLock()
+	options := make([]fieldOption, 0, len(fieldOptions))
+	for _, option := range fieldOptions {
+		options = append(options, option)
+	}
+	fieldOptionsMu.RUnlock()
+
+	var rules []Rule
+	fields := md.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		var fieldRules []Rule
+		if fd.Cardinality() == protoreflect.Required {
+			fieldRules = append(fieldRules, Required())
+		}
+		if opts, ok := fd.Options().(*descriptorpb.FieldOptions); ok && opts != nil {
+			for _, option := range options {
+				if proto.HasExtension(opts, option.ext) {
+					fieldRules = append(fieldRules, option.translate(proto.GetExtension(opts, option.ext))...)
+				}
+			}
+		}
+		if nested := fd.Message(); nested != nil && !fd.IsMap() && !isWellKnown(nested) {
+			if fd.IsList() {
+				fieldRules = append(fieldRules, Each(messageRule(nested)))
+			} else {
+				fieldRules = append(fieldRules, messageRule(nested))
+			}
+		}
+		if wkt := fd.Message(); wkt != nil && !fd.IsMap() && isWellKnown(wkt) {
+			fieldRules = append(fieldRules, wellKnownRule(wkt.FullName(), fd.IsList()))
+		}
+		if len(fieldRules) > 0 {
+			rules = append(rules, Field(string(fd.Name()), fieldRules...))
+		}
+	}
+	return All(rules...)
+}
+
+// protoMessage converts a message to a map keyed by proto field name.
+// Fields with presence (messages, oneofs, optional and proto2 fields) are
+// absent when unset; other fields carry their zero value, as in Go
+func protoMessage(m protoreflect.Message) interface{} {
+	if value, ok := wellKnownValue(m); ok {
+		return value
+	}
+	out := make(map[string]interface{})
+	fields := m.Descriptor().Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		if fd.HasPresence() && !m.Has(fd) {
+			continue
+		}
+		out[string(fd.Name())] = protoField(fd, m.Get(fd))
+	}
+	return out
+}
+
+// protoField converts the value of a field
+func protoField(fd protoreflect.FieldDescriptor, v protoreflect.Value) interface{} {
+	switch {
+	case fd.IsList():
+		list := v.List()
+		out := make([]interface{}, list.Len())
+		for i := range out {
+			out[i] = protoScalar(fd, list.Get(i))
+		}
+		return out
+	case fd.IsMap():
+		out := make(map[string]interface{}, v.Map().Len())
+		v.Map().Range(func(key protoreflect.MapKey, value protoreflect.Value) bool {
+			out[key.String()] = protoScalar(fd.MapValue(), value)
+			return true
+		})
+		return out
+	}
+	return protoScalar(fd, v)
+}
+
+// protoScalar converts a single value; enums become their value names
+func protoScalar(fd protoreflect.FieldDescriptor, v protoreflect.Value) interface{} {
+	switch fd.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return protoMessage(v.Message())
+	case protoreflect.EnumKind:
+		if value := fd.Enum().Values().ByNumber(v.Enum()); value != nil {
+			return string(value.Name())
+		}
+		return int32(v.Enum())
+	}
+	return v.Interface()
+}
+
+// Full names of the well-known types converted to Go values
+const (
+	timestampName = "google.protobuf.Timestamp"
+	durationName  = "google.protobuf.Duration"
+)
+
+// isWellKnown reports whether a message is converted to a plain Go value:
+// Timestamp to time.Time, Duration to time.Duration and the wrappers to
+// the value they wrap
+func isWellKnown(md protoreflect.MessageDescriptor) bool {
+	switch md.FullName() {
+	case timestampName, durationName:
+		return true
+	}
+	return md.FullName().Parent() == "google.protobuf" && strings.HasSuffix(string(md.Name()), "Value") &&
+		md.Fields().Len() == 1 && md.Fields().Get(0).Name() == "value"
+}
+
+// wellKnownValue converts well-known types, leaving out-of-range
+// timestamps and durations as maps for wellKnownRule to report
+func wellKnownValue(m protoreflect.Message) (interface{}, bool) {
+	md := m.Descriptor()
+	if !isWellKnown(md) {
+		return nil, false
+	}
+	fields := md.Fields()
+	switch md.FullName() {
+	case timestampName, durationName:
+		seconds := m.Get(fields.ByName("seconds")).Int()
+		nanos := m.Get(fields.ByName("nanos")).Int()
+		if md.FullName() == timestampName {
+			if seconds < minTimestamp || seconds > maxTimestamp || nanos < 0 || nanos >= 1e9 {
+				return nil, false
+			}
+			return time.Unix(seconds, nanos).UTC(), true
+		}
+		if seconds < -maxDurationSeconds || seconds > maxDurationSeconds || nanos <= -1e9 || nanos >= 1e9 ||
+			(seconds > 0 && nanos < 0) || (seconds < 0 && nanos > 0) {
+			return nil, false
+		}
+		return time.Duration(seconds)*time.Second + time.Duration(nanos), true
+	}
+	return m.Get(fields.Get(0)).Interface(), true
+}
+
+// Limits of the seconds of valid timestamps, as documented in
+// timestamp.proto, and of durations time.Duration can hold (about 292
+// years, less than the 10000 duration.proto allows)
+const (
+	minTimestamp       = -62135596800 // 0001-01-01T00:00:00Z
+	maxTimestamp       = 253402300799 // 9999-12-31T23:59:59Z
+	maxDurationSeconds = int64(math.MaxInt64/time.Second) - 1
+)
+
+// wellKnownRule rejects timestamps and durations that could not be
+// converted because they are out of range
+func wellKnownRule(name protoreflect.FullName, list bool) Rule {
+	if name != timestampName && name != durationName {
+		return All()
+	}
+	expected := "valid timestamp"
+	if name == durationName {
+		expected = "valid duration"
+	}
+	check := RuleFunc(func(ctx context.Context, value interface{}) []Violation {
+		if _, ok := value.(map[string]interface{}); ok {
+			return []Violation{{
+				Code:    CodeType,
+				Message: fmt.Sprintf("must be a %s", expected),
+				Params:  map[string]interface{}{"expected": expected, "actual": string(name)},
+			}}
+		}
+		return nil
+	})
+	if list {
+		return Each(check)
+	}
+	return check
+}
@@ -0,0 +1,213 @@
+package validation
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// CodeRateLimit is reported when a key was seen too often within a window
+const CodeRateLimit = "rate_limit"
+
+// CounterStore counts events per key over time for RateLimit, e.g. an
+// in-process MemoryCounterStore or Redis sorted sets shared by replicas
+type CounterStore interface {
+	// Increment records an event for key at now and returns how many
+	// events, this one included, happened within window before now
+	Increment(ctx context.Context, key string, window time.Duration, now time.Time) (int, error)
+}
+
+// CounterStoreFunc adapts a function to CounterStore
+type CounterStoreFunc func(ctx context.Context, key string, window time.Duration, now time.Time) (int, error)
+
+// Increment implements CounterStore
+func (f CounterStoreFunc) Increment(ctx context.Context, key string, window time.Duration, now time.Time) (int, error) {
+	return f(ctx, key, window, now)
+}
+
+// rateOptions holds settings for RateLimit
+type rateOptions struct {
+	timeout   time.Duration
+	policy    RemotePolicy
+	now       func() time.Time
+	eventTime string
+	name      string
+}
+
+// RateOption configures RateLimit
+type RateOption func(*rateOptions)
+
+// WithRateTimeout bounds each store call; the default is 1s
+func WithRateTimeout(d time.Duration) RateOption {
+	return func(o *rateOptions) {
+		o.timeout = d
+	}
+}
+
+// WithRatePolicy sets the behaviour while the store fails; the default is
+// FailClosed, and FailOpen reports a warning instead
+func WithRatePolicy(policy RemotePolicy) RateOption {
+	return func(o *rateOptions) {
+		o.policy = policy
+	}
+}
+
+// WithRateClock replaces time.Now, e.g. in tests
+func WithRateClock(now func() time.Time) RateOption {
+	return func(o *rateOptions) {
+		o.now = now
+	}
+}
+
+// WithEventTime counts events at the time under path, a time.Time or an
+// RFC 3339 string, rather than when they are validated, for replaying
+// event streams; events without one use the clock
+func WithEventTime(path string) RateOption {
+	return func(o *rateOptions) {
+		o.eventTime = path
+	}
+}
+
+// WithRateName names the counter, by default after keyPath and window;
+// rules with the same name and store share their counts
+func WithRateName(name string) RateOption {
+	return func(o *rateOptions) {
+		o.name = name
+	}
+}
+
+// RateLimit allows at most limit values with the same key, the value under
+// keyPath such as a user ID or client IP, within any window, e.g.
+// RateLimit(store, "user_id", 5, time.Minute) for five submissions a
+// minute. Every value checked counts, rejected ones included; values
+// without a key pass uncounted. The rule is Stateful, so checks running it
+// are not cached and Preview skips it
+func RateLimit(store CounterStore, keyPath string, limit int, window time.Duration, opts ...RateOption) Rule {
+	options := rateOptions{timeout: time.Second, now: time.Now, name: fmt.Sprintf("%s/%s", keyPath, window)}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return StatefulRule(RuleFunc(func(ctx context.Context, value interface{}) []Violation {
+		raw, _ := Lookup(value, keyPath)
+		if isEmpty(raw) {
+			return nil
+		}
+		key := "rate:" + options.name + ":" + storeKey(indirect(raw))
+		now := options.now()
+		if options.eventTime != "" {
+			if at, ok := eventTime(value, options.eventTime); ok {
+				now = at
+			}
+		}
+		if options.timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, options.timeout)
+			defer cancel()
+		}
+
+		count, err := store.Increment(ctx, key, window, now)
+		if err != nil {
+			params := map[string]interface{}{"reason": unavailableReason(err)}
+			if options.policy == FailOpen {
+				return []Violation{{Path: keyPath, Code: CodeUnavailable, Message: fmt.Sprintf("not rate limited, counting failed: %v", err), Severity: SeverityWarning, Params: params}}
+			}
+			return []Violation{{Path: keyPath, Code: CodeUnavailable, Message: fmt.Sprintf("cannot be rate limited, counting failed: %v", err), Params: params}}
+		}
+		if count <= limit {
+			return nil
+		}
+		return []Violation{{
+			Path:    keyPath,
+			Code:    CodeRateLimit,
+			Message: fmt.Sprintf("exceeds %d per %s", limit, window),
+			Params:  map[string]interface{}{"limit": limit, "window": window.String(), "count": count},
+		}}
+	}))
+}
+
+// eventTime reads the time of an event under path
+func eventTime(value interface{}, path string) (time.Time, bool) {
+	raw, ok := Lookup(value, path)
+	if !ok {
+		return time.Time{}, false
+	}
+	switch t := indirect(raw).(type) {
+	case time.Time:
+		return t, true
+	case string:
+		parsed, err := time.Parse(time.RFC3339Nano, t)
+		return parsed, err == nil
+	}
+	return time.Time{}, false
+}
+
+// sweepEvery is how many increments a MemoryCounterStore takes between
+// dropping idle keys
+const sweepEvery = 1024
+
+// MemoryCounterStore is a sliding-window CounterStore held in memory; it
+// counts per process, so replicas need a shared store instead
+type MemoryCounterStore struct {
+	mu         sync.Mutex
+	keys       map[string]*eventLog
+	increments int
+}
+
+// eventLog is the sorted event times of one key
+type eventLog struct {
+	times  []time.Time
+	window time.Duration
+}
+
+// NewMemoryCounterStore creates an empty MemoryCounterStore
+func NewMemoryCounterStore() *MemoryCounterStore {
+	return &MemoryCounterStore{keys: make(map[string]*eventLog)}
+}
+
+// Increment implements CounterStore
+func (s *MemoryCounterStore) Increment(ctx context.Context, key string, window time.Duration, now time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.increments++; s.increments%sweepEvery == 0 {
+		s.sweep(now)
+	}
+	log, ok := s.keys[key]
+	if !ok {
+		log = &eventLog{}
+		s.keys[key] = log
+	}
+	log.window = window
+	// Events may arrive out of order when replayed by event time
+	i := sort.Search(len(log.times), func(i int) bool { return log.times[i].After(now) })
+	log.times = append(log.times, time.Time{})
+	copy(log.times[i+1:], log.times[i:])
+	log.times[i] = now
+	log.prune(now)
+
+	start := now.Add(-window)
+	count := 0
+	for _, t := range log.times {
+		if t.After(start) && !t.After(now) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// prune drops events too old to fall in any window ending at or after now
+func (l *eventLog) prune(now time.Time) {
+	start := now.Add(-l.window)
+	i := sort.Search(len(l.times), func(i int) bool { return l.times[i].After(start) })
+	l.times = append(l.times[:0], l.times[i:]...)
+}
+
+// sweep drops keys without events in their window
+func (s *MemoryCounterStore) sweep(now time.Time) {
+	for key, log := range s.keys {
+		if len(log.times) == 0 || !log.times[len(log.times)-1].After(now.Add(-log.window)) {
+			delete(s.keys, key)
+		}
+	}
+}
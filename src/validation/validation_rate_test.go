@@ -0,0 +1,74 @@
+package validation
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRateLimitIsNotCached(t *testing.T) {
+	m := NewManager(DefaultConfig())
+	m.EnableResultCache(time.Minute, 100)
+	limit := RateLimit(NewMemoryCounterStore(), "user", 2, time.Minute)
+	if err := m.Register(NewRuleSet("signup", When(Field("user", Required()), limit))); err != nil {
+		t.Fatal(err)
+	}
+	input := map[string]interface{}{"user": "alice"}
+	for i, want := range []bool{true, true, false, false} {
+		if got := m.Check(context.Background(), input).Valid(); got != want {
+			t.Errorf("check %d valid = %v, want %v", i+1, got, want)
+		}
+	}
+	if stats := m.ResultCacheStats(); stats.Hits != 0 {
+		t.Errorf("cache stats = %+v, want no hits", stats)
+	}
+
+	// Checks that never reach the rate limit are still cached
+	for i := 0; i < 2; i++ {
+		m.Check(context.Background(), map[string]interface{}{"other": 1})
+	}
+	if stats := m.ResultCacheStats(); stats.Hits != 1 {
+		t.Errorf("cache stats = %+v, want a hit for input without a user", stats)
+	}
+}
+
+func TestPreviewSkipsStatefulRules(t *testing.T) {
+	counted := 0
+	counter := CounterStoreFunc(func(ctx context.Context, key string, window time.Duration, now time.Time) (int, error) {
+		counted++
+		return 100, nil
+	})
+	taken := StoreFunc(func(ctx context.Context, field string, values []interface{}) ([]bool, error) {
+		counted++
+		return []bool{true}, nil
+	})
+	custom := StatefulRule(RuleFunc(func(ctx context.Context, value interface{}) []Violation {
+		counted++
+		return []Violation{{Code: "custom"}}
+	}))
+
+	m := NewManager(DefaultConfig())
+	if err := m.Register(NewRuleSet("signup", RateLimit(counter, "user", 1, time.Minute))); err != nil {
+		t.Fatal(err)
+	}
+	candidate := NewRuleSet("signup",
+		RateLimit(counter, "user", 1, time.Minute),
+		Field("email", UniqueIn(taken, "email")),
+		When(Field("email", Required()), Field("user", custom)),
+		Field("user", Length(10, 20)),
+	)
+	preview, err := m.Preview(context.Background(), map[string]interface{}{"user": "alice", "email": "a@example.com"}, candidate)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if counted != 0 {
+		t.Errorf("Preview ran stateful rules %d times", counted)
+	}
+	if !preview.Current.Valid() || len(preview.Added) != 1 || preview.Added[0].Code != CodeLength {
+		t.Errorf("preview = %+v, want only the length violation added", preview)
+	}
+
+	if m.Check(context.Background(), map[string]interface{}{"user": "alice"}).Valid() || counted != 1 {
+		t.Errorf("Check after Preview did not run the rate limit (%d runs)", counted)
+	}
+}
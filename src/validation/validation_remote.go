@@ -0,0 +1,328 @@
+package validation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Violation codes reported by RemoteRule
+const (
+	// CodeRemote is reported when the service rejects a value without details
+	CodeRemote = "remote"
+	// CodeUnavailable is reported when the service cannot be reached
+	CodeUnavailable = "unavailable"
+)
+
+// ErrCircuitOpen is returned while a RemoteRule's circuit breaker is open
+var ErrCircuitOpen = errors.New("circuit breaker open")
+
+// RemotePolicy decides how a RemoteRule treats an unreachable service
+type RemotePolicy int
+
+const (
+	// FailClosed rejects values while the service is unavailable; it is the default
+	FailClosed RemotePolicy = iota
+	// FailOpen accepts values while the service is unavailable, reporting a warning
+	FailOpen
+)
+
+// CircuitState is the state of a RemoteRule's circuit breaker
+type CircuitState int
+
+const (
+	// CircuitClosed lets calls through
+	CircuitClosed CircuitState = iota
+	// CircuitOpen fails calls without trying the service
+	CircuitOpen
+	// CircuitHalfOpen lets one trial call through after the cooldown
+	CircuitHalfOpen
+)
+
+// String returns string representation of CircuitState
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	default:
+		return "unknown"
+	}
+}
+
+// remoteRequest is the body POSTed to the validation service
+type remoteRequest struct {
+	Value interface{} `json:"value"`
+}
+
+// remoteResponse is the body expected back from the validation service
+type remoteResponse struct {
+	Valid      bool        `json:"valid"`
+	Violations []Violation `json:"violations"`
+}
+
+// RemoteRule outsources a check, such as address verification or tax-ID
+// validation, to an HTTP service. It POSTs {"value": ...} and expects a
+// 200 response of {"valid": bool, "violations": [{"path", "code",
+// "message"}]}. Timeouts, 408, 429 and 5xx responses are retried with
+// backoff; repeated failures open a circuit breaker, and while the service
+// is unavailable the policy decides whether values pass
+type RemoteRule struct {
+	name    string
+	url     string
+	client  *http.Client
+	timeout time.Duration
+	retries int
+	policy  RemotePolicy
+	headers http.Header
+	breaker *circuitBreaker
+	backoff time.Duration
+}
+
+// RemoteOption configures a RemoteRule
+type RemoteOption func(*RemoteRule)
+
+// WithRemoteClient sets the HTTP client; the default is http.DefaultClient
+func WithRemoteClient(client *http.Client) RemoteOption {
+	return func(r *RemoteRule) {
+		r.client = client
+	}
+}
+
+// WithRemoteTimeout bounds each attempt; the default is 2s
+func WithRemoteTimeout(d time.Duration) RemoteOption {
+	return func(r *RemoteRule) {
+		r.timeout = d
+	}
+}
+
+// WithRemoteRetries sets how often a failed attempt is retried; the default is 2
+func WithRemoteRetries(n int) RemoteOption {
+	return func(r *RemoteRule) {
+		r.retries = n
+	}
+}
+
+// WithRemotePolicy sets the behaviour while the service is unavailable;
+// the default is FailClosed
+func WithRemotePolicy(policy RemotePolicy) RemoteOption {
+	return func(r *RemoteRule) {
+		r.policy = policy
+	}
+}
+
+// WithRemoteHeader adds a header, e.g. for authentication, to every request
+func WithRemoteHeader(key, value string) RemoteOption {
+	return func(r *RemoteRule) {
+		r.headers.Add(key, value)
+	}
+}
+
+// WithCircuitBreaker opens the circuit after threshold consecutive failed
+// checks and tries the service again after cooldown; the default is 5
+// failures and 30s
+func WithCircuitBreaker(threshold int, cooldown time.Duration) RemoteOption {
+	return func(r *RemoteRule) {
+		r.breaker.threshold = threshold
+		r.breaker.cooldown = cooldown
+	}
+}
+
+// NewRemoteRule creates a rule validating values through the service at
+// url; name identifies it in messages
+func NewRemoteRule(name, url string, opts ...RemoteOption) *RemoteRule {
+	r := &RemoteRule{
+		name:    name,
+		url:     url,
+		client:  http.DefaultClient,
+		timeout: 2 * time.Second,
+		retries: 2,
+		headers: make(http.Header),
+		breaker: &circuitBreaker{threshold: 5, cooldown: 30 * time.Second},
+		backoff: 100 * time.Millisecond,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// State returns the circuit breaker state
+func (r *RemoteRule) State() CircuitState {
+	return r.breaker.state(time.Now())
+}
+
+// Check implements Rule
+func (r *RemoteRule) Check(ctx context.Context, value interface{}) []Violation {
+	response, err := r.call(ctx, value)
+	if err != nil {
+		params := map[string]interface{}{"service": r.name, "reason": unavailableReason(err)}
+		if r.policy == FailOpen {
+			return []Violation{{Code: CodeUnavailable, Message: fmt.Sprintf("not verified, %s is unavailable: %v", r.name, err), Severity: SeverityWarning, Params: params}}
+		}
+		return []Violation{{Code: CodeUnavailable, Message: fmt.Sprintf("cannot be verified, %s is unavailable: %v", r.name, err), Params: params}}
+	}
+	if response.Valid {
+		return nil
+	}
+	if len(response.Violations) == 0 {
+		return []Violation{{Code: CodeRemote, Message: "rejected by " + r.name, Params: map[string]interface{}{"service": r.name}}}
+	}
+	for i := range response.Violations {
+		if response.Violations[i].Code == "" {
+			response.Violations[i].Code = CodeRemote
+		}
+	}
+	return response.Violations
+}
+
+// unavailableReason classifies a failure to reach a service as
+// "circuit_open", "timeout" or "error", reported as Params["reason"]
+func unavailableReason(err error) string {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, ErrCircuitOpen):
+		return "circuit_open"
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return "timeout"
+	}
+	return "error"
+}
+
+// call asks the service about value, retrying transient failures and
+// recording the outcome with the circuit breaker
+func (r *RemoteRule) call(ctx context.Context, value interface{}) (*remoteResponse, error) {
+	body, err := json.Marshal(remoteRequest{Value: value})
+	if err != nil {
+		return nil, fmt.Errorf("encode request: %w", err)
+	}
+	if !r.breaker.allow(time.Now()) {
+		return nil, ErrCircuitOpen
+	}
+
+	delay := r.backoff
+	for attempt := 0; ; attempt++ {
+		response, retry, err := r.attempt(ctx, body)
+		if err == nil || !retry || attempt >= r.retries {
+			r.breaker.record(err == nil || !retry, time.Now())
+			return response, err
+		}
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			// The caller gave up, which says nothing about the service
+			r.breaker.abandon()
+			return nil, fmt.Errorf("%w (last error: %v)", ctx.Err(), err)
+		}
+		if delay *= 2; delay > 2*time.Second {
+			delay = 2 * time.Second
+		}
+	}
+}
+
+// attempt makes one request, reporting whether a failure is worth retrying
+func (r *RemoteRule) attempt(ctx context.Context, body []byte) (*remoteResponse, bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.url, bytes.NewReader(body))
+	if err != nil {
+		return nil, false, err
+	}
+	for key, values := range r.headers {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, true, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, true, fmt.Errorf("read response: %w", err)
+	}
+	switch {
+	case resp.StatusCode == http.StatusRequestTimeout || resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return nil, true, fmt.Errorf("%s", resp.Status)
+	case resp.StatusCode != http.StatusOK:
+		return nil, false, fmt.Errorf("%s", resp.Status)
+	}
+	var response remoteResponse
+	if err := json.Unmarshal(data, &response); err != nil {
+		return nil, false, fmt.Errorf("decode response: %w", err)
+	}
+	return &response, false, nil
+}
+
+// circuitBreaker counts consecutive failures and stops calls for a
+// cooldown once they reach the threshold
+type circuitBreaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	openedAt  time.Time
+	trial     bool
+}
+
+// state reports the breaker state at now
+func (b *circuitBreaker) state(now time.Time) CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch {
+	case b.threshold <= 0 || b.failures < b.threshold:
+		return CircuitClosed
+	case now.Sub(b.openedAt) >= b.cooldown:
+		return CircuitHalfOpen
+	}
+	return CircuitOpen
+}
+
+// allow reports whether a call may proceed; after the cooldown a single
+// trial call is let through
+func (b *circuitBreaker) allow(now time.Time) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.threshold <= 0 || b.failures < b.threshold {
+		return true
+	}
+	if now.Sub(b.openedAt) < b.cooldown || b.trial {
+		return false
+	}
+	b.trial = true
+	return true
+}
+
+// abandon ends a call without an outcome
+func (b *circuitBreaker) abandon() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.trial = false
+}
+
+// record notes the outcome of a call; ok means the service answered
+func (b *circuitBreaker) record(ok bool, now time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.trial = false
+	if ok {
+		b.failures = 0
+		return
+	}
+	b.failures++
+	if b.failures >= b.threshold {
+		b.openedAt = now
+	}
+}
@@ -0,0 +1,272 @@
+package validation
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"html/template"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// indexPattern matches list indices in violation paths
+var indexPattern = regexp.MustCompile(`\[\d+\]`)
+
+// RuleStats counts how often one rule, identified by path and code, failed
+type RuleStats struct {
+	// Path has list indices collapsed, e.g. "items[].sku"
+	Path     string `json:"path"`
+	Code     string `json:"code"`
+	Failures int    `json:"failures"`
+	Warnings int    `json:"warnings"`
+}
+
+// Sample is a failing record kept for inspection, with sensitive fields masked
+type Sample struct {
+	Record     int         `json:"record"`
+	Value      interface{} `json:"value,omitempty"`
+	Violations []Violation `json:"violations,omitempty"`
+	Error      string      `json:"error,omitempty"`
+}
+
+// Report summarises the outcomes of many validations
+type Report struct {
+	GeneratedAt  time.Time   `json:"generated_at"`
+	Total        int         `json:"total"`
+	Valid        int         `json:"valid"`
+	Invalid      int         `json:"invalid"`
+	Unreadable   int         `json:"unreadable"`
+	WithWarnings int         `json:"with_warnings"`
+	Rules        []RuleStats `json:"rules"`
+	Samples      []Sample    `json:"samples"`
+}
+
+// Reporter aggregates batch and stream outcomes into a Report for
+// data-quality audits. It is safe for concurrent use
+type Reporter struct {
+	mu         sync.Mutex
+	maxSamples int
+	sensitive  []string
+	report     Report
+	rules      map[[2]string]*RuleStats
+}
+
+// ReporterOption configures a Reporter
+type ReporterOption func(*Reporter)
+
+// WithReportSamples keeps up to n failing records; the default is 10
+func WithReportSamples(n int) ReporterOption {
+	return func(r *Reporter) {
+		r.maxSamples = n
+	}
+}
+
+// WithSensitiveFields masks sample fields whose name contains one of the
+// patterns, case-insensitively; the default covers passwords, secrets,
+// tokens, card numbers and the like
+func WithSensitiveFields(patterns ...string) ReporterOption {
+	return func(r *Reporter) {
+		r.sensitive = patterns
+	}
+}
+
+// NewReporter creates an empty reporter
+func NewReporter(opts ...ReporterOption) *Reporter {
+	r := &Reporter{maxSamples: 10, sensitive: defaultSensitiveFields, rules: make(map[[2]string]*RuleStats)}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Add records the outcome of validating one record; err marks a record
+// that could not be read
+func (r *Reporter) Add(record int, value interface{}, violations, warnings []Violation, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.report.Total++
+	switch {
+	case err != nil:
+		r.report.Unreadable++
+	case len(violations) > 0:
+		r.report.Invalid++
+	default:
+		r.report.Valid++
+	}
+	if len(warnings) > 0 {
+		r.report.WithWarnings++
+	}
+	for _, v := range violations {
+		r.stats(v).Failures++
+	}
+	for _, v := range warnings {
+		r.stats(v).Warnings++
+	}
+	if (err != nil || len(violations) > 0) && len(r.report.Samples) < r.maxSamples {
+		sample := Sample{Record: record, Violations: violations}
+		if value != nil {
+			sample.Value = r.mask(value)
+		}
+		if err != nil {
+			sample.Error = err.Error()
+		}
+		r.report.Samples = append(r.report.Samples, sample)
+	}
+}
+
+// AddBatch records the results of ValidateBatch for items
+func (r *Reporter) AddBatch(items []interface{}, results []BatchResult) {
+	for _, result := range results {
+		var value interface{}
+		if result.Index < len(items) {
+			value = items[result.Index]
+		}
+		r.Add(result.Index, value, result.Violations, result.Warnings, result.Err)
+	}
+}
+
+// AddStream records one result of ValidateStream
+func (r *Reporter) AddStream(result StreamResult) {
+	r.Add(result.Record, result.Value, result.Violations, result.Warnings, result.Err)
+}
+
+// stats returns the counters of the rule that reported v
+func (r *Reporter) stats(v Violation) *RuleStats {
+	path := indexPattern.ReplaceAllString(v.Path, "[]")
+	key := [2]string{path, v.Code}
+	stats, ok := r.rules[key]
+	if !ok {
+		stats = &RuleStats{Path: path, Code: v.Code}
+		r.rules[key] = stats
+	}
+	return stats
+}
+
+// mask copies value as generic JSON with sensitive fields replaced
+func (r *Reporter) mask(value interface{}) interface{} {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return maskedValue
+	}
+	var tree interface{}
+	if err := json.Unmarshal(data, &tree); err != nil {
+		return maskedValue
+	}
+	return r.maskTree(tree)
+}
+
+// maskTree masks the values of sensitive keys throughout tree
+func (r *Reporter) maskTree(tree interface{}) interface{} {
+	switch t := tree.(type) {
+	case map[string]interface{}:
+		for key, value := range t {
+			if sensitiveName(key, r.sensitive) {
+				t[key] = maskedValue
+			} else {
+				t[key] = r.maskTree(value)
+			}
+		}
+	case []interface{}:
+		for i, value := range t {
+			t[i] = r.maskTree(value)
+		}
+	}
+	return tree
+}
+
+// Report returns a snapshot of the aggregate, rules ordered by failures
+func (r *Reporter) Report() *Report {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	report := r.report
+	report.GeneratedAt = time.Now()
+	report.Samples = append([]Sample(nil), r.report.Samples...)
+	report.Rules = make([]RuleStats, 0, len(r.rules))
+	for _, stats := range r.rules {
+		report.Rules = append(report.Rules, *stats)
+	}
+	sort.Slice(report.Rules, func(i, j int) bool {
+		a, b := report.Rules[i], report.Rules[j]
+		if a.Failures != b.Failures {
+			return a.Failures > b.Failures
+		}
+		if a.Warnings != b.Warnings {
+			return a.Warnings > b.Warnings
+		}
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		return a.Code < b.Code
+	})
+	return &report
+}
+
+// WriteJSON writes the report as indented JSON
+func (r *Report) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+// WriteCSV writes the per-rule counts, one row per rule under a header of
+// path, code, failures and warnings
+func (r *Report) WriteCSV(w io.Writer) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"path", "code", "failures", "warnings"}); err != nil {
+		return err
+	}
+	for _, stats := range r.Rules {
+		if err := writer.Write([]string{stats.Path, stats.Code, strconv.Itoa(stats.Failures), strconv.Itoa(stats.Warnings)}); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// reportTemplate renders a report as a standalone HTML page
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"json": func(v interface{}) string {
+		data, _ := json.MarshalIndent(v, "", "  ")
+		return string(data)
+	},
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Validation report</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; vertical-align: top; }
+pre { margin: 0; }
+</style>
+</head>
+<body>
+<h1>Validation report</h1>
+<p>Generated {{.GeneratedAt.Format "2006-01-02 15:04:05 MST"}}</p>
+<table>
+<tr><th>Total</th><th>Valid</th><th>Invalid</th><th>Unreadable</th><th>With warnings</th></tr>
+<tr><td>{{.Total}}</td><td>{{.Valid}}</td><td>{{.Invalid}}</td><td>{{.Unreadable}}</td><td>{{.WithWarnings}}</td></tr>
+</table>
+<h2>Rules</h2>
+<table>
+<tr><th>Path</th><th>Code</th><th>Failures</th><th>Warnings</th></tr>
+{{range .Rules}}<tr><td>{{.Path}}</td><td>{{.Code}}</td><td>{{.Failures}}</td><td>{{.Warnings}}</td></tr>
+{{end}}</table>
+<h2>Samples</h2>
+<table>
+<tr><th>Record</th><th>Problems</th><th>Value</th></tr>
+{{range .Samples}}<tr><td>{{.Record}}</td><td>{{if .Error}}{{.Error}}<br>{{end}}{{range .Violations}}{{.}}<br>{{end}}</td><td>{{with .Value}}<pre>{{json .}}</pre>{{end}}</td></tr>
+{{end}}</table>
+</body>
+</html>
+`))
+
+// WriteHTML writes the report as a standalone HTML page
+func (r *Report) WriteHTML(w io.Writer) error {
+	return reportTemplate.Execute(w, r)
+}
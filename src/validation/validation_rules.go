@@ -0,0 +1,607 @@
+package validation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"unicode/utf8"
+)
+
+// Violation codes reported by the built-in rules
+const (
+	CodeRequired = "required"
+	CodeLength   = "length"
+	CodeRange    = "range"
+	CodePattern  = "pattern"
+	CodeOneOf    = "one_of"
+	CodeNot      = "not"
+	CodeType     = "type"
+)
+
+// Severity tells blocking errors from warnings
+type Severity int
+
+const (
+	// SeverityError fails the validation; it is the zero value
+	SeverityError Severity = iota
+	// SeverityWarning is reported without failing, unless Config.FailOnWarnings is set
+	SeverityWarning
+)
+
+// String returns string representation of Severity
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	default:
+		return "unknown"
+	}
+}
+
+// MarshalText encodes the severity by name
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText decodes "error" or "warning"
+func (s *Severity) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "error", "":
+		*s = SeverityError
+	case "warning":
+		*s = SeverityWarning
+	default:
+		return fmt.Errorf("unknown severity %q", text)
+	}
+	return nil
+}
+
+// Violation is one failed rule: what failed, where and why. Params hold
+// the rule's arguments (such as "min" and "max") for message templates
+type Violation struct {
+	Path     string                 `json:"path"`
+	Code     string                 `json:"code"`
+	Message  string                 `json:"message"`
+	Severity Severity               `json:"severity"`
+	Params   map[string]interface{} `json:"params,omitempty"`
+
+	// key selects a message template variant, e.g. "length.min"; Code when empty
+	key string
+	// sensitive marks violations of values checked by Sensitive rules
+	sensitive bool
+}
+
+// String formats the violation as "path: message (code)", marking warnings
+// as "(code, warning)"
+func (v Violation) String() string {
+	code := v.Code
+	if v.Severity != SeverityError {
+		code += ", " + v.Severity.String()
+	}
+	if v.Path == "" {
+		return fmt.Sprintf("%s (%s)", v.Message, code)
+	}
+	return fmt.Sprintf("%s: %s (%s)", v.Path, v.Message, code)
+}
+
+// Error implements error, so violations can be matched with errors.As
+func (v Violation) Error() string {
+	return v.String()
+}
+
+// checkModeKey carries the checkMode of a validation in a context
+type checkModeKey struct{}
+
+// checkMode holds the configuration that decides when a validation fails
+// and how deep it descends into collections
+type checkMode struct {
+	failFast       bool
+	failOnWarnings bool
+	maxDepth       int
+	maxElements    int
+	maxBytes       int
+	maxItems       int
+	maxKeys        int
+	depth          int
+	// preview skips Stateful rules, see Manager.Preview
+	preview bool
+	// stateful, if set, records that a Stateful rule ran
+	stateful *atomic.Bool
+}
+
+// modeOf returns the check mode carried by ctx
+func modeOf(ctx context.Context) checkMode {
+	mode, _ := ctx.Value(checkModeKey{}).(checkMode)
+	return mode
+}
+
+// blocks reports whether v fails the validation
+func (c checkMode) blocks(v Violation) bool {
+	return v.Severity == SeverityError || c.failOnWarnings
+}
+
+// stop truncates violations after the first blocking one when validating
+// fail-fast, reporting whether it did
+func (c checkMode) stop(violations []Violation) ([]Violation, bool) {
+	if !c.failFast {
+		return violations, false
+	}
+	for i, v := range violations {
+		if c.blocks(v) {
+			return violations[:i+1], true
+		}
+	}
+	return violations, false
+}
+
+// Rule checks a value and returns every violation it finds, with paths
+// relative to the value
+type Rule interface {
+	Check(ctx context.Context, value interface{}) []Violation
+}
+
+// Stateful is implemented by rules whose outcome depends on state outside
+// the value, which checking may change, e.g. RateLimit counting values and
+// UniqueIn consulting a store. Results of checks that ran one are not
+// cached, and Preview skips them. Combinators such as All, Field and When
+// honour it in the rules they are given
+type Stateful interface {
+	Rule
+	Stateful() bool
+}
+
+// StatefulRule marks rule as Stateful, e.g. a custom rule querying a
+// database
+func StatefulRule(rule Rule) Rule {
+	return statefulRule{rule}
+}
+
+// statefulRule is the Stateful rule returned by StatefulRule
+type statefulRule struct {
+	Rule
+}
+
+// Stateful implements Stateful
+func (statefulRule) Stateful() bool {
+	return true
+}
+
+// checkRule checks value against rule, skipping Stateful rules in a
+// preview and otherwise noting that one ran
+func checkRule(ctx context.Context, rule Rule, value interface{}) []Violation {
+	if s, ok := rule.(Stateful); ok && s.Stateful() {
+		mode := modeOf(ctx)
+		if mode.preview {
+			return nil
+		}
+		if mode.stateful != nil {
+			mode.stateful.Store(true)
+		}
+	}
+	return rule.Check(ctx, value)
+}
+
+// RuleFunc adapts a function to the Rule interface
+type RuleFunc func(ctx context.Context, value interface{}) []Violation
+
+// Check calls f
+func (f RuleFunc) Check(ctx context.Context, value interface{}) []Violation {
+	return f(ctx, value)
+}
+
+// Predicate returns a rule that reports code and message when ok is false
+func Predicate(code, message string, ok func(value interface{}) bool) Rule {
+	return RuleFunc(func(ctx context.Context, value interface{}) []Violation {
+		if ok(value) {
+			return nil
+		}
+		return []Violation{{Code: code, Message: message}}
+	})
+}
+
+// All passes when every rule passes and reports all of their violations,
+// up to the first blocking one when validating fail-fast
+func All(rules ...Rule) Rule {
+	return RuleFunc(func(ctx context.Context, value interface{}) []Violation {
+		mode := modeOf(ctx)
+		var violations []Violation
+		for _, rule := range rules {
+			violations = append(violations, checkRule(ctx, rule, value)...)
+			if stopped, ok := mode.stop(violations); ok {
+				return stopped
+			}
+		}
+		return violations
+	})
+}
+
+// Warn reports the violations of rules as warnings, which do not fail the
+// validation unless Config.FailOnWarnings is set; use it to soft-launch new
+// rules and watch what they would reject
+func Warn(rules ...Rule) Rule {
+	return RuleFunc(func(ctx context.Context, value interface{}) []Violation {
+		// Warnings never stop a fail-fast validation, so check them all
+		mode := modeOf(ctx)
+		mode.failFast = false
+		violations := All(rules...).Check(context.WithValue(ctx, checkModeKey{}, mode), value)
+		for i := range violations {
+			violations[i].Severity = SeverityWarning
+		}
+		return violations
+	})
+}
+
+// Any passes when at least one rule passes; otherwise it reports the
+// violations of every alternative
+func Any(rules ...Rule) Rule {
+	return RuleFunc(func(ctx context.Context, value interface{}) []Violation {
+		var violations []Violation
+		for _, rule := range rules {
+			v := checkRule(ctx, rule, value)
+			if len(v) == 0 {
+				return nil
+			}
+			violations = append(violations, v...)
+		}
+		return violations
+	})
+}
+
+// Not passes when rule fails, reporting message under CodeNot otherwise
+func Not(rule Rule, message string) Rule {
+	return RuleFunc(func(ctx context.Context, value interface{}) []Violation {
+		if len(checkRule(ctx, rule, value)) > 0 {
+			return nil
+		}
+		return []Violation{{Code: CodeNot, Message: message}}
+	})
+}
+
+// When applies then only to values that pass cond, e.g. requiring a card
+// number when the payment method is "card"
+func When(cond Rule, then ...Rule) Rule {
+	return RuleFunc(func(ctx context.Context, value interface{}) []Violation {
+		if len(checkRule(ctx, cond, value)) > 0 {
+			return nil
+		}
+		return All(then...).Check(ctx, value)
+	})
+}
+
+// Field applies rules to the value under a dotted path of struct fields
+// (by json name) or map keys, prefixing violation paths with it; a missing
+// field is checked as nil. Like Pattern with a bad expression, Field
+// panics when path is malformed, see CheckPath
+func Field(path string, rules ...Rule) Rule {
+	if err := CheckPath(path); err != nil {
+		panic(err)
+	}
+	return fieldRule{path: path, rules: rules}
+}
+
+// fieldRule is the Rule returned by Field; ValidateDelta skips it when the
+// value under its path did not change
+type fieldRule struct {
+	path  string
+	rules []Rule
+}
+
+// Check implements Rule
+func (f fieldRule) Check(ctx context.Context, value interface{}) []Violation {
+	field, _ := Lookup(value, f.path)
+	violations := All(f.rules...).Check(ctx, field)
+	for i := range violations {
+		violations[i].Path = joinPath(f.path, violations[i].Path)
+	}
+	return violations
+}
+
+// Required rejects nil values, nil pointers and empty strings, slices and maps
+func Required() Rule {
+	return Predicate(CodeRequired, "is required", func(value interface{}) bool {
+		return !isEmpty(value)
+	})
+}
+
+// Length requires a string (counted in runes), slice or map length within
+// [min, max]; max < 0 means unbounded. Nil values pass; combine with Required
+func Length(min, max int) Rule {
+	return RuleFunc(func(ctx context.Context, value interface{}) []Violation {
+		n, ok := length(value)
+		if !ok {
+			if value == nil {
+				return nil
+			}
+			return []Violation{typeViolation("string, list or map", value)}
+		}
+		if n < min || (max >= 0 && n > max) {
+			key, message := lengthMessage(min, max)
+			return []Violation{{Code: CodeLength, Message: message, Params: map[string]interface{}{"min": min, "max": max}, key: key}}
+		}
+		return nil
+	})
+}
+
+// Range requires a number within [min, max]. Nil values pass
+func Range(min, max float64) Rule {
+	return RuleFunc(func(ctx context.Context, value interface{}) []Violation {
+		if value == nil {
+			return nil
+		}
+		n, ok := number(value)
+		if !ok {
+			return []Violation{typeViolation("number", value)}
+		}
+		if n < min || n > max {
+			key, message := rangeMessage(min, max)
+			return []Violation{{Code: CodeRange, Message: message, Params: map[string]interface{}{"min": min, "max": max}, key: key}}
+		}
+		return nil
+	})
+}
+
+// Pattern requires a string matching expr. Empty and nil values pass
+func Pattern(expr string) Rule {
+	re := regexp.MustCompile(expr)
+	return RuleFunc(func(ctx context.Context, value interface{}) []Violation {
+		value = indirect(value)
+		s, ok := value.(string)
+		if value == nil || (ok && s == "") {
+			return nil
+		}
+		if !ok {
+			return []Violation{typeViolation("string", value)}
+		}
+		if !re.MatchString(s) {
+			return []Violation{{Code: CodePattern, Message: fmt.Sprintf("must match %s", expr), Params: map[string]interface{}{"pattern": expr}}}
+		}
+		return nil
+	})
+}
+
+// OneOf requires the value to equal one of allowed. Empty values pass;
+// combine with Required
+func OneOf(allowed ...interface{}) Rule {
+	return RuleFunc(func(ctx context.Context, value interface{}) []Violation {
+		if isEmpty(value) {
+			return nil
+		}
+		for _, a := range allowed {
+			if reflect.DeepEqual(a, indirect(value)) {
+				return nil
+			}
+		}
+		names := make([]string, len(allowed))
+		for i, a := range allowed {
+			names[i] = fmt.Sprint(a)
+		}
+		return []Violation{{Code: CodeOneOf, Message: "must be one of " + strings.Join(names, ", "), Params: map[string]interface{}{"allowed": strings.Join(names, ", ")}}}
+	})
+}
+
+// Lookup returns the value under a dotted path of struct fields (matched
+// by json name, then field name) and string-keyed map entries, with list
+// elements addressed by index as in "items[3].address.zip"
+func Lookup(value interface{}, path string) (interface{}, bool) {
+	if path == "" {
+		return value, true
+	}
+	current := value
+	for _, part := range pathSegments(path) {
+		rv := reflect.ValueOf(current)
+		for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+			if rv.IsNil() {
+				return nil, false
+			}
+			rv = rv.Elem()
+		}
+		if strings.HasPrefix(part, "[") {
+			if !strings.HasSuffix(part, "]") || len(part) < 2 {
+				return nil, false
+			}
+			i, err := strconv.Atoi(part[1 : len(part)-1])
+			if err != nil || (rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array) || i < 0 || i >= rv.Len() {
+				return nil, false
+			}
+			current = rv.Index(i).Interface()
+			continue
+		}
+		switch rv.Kind() {
+		case reflect.Map:
+			if rv.Type().Key().Kind() != reflect.String {
+				return nil, false
+			}
+			v := rv.MapIndex(reflect.ValueOf(part).Convert(rv.Type().Key()))
+			if !v.IsValid() {
+				return nil, false
+			}
+			current = v.Interface()
+		case reflect.Struct:
+			f, ok := structField(rv, part)
+			if !ok {
+				return nil, false
+			}
+			current = f.Interface()
+		default:
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+// CheckPath reports whether path is well formed: dot-separated field names
+// or map keys, each optionally followed by indexes such as "[3]"
+func CheckPath(path string) error {
+	for _, segment := range pathSegments(path) {
+		if !strings.HasPrefix(segment, "[") {
+			if strings.ContainsRune(segment, ']') {
+				return fmt.Errorf("path %q: unexpected \"]\" in %q", path, segment)
+			}
+			continue
+		}
+		if len(segment) < 2 || !strings.HasSuffix(segment, "]") {
+			return fmt.Errorf("path %q: unclosed %q", path, segment)
+		}
+		if i, err := strconv.Atoi(segment[1 : len(segment)-1]); err != nil || i < 0 {
+			return fmt.Errorf("path %q: index %s is not a non-negative integer", path, segment)
+		}
+	}
+	return nil
+}
+
+// pathSegments splits "items[3].zip" into "items", "[3]" and "zip"
+func pathSegments(path string) []string {
+	var segments []string
+	for _, part := range strings.Split(path, ".") {
+		for part != "" {
+			open := strings.IndexByte(part, '[')
+			if open < 0 {
+				segments = append(segments, part)
+				break
+			}
+			if open > 0 {
+				segments = append(segments, part[:open])
+			}
+			end := strings.IndexByte(part[open:], ']')
+			if end < 0 {
+				segments = append(segments, part[open:])
+				break
+			}
+			segments = append(segments, part[open:open+end+1])
+			part = part[open+end+1:]
+		}
+	}
+	return segments
+}
+
+// structField finds the exported field named by json tag or name
+func structField(rv reflect.Value, name string) (reflect.Value, bool) {
+	t := rv.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		if tag := strings.Split(f.Tag.Get("json"), ",")[0]; tag == name || (tag == "" && f.Name == name) {
+			return rv.Field(i), true
+		}
+	}
+	for i := 0; i < t.NumField(); i++ {
+		if f := t.Field(i); f.PkgPath == "" && strings.EqualFold(f.Name, name) {
+			return rv.Field(i), true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+// joinPath joins a field path and a nested violation path
+func joinPath(prefix, path string) string {
+	switch {
+	case prefix == "":
+		return path
+	case path == "":
+		return prefix
+	case strings.HasPrefix(path, "["):
+		return prefix + path
+	}
+	return prefix + "." + path
+}
+
+// indirect dereferences pointers, returning nil for nil pointers
+func indirect(value interface{}) interface{} {
+	rv := reflect.ValueOf(value)
+	for rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if !rv.IsValid() {
+		return nil
+	}
+	return rv.Interface()
+}
+
+// isEmpty reports whether value counts as missing for Required
+func isEmpty(value interface{}) bool {
+	value = indirect(value)
+	if value == nil {
+		return true
+	}
+	if n, ok := length(value); ok {
+		return n == 0
+	}
+	return false
+}
+
+// length returns the length of strings, slices, arrays and maps
+func length(value interface{}) (int, bool) {
+	rv := reflect.ValueOf(indirect(value))
+	switch rv.Kind() {
+	case reflect.String:
+		return utf8.RuneCountInString(rv.String()), true
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return rv.Len(), true
+	}
+	return 0, false
+}
+
+// number converts numeric kinds to float64
+func number(value interface{}) (float64, bool) {
+	if n, ok := indirect(value).(json.Number); ok {
+		f, err := n.Float64()
+		return f, err == nil
+	}
+	rv := reflect.ValueOf(indirect(value))
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	}
+	return 0, false
+}
+
+// lengthMessage describes a length bound, returning its template key too
+func lengthMessage(min, max int) (string, string) {
+	switch {
+	case max < 0:
+		return "length.min", fmt.Sprintf("must have at least %d items or characters", min)
+	case min == max:
+		return "length.exact", fmt.Sprintf("must have exactly %d items or characters", min)
+	case min <= 0:
+		return "length.max", fmt.Sprintf("must have at most %d items or characters", max)
+	}
+	return "length.between", fmt.Sprintf("must have between %d and %d items or characters", min, max)
+}
+
+// rangeMessage describes a numeric bound, returning its template key too;
+// infinite bounds read as "at least" or "at most"
+func rangeMessage(min, max float64) (string, string) {
+	switch {
+	case math.IsInf(max, 1):
+		return "range.min", fmt.Sprintf("must be at least %v", min)
+	case math.IsInf(min, -1):
+		return "range.max", fmt.Sprintf("must be at most %v", max)
+	}
+	return CodeRange, fmt.Sprintf("must be between %v and %v", min, max)
+}
+
+// typeViolation reports a value of the wrong kind
+func typeViolation(expected string, value interface{}) Violation {
+	return Violation{
+		Code:    CodeType,
+		Message: fmt.Sprintf("must be a %s, got %T", expected, value),
+		Params:  map[string]interface{}{"expected": expected, "actual": fmt.Sprintf("%T", value)},
+	}
+}
@@ -0,0 +1,53 @@
+package validation
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckPath(t *testing.T) {
+	for _, path := range []string{"", "name", "address.zip", "items[3].zip", "[0]", "matrix[1][2]"} {
+		if err := CheckPath(path); err != nil {
+			t.Errorf("CheckPath(%q) = %v, want nil", path, err)
+		}
+	}
+	for _, path := range []string{"[", "a[", "a[1", "a[]", "a[x]", "a[-1]", "a]", "a[1]]"} {
+		if err := CheckPath(path); err == nil {
+			t.Errorf("CheckPath(%q) = nil, want an error", path)
+		}
+	}
+}
+
+func TestLookupMalformedPath(t *testing.T) {
+	value := map[string]interface{}{"a": []interface{}{1, 2}}
+	for _, path := range []string{"[", "a[", "a[1", "a[]", "a[x]"} {
+		if got, ok := Lookup(value, path); ok {
+			t.Errorf("Lookup(%q) = %v, true; want not found", path, got)
+		}
+	}
+	if got, ok := Lookup(value, "a[1]"); !ok || got != 2 {
+		t.Errorf("Lookup(a[1]) = %v, %v; want 2, true", got, ok)
+	}
+}
+
+func TestFieldPanicsOnMalformedPath(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Field(\"a[\") did not panic")
+		}
+	}()
+	Field("a[", Required())
+}
+
+func TestCompileRulesRejectsMalformedPath(t *testing.T) {
+	for name, def := range map[string]RuleSetDefinition{
+		"field":         {Name: "s", Fields: []FieldDefinition{{Path: "a[", Required: true}}},
+		"branch field":  {Name: "s", Discriminator: "kind", Branches: map[string][]FieldDefinition{"x": {{Path: "[", Required: true}}}},
+		"discriminator": {Name: "s", Discriminator: "kind[", Branches: map[string][]FieldDefinition{"x": {{Path: "a", Required: true}}}},
+	} {
+		_, err := CompileRules([]RuleSetDefinition{def})
+		if err == nil || !strings.Contains(err.Error(), "path") {
+			t.Errorf("%s: CompileRules error = %v, want a path error", name, err)
+		}
+	}
+}
@@ -0,0 +1,327 @@
+package validation
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"reflect"
+	"sync/atomic"
+	"time"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// RuleSet is a named group of rules, optionally restricted to values of one
+// type and to validation profiles
+type RuleSet struct {
+	name     string
+	typ      reflect.Type
+	typeName string
+	profiles []string
+	rules    []Rule
+	steps    int
+
+	// origin is the configuration section a loaded set came from
+	origin string
+}
+
+// NewRuleSet creates a rule set applied to every validated value
+func NewRuleSet(name string, rules ...Rule) *RuleSet {
+	return &RuleSet{name: name, rules: rules}
+}
+
+// Name returns the rule set name
+func (s *RuleSet) Name() string {
+	return s.name
+}
+
+// For restricts the set to values of the same type as prototype (pointers
+// to it included)
+func (s *RuleSet) For(prototype interface{}) *RuleSet {
+	s.typ = indirectType(reflect.TypeOf(prototype))
+	return s
+}
+
+// Add appends rules to the set
+func (s *RuleSet) Add(rules ...Rule) *RuleSet {
+	s.rules = append(s.rules, rules...)
+	return s
+}
+
+// Field appends rules for the value under path
+func (s *RuleSet) Field(path string, rules ...Rule) *RuleSet {
+	return s.Add(Field(path, rules...))
+}
+
+// AppliesTo reports whether the set checks value
+func (s *RuleSet) AppliesTo(value interface{}) bool {
+	if s.typ == nil && s.typeName == "" {
+		return true
+	}
+	if value == nil {
+		return false
+	}
+	t := indirectType(reflect.TypeOf(value))
+	if s.typ != nil {
+		return t == s.typ
+	}
+	if name, ok := messageName(value); ok && name == s.typeName {
+		return true
+	}
+	return matchesTypeName(t, s.typeName)
+}
+
+// Check runs every rule of the set in plan order, so a RuleSet is itself
+// a Rule
+func (s *RuleSet) Check(ctx context.Context, value interface{}) []Violation {
+	return s.run(ctx, value, nil)
+}
+
+// Results lists every violated rule of a validation: Violations fail it,
+// Warnings are reported without failing it
+type Results struct {
+	Violations []Violation `json:"violations"`
+	Warnings   []Violation `json:"warnings,omitempty"`
+}
+
+// Valid reports whether no blocking rule was violated
+func (r *Results) Valid() bool {
+	return len(r.Violations) == 0
+}
+
+// Err returns the violations as ValidationErrors, or nil when valid
+func (r *Results) Err() error {
+	if r.Valid() {
+		return nil
+	}
+	return ValidationErrors(append([]Violation(nil), r.Violations...))
+}
+
+// Register adds rule sets to the manager; names must be unique
+func (m *Manager) Register(sets ...*RuleSet) error {
+	m.rulesMu.Lock()
+	defer m.rulesMu.Unlock()
+	for _, set := range sets {
+		if set == nil || set.name == "" {
+			return fmt.Errorf("register rule set: a name is required")
+		}
+		if _, err := set.Plan(); err != nil {
+			return fmt.Errorf("register rule set %q: %w", set.name, err)
+		}
+		for _, existing := range m.ruleSets {
+			if existing.name == set.name {
+				return fmt.Errorf("register rule set %q: already registered", set.name)
+			}
+		}
+		m.ruleSets = append(m.ruleSets, set)
+		m.rulesVersion++
+		m.logger.Printf("Registered rule set %s", set.name)
+	}
+	return nil
+}
+
+// Unregister removes the named rule set, reporting whether it was registered
+func (m *Manager) Unregister(name string) bool {
+	m.rulesMu.Lock()
+	defer m.rulesMu.Unlock()
+	for i, set := range m.ruleSets {
+		if set.name == name {
+			m.ruleSets = append(m.ruleSets[:i:i], m.ruleSets[i+1:]...)
+			m.rulesVersion++
+			return true
+		}
+	}
+	return false
+}
+
+// RuleSets returns the names of the registered rule sets in registration order
+func (m *Manager) RuleSets() []string {
+	m.rulesMu.RLock()
+	defer m.rulesMu.RUnlock()
+	names := make([]string, len(m.ruleSets))
+	for i, set := range m.ruleSets {
+		names[i] = set.name
+	}
+	return names
+}
+
+// Check validates data against every registered rule set that applies to
+// it and to the profile carried by ctx, and returns all violations, or only the first with Config.FailFast;
+// nil data is a single CodeRequired violation. Warnings count as violations
+// only with Config.FailOnWarnings
+func (m *Manager) Check(ctx context.Context, data interface{}) *Results {
+	m.mu.RLock()
+	config := m.config
+	m.mu.RUnlock()
+	return m.check(ctx, data, config)
+}
+
+// check runs checkData between the registered hooks
+func (m *Manager) check(ctx context.Context, data interface{}, config *Config) *Results {
+	return m.hooked(ctx, data, func(ctx context.Context) *Results {
+		return m.checkData(ctx, data, config)
+	})
+}
+
+// checkData runs the applicable rule sets as configured, stopping at the
+// first blocking violation with FailFast, and localizes messages when ctx
+// carries a locale
+func (m *Manager) checkData(ctx context.Context, data interface{}, config *Config) *Results {
+	results := &Results{}
+	if data == nil {
+		results.Violations = append(results.Violations, Violation{Code: CodeRequired, Message: "data cannot be nil"})
+		return results
+	}
+
+	m.rulesMu.RLock()
+	sets := append([]*RuleSet(nil), m.ruleSets...)
+	catalog, cache, version := m.catalog, m.cache, m.rulesVersion
+	m.rulesMu.RUnlock()
+	profile, locale := Profile(ctx), Locale(ctx)
+	mode := newCheckMode(config)
+	mode.stateful = new(atomic.Bool)
+
+	var key [sha256.Size]byte
+	if cache != nil {
+		var ok bool
+		if key, ok = resultKey(data, version, profile, locale, mode); !ok {
+			cache = nil
+		} else if cached, hit := cache.get(key, time.Now()); hit {
+			return cached
+		}
+	}
+	value, violations := evaluate(m.instrument(context.WithValue(ctx, checkModeKey{}, mode)), data, sets, profile, mode)
+	if value == nil {
+		results.Violations = append(results.Violations, Violation{Code: CodeRequired, Message: "data cannot be nil"})
+		return results
+	}
+	results = mode.results(catalog, locale, m.maskViolations(value, violations))
+	if cache != nil && !mode.stateful.Load() {
+		cache.put(key, results, time.Now())
+	}
+	return results
+}
+
+// CheckWith validates data against sets, whether registered or not and
+// whatever type they are restricted to, with the manager's configuration,
+// catalog, masking and metrics; results are not cached
+func (m *Manager) CheckWith(ctx context.Context, data interface{}, sets ...*RuleSet) *Results {
+	return m.hooked(ctx, data, func(ctx context.Context) *Results {
+		return m.checkWith(ctx, data, sets)
+	})
+}
+
+// checkWith implements CheckWith
+func (m *Manager) checkWith(ctx context.Context, data interface{}, sets []*RuleSet) *Results {
+	m.mu.RLock()
+	config := m.config
+	m.mu.RUnlock()
+	m.rulesMu.RLock()
+	catalog := m.catalog
+	m.rulesMu.RUnlock()
+
+	mode := newCheckMode(config)
+	checkCtx := m.instrument(context.WithValue(ctx, checkModeKey{}, mode))
+	violations := mode.checkLimits(data)
+	if violations != nil {
+		return mode.results(catalog, Locale(ctx), violations)
+	}
+	for _, set := range sets {
+		var stopped bool
+		if violations, stopped = mode.stop(append(violations, set.Check(checkCtx, data)...)); stopped {
+			break
+		}
+	}
+	return mode.results(catalog, Locale(ctx), m.maskViolations(data, violations))
+}
+
+// evaluate checks data against the sets that apply to it, returning the
+// value the rules saw, nil for a nil message
+func evaluate(ctx context.Context, data interface{}, sets []*RuleSet, profile string, mode checkMode) (interface{}, []Violation) {
+	value := data
+	var violations []Violation
+	if msg, ok := data.(proto.Message); ok {
+		// Messages are checked as maps of their fields, after the rules
+		// from their field options
+		var options Rule
+		if value, options = messageInput(msg); value == nil {
+			return nil, nil
+		}
+		if violations = mode.checkLimits(value); violations != nil {
+			return value, violations
+		}
+		violations, _ = mode.stop(options.Check(ctx, value))
+	} else if violations = mode.checkLimits(value); violations != nil {
+		return value, violations
+	}
+	for _, set := range sets {
+		if mode.failFast && len(violations) > 0 && mode.blocks(violations[len(violations)-1]) {
+			break
+		}
+		if !set.AppliesTo(data) || !set.inProfile(profile) {
+			continue
+		}
+		var stopped bool
+		if violations, stopped = mode.stop(append(violations, set.Check(ctx, value)...)); stopped {
+			break
+		}
+	}
+	return value, violations
+}
+
+// newCheckMode derives how rules run from config
+func newCheckMode(config *Config) checkMode {
+	return checkMode{
+		failFast:       config.FailFast,
+		failOnWarnings: config.FailOnWarnings,
+		maxDepth:       config.MaxDepth,
+		maxElements:    config.MaxElements,
+		maxBytes:       config.MaxBytes,
+		maxItems:       config.MaxArrayLength,
+		maxKeys:        config.MaxMapKeys,
+	}
+}
+
+// results localizes violations when a locale is set and splits them into
+// blocking violations and warnings
+func (c checkMode) results(catalog *Catalog, locale string, violations []Violation) *Results {
+	if locale != "" && len(violations) > 0 {
+		if catalog == nil {
+			catalog = defaultCatalog
+		}
+		violations = catalog.Localize(locale, violations)
+	}
+	results := &Results{}
+	for _, v := range violations {
+		if c.blocks(v) {
+			results.Violations = append(results.Violations, v)
+		} else {
+			results.Warnings = append(results.Warnings, v)
+		}
+	}
+	return results
+}
+
+// validate checks data against config, logs the outcome and returns the
+// warnings of a passing validation; callers read config under m.mu
+func (m *Manager) validate(ctx context.Context, data interface{}, config *Config) ([]Violation, error) {
+	results := m.check(ctx, data, config)
+	if err := results.Err(); err != nil {
+		m.logger.Warnf("Validation failed: %v", err)
+		return nil, err
+	}
+	if len(results.Warnings) > 0 {
+		m.logger.Warnf("Data validation passed with %d warning(s): %v", len(results.Warnings), results.Warnings)
+		return results.Warnings, nil
+	}
+	m.logger.Debugf("Data validation passed")
+	return nil, nil
+}
+
+// indirectType strips pointer indirections
+func indirectType(t reflect.Type) reflect.Type {
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
@@ -0,0 +1,206 @@
+package validation
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+// Migration transforms a document of the previous schema version into the
+// version it is registered with; returning an error marks the document as
+// not migratable
+type Migration func(ctx context.Context, doc interface{}) (interface{}, error)
+
+// schemaVersion is one version of a Schema
+type schemaVersion struct {
+	version int
+	rules   *RuleSet
+	migrate Migration
+}
+
+// Schema is a sequence of versions of a document type, each with the rule
+// set documents of that version must satisfy and the migration from the
+// version before it, e.g.
+//
+//	schema := NewSchema("customer").
+//		Version(1, v1, nil).
+//		Version(2, v2, splitName)
+type Schema struct {
+	name     string
+	versions []schemaVersion
+	err      error
+}
+
+// NewSchema creates a schema without versions
+func NewSchema(name string) *Schema {
+	return &Schema{name: name}
+}
+
+// Version adds a version validated by rules, reached from the previous
+// version by migrate; the first version needs no migration
+func (s *Schema) Version(version int, rules *RuleSet, migrate Migration) *Schema {
+	i := sort.Search(len(s.versions), func(i int) bool { return s.versions[i].version >= version })
+	if i < len(s.versions) && s.versions[i].version == version {
+		if s.err == nil {
+			s.err = fmt.Errorf("schema %s: version %d defined twice", s.name, version)
+		}
+		return s
+	}
+	s.versions = append(s.versions, schemaVersion{})
+	copy(s.versions[i+1:], s.versions[i:])
+	s.versions[i] = schemaVersion{version: version, rules: rules, migrate: migrate}
+	return s
+}
+
+// Name returns the schema name
+func (s *Schema) Name() string {
+	return s.name
+}
+
+// Latest returns the highest version, or 0 without versions
+func (s *Schema) Latest() int {
+	if len(s.versions) == 0 {
+		return 0
+	}
+	return s.versions[len(s.versions)-1].version
+}
+
+// Versions returns the versions in ascending order
+func (s *Schema) Versions() []int {
+	out := make([]int, len(s.versions))
+	for i, v := range s.versions {
+		out[i] = v.version
+	}
+	return out
+}
+
+// find returns the index of version
+func (s *Schema) find(version int) (int, error) {
+	if s.err != nil {
+		return 0, s.err
+	}
+	for i, v := range s.versions {
+		if v.version == version {
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("schema %s: unknown version %d", s.name, version)
+}
+
+// MigrationError reports the version a document could not be migrated to
+type MigrationError struct {
+	Schema  string
+	Version int
+	Err     error
+}
+
+// Error implements error
+func (e *MigrationError) Error() string {
+	return fmt.Sprintf("migrate %s to version %d: %v", e.Schema, e.Version, e.Err)
+}
+
+// Unwrap returns the underlying error
+func (e *MigrationError) Unwrap() error {
+	return e.Err
+}
+
+// Migrate runs the migrations from version from up to version to
+func (s *Schema) Migrate(ctx context.Context, doc interface{}, from, to int) (interface{}, error) {
+	start, err := s.find(from)
+	if err != nil {
+		return nil, err
+	}
+	end, err := s.find(to)
+	if err != nil {
+		return nil, err
+	}
+	if end < start {
+		return nil, fmt.Errorf("schema %s: cannot migrate from version %d back to %d", s.name, from, to)
+	}
+	for _, v := range s.versions[start+1 : end+1] {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if v.migrate == nil {
+			return nil, &MigrationError{Schema: s.name, Version: v.version, Err: fmt.Errorf("no migration defined")}
+		}
+		if doc, err = v.migrate(ctx, doc); err != nil {
+			return nil, &MigrationError{Schema: s.name, Version: v.version, Err: err}
+		}
+	}
+	return doc, nil
+}
+
+// ValidateVersion checks doc against the rules of version of schema
+func (m *Manager) ValidateVersion(ctx context.Context, schema *Schema, version int, doc interface{}) (*Results, error) {
+	i, err := schema.find(version)
+	if err != nil {
+		return nil, err
+	}
+	if schema.versions[i].rules == nil {
+		return &Results{}, nil
+	}
+	return m.CheckWith(ctx, doc, schema.versions[i].rules), nil
+}
+
+// Migrate upgrades doc from version from to the latest version of schema
+// and re-validates it under that version's rules. The error reports a
+// failed migration or an unknown version; violations are in the results
+func (m *Manager) Migrate(ctx context.Context, schema *Schema, doc interface{}, from int) (interface{}, *Results, error) {
+	latest := schema.Latest()
+	migrated, err := schema.Migrate(ctx, doc, from, latest)
+	if err != nil {
+		return nil, nil, err
+	}
+	results, err := m.ValidateVersion(ctx, schema, latest, migrated)
+	if err != nil {
+		return nil, nil, err
+	}
+	return migrated, results, nil
+}
+
+// MigrationFailure is a document that could not be migrated, or that is
+// invalid once migrated
+type MigrationFailure struct {
+	Index      int         `json:"index"`
+	Violations []Violation `json:"violations,omitempty"`
+	Error      string      `json:"error,omitempty"`
+	// Err is the migration error, nil for invalid documents
+	Err error `json:"-"`
+}
+
+// MigrationReport summarises the migration of many documents
+type MigrationReport struct {
+	Schema   string             `json:"schema"`
+	From     int                `json:"from"`
+	To       int                `json:"to"`
+	Total    int                `json:"total"`
+	Migrated int                `json:"migrated"`
+	Failures []MigrationFailure `json:"failures,omitempty"`
+}
+
+// MigrateBatch migrates docs stored under version from to the latest
+// version, returning the migrated documents in order (nil for failures)
+// and a report of those that failed
+func (m *Manager) MigrateBatch(ctx context.Context, schema *Schema, docs []interface{}, from int) ([]interface{}, *MigrationReport) {
+	report := &MigrationReport{Schema: schema.name, From: from, To: schema.Latest(), Total: len(docs)}
+	out := make([]interface{}, len(docs))
+	for i, doc := range docs {
+		migrated, results, err := m.Migrate(ctx, schema, doc, from)
+		switch {
+		case err != nil:
+			report.Failures = append(report.Failures, MigrationFailure{Index: i, Error: err.Error(), Err: err})
+		case !results.Valid():
+			report.Failures = append(report.Failures, MigrationFailure{Index: i, Violations: results.Violations})
+		default:
+			out[i] = migrated
+			report.Migrated++
+		}
+	}
+	if len(report.Failures) > 0 {
+		m.logger.Warnf("Migrated %d of %d %s document(s) to version %d", report.Migrated, report.Total, schema.name, report.To)
+	} else {
+		m.logger.Debugf("Migrated %d %s document(s) to version %d", report.Migrated, schema.name, report.To)
+	}
+	return out, report
+}
@@ -0,0 +1,340 @@
+package validation
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Violation codes reported by UniqueIn and ExistsIn
+const (
+	// CodeUnique is reported for values a store already has
+	CodeUnique = "unique"
+	// CodeExists is reported for values a store does not have
+	CodeExists = "exists"
+)
+
+// Store answers whether records with given field values exist, e.g. a
+// database table or a Redis set; UniqueIn and ExistsIn check values
+// against it. Exists returns one answer per value, in order
+type Store interface {
+	Exists(ctx context.Context, field string, values []interface{}) ([]bool, error)
+}
+
+// StoreFunc adapts a function, e.g. one calling Redis SMISMEMBER, to Store
+type StoreFunc func(ctx context.Context, field string, values []interface{}) ([]bool, error)
+
+// Exists implements Store
+func (f StoreFunc) Exists(ctx context.Context, field string, values []interface{}) ([]bool, error) {
+	return f(ctx, field, values)
+}
+
+// storeOptions holds settings for UniqueIn and ExistsIn
+type storeOptions struct {
+	timeout time.Duration
+	policy  RemotePolicy
+}
+
+// StoreOption configures UniqueIn and ExistsIn
+type StoreOption func(*storeOptions)
+
+// WithStoreTimeout bounds each store call; the default is 1s
+func WithStoreTimeout(d time.Duration) StoreOption {
+	return func(o *storeOptions) {
+		o.timeout = d
+	}
+}
+
+// WithStorePolicy sets the behaviour while the store fails; the default is
+// FailClosed, and FailOpen reports a warning instead
+func WithStorePolicy(policy RemotePolicy) StoreOption {
+	return func(o *storeOptions) {
+		o.policy = policy
+	}
+}
+
+// UniqueIn rejects values that store already has in field, e.g. an email
+// address that is already registered. A list is checked in one store call,
+// element by element. Nil and empty values pass; store failures are
+// CodeUnavailable violations. Results change as the store does, so the
+// rules are Stateful: checks running them are not cached and Preview
+// skips them
+func UniqueIn(store Store, field string, opts ...StoreOption) Rule {
+	return storeRule(store, field, false, opts)
+}
+
+// ExistsIn rejects values that store does not have in field, e.g. the ID
+// of a missing customer, and otherwise behaves like UniqueIn
+func ExistsIn(store Store, field string, opts ...StoreOption) Rule {
+	return storeRule(store, field, true, opts)
+}
+
+// storeRule checks that values exist in store, or that they do not
+func storeRule(store Store, field string, want bool, opts []StoreOption) Rule {
+	options := storeOptions{timeout: time.Second}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return StatefulRule(RuleFunc(func(ctx context.Context, value interface{}) []Violation {
+		if isEmpty(value) {
+			return nil
+		}
+		values, paths := storeValues(indirect(value))
+		if options.timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, options.timeout)
+			defer cancel()
+		}
+		found, err := store.Exists(ctx, field, values)
+		if err == nil && len(found) != len(values) {
+			err = fmt.Errorf("store answered %d of %d values", len(found), len(values))
+		}
+		if err != nil {
+			params := map[string]interface{}{"field": field, "reason": unavailableReason(err)}
+			if options.policy == FailOpen {
+				return []Violation{{Code: CodeUnavailable, Message: fmt.Sprintf("not verified, lookup of %s failed: %v", field, err), Severity: SeverityWarning, Params: params}}
+			}
+			return []Violation{{Code: CodeUnavailable, Message: fmt.Sprintf("cannot be verified, lookup of %s failed: %v", field, err), Params: params}}
+		}
+
+		var violations []Violation
+		for i, exists := range found {
+			switch {
+			case exists == want:
+			case want:
+				violations = append(violations, Violation{Path: paths[i], Code: CodeExists, Message: "does not exist", Params: map[string]interface{}{"field": field}})
+			default:
+				violations = append(violations, Violation{Path: paths[i], Code: CodeUnique, Message: "is already taken", Params: map[string]interface{}{"field": field}})
+			}
+		}
+		return violations
+	}))
+}
+
+// storeValues spreads a list into its elements with their paths
+func storeValues(value interface{}) ([]interface{}, []string) {
+	rv := reflect.ValueOf(value)
+	if k := rv.Kind(); (k != reflect.Slice && k != reflect.Array) || rv.Type().Elem().Kind() == reflect.Uint8 {
+		return []interface{}{value}, []string{""}
+	}
+	values := make([]interface{}, rv.Len())
+	paths := make([]string, rv.Len())
+	for i := range values {
+		values[i] = indirect(rv.Index(i).Interface())
+		paths[i] = fmt.Sprintf("[%d]", i)
+	}
+	return values, paths
+}
+
+// storeKey normalizes values so that e.g. 42 and int64(42), or a string
+// and the []byte a SQL driver returns, compare equal
+func storeKey(value interface{}) string {
+	if b, ok := value.([]byte); ok {
+		return string(b)
+	}
+	return fmt.Sprint(value)
+}
+
+// MemoryStore is a Store held in memory, for tests and small reference sets
+type MemoryStore struct {
+	mu     sync.RWMutex
+	fields map[string]map[string]bool
+}
+
+// NewMemoryStore creates an empty MemoryStore
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{fields: make(map[string]map[string]bool)}
+}
+
+// Add records values for field
+func (s *MemoryStore) Add(field string, values ...interface{}) *MemoryStore {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fields[field] == nil {
+		s.fields[field] = make(map[string]bool)
+	}
+	for _, value := range values {
+		s.fields[field][storeKey(value)] = true
+	}
+	return s
+}
+
+// Remove forgets values for field
+func (s *MemoryStore) Remove(field string, values ...interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, value := range values {
+		delete(s.fields[field], storeKey(value))
+	}
+}
+
+// Exists implements Store
+func (s *MemoryStore) Exists(ctx context.Context, field string, values []interface{}) ([]bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	found := make([]bool, len(values))
+	for i, value := range values {
+		found[i] = s.fields[field][storeKey(value)]
+	}
+	return found, nil
+}
+
+// sqlIdentifier matches table and column names safe to put in a query
+var sqlIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*(\.[A-Za-z_][A-Za-z0-9_]*)?$`)
+
+// SQLStore is a Store over a database table, with fields as columns
+type SQLStore struct {
+	db          *sql.DB
+	table       string
+	placeholder func(n int) string
+}
+
+// SQLOption configures an SQLStore
+type SQLOption func(*SQLStore)
+
+// WithDollarPlaceholders numbers query parameters $1, $2, ... as
+// PostgreSQL expects; the default is ?
+func WithDollarPlaceholders() SQLOption {
+	return func(s *SQLStore) {
+		s.placeholder = func(n int) string { return fmt.Sprintf("$%d", n) }
+	}
+}
+
+// NewSQLStore creates a Store querying table of db. Table and column
+// names must be plain identifiers, since they cannot be query parameters
+func NewSQLStore(db *sql.DB, table string, opts ...SQLOption) (*SQLStore, error) {
+	if !sqlIdentifier.MatchString(table) {
+		return nil, fmt.Errorf("invalid table name %q", table)
+	}
+	s := &SQLStore{db: db, table: table, placeholder: func(int) string { return "?" }}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s, nil
+}
+
+// Exists implements Store with a single SELECT ... WHERE field IN (...)
+func (s *SQLStore) Exists(ctx context.Context, field string, values []interface{}) ([]bool, error) {
+	if !sqlIdentifier.MatchString(field) {
+		return nil, fmt.Errorf("invalid column name %q", field)
+	}
+	placeholders := make([]string, len(values))
+	for i := range values {
+		placeholders[i] = s.placeholder(i + 1)
+	}
+	query := fmt.Sprintf("SELECT DISTINCT %s FROM %s WHERE %s IN (%s)", field, s.table, field, strings.Join(placeholders, ", "))
+	rows, err := s.db.QueryContext(ctx, query, values...)
+	if err != nil {
+		return nil, fmt.Errorf("query %s.%s: %w", s.table, field, err)
+	}
+	defer rows.Close()
+	present := make(map[string]bool)
+	for rows.Next() {
+		var value interface{}
+		if err := rows.Scan(&value); err != nil {
+			return nil, fmt.Errorf("scan %s.%s: %w", s.table, field, err)
+		}
+		present[storeKey(value)] = true
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("query %s.%s: %w", s.table, field, err)
+	}
+	found := make([]bool, len(values))
+	for i, value := range values {
+		found[i] = present[storeKey(value)]
+	}
+	return found, nil
+}
+
+// errBatchCanceled is returned to callers whose context ends while their
+// values wait in a batch
+var errBatchCanceled = errors.New("lookup canceled")
+
+// BatchingStore merges concurrent lookups of the same field, e.g. from
+// ValidateBatch workers, into one call to the underlying store
+type BatchingStore struct {
+	store    Store
+	wait     time.Duration
+	maxBatch int
+
+	mu      sync.Mutex
+	pending map[string]*storeBatch
+}
+
+// storeBatch collects the values of one field until it is flushed
+type storeBatch struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	values []interface{}
+	done   chan struct{}
+	found  []bool
+	err    error
+	timer  *time.Timer
+}
+
+// NewBatchingStore wraps store so that lookups arriving within wait of the
+// first, up to maxBatch values, share one call
+func NewBatchingStore(store Store, wait time.Duration, maxBatch int) *BatchingStore {
+	if maxBatch < 1 {
+		maxBatch = 1
+	}
+	return &BatchingStore{store: store, wait: wait, maxBatch: maxBatch, pending: make(map[string]*storeBatch)}
+}
+
+// Exists implements Store
+func (s *BatchingStore) Exists(ctx context.Context, field string, values []interface{}) ([]bool, error) {
+	s.mu.Lock()
+	batch := s.pending[field]
+	if batch == nil {
+		// The batch runs on behalf of several callers, so one giving up
+		// must not cancel it; its deadline still bounds the call
+		batch = &storeBatch{ctx: context.WithoutCancel(ctx), cancel: func() {}, done: make(chan struct{})}
+		if deadline, ok := ctx.Deadline(); ok {
+			batch.ctx, batch.cancel = context.WithDeadline(batch.ctx, deadline)
+		}
+		s.pending[field] = batch
+		batch.timer = time.AfterFunc(s.wait, func() { s.flush(field, batch) })
+	}
+	offset := len(batch.values)
+	batch.values = append(batch.values, values...)
+	full := len(batch.values) >= s.maxBatch
+	s.mu.Unlock()
+	if full {
+		s.flush(field, batch)
+	}
+
+	select {
+	case <-batch.done:
+	case <-ctx.Done():
+		return nil, fmt.Errorf("%w: %v", errBatchCanceled, ctx.Err())
+	}
+	if batch.err != nil {
+		return nil, batch.err
+	}
+	return batch.found[offset : offset+len(values)], nil
+}
+
+// flush queries the store for a batch once
+func (s *BatchingStore) flush(field string, batch *storeBatch) {
+	s.mu.Lock()
+	if s.pending[field] != batch {
+		s.mu.Unlock()
+		return
+	}
+	delete(s.pending, field)
+	s.mu.Unlock()
+	batch.timer.Stop()
+	defer batch.cancel()
+
+	batch.found, batch.err = s.store.Exists(batch.ctx, field, batch.values)
+	if batch.err == nil && len(batch.found) != len(batch.values) {
+		batch.err = fmt.Errorf("store answered %d of %d values", len(batch.found), len(batch.values))
+	}
+	close(batch.done)
+}
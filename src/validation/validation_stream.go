@@ -0,0 +1,299 @@
+package validation
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// ErrRecordTooLarge reports a stream record longer than the maximum size
+var ErrRecordTooLarge = errors.New("record too large")
+
+// StreamFormat selects how ValidateStream splits its input into records
+type StreamFormat int
+
+const (
+	// StreamNDJSON reads one JSON value per line; blank lines are skipped
+	StreamNDJSON StreamFormat = iota
+	// StreamCSV reads a header row, then validates every row as a map from
+	// column name to string
+	StreamCSV
+)
+
+// StreamResult is the outcome of validating one record of a stream
+type StreamResult struct {
+	// Record numbers the records from 1, not counting blank lines or a CSV header
+	Record     int         `json:"record"`
+	Line       int         `json:"line"`
+	Violations []Violation `json:"violations,omitempty"`
+	Warnings   []Violation `json:"warnings,omitempty"`
+	// Value is the decoded record, e.g. for a Reporter's samples
+	Value interface{} `json:"-"`
+	// Err is set when the record could not be read or decoded
+	Err error `json:"-"`
+}
+
+// Valid reports whether the record was read and has no violations
+func (r StreamResult) Valid() bool {
+	return r.Err == nil && len(r.Violations) == 0
+}
+
+// streamOptions holds settings for ValidateStream
+type streamOptions struct {
+	format        StreamFormat
+	maxRecordSize int
+	prototype     reflect.Type
+	buffer        int
+}
+
+// StreamOption configures ValidateStream
+type StreamOption func(*streamOptions)
+
+// WithStreamFormat sets the input format; the default is StreamNDJSON
+func WithStreamFormat(format StreamFormat) StreamOption {
+	return func(o *streamOptions) {
+		o.format = format
+	}
+}
+
+// WithMaxRecordSize limits the size of one record in bytes; the default is 1 MiB
+func WithMaxRecordSize(n int) StreamOption {
+	return func(o *streamOptions) {
+		o.maxRecordSize = n
+	}
+}
+
+// WithStreamType decodes NDJSON records into new values of prototype's type
+// instead of generic maps, so rule sets restricted with For apply
+func WithStreamType(prototype interface{}) StreamOption {
+	return func(o *streamOptions) {
+		o.prototype = indirectType(reflect.TypeOf(prototype))
+	}
+}
+
+// WithStreamBuffer sets how many results may wait unread on the channel;
+// the default is 16
+func WithStreamBuffer(n int) StreamOption {
+	return func(o *streamOptions) {
+		o.buffer = n
+	}
+}
+
+// ValidateStream reads records from r one at a time and sends a result for
+// each on the returned channel, which is closed at the end of the input.
+// Only the current record is held in memory. An NDJSON line longer than the
+// maximum record size is skipped and reported with ErrRecordTooLarge; CSV
+// framing cannot be recovered after an oversized or malformed row, so the
+// stream ends with that error. Cancelling ctx stops reading
+func (m *Manager) ValidateStream(ctx context.Context, r io.Reader, opts ...StreamOption) <-chan StreamResult {
+	options := streamOptions{maxRecordSize: 1 << 20, buffer: 16}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	if options.buffer < 0 {
+		options.buffer = 0
+	}
+
+	m.mu.RLock()
+	config := m.config
+	m.mu.RUnlock()
+
+	results := make(chan StreamResult, options.buffer)
+	go func() {
+		defer close(results)
+		invalid := 0
+		emit := func(result StreamResult, value interface{}) bool {
+			result.Value = value
+			if result.Err == nil {
+				checked := m.check(ctx, value, config)
+				result.Violations, result.Warnings = checked.Violations, checked.Warnings
+			}
+			if !result.Valid() {
+				invalid++
+			}
+			select {
+			case results <- result:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		var records int
+		var err error
+		switch options.format {
+		case StreamCSV:
+			records, err = streamCSV(ctx, r, options, emit)
+		default:
+			records, err = streamNDJSON(ctx, r, options, emit)
+		}
+		if err != nil && ctx.Err() == nil {
+			m.logger.Warnf("Stream validation stopped after %d record(s): %v", records, err)
+			return
+		}
+		m.logger.Debugf("Validated stream of %d record(s): %d invalid", records, invalid)
+	}()
+	return results
+}
+
+// streamNDJSON reads newline-delimited JSON, calling emit for each record;
+// it returns the number of records read
+func streamNDJSON(ctx context.Context, r io.Reader, options streamOptions, emit func(StreamResult, interface{}) bool) (int, error) {
+	reader := bufio.NewReader(r)
+	var records, line int
+	for ctx.Err() == nil {
+		data, err := readLine(reader, options.maxRecordSize)
+		if err == io.EOF {
+			return records, nil
+		}
+		line++
+		if err != nil && !errors.Is(err, ErrRecordTooLarge) {
+			return records, err
+		}
+		data = bytes.TrimSpace(data)
+		if err == nil && len(data) == 0 {
+			continue
+		}
+
+		records++
+		result := StreamResult{Record: records, Line: line}
+		var value interface{}
+		if err != nil {
+			result.Err = fmt.Errorf("line %d: %w", line, err)
+		} else if value, err = decodeRecord(data, options.prototype); err != nil {
+			result.Err = fmt.Errorf("line %d: %w", line, err)
+		}
+		if !emit(result, value) {
+			break
+		}
+	}
+	return records, ctx.Err()
+}
+
+// readLine returns the next line without its terminator. A line longer
+// than max is consumed and discarded, returning ErrRecordTooLarge, so at
+// most max bytes of it are ever held
+func readLine(reader *bufio.Reader, max int) ([]byte, error) {
+	var line []byte
+	tooLarge := false
+	for {
+		chunk, err := reader.ReadSlice('\n')
+		if !tooLarge {
+			if len(line)+len(chunk) > max+1 {
+				tooLarge, line = true, nil
+			} else {
+				line = append(line, chunk...)
+			}
+		}
+		switch {
+		case err == bufio.ErrBufferFull:
+			continue
+		case err == io.EOF && (len(line) > 0 || tooLarge):
+			err = nil
+		case err != nil:
+			return nil, err
+		}
+		if tooLarge {
+			return nil, fmt.Errorf("%w: over %d bytes", ErrRecordTooLarge, max)
+		}
+		line = bytes.TrimSuffix(line, []byte("\n"))
+		if len(line) > max {
+			return nil, fmt.Errorf("%w: over %d bytes", ErrRecordTooLarge, max)
+		}
+		return line, nil
+	}
+}
+
+// decodeRecord unmarshals one JSON record, into a new value of typ when set
+func decodeRecord(data []byte, typ reflect.Type) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if typ == nil {
+		var value interface{}
+		if err := decoder.Decode(&value); err != nil {
+			return nil, fmt.Errorf("decode: %w", err)
+		}
+		return value, nil
+	}
+	value := reflect.New(typ)
+	if err := decoder.Decode(value.Interface()); err != nil {
+		return nil, fmt.Errorf("decode: %w", err)
+	}
+	return value.Interface(), nil
+}
+
+// streamCSV reads a header row and then one map per row, calling emit for
+// each; it returns the number of records read
+func streamCSV(ctx context.Context, r io.Reader, options streamOptions, emit func(StreamResult, interface{}) bool) (int, error) {
+	guard := &recordGuard{r: r, limit: int64(options.maxRecordSize)}
+	reader := csv.NewReader(guard)
+	reader.ReuseRecord = true
+	header, err := reader.Read()
+	if err != nil {
+		if err == io.EOF {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("header: %w", err)
+	}
+	header = append([]string(nil), header...)
+	guard.mark = reader.InputOffset()
+
+	records := 0
+	for ctx.Err() == nil {
+		row, err := reader.Read()
+		if err == io.EOF {
+			return records, nil
+		}
+		records++
+		result := StreamResult{Record: records}
+		if err == nil {
+			result.Line, _ = reader.FieldPos(0)
+			if reader.InputOffset()-guard.mark > guard.limit {
+				err = fmt.Errorf("%w: over %d bytes", ErrRecordTooLarge, options.maxRecordSize)
+			}
+		}
+		guard.mark = reader.InputOffset()
+		if err != nil {
+			result.Err = fmt.Errorf("record %d: %w", records, err)
+			emit(result, nil)
+			return records, result.Err
+		}
+
+		value := make(map[string]interface{}, len(header))
+		for i, column := range header {
+			if i < len(row) {
+				value[column] = row[i]
+			}
+		}
+		if !emit(result, value) {
+			break
+		}
+	}
+	return records, ctx.Err()
+}
+
+// recordGuard fails reads once the current CSV record grows past limit
+// (plus read-ahead slack), so an unterminated row cannot exhaust memory
+type recordGuard struct {
+	r     io.Reader
+	read  int64
+	mark  int64
+	limit int64
+}
+
+// Read implements io.Reader
+func (g *recordGuard) Read(p []byte) (int, error) {
+	// csv.Reader reads ahead through a 4 KiB buffer
+	if g.read-g.mark > g.limit+4096 {
+		return 0, fmt.Errorf("%w: over %d bytes", ErrRecordTooLarge, g.limit)
+	}
+	n, err := g.r.Read(p)
+	g.read += int64(n)
+	return n, err
+}
@@ -0,0 +1,442 @@
+package validation
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ColumnType is the type a table column's cells must convert to; rules of
+// the column see converted values (int64, float64, bool, time.Time)
+type ColumnType string
+
+// Column types; an empty type leaves cells as read
+const (
+	ColumnString    ColumnType = "string"
+	ColumnInt       ColumnType = "int"
+	ColumnFloat     ColumnType = "float"
+	ColumnBool      ColumnType = "bool"
+	ColumnDate      ColumnType = "date"
+	ColumnTimestamp ColumnType = "timestamp"
+)
+
+// defaultSampleSize is how many failures per rule a TableReport keeps
+const defaultSampleSize = 10
+
+// Column declares the constraints on one column of a table
+type Column struct {
+	Name     string
+	Type     ColumnType
+	Nullable bool
+	// Unique requires non-null cells to differ; every distinct value is
+	// kept in memory for the whole table
+	Unique bool
+	Rules  []Rule
+}
+
+// Table declares the columns of tabular data, such as a CSV file or
+// Parquet rows, and rules across them
+type Table struct {
+	name       string
+	columns    []Column
+	rowRules   []tableRowRule
+	nulls      []string
+	sampleSize int
+}
+
+// tableRowRule is a named cross-column rule
+type tableRowRule struct {
+	name  string
+	rules []Rule
+}
+
+// NewTable returns a table of columns; columns not declared are ignored
+func NewTable(name string, columns ...Column) *Table {
+	return &Table{name: name, columns: columns, nulls: []string{""}, sampleSize: defaultSampleSize}
+}
+
+// RowRule adds rules checked against each row as a map of converted cells
+// by column name, e.g. Expr("ends_at >= starts_at", ...); they run only
+// when every cell converted to its column type
+func (t *Table) RowRule(name string, rules ...Rule) *Table {
+	t.rowRules = append(t.rowRules, tableRowRule{name: name, rules: rules})
+	return t
+}
+
+// NullValues sets the cell texts read as null, "" by default, e.g. "",
+// "NULL" and "NA"
+func (t *Table) NullValues(values ...string) *Table {
+	t.nulls = values
+	return t
+}
+
+// SampleSize sets how many failing rows a TableReport keeps per rule; the
+// default is 10
+func (t *Table) SampleSize(n int) *Table {
+	t.sampleSize = n
+	return t
+}
+
+// Name returns the table name
+func (t *Table) Name() string {
+	return t.name
+}
+
+// RowReader streams rows of tabular data. Parquet, Arrow or database rows
+// are validated by adapting their readers to it; CSVRows reads CSV
+type RowReader interface {
+	// Columns returns the column names, in the order of row cells
+	Columns() ([]string, error)
+	// Read returns the next row, or io.EOF after the last one
+	Read() ([]interface{}, error)
+}
+
+// csvRows is the RowReader returned by CSVRows
+type csvRows struct {
+	reader *csv.Reader
+	header []string
+	err    error
+}
+
+// CSVRows reads CSV with a header row from r; comma is the delimiter, ','
+// when zero
+func CSVRows(r io.Reader, comma rune) RowReader {
+	reader := csv.NewReader(r)
+	if comma != 0 {
+		reader.Comma = comma
+	}
+	return &csvRows{reader: reader}
+}
+
+// Columns implements RowReader
+func (c *csvRows) Columns() ([]string, error) {
+	if c.header == nil && c.err == nil {
+		header, err := c.reader.Read()
+		if errors.Is(err, io.EOF) {
+			err = errors.New("missing header row")
+		}
+		if err != nil {
+			c.err = err
+		} else {
+			c.header = make([]string, len(header))
+			for i, name := range header {
+				c.header[i] = strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))
+			}
+		}
+	}
+	return c.header, c.err
+}
+
+// Read implements RowReader
+func (c *csvRows) Read() ([]interface{}, error) {
+	if _, err := c.Columns(); err != nil {
+		return nil, err
+	}
+	record, err := c.reader.Read()
+	if err != nil {
+		return nil, err
+	}
+	row := make([]interface{}, len(record))
+	for i, cell := range record {
+		row[i] = cell
+	}
+	return row, nil
+}
+
+// TableReport summarizes the validation of a table
+type TableReport struct {
+	Table      string `json:"table"`
+	Rows       int    `json:"rows"`
+	FailedRows int    `json:"failed_rows"`
+	// Violations are problems with the table itself, such as missing columns
+	Violations []Violation    `json:"violations,omitempty"`
+	Rules      []TableRuleSum `json:"rules,omitempty"`
+}
+
+// TableRuleSum counts the failures of one rule, keeping a bounded sample
+type TableRuleSum struct {
+	// Rule is "<column>.type", "<column>.required", "<column>.unique",
+	// "<column>.rules" or the name of a row rule
+	Rule     string        `json:"rule"`
+	Failures int           `json:"failures"`
+	Samples  []TableSample `json:"samples,omitempty"`
+}
+
+// TableSample is a failing row; rows are numbered from 1, not counting
+// the header
+type TableSample struct {
+	Row        int         `json:"row"`
+	Violations []Violation `json:"violations"`
+}
+
+// Valid reports whether every row passed
+func (r *TableReport) Valid() bool {
+	return r.FailedRows == 0 && len(r.Violations) == 0
+}
+
+// Failures returns the summary of rule, if it failed
+func (r *TableReport) Failures(rule string) (TableRuleSum, bool) {
+	for _, sum := range r.Rules {
+		if sum.Rule == rule {
+			return sum, true
+		}
+	}
+	return TableRuleSum{}, false
+}
+
+// ValidateTable streams rows through the column and row rules of table.
+// Failing rows are counted per rule, paths are the column names; errors
+// are only returned when rows cannot be read or ctx is done, along with
+// the report so far. Missing columns that are not nullable are reported
+// once in TableReport.Violations
+func (m *Manager) ValidateTable(ctx context.Context, table *Table, rows RowReader) (*TableReport, error) {
+	m.mu.RLock()
+	catalog := m.catalog
+	m.mu.RUnlock()
+	locale := Locale(ctx)
+	localize := func(violations []Violation) []Violation {
+		if locale == "" || len(violations) == 0 {
+			return violations
+		}
+		if catalog == nil {
+			catalog = defaultCatalog
+		}
+		return catalog.Localize(locale, violations)
+	}
+
+	start := time.Now()
+	report := &TableReport{Table: table.name}
+	header, err := rows.Columns()
+	if err != nil {
+		return report, fmt.Errorf("table %s: read columns: %w", table.name, err)
+	}
+	positions := make(map[string]int, len(header))
+	for i, name := range header {
+		positions[name] = i
+	}
+	for _, column := range table.columns {
+		if _, ok := positions[column.Name]; !ok && !column.Nullable {
+			report.Violations = append(report.Violations, Violation{
+				Path:    column.Name,
+				Code:    CodeRequired,
+				Message: "is a required column",
+				Params:  map[string]interface{}{"column": column.Name},
+			})
+		}
+	}
+	report.Violations = localize(report.Violations)
+
+	sums := make(map[string]*TableRuleSum)
+	record := func(rule string, row int, violations []Violation) {
+		sum, ok := sums[rule]
+		if !ok {
+			sum = &TableRuleSum{Rule: rule}
+			sums[rule] = sum
+		}
+		sum.Failures++
+		if len(sum.Samples) < table.sampleSize {
+			sum.Samples = append(sum.Samples, TableSample{Row: row, Violations: localize(violations)})
+		}
+	}
+	seen := make(map[string]map[string]int)
+	for _, column := range table.columns {
+		if column.Unique {
+			seen[column.Name] = make(map[string]int)
+		}
+	}
+
+	for {
+		if err := ctx.Err(); err != nil {
+			report.finish(sums)
+			return report, fmt.Errorf("table %s: stopped after %d row(s): %w", table.name, report.Rows, err)
+		}
+		cells, err := rows.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			report.finish(sums)
+			return report, fmt.Errorf("table %s: row %d: %w", table.name, report.Rows+1, err)
+		}
+		report.Rows++
+		if table.checkRow(ctx, report.Rows, cells, positions, seen, record) {
+			report.FailedRows++
+		}
+	}
+	report.finish(sums)
+
+	if report.Valid() {
+		m.logger.Debugf("Validated table %s: %d row(s) in %s", table.name, report.Rows, time.Since(start))
+	} else {
+		m.logger.Warnf("Table %s failed validation: %d of %d row(s) invalid", table.name, report.FailedRows, report.Rows)
+	}
+	return report, nil
+}
+
+// checkRow checks one row, recording failures per rule, and reports
+// whether any rule failed
+func (t *Table) checkRow(ctx context.Context, row int, cells []interface{}, positions map[string]int, seen map[string]map[string]int, record func(string, int, []Violation)) bool {
+	values := make(map[string]interface{}, len(t.columns))
+	failed, converted := false, true
+	for _, column := range t.columns {
+		i, ok := positions[column.Name]
+		if !ok {
+			// Reported once for the table
+			values[column.Name] = nil
+			continue
+		}
+		var cell interface{}
+		if i < len(cells) {
+			cell = cells[i]
+		}
+		if t.isNull(cell) {
+			values[column.Name] = nil
+			if !column.Nullable {
+				record(column.Name+".required", row, []Violation{{Path: column.Name, Code: CodeRequired, Message: "is required"}})
+				failed = true
+			}
+			continue
+		}
+		value, ok := convertCell(column.Type, cell)
+		if !ok {
+			violation := typeViolation(string(column.Type), cell)
+			violation.Path = column.Name
+			record(column.Name+".type", row, []Violation{violation})
+			failed, converted = true, false
+			continue
+		}
+		values[column.Name] = value
+
+		if column.Unique {
+			key := fmt.Sprint(value)
+			if first, dup := seen[column.Name][key]; dup {
+				record(column.Name+".unique", row, []Violation{{
+					Path:    column.Name,
+					Code:    CodeUnique,
+					Message: fmt.Sprintf("duplicates row %d", first),
+					Params:  map[string]interface{}{"row": first},
+				}})
+				failed = true
+			} else {
+				seen[column.Name][key] = row
+			}
+		}
+		if violations := blocking(All(column.Rules...).Check(ctx, value)); len(violations) > 0 {
+			for i := range violations {
+				violations[i].Path = joinPath(column.Name, violations[i].Path)
+			}
+			record(column.Name+".rules", row, violations)
+			failed = true
+		}
+	}
+
+	if !converted {
+		return failed
+	}
+	for _, rule := range t.rowRules {
+		if violations := blocking(All(rule.rules...).Check(ctx, values)); len(violations) > 0 {
+			record(rule.name, row, violations)
+			failed = true
+		}
+	}
+	return failed
+}
+
+// blocking drops warnings, which do not fail a row
+func blocking(violations []Violation) []Violation {
+	out := violations[:0]
+	for _, v := range violations {
+		if v.Severity != SeverityWarning {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// isNull reports whether cell is nil or one of the null texts
+func (t *Table) isNull(cell interface{}) bool {
+	cell = indirect(cell)
+	if cell == nil {
+		return true
+	}
+	if s, ok := cell.(string); ok {
+		for _, null := range t.nulls {
+			if s == null {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// finish sorts the rule summaries into the report
+func (r *TableReport) finish(sums map[string]*TableRuleSum) {
+	r.Rules = r.Rules[:0]
+	for _, sum := range sums {
+		r.Rules = append(r.Rules, *sum)
+	}
+	sort.Slice(r.Rules, func(i, j int) bool {
+		return r.Rules[i].Rule < r.Rules[j].Rule
+	})
+}
+
+// convertCell converts cell, as read or text, to the Go value of typ
+func convertCell(typ ColumnType, cell interface{}) (interface{}, bool) {
+	cell = indirect(cell)
+	s, isText := cell.(string)
+	if isText {
+		s = strings.TrimSpace(s)
+	}
+	switch typ {
+	case "":
+		return cell, true
+	case ColumnString:
+		if isText {
+			return cell, true
+		}
+		if b, ok := cell.([]byte); ok {
+			return string(b), true
+		}
+	case ColumnInt:
+		if isText {
+			n, err := strconv.ParseInt(s, 10, 64)
+			return n, err == nil
+		}
+		if f, ok := number(cell); ok && f == math.Trunc(f) {
+			return int64(f), true
+		}
+	case ColumnFloat:
+		if isText {
+			f, err := strconv.ParseFloat(s, 64)
+			return f, err == nil
+		}
+		return number(cell)
+	case ColumnBool:
+		if isText {
+			b, err := strconv.ParseBool(s)
+			return b, err == nil
+		}
+		b, ok := cell.(bool)
+		return b, ok
+	case ColumnDate, ColumnTimestamp:
+		if t, ok := cell.(time.Time); ok {
+			return t, true
+		}
+		layout := time.RFC3339Nano
+		if typ == ColumnDate {
+			layout = "2006-01-02"
+		}
+		if isText {
+			t, err := time.Parse(layout, s)
+			return t, err == nil
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,213 @@
+package validation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// TypedRule checks values of type T without type assertions; it is the
+// generic counterpart of Rule, which keeps its name for compatibility
+type TypedRule[T any] interface {
+	Check(ctx context.Context, value T) []Violation
+}
+
+// TypedRuleFunc adapts a function to TypedRule
+type TypedRuleFunc[T any] func(ctx context.Context, value T) []Violation
+
+// Check implements TypedRule
+func (f TypedRuleFunc[T]) Check(ctx context.Context, value T) []Violation {
+	return f(ctx, value)
+}
+
+// TypedPredicate reports a violation with code and message when ok returns false
+func TypedPredicate[T any](code, message string, ok func(value T) bool) TypedRule[T] {
+	return TypedRuleFunc[T](func(ctx context.Context, value T) []Violation {
+		if ok(value) {
+			return nil
+		}
+		return []Violation{{Code: code, Message: message}}
+	})
+}
+
+// FieldOf applies rules to the field get selects, reporting violations
+// under path; unlike Field it needs no reflection, e.g.
+//
+//	FieldOf("email", func(u User) string { return u.Email }, Typed[string](Required(), Format("email")))
+func FieldOf[T, F any](path string, get func(T) F, rules ...TypedRule[F]) TypedRule[T] {
+	return TypedRuleFunc[T](func(ctx context.Context, value T) []Violation {
+		field := get(value)
+		mode := modeOf(ctx)
+		var violations []Violation
+		for _, rule := range rules {
+			found := rule.Check(ctx, field)
+			for i := range found {
+				found[i].Path = joinPath(path, found[i].Path)
+			}
+			var stopped bool
+			if violations, stopped = mode.stop(append(violations, found...)); stopped {
+				break
+			}
+		}
+		return violations
+	})
+}
+
+// Typed adapts untyped rules, such as Required or Field, to values of type T
+func Typed[T any](rules ...Rule) TypedRule[T] {
+	all := All(rules...)
+	return TypedRuleFunc[T](func(ctx context.Context, value T) []Violation {
+		return all.Check(ctx, value)
+	})
+}
+
+// Untyped adapts a typed rule to Rule, e.g. to add it to a RuleSet;
+// values of another type than T or *T are a CodeType violation and nil
+// pointers pass
+func Untyped[T any](rule TypedRule[T]) Rule {
+	return RuleFunc(func(ctx context.Context, value interface{}) []Violation {
+		switch v := value.(type) {
+		case T:
+			return rule.Check(ctx, v)
+		case *T:
+			if v == nil {
+				return nil
+			}
+			return rule.Check(ctx, *v)
+		}
+		return []Violation{typeViolation(reflect.TypeOf((*T)(nil)).Elem().String(), value)}
+	})
+}
+
+// TypedManager validates values of type T with typed rules, followed by
+// the rule sets registered with its Manager, which remains available for
+// the untyped API
+type TypedManager[T any] struct {
+	manager *Manager
+	rules   []TypedRule[T]
+	name    string
+}
+
+// NewTypedManager creates a typed manager with the default configuration
+func NewTypedManager[T any](rules ...TypedRule[T]) *TypedManager[T] {
+	return NewTypedManagerWithConfig(DefaultConfig(), rules...)
+}
+
+// NewTypedManagerWithConfig creates a typed manager with config
+func NewTypedManagerWithConfig[T any](config *Config, rules ...TypedRule[T]) *TypedManager[T] {
+	return &TypedManager[T]{
+		manager: NewManager(config),
+		rules:   rules,
+		name:    reflect.TypeOf((*T)(nil)).Elem().String(),
+	}
+}
+
+// Manager returns the underlying manager, e.g. to register rule sets or
+// set a catalog
+func (t *TypedManager[T]) Manager() *Manager {
+	return t.manager
+}
+
+// Check validates value against the typed rules, then, unless validating
+// fail-fast stopped early, against the registered rule sets
+func (t *TypedManager[T]) Check(ctx context.Context, value T) *Results {
+	t.manager.mu.RLock()
+	config := t.manager.config
+	t.manager.mu.RUnlock()
+	return t.check(ctx, value, config)
+}
+
+// check runs checkValue between the registered hooks
+func (t *TypedManager[T]) check(ctx context.Context, value T, config *Config) *Results {
+	return t.manager.hooked(ctx, value, func(ctx context.Context) *Results {
+		return t.checkValue(ctx, value, config)
+	})
+}
+
+// checkValue validates value with config
+func (t *TypedManager[T]) checkValue(ctx context.Context, value T, config *Config) *Results {
+	m := t.manager
+	m.rulesMu.RLock()
+	catalog := m.catalog
+	m.rulesMu.RUnlock()
+
+	mode := newCheckMode(config)
+	checkCtx := m.instrument(context.WithValue(ctx, checkModeKey{}, mode))
+	violations := mode.checkLimits(value)
+	if violations != nil {
+		return mode.results(catalog, Locale(ctx), violations)
+	}
+	stopped := false
+	for i, rule := range t.rules {
+		found := observeRule(checkCtx, t.name, fmt.Sprintf("#%d", i+1), Untyped(rule), value)
+		if violations, stopped = mode.stop(append(violations, found...)); stopped {
+			break
+		}
+	}
+	results := mode.results(catalog, Locale(ctx), m.maskViolations(value, violations))
+	if stopped {
+		return results
+	}
+	if registered := m.checkData(ctx, value, config); registered != nil {
+		results.Violations = append(results.Violations, registered.Violations...)
+		results.Warnings = append(results.Warnings, registered.Warnings...)
+	}
+	return results
+}
+
+// Validate returns the violations of value as ValidationErrors, or nil
+func (t *TypedManager[T]) Validate(ctx context.Context, value T) error {
+	t.manager.mu.RLock()
+	config := t.manager.config
+	t.manager.mu.RUnlock()
+	_, err := t.validate(ctx, value, config)
+	return err
+}
+
+// Process validates and processes value like Manager.Process, taking
+// DataSize from Size instead of formatting value
+func (t *TypedManager[T]) Process(ctx context.Context, value T) (*Result, error) {
+	return t.manager.process(ctx, value, func(config *Config) ([]Violation, error) {
+		return t.validate(ctx, value, config)
+	}, func() int {
+		return Size(value)
+	})
+}
+
+// validate returns the warnings of value, or its violations as an error
+func (t *TypedManager[T]) validate(ctx context.Context, value T, config *Config) ([]Violation, error) {
+	results := t.check(ctx, value, config)
+	if err := results.Err(); err != nil {
+		t.manager.logger.Warnf("Validation of %s failed: %v", t.name, err)
+		return nil, err
+	}
+	if len(results.Warnings) > 0 {
+		t.manager.logger.Warnf("Validation of %s passed with %d warning(s): %v", t.name, len(results.Warnings), results.Warnings)
+	}
+	return results.Warnings, nil
+}
+
+// Sizer reports the size of a value in bytes, for Result.DataSize
+type Sizer interface {
+	Size() int
+}
+
+// Size returns the size of value without formatting it: Size for Sizer
+// values, the length of strings and byte slices, and otherwise the length
+// of its JSON encoding
+func Size[T any](value T) int {
+	switch v := interface{}(value).(type) {
+	case Sizer:
+		return v.Size()
+	case string:
+		return len(v)
+	case []byte:
+		return len(v)
+	}
+	data, err := json.Marshal(value)
+	if err != nil {
+		return 0
+	}
+	return len(data)
+}
@@ -0,0 +1,155 @@
+// Package validtest provides helpers for testing validation rule suites
+package validtest
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/nerufuyo/roastume/src/validation"
+)
+
+// Checker validates inputs; *validation.Manager is one, and Rules adapts
+// single rules
+type Checker interface {
+	Check(ctx context.Context, data interface{}) *validation.Results
+}
+
+// CheckerFunc adapts a function to a Checker
+type CheckerFunc func(ctx context.Context, data interface{}) *validation.Results
+
+// Check implements Checker
+func (f CheckerFunc) Check(ctx context.Context, data interface{}) *validation.Results {
+	return f(ctx, data)
+}
+
+// Rules returns a Checker running rules as a rule set of a fresh manager,
+// so they can be tested without registering them anywhere
+func Rules(rules ...validation.Rule) Checker {
+	set := validation.NewRuleSet("validtest", rules...)
+	manager := validation.NewManager(validation.DefaultConfig())
+	return CheckerFunc(func(ctx context.Context, data interface{}) *validation.Results {
+		return manager.CheckWith(ctx, data, set)
+	})
+}
+
+// AssertPasses fails t when input has blocking violations
+func AssertPasses(t testing.TB, c Checker, input interface{}) *validation.Results {
+	t.Helper()
+	results := c.Check(context.Background(), input)
+	if !results.Valid() {
+		t.Errorf("expected input to pass, got violations:\n%s", describe(results.Violations))
+	}
+	return results
+}
+
+// AssertFails fails t unless input has blocking violations matching each
+// of want. A want matches a violation by its code ("format") or by path
+// and code ("email.format", "items[0].sku.required"); with no want any
+// violation will do
+func AssertFails(t testing.TB, c Checker, input interface{}, want ...string) *validation.Results {
+	t.Helper()
+	results := c.Check(context.Background(), input)
+	if results.Valid() {
+		t.Errorf("expected input to fail with %s, but it passed", wanted(want))
+		return results
+	}
+	if missing := unmatched(want, results.Violations); len(missing) > 0 {
+		t.Errorf("expected violations %s, got:\n%s", wanted(missing), describe(results.Violations))
+	}
+	return results
+}
+
+// AssertViolations fails t unless the blocking violations of input match
+// want exactly, one for one and in any order
+func AssertViolations(t testing.TB, c Checker, input interface{}, want ...string) *validation.Results {
+	t.Helper()
+	results := c.Check(context.Background(), input)
+	missing, extra := compare(want, results.Violations)
+	if len(missing) > 0 || len(extra) > 0 {
+		t.Errorf("violations differ: missing %s, unexpected:\n%s", wanted(missing), describe(extra))
+	}
+	return results
+}
+
+// AssertWarns fails t unless input has warnings matching each of want
+func AssertWarns(t testing.TB, c Checker, input interface{}, want ...string) *validation.Results {
+	t.Helper()
+	results := c.Check(context.Background(), input)
+	if len(results.Warnings) == 0 {
+		t.Errorf("expected warnings %s, got none", wanted(want))
+		return results
+	}
+	if missing := unmatched(want, results.Warnings); len(missing) > 0 {
+		t.Errorf("expected warnings %s, got:\n%s", wanted(missing), describe(results.Warnings))
+	}
+	return results
+}
+
+// matches reports whether want names v by code or by path and code
+func matches(want string, v validation.Violation) bool {
+	return want == v.Code || (v.Path != "" && want == v.Path+"."+v.Code)
+}
+
+// unmatched returns the wants no violation matches
+func unmatched(want []string, violations []validation.Violation) []string {
+	var missing []string
+	for _, w := range want {
+		found := false
+		for _, v := range violations {
+			if matches(w, v) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, w)
+		}
+	}
+	return missing
+}
+
+// compare pairs wants with violations one for one, returning what is left
+// of each
+func compare(want []string, violations []validation.Violation) ([]string, []validation.Violation) {
+	extra := append([]validation.Violation(nil), violations...)
+	var missing []string
+	for _, w := range want {
+		found := false
+		for i, v := range extra {
+			if matches(w, v) {
+				extra = append(extra[:i], extra[i+1:]...)
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, w)
+		}
+	}
+	return missing, extra
+}
+
+// wanted formats wants for failure messages
+func wanted(want []string) string {
+	if len(want) == 0 {
+		return "any violation"
+	}
+	sorted := append([]string(nil), want...)
+	sort.Strings(sorted)
+	return fmt.Sprintf("[%s]", strings.Join(sorted, ", "))
+}
+
+// describe lists violations one per line for failure messages
+func describe(violations []validation.Violation) string {
+	if len(violations) == 0 {
+		return "  (none)"
+	}
+	lines := make([]string, len(violations))
+	for i, v := range violations {
+		lines[i] = "  " + v.String()
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,96 @@
+package validtest
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/nerufuyo/roastume/src/validation"
+)
+
+// recorder is a testing.TB that records failures instead of failing
+type recorder struct {
+	testing.TB
+	errors []string
+	fatal  bool
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func (r *recorder) Fatalf(format string, args ...interface{}) {
+	r.Errorf(format, args...)
+	r.fatal = true
+	runtime.Goexit()
+}
+
+// record runs assert against a recorder on its own goroutine, so Fatalf
+// can stop it
+func record(t *testing.T, assert func(tb testing.TB)) *recorder {
+	r := &recorder{TB: t}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		assert(r)
+	}()
+	<-done
+	return r
+}
+
+var signup = Rules(
+	validation.Field("email", validation.Required(), validation.Format(validation.FormatEmail)),
+	validation.Field("name", validation.Required()),
+	validation.Field("nick", validation.Warn(validation.Length(0, 4))),
+)
+
+func TestAsserts(t *testing.T) {
+	valid := map[string]interface{}{"email": "ada@example.com", "name": "Ada"}
+	badEmail := map[string]interface{}{"email": "ada@", "name": "Ada"}
+	empty := map[string]interface{}{}
+	longNick := map[string]interface{}{"email": "ada@example.com", "name": "Ada", "nick": "countess"}
+
+	for _, tt := range []struct {
+		name   string
+		assert func(tb testing.TB)
+		fails  bool
+	}{
+		{"passes", func(tb testing.TB) { AssertPasses(tb, signup, valid) }, false},
+		{"passes with violations", func(tb testing.TB) { AssertPasses(tb, signup, badEmail) }, true},
+		{"fails with any", func(tb testing.TB) { AssertFails(tb, signup, badEmail) }, false},
+		{"fails by code", func(tb testing.TB) { AssertFails(tb, signup, badEmail, "format") }, false},
+		{"fails by path and code", func(tb testing.TB) { AssertFails(tb, signup, badEmail, "email.format") }, false},
+		{"fails with another code", func(tb testing.TB) { AssertFails(tb, signup, badEmail, "email.required") }, true},
+		{"fails when valid", func(tb testing.TB) { AssertFails(tb, signup, valid) }, true},
+		{"violations exact", func(tb testing.TB) { AssertViolations(tb, signup, empty, "name.required", "email.required") }, false},
+		{"violations missing", func(tb testing.TB) { AssertViolations(tb, signup, empty, "email.required") }, true},
+		{"violations extra", func(tb testing.TB) { AssertViolations(tb, signup, badEmail, "email.format", "name.required") }, true},
+		{"violations repeated", func(tb testing.TB) { AssertViolations(tb, signup, badEmail, "format", "format") }, true},
+		{"violations none", func(tb testing.TB) { AssertViolations(tb, signup, valid) }, false},
+		{"warns", func(tb testing.TB) { AssertWarns(tb, signup, longNick, "nick.length") }, false},
+		{"warns with another code", func(tb testing.TB) { AssertWarns(tb, signup, longNick, "nick.required") }, true},
+		{"warns without warnings", func(tb testing.TB) { AssertWarns(tb, signup, valid) }, true},
+	} {
+		r := record(t, tt.assert)
+		if failed := len(r.errors) > 0; failed != tt.fails {
+			t.Errorf("%s: failed = %v, want %v: %v", tt.name, failed, tt.fails, r.errors)
+		}
+	}
+}
+
+func TestFailureMessages(t *testing.T) {
+	r := record(t, func(tb testing.TB) {
+		AssertFails(tb, signup, map[string]interface{}{}, "name.required", "email.format")
+	})
+	if len(r.errors) != 1 {
+		t.Fatalf("errors = %q, want one", r.errors)
+	}
+	for _, want := range []string{"[email.format]", "email", "required"} {
+		if !strings.Contains(r.errors[0], want) {
+			t.Errorf("message %q does not mention %q", r.errors[0], want)
+		}
+	}
+}
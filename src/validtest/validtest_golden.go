@@ -0,0 +1,56 @@
+package validtest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/nerufuyo/roastume/src/validation"
+)
+
+// UpdateEnv names the environment variable that makes AssertGolden write
+// golden files instead of comparing against them, e.g.
+// VALIDTEST_UPDATE=1 go test ./...
+const UpdateEnv = "VALIDTEST_UPDATE"
+
+// AssertGolden compares the results for input, as indented JSON, with the
+// golden file at path (conventionally under testdata); with UpdateEnv set
+// the file is rewritten instead
+func AssertGolden(t testing.TB, c Checker, input interface{}, path string) *validation.Results {
+	t.Helper()
+	results := c.Check(context.Background(), input)
+	CompareGolden(t, results, path)
+	return results
+}
+
+// CompareGolden compares v, typically Results or ValidationErrors, as
+// indented JSON with the golden file at path, see AssertGolden
+func CompareGolden(t testing.TB, v interface{}, path string) {
+	t.Helper()
+	got, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		t.Fatalf("encode %s: %v", path, err)
+	}
+	got = append(got, '\n')
+
+	if os.Getenv(UpdateEnv) != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("update %s: %v", path, err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatalf("update %s: %v", path, err)
+		}
+		return
+	}
+
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read golden file (set %s=1 to create it): %v", UpdateEnv, err)
+	}
+	if !bytes.Equal(bytes.TrimSpace(got), bytes.TrimSpace(want)) {
+		t.Errorf("results differ from %s (set %s=1 to update)\ngot:\n%s\nwant:\n%s", path, UpdateEnv, got, want)
+	}
+}
@@ -0,0 +1,34 @@
+package validtest
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestGolden(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "testdata", "signup.json")
+	input := map[string]interface{}{"email": "ada@"}
+
+	r := record(t, func(tb testing.TB) { AssertGolden(tb, signup, input, path) })
+	if !r.fatal {
+		t.Errorf("missing golden file did not stop the test: %v", r.errors)
+	}
+
+	t.Setenv(UpdateEnv, "1")
+	if r := record(t, func(tb testing.TB) { AssertGolden(tb, signup, input, path) }); len(r.errors) > 0 {
+		t.Fatalf("update: %v", r.errors)
+	}
+	if data, err := os.ReadFile(path); err != nil || !strings.Contains(string(data), `"format"`) {
+		t.Fatalf("golden file = %q, %v", data, err)
+	}
+
+	t.Setenv(UpdateEnv, "")
+	if r := record(t, func(tb testing.TB) { AssertGolden(tb, signup, input, path) }); len(r.errors) > 0 {
+		t.Errorf("unchanged results differ from golden file: %v", r.errors)
+	}
+	if r := record(t, func(tb testing.TB) { AssertGolden(tb, signup, map[string]interface{}{}, path) }); len(r.errors) == 0 {
+		t.Error("changed results match the golden file")
+	}
+}